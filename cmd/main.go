@@ -32,6 +32,18 @@ func main() {
 		},
 	}
 	app.Action = startApi
+	app.Commands = []cli.Command{
+		{
+			Name:   "encrypt-pii",
+			Usage:  "encrypts the stored personal data with the active pii key",
+			Action: encryptPii,
+		},
+		{
+			Name:   "backfill-paid-at",
+			Usage:  "dates the payment of the invoices stored before it was kept at the block of their purchase",
+			Action: backfillPaidAt,
+		},
+	}
 
 	err := app.Run(os.Args)
 	if err != nil {
@@ -47,35 +59,39 @@ func startApi(ctx *cli.Context) error {
 		return err
 	}
 
-	generalConfigPath := ctx.GlobalString(generalConfigFile.Name)
-	network := os.Getenv("EE_EVM_NET")
-	if network == "" {
-		err = errors.New("EE_EVM_NET environment variable not set, cannot load config")
-		return err
-	}
-
-	cfg, err := config.LoadConfig(generalConfigPath + "config." + network + ".json")
+	err = loadConfig(ctx)
 	if err != nil {
-		err = errors.New("error while loading configs: " + err.Error())
 		return err
 	}
 
-	config.Config = *cfg
-
 	storage.Connect()
+	repos := storage.NewGormRepositories()
+	service.SetRepositories(repos)
 	templates.LoadAndCacheTemplates()
 
-	if !config.Config.Api.DevTesting {
-		buyLicenseInvoiceNodeTiming, found := config.Config.GetBuyLicenseInvoiceCronJobTiming(nodeAddress)
-		if found {
-			c := cron.New()
-			_, err = c.AddFunc(buyLicenseInvoiceNodeTiming, service.ElaborateInvoices)
-			if err != nil {
-				return errors.New("error while starting cronjob: " + err.Error())
-			}
-			c.Start()
+	// the purchase to invoice flow also runs in dev testing, where invoices are
+	// issued by the local provider
+	indexerNodeTiming, found := config.Config.GetIndexerCronJobTiming(nodeAddress)
+	if found {
+		c := cron.New(cron.WithChain(cron.SkipIfStillRunning(cron.DefaultLogger)))
+		_, err = c.AddFunc(indexerNodeTiming, service.IndexChain)
+		if err != nil {
+			return errors.New("error while starting indexer cronjob: " + err.Error())
 		}
+		c.Start()
+	}
+
+	buyLicenseInvoiceNodeTiming, found := config.Config.GetBuyLicenseInvoiceCronJobTiming(nodeAddress)
+	if found {
+		c := cron.New(cron.WithChain(cron.SkipIfStillRunning(cron.DefaultLogger)))
+		_, err = c.AddFunc(buyLicenseInvoiceNodeTiming, service.ElaborateInvoices)
+		if err != nil {
+			return errors.New("error while starting cronjob: " + err.Error())
+		}
+		c.Start()
+	}
 
+	if !config.Config.Api.DevTesting {
 		dailyNodeTiming, found := config.Config.GetDailyCronJobTiming(nodeAddress)
 		if found {
 			c := cron.New(cron.WithChain(cron.SkipIfStillRunning(cron.DefaultLogger)))
@@ -83,6 +99,14 @@ func startApi(ctx *cli.Context) error {
 			if err != nil {
 				return errors.New("error while starting daily cronjob: " + err.Error())
 			}
+			_, err = c.AddFunc(dailyNodeTiming, service.StoreDailyFxRates)
+			if err != nil {
+				return errors.New("error while starting fx rates cronjob: " + err.Error())
+			}
+			_, err = c.AddFunc(dailyNodeTiming, service.RevalidateVatNumbers)
+			if err != nil {
+				return errors.New("error while starting vat numbers cronjob: " + err.Error())
+			}
 			c.Start()
 		}
 
@@ -110,7 +134,7 @@ func startApi(ctx *cli.Context) error {
 		}
 	}
 
-	api, err := proxy.NewWebServer()
+	api, err := proxy.NewWebServer(repos)
 	if err != nil {
 		return errors.New("error while starting new web server: " + err.Error())
 	}
@@ -121,6 +145,53 @@ func startApi(ctx *cli.Context) error {
 	return nil
 }
 
+func encryptPii(ctx *cli.Context) error {
+	err := loadConfig(ctx)
+	if err != nil {
+		return err
+	}
+
+	storage.Connect()
+	err = storage.EncryptPiiColumns()
+	if err != nil {
+		return errors.New("error while encrypting pii columns: " + err.Error())
+	}
+
+	return nil
+}
+
+func backfillPaidAt(ctx *cli.Context) error {
+	err := loadConfig(ctx)
+	if err != nil {
+		return err
+	}
+
+	storage.Connect()
+	service.SetRepositories(storage.NewGormRepositories())
+	err = service.BackfillInvoicePaidAt()
+	if err != nil {
+		return errors.New("error while backfilling invoice payment dates: " + err.Error())
+	}
+
+	return nil
+}
+
+func loadConfig(ctx *cli.Context) error {
+	generalConfigPath := ctx.GlobalString(generalConfigFile.Name)
+	network := os.Getenv("EE_EVM_NET")
+	if network == "" {
+		return errors.New("EE_EVM_NET environment variable not set, cannot load config")
+	}
+
+	cfg, err := config.LoadConfig(generalConfigPath + "config." + network + ".json")
+	if err != nil {
+		return errors.New("error while loading configs: " + err.Error())
+	}
+
+	config.Config = *cfg
+	return nil
+}
+
 func waitForGracefulShutdown(server *http.Server) {
 	quit := make(chan os.Signal)
 	signal.Notify(quit, os.Interrupt, os.Kill)
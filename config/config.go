@@ -27,7 +27,9 @@ type GeneralConfig struct {
 	AcceptedDomains                AcceptedDomains
 	ChainID                        int
 	Oblio                          Oblio
+	Invoicing                      InvoicingConfig
 	Infura                         Infura
+	Rpc                            RpcConfig
 	DeeployApi                     string
 	OraclesApi                     string
 	NDContractAddress              string
@@ -41,6 +43,8 @@ type GeneralConfig struct {
 	DailyCronJobTiming             map[string]string
 	OfflineNodesCronJobTiming      map[string]string
 	MonthlyCronJobTiming           map[string]string
+	IndexerCronJobTiming           map[string]string
+	Indexer                        IndexerConfig
 	AdminAddresses                 []string
 	EmailTemplatesPath             string
 	BuyLimitUSD                    BuyLimitUSDConfig
@@ -49,6 +53,7 @@ type GeneralConfig struct {
 	Ratio1redirectUrl              Ratio1redirectUrl
 	FreeCurrencyApiKey             string
 	R1fsClient                     *r1fs.Client
+	Pii                            PiiConfig
 }
 
 type ApiConfig struct {
@@ -57,15 +62,24 @@ type ApiConfig struct {
 	AdminKey   string
 }
 
+const (
+	DialectPostgres    = "postgres"
+	DialectCockroachDB = "cockroachdb"
+)
+
 type DatabaseConfig struct {
-	User         string
-	Password     string
-	Host         string
-	Port         int
-	DbName       string
-	MaxOpenConns int
-	MaxIdleConns int
-	SslMode      string
+	User                      string
+	Password                  string
+	Host                      string
+	Port                      int
+	DbName                    string
+	MaxOpenConns              int
+	MaxIdleConns              int
+	SslMode                   string
+	Dialect                   string
+	MaxTxRetries              int
+	ReplicaUrls               []string
+	ReplicaHealthCheckSeconds int
 }
 
 type JwtConfig struct {
@@ -75,6 +89,8 @@ type JwtConfig struct {
 	Secret            string
 	ConfirmSecret     string
 	ConfirmExpiryMins int
+	ExportSecret      string
+	ExportExpiryMins  int
 }
 
 type MailConfig struct {
@@ -113,11 +129,56 @@ type Oblio struct {
 	ClientSecret string
 }
 
+const (
+	InvoiceProviderOblio = "oblio"
+	InvoiceProviderLocal = "local"
+)
+
+type InvoicingConfig struct {
+	// Provider issues the license invoices, oblio by default and local when
+	// DevTesting is set
+	Provider string
+	// LocalDir is where the local provider writes the invoice pdfs
+	LocalDir string
+}
+
 type Infura struct {
 	ApiUrl string
 	Secret string
 }
 
+type RpcConfig struct {
+	// endpoints tried in order until their health scores say otherwise, the
+	// infura endpoint is used when empty
+	Providers []RpcProvider
+	// consecutive failures after which a provider is skipped for CooldownSeconds
+	MaxFailures     int
+	CooldownSeconds int
+}
+
+type RpcProvider struct {
+	Name   string
+	ApiUrl string
+	// environment variable holding the key appended to ApiUrl, empty for
+	// public endpoints
+	SecretEnv         string
+	Secret            string
+	RequestsPerSecond float64
+	// plans billed in credits set the credits per second and the credits of
+	// each json-rpc method, RequestsPerSecond is ignored then
+	CreditsPerSecond float64
+	MethodCredits    map[string]float64
+}
+
+type IndexerConfig struct {
+	// blocks kept between the chain head and the indexed events
+	Confirmations int64
+	// block hashes kept per stream to find the fork point of a reorg
+	TrackedBlocks int
+	// blocks indexed before each checkpoint
+	MaxBlockRange int64
+}
+
 type BuyLimitUSDConfig struct {
 	Individual int
 	Company    int
@@ -129,6 +190,15 @@ type ViesConfig struct {
 	Password string
 }
 
+type PiiConfig struct {
+	EncryptionKeys   string
+	ActiveKeyVersion uint32
+	BlindIndexKey    string
+	// AllowPlaintext lets a DevTesting node run without keys, personal data
+	// is then stored in plaintext
+	AllowPlaintext bool
+}
+
 type Ratio1redirectUrl struct {
 	OperatorUrl string
 	CspUrl      string
@@ -138,6 +208,12 @@ func (d DatabaseConfig) Url() string {
 	format := "host=%s port=%d user=%s password=%s dbname=%s sslmode=%s"
 	return fmt.Sprintf(format, d.Host, d.Port, d.User, d.Password, d.DbName, d.SslMode)
 }
+
+// IsCockroachDB reports whether the database is CockroachDB, an empty dialect
+// means Postgres.
+func (d DatabaseConfig) IsCockroachDB() bool {
+	return strings.EqualFold(d.Dialect, DialectCockroachDB)
+}
 func LoadNodes(filePath string) (map[string]string, error) {
 	var nodes = make(map[string]string)
 	err := core.LoadJsonFile(&nodes, filePath)
@@ -178,6 +254,15 @@ func LoadConfig(filePath string) (*GeneralConfig, error) {
 	if cfg.Database.Password == "" {
 		return nil, errors.New("DATABASE_PASSWORD is not set")
 	}
+	if replicaUrls := os.Getenv("DATABASE_REPLICA_URLS"); replicaUrls != "" {
+		cfg.Database.ReplicaUrls = strings.Split(replicaUrls, ",")
+	}
+	if dialect := os.Getenv("DATABASE_DIALECT"); dialect != "" {
+		cfg.Database.Dialect = dialect
+	}
+	if cfg.Database.Dialect != "" && cfg.Database.Dialect != DialectPostgres && !cfg.Database.IsCockroachDB() {
+		return nil, errors.New("unknown DATABASE_DIALECT: " + cfg.Database.Dialect)
+	}
 
 	/*	JWT ENV VARIABLES	*/
 	cfg.Jwt.KeySeedHex = os.Getenv("JWT_KEYSEED_HEX")
@@ -192,6 +277,10 @@ func LoadConfig(filePath string) (*GeneralConfig, error) {
 	if cfg.Jwt.ConfirmSecret == "" {
 		return nil, errors.New("JWT_CONFIRM_SECRET is not set")
 	}
+	cfg.Jwt.ExportSecret = os.Getenv("JWT_EXPORT_SECRET")
+	if cfg.Jwt.ExportSecret == "" {
+		return nil, errors.New("JWT_EXPORT_SECRET is not set")
+	}
 
 	/*	MAIL ENV VARIABLES	*/
 	cfg.Mail.ApiKey = os.Getenv("MAIL_API_KEY")
@@ -219,10 +308,31 @@ func LoadConfig(filePath string) (*GeneralConfig, error) {
 		return nil, errors.New("INFURA_SECRET is not set")
 	}
 
+	/*	RPC PROVIDERS ENV VARIABLES */
+	providers := make([]RpcProvider, 0, len(cfg.Rpc.Providers))
+	for _, provider := range cfg.Rpc.Providers {
+		if provider.SecretEnv != "" {
+			provider.Secret = os.Getenv(provider.SecretEnv)
+			if provider.Secret == "" {
+				fmt.Println(provider.SecretEnv + " is not set, skipping rpc provider " + provider.Name)
+				continue
+			}
+		}
+		providers = append(providers, provider)
+	}
+	cfg.Rpc.Providers = providers
+
+	if cfg.Invoicing.Provider == "" {
+		cfg.Invoicing.Provider = InvoiceProviderOblio
+		if cfg.Api.DevTesting {
+			cfg.Invoicing.Provider = InvoiceProviderLocal
+		}
+	}
+
 	if !cfg.Api.DevTesting {
 		/*	OBLIO ENV VARIABLES	*/
 		cfg.Oblio.ClientSecret = os.Getenv("OBLIO_CLIENT_SECRET")
-		if cfg.Oblio.ClientSecret == "" {
+		if cfg.Oblio.ClientSecret == "" && cfg.Invoicing.Provider == InvoiceProviderOblio {
 			return nil, errors.New("OBLIO_CLIENT_SECRET is not set")
 		}
 
@@ -262,6 +372,24 @@ func LoadConfig(filePath string) (*GeneralConfig, error) {
 		}
 	}
 
+	/* PII ENCRYPTION ENV VARIABLES */
+	cfg.Pii.EncryptionKeys = os.Getenv("PII_ENCRYPTION_KEYS")
+	cfg.Pii.AllowPlaintext = cfg.Api.DevTesting && os.Getenv("PII_ALLOW_PLAINTEXT") == "true"
+	if cfg.Pii.EncryptionKeys == "" && !cfg.Pii.AllowPlaintext {
+		return nil, errors.New("PII_ENCRYPTION_KEYS is not set")
+	}
+	if cfg.Pii.EncryptionKeys != "" {
+		activeKeyVersion, err := strconv.ParseUint(os.Getenv("PII_ACTIVE_KEY_VERSION"), 10, 32)
+		if err != nil {
+			return nil, errors.New("PII_ACTIVE_KEY_VERSION return error: " + err.Error())
+		}
+		cfg.Pii.ActiveKeyVersion = uint32(activeKeyVersion)
+		cfg.Pii.BlindIndexKey = os.Getenv("PII_BLIND_INDEX_KEY")
+		if cfg.Pii.BlindIndexKey == "" {
+			return nil, errors.New("PII_BLIND_INDEX_KEY is not set")
+		}
+	}
+
 	/* GENERAL ENV VARIABLES */
 	cfg.EmailTemplatesPath = os.Getenv("EMAIL_TEMPLATES_PATH")
 	if cfg.EmailTemplatesPath == "" {
@@ -299,3 +427,8 @@ func (c *GeneralConfig) GetOfflineNodesCronJobTiming(nodeAddress string) (string
 	nodeTiming, found := c.OfflineNodesCronJobTiming[nodeAddress]
 	return nodeTiming, found
 }
+
+func (c *GeneralConfig) GetIndexerCronJobTiming(nodeAddress string) (string, bool) {
+	nodeTiming, found := c.IndexerCronJobTiming[nodeAddress]
+	return nodeTiming, found
+}
@@ -18,6 +18,11 @@ type MailConfirmClaims struct {
 	jwt.RegisteredClaims
 }
 
+type ExportClaims struct {
+	ExportId string
+	jwt.RegisteredClaims
+}
+
 var isExpired = func(claims JwtClaims) bool {
 	return claims.ExpiresAt.Unix() < time.Now().Unix()
 }
@@ -48,6 +53,18 @@ func GenerateConfirmJwt(address, email, secret, issuer string, minsToExpiration
 	return payload.SignedString([]byte(secret))
 }
 
+func GenerateExportJwt(exportId, secret, issuer string, minsToExpiration int) (string, error) {
+	claims := ExportClaims{
+		ExportId: exportId,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Minute * time.Duration(minsToExpiration))),
+			Issuer:    issuer,
+		},
+	}
+	payload := jwt.NewWithClaims(jwt.SigningMethodHS256, &claims)
+	return payload.SignedString([]byte(secret))
+}
+
 func ValidateJwt(signedToken, secret string) (JwtClaims, error) {
 	claims, err := parseToken(signedToken, secret)
 	if err != nil {
@@ -83,6 +100,24 @@ func ValidateConfirmJwt(signedToken, secret string) (MailConfirmClaims, error) {
 	return *claims, nil
 }
 
+func ValidateExportJwt(signedToken, secret string) (ExportClaims, error) {
+	token, err := jwt.ParseWithClaims(
+		signedToken,
+		&ExportClaims{},
+		func(token *jwt.Token) (interface{}, error) {
+			return []byte(secret), nil
+		},
+	)
+	if err != nil {
+		return ExportClaims{}, err
+	}
+	claims, ok := token.Claims.(*ExportClaims)
+	if !ok || claims.ExpiresAt == nil {
+		return ExportClaims{}, ErrJwtParse
+	}
+	return *claims, nil
+}
+
 func GetClaims(signedToken, secret string, verify bool) (JwtClaims, error) {
 	var claims *JwtClaims
 	var err error
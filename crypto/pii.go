@@ -0,0 +1,232 @@
+package crypto
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"strconv"
+	"strings"
+
+	"github.com/NaeuralEdgeProtocol/ratio1-backend/config"
+)
+
+const (
+	piiPrefix     = "enc:v1:"
+	piiKeyLength  = 32
+	piiPartsCount = 3
+)
+
+var ErrPiiUnknownKeyVersion = errors.New("unknown pii key version")
+
+var ErrPiiMalformedCiphertext = errors.New("malformed pii ciphertext")
+
+var ErrPiiKeysNotConfigured = errors.New("no pii encryption key configured")
+
+// PiiKeyring encrypts personal data with envelope encryption: every value gets
+// its own data key, which is wrapped by the active key encryption key. Older
+// key versions are kept to decrypt values written before a rotation.
+type PiiKeyring struct {
+	keys          map[uint32][]byte
+	activeVersion uint32
+	indexKey      []byte
+}
+
+// LoadPiiKeyring builds the keyring from the configured secrets. Without keys
+// personal data would be written in plaintext, the nil keyring is only
+// returned when that is explicitly allowed.
+func LoadPiiKeyring(cfg config.PiiConfig) (*PiiKeyring, error) {
+	if cfg.EncryptionKeys == "" {
+		if !cfg.AllowPlaintext {
+			return nil, ErrPiiKeysNotConfigured
+		}
+		return nil, nil
+	}
+
+	keys := make(map[uint32][]byte)
+	for _, entry := range strings.Split(cfg.EncryptionKeys, ",") {
+		versionString, encodedKey, found := strings.Cut(strings.TrimSpace(entry), ":")
+		if !found {
+			return nil, errors.New("pii key entry must be in the form <version>:<base64 key>")
+		}
+		version, err := strconv.ParseUint(versionString, 10, 32)
+		if err != nil {
+			return nil, errors.New("invalid pii key version " + versionString + ": " + err.Error())
+		}
+		key, err := base64.StdEncoding.DecodeString(encodedKey)
+		if err != nil {
+			return nil, errors.New("invalid pii key " + versionString + ": " + err.Error())
+		}
+		keys[uint32(version)] = key
+	}
+
+	indexKey, err := base64.StdEncoding.DecodeString(cfg.BlindIndexKey)
+	if err != nil {
+		return nil, errors.New("invalid pii blind index key: " + err.Error())
+	}
+
+	return NewPiiKeyring(keys, cfg.ActiveKeyVersion, indexKey)
+}
+
+func NewPiiKeyring(keys map[uint32][]byte, activeVersion uint32, indexKey []byte) (*PiiKeyring, error) {
+	for version, key := range keys {
+		if len(key) != piiKeyLength {
+			return nil, errors.New("pii key " + strconv.FormatUint(uint64(version), 10) + " must be 32 bytes long")
+		}
+	}
+	if _, ok := keys[activeVersion]; !ok {
+		return nil, ErrPiiUnknownKeyVersion
+	}
+	if len(indexKey) < piiKeyLength {
+		return nil, errors.New("pii blind index key must be at least 32 bytes long")
+	}
+
+	return &PiiKeyring{
+		keys:          keys,
+		activeVersion: activeVersion,
+		indexKey:      indexKey,
+	}, nil
+}
+
+func (k *PiiKeyring) ActiveVersion() uint32 {
+	return k.activeVersion
+}
+
+// Encrypt returns the value as enc:v1:<key version>:<wrapped data key>:<ciphertext>.
+// Empty values are kept empty so that emptiness checks keep working.
+func (k *PiiKeyring) Encrypt(plaintext string) (string, error) {
+	if plaintext == "" {
+		return "", nil
+	}
+
+	dataKey := make([]byte, piiKeyLength)
+	if _, err := rand.Read(dataKey); err != nil {
+		return "", err
+	}
+
+	version := strconv.FormatUint(uint64(k.activeVersion), 10)
+	wrappedKey, err := sealAesGcm(k.keys[k.activeVersion], dataKey, []byte(version))
+	if err != nil {
+		return "", err
+	}
+	ciphertext, err := sealAesGcm(dataKey, []byte(plaintext), []byte(version))
+	if err != nil {
+		return "", err
+	}
+
+	return piiPrefix + version + ":" +
+		base64.RawStdEncoding.EncodeToString(wrappedKey) + ":" +
+		base64.RawStdEncoding.EncodeToString(ciphertext), nil
+}
+
+// Decrypt reverses Encrypt. Values without the encryption prefix are returned
+// unchanged, they are rows written before encryption was enabled.
+func (k *PiiKeyring) Decrypt(value string) (string, error) {
+	if !IsPiiEncrypted(value) {
+		return value, nil
+	}
+
+	parts := strings.Split(strings.TrimPrefix(value, piiPrefix), ":")
+	if len(parts) != piiPartsCount {
+		return "", ErrPiiMalformedCiphertext
+	}
+	version, err := strconv.ParseUint(parts[0], 10, 32)
+	if err != nil {
+		return "", ErrPiiMalformedCiphertext
+	}
+	key, ok := k.keys[uint32(version)]
+	if !ok {
+		return "", ErrPiiUnknownKeyVersion
+	}
+	wrappedKey, err := base64.RawStdEncoding.DecodeString(parts[1])
+	if err != nil {
+		return "", ErrPiiMalformedCiphertext
+	}
+	ciphertext, err := base64.RawStdEncoding.DecodeString(parts[2])
+	if err != nil {
+		return "", ErrPiiMalformedCiphertext
+	}
+
+	dataKey, err := openAesGcm(key, wrappedKey, []byte(parts[0]))
+	if err != nil {
+		return "", errors.New("error while unwrapping pii data key: " + err.Error())
+	}
+	plaintext, err := openAesGcm(dataKey, ciphertext, []byte(parts[0]))
+	if err != nil {
+		return "", errors.New("error while decrypting pii value: " + err.Error())
+	}
+
+	return string(plaintext), nil
+}
+
+// NeedsReencryption reports whether value is plaintext or was encrypted with a
+// key other than the active one.
+func (k *PiiKeyring) NeedsReencryption(value string) bool {
+	if value == "" {
+		return false
+	}
+	if !IsPiiEncrypted(value) {
+		return true
+	}
+	return !strings.HasPrefix(value, piiPrefix+strconv.FormatUint(uint64(k.activeVersion), 10)+":")
+}
+
+// BlindIndex returns a keyed hash of the normalized value, it allows equality
+// lookups on encrypted columns without decrypting them.
+func (k *PiiKeyring) BlindIndex(value string) string {
+	return blindIndex(k.indexKey, value)
+}
+
+// UnkeyedBlindIndex is the blind index used when no keyring is configured, as
+// in local development.
+func UnkeyedBlindIndex(value string) string {
+	return blindIndex(nil, value)
+}
+
+func IsPiiEncrypted(value string) bool {
+	return strings.HasPrefix(value, piiPrefix)
+}
+
+func blindIndex(key []byte, value string) string {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(strings.ToLower(strings.TrimSpace(value))))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func sealAesGcm(key, plaintext, additionalData []byte) ([]byte, error) {
+	gcm, err := newAesGcm(key)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return gcm.Seal(nonce, nonce, plaintext, additionalData), nil
+}
+
+func openAesGcm(key, sealed, additionalData []byte) ([]byte, error) {
+	gcm, err := newAesGcm(key)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(sealed) < gcm.NonceSize() {
+		return nil, ErrPiiMalformedCiphertext
+	}
+	nonce, ciphertext := sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():]
+	return gcm.Open(nil, nonce, ciphertext, additionalData)
+}
+
+func newAesGcm(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
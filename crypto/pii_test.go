@@ -0,0 +1,142 @@
+package crypto
+
+import (
+	"bytes"
+	"encoding/base64"
+	"strings"
+	"testing"
+
+	"github.com/NaeuralEdgeProtocol/ratio1-backend/config"
+	"github.com/stretchr/testify/require"
+)
+
+func testPiiKeyring(t *testing.T, activeVersion uint32) *PiiKeyring {
+	keyring, err := NewPiiKeyring(map[uint32][]byte{
+		1: bytes.Repeat([]byte{1}, piiKeyLength),
+		2: bytes.Repeat([]byte{2}, piiKeyLength),
+	}, activeVersion, bytes.Repeat([]byte{3}, piiKeyLength))
+	require.Nil(t, err)
+	return keyring
+}
+
+func TestPiiEncryptDecrypt(t *testing.T) {
+	keyring := testPiiKeyring(t, 1)
+
+	encrypted, err := keyring.Encrypt("Mario Rossi")
+	require.Nil(t, err)
+	require.True(t, IsPiiEncrypted(encrypted))
+	require.NotContains(t, encrypted, "Mario")
+
+	other, err := keyring.Encrypt("Mario Rossi")
+	require.Nil(t, err)
+	require.NotEqual(t, encrypted, other)
+
+	decrypted, err := keyring.Decrypt(encrypted)
+	require.Nil(t, err)
+	require.Equal(t, "Mario Rossi", decrypted)
+
+	empty, err := keyring.Encrypt("")
+	require.Nil(t, err)
+	require.Equal(t, "", empty)
+}
+
+func TestPiiDecryptPlaintext(t *testing.T) {
+	keyring := testPiiKeyring(t, 1)
+
+	decrypted, err := keyring.Decrypt("legacy@example.com")
+	require.Nil(t, err)
+	require.Equal(t, "legacy@example.com", decrypted)
+	require.True(t, keyring.NeedsReencryption("legacy@example.com"))
+	require.False(t, keyring.NeedsReencryption(""))
+}
+
+func TestPiiKeyRotation(t *testing.T) {
+	oldKeyring := testPiiKeyring(t, 1)
+	encrypted, err := oldKeyring.Encrypt("RO123456")
+	require.Nil(t, err)
+	require.False(t, oldKeyring.NeedsReencryption(encrypted))
+
+	rotated := testPiiKeyring(t, 2)
+	require.True(t, rotated.NeedsReencryption(encrypted))
+	decrypted, err := rotated.Decrypt(encrypted)
+	require.Nil(t, err)
+	require.Equal(t, "RO123456", decrypted)
+
+	reencrypted, err := rotated.Encrypt(decrypted)
+	require.Nil(t, err)
+	require.True(t, strings.HasPrefix(reencrypted, piiPrefix+"2:"))
+	require.False(t, rotated.NeedsReencryption(reencrypted))
+
+	withoutOldKey, err := NewPiiKeyring(map[uint32][]byte{
+		2: bytes.Repeat([]byte{2}, piiKeyLength),
+	}, 2, bytes.Repeat([]byte{3}, piiKeyLength))
+	require.Nil(t, err)
+	_, err = withoutOldKey.Decrypt(encrypted)
+	require.Equal(t, ErrPiiUnknownKeyVersion, err)
+}
+
+func TestPiiDecryptTampered(t *testing.T) {
+	keyring := testPiiKeyring(t, 1)
+	encrypted, err := keyring.Encrypt("Via Roma 1")
+	require.Nil(t, err)
+
+	parts := strings.Split(strings.TrimPrefix(encrypted, piiPrefix), ":")
+	ciphertext, err := base64.RawStdEncoding.DecodeString(parts[2])
+	require.Nil(t, err)
+	ciphertext[len(ciphertext)-1] ^= 0xff
+	tampered := piiPrefix + parts[0] + ":" + parts[1] + ":" + base64.RawStdEncoding.EncodeToString(ciphertext)
+
+	_, err = keyring.Decrypt(tampered)
+	require.NotNil(t, err)
+
+	// the key version is authenticated, so it cannot be swapped
+	swapped := piiPrefix + "2:" + parts[1] + ":" + parts[2]
+	_, err = keyring.Decrypt(swapped)
+	require.NotNil(t, err)
+
+	_, err = keyring.Decrypt(piiPrefix + "1:abc")
+	require.Equal(t, ErrPiiMalformedCiphertext, err)
+}
+
+func TestPiiBlindIndex(t *testing.T) {
+	keyring := testPiiKeyring(t, 1)
+
+	require.Equal(t, keyring.BlindIndex("user@example.com"), keyring.BlindIndex(" User@Example.com "))
+	require.NotEqual(t, keyring.BlindIndex("user@example.com"), keyring.BlindIndex("other@example.com"))
+	require.NotEqual(t, keyring.BlindIndex("user@example.com"), UnkeyedBlindIndex("user@example.com"))
+	require.Len(t, keyring.BlindIndex("user@example.com"), 64)
+}
+
+func TestLoadPiiKeyring(t *testing.T) {
+	_, err := LoadPiiKeyring(config.PiiConfig{})
+	require.Equal(t, ErrPiiKeysNotConfigured, err)
+	keyring, err := LoadPiiKeyring(config.PiiConfig{AllowPlaintext: true})
+	require.Nil(t, err)
+	require.Nil(t, keyring)
+
+	key1 := base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{1}, piiKeyLength))
+	key2 := base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{2}, piiKeyLength))
+	indexKey := base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{3}, piiKeyLength))
+
+	keyring, err = LoadPiiKeyring(config.PiiConfig{
+		EncryptionKeys:   "1:" + key1 + ", 2:" + key2,
+		ActiveKeyVersion: 2,
+		BlindIndexKey:    indexKey,
+	})
+	require.Nil(t, err)
+	require.Equal(t, uint32(2), keyring.ActiveVersion())
+
+	_, err = LoadPiiKeyring(config.PiiConfig{
+		EncryptionKeys:   "1:" + key1,
+		ActiveKeyVersion: 3,
+		BlindIndexKey:    indexKey,
+	})
+	require.Equal(t, ErrPiiUnknownKeyVersion, err)
+
+	_, err = LoadPiiKeyring(config.PiiConfig{
+		EncryptionKeys:   key1,
+		ActiveKeyVersion: 1,
+		BlindIndexKey:    indexKey,
+	})
+	require.NotNil(t, err)
+}
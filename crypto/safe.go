@@ -7,12 +7,11 @@ import (
 	"fmt"
 	"strings"
 
-	"github.com/NaeuralEdgeProtocol/ratio1-backend/config"
+	"github.com/NaeuralEdgeProtocol/ratio1-backend/rpc"
 	"github.com/ethereum/go-ethereum"
 	"github.com/ethereum/go-ethereum/accounts/abi"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/crypto"
-	"github.com/ethereum/go-ethereum/ethclient"
 )
 
 func VerifySafeSignature(safeAddress string, message string, signature string) error {
@@ -54,18 +53,16 @@ func VerifySafeSignature(safeAddress string, message string, signature string) e
 		return err
 	}
 
-	client, err := ethclient.Dial(config.Config.Infura.ApiUrl + config.Config.Infura.Secret)
-	if err != nil {
-		return err
-	}
-	defer client.Close()
+	client := rpc.Get()
 
 	msg := ethereum.CallMsg{
 		To:   &safeAddr,
 		Data: input,
 	}
 
-	output, err := client.CallContract(context.Background(), msg, nil)
+	// users wait on this call while signing in, it goes before the cron jobs
+	ctx := rpc.WithPriority(context.Background(), rpc.PriorityUser)
+	output, err := client.CallContract(ctx, msg, nil)
 	if err != nil {
 		return err
 	}
@@ -34,6 +34,7 @@ if __name__ == "__main__":
     'JWT_KEYSEED_HEX':'',
     'JWT_SECRET':'',
     'JWT_CONFIRM_SECRET':'',
+    'JWT_EXPORT_SECRET':'',
 
     'MAIL_API_KEY':'',
     
@@ -41,6 +42,10 @@ if __name__ == "__main__":
     'SUMSUB_SECRET_KEY':'',
     'SUMSUB_JWT_SECRET_KEY':'',
 
+    'PII_ENCRYPTION_KEYS':'',
+    'PII_ACTIVE_KEY_VERSION':'',
+    'PII_BLIND_INDEX_KEY':'',
+
     'MAILERLITE_GROUP_ID':'',
     'MAILERLITE_API_KEY':'',
 
@@ -48,6 +53,7 @@ if __name__ == "__main__":
     'OBLIO_EVENT_SIGNATURE': 'LicensesCreated(address,bytes32,uint256,uint256,uint256)',
 
     'INFURA_SECRET':'',
+    'ALCHEMY_SECRET':'',
 
     'EMAIL_TEMPLATES_PATH':'./templates/html/',
     'EE_CHAINSTORE_API_URL':'',
@@ -5,16 +5,17 @@ import (
 )
 
 type Account struct {
-	Address               string    `gorm:"primarykey;length:42;" json:"address"`
-	CreatedAt             time.Time `json:"createdAt"`
-	UpdatedAt             time.Time `json:"updatedAt"`
-	Email                 *string   `gorm:"unique;default:null" json:"email"`
-	EmailConfirmed        bool      `json:"emailConfirmed"`
-	PendingEmail          string    `gorm:"default:null" json:"pendingEmail"`
-	PendingReceiveUpdates bool      `gorm:"not null;default:false" json:"pendingReceiveUpdates"`
-	IsBlacklisted         bool      `gorm:"not null;default:false" json:"isBlacklisted"`
-	BlacklistedReason     *string   `gorm:"default:null" json:"blacklistedReason"`
-	UsedSellerCode        *string   `gorm:"default:null" json:"usedSellerCode"`
+	Address               string     `gorm:"primarykey;length:42;" json:"address"`
+	CreatedAt             time.Time  `json:"createdAt"`
+	UpdatedAt             time.Time  `json:"updatedAt"`
+	Email                 *string    `gorm:"unique;default:null" json:"email"`
+	EmailConfirmed        bool       `json:"emailConfirmed"`
+	PendingEmail          string     `gorm:"default:null" json:"pendingEmail"`
+	PendingReceiveUpdates bool       `gorm:"not null;default:false" json:"pendingReceiveUpdates"`
+	IsBlacklisted         bool       `gorm:"not null;default:false" json:"isBlacklisted"`
+	BlacklistedReason     *string    `gorm:"default:null" json:"blacklistedReason"`
+	UsedSellerCode        *string    `gorm:"default:null" json:"usedSellerCode"`
+	ErasedAt              *time.Time `gorm:"default:null" json:"erasedAt"`
 }
 
 type AccountNotificationEmail struct {
@@ -0,0 +1,30 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	AccountErasureStatusPending   = "pending"
+	AccountErasureStatusApproved  = "approved"
+	AccountErasureStatusRejected  = "rejected"
+	AccountErasureStatusCompleted = "completed"
+)
+
+// AccountErasureRequest is a user request, signed with the wallet, to erase
+// the personal data stored for an address. It is executed once an admin
+// approves it.
+type AccountErasureRequest struct {
+	Id          uuid.UUID  `gorm:"type:uuid;primaryKey" json:"id"`
+	Address     string     `gorm:"type:varchar(66);not null;index" json:"address"`
+	Status      string     `gorm:"type:varchar(16);not null;index" json:"status"`
+	Message     string     `gorm:"type:text;not null" json:"message"`
+	Signature   string     `gorm:"type:text;not null" json:"signature"`
+	CreatedAt   time.Time  `json:"createdAt"`
+	ReviewedBy  *string    `gorm:"type:varchar(66);default:null" json:"reviewedBy"`
+	ReviewedAt  *time.Time `gorm:"default:null" json:"reviewedAt"`
+	ReviewNote  *string    `gorm:"type:text;default:null" json:"reviewNote"`
+	CompletedAt *time.Time `gorm:"default:null" json:"completedAt"`
+}
@@ -0,0 +1,26 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	AccountExportStatusPending = "pending"
+	AccountExportStatusReady   = "ready"
+	AccountExportStatusFailed  = "failed"
+)
+
+// AccountExport is a data subject access export of everything stored for an
+// address. Archive holds the base64 encoded ZIP and is encrypted like the other
+// personal data columns.
+type AccountExport struct {
+	Id          uuid.UUID  `gorm:"type:uuid;primaryKey" json:"id"`
+	Address     string     `gorm:"type:varchar(66);not null;index" json:"-"`
+	Status      string     `gorm:"type:varchar(16);not null" json:"status"`
+	Archive     string     `gorm:"type:text;serializer:pii" json:"-"`
+	CreatedAt   time.Time  `json:"createdAt"`
+	CompletedAt *time.Time `gorm:"default:null" json:"completedAt"`
+	ExpiresAt   time.Time  `gorm:"not null;index" json:"expiresAt"`
+}
@@ -0,0 +1,23 @@
+package model
+
+import "time"
+
+const (
+	AuditActionAccountErasureRequested = "account_erasure_requested"
+	AuditActionAccountErasureRejected  = "account_erasure_rejected"
+	AuditActionAccountErased           = "account_erased"
+	AuditActionLicensePurchaseRetried  = "license_purchase_retried"
+	AuditActionInvoiceCancelled        = "invoice_cancelled"
+	AuditActionInvoiceCredited         = "invoice_credited"
+)
+
+// AuditLog records an action on an account, Actor is the address that
+// performed it and Subject the address it was performed on.
+type AuditLog struct {
+	Id        uint      `gorm:"primaryKey;autoIncrement" json:"id"`
+	Action    string    `gorm:"type:varchar(64);not null;index" json:"action"`
+	Actor     string    `gorm:"type:varchar(66);not null" json:"actor"`
+	Subject   string    `gorm:"type:varchar(66);not null;index" json:"subject"`
+	Details   *string   `gorm:"type:text;default:null" json:"details"`
+	CreatedAt time.Time `json:"createdAt"`
+}
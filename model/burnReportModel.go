@@ -9,7 +9,8 @@ type BurnEvent struct {
 	Id            uint      `gorm:"primaryKey;autoIncrement" json:"id"`
 	BurnTimestamp time.Time `json:"burnTimestamp"`
 	BlockNumber   int64     `gorm:"type:bigint;not null" json:"blockNumber"`
-	TxHash        string    `gorm:"type:varchar(66);not null" json:"txHash"`
+	TxHash        string    `gorm:"type:varchar(66);not null;uniqueIndex:idx_burn_event_tx_log,priority:1,where:log_index IS NOT NULL" json:"txHash"`
+	LogIndex      *uint     `gorm:"type:bigint;uniqueIndex:idx_burn_event_tx_log,priority:2,where:log_index IS NOT NULL" json:"logIndex"`
 
 	CspAddress        string  `gorm:"type:varchar(66);not null;index" json:"cspAddress"`
 	CspOwner          string  `gorm:"type:varchar(66);not null" json:"cspOwner"`
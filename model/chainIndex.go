@@ -0,0 +1,111 @@
+package model
+
+import (
+	"math/big"
+	"time"
+)
+
+const (
+	IndexerStreamAllocations      = "rewards_allocated_v3"
+	IndexerStreamBurns            = "tokens_burned"
+	IndexerStreamLicensePurchases = "licenses_created"
+	IndexerStreamTokenTransfers   = "erc20_transfer"
+)
+
+const (
+	LicensePurchaseStatusPending    = "pending"
+	LicensePurchaseStatusCompleted  = "completed"
+	LicensePurchaseStatusDeadLetter = "dead_letter"
+)
+
+// IndexerCheckpoint is the last block whose events of a stream are stored.
+type IndexerCheckpoint struct {
+	Stream      string    `gorm:"type:varchar(64);primaryKey" json:"stream"`
+	BlockNumber int64     `gorm:"type:bigint;not null" json:"blockNumber"`
+	BlockHash   string    `gorm:"type:varchar(66);not null" json:"blockHash"`
+	UpdatedAt   time.Time `json:"updatedAt"`
+}
+
+// IndexedBlock is the hash of a block a stream checkpoint moved to, the last
+// ones of each stream are kept to find where the chain forked on a reorg.
+type IndexedBlock struct {
+	Stream      string `gorm:"type:varchar(64);primaryKey" json:"stream"`
+	BlockNumber int64  `gorm:"type:bigint;primaryKey;autoIncrement:false" json:"blockNumber"`
+	BlockHash   string `gorm:"type:varchar(66);not null" json:"blockHash"`
+}
+
+// Block is a cached block header, filled by the indexer and by the block time
+// lookups.
+type Block struct {
+	Number    int64     `gorm:"type:bigint;primaryKey;autoIncrement:false" json:"number"`
+	Hash      string    `gorm:"type:varchar(66);not null" json:"hash"`
+	Timestamp time.Time `gorm:"not null;index" json:"timestamp"`
+}
+
+// LicensePurchaseEvent is a decoded LicensesCreated event of the ND contract.
+// Events are stored by the indexer first, the invoice of the purchase is then
+// generated by a worker retrying with backoff until it succeeds or the event
+// is moved to the dead letter status for an admin to look at.
+type LicensePurchaseEvent struct {
+	Id            uint       `gorm:"primaryKey;autoIncrement" json:"id"`
+	BlockNumber   int64      `gorm:"type:bigint;not null;index" json:"blockNumber"`
+	TxHash        string     `gorm:"type:varchar(66);not null;uniqueIndex:idx_license_purchase_tx_log,priority:1" json:"txHash"`
+	LogIndex      uint       `gorm:"type:bigint;not null;uniqueIndex:idx_license_purchase_tx_log,priority:2" json:"logIndex"`
+	Address       string     `gorm:"type:varchar(66);not null" json:"address"`
+	InvoiceID     string     `gorm:"type:text;not null" json:"invoiceID"`
+	NumLicenses   int        `gorm:"type:integer" json:"numLicenses"`
+	UnitUsdPrice  int        `gorm:"type:integer" json:"unitUsdPrice"`
+	TokenPaid     float64    `gorm:"type:numeric" json:"tokenPaid"`
+	Status        string     `gorm:"type:varchar(16);not null;default:pending;index" json:"status"`
+	Attempts      int        `gorm:"type:integer;not null;default:0" json:"attempts"`
+	NextAttemptAt *time.Time `gorm:"default:null;index" json:"nextAttemptAt"`
+	LastError     *string    `gorm:"type:text;default:null" json:"lastError"`
+	// the invoice is kept as soon as it is issued, so that a retry never
+	// issues it twice
+	InvoiceSeries *string    `gorm:"default:null" json:"invoiceSeries"`
+	InvoiceNumber *string    `gorm:"default:null" json:"invoiceNumber"`
+	InvoiceUrl    *string    `gorm:"default:null" json:"invoiceUrl"`
+	ProcessedAt   *time.Time `gorm:"default:null" json:"processedAt"`
+	CreatedAt     time.Time  `json:"createdAt"`
+	UpdatedAt     time.Time  `json:"updatedAt"`
+}
+
+func (l *LicensePurchaseEvent) Event() Event {
+	return Event{
+		Address:      l.Address,
+		InvoiceID:    l.InvoiceID,
+		NumLicenses:  l.NumLicenses,
+		UnitUsdPrice: l.UnitUsdPrice,
+		TokenPaid:    l.TokenPaid,
+		TxHash:       l.TxHash,
+		BlockNumber:  l.BlockNumber,
+	}
+}
+
+// TokenTransfer is a decoded ERC20 Transfer event of the R1 token. Only mints
+// and burns are indexed.
+type TokenTransfer struct {
+	Id          uint   `gorm:"primaryKey;autoIncrement" json:"id"`
+	BlockNumber int64  `gorm:"type:bigint;not null;index" json:"blockNumber"`
+	TxHash      string `gorm:"type:varchar(66);not null;uniqueIndex:idx_token_transfer_tx_log,priority:1" json:"txHash"`
+	LogIndex    uint   `gorm:"type:bigint;not null;uniqueIndex:idx_token_transfer_tx_log,priority:2" json:"logIndex"`
+	From        string `gorm:"type:varchar(66);not null" json:"from"`
+	To          string `gorm:"type:varchar(66);not null" json:"to"`
+	Amount      string `gorm:"type:numeric;not null" json:"amount"`
+}
+
+func (t *TokenTransfer) GetAmount() *big.Int {
+	amount, ok := new(big.Int).SetString(t.Amount, 10)
+	if !ok {
+		return big.NewInt(0)
+	}
+	return amount
+}
+
+func (t *TokenTransfer) SetAmount(amount *big.Int) {
+	if amount == nil {
+		t.Amount = "0"
+	} else {
+		t.Amount = amount.String()
+	}
+}
@@ -0,0 +1,13 @@
+package model
+
+import "time"
+
+// FxRate is the reference rate of a currency on a day, expressed as units of
+// the currency for 1 USD. Date is the day the source published the rate.
+type FxRate struct {
+	Date      time.Time `gorm:"type:date;primaryKey" json:"date"`
+	Currency  string    `gorm:"type:varchar(3);primaryKey" json:"currency"`
+	Rate      float64   `gorm:"not null" json:"rate"`
+	Source    string    `gorm:"type:varchar(32);not null" json:"source"`
+	CreatedAt time.Time `json:"createdAt"`
+}
@@ -1,7 +1,11 @@
 package model
 
+import "time"
+
 const InvoiceStatusPending = "pending"
 const InvoiceStatusPaid = "paid"
+const InvoiceStatusCancelled = "cancelled"
+const InvoiceStatusRefunded = "refunded"
 
 const (
 	InvoiceCif           = "50252500"
@@ -12,27 +16,57 @@ const (
 
 type InvoiceClient struct {
 	Uuid               *string `gorm:"primarykey;"`
-	Name               *string `gorm:"default:null" json:"name"`
-	Surname            *string `gorm:"default:null" json:"surname"`
-	CompanyName        *string `gorm:"default:null" json:"companyName"`
-	UserEmail          *string `gorm:"not null"`
-	IdentificationCode string  `gorm:"not null" json:"identificationCode"`
-	Address            string  `json:"address"`
+	BlockchainAddress  string  `gorm:"type:varchar(66);index;default:null" json:"blockchainAddress"`
+	Name               *string `gorm:"default:null;serializer:pii" json:"name"`
+	Surname            *string `gorm:"default:null;serializer:pii" json:"surname"`
+	CompanyName        *string `gorm:"default:null;serializer:pii" json:"companyName"`
+	UserEmail          *string `gorm:"not null;serializer:pii"`
+	IdentificationCode string  `gorm:"not null;serializer:pii" json:"identificationCode"`
+	Address            string  `gorm:"serializer:pii" json:"address"`
 	State              string  `json:"state"`
 	City               string  `json:"city"`
 	Country            string  `json:"country"`
 	IsCompany          bool    `json:"isCompany"`
 	Status             *string `gorm:"not null" `
 	InvoiceUrl         *string `gorm:"default:null"`
+	InvoiceSeries      *string `gorm:"default:null"`
 	InvoiceNumber      *string `gorm:"default:null"`
 	TxHash             *string `gorm:"default:null"`
 	BlockNumber        *int64  `gorm:"default:null"`
 	ReverseCharge      bool
 	IsUe               bool
-	NumLicenses        *int `gorm:"default:null"`
-	UnitUsdPrice       *int `gorm:"default:null"`
+	NumLicenses        *int       `gorm:"default:null"`
+	UnitUsdPrice       *int       `gorm:"default:null"`
+	VatRate            *float64   `gorm:"type:numeric;default:null"`
+	VatScheme          *string    `gorm:"type:varchar(16);default:null"`
+	ViesCheckId        *uint      `gorm:"default:null"`
+	PaidAt             *time.Time `gorm:"default:null;index"`
+}
+
+// InvoiceStatusChange is an entry of the status history of an invoice, with
+// the provider document the status refers to and the vat it was issued with.
+// Actor is the admin address, or system for the automatic transitions.
+type InvoiceStatusChange struct {
+	Id            uint      `gorm:"primaryKey;autoIncrement" json:"id"`
+	InvoiceUuid   string    `gorm:"type:text;not null;index" json:"invoiceUuid"`
+	Status        string    `gorm:"type:varchar(16);not null" json:"status"`
+	DocumentType  string    `gorm:"type:varchar(16);not null;index" json:"documentType"`
+	InvoiceSeries *string   `gorm:"default:null" json:"invoiceSeries"`
+	InvoiceNumber *string   `gorm:"default:null" json:"invoiceNumber"`
+	InvoiceUrl    *string   `gorm:"default:null" json:"invoiceUrl"`
+	Country       string    `json:"country"`
+	VatRate       *float64  `gorm:"type:numeric;default:null" json:"vatRate"`
+	VatScheme     *string   `gorm:"type:varchar(16);default:null" json:"vatScheme"`
+	Actor         string    `gorm:"type:varchar(66);not null" json:"actor"`
+	Reason        *string   `gorm:"type:text;default:null" json:"reason"`
+	CreatedAt     time.Time `gorm:"index" json:"createdAt"`
 }
 
+const (
+	InvoiceDocumentInvoice    = "invoice"
+	InvoiceDocumentCreditNote = "credit_note"
+)
+
 type AuthRequest struct {
 	AccessToken string `json:"access_token"`
 	ExpiresIn   any    `json:"expires_in"`
@@ -51,7 +85,24 @@ type InvoiceRequest struct {
 	Product    []InvoiceProduct   `json:"products"`
 	SendEmail  int                `json:"sendEmail,omitempty"`
 	Mentions   string             `json:"mentions,omitempty"`
-	Collect    InvoiceCollect     `json:"collect,omitempty"`
+	Collect    *InvoiceCollect    `json:"collect,omitempty"`
+	// ReferenceDocument makes the request a credit note of the referenced
+	// invoice
+	ReferenceDocument *InvoiceReferenceDocument `json:"referenceDocument,omitempty"`
+}
+
+type InvoiceReferenceDocument struct {
+	Type       string `json:"type"`
+	SeriesName string `json:"seriesName"`
+	Number     string `json:"number"`
+	Refund     int    `json:"refund"`
+}
+
+// OblioDocumentRequest identifies an issued invoice in the oblio api.
+type OblioDocumentRequest struct {
+	CIF        string `json:"cif"`
+	SeriesName string `json:"seriesName"`
+	Number     string `json:"number"`
 }
 
 type OblioInvoiceClient struct {
@@ -0,0 +1,29 @@
+package model
+
+import "time"
+
+// InvoiceSeries is a numbering sequence of a node owner. NextNumber is the
+// number the next draft of the series takes, when YearlyReset is set the
+// sequence starts again from 1 on the first draft of a new year, Year is the
+// year of the last number handed out.
+type InvoiceSeries struct {
+	Id          uint      `gorm:"primaryKey;autoIncrement" json:"id"`
+	UserAddress string    `gorm:"type:varchar(66);not null;uniqueIndex:idx_invoice_series_user_name" json:"userAddress"`
+	Name        string    `gorm:"type:varchar(32);not null;uniqueIndex:idx_invoice_series_user_name" json:"name"`
+	NextNumber  int       `gorm:"type:integer;not null;default:1" json:"nextNumber"`
+	YearlyReset bool      `gorm:"not null;default:false" json:"yearlyReset"`
+	Year        int       `gorm:"type:integer;not null;default:0" json:"year"`
+	CreatedAt   time.Time `json:"createdAt"`
+	UpdatedAt   time.Time `json:"updatedAt"`
+}
+
+// Take hands out the next number of the series for a draft dated at.
+func (s *InvoiceSeries) Take(at time.Time) int {
+	if s.YearlyReset && s.Year != 0 && s.Year != at.Year() {
+		s.NextNumber = 1
+	}
+	s.Year = at.Year()
+	number := s.NextNumber
+	s.NextNumber++
+	return number
+}
@@ -10,7 +10,8 @@ type Kyc struct {
 	Uuid           uuid.UUID `gorm:"primarykey;unique" json:"uuid"`
 	ApplicantId    string    `json:"applicant_id"`
 	ApplicantType  string    `json:"applicant_type"`
-	Email          string    `json:"email"`
+	Email          string    `gorm:"serializer:pii" json:"email"`
+	EmailIndex     string    `gorm:"type:varchar(64);index" json:"-"`
 	KycStatus      string    `json:"kyc_status"`
 	LastUpdated    time.Time `json:"last_updated"`
 	IsActive       bool      `gorm:"not null;default:true" json:"is_active"`
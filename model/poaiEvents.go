@@ -56,29 +56,134 @@ type InvoiceDraft struct {
 	CspOwner                   string    `gorm:"type:varchar(66);not null;index" json:"cspOwner"`
 	TotalUsdcAmount            float64   `gorm:"type:numeric" json:"totalUsdcAmount"`
 	VatApplied                 float64   `gorm:"type:numeric" json:"vatApplied"`
+	VatCategory                string    `gorm:"type:varchar(2)" json:"vatCategory"`
+	VatMention                 string    `gorm:"type:text" json:"vatMention"`
 	InvoiceSeries              string    `gorm:"type:text;default:null" json:"invoiceSeries"`
 	InvoiceNumber              int       `gorm:"type:integer;default:null" json:"invoiceNumber"`
 	ExtraText                  *string   `gorm:"type:text;default:null" json:"extraText"`
 	ExtraTaxes                 *string   `gorm:"type:jsonb;default:'{}'" json:"extraTaxes"`
 	LocalCurrency              string    `gorm:"type:varchar(3)" json:"localCurrency"` // es. "EUR", "USD"
 	LocalCurrencyExchangeRatio float64   `gorm:"type:numeric" json:"localCurrencyExchangeRatio"`
+	Status                     string    `gorm:"type:varchar(16);not null;default:'draft';index" json:"status"`
+
+	// a regenerated draft is a new version, the previous one is kept read only
+	// and points to the version that replaced it
+	Version           int        `gorm:"type:integer;not null;default:1" json:"version"`
+	PreviousVersionId *uuid.UUID `gorm:"type:uuid;default:null" json:"previousVersionId"`
+	SupersededBy      *uuid.UUID `gorm:"type:uuid;default:null" json:"supersededBy"`
+
+	// identities of the node owner (seller) and of the csp owner (buyer) frozen
+	// when the draft was generated, the versions are nil on older drafts
+	SellerInfoVersion *int            `gorm:"type:integer;default:null" json:"sellerInfoVersion"`
+	SellerIdentity    BillingIdentity `gorm:"embedded;embeddedPrefix:seller_" json:"sellerIdentity"`
+	BuyerInfoVersion  *int            `gorm:"type:integer;default:null" json:"buyerInfoVersion"`
+	BuyerIdentity     BillingIdentity `gorm:"embedded;embeddedPrefix:buyer_" json:"buyerIdentity"`
 
 	CspProfile  UserInfo `gorm:"foreignKey:CspOwner;references:BlockchainAddress" json:"cspProfile"`
 	UserProfile UserInfo `gorm:"foreignKey:UserAddress;references:BlockchainAddress" json:"userProfile"`
 }
 
+const (
+	DraftStatusDraft        = "draft"
+	DraftStatusIssued       = "issued"
+	DraftStatusAcknowledged = "acknowledged"
+	DraftStatusPaid         = "paid"
+	DraftStatusDisputed     = "disputed"
+	DraftStatusVoid         = "void"
+	DraftStatusSuperseded   = "superseded"
+)
+
+// CurrentStatus returns the lifecycle status of the draft, drafts stored
+// before the status existed are still drafts.
+func (d *InvoiceDraft) CurrentStatus() string {
+	if d.Status == "" {
+		return DraftStatusDraft
+	}
+	return d.Status
+}
+
+// CurrentVersion returns the version of the draft, drafts stored before the
+// versioning are the first one.
+func (d *InvoiceDraft) CurrentVersion() int {
+	if d.Version == 0 {
+		return 1
+	}
+	return d.Version
+}
+
+// InvoiceDraftTransition is an entry of the lifecycle history of a draft.
+// Actor is the address of the node owner or of the csp owner who moved it,
+// Reference is the payment tx hash or reference and Reason explains a void.
+type InvoiceDraftTransition struct {
+	Id         uint      `gorm:"primaryKey;autoIncrement" json:"id"`
+	DraftId    uuid.UUID `gorm:"type:uuid;not null;index" json:"draftId"`
+	FromStatus string    `gorm:"type:varchar(16);not null" json:"fromStatus"`
+	ToStatus   string    `gorm:"type:varchar(16);not null" json:"toStatus"`
+	Actor      string    `gorm:"type:varchar(66);not null" json:"actor"`
+	Reference  *string   `gorm:"type:text;default:null" json:"reference"`
+	Reason     *string   `gorm:"type:text;default:null" json:"reason"`
+	CreatedAt  time.Time `json:"createdAt"`
+}
+
+// SnapshotIdentities freezes the billing identities of the loaded profiles.
+func (d *InvoiceDraft) SnapshotIdentities() {
+	sellerVersion := d.UserProfile.Version
+	buyerVersion := d.CspProfile.Version
+	d.SellerInfoVersion = &sellerVersion
+	d.SellerIdentity = d.UserProfile.Identity()
+	d.BuyerInfoVersion = &buyerVersion
+	d.BuyerIdentity = d.CspProfile.Identity()
+}
+
+// HasIdentitySnapshot reports whether the draft was generated with its
+// identities frozen.
+func (d *InvoiceDraft) HasIdentitySnapshot() bool {
+	return d.SellerInfoVersion != nil && d.BuyerInfoVersion != nil
+}
+
+// Seller returns the node owner profile to print on the draft: the frozen
+// identity when available, the current profile on older drafts.
+func (d *InvoiceDraft) Seller() UserInfo {
+	if d.SellerInfoVersion == nil {
+		return d.UserProfile
+	}
+	seller := d.SellerIdentity.UserInfo(d.UserAddress)
+	seller.Email = d.UserProfile.Email
+	seller.Version = *d.SellerInfoVersion
+	return seller
+}
+
+// Buyer returns the csp owner profile to print on the draft, see Seller.
+func (d *InvoiceDraft) Buyer() UserInfo {
+	if d.BuyerInfoVersion == nil {
+		return d.CspProfile
+	}
+	buyer := d.BuyerIdentity.UserInfo(d.CspOwner)
+	buyer.Email = d.CspProfile.Email
+	buyer.Version = *d.BuyerInfoVersion
+	return buyer
+}
+
+// Preference is how a node owner invoices its csps. CountryVat is the rate
+// charged on the supplies to csps of its own country when it is outside the
+// EU, the VAT of the other supplies is decided by the VAT engine.
 type Preference struct {
 	UserAddress   string  `gorm:"type:varchar(66);primaryKey" json:"userAddress"`
 	InvoiceSeries string  `gorm:"type:text;default:null"      json:"invoiceSeries"`
 	NextNumber    int     `gorm:"type:integer;default:1"      json:"nextNumber"`
 	CountryVat    float64 `gorm:"type:numeric"                json:"countryVat"`
-	UeVat         float64 `gorm:"type:numeric"                json:"ueVat"`
-	ExtraUeVat    float64 `gorm:"type:numeric"                json:"extraUeVat"`
+	VatRegistered bool    `gorm:"not null;default:false"      json:"vatRegistered"`
 	ExtraText     *string `gorm:"type:text;default:null"      json:"extraText"`
 	LocalCurrency string  `gorm:"type:varchar(3)"             json:"localCurrency"` // es. "EUR", "USD"
 	ExtraTaxes    *string `gorm:"type:jsonb;default:'{}'"     json:"extraTaxes"`
 }
 
+// IsVatRegistered tells whether the node owner charges VAT, the preferences
+// saved before the flag existed declared it through their domestic rate.
+func (p *Preference) IsVatRegistered() bool {
+	return p.VatRegistered || p.CountryVat > 0
+}
+
 type ExtraTax struct {
 	Description string      `json:"description"`
 	TaxType     TaxTypeEnum `json:"taxType"`
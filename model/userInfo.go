@@ -1,19 +1,76 @@
 package model
 
+import "time"
+
 type UserInfo struct {
 	BlockchainAddress  string  `gorm:"primaryKey;type:varchar(66)" json:"blockchainAddress"`
-	Email              string  `gorm:"type:text" json:"email"`
-	Name               *string `gorm:"type:text;default:null" json:"name"`
-	Surname            *string `gorm:"type:text;default:null" json:"surname"`
-	CompanyName        *string `gorm:"type:text;default:null" json:"companyName"`
-	IdentificationCode string  `gorm:"type:text" json:"identificationCode"`
-	Address            string  `gorm:"type:text" json:"address"`
+	Email              string  `gorm:"type:text;serializer:pii" json:"email"`
+	Name               *string `gorm:"type:text;default:null;serializer:pii" json:"name"`
+	Surname            *string `gorm:"type:text;default:null;serializer:pii" json:"surname"`
+	CompanyName        *string `gorm:"type:text;default:null;serializer:pii" json:"companyName"`
+	IdentificationCode string  `gorm:"type:text;serializer:pii" json:"identificationCode"`
+	Address            string  `gorm:"type:text;serializer:pii" json:"address"`
+	State              string  `gorm:"type:text" json:"state"`
+	City               string  `gorm:"type:text" json:"city"`
+	Country            string  `gorm:"type:text" json:"country"`
+	IsCompany          bool    `gorm:"type:boolean" json:"isCompany"`
+	Version            int     `gorm:"type:integer;not null;default:0" json:"version"`
+}
+
+// BillingIdentity is the part of a UserInfo printed on invoices and drafts.
+type BillingIdentity struct {
+	Name               *string `gorm:"type:text;default:null;serializer:pii" json:"name"`
+	Surname            *string `gorm:"type:text;default:null;serializer:pii" json:"surname"`
+	CompanyName        *string `gorm:"type:text;default:null;serializer:pii" json:"companyName"`
+	IdentificationCode string  `gorm:"type:text;serializer:pii" json:"identificationCode"`
+	Address            string  `gorm:"type:text;serializer:pii" json:"address"`
 	State              string  `gorm:"type:text" json:"state"`
 	City               string  `gorm:"type:text" json:"city"`
 	Country            string  `gorm:"type:text" json:"country"`
 	IsCompany          bool    `gorm:"type:boolean" json:"isCompany"`
 }
 
+// UserInfoVersion is a row of the UserInfo history, a new version is stored
+// on every change of the profile.
+type UserInfoVersion struct {
+	Id                uint      `gorm:"primaryKey;autoIncrement" json:"id"`
+	BlockchainAddress string    `gorm:"type:varchar(66);not null;uniqueIndex:idx_user_info_version,priority:1" json:"blockchainAddress"`
+	Version           int       `gorm:"type:integer;not null;uniqueIndex:idx_user_info_version,priority:2" json:"version"`
+	Email             string    `gorm:"type:text;serializer:pii" json:"email"`
+	CreatedAt         time.Time `json:"createdAt"`
+	BillingIdentity
+}
+
+func (u *UserInfo) Identity() BillingIdentity {
+	return BillingIdentity{
+		Name:               u.Name,
+		Surname:            u.Surname,
+		CompanyName:        u.CompanyName,
+		IdentificationCode: u.IdentificationCode,
+		Address:            u.Address,
+		State:              u.State,
+		City:               u.City,
+		Country:            u.Country,
+		IsCompany:          u.IsCompany,
+	}
+}
+
+// UserInfo rebuilds a profile holding only the billing identity of address.
+func (b BillingIdentity) UserInfo(address string) UserInfo {
+	return UserInfo{
+		BlockchainAddress:  address,
+		Name:               b.Name,
+		Surname:            b.Surname,
+		CompanyName:        b.CompanyName,
+		IdentificationCode: b.IdentificationCode,
+		Address:            b.Address,
+		State:              b.State,
+		City:               b.City,
+		Country:            b.Country,
+		IsCompany:          b.IsCompany,
+	}
+}
+
 func (u *UserInfo) GetNameAsString() (string, bool) {
 	empty := ""
 	if u.IsCompany {
@@ -0,0 +1,14 @@
+package model
+
+import "time"
+
+// VatRate is the standard VAT rate of a country from EffectiveFrom until the
+// next version of the same country takes effect. Rate is a percentage.
+type VatRate struct {
+	Id            uint      `gorm:"primaryKey;autoIncrement" json:"id"`
+	Country       string    `gorm:"type:varchar(3);not null;uniqueIndex:idx_vat_rate_country_from" json:"country"`
+	EffectiveFrom time.Time `gorm:"type:date;not null;uniqueIndex:idx_vat_rate_country_from" json:"effectiveFrom"`
+	Rate          float64   `gorm:"type:numeric;not null" json:"rate"`
+	CreatedBy     string    `gorm:"type:varchar(66)" json:"createdBy"`
+	CreatedAt     time.Time `json:"createdAt"`
+}
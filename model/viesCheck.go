@@ -0,0 +1,20 @@
+package model
+
+import "time"
+
+// ViesCheck is the answer VIES gave for a VAT number at CheckedAt, it is
+// kept as evidence of the validity of the number at that moment. RequestId
+// is the consultation identifier returned by VIES, ErrorCode is set when VIES
+// rejected the number itself, for example as malformed.
+type ViesCheck struct {
+	Id            uint      `gorm:"primaryKey;autoIncrement" json:"id"`
+	VatNumber     string    `gorm:"type:varchar(20);not null;index:idx_vies_check_vat,priority:1" json:"vatNumber"`
+	Valid         bool      `gorm:"not null" json:"valid"`
+	RequestId     string    `gorm:"type:text" json:"requestId"`
+	TraderName    string    `gorm:"type:text;serializer:pii" json:"traderName"`
+	TraderAddress string    `gorm:"type:text;serializer:pii" json:"traderAddress"`
+	ViesDate      string    `gorm:"type:text" json:"viesDate"`
+	Source        string    `gorm:"type:text" json:"source"`
+	ErrorCode     string    `gorm:"type:text" json:"errorCode"`
+	CheckedAt     time.Time `gorm:"not null;index:idx_vies_check_vat,priority:2" json:"checkedAt"`
+}
@@ -0,0 +1,266 @@
+package pdf
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// A4 page size in points.
+const (
+	PageWidth  = 595.28
+	PageHeight = 841.89
+)
+
+// Font is one of the standard fonts every reader ships, they need no
+// embedding. Every font is declared twice, with WinAnsi and with an encoding
+// of the central european letters WinAnsi lacks, see latinExtended.
+type Font string
+
+const (
+	Helvetica     Font = "Helvetica"
+	HelveticaBold Font = "Helvetica-Bold"
+)
+
+var fonts = []Font{Helvetica, HelveticaBold}
+
+// Document is a text only pdf. Coordinates start from the top left corner of
+// the page and grow to the right and downwards.
+type Document struct {
+	pages []*bytes.Buffer
+	title string
+}
+
+func New(title string) *Document {
+	return &Document{title: title}
+}
+
+// AddPage starts a new page, the following drawing goes to it.
+func (d *Document) AddPage() {
+	d.pages = append(d.pages, &bytes.Buffer{})
+}
+
+// Pages returns the number of pages added.
+func (d *Document) Pages() int {
+	return len(d.pages)
+}
+
+func (d *Document) page() *bytes.Buffer {
+	if len(d.pages) == 0 {
+		d.AddPage()
+	}
+	return d.pages[len(d.pages)-1]
+}
+
+// Text writes text with its baseline at y.
+func (d *Document) Text(x, y float64, font Font, size float64, text string) {
+	page := d.page()
+	for i, run := range encode(text) {
+		index := fontIndex(font)
+		if run.extended {
+			index += len(fonts)
+		}
+		if i == 0 {
+			fmt.Fprintf(page, "BT /F%d %s Tf %s %s Td (%s) Tj", index, number(size), number(x), number(PageHeight-y), run.text)
+			continue
+		}
+		fmt.Fprintf(page, " /F%d %s Tf (%s) Tj", index, number(size), run.text)
+	}
+	page.WriteString(" ET\n")
+}
+
+// TextRight writes text ending at x.
+func (d *Document) TextRight(x, y float64, font Font, size float64, text string) {
+	d.Text(x-TextWidth(text, font, size), y, font, size, text)
+}
+
+// Line draws a thin line.
+func (d *Document) Line(x1, y1, x2, y2 float64) {
+	fmt.Fprintf(d.page(), "0.5 w %s %s m %s %s l S\n",
+		number(x1), number(PageHeight-y1), number(x2), number(PageHeight-y2))
+}
+
+// Bytes returns the encoded document.
+func (d *Document) Bytes() []byte {
+	if len(d.pages) == 0 {
+		d.AddPage()
+	}
+
+	var out bytes.Buffer
+	var offsets []int
+	object := func(body string) {
+		offsets = append(offsets, out.Len())
+		fmt.Fprintf(&out, "%d 0 obj\n%s\nendobj\n", len(offsets), body)
+	}
+
+	// objects: catalog, pages, info, fonts in WinAnsi then in the extended
+	// encoding, then a page and its content for every page
+	const firstFont = 4
+	firstPage := firstFont + 2*len(fonts)
+
+	out.WriteString("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+	object("<< /Type /Catalog /Pages 2 0 R >>")
+
+	kids := make([]string, 0, len(d.pages))
+	for i := range d.pages {
+		kids = append(kids, strconv.Itoa(firstPage+2*i)+" 0 R")
+	}
+	object(fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(d.pages)))
+	object(fmt.Sprintf("<< /Title (%s) /Producer (ratio1-backend) >>", escape(d.title)))
+
+	var fontResources []string
+	for i, font := range fonts {
+		object(fmt.Sprintf("<< /Type /Font /Subtype /Type1 /BaseFont /%s /Encoding /WinAnsiEncoding >>", font))
+		fontResources = append(fontResources, fmt.Sprintf("/F%d %d 0 R", i+1, firstFont+i))
+	}
+	differences := make([]string, 0, len(latinExtended))
+	for _, letter := range latinExtended {
+		differences = append(differences, "/"+letter.glyph)
+	}
+	for i, font := range fonts {
+		object(fmt.Sprintf("<< /Type /Font /Subtype /Type1 /BaseFont /%s /Encoding << /Type /Encoding /Differences [1 %s] >> >>", font, strings.Join(differences, " ")))
+		fontResources = append(fontResources, fmt.Sprintf("/F%d %d 0 R", len(fonts)+i+1, firstFont+len(fonts)+i))
+	}
+
+	for i, content := range d.pages {
+		object(fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %s %s] /Resources << /Font << %s >> >> /Contents %d 0 R >>",
+			number(PageWidth), number(PageHeight), strings.Join(fontResources, " "), firstPage+2*i+1))
+		object(fmt.Sprintf("<< /Length %d >>\nstream\n%sendstream", content.Len(), content.String()))
+	}
+
+	xref := out.Len()
+	fmt.Fprintf(&out, "xref\n0 %d\n0000000000 65535 f \n", len(offsets)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&out, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&out, "trailer\n<< /Size %d /Root 1 0 R /Info 3 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(offsets)+1, xref)
+	return out.Bytes()
+}
+
+func fontIndex(font Font) int {
+	for i, f := range fonts {
+		if f == font {
+			return i + 1
+		}
+	}
+	return 1
+}
+
+func number(n float64) string {
+	return strconv.FormatFloat(n, 'f', 2, 64)
+}
+
+// winAnsi maps the characters WinAnsi places in the latin 1 control range.
+var winAnsi = map[rune]byte{
+	'€': 0x80, '…': 0x85, '‘': 0x91, '’': 0x92, '“': 0x93, '”': 0x94, '•': 0x95, '–': 0x96, '—': 0x97,
+}
+
+// latinExtended are the letters of the central european languages WinAnsi has
+// no code for, by the name of their glyph in the standard fonts. The extended
+// encoding gives them the codes from 1 in this order, base is the letter
+// they are drawn from. The romanian letters with comma below and with cedilla
+// share the glyphs the fonts have for them.
+var latinExtended = []struct {
+	runes []rune
+	glyph string
+	base  rune
+}{
+	{[]rune{'Ă'}, "Abreve", 'A'}, {[]rune{'ă'}, "abreve", 'a'},
+	{[]rune{'Ą'}, "Aogonek", 'A'}, {[]rune{'ą'}, "aogonek", 'a'},
+	{[]rune{'Ć'}, "Cacute", 'C'}, {[]rune{'ć'}, "cacute", 'c'},
+	{[]rune{'Č'}, "Ccaron", 'C'}, {[]rune{'č'}, "ccaron", 'c'},
+	{[]rune{'Ď'}, "Dcaron", 'D'}, {[]rune{'ď'}, "dcaron", 'd'},
+	{[]rune{'Đ'}, "Dcroat", 'D'}, {[]rune{'đ'}, "dcroat", 'd'},
+	{[]rune{'Ę'}, "Eogonek", 'E'}, {[]rune{'ę'}, "eogonek", 'e'},
+	{[]rune{'Ě'}, "Ecaron", 'E'}, {[]rune{'ě'}, "ecaron", 'e'},
+	{[]rune{'Ğ'}, "Gbreve", 'G'}, {[]rune{'ğ'}, "gbreve", 'g'},
+	{[]rune{'İ'}, "Idotaccent", 'I'}, {[]rune{'ı'}, "dotlessi", 'i'},
+	{[]rune{'Ĺ'}, "Lacute", 'L'}, {[]rune{'ĺ'}, "lacute", 'l'},
+	{[]rune{'Ľ'}, "Lcaron", 'L'}, {[]rune{'ľ'}, "lcaron", 'l'},
+	{[]rune{'Ł'}, "Lslash", 'L'}, {[]rune{'ł'}, "lslash", 'l'},
+	{[]rune{'Ń'}, "Nacute", 'N'}, {[]rune{'ń'}, "nacute", 'n'},
+	{[]rune{'Ň'}, "Ncaron", 'N'}, {[]rune{'ň'}, "ncaron", 'n'},
+	{[]rune{'Ő'}, "Ohungarumlaut", 'O'}, {[]rune{'ő'}, "ohungarumlaut", 'o'},
+	{[]rune{'Ŕ'}, "Racute", 'R'}, {[]rune{'ŕ'}, "racute", 'r'},
+	{[]rune{'Ř'}, "Rcaron", 'R'}, {[]rune{'ř'}, "rcaron", 'r'},
+	{[]rune{'Ś'}, "Sacute", 'S'}, {[]rune{'ś'}, "sacute", 's'},
+	{[]rune{'Ş'}, "Scedilla", 'S'}, {[]rune{'ş'}, "scedilla", 's'},
+	{[]rune{'Ș'}, "Scommaaccent", 'S'}, {[]rune{'ș'}, "scommaaccent", 's'},
+	{[]rune{'Ţ', 'Ț'}, "Tcommaaccent", 'T'}, {[]rune{'ţ', 'ț'}, "tcommaaccent", 't'},
+	{[]rune{'Ť'}, "Tcaron", 'T'}, {[]rune{'ť'}, "tcaron", 't'},
+	{[]rune{'Ů'}, "Uring", 'U'}, {[]rune{'ů'}, "uring", 'u'},
+	{[]rune{'Ű'}, "Uhungarumlaut", 'U'}, {[]rune{'ű'}, "uhungarumlaut", 'u'},
+	{[]rune{'Ź'}, "Zacute", 'Z'}, {[]rune{'ź'}, "zacute", 'z'},
+	{[]rune{'Ż'}, "Zdotaccent", 'Z'}, {[]rune{'ż'}, "zdotaccent", 'z'},
+}
+
+// latinExtendedCodes maps the letters of latinExtended to their code in the
+// extended encoding.
+var latinExtendedCodes = func() map[rune]byte {
+	codes := make(map[rune]byte)
+	for i, letter := range latinExtended {
+		for _, r := range letter.runes {
+			codes[r] = byte(i + 1)
+		}
+	}
+	return codes
+}()
+
+// textRun is a part of a text written with one of the encodings of a font,
+// already escaped.
+type textRun struct {
+	extended bool
+	text     string
+}
+
+// encode splits text in the runs written in WinAnsi and in the extended
+// encoding.
+func encode(text string) []textRun {
+	var runs []textRun
+	var out strings.Builder
+	extended := false
+	for _, r := range text {
+		code, isExtended := latinExtendedCodes[r]
+		if isExtended != extended && out.Len() > 0 {
+			runs = append(runs, textRun{extended: extended, text: out.String()})
+			out.Reset()
+		}
+		extended = isExtended
+		if isExtended {
+			fmt.Fprintf(&out, "\\%03o", code)
+		} else {
+			out.WriteString(escape(string(r)))
+		}
+	}
+	if out.Len() > 0 || len(runs) == 0 {
+		runs = append(runs, textRun{extended: extended, text: out.String()})
+	}
+	return runs
+}
+
+// escape encodes text in WinAnsi, characters it has no code for are replaced
+// by a question mark.
+func escape(text string) string {
+	var out strings.Builder
+	for _, r := range text {
+		if code, ok := winAnsi[r]; ok {
+			fmt.Fprintf(&out, "\\%03o", code)
+			continue
+		}
+		switch {
+		case r == '(' || r == ')' || r == '\\':
+			out.WriteByte('\\')
+			out.WriteRune(r)
+		case r == '\n' || r == '\r' || r == '\t':
+			out.WriteByte(' ')
+		case r < 0x20 || r > 0xff || (r >= 0x7f && r < 0xa0):
+			out.WriteByte('?')
+		case r < 0x80:
+			out.WriteRune(r)
+		default:
+			fmt.Fprintf(&out, "\\%03o", r)
+		}
+	}
+	return out.String()
+}
@@ -0,0 +1,70 @@
+package pdf
+
+import (
+	"bytes"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestBytesWritesValidCrossReference(t *testing.T) {
+	doc := New("Invoice (draft)")
+	doc.Text(40, 60, HelveticaBold, 14, "Invoice NAE-1")
+	doc.Line(40, 70, 555, 70)
+	doc.AddPage()
+	doc.TextRight(555, 60, Helvetica, 10, "Total: 1.000,00 € (EUR)")
+
+	out := doc.Bytes()
+	require.True(t, bytes.HasPrefix(out, []byte("%PDF-1.4\n")))
+	require.True(t, bytes.HasSuffix(out, []byte("%%EOF\n")))
+	require.Contains(t, string(out), "/Count 2")
+	require.Contains(t, string(out), `(Total: 1.000,00 \200 \(EUR\)) Tj`)
+
+	startxref := regexp.MustCompile(`startxref\n(\d+)\n`).FindSubmatch(out)
+	require.NotNil(t, startxref)
+	xref, err := strconv.Atoi(string(startxref[1]))
+	require.NoError(t, err)
+	require.True(t, bytes.HasPrefix(out[xref:], []byte("xref\n")))
+
+	for i, entry := range regexp.MustCompile(`(\d{10}) 00000 n`).FindAllSubmatch(out, -1) {
+		offset, err := strconv.Atoi(string(entry[1]))
+		require.NoError(t, err)
+		require.True(t, bytes.HasPrefix(out[offset:], []byte(strconv.Itoa(i+1)+" 0 obj\n")))
+	}
+}
+
+func TestTextWritesCentralEuropeanLetters(t *testing.T) {
+	doc := New("Invoice")
+	doc.Text(40, 60, Helvetica, 10, "Ştefănescu Łódź")
+	doc.Text(40, 80, HelveticaBold, 10, "Ștefănescu")
+
+	out := string(doc.Bytes())
+	require.Contains(t, out, `BT /F3 10.00 Tf 40.00 781.89 Td (\047) Tj /F1 10.00 Tf (tef) Tj /F3 10.00 Tf (\002) Tj /F1 10.00 Tf (nescu ) Tj /F3 10.00 Tf (\031) Tj /F1 10.00 Tf (\363d) Tj /F3 10.00 Tf (\064) Tj ET`)
+	require.Contains(t, out, `BT /F4 10.00 Tf 40.00 761.89 Td (\051) Tj /F2 10.00 Tf (tef) Tj /F4 10.00 Tf (\002) Tj /F2 10.00 Tf (nescu) Tj ET`)
+	require.Contains(t, out, "/BaseFont /Helvetica-Bold /Encoding << /Type /Encoding /Differences [1 /Abreve /abreve")
+	require.NotContains(t, out, "?")
+	require.Contains(t, out, "/F3 6 0 R /F4 7 0 R")
+
+	require.Equal(t, TextWidth("Stefanescu", Helvetica, 10), TextWidth("Ștefănescu", Helvetica, 10))
+	require.Equal(t, TextWidth("Stefanescu", Helvetica, 10), TextWidth("Ştefănescu", Helvetica, 10))
+	require.Greater(t, TextWidth("ť", HelveticaBold, 10), TextWidth("t", HelveticaBold, 10))
+}
+
+func TestWrap(t *testing.T) {
+	lines := Wrap("R1 Node License for the operation of a R1 Edge Node", Helvetica, 10, 120)
+	require.Greater(t, len(lines), 1)
+	for _, line := range lines {
+		require.LessOrEqual(t, TextWidth(line, Helvetica, 10), 120.0)
+	}
+	require.Equal(t, []string{""}, Wrap("", Helvetica, 10, 120))
+}
+
+func TestTruncate(t *testing.T) {
+	require.Equal(t, "short", Truncate("short", Helvetica, 10, 100))
+	truncated := Truncate("a job name much longer than its column", Helvetica, 10, 60)
+	require.True(t, strings.HasSuffix(truncated, "..."))
+	require.LessOrEqual(t, TextWidth(truncated, Helvetica, 10), 60.0)
+}
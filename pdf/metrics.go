@@ -0,0 +1,110 @@
+package pdf
+
+import "strings"
+
+// widths of the printable ascii characters, from space to tilde, in
+// thousandths of the font size, from the adobe font metrics.
+var widths = map[Font][95]int{
+	Helvetica: {
+		278, 278, 355, 556, 556, 889, 667, 191, 333, 333, 389, 584, 278, 333, 278, 278,
+		556, 556, 556, 556, 556, 556, 556, 556, 556, 556, 278, 278, 584, 584, 584, 556,
+		1015, 667, 667, 722, 722, 667, 611, 778, 722, 278, 500, 667, 556, 833, 722, 778,
+		667, 778, 722, 667, 611, 722, 667, 944, 667, 667, 611, 278, 278, 278, 469, 556,
+		333, 556, 556, 500, 556, 556, 278, 556, 556, 222, 222, 500, 222, 833, 556, 556,
+		556, 556, 333, 500, 278, 556, 500, 722, 500, 500, 500, 334, 260, 334, 584,
+	},
+	HelveticaBold: {
+		278, 333, 474, 556, 556, 889, 722, 238, 333, 333, 389, 584, 278, 333, 278, 278,
+		556, 556, 556, 556, 556, 556, 556, 556, 556, 556, 333, 333, 584, 584, 584, 611,
+		975, 722, 722, 722, 722, 667, 611, 778, 722, 278, 556, 722, 611, 833, 722, 778,
+		667, 778, 722, 667, 611, 722, 667, 944, 667, 667, 611, 333, 278, 333, 584, 556,
+		333, 556, 611, 556, 611, 556, 333, 611, 611, 278, 278, 556, 278, 889, 611, 611,
+		611, 611, 389, 556, 333, 611, 556, 778, 556, 556, 500, 389, 280, 389, 584,
+	},
+}
+
+// defaultWidth is used for the characters outside ascii.
+const defaultWidth = 556
+
+// extendedWidths are the letters of latinExtended not as wide as the letter
+// they are drawn from.
+var extendedWidths = map[Font]map[rune]int{
+	Helvetica:     {'ď': 643, 'ľ': 299, 'ť': 316, 'ı': 278},
+	HelveticaBold: {'ď': 743, 'ľ': 400, 'ť': 389, 'ı': 278},
+}
+
+// extendedBases maps the letters of latinExtended to the letter they are
+// drawn from.
+var extendedBases = func() map[rune]rune {
+	bases := make(map[rune]rune)
+	for _, letter := range latinExtended {
+		for _, r := range letter.runes {
+			bases[r] = letter.base
+		}
+	}
+	return bases
+}()
+
+// TextWidth returns the width of text written with font at size.
+func TextWidth(text string, font Font, size float64) float64 {
+	table, ok := widths[font]
+	if !ok {
+		table = widths[Helvetica]
+	}
+
+	total := 0
+	for _, r := range text {
+		if width, ok := extendedWidths[font][r]; ok {
+			total += width
+			continue
+		}
+		if base, ok := extendedBases[r]; ok {
+			r = base
+		}
+		if r >= ' ' && r <= '~' {
+			total += table[r-' ']
+		} else {
+			total += defaultWidth
+		}
+	}
+	return float64(total) * size / 1000
+}
+
+// Wrap splits text in lines no wider than width, words longer than a line are
+// left on their own line.
+func Wrap(text string, font Font, size, width float64) []string {
+	var lines []string
+	line := ""
+	for _, word := range strings.Fields(text) {
+		candidate := word
+		if line != "" {
+			candidate = line + " " + word
+		}
+		if line != "" && TextWidth(candidate, font, size) > width {
+			lines = append(lines, line)
+			line = word
+			continue
+		}
+		line = candidate
+	}
+	if line != "" || len(lines) == 0 {
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+// Truncate shortens text to fit width, marking the cut with an ellipsis.
+func Truncate(text string, font Font, size, width float64) string {
+	if TextWidth(text, font, size) <= width {
+		return text
+	}
+	runes := []rune(text)
+	for len(runes) > 0 {
+		runes = runes[:len(runes)-1]
+		candidate := string(runes) + "..."
+		if TextWidth(candidate, font, size) <= width {
+			return candidate
+		}
+	}
+	return ""
+}
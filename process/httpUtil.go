@@ -50,6 +50,35 @@ func HttpGet(url string, castTarget interface{}, headers ...HttpHeaderPair) erro
 	return json.Unmarshal(respBytes, castTarget)
 }
 
+// HttpGetBytes returns the raw body of a GET request, failing on a non 2xx
+// status.
+func HttpGetBytes(url string, headers ...HttpHeaderPair) ([]byte, error) {
+	client := &http.Client{Timeout: defaultHTTPTimeout}
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, head := range headers {
+		req.Header.Set(head.Key, head.Value)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		bodyCloseErr := resp.Body.Close()
+		if bodyCloseErr != nil {
+			log.Printf("HttpGetBytes - error while trying to close response body: %v", bodyCloseErr)
+		}
+	}()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, errors.New("unexpected status: " + resp.Status)
+	}
+	return io.ReadAll(resp.Body)
+}
+
 func HttpPost(url string, payload interface{}, response interface{}, headers ...HttpHeaderPair) error {
 	jsonData, err := json.Marshal(payload)
 	if err != nil {
@@ -82,6 +111,38 @@ func HttpPost(url string, payload interface{}, response interface{}, headers ...
 	return json.Unmarshal(resBody, &response)
 }
 
+func HttpPut(url string, payload interface{}, response interface{}, headers ...HttpHeaderPair) error {
+	jsonData, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	client := &http.Client{Timeout: defaultHTTPTimeout}
+	req, err := http.NewRequest(http.MethodPut, url, bytes.NewReader(jsonData))
+	if err != nil {
+		return err
+	}
+	req.Header.Set(contentTypeKey, contentTypeValue)
+	for _, head := range headers {
+		req.Header.Set(head.Key, head.Value)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return errors.New("error while doing http put request: " + err.Error())
+	}
+	defer func() {
+		bodyCloseErr := resp.Body.Close()
+		if bodyCloseErr != nil {
+			log.Printf("HttpPut - error while trying to close response body: %v", bodyCloseErr)
+		}
+	}()
+	resBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(resBody, &response)
+}
+
 func HttpPostWithUrlEncoded(url string, payload interface{}, response interface{}, headers ...HttpHeaderPair) error {
 	var reqBody io.Reader
 
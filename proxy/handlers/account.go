@@ -1,7 +1,11 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
+	"net/url"
+	"strconv"
+	"time"
 
 	"github.com/NaeuralEdgeProtocol/ratio1-backend/config"
 	"github.com/NaeuralEdgeProtocol/ratio1-backend/model"
@@ -24,6 +28,11 @@ const (
 	addSellerCodeEndpoint             = "/add-seller-code"
 	getKycinfoEndpoint                = "/kyc-info"
 	getIsKybEndpoint                  = "/is-kyb"
+	exportEndpoint                    = "/export"
+	downloadExportEndpoint            = "/export/download"
+	erasureEndpoint                   = "/erasure"
+	getInvoicesEndpoint               = "/invoices"
+	getInvoicePdfEndpoint             = "/invoices/:id/pdf"
 )
 
 type registerEmailRequest struct {
@@ -51,14 +60,30 @@ type clientInfoResponse struct {
 	IsCompany          bool   `json:"isCompany"`
 }
 
-type accountHandler struct{}
+type erasureRequest struct {
+	Message   string `json:"message"`
+	Signature string `json:"signature"`
+}
+
+type accountExportResponse struct {
+	Id          string    `json:"id"`
+	Status      string    `json:"status"`
+	CreatedAt   time.Time `json:"createdAt"`
+	ExpiresAt   time.Time `json:"expiresAt"`
+	DownloadUrl string    `json:"downloadUrl,omitempty"`
+}
+
+type accountHandler struct {
+	repos *storage.Repositories
+}
 
-func NewAccountHandler(groupHandler *groupHandler) {
-	h := accountHandler{}
+func NewAccountHandler(groupHandler *groupHandler, repos *storage.Repositories) {
+	h := accountHandler{repos: repos}
 
 	publicEndpoints := []EndpointHandler{
 		{Method: http.MethodGet, Path: confirmEmailEndpoint, HandlerFunc: h.confirmEmail},
 		{Method: http.MethodGet, Path: getIsKybEndpoint, HandlerFunc: h.isKyb},
+		{Method: http.MethodGet, Path: downloadExportEndpoint, HandlerFunc: h.downloadExport},
 	}
 
 	publicEndpointsGroupHandler := EndpointGroupHandler{
@@ -78,6 +103,10 @@ func NewAccountHandler(groupHandler *groupHandler) {
 		{Method: http.MethodPost, Path: blacklistEndpoint, HandlerFunc: h.blackListAccount},
 		{Method: http.MethodPost, Path: addSellerCodeEndpoint, HandlerFunc: h.addSellerCode},
 		{Method: http.MethodGet, Path: getKycinfoEndpoint, HandlerFunc: h.getKycinfo},
+		{Method: http.MethodGet, Path: exportEndpoint, HandlerFunc: h.requestExport},
+		{Method: http.MethodPost, Path: erasureEndpoint, HandlerFunc: h.requestErasure},
+		{Method: http.MethodGet, Path: getInvoicesEndpoint, HandlerFunc: h.getInvoices},
+		{Method: http.MethodGet, Path: getInvoicePdfEndpoint, HandlerFunc: h.getInvoicePdf},
 	}
 
 	auth := middleware.Authorization(config.Config.Jwt.Secret)
@@ -125,7 +154,7 @@ func (h *accountHandler) getOrCreateAccount(c *gin.Context) {
 
 	var kyc *model.Kyc
 	if account.Email != nil {
-		kyc, _, err = storage.GetKycByEmail(*account.Email)
+		kyc, _, err = h.repos.Kycs.GetByEmail(*account.Email)
 		if err != nil {
 			log.Error("error while retrieving kyc information from storage: " + err.Error())
 			model.JsonResponse(c, http.StatusInternalServerError, nil, nodeAddress, err.Error())
@@ -175,7 +204,7 @@ func (h *accountHandler) registerEmail(c *gin.Context) {
 
 	var kyc *model.Kyc
 	if account.Email != nil {
-		kyc, _, err = storage.GetKycByEmail(*account.Email)
+		kyc, _, err = h.repos.Kycs.GetByEmail(*account.Email)
 		if err != nil {
 			log.Error("error while retrieving kyc information from storage: " + err.Error())
 			model.JsonResponse(c, http.StatusInternalServerError, nil, nodeAddress, err.Error())
@@ -225,7 +254,7 @@ func (h *accountHandler) registerNotificationEmail(c *gin.Context) {
 
 	var kyc *model.Kyc
 	if account.Email != nil {
-		kyc, _, err = storage.GetKycByEmail(*account.Email)
+		kyc, _, err = h.repos.Kycs.GetByEmail(*account.Email)
 		if err != nil {
 			log.Error("error while retrieving kyc information from storage: " + err.Error())
 			model.JsonResponse(c, http.StatusInternalServerError, nil, nodeAddress, err.Error())
@@ -267,7 +296,7 @@ func (h *accountHandler) deleteNotificationEmail(c *gin.Context) {
 
 	var kyc *model.Kyc
 	if account.Email != nil {
-		kyc, _, err = storage.GetKycByEmail(*account.Email)
+		kyc, _, err = h.repos.Kycs.GetByEmail(*account.Email)
 		if err != nil {
 			log.Error("error while retrieving kyc information from storage: " + err.Error())
 			model.JsonResponse(c, http.StatusInternalServerError, nil, nodeAddress, err.Error())
@@ -307,7 +336,7 @@ func (h *accountHandler) confirmEmail(c *gin.Context) {
 		return
 	}
 
-	kyc, _, err := storage.GetKycByEmail(*account.Email)
+	kyc, _, err := h.repos.Kycs.GetByEmail(*account.Email)
 	if err != nil {
 		log.Error("error while retrieving kyc information from storage: " + err.Error())
 		model.JsonResponse(c, http.StatusInternalServerError, nil, nodeAddress, err.Error())
@@ -364,7 +393,7 @@ func (h *accountHandler) subscribe(c *gin.Context) {
 		}
 	}
 
-	kyc, found, err := storage.GetKycByEmail(*account.Email)
+	kyc, found, err := h.repos.Kycs.GetByEmail(*account.Email)
 	if err != nil {
 		log.Error("error while retrieving kyc information from storage: " + err.Error())
 		model.JsonResponse(c, http.StatusInternalServerError, nil, nodeAddress, err.Error())
@@ -432,7 +461,7 @@ func (h *accountHandler) unsubscribe(c *gin.Context) {
 		}
 	}
 
-	kyc, found, err := storage.GetKycByEmail(*account.Email)
+	kyc, found, err := h.repos.Kycs.GetByEmail(*account.Email)
 	if err != nil {
 		log.Error("error while retrieving kyc information from storage: " + err.Error())
 		model.JsonResponse(c, http.StatusInternalServerError, nil, nodeAddress, err.Error())
@@ -514,7 +543,7 @@ func (h *accountHandler) blackListAccount(c *gin.Context) {
 		return
 	}
 
-	kyc, _, err := storage.GetKycByEmail(*account.Email)
+	kyc, _, err := h.repos.Kycs.GetByEmail(*account.Email)
 	if err != nil {
 		log.Error("error while retrieving kyc information from storage: " + err.Error())
 		model.JsonResponse(c, http.StatusInternalServerError, nil, nodeAddress, err.Error())
@@ -572,7 +601,7 @@ func (h *accountHandler) addSellerCode(c *gin.Context) {
 		}
 	}
 
-	exist, err := storage.SellerCodeDoExist(referralCode)
+	exist, err := h.repos.Sellers.CodeExists(referralCode)
 	if err != nil {
 		log.Error("error while checking referral code: " + err.Error())
 		model.JsonResponse(c, http.StatusInternalServerError, nil, nodeAddress, err.Error())
@@ -585,7 +614,7 @@ func (h *accountHandler) addSellerCode(c *gin.Context) {
 		return
 	}
 
-	sellerCode, err := storage.GetSellerCodeByAddress(address)
+	sellerCode, err := h.repos.Sellers.GetCodeByAddress(address)
 	if err != nil {
 		log.Error("error while retrieving seller code: " + err.Error())
 		model.JsonResponse(c, http.StatusInternalServerError, nil, nodeAddress, "error while retrieving seller code: "+err.Error())
@@ -597,7 +626,7 @@ func (h *accountHandler) addSellerCode(c *gin.Context) {
 	}
 
 	account.UsedSellerCode = &referralCode
-	err = storage.UpdateAccount(account)
+	err = h.repos.Accounts.Update(account)
 	if err != nil {
 		log.Error("error while updating account: " + err.Error())
 		model.JsonResponse(c, http.StatusInternalServerError, nil, nodeAddress, err.Error())
@@ -606,7 +635,7 @@ func (h *accountHandler) addSellerCode(c *gin.Context) {
 
 	var kyc *model.Kyc
 	if account.Email != nil {
-		kyc, _, err = storage.GetKycByEmail(*account.Email)
+		kyc, _, err = h.repos.Kycs.GetByEmail(*account.Email)
 		if err != nil {
 			log.Error("error while retrieving kyc information from storage: " + err.Error())
 			model.JsonResponse(c, http.StatusInternalServerError, nil, nodeAddress, err.Error())
@@ -652,7 +681,7 @@ func (h *accountHandler) getKycinfo(c *gin.Context) {
 		return
 	}
 
-	kyc, found, err := storage.GetKycByEmail(*account.Email)
+	kyc, found, err := h.repos.Kycs.GetByEmail(*account.Email)
 	if err != nil {
 		log.Error("error while retrieving kyc information from storage: " + err.Error())
 		model.JsonResponse(c, http.StatusInternalServerError, nil, nodeAddress, err.Error())
@@ -669,7 +698,7 @@ func (h *accountHandler) getKycinfo(c *gin.Context) {
 		return
 	}
 
-	userInfo, err := storage.GetUserInfoByAddress(address)
+	userInfo, err := h.repos.UserInfos.GetByAddress(address)
 	if err != nil {
 		log.Error("error while retrieving client info from storage: " + err.Error())
 		model.JsonResponse(c, http.StatusInternalServerError, nil, nodeAddress, err.Error())
@@ -727,7 +756,7 @@ func (h *accountHandler) isKyb(c *gin.Context) {
 		return
 	}
 
-	kyc, found, err := storage.GetKycByEmail(*account.Email)
+	kyc, found, err := h.repos.Kycs.GetByEmail(*account.Email)
 	if err != nil {
 		log.Error("error while retrieving kyc information from storage: " + err.Error())
 		model.JsonResponse(c, http.StatusBadRequest, nil, nodeAddress, err.Error())
@@ -746,3 +775,178 @@ func (h *accountHandler) isKyb(c *gin.Context) {
 
 	model.JsonResponse(c, http.StatusOK, kyc.ApplicantType == model.BusinessCustomer, nodeAddress, "")
 }
+
+func (h *accountHandler) requestExport(c *gin.Context) {
+	nodeAddress, err := service.GetAddress()
+	if err != nil {
+		log.Error("error while retrieving node address: " + err.Error())
+		model.JsonResponse(c, http.StatusInternalServerError, nil, "", err.Error())
+		return
+	}
+
+	address, err := middleware.AddressFromBearer(c)
+	if err != nil {
+		log.Error("error while retrieving address from bearer: " + err.Error())
+		model.JsonResponse(c, http.StatusBadRequest, nil, nodeAddress, err.Error())
+		return
+	}
+
+	refresh := false
+	if value, ok := c.GetQuery("refresh"); ok {
+		refresh, err = strconv.ParseBool(value)
+		if err != nil {
+			log.Error("invalid refresh parameter: " + err.Error())
+			model.JsonResponse(c, http.StatusBadRequest, nil, nodeAddress, "invalid refresh parameter")
+			return
+		}
+	}
+
+	export, err := service.RequestAccountExport(address, refresh)
+	if err != nil {
+		log.Error("error while requesting account export: " + err.Error())
+		model.JsonResponse(c, http.StatusInternalServerError, nil, nodeAddress, err.Error())
+		return
+	}
+
+	response := accountExportResponse{
+		Id:        export.Id.String(),
+		Status:    export.Status,
+		CreatedAt: export.CreatedAt,
+		ExpiresAt: export.ExpiresAt,
+	}
+	if export.Status != model.AccountExportStatusReady {
+		model.JsonResponse(c, http.StatusAccepted, response, nodeAddress, "")
+		return
+	}
+
+	token, err := service.NewAccountExportToken(export)
+	if err != nil {
+		log.Error("error while signing account export link: " + err.Error())
+		model.JsonResponse(c, http.StatusInternalServerError, nil, nodeAddress, err.Error())
+		return
+	}
+	response.DownloadUrl = baseAccountEndpoint + downloadExportEndpoint + "?token=" + url.QueryEscape(token)
+
+	model.JsonResponse(c, http.StatusOK, response, nodeAddress, "")
+}
+
+func (h *accountHandler) downloadExport(c *gin.Context) {
+	nodeAddress, err := service.GetAddress()
+	if err != nil {
+		log.Error("error while retrieving node address: " + err.Error())
+		model.JsonResponse(c, http.StatusInternalServerError, nil, "", err.Error())
+		return
+	}
+
+	token, ok := c.GetQuery("token")
+	if !ok {
+		log.Error("error while retrieving token from params")
+		model.JsonResponse(c, http.StatusBadRequest, nil, nodeAddress, "empty or invalid token query")
+		return
+	}
+
+	archive, err := service.GetAccountExportArchive(token)
+	if err != nil {
+		log.Error("error while retrieving account export: " + err.Error())
+		model.JsonResponse(c, http.StatusUnauthorized, nil, nodeAddress, err.Error())
+		return
+	}
+
+	c.Header("Content-Disposition", "attachment; filename=account_export.zip")
+	c.Data(http.StatusOK, "application/zip", archive)
+}
+
+func (h *accountHandler) requestErasure(c *gin.Context) {
+	nodeAddress, err := service.GetAddress()
+	if err != nil {
+		log.Error("error while retrieving node address: " + err.Error())
+		model.JsonResponse(c, http.StatusInternalServerError, nil, "", err.Error())
+		return
+	}
+
+	address, err := middleware.AddressFromBearer(c)
+	if err != nil {
+		log.Error("error while retrieving address from bearer: " + err.Error())
+		model.JsonResponse(c, http.StatusBadRequest, nil, nodeAddress, err.Error())
+		return
+	}
+
+	req := erasureRequest{}
+	err = c.Bind(&req)
+	if err != nil {
+		log.Error("error while binding request: " + err.Error())
+		model.JsonResponse(c, http.StatusBadRequest, nil, nodeAddress, err.Error())
+		return
+	}
+
+	request, err := service.RequestAccountErasure(address, req.Message, req.Signature)
+	if err != nil {
+		log.Error("error while requesting account erasure: " + err.Error())
+		if errors.Is(err, service.ErrorAccountErasureAlreadyOpen) {
+			model.JsonResponse(c, http.StatusConflict, nil, nodeAddress, err.Error())
+			return
+		}
+		model.JsonResponse(c, http.StatusBadRequest, nil, nodeAddress, err.Error())
+		return
+	}
+
+	model.JsonResponse(c, http.StatusAccepted, request, nodeAddress, "")
+}
+
+func (h *accountHandler) getInvoices(c *gin.Context) {
+	nodeAddress, err := service.GetAddress()
+	if err != nil {
+		log.Error("error while retrieving node address: " + err.Error())
+		model.JsonResponse(c, http.StatusInternalServerError, nil, "", err.Error())
+		return
+	}
+
+	address, err := middleware.AddressFromBearer(c)
+	if err != nil {
+		log.Error("error while retrieving address from bearer: " + err.Error())
+		model.JsonResponse(c, http.StatusBadRequest, nil, nodeAddress, err.Error())
+		return
+	}
+
+	history, err := service.GetLicensePurchaseHistory(address)
+	if err != nil {
+		log.Error("error while retrieving license purchases: " + err.Error())
+		model.JsonResponse(c, http.StatusInternalServerError, nil, nodeAddress, err.Error())
+		return
+	}
+
+	model.JsonResponse(c, http.StatusOK, history, nodeAddress, "")
+}
+
+func (h *accountHandler) getInvoicePdf(c *gin.Context) {
+	nodeAddress, err := service.GetAddress()
+	if err != nil {
+		log.Error("error while retrieving node address: " + err.Error())
+		model.JsonResponse(c, http.StatusInternalServerError, nil, "", err.Error())
+		return
+	}
+
+	address, err := middleware.AddressFromBearer(c)
+	if err != nil {
+		log.Error("error while retrieving address from bearer: " + err.Error())
+		model.JsonResponse(c, http.StatusBadRequest, nil, nodeAddress, err.Error())
+		return
+	}
+
+	content, name, err := service.GetLicenseInvoicePdf(address, c.Param("id"))
+	if err != nil {
+		log.Error("error while retrieving invoice pdf: " + err.Error())
+		switch {
+		case errors.Is(err, service.ErrorInvoiceNotFound):
+			model.JsonResponse(c, http.StatusNotFound, nil, nodeAddress, err.Error())
+		case errors.Is(err, service.ErrorLicenseInvoiceNotIssued):
+			model.JsonResponse(c, http.StatusConflict, nil, nodeAddress, err.Error())
+		default:
+			model.JsonResponse(c, http.StatusBadGateway, nil, nodeAddress, err.Error())
+		}
+		return
+	}
+
+	c.Header("Content-Disposition", "attachment; filename="+name)
+	c.Data(http.StatusOK, "application/pdf", content)
+}
@@ -2,33 +2,81 @@ package handlers
 
 import (
 	"errors"
+	"fmt"
 	"io"
 	"net/http"
 	"slices"
+	"strconv"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/NaeuralEdgeProtocol/ratio1-backend/config"
 	"github.com/NaeuralEdgeProtocol/ratio1-backend/model"
 	"github.com/NaeuralEdgeProtocol/ratio1-backend/proxy/middleware"
+	"github.com/NaeuralEdgeProtocol/ratio1-backend/rpc"
 	"github.com/NaeuralEdgeProtocol/ratio1-backend/service"
 	"github.com/NaeuralEdgeProtocol/ratio1-backend/storage"
 
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
 )
 
 const (
-	adminBaseEndpoint  = "/admin"
-	newsLetterEndpoint = "/news"
+	adminBaseEndpoint             = "/admin"
+	newsLetterEndpoint            = "/news"
+	erasureRequestsEndpoint       = "/erasure-requests"
+	approveErasureRequestEndpoint = "/erasure-requests/:id/approve"
+	rejectErasureRequestEndpoint  = "/erasure-requests/:id/reject"
+	rpcStatusEndpoint             = "/rpc-status"
+	licensePurchasesEndpoint      = "/license-purchases"
+	retryLicensePurchaseEndpoint  = "/license-purchases/:id/retry"
+	cancelInvoiceEndpoint         = "/invoices/:id/cancel"
+	creditInvoiceEndpoint         = "/invoices/:id/credit-note"
+	invoiceHistoryEndpoint        = "/invoices/:id/history"
+	vatRatesEndpoint              = "/vat-rates"
+	viesChecksEndpoint            = "/vies-checks"
+	ossReportEndpoint             = "/oss-report"
 )
 
-type adminHandler struct{}
+type rejectErasureRequest struct {
+	Reason string `json:"reason"`
+}
+
+type invoiceCorrectionRequest struct {
+	Reason  string                       `json:"reason"`
+	Reissue bool                         `json:"reissue"`
+	Client  *service.InvoiceClientUpdate `json:"client"`
+}
+
+type vatRateRequest struct {
+	Country       string  `json:"country"`
+	Rate          float64 `json:"rate"`
+	EffectiveFrom string  `json:"effectiveFrom"`
+}
+
+type adminHandler struct {
+	repos *storage.Repositories
+}
 
-func NewAdminHandler(groupHandler *groupHandler) {
-	h := &adminHandler{}
+func NewAdminHandler(groupHandler *groupHandler, repos *storage.Repositories) {
+	h := &adminHandler{repos: repos}
 
 	endpoints := []EndpointHandler{
 		{Method: http.MethodPost, Path: newsLetterEndpoint, HandlerFunc: h.sendNewsLetterEmail},
+		{Method: http.MethodGet, Path: erasureRequestsEndpoint, HandlerFunc: h.getErasureRequests},
+		{Method: http.MethodPost, Path: approveErasureRequestEndpoint, HandlerFunc: h.approveErasureRequest},
+		{Method: http.MethodPost, Path: rejectErasureRequestEndpoint, HandlerFunc: h.rejectErasureRequest},
+		{Method: http.MethodGet, Path: rpcStatusEndpoint, HandlerFunc: h.getRpcStatus},
+		{Method: http.MethodGet, Path: licensePurchasesEndpoint, HandlerFunc: h.getLicensePurchases},
+		{Method: http.MethodPost, Path: retryLicensePurchaseEndpoint, HandlerFunc: h.retryLicensePurchase},
+		{Method: http.MethodPost, Path: cancelInvoiceEndpoint, HandlerFunc: h.cancelInvoice},
+		{Method: http.MethodPost, Path: creditInvoiceEndpoint, HandlerFunc: h.creditInvoice},
+		{Method: http.MethodGet, Path: invoiceHistoryEndpoint, HandlerFunc: h.getInvoiceHistory},
+		{Method: http.MethodGet, Path: vatRatesEndpoint, HandlerFunc: h.getVatRates},
+		{Method: http.MethodPost, Path: vatRatesEndpoint, HandlerFunc: h.saveVatRate},
+		{Method: http.MethodGet, Path: viesChecksEndpoint, HandlerFunc: h.getViesChecks},
+		{Method: http.MethodGet, Path: ossReportEndpoint, HandlerFunc: h.getOssReport},
 	}
 
 	endpointGroupHandler := EndpointGroupHandler{
@@ -91,7 +139,7 @@ func (h *adminHandler) sendNewsLetterEmail(c *gin.Context) {
 	}
 	htmlContent := string(contentBytes)
 
-	emails, err := storage.GetAllUsersEmails()
+	emails, err := h.repos.Kycs.GetAllUsersEmails()
 	if err != nil {
 		log.Error("error while retrieving all users emails: " + err.Error())
 		model.JsonResponse(c, http.StatusBadRequest, nil, nodeAddress, err.Error())
@@ -132,3 +180,391 @@ func (h *adminHandler) sendNewsLetterEmail(c *gin.Context) {
 	model.JsonResponse(c, http.StatusOK, emails, nodeAddress, "")
 
 }
+
+func (h *adminHandler) getErasureRequests(c *gin.Context) {
+	nodeAddress, _, ok := h.adminFromBearer(c)
+	if !ok {
+		return
+	}
+
+	requests, err := service.GetPendingAccountErasures()
+	if err != nil {
+		log.Error("error while retrieving account erasure requests: " + err.Error())
+		model.JsonResponse(c, http.StatusInternalServerError, nil, nodeAddress, err.Error())
+		return
+	}
+
+	model.JsonResponse(c, http.StatusOK, requests, nodeAddress, "")
+}
+
+func (h *adminHandler) approveErasureRequest(c *gin.Context) {
+	nodeAddress, adminAddress, ok := h.adminFromBearer(c)
+	if !ok {
+		return
+	}
+
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		log.Error("error while parsing erasure request id: " + err.Error())
+		model.JsonResponse(c, http.StatusBadRequest, nil, nodeAddress, "invalid erasure request id")
+		return
+	}
+
+	request, err := service.ApproveAccountErasure(id, adminAddress)
+	if err != nil {
+		log.Error("error while approving account erasure: " + err.Error())
+		model.JsonResponse(c, erasureErrorStatus(err), nil, nodeAddress, err.Error())
+		return
+	}
+
+	model.JsonResponse(c, http.StatusOK, request, nodeAddress, "")
+}
+
+func (h *adminHandler) rejectErasureRequest(c *gin.Context) {
+	nodeAddress, adminAddress, ok := h.adminFromBearer(c)
+	if !ok {
+		return
+	}
+
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		log.Error("error while parsing erasure request id: " + err.Error())
+		model.JsonResponse(c, http.StatusBadRequest, nil, nodeAddress, "invalid erasure request id")
+		return
+	}
+
+	req := rejectErasureRequest{}
+	err = c.Bind(&req)
+	if err != nil {
+		log.Error("error while binding request: " + err.Error())
+		model.JsonResponse(c, http.StatusBadRequest, nil, nodeAddress, err.Error())
+		return
+	}
+	if req.Reason == "" {
+		log.Error("empty rejection reason")
+		model.JsonResponse(c, http.StatusBadRequest, nil, nodeAddress, "rejection reason is required")
+		return
+	}
+
+	request, err := service.RejectAccountErasure(id, adminAddress, req.Reason)
+	if err != nil {
+		log.Error("error while rejecting account erasure: " + err.Error())
+		model.JsonResponse(c, erasureErrorStatus(err), nil, nodeAddress, err.Error())
+		return
+	}
+
+	model.JsonResponse(c, http.StatusOK, request, nodeAddress, "")
+}
+
+// getRpcStatus returns the health and the budget usage of the rpc providers
+// of this node.
+func (h *adminHandler) getRpcStatus(c *gin.Context) {
+	nodeAddress, _, ok := h.adminFromBearer(c)
+	if !ok {
+		return
+	}
+
+	model.JsonResponse(c, http.StatusOK, rpc.Status(), nodeAddress, "")
+}
+
+// getLicensePurchases returns the license purchases in the statuses of the
+// status query parameter, the pending and the dead letter ones by default.
+func (h *adminHandler) getLicensePurchases(c *gin.Context) {
+	nodeAddress, _, ok := h.adminFromBearer(c)
+	if !ok {
+		return
+	}
+
+	statuses := c.QueryArray("status")
+	if len(statuses) == 0 {
+		statuses = []string{model.LicensePurchaseStatusPending, model.LicensePurchaseStatusDeadLetter}
+	}
+
+	purchases, err := service.GetLicensePurchases(statuses...)
+	if err != nil {
+		log.Error("error while retrieving license purchases: " + err.Error())
+		model.JsonResponse(c, http.StatusInternalServerError, nil, nodeAddress, err.Error())
+		return
+	}
+
+	model.JsonResponse(c, http.StatusOK, purchases, nodeAddress, "")
+}
+
+func (h *adminHandler) retryLicensePurchase(c *gin.Context) {
+	nodeAddress, adminAddress, ok := h.adminFromBearer(c)
+	if !ok {
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		log.Error("error while parsing license purchase id: " + err.Error())
+		model.JsonResponse(c, http.StatusBadRequest, nil, nodeAddress, "invalid license purchase id")
+		return
+	}
+
+	purchase, err := service.RetryLicensePurchase(uint(id), adminAddress)
+	if err != nil {
+		log.Error("error while retrying license purchase: " + err.Error())
+		model.JsonResponse(c, licensePurchaseErrorStatus(err), nil, nodeAddress, err.Error())
+		return
+	}
+
+	model.JsonResponse(c, http.StatusOK, purchase, nodeAddress, "")
+}
+
+// cancelInvoice cancels an issued license invoice, and reissues it with the
+// corrected client data when asked to.
+func (h *adminHandler) cancelInvoice(c *gin.Context) {
+	h.correctInvoice(c, service.CancelInvoice)
+}
+
+// creditInvoice reverses an issued license invoice with a credit note, and
+// reissues it with the corrected client data when asked to.
+func (h *adminHandler) creditInvoice(c *gin.Context) {
+	h.correctInvoice(c, service.CreditInvoice)
+}
+
+func (h *adminHandler) correctInvoice(c *gin.Context, correct func(string, string, service.InvoiceCorrection) (*model.InvoiceClient, error)) {
+	nodeAddress, adminAddress, ok := h.adminFromBearer(c)
+	if !ok {
+		return
+	}
+
+	req := invoiceCorrectionRequest{}
+	err := c.Bind(&req)
+	if err != nil {
+		log.Error("error while binding request: " + err.Error())
+		model.JsonResponse(c, http.StatusBadRequest, nil, nodeAddress, err.Error())
+		return
+	}
+	if req.Reason == "" {
+		log.Error("empty correction reason")
+		model.JsonResponse(c, http.StatusBadRequest, nil, nodeAddress, "correction reason is required")
+		return
+	}
+
+	invoice, err := correct(c.Param("id"), adminAddress, service.InvoiceCorrection{
+		Reason:  req.Reason,
+		Reissue: req.Reissue,
+		Client:  req.Client,
+	})
+	if err != nil {
+		log.Error("error while correcting invoice: " + err.Error())
+		model.JsonResponse(c, invoiceErrorStatus(err), nil, nodeAddress, err.Error())
+		return
+	}
+
+	model.JsonResponse(c, http.StatusOK, invoice, nodeAddress, "")
+}
+
+func (h *adminHandler) getInvoiceHistory(c *gin.Context) {
+	nodeAddress, _, ok := h.adminFromBearer(c)
+	if !ok {
+		return
+	}
+
+	history, err := service.GetInvoiceStatusHistory(c.Param("id"))
+	if err != nil {
+		log.Error("error while retrieving invoice history: " + err.Error())
+		model.JsonResponse(c, invoiceErrorStatus(err), nil, nodeAddress, err.Error())
+		return
+	}
+
+	model.JsonResponse(c, http.StatusOK, history, nodeAddress, "")
+}
+
+// getVatRates returns every version of the VAT rates the engine uses.
+func (h *adminHandler) getVatRates(c *gin.Context) {
+	nodeAddress, _, ok := h.adminFromBearer(c)
+	if !ok {
+		return
+	}
+
+	rates, err := service.GetVatRates()
+	if err != nil {
+		log.Error("error while retrieving vat rates: " + err.Error())
+		model.JsonResponse(c, http.StatusInternalServerError, nil, nodeAddress, err.Error())
+		return
+	}
+
+	model.JsonResponse(c, http.StatusOK, rates, nodeAddress, "")
+}
+
+// saveVatRate adds a version of the rate of a country, effective from the
+// given day.
+func (h *adminHandler) saveVatRate(c *gin.Context) {
+	nodeAddress, adminAddress, ok := h.adminFromBearer(c)
+	if !ok {
+		return
+	}
+
+	req := vatRateRequest{}
+	err := c.Bind(&req)
+	if err != nil {
+		log.Error("error while binding request: " + err.Error())
+		model.JsonResponse(c, http.StatusBadRequest, nil, nodeAddress, err.Error())
+		return
+	}
+	effectiveFrom, err := time.Parse(time.DateOnly, req.EffectiveFrom)
+	if err != nil {
+		log.Error("error while parsing effective date: " + err.Error())
+		model.JsonResponse(c, http.StatusBadRequest, nil, nodeAddress, "effective date must be formatted as YYYY-MM-DD")
+		return
+	}
+
+	rate, err := service.SaveVatRate(req.Country, req.Rate, effectiveFrom, adminAddress)
+	if err != nil {
+		log.Error("error while saving vat rate: " + err.Error())
+		status := http.StatusInternalServerError
+		if errors.Is(err, service.ErrorVatRateInvalid) {
+			status = http.StatusBadRequest
+		}
+		model.JsonResponse(c, status, nil, nodeAddress, err.Error())
+		return
+	}
+
+	model.JsonResponse(c, http.StatusOK, rate, nodeAddress, "")
+}
+
+// getViesChecks returns the VIES answers stored for the VAT number of the
+// country and vatNumber query parameters, the evidence of its validity over
+// time.
+func (h *adminHandler) getViesChecks(c *gin.Context) {
+	nodeAddress, _, ok := h.adminFromBearer(c)
+	if !ok {
+		return
+	}
+
+	country, vatNumber := c.Query("country"), c.Query("vatNumber")
+	if country == "" || vatNumber == "" {
+		log.Error("missing country or vat number")
+		model.JsonResponse(c, http.StatusBadRequest, nil, nodeAddress, "country and vatNumber are required")
+		return
+	}
+
+	checks, err := service.GetViesChecks(country, vatNumber)
+	if err != nil {
+		log.Error("error while retrieving vies checks: " + err.Error())
+		model.JsonResponse(c, http.StatusInternalServerError, nil, nodeAddress, err.Error())
+		return
+	}
+
+	model.JsonResponse(c, http.StatusOK, checks, nodeAddress, "")
+}
+
+// getOssReport returns the OSS return of the year and quarter query
+// parameters, format csv or xml downloads it instead of the json report.
+func (h *adminHandler) getOssReport(c *gin.Context) {
+	nodeAddress, _, ok := h.adminFromBearer(c)
+	if !ok {
+		return
+	}
+
+	year, err := strconv.Atoi(c.Query("year"))
+	if err != nil {
+		log.Error("error while parsing year: " + err.Error())
+		model.JsonResponse(c, http.StatusBadRequest, nil, nodeAddress, "invalid year")
+		return
+	}
+	quarter, err := strconv.Atoi(c.Query("quarter"))
+	if err != nil {
+		log.Error("error while parsing quarter: " + err.Error())
+		model.JsonResponse(c, http.StatusBadRequest, nil, nodeAddress, "invalid quarter")
+		return
+	}
+
+	report, err := service.GetOssReport(year, quarter)
+	if err != nil {
+		log.Error("error while generating oss report: " + err.Error())
+		status := http.StatusInternalServerError
+		if errors.Is(err, service.ErrorOssReportInvalidPeriod) {
+			status = http.StatusBadRequest
+		}
+		model.JsonResponse(c, status, nil, nodeAddress, err.Error())
+		return
+	}
+
+	name := fmt.Sprintf("oss_report_%d_q%d", year, quarter)
+	switch c.Query("format") {
+	case "csv":
+		byteFile, err := service.GenerateOssReportCSV(report)
+		if err != nil {
+			log.Error("error while generating oss report csv: " + err.Error())
+			model.JsonResponse(c, http.StatusInternalServerError, nil, nodeAddress, err.Error())
+			return
+		}
+		c.Header("Content-Disposition", "attachment; filename="+name+".csv")
+		c.Data(http.StatusOK, "text/csv", byteFile)
+	case "xml":
+		byteFile, err := service.GenerateOssReportXML(report)
+		if err != nil {
+			log.Error("error while generating oss report xml: " + err.Error())
+			model.JsonResponse(c, http.StatusInternalServerError, nil, nodeAddress, err.Error())
+			return
+		}
+		c.Header("Content-Disposition", "attachment; filename="+name+".xml")
+		c.Data(http.StatusOK, "application/xml", byteFile)
+	default:
+		model.JsonResponse(c, http.StatusOK, report, nodeAddress, "")
+	}
+}
+
+// adminFromBearer returns the node address and the caller address, it writes
+// the error response and returns false when the caller is not an admin.
+func (h *adminHandler) adminFromBearer(c *gin.Context) (string, string, bool) {
+	nodeAddress, err := service.GetAddress()
+	if err != nil {
+		log.Error("error while retrieving node address: " + err.Error())
+		model.JsonResponse(c, http.StatusInternalServerError, nil, "", err.Error())
+		return "", "", false
+	}
+
+	userAddress, err := middleware.AddressFromBearer(c)
+	if err != nil {
+		log.Error("error while retrieving address from bearer: " + err.Error())
+		model.JsonResponse(c, http.StatusBadRequest, nil, nodeAddress, err.Error())
+		return "", "", false
+	}
+
+	if !slices.Contains(config.Config.AdminAddresses, userAddress) {
+		log.Error("user: " + userAddress + " is not an admin")
+		model.JsonResponse(c, http.StatusUnauthorized, nil, nodeAddress, "user is not an admin")
+		return "", "", false
+	}
+
+	return nodeAddress, userAddress, true
+}
+
+func erasureErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, service.ErrorAccountErasureNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, service.ErrorAccountErasureNotReviewable):
+		return http.StatusConflict
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+func licensePurchaseErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, service.ErrorLicensePurchaseNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, service.ErrorLicensePurchaseNotRetryable):
+		return http.StatusConflict
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+func invoiceErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, service.ErrorInvoiceNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, service.ErrorInvoiceNotCorrectable):
+		return http.StatusConflict
+	default:
+		return http.StatusInternalServerError
+	}
+}
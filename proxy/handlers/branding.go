@@ -45,10 +45,12 @@ type ParsedBrands struct {
 	Links       map[string]string `json:"links"`
 }
 
-type brandingHandler struct{}
+type brandingHandler struct {
+	repos *storage.Repositories
+}
 
-func NewBrandingHandler(groupHandler *groupHandler) {
-	h := brandingHandler{}
+func NewBrandingHandler(groupHandler *groupHandler, repos *storage.Repositories) {
+	h := brandingHandler{repos: repos}
 
 	//pub endpoiints
 	pubEndpoints := []EndpointHandler{
@@ -104,7 +106,7 @@ func (h *brandingHandler) editBrand(c *gin.Context) {
 		return
 	}
 
-	brand, err := storage.GetBrandByAddress(userAddress)
+	brand, err := h.repos.Brandings.GetByAddress(userAddress)
 	if err != nil {
 		err = errors.New("error while retrieving brand: " + err.Error())
 		log.Error(err.Error())
@@ -134,7 +136,7 @@ func (h *brandingHandler) editBrand(c *gin.Context) {
 		return
 	}
 
-	err = storage.SaveBrand(brand)
+	err = h.repos.Brandings.Save(brand)
 	if err != nil {
 		err = errors.New("error while saving brand: " + err.Error())
 		log.Error(err.Error())
@@ -188,7 +190,7 @@ func (h *brandingHandler) editBrandLogo(c *gin.Context) {
 	}
 	defer fileReader.Close()
 
-	brand, err := storage.GetBrandByAddress(userAddress)
+	brand, err := h.repos.Brandings.GetByAddress(userAddress)
 	if err != nil {
 		err = errors.New("error while retrieving brand: " + err.Error())
 		log.Error(err.Error())
@@ -209,7 +211,7 @@ func (h *brandingHandler) editBrandLogo(c *gin.Context) {
 		return
 	}
 
-	err = storage.SaveBrand(brand)
+	err = h.repos.Brandings.Save(brand)
 	if err != nil {
 		err = errors.New("error while saving brand: " + err.Error())
 		log.Error(err.Error())
@@ -240,7 +242,7 @@ func (h *brandingHandler) getBrands(c *gin.Context) {
 	var response getBrandsResponse
 	for _, a := range req.Addresses {
 		a = strings.ToLower(a)
-		b, err := storage.GetBrandByAddress(a)
+		b, err := h.repos.Brandings.GetByAddress(a)
 		if err != nil {
 			err = errors.New("error while retrieving brand: " + err.Error())
 			log.Error(err.Error())
@@ -286,7 +288,7 @@ func (h *brandingHandler) getBrandLogo(c *gin.Context) {
 	}
 	address = strings.ToLower(address)
 
-	brand, err := storage.GetBrandByAddress(address)
+	brand, err := h.repos.Brandings.GetByAddress(address)
 	if err != nil {
 		err = errors.New("error while retrieving brand: " + err.Error())
 		log.Error(err.Error())
@@ -32,10 +32,12 @@ type burnReportStruct struct {
 	TotalR1Amount     float64   `json:"totalR1Amount"`
 }
 
-type burnReportHandler struct{}
+type burnReportHandler struct {
+	repos *storage.Repositories
+}
 
-func NewBurnReportHandler(groupHandler *groupHandler) {
-	h := &burnReportHandler{}
+func NewBurnReportHandler(groupHandler *groupHandler, repos *storage.Repositories) {
+	h := &burnReportHandler{repos: repos}
 
 	endpoints := []EndpointHandler{
 		{Method: http.MethodGet, Path: getCspBurnReportEndpoint, HandlerFunc: h.getBurnReport},
@@ -122,7 +124,7 @@ func (h *burnReportHandler) getBurnReport(c *gin.Context) {
 		model.JsonResponse(c, http.StatusOK, response, nodeAddress, "")
 	}
 
-	burnEvents, err := storage.GetBurnEventsByOwnerAddress(userAddress)
+	burnEvents, err := h.repos.BurnEvents.GetByOwnerAddress(userAddress)
 	if err != nil {
 		log.Error("error while retrieving report: " + err.Error())
 		model.JsonResponse(c, http.StatusBadRequest, nil, nodeAddress, err.Error())
@@ -219,7 +221,7 @@ func (h *burnReportHandler) downloadBurnReport(c *gin.Context) {
 		return
 	}
 
-	burnEvents, err := storage.GetBurnEventsForUserInTimeRange(startTime, endTime, userAddress)
+	burnEvents, err := h.repos.BurnEvents.GetForUserInTimeRange(startTime, endTime, userAddress)
 	if err != nil {
 		log.Error("error while retrieving report: " + err.Error())
 		model.JsonResponse(c, http.StatusBadRequest, nil, nodeAddress, err.Error())
@@ -301,7 +303,7 @@ func (h *burnReportHandler) downloadBurnReportJSON(c *gin.Context) {
 		return
 	}
 
-	burnEvents, err := storage.GetBurnEventsForUserInTimeRange(startTime, endTime, userAddress)
+	burnEvents, err := h.repos.BurnEvents.GetForUserInTimeRange(startTime, endTime, userAddress)
 	if err != nil {
 		log.Error("error while retrieving report: " + err.Error())
 		model.JsonResponse(c, http.StatusBadRequest, nil, nodeAddress, err.Error())
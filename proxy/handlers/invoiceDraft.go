@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
 	"time"
 
@@ -20,44 +21,95 @@ const (
 	getNodeOwnerDraftListEndpoint      = "/get-drafts"
 	downloadNodeOwnerDraftEndpoint     = "/download-draft"
 	downloadNodeOwnerDraftJSONEndpoint = "/download-draft-json"
+	downloadDraftPdfEndpoint           = "/download-draft-pdf"
+	downloadDraftUblEndpoint           = "/download-draft-ubl"
 	createPreferenceEndpoint           = "/create-preferences"
 	changePreferencesEndpoint          = "/change-preferences"
 	getPreferencesEndpoint             = "/get-preferences"
+	getSeriesEndpoint                  = "/get-series"
+	createSeriesEndpoint               = "/create-series"
+	changeSeriesEndpoint               = "/change-series"
+	getSeriesReportEndpoint            = "/get-series-report"
 
 	/* CSP endpoints */
 	getCspDraftListEndpoint      = "/get-csp-drafts"
 	downloadCspDraftEndpoint     = "/download-csp-draft"
 	downloadCspDraftJSONEndpoint = "/download-csp-draft-json"
+
+	/* Lifecycle endpoints, for both parties of a draft */
+	finalizeDraftEndpoint    = "/finalize-draft"
+	acknowledgeDraftEndpoint = "/acknowledge-draft"
+	markDraftPaidEndpoint    = "/mark-draft-paid"
+	disputeDraftEndpoint     = "/dispute-draft"
+	voidDraftEndpoint        = "/void-draft"
+	getDraftHistoryEndpoint  = "/get-draft-history"
+	regenerateDraftEndpoint  = "/regenerate-draft"
 )
 
 type getInvoiceDraftsResponse struct {
-	DraftId           uuid.UUID `json:"draftId"`
-	CreationTimestamp time.Time `json:"creationTimestamp"`
-	UserAddress       string    `json:"userAddress"`
-	CspOwner          string    `json:"cspOwnerAddress"`
-	TotalUsdcAmount   float64   `json:"totalUsdcAmount"`
-	InvoiceSeries     string    `json:"invoiceSeries"`
-	InvoiceNumber     int       `json:"invoiceNumber"`
-	NodeOwnerName     string    `json:"nodeOwnerName"`
-	CspOwnerName      string    `json:"cspOwnerName"`
+	DraftId           uuid.UUID  `json:"draftId"`
+	CreationTimestamp time.Time  `json:"creationTimestamp"`
+	UserAddress       string     `json:"userAddress"`
+	CspOwner          string     `json:"cspOwnerAddress"`
+	TotalUsdcAmount   float64    `json:"totalUsdcAmount"`
+	InvoiceSeries     string     `json:"invoiceSeries"`
+	InvoiceNumber     int        `json:"invoiceNumber"`
+	NodeOwnerName     string     `json:"nodeOwnerName"`
+	CspOwnerName      string     `json:"cspOwnerName"`
+	Status            string     `json:"status"`
+	Version           int        `json:"version"`
+	PreviousVersionId *uuid.UUID `json:"previousVersionId"`
+	SupersededBy      *uuid.UUID `json:"supersededBy"`
 }
 
-type invoiceDraftHandler struct{}
+type invoiceSeriesRequest struct {
+	Name        string `json:"name" binding:"required"`
+	NextNumber  int    `json:"nextNumber"`
+	YearlyReset bool   `json:"yearlyReset"`
+}
 
-func NewInvoiceDraftHandler(groupHandler *groupHandler) {
-	h := &invoiceDraftHandler{}
+type regenerateDraftRequest struct {
+	DraftId   string `json:"draftId" binding:"required"`
+	NewNumber bool   `json:"newNumber"`
+}
+
+type draftTransitionRequest struct {
+	DraftId   string `json:"draftId" binding:"required"`
+	Reference string `json:"reference"`
+	Reason    string `json:"reason"`
+}
+
+type invoiceDraftHandler struct {
+	repos *storage.Repositories
+}
+
+func NewInvoiceDraftHandler(groupHandler *groupHandler, repos *storage.Repositories) {
+	h := &invoiceDraftHandler{repos: repos}
 
 	endpoints := []EndpointHandler{
 		{Method: http.MethodGet, Path: getNodeOwnerDraftListEndpoint, HandlerFunc: h.getNodeOwnerDraftList},
 		{Method: http.MethodGet, Path: getCspDraftListEndpoint, HandlerFunc: h.getCspDraftList},
 		{Method: http.MethodGet, Path: getPreferencesEndpoint, HandlerFunc: h.getPreferences},
+		{Method: http.MethodGet, Path: getSeriesEndpoint, HandlerFunc: h.getSeries},
+		{Method: http.MethodGet, Path: getSeriesReportEndpoint, HandlerFunc: h.getSeriesReport},
 		{Method: http.MethodGet, Path: downloadNodeOwnerDraftEndpoint, HandlerFunc: h.downloadNodeOwnerDraft},
 		{Method: http.MethodGet, Path: downloadNodeOwnerDraftJSONEndpoint, HandlerFunc: h.downloadNodeOwnerDraftJSON},
+		{Method: http.MethodGet, Path: downloadDraftPdfEndpoint, HandlerFunc: h.downloadDraftPdf},
+		{Method: http.MethodGet, Path: downloadDraftUblEndpoint, HandlerFunc: h.downloadDraftUbl},
 		{Method: http.MethodGet, Path: downloadCspDraftEndpoint, HandlerFunc: h.downloadCspDraft},
 		{Method: http.MethodGet, Path: downloadCspDraftJSONEndpoint, HandlerFunc: h.downloadCspDraftJSON},
+		{Method: http.MethodGet, Path: getDraftHistoryEndpoint, HandlerFunc: h.getDraftHistory},
 
 		{Method: http.MethodPost, Path: changePreferencesEndpoint, HandlerFunc: h.changePreferences},
 		{Method: http.MethodPost, Path: createPreferenceEndpoint, HandlerFunc: h.createPreferences},
+		{Method: http.MethodPost, Path: createSeriesEndpoint, HandlerFunc: h.createSeries},
+		{Method: http.MethodPost, Path: changeSeriesEndpoint, HandlerFunc: h.changeSeries},
+		{Method: http.MethodPost, Path: finalizeDraftEndpoint, HandlerFunc: h.finalizeDraft},
+		{Method: http.MethodPost, Path: acknowledgeDraftEndpoint, HandlerFunc: h.acknowledgeDraft},
+		{Method: http.MethodPost, Path: markDraftPaidEndpoint, HandlerFunc: h.markDraftPaid},
+		{Method: http.MethodPost, Path: disputeDraftEndpoint, HandlerFunc: h.disputeDraft},
+		{Method: http.MethodPost, Path: voidDraftEndpoint, HandlerFunc: h.voidDraft},
+		{Method: http.MethodPost, Path: regenerateDraftEndpoint, HandlerFunc: h.regenerateDraft},
 	}
 
 	endpointGroupHandler := EndpointGroupHandler{
@@ -87,9 +139,15 @@ func (h *invoiceDraftHandler) getNodeOwnerDraftList(c *gin.Context) {
 		return
 	}
 
+	status, ok := draftStatusFilter(c, nodeAddress)
+	if !ok {
+		return
+	}
+
 	if config.Config.Api.DevTesting {
 		service.BuildMocks()
 		i, _ := service.GetMockOperatorData()
+		i = service.FilterDraftsByStatus(i, status)
 		userName, _ := i[0].UserProfile.GetNameAsString()
 		var parsedDraft []getInvoiceDraftsResponse
 		for _, d := range i {
@@ -107,6 +165,10 @@ func (h *invoiceDraftHandler) getNodeOwnerDraftList(c *gin.Context) {
 				InvoiceNumber:     d.InvoiceNumber,
 				NodeOwnerName:     userName,
 				CspOwnerName:      cspName,
+				Status:            d.CurrentStatus(),
+				Version:           d.CurrentVersion(),
+				PreviousVersionId: d.PreviousVersionId,
+				SupersededBy:      d.SupersededBy,
 			}
 			parsedDraft = append(parsedDraft, newParsedDraft)
 		}
@@ -121,24 +183,26 @@ func (h *invoiceDraftHandler) getNodeOwnerDraftList(c *gin.Context) {
 		return
 	}
 
-	drafts, err := storage.GetDraftListByNodeOwner(userAddress)
+	drafts, err := h.repos.Drafts.GetListByNodeOwner(userAddress)
 	if err != nil {
 		log.Error("error while retrieving report: " + err.Error())
 		model.JsonResponse(c, http.StatusBadRequest, nil, nodeAddress, err.Error())
 		return
 	}
+	drafts = service.FilterDraftsByStatus(drafts, status)
 
 	parsedDraft := []getInvoiceDraftsResponse{}
 	if len(drafts) == 0 {
 		model.JsonResponse(c, http.StatusOK, parsedDraft, nodeAddress, "")
 		return
 	}
-	userName, _ := drafts[0].UserProfile.GetNameAsString() //it's always the same
 	for _, d := range drafts {
 		if d.UserAddress == d.CspOwner {
 			continue
 		}
-		cspName, _ := d.CspProfile.GetNameAsString()
+		seller, buyer := d.Seller(), d.Buyer()
+		userName, _ := seller.GetNameAsString()
+		cspName, _ := buyer.GetNameAsString()
 		newParsedDraft := getInvoiceDraftsResponse{
 			DraftId:           d.DraftId,
 			CreationTimestamp: d.CreationTimestamp,
@@ -149,6 +213,10 @@ func (h *invoiceDraftHandler) getNodeOwnerDraftList(c *gin.Context) {
 			InvoiceNumber:     d.InvoiceNumber,
 			NodeOwnerName:     userName,
 			CspOwnerName:      cspName,
+			Status:            d.CurrentStatus(),
+			Version:           d.CurrentVersion(),
+			PreviousVersionId: d.PreviousVersionId,
+			SupersededBy:      d.SupersededBy,
 		}
 		parsedDraft = append(parsedDraft, newParsedDraft)
 	}
@@ -164,9 +232,15 @@ func (h *invoiceDraftHandler) getCspDraftList(c *gin.Context) {
 		return
 	}
 
+	status, ok := draftStatusFilter(c, nodeAddress)
+	if !ok {
+		return
+	}
+
 	if config.Config.Api.DevTesting {
 		service.BuildMocks()
 		i, _ := service.GetMockCspData()
+		i = service.FilterDraftsByStatus(i, status)
 		var parsedDraft []getInvoiceDraftsResponse
 		cspName, _ := i[0].CspProfile.GetNameAsString() //it's always the same
 		for _, d := range i {
@@ -184,6 +258,10 @@ func (h *invoiceDraftHandler) getCspDraftList(c *gin.Context) {
 				InvoiceNumber:     d.InvoiceNumber,
 				NodeOwnerName:     userName,
 				CspOwnerName:      cspName,
+				Status:            d.CurrentStatus(),
+				Version:           d.CurrentVersion(),
+				PreviousVersionId: d.PreviousVersionId,
+				SupersededBy:      d.SupersededBy,
 			}
 			parsedDraft = append(parsedDraft, newParsedDraft)
 		}
@@ -198,24 +276,26 @@ func (h *invoiceDraftHandler) getCspDraftList(c *gin.Context) {
 		return
 	}
 
-	drafts, err := storage.GetDraftListByCSP(userAddress)
+	drafts, err := h.repos.Drafts.GetListByCSP(userAddress)
 	if err != nil {
 		log.Error("error while retrieving report: " + err.Error())
 		model.JsonResponse(c, http.StatusBadRequest, nil, nodeAddress, err.Error())
 		return
 	}
+	drafts = service.FilterDraftsByStatus(drafts, status)
 
 	parsedDraft := []getInvoiceDraftsResponse{}
 	if len(drafts) == 0 {
 		model.JsonResponse(c, http.StatusOK, parsedDraft, nodeAddress, "")
 		return
 	}
-	cspName, _ := drafts[0].CspProfile.GetNameAsString() //it's always the same
 	for _, d := range drafts {
 		if d.UserAddress == d.CspOwner {
 			continue
 		}
-		userName, _ := d.UserProfile.GetNameAsString()
+		seller, buyer := d.Seller(), d.Buyer()
+		userName, _ := seller.GetNameAsString()
+		cspName, _ := buyer.GetNameAsString()
 		newParsedDraft := getInvoiceDraftsResponse{
 			DraftId:           d.DraftId,
 			CreationTimestamp: d.CreationTimestamp,
@@ -226,6 +306,10 @@ func (h *invoiceDraftHandler) getCspDraftList(c *gin.Context) {
 			InvoiceNumber:     d.InvoiceNumber,
 			NodeOwnerName:     userName,
 			CspOwnerName:      cspName,
+			Status:            d.CurrentStatus(),
+			Version:           d.CurrentVersion(),
+			PreviousVersionId: d.PreviousVersionId,
+			SupersededBy:      d.SupersededBy,
 		}
 		parsedDraft = append(parsedDraft, newParsedDraft)
 	}
@@ -233,6 +317,17 @@ func (h *invoiceDraftHandler) getCspDraftList(c *gin.Context) {
 	model.JsonResponse(c, http.StatusOK, parsedDraft, nodeAddress, "")
 }
 
+// draftStatusFilter reads the optional status query of the draft lists.
+func draftStatusFilter(c *gin.Context, nodeAddress string) (string, bool) {
+	status := c.Query("status")
+	if status != "" && !service.IsDraftStatus(status) {
+		log.Error("invalid draft status filter: " + status)
+		model.JsonResponse(c, http.StatusBadRequest, nil, nodeAddress, "invalid draft status: "+status)
+		return "", false
+	}
+	return status, true
+}
+
 func (h *invoiceDraftHandler) downloadNodeOwnerDraft(c *gin.Context) {
 	nodeAddress, err := service.GetAddress()
 	if err != nil {
@@ -282,14 +377,14 @@ func (h *invoiceDraftHandler) downloadNodeOwnerDraft(c *gin.Context) {
 		return
 	}
 
-	drafts, err := storage.GetDraftByReportId(draftId, userAddress)
+	drafts, err := h.repos.Drafts.GetByReportId(draftId, userAddress)
 	if err != nil {
 		log.Error("error while retrieving report: " + err.Error())
 		model.JsonResponse(c, http.StatusBadRequest, nil, nodeAddress, err.Error())
 		return
 	}
 
-	allocations, err := storage.GetAllocationsByDraftId(draftId)
+	allocations, err := service.GetDraftAllocations(*drafts)
 	if err != nil {
 		log.Error("error while retrieving allocations: " + err.Error())
 		model.JsonResponse(c, http.StatusBadRequest, nil, nodeAddress, err.Error())
@@ -307,6 +402,122 @@ func (h *invoiceDraftHandler) downloadNodeOwnerDraft(c *gin.Context) {
 	c.Data(http.StatusOK, "application/msword", byteFile)
 }
 
+// downloadDraftPdf serves the draft as pdf to both its node owner and its csp.
+func (h *invoiceDraftHandler) downloadDraftPdf(c *gin.Context) {
+	nodeAddress, err := service.GetAddress()
+	if err != nil {
+		log.Error("error while retrieving node address: " + err.Error())
+		model.JsonResponse(c, http.StatusInternalServerError, nil, "", err.Error())
+		return
+	}
+
+	draft, allocations, ok := h.getPartyDraft(c, nodeAddress)
+	if !ok {
+		return
+	}
+
+	byteFile, err := service.RenderInvoiceDraftPdf(*draft, allocations)
+	if err != nil {
+		log.Error("error while generating invoice pdf: " + err.Error())
+		model.JsonResponse(c, http.StatusInternalServerError, nil, nodeAddress, err.Error())
+		return
+	}
+
+	c.Header("Content-Disposition", "attachment; filename=invoice_draft.pdf")
+	c.Data(http.StatusOK, "application/pdf", byteFile)
+}
+
+// downloadDraftUbl serves the draft as an UBL e-invoice to both its node
+// owner and its csp.
+func (h *invoiceDraftHandler) downloadDraftUbl(c *gin.Context) {
+	nodeAddress, err := service.GetAddress()
+	if err != nil {
+		log.Error("error while retrieving node address: " + err.Error())
+		model.JsonResponse(c, http.StatusInternalServerError, nil, "", err.Error())
+		return
+	}
+
+	draft, allocations, ok := h.getPartyDraft(c, nodeAddress)
+	if !ok {
+		return
+	}
+
+	byteFile, err := service.ExportInvoiceDraftUbl(*draft, allocations)
+	if err != nil {
+		log.Error("error while generating invoice xml: " + err.Error())
+		if errors.Is(err, service.ErrorUblMissingData) {
+			model.JsonResponse(c, http.StatusUnprocessableEntity, nil, nodeAddress, err.Error())
+			return
+		}
+		model.JsonResponse(c, http.StatusInternalServerError, nil, nodeAddress, err.Error())
+		return
+	}
+
+	c.Header("Content-Disposition", "attachment; filename=invoice_draft.xml")
+	c.Data(http.StatusOK, "application/xml", byteFile)
+}
+
+// getPartyDraft returns the draft of the draftId query with its allocations
+// when the caller is its node owner or its csp, otherwise it writes the error
+// response.
+func (h *invoiceDraftHandler) getPartyDraft(c *gin.Context, nodeAddress string) (*model.InvoiceDraft, []model.Allocation, bool) {
+	draftId, ok := c.GetQuery("draftId")
+	if !ok || draftId == "" {
+		log.Error("draft id not received")
+		model.JsonResponse(c, http.StatusBadRequest, nil, nodeAddress, "draft id not received")
+		return nil, nil, false
+	}
+
+	if config.Config.Api.DevTesting {
+		service.BuildMocks()
+		operatorDrafts, operatorAllocations := service.GetMockOperatorData()
+		cspDrafts, cspAllocations := service.GetMockCspData()
+		for _, v := range operatorDrafts {
+			if v.DraftId.String() == draftId {
+				return &v, operatorAllocations, true
+			}
+		}
+		for _, v := range cspDrafts {
+			if v.DraftId.String() == draftId {
+				return &v, cspAllocations, true
+			}
+		}
+		log.Error("draft id not found in storage")
+		model.JsonResponse(c, http.StatusInternalServerError, nil, nodeAddress, "draft id not found in storage")
+		return nil, nil, false
+	}
+
+	userAddress, err := middleware.AddressFromBearer(c)
+	if err != nil {
+		log.Error("error while retrieving address from bearer: " + err.Error())
+		model.JsonResponse(c, http.StatusBadRequest, nil, nodeAddress, err.Error())
+		return nil, nil, false
+	}
+
+	draft, err := h.repos.Drafts.GetByReportId(draftId, userAddress)
+	if err == nil && draft.DraftId == uuid.Nil {
+		draft, err = h.repos.Drafts.GetCspByReportId(draftId, userAddress)
+	}
+	if err != nil {
+		log.Error("error while retrieving report: " + err.Error())
+		model.JsonResponse(c, http.StatusBadRequest, nil, nodeAddress, err.Error())
+		return nil, nil, false
+	}
+	if draft.DraftId == uuid.Nil {
+		log.Error("draft id not found in storage")
+		model.JsonResponse(c, http.StatusNotFound, nil, nodeAddress, "draft id not found in storage")
+		return nil, nil, false
+	}
+
+	allocations, err := service.GetDraftAllocations(*draft)
+	if err != nil {
+		log.Error("error while retrieving allocations: " + err.Error())
+		model.JsonResponse(c, http.StatusBadRequest, nil, nodeAddress, err.Error())
+		return nil, nil, false
+	}
+	return draft, allocations, true
+}
+
 func (h *invoiceDraftHandler) downloadNodeOwnerDraftJSON(c *gin.Context) {
 	nodeAddress, err := service.GetAddress()
 	if err != nil {
@@ -355,14 +566,14 @@ func (h *invoiceDraftHandler) downloadNodeOwnerDraftJSON(c *gin.Context) {
 		return
 	}
 
-	drafts, err := storage.GetDraftByReportId(draftId, userAddress)
+	drafts, err := h.repos.Drafts.GetByReportId(draftId, userAddress)
 	if err != nil {
 		log.Error("error while retrieving report: " + err.Error())
 		model.JsonResponse(c, http.StatusBadRequest, nil, nodeAddress, err.Error())
 		return
 	}
 
-	allocations, err := storage.GetAllocationsByDraftId(draftId)
+	allocations, err := service.GetDraftAllocations(*drafts)
 	if err != nil {
 		log.Error("error while retrieving allocations: " + err.Error())
 		model.JsonResponse(c, http.StatusBadRequest, nil, nodeAddress, err.Error())
@@ -429,14 +640,14 @@ func (h *invoiceDraftHandler) downloadCspDraft(c *gin.Context) {
 		return
 	}
 
-	drafts, err := storage.GetCspDraftByReportId(draftId, userAddress)
+	drafts, err := h.repos.Drafts.GetCspByReportId(draftId, userAddress)
 	if err != nil {
 		log.Error("error while retrieving report: " + err.Error())
 		model.JsonResponse(c, http.StatusBadRequest, nil, nodeAddress, err.Error())
 		return
 	}
 
-	allocations, err := storage.GetAllocationsByDraftId(draftId)
+	allocations, err := service.GetDraftAllocations(*drafts)
 	if err != nil {
 		log.Error("error while retrieving allocations: " + err.Error())
 		model.JsonResponse(c, http.StatusBadRequest, nil, nodeAddress, err.Error())
@@ -504,14 +715,14 @@ func (h *invoiceDraftHandler) downloadCspDraftJSON(c *gin.Context) {
 		return
 	}
 
-	drafts, err := storage.GetCspDraftByReportId(draftId, userAddress)
+	drafts, err := h.repos.Drafts.GetCspByReportId(draftId, userAddress)
 	if err != nil {
 		log.Error("error while retrieving report: " + err.Error())
 		model.JsonResponse(c, http.StatusBadRequest, nil, nodeAddress, err.Error())
 		return
 	}
 
-	allocations, err := storage.GetAllocationsByDraftId(draftId)
+	allocations, err := service.GetDraftAllocations(*drafts)
 	if err != nil {
 		log.Error("error while retrieving allocations: " + err.Error())
 		model.JsonResponse(c, http.StatusBadRequest, nil, nodeAddress, err.Error())
@@ -542,7 +753,7 @@ func (h *invoiceDraftHandler) getPreferences(c *gin.Context) {
 		return
 	}
 
-	preference, err := storage.GetPreferenceByAddress(userAddress)
+	preference, err := h.repos.Preferences.GetByAddress(userAddress)
 	if err != nil {
 		log.Error("error while retrieving report: " + err.Error())
 		model.JsonResponse(c, http.StatusBadRequest, nil, nodeAddress, err.Error())
@@ -591,10 +802,10 @@ func (h *invoiceDraftHandler) createPreferences(c *gin.Context) {
 	}
 	pref.UserAddress = userAddress
 
-	err = storage.CreatePreference(&pref)
+	err = service.CreatePreference(&pref)
 	if err != nil {
 		log.Error("error while updating preference: " + err.Error())
-		model.JsonResponse(c, http.StatusBadRequest, nil, nodeAddress, err.Error())
+		model.JsonResponse(c, invoiceSeriesErrorStatus(err), nil, nodeAddress, err.Error())
 		return
 	}
 
@@ -629,12 +840,294 @@ func (h *invoiceDraftHandler) changePreferences(c *gin.Context) {
 		return
 	}
 
-	err = storage.UpdatePreference(&pref)
+	err = service.ChangePreference(&pref)
 	if err != nil {
 		log.Error("error while updating preference: " + err.Error())
-		model.JsonResponse(c, http.StatusBadRequest, nil, nodeAddress, err.Error())
+		model.JsonResponse(c, invoiceSeriesErrorStatus(err), nil, nodeAddress, err.Error())
 		return
 	}
 
 	model.JsonResponse(c, http.StatusOK, nil, nodeAddress, "")
 }
+
+func (h *invoiceDraftHandler) finalizeDraft(c *gin.Context) {
+	h.transitionDraft(c, func(address string, req draftTransitionRequest) (*model.InvoiceDraft, error) {
+		return service.FinalizeDraft(address, req.DraftId)
+	})
+}
+
+func (h *invoiceDraftHandler) acknowledgeDraft(c *gin.Context) {
+	h.transitionDraft(c, func(address string, req draftTransitionRequest) (*model.InvoiceDraft, error) {
+		return service.AcknowledgeDraft(address, req.DraftId)
+	})
+}
+
+func (h *invoiceDraftHandler) markDraftPaid(c *gin.Context) {
+	h.transitionDraft(c, func(address string, req draftTransitionRequest) (*model.InvoiceDraft, error) {
+		return service.MarkDraftPaid(address, req.DraftId, req.Reference)
+	})
+}
+
+func (h *invoiceDraftHandler) disputeDraft(c *gin.Context) {
+	h.transitionDraft(c, func(address string, req draftTransitionRequest) (*model.InvoiceDraft, error) {
+		return service.DisputeDraft(address, req.DraftId, req.Reason)
+	})
+}
+
+func (h *invoiceDraftHandler) voidDraft(c *gin.Context) {
+	h.transitionDraft(c, func(address string, req draftTransitionRequest) (*model.InvoiceDraft, error) {
+		return service.VoidDraft(address, req.DraftId, req.Reason)
+	})
+}
+
+func (h *invoiceDraftHandler) transitionDraft(c *gin.Context, transition func(address string, req draftTransitionRequest) (*model.InvoiceDraft, error)) {
+	nodeAddress, err := service.GetAddress()
+	if err != nil {
+		log.Error("error while retrieving node address: " + err.Error())
+		model.JsonResponse(c, http.StatusInternalServerError, nil, "", err.Error())
+		return
+	}
+
+	userAddress, err := middleware.AddressFromBearer(c)
+	if err != nil {
+		log.Error("error while retrieving address from bearer: " + err.Error())
+		model.JsonResponse(c, http.StatusBadRequest, nil, nodeAddress, err.Error())
+		return
+	}
+
+	var req draftTransitionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		log.Error("error while binding json: " + err.Error())
+		model.JsonResponse(c, http.StatusBadRequest, nil, nodeAddress, "error while binding json: "+err.Error())
+		return
+	}
+
+	draft, err := transition(userAddress, req)
+	if err != nil {
+		log.Error("error while changing draft status: " + err.Error())
+		model.JsonResponse(c, draftLifecycleErrorStatus(err), nil, nodeAddress, err.Error())
+		return
+	}
+
+	model.JsonResponse(c, http.StatusOK, draftSummary(draft), nodeAddress, "")
+}
+
+func (h *invoiceDraftHandler) regenerateDraft(c *gin.Context) {
+	nodeAddress, err := service.GetAddress()
+	if err != nil {
+		log.Error("error while retrieving node address: " + err.Error())
+		model.JsonResponse(c, http.StatusInternalServerError, nil, "", err.Error())
+		return
+	}
+
+	userAddress, err := middleware.AddressFromBearer(c)
+	if err != nil {
+		log.Error("error while retrieving address from bearer: " + err.Error())
+		model.JsonResponse(c, http.StatusBadRequest, nil, nodeAddress, err.Error())
+		return
+	}
+
+	var req regenerateDraftRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		log.Error("error while binding json: " + err.Error())
+		model.JsonResponse(c, http.StatusBadRequest, nil, nodeAddress, "error while binding json: "+err.Error())
+		return
+	}
+
+	draft, err := service.RegenerateDraft(userAddress, req.DraftId, req.NewNumber)
+	if err != nil {
+		log.Error("error while regenerating draft: " + err.Error())
+		model.JsonResponse(c, draftLifecycleErrorStatus(err), nil, nodeAddress, err.Error())
+		return
+	}
+
+	model.JsonResponse(c, http.StatusOK, draftSummary(draft), nodeAddress, "")
+}
+
+func (h *invoiceDraftHandler) getDraftHistory(c *gin.Context) {
+	nodeAddress, err := service.GetAddress()
+	if err != nil {
+		log.Error("error while retrieving node address: " + err.Error())
+		model.JsonResponse(c, http.StatusInternalServerError, nil, "", err.Error())
+		return
+	}
+
+	userAddress, err := middleware.AddressFromBearer(c)
+	if err != nil {
+		log.Error("error while retrieving address from bearer: " + err.Error())
+		model.JsonResponse(c, http.StatusBadRequest, nil, nodeAddress, err.Error())
+		return
+	}
+
+	transitions, err := service.GetDraftTransitions(userAddress, c.Query("draftId"))
+	if err != nil {
+		log.Error("error while retrieving draft history: " + err.Error())
+		model.JsonResponse(c, draftLifecycleErrorStatus(err), nil, nodeAddress, err.Error())
+		return
+	}
+
+	model.JsonResponse(c, http.StatusOK, transitions, nodeAddress, "")
+}
+
+func draftLifecycleErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, service.ErrorDraftNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, service.ErrorDraftActorNotAllowed):
+		return http.StatusForbidden
+	case errors.Is(err, service.ErrorDraftTransitionNotAllowed), errors.Is(err, service.ErrorDraftNotRegenerable):
+		return http.StatusConflict
+	case errors.Is(err, service.ErrorDraftVoidReasonRequired), errors.Is(err, service.ErrorDraftDisputeReasonRequired):
+		return http.StatusBadRequest
+	}
+	return http.StatusInternalServerError
+}
+
+func draftSummary(draft *model.InvoiceDraft) getInvoiceDraftsResponse {
+	seller, buyer := draft.Seller(), draft.Buyer()
+	userName, _ := seller.GetNameAsString()
+	cspName, _ := buyer.GetNameAsString()
+	return getInvoiceDraftsResponse{
+		DraftId:           draft.DraftId,
+		CreationTimestamp: draft.CreationTimestamp,
+		UserAddress:       draft.UserAddress,
+		CspOwner:          draft.CspOwner,
+		TotalUsdcAmount:   draft.TotalUsdcAmount,
+		InvoiceSeries:     draft.InvoiceSeries,
+		InvoiceNumber:     draft.InvoiceNumber,
+		NodeOwnerName:     userName,
+		CspOwnerName:      cspName,
+		Status:            draft.CurrentStatus(),
+		Version:           draft.CurrentVersion(),
+		PreviousVersionId: draft.PreviousVersionId,
+		SupersededBy:      draft.SupersededBy,
+	}
+}
+
+func (h *invoiceDraftHandler) getSeries(c *gin.Context) {
+	nodeAddress, err := service.GetAddress()
+	if err != nil {
+		log.Error("error while retrieving node address: " + err.Error())
+		model.JsonResponse(c, http.StatusInternalServerError, nil, "", err.Error())
+		return
+	}
+
+	userAddress, err := middleware.AddressFromBearer(c)
+	if err != nil {
+		log.Error("error while retrieving address from bearer: " + err.Error())
+		model.JsonResponse(c, http.StatusBadRequest, nil, nodeAddress, err.Error())
+		return
+	}
+
+	series, err := service.GetInvoiceSeries(userAddress)
+	if err != nil {
+		log.Error("error while retrieving invoice series: " + err.Error())
+		model.JsonResponse(c, http.StatusInternalServerError, nil, nodeAddress, err.Error())
+		return
+	}
+
+	model.JsonResponse(c, http.StatusOK, series, nodeAddress, "")
+}
+
+func (h *invoiceDraftHandler) createSeries(c *gin.Context) {
+	nodeAddress, err := service.GetAddress()
+	if err != nil {
+		log.Error("error while retrieving node address: " + err.Error())
+		model.JsonResponse(c, http.StatusInternalServerError, nil, "", err.Error())
+		return
+	}
+
+	userAddress, err := middleware.AddressFromBearer(c)
+	if err != nil {
+		log.Error("error while retrieving address from bearer: " + err.Error())
+		model.JsonResponse(c, http.StatusBadRequest, nil, nodeAddress, err.Error())
+		return
+	}
+
+	var req invoiceSeriesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		log.Error("error while binding json: " + err.Error())
+		model.JsonResponse(c, http.StatusBadRequest, nil, nodeAddress, "error while binding json: "+err.Error())
+		return
+	}
+
+	series, err := service.CreateInvoiceSeries(userAddress, model.InvoiceSeries{
+		Name:        req.Name,
+		NextNumber:  req.NextNumber,
+		YearlyReset: req.YearlyReset,
+	})
+	if err != nil {
+		log.Error("error while creating invoice series: " + err.Error())
+		model.JsonResponse(c, invoiceSeriesErrorStatus(err), nil, nodeAddress, err.Error())
+		return
+	}
+
+	model.JsonResponse(c, http.StatusOK, series, nodeAddress, "")
+}
+
+func (h *invoiceDraftHandler) changeSeries(c *gin.Context) {
+	nodeAddress, err := service.GetAddress()
+	if err != nil {
+		log.Error("error while retrieving node address: " + err.Error())
+		model.JsonResponse(c, http.StatusInternalServerError, nil, "", err.Error())
+		return
+	}
+
+	userAddress, err := middleware.AddressFromBearer(c)
+	if err != nil {
+		log.Error("error while retrieving address from bearer: " + err.Error())
+		model.JsonResponse(c, http.StatusBadRequest, nil, nodeAddress, err.Error())
+		return
+	}
+
+	var req invoiceSeriesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		log.Error("error while binding json: " + err.Error())
+		model.JsonResponse(c, http.StatusBadRequest, nil, nodeAddress, "error while binding json: "+err.Error())
+		return
+	}
+
+	series, err := service.ChangeInvoiceSeries(userAddress, req.Name, req.NextNumber, req.YearlyReset)
+	if err != nil {
+		log.Error("error while changing invoice series: " + err.Error())
+		model.JsonResponse(c, invoiceSeriesErrorStatus(err), nil, nodeAddress, err.Error())
+		return
+	}
+
+	model.JsonResponse(c, http.StatusOK, series, nodeAddress, "")
+}
+
+func (h *invoiceDraftHandler) getSeriesReport(c *gin.Context) {
+	nodeAddress, err := service.GetAddress()
+	if err != nil {
+		log.Error("error while retrieving node address: " + err.Error())
+		model.JsonResponse(c, http.StatusInternalServerError, nil, "", err.Error())
+		return
+	}
+
+	userAddress, err := middleware.AddressFromBearer(c)
+	if err != nil {
+		log.Error("error while retrieving address from bearer: " + err.Error())
+		model.JsonResponse(c, http.StatusBadRequest, nil, nodeAddress, err.Error())
+		return
+	}
+
+	report, err := service.GetInvoiceSeriesReport(userAddress, c.Query("series"))
+	if err != nil {
+		log.Error("error while building invoice series report: " + err.Error())
+		model.JsonResponse(c, invoiceSeriesErrorStatus(err), nil, nodeAddress, err.Error())
+		return
+	}
+
+	model.JsonResponse(c, http.StatusOK, report, nodeAddress, "")
+}
+
+func invoiceSeriesErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, service.ErrorSeriesNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, service.ErrorSeriesExists), errors.Is(err, service.ErrorSeriesMovedBackwards):
+		return http.StatusConflict
+	}
+	return http.StatusBadRequest
+}
@@ -4,6 +4,7 @@ import (
 	"errors"
 	"net/http"
 	"strings"
+	"time"
 
 	"github.com/NaeuralEdgeProtocol/ratio1-backend/config"
 	"github.com/NaeuralEdgeProtocol/ratio1-backend/model"
@@ -37,10 +38,12 @@ type LinkNodeResponse struct {
 	Signature string `json:"signature"`
 }
 
-type launchpadHandler struct{}
+type launchpadHandler struct {
+	repos *storage.Repositories
+}
 
-func NewLaunchpadHandler(groupHandler *groupHandler) {
-	h := &launchpadHandler{}
+func NewLaunchpadHandler(groupHandler *groupHandler, repos *storage.Repositories) {
+	h := &launchpadHandler{repos: repos}
 
 	endpoints := []EndpointHandler{
 		{Method: http.MethodPost, Path: mintTokensEndpoint, HandlerFunc: h.buyLicense},
@@ -108,7 +111,7 @@ func (h *launchpadHandler) linkNode(c *gin.Context) {
 			}
 		}
 
-		kyc, found, err := storage.GetKycByEmail(*acc.Email)
+		kyc, found, err := h.repos.Kycs.GetByEmail(*acc.Email)
 		if err != nil {
 			log.Error("error while retrieving kyc information from storage: " + err.Error())
 			model.JsonResponse(c, http.StatusInternalServerError, nil, nodeAddress, err.Error())
@@ -198,7 +201,7 @@ func (h *launchpadHandler) buyLicense(c *gin.Context) {
 		}
 
 		var found bool
-		kyc, found, err = storage.GetKycByEmail(*acc.Email)
+		kyc, found, err = h.repos.Kycs.GetByEmail(*acc.Email)
 		if err != nil {
 			log.Error("error while retrieving kyc information from storage: " + err.Error())
 			model.JsonResponse(c, http.StatusInternalServerError, nil, nodeAddress, err.Error())
@@ -221,7 +224,7 @@ func (h *launchpadHandler) buyLicense(c *gin.Context) {
 			return
 		}
 
-		userInfo, err := storage.GetUserInfoByAddress(address)
+		userInfo, err := h.repos.UserInfos.GetByAddress(address)
 		if err != nil {
 			log.Error("error while retrieving client info from storage: " + err.Error())
 			model.JsonResponse(c, http.StatusInternalServerError, nil, nodeAddress, err.Error())
@@ -252,29 +255,42 @@ func (h *launchpadHandler) buyLicense(c *gin.Context) {
 		}
 	}
 
-	vatPercentage := int64(service.ROUVatPerc)
-	if address == config.Config.NaeuralAddress { //naeural not gonna pay itself VAT
-		vatPercentage = 0
-	} else if client.IsCompany && client.Country != model.ROU_ID {
-		client.ReverseCharge, client.IsUe = service.IsCompanyRegisteredAndUE(client.Country, client.IdentificationCode)
-		vatPercentage = 0
-	} else if !client.IsCompany && client.Country != model.ROU_ID {
-		vat := service.GetEuVatPercentage(client.Country)
-		if vat != nil {
-			vatPercentage = *vat
-		} else {
-			vatPercentage = 0
+	vat := service.VatTreatment{Category: service.VatCategoryNotSubject, Scheme: service.VatSchemeOutOfScope}
+	if address != config.Config.NaeuralAddress { //naeural not gonna pay itself VAT
+		if client.IsCompany && client.Country != model.ROU_ID {
+			check, err := service.ValidateVatNumber(client.Country, client.IdentificationCode)
+			if errors.Is(err, service.ErrorViesUnavailable) {
+				log.Error("error while validating vat number: " + err.Error())
+				model.JsonResponse(c, http.StatusServiceUnavailable, nil, nodeAddress, err.Error())
+				return
+			} else if err != nil {
+				log.Error("error while validating vat number: " + err.Error())
+				model.JsonResponse(c, http.StatusInternalServerError, nil, nodeAddress, err.Error())
+				return
+			}
+			if check != nil {
+				client.IsUe, client.ReverseCharge, client.ViesCheckId = true, check.Valid, &check.Id
+			}
+		}
+		vat, err = service.LicenseVat(client.Country, client.IsCompany, client.ReverseCharge, time.Now())
+		if err != nil {
+			log.Error("error while computing vat: " + err.Error())
+			model.JsonResponse(c, http.StatusInternalServerError, nil, nodeAddress, err.Error())
+			return
 		}
 	}
+	vatPercentage := service.VatBasisPoints(vat.Rate)
+	client.VatRate, client.VatScheme = &vat.Rate, &vat.Scheme
 
 	newUuid := uuid.New()
 	newString := strings.ReplaceAll(newUuid.String(), "-", "")
 	status := model.InvoiceStatusPending
 	client.Uuid = &newString
+	client.BlockchainAddress = address
 	client.Status = &status
 	client.UserEmail = acc.Email
 
-	err = storage.CreateInvoice(client)
+	err = h.repos.Invoices.Create(client)
 	if err != nil {
 		log.Error("error while creating invoice in storage: " + err.Error())
 		model.JsonResponse(c, http.StatusInternalServerError, nil, nodeAddress, err.Error())
@@ -366,7 +382,7 @@ func (h *launchpadHandler) multiLinkNode(c *gin.Context) {
 			}
 		}
 
-		kyc, found, err := storage.GetKycByEmail(*acc.Email)
+		kyc, found, err := h.repos.Kycs.GetByEmail(*acc.Email)
 		if err != nil {
 			log.Error("error while retrieving kyc information from storage: " + err.Error())
 			model.JsonResponse(c, http.StatusInternalServerError, nil, nodeAddress, err.Error())
@@ -34,10 +34,12 @@ type sellerClientsResponse struct {
 	TotalValue     int    `json:"totalValue"`
 }
 
-type sellerHandler struct{}
+type sellerHandler struct {
+	repos *storage.Repositories
+}
 
-func NewSellerHandler(groupHandler *groupHandler) {
-	h := sellerHandler{}
+func NewSellerHandler(groupHandler *groupHandler, repos *storage.Repositories) {
+	h := sellerHandler{repos: repos}
 
 	authEndpoints := []EndpointHandler{
 		{Method: http.MethodPost, Path: newSellerEndpoint, HandlerFunc: h.newSeller},
@@ -112,7 +114,7 @@ func (h *sellerHandler) newSeller(c *gin.Context) {
 		}
 	}
 
-	ok, err := storage.AddressHasCode(account.Address)
+	ok, err := h.repos.Sellers.AddressHasCode(account.Address)
 	if err != nil {
 		log.Error("error while checking if address has code: " + err.Error())
 		model.JsonResponse(c, http.StatusInternalServerError, nil, nodeAddress, "error while checking if address has code: "+err.Error())
@@ -129,7 +131,7 @@ func (h *sellerHandler) newSeller(c *gin.Context) {
 		newCode = newSellerRequest.ForcedCode
 	}
 
-	ok, err = storage.SellerCodeDoExist(newCode)
+	ok, err = h.repos.Sellers.CodeExists(newCode)
 	if err != nil {
 		log.Error("error while checking if seller code already exists: " + err.Error())
 		model.JsonResponse(c, http.StatusInternalServerError, nil, nodeAddress, "error while checking if seller code already exists: "+err.Error())
@@ -141,7 +143,7 @@ func (h *sellerHandler) newSeller(c *gin.Context) {
 		return
 	}
 
-	err = storage.CreateSeller(&model.Seller{
+	err = h.repos.Sellers.Create(&model.Seller{
 		SellerCode: newCode,
 		AccountID:  account.Address})
 	if err != nil {
@@ -168,7 +170,7 @@ func (h *sellerHandler) getClients(c *gin.Context) {
 		return
 	}
 
-	sellerCode, err := storage.GetSellerCodeByAddress(address)
+	sellerCode, err := h.repos.Sellers.GetCodeByAddress(address)
 	if err != nil {
 		log.Error("error while retrieving seller code: " + err.Error())
 		model.JsonResponse(c, http.StatusInternalServerError, nil, nodeAddress, "error while retrieving seller code: "+err.Error())
@@ -181,7 +183,7 @@ func (h *sellerHandler) getClients(c *gin.Context) {
 		return
 	}
 
-	users, err := storage.GetAccountsBySellerCode(*sellerCode)
+	users, err := h.repos.Accounts.GetBySellerCode(*sellerCode)
 	if err != nil {
 		log.Error("error while retrieving users: " + err.Error())
 		model.JsonResponse(c, http.StatusInternalServerError, nil, nodeAddress, "error while retrieving users: "+err.Error())
@@ -190,7 +192,7 @@ func (h *sellerHandler) getClients(c *gin.Context) {
 	var response []sellerClientsResponse
 	for _, u := range *users {
 
-		invoices, err := storage.GetUserInvoices(u.Address)
+		invoices, err := h.repos.Invoices.GetUserInvoices(u.Address)
 		if err != nil {
 			log.Error("error while retrieving invoices: " + err.Error())
 			model.JsonResponse(c, http.StatusInternalServerError, nil, nodeAddress, "error while retrieving invoices: "+err.Error())
@@ -229,7 +231,7 @@ func (h *sellerHandler) getSellerCode(c *gin.Context) {
 		return
 	}
 
-	sellerCode, err := storage.GetSellerCodeByAddress(address)
+	sellerCode, err := h.repos.Sellers.GetCodeByAddress(address)
 	if err != nil {
 		log.Error("error while retrieving seller code: " + err.Error())
 		model.JsonResponse(c, http.StatusInternalServerError, nil, nodeAddress, "error while retrieving seller code: "+err.Error())
@@ -260,7 +262,7 @@ func (h *sellerHandler) getSellersCode(c *gin.Context) {
 		return
 	}
 
-	sellers, err := storage.GetAllSellerCode()
+	sellers, err := h.repos.Sellers.GetAll()
 	if err != nil {
 		log.Error("error while retrieving all seller codes: " + err.Error())
 		model.JsonResponse(c, http.StatusInternalServerError, nil, nodeAddress, "error while retrieving all seller codes: "+err.Error())
@@ -294,7 +296,7 @@ func (h *sellerHandler) disableSellerCode(c *gin.Context) {
 	var seller *model.Seller
 	userAddress, ok := c.GetQuery("userAddress")
 	if ok && userAddress != "" {
-		seller, err = storage.GetSellerByAddress(userAddress)
+		seller, err = h.repos.Sellers.GetByAddress(userAddress)
 		if err != nil {
 			log.Error("error while retrieving seller by address: " + err.Error())
 			model.JsonResponse(c, http.StatusInternalServerError, nil, nodeAddress, "error while retrieving seller by address: "+err.Error())
@@ -307,7 +309,7 @@ func (h *sellerHandler) disableSellerCode(c *gin.Context) {
 			model.JsonResponse(c, http.StatusBadRequest, nil, nodeAddress, "seller code is required")
 			return
 		}
-		seller, err = storage.GetSellerByCode(sellerCode)
+		seller, err = h.repos.Sellers.GetByCode(sellerCode)
 		if err != nil {
 			log.Error("error while retrieving seller by code: " + err.Error())
 			model.JsonResponse(c, http.StatusInternalServerError, nil, nodeAddress, "error while retrieving seller by code: "+err.Error())
@@ -322,7 +324,7 @@ func (h *sellerHandler) disableSellerCode(c *gin.Context) {
 	}
 
 	seller.IsDisabled = true
-	err = storage.UpdateSeller(seller)
+	err = h.repos.Sellers.Update(seller)
 	if err != nil {
 		log.Error("error while updating seller: " + err.Error())
 		model.JsonResponse(c, http.StatusInternalServerError, nil, nodeAddress, "error while updating seller: "+err.Error())
@@ -356,7 +358,7 @@ func (h *sellerHandler) enableSellerCode(c *gin.Context) {
 	var seller *model.Seller
 	userAddress, ok := c.GetQuery("userAddress")
 	if ok && userAddress != "" {
-		seller, err = storage.GetSellerByAddress(userAddress)
+		seller, err = h.repos.Sellers.GetByAddress(userAddress)
 		if err != nil {
 			log.Error("error while retrieving seller by address: " + err.Error())
 			model.JsonResponse(c, http.StatusInternalServerError, nil, nodeAddress, "error while retrieving seller by address: "+err.Error())
@@ -369,7 +371,7 @@ func (h *sellerHandler) enableSellerCode(c *gin.Context) {
 			model.JsonResponse(c, http.StatusBadRequest, nil, nodeAddress, "seller code is required")
 			return
 		}
-		seller, err = storage.GetSellerByCode(sellerCode)
+		seller, err = h.repos.Sellers.GetByCode(sellerCode)
 		if err != nil {
 			log.Error("error while retrieving seller by code: " + err.Error())
 			model.JsonResponse(c, http.StatusInternalServerError, nil, nodeAddress, "error while retrieving seller by code: "+err.Error())
@@ -384,7 +386,7 @@ func (h *sellerHandler) enableSellerCode(c *gin.Context) {
 	}
 
 	seller.IsDisabled = false
-	err = storage.UpdateSeller(seller)
+	err = h.repos.Sellers.Update(seller)
 	if err != nil {
 		log.Error("error while updating seller: " + err.Error())
 		model.JsonResponse(c, http.StatusInternalServerError, nil, nodeAddress, "error while updating seller: "+err.Error())
@@ -30,10 +30,12 @@ type initSessionRequest struct {
 	UserType string `json:"type"`
 }
 
-type sumsubHandler struct{}
+type sumsubHandler struct {
+	repos *storage.Repositories
+}
 
-func NewSumsubHandler(groupHandler *groupHandler) {
-	h := sumsubHandler{}
+func NewSumsubHandler(groupHandler *groupHandler, repos *storage.Repositories) {
+	h := sumsubHandler{repos: repos}
 
 	auth := middleware.Authorization(config.Config.Jwt.Secret)
 	authEndpoints := []EndpointHandler{
@@ -108,7 +110,7 @@ func (h *sumsubHandler) initSession(c *gin.Context) {
 		}
 	}
 
-	kyc, found, err := storage.GetKycByEmail(*account.Email)
+	kyc, found, err := h.repos.Kycs.GetByEmail(*account.Email)
 	if err != nil {
 		log.Error("error while retrieving kyc information from storage: " + err.Error())
 		model.JsonResponse(c, http.StatusInternalServerError, nil, nodeAddress, err.Error())
@@ -156,7 +158,7 @@ func (h *sumsubHandler) initSession(c *gin.Context) {
 		return
 	}
 
-	err = storage.CreateOrUpdateKyc(kyc)
+	err = h.repos.Kycs.CreateOrUpdate(kyc)
 	if err != nil {
 		log.Error("error while saving kyc information in storage: " + err.Error())
 		model.JsonResponse(c, http.StatusBadRequest, nil, nodeAddress, err.Error())
@@ -206,7 +208,7 @@ func (h *sumsubHandler) processEvents(c *gin.Context) {
 		return
 	}
 
-	kyc, found, err := storage.GetKycByUuid(uuid)
+	kyc, found, err := h.repos.Kycs.GetByUuid(uuid)
 	if err != nil {
 		log.Error("error while retrieving kyc information from storage: " + err.Error())
 		model.JsonResponse(c, http.StatusInternalServerError, nil, nodeAddress, err.Error())
@@ -223,7 +225,7 @@ func (h *sumsubHandler) processEvents(c *gin.Context) {
 		return
 	}
 
-	user, found, err := storage.GetAccountByEmail(kyc.Email)
+	user, found, err := h.repos.Accounts.GetByEmail(kyc.Email)
 	if err != nil {
 		log.Error("error while retrieving account information from storage: " + err.Error())
 		model.JsonResponse(c, http.StatusInternalServerError, nil, nodeAddress, err.Error())
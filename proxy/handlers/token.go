@@ -45,10 +45,12 @@ type botStatsResponse struct {
 	NodeAddress          string `json:"nodeAddress"`
 }
 
-type tokenHandler struct{}
+type tokenHandler struct {
+	repos *storage.Repositories
+}
 
-func NewTokenHandler(groupHandler *groupHandler) {
-	h := tokenHandler{}
+func NewTokenHandler(groupHandler *groupHandler, repos *storage.Repositories) {
+	h := tokenHandler{repos: repos}
 
 	publicEndpoints := []EndpointHandler{
 		{Method: http.MethodGet, Path: getSupplyEndpoint, HandlerFunc: h.getTokenSupply},
@@ -82,7 +84,7 @@ func (h *tokenHandler) getTokenSupply(c *gin.Context) {
 		return
 	}
 
-	stats, err := storage.GetLatestStats()
+	stats, err := h.repos.Stats.GetLatest()
 	if err != nil {
 		log.Error("error while retrieving latest stats from db: " + err.Error())
 		model.JsonResponse(c, http.StatusInternalServerError, nil, nodeAddress, "error while retrieving latest stats from db: "+err.Error())
@@ -194,7 +196,7 @@ func (h *tokenHandler) getStats(c *gin.Context) {
 		return
 	}
 
-	stats, err := storage.GetAllStatsASC()
+	stats, err := h.repos.Stats.GetAllASC()
 	if err != nil {
 		log.Error("error while retrieving stats from db: " + err.Error())
 		model.JsonResponse(c, http.StatusInternalServerError, nil, nodeAddress, "error while retrieving stats from db: "+err.Error())
@@ -221,7 +223,7 @@ func (h *tokenHandler) getStatsForBot(c *gin.Context) {
 		return
 	}
 
-	stats, err := storage.GetLatestStats()
+	stats, err := h.repos.Stats.GetLatest()
 	if err != nil {
 		log.Error("error while retrieving stats from db: " + err.Error())
 		model.JsonResponse(c, http.StatusInternalServerError, nil, nodeAddress, "error while retrieving stats from db: "+err.Error())
@@ -8,6 +8,7 @@ import (
 	"github.com/NaeuralEdgeProtocol/ratio1-backend/config"
 	"github.com/NaeuralEdgeProtocol/ratio1-backend/proxy/handlers"
 	"github.com/NaeuralEdgeProtocol/ratio1-backend/service"
+	"github.com/NaeuralEdgeProtocol/ratio1-backend/storage"
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
 )
@@ -23,7 +24,7 @@ type WebServer struct {
 	router *gin.Engine
 }
 
-func NewWebServer() (*WebServer, error) {
+func NewWebServer(repos *storage.Repositories) (*WebServer, error) {
 	router := gin.Default()
 	corsCfg := cors.DefaultConfig()
 	corsCfg.AllowHeaders = corsHeaders
@@ -37,15 +38,15 @@ func NewWebServer() (*WebServer, error) {
 	service.NewAuthService()
 
 	handlers.NewAuthHandler(groupHandler)
-	handlers.NewLaunchpadHandler(groupHandler)
-	handlers.NewAccountHandler(groupHandler)
-	handlers.NewSumsubHandler(groupHandler)
-	handlers.NewTokenHandler(groupHandler)
-	handlers.NewSellerHandler(groupHandler)
-	handlers.NewAdminHandler(groupHandler)
-	handlers.NewInvoiceDraftHandler(groupHandler)
-	handlers.NewBurnReportHandler(groupHandler)
-	handlers.NewBrandingHandler(groupHandler)
+	handlers.NewLaunchpadHandler(groupHandler, repos)
+	handlers.NewAccountHandler(groupHandler, repos)
+	handlers.NewSumsubHandler(groupHandler, repos)
+	handlers.NewTokenHandler(groupHandler, repos)
+	handlers.NewSellerHandler(groupHandler, repos)
+	handlers.NewAdminHandler(groupHandler, repos)
+	handlers.NewInvoiceDraftHandler(groupHandler, repos)
+	handlers.NewBurnReportHandler(groupHandler, repos)
+	handlers.NewBrandingHandler(groupHandler, repos)
 
 	groupHandler.RegisterEndpoints(router)
 
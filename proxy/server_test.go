@@ -0,0 +1,328 @@
+package proxy
+
+import (
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/json"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/NaeuralEdgeProtocol/ratio1-backend/config"
+	"github.com/NaeuralEdgeProtocol/ratio1-backend/crypto"
+	"github.com/NaeuralEdgeProtocol/ratio1-backend/model"
+	"github.com/NaeuralEdgeProtocol/ratio1-backend/service"
+	"github.com/NaeuralEdgeProtocol/ratio1-backend/storage"
+	"github.com/NaeuralEdgeProtocol/ratio1-backend/storage/memory"
+	ethcrypto "github.com/ethereum/go-ethereum/crypto"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+const testUserAddress = "0x000000000000000000000000000000000000dEaD"
+
+func newTestServer(t *testing.T) (*WebServer, *storage.Repositories) {
+	gin.SetMode(gin.TestMode)
+	writeTestPemFile(t)
+
+	config.Config.Jwt.Secret = "test-secret"
+	config.Config.Jwt.Issuer = "test"
+	config.Config.Jwt.KeySeedHex = strings.Repeat("01", 32)
+	config.Config.R1ContractAddress = "0x6444C6c2D527D85EA97032da9A7504d6d1448ecF"
+
+	repos := memory.NewRepositories()
+	previousRepos := service.GetRepositories()
+	service.SetRepositories(repos)
+	t.Cleanup(func() { service.SetRepositories(previousRepos) })
+
+	server, err := NewWebServer(repos)
+	require.NoError(t, err)
+	return server, repos
+}
+
+func writeTestPemFile(t *testing.T) {
+	if os.Getenv("NAEURAL_PEM_FILE") != "" {
+		return
+	}
+
+	sk, err := ethcrypto.GenerateKey()
+	require.NoError(t, err)
+	ecKey, err := asn1.Marshal(struct {
+		Version    int
+		PrivateKey []byte
+	}{Version: 1, PrivateKey: ethcrypto.FromECDSA(sk)})
+	require.NoError(t, err)
+	pkcs8, err := asn1.Marshal(struct {
+		Version             int
+		PrivateKeyAlgorithm pkix.AlgorithmIdentifier
+		PrivateKey          []byte
+	}{PrivateKeyAlgorithm: pkix.AlgorithmIdentifier{Algorithm: asn1.ObjectIdentifier{1, 2, 840, 10045, 2, 1}}, PrivateKey: ecKey})
+	require.NoError(t, err)
+
+	path := filepath.Join(t.TempDir(), "key.pem")
+	require.NoError(t, os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: pkcs8}), 0600))
+	t.Setenv("NAEURAL_PEM_FILE", path)
+}
+
+func doRequest(t *testing.T, server *WebServer, method, path, body string, auth bool) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	if auth {
+		token, err := crypto.GenerateJwt(testUserAddress, config.Config.Jwt.Secret, config.Config.Jwt.Issuer, 5)
+		require.NoError(t, err)
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	w := httptest.NewRecorder()
+	server.router.ServeHTTP(w, req)
+	return w
+}
+
+func TestPreferencesRoundTrip(t *testing.T) {
+	server, repos := newTestServer(t)
+
+	w := doRequest(t, server, http.MethodGet, "/invoice-draft/get-preferences", "", false)
+	require.Equal(t, http.StatusUnauthorized, w.Code)
+
+	w = doRequest(t, server, http.MethodPost, "/invoice-draft/create-preferences", `{"invoiceSeries":"R1","nextNumber":7,"localCurrency":"EUR"}`, true)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	stored, err := repos.Preferences.GetByAddress(testUserAddress)
+	require.NoError(t, err)
+	require.Equal(t, "R1", stored.InvoiceSeries)
+	require.Equal(t, 7, stored.NextNumber)
+
+	w = doRequest(t, server, http.MethodGet, "/invoice-draft/get-preferences", "", true)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	var response struct {
+		Data model.Preference `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	require.Equal(t, testUserAddress, response.Data.UserAddress)
+	require.Equal(t, "EUR", response.Data.LocalCurrency)
+}
+
+func TestDownloadDraftPdfServesNodeOwnerAndCsp(t *testing.T) {
+	server, repos := newTestServer(t)
+
+	draft := &model.InvoiceDraft{
+		CreationTimestamp:          time.Now().UTC(),
+		UserAddress:                "0x00000000000000000000000000000000000000a1",
+		CspOwner:                   testUserAddress,
+		InvoiceSeries:              "R1",
+		InvoiceNumber:              3,
+		TotalUsdcAmount:            121,
+		VatApplied:                 21,
+		LocalCurrencyExchangeRatio: 1,
+	}
+	require.NoError(t, repos.Drafts.Create(draft))
+	require.NoError(t, repos.Allocations.Create(&model.Allocation{
+		BlockNumber:     1,
+		TxHash:          "0x01",
+		JobId:           "1",
+		NodeAddress:     "0xai_000000000000000000000000000000000000001",
+		UserAddress:     draft.UserAddress,
+		CspOwner:        draft.CspOwner,
+		UsdcAmountPayed: "121000000",
+		DraftId:         &draft.DraftId,
+	}))
+
+	w := doRequest(t, server, http.MethodGet, "/invoice-draft/download-draft-pdf?draftId="+draft.DraftId.String(), "", true)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	require.Equal(t, "application/pdf", w.Header().Get("Content-Type"))
+	require.True(t, strings.HasPrefix(w.Body.String(), "%PDF-"))
+
+	w = doRequest(t, server, http.MethodGet, "/invoice-draft/download-draft-pdf?draftId="+uuid.NewString(), "", true)
+	require.Equal(t, http.StatusNotFound, w.Code, w.Body.String())
+
+	// the parties have no profile, the e-invoice cannot be built
+	w = doRequest(t, server, http.MethodGet, "/invoice-draft/download-draft-ubl?draftId="+draft.DraftId.String(), "", true)
+	require.Equal(t, http.StatusUnprocessableEntity, w.Code, w.Body.String())
+}
+
+func TestDraftLifecycleEndpoints(t *testing.T) {
+	server, repos := newTestServer(t)
+
+	draft := &model.InvoiceDraft{
+		CreationTimestamp: time.Now().UTC(),
+		UserAddress:       testUserAddress,
+		CspOwner:          "0x00000000000000000000000000000000000000c1",
+	}
+	require.NoError(t, repos.Drafts.Create(draft))
+
+	w := doRequest(t, server, http.MethodGet, "/invoice-draft/get-drafts?status=sent", "", true)
+	require.Equal(t, http.StatusBadRequest, w.Code, w.Body.String())
+
+	w = doRequest(t, server, http.MethodPost, "/invoice-draft/finalize-draft", `{"draftId":"`+draft.DraftId.String()+`"}`, true)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	require.Contains(t, w.Body.String(), `"status":"issued"`)
+
+	w = doRequest(t, server, http.MethodPost, "/invoice-draft/acknowledge-draft", `{"draftId":"`+draft.DraftId.String()+`"}`, true)
+	require.Equal(t, http.StatusForbidden, w.Code, w.Body.String())
+
+	w = doRequest(t, server, http.MethodPost, "/invoice-draft/void-draft", `{"draftId":"`+draft.DraftId.String()+`"}`, true)
+	require.Equal(t, http.StatusBadRequest, w.Code, w.Body.String())
+
+	w = doRequest(t, server, http.MethodPost, "/invoice-draft/dispute-draft", `{"draftId":"`+draft.DraftId.String()+`"}`, true)
+	require.Equal(t, http.StatusBadRequest, w.Code, w.Body.String())
+	w = doRequest(t, server, http.MethodPost, "/invoice-draft/dispute-draft", `{"draftId":"`+draft.DraftId.String()+`","reason":"wrong amount"}`, true)
+	require.Equal(t, http.StatusForbidden, w.Code, w.Body.String())
+
+	w = doRequest(t, server, http.MethodGet, "/invoice-draft/get-drafts?status=disputed", "", true)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	require.NotContains(t, w.Body.String(), draft.DraftId.String())
+
+	w = doRequest(t, server, http.MethodGet, "/invoice-draft/get-drafts?status=draft", "", true)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	require.NotContains(t, w.Body.String(), draft.DraftId.String())
+
+	w = doRequest(t, server, http.MethodGet, "/invoice-draft/get-drafts?status=issued", "", true)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	require.Contains(t, w.Body.String(), draft.DraftId.String())
+
+	w = doRequest(t, server, http.MethodGet, "/invoice-draft/get-draft-history?draftId="+draft.DraftId.String(), "", true)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	require.Contains(t, w.Body.String(), `"toStatus":"issued"`)
+
+	w = doRequest(t, server, http.MethodGet, "/invoice-draft/get-draft-history?draftId="+uuid.NewString(), "", true)
+	require.Equal(t, http.StatusNotFound, w.Code, w.Body.String())
+}
+
+func TestRegenerateDraftKeepsBothVersionsDownloadable(t *testing.T) {
+	server, repos := newTestServer(t)
+
+	draft := &model.InvoiceDraft{
+		CreationTimestamp:          time.Now().UTC(),
+		UserAddress:                testUserAddress,
+		CspOwner:                   "0x00000000000000000000000000000000000000c1",
+		InvoiceSeries:              "R1",
+		InvoiceNumber:              3,
+		LocalCurrencyExchangeRatio: 1,
+	}
+	require.NoError(t, repos.Drafts.Create(draft))
+	require.NoError(t, repos.Allocations.Create(&model.Allocation{
+		BlockNumber:     1,
+		TxHash:          "0x01",
+		JobId:           "1",
+		NodeAddress:     "0xai_000000000000000000000000000000000000001",
+		UserAddress:     draft.UserAddress,
+		CspOwner:        draft.CspOwner,
+		UsdcAmountPayed: "121000000",
+		DraftId:         &draft.DraftId,
+	}))
+
+	w := doRequest(t, server, http.MethodPost, "/invoice-draft/regenerate-draft", `{"draftId":"`+draft.DraftId.String()+`"}`, true)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var response struct {
+		Data struct {
+			DraftId uuid.UUID `json:"draftId"`
+			Version int       `json:"version"`
+		} `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	require.Equal(t, 2, response.Data.Version)
+
+	for _, id := range []uuid.UUID{draft.DraftId, response.Data.DraftId} {
+		w = doRequest(t, server, http.MethodGet, "/invoice-draft/download-draft-pdf?draftId="+id.String(), "", true)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	}
+
+	w = doRequest(t, server, http.MethodPost, "/invoice-draft/regenerate-draft", `{"draftId":"`+draft.DraftId.String()+`"}`, true)
+	require.Equal(t, http.StatusConflict, w.Code, w.Body.String())
+}
+
+func TestInvoiceSeriesEndpoints(t *testing.T) {
+	server, _ := newTestServer(t)
+
+	w := doRequest(t, server, http.MethodPost, "/invoice-draft/create-series", `{"name":"R2","nextNumber":10,"yearlyReset":true}`, true)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	w = doRequest(t, server, http.MethodPost, "/invoice-draft/create-series", `{"name":"R2"}`, true)
+	require.Equal(t, http.StatusConflict, w.Code, w.Body.String())
+
+	w = doRequest(t, server, http.MethodPost, "/invoice-draft/change-series", `{"name":"R2","nextNumber":4,"yearlyReset":true}`, true)
+	require.Equal(t, http.StatusConflict, w.Code, w.Body.String())
+	w = doRequest(t, server, http.MethodPost, "/invoice-draft/change-series", `{"name":"R2","nextNumber":12,"yearlyReset":true}`, true)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	require.Contains(t, w.Body.String(), `"nextNumber":12`)
+
+	w = doRequest(t, server, http.MethodGet, "/invoice-draft/get-series", "", true)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	require.Contains(t, w.Body.String(), `"name":"R2"`)
+
+	w = doRequest(t, server, http.MethodGet, "/invoice-draft/get-series-report?series=R2", "", true)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	require.Contains(t, w.Body.String(), `"periods":[]`)
+	w = doRequest(t, server, http.MethodGet, "/invoice-draft/get-series-report?series=R3", "", true)
+	require.Equal(t, http.StatusNotFound, w.Code, w.Body.String())
+}
+
+func TestAdminVatRatesEndpoints(t *testing.T) {
+	server, _ := newTestServer(t)
+
+	w := doRequest(t, server, http.MethodPost, "/admin/vat-rates", `{"country":"DEU","rate":21,"effectiveFrom":"2027-01-01"}`, true)
+	require.Equal(t, http.StatusUnauthorized, w.Code, w.Body.String())
+
+	previousAdmins := config.Config.AdminAddresses
+	config.Config.AdminAddresses = []string{testUserAddress}
+	t.Cleanup(func() { config.Config.AdminAddresses = previousAdmins })
+
+	w = doRequest(t, server, http.MethodPost, "/admin/vat-rates", `{"country":"DEU","rate":150,"effectiveFrom":"2027-01-01"}`, true)
+	require.Equal(t, http.StatusBadRequest, w.Code, w.Body.String())
+	w = doRequest(t, server, http.MethodPost, "/admin/vat-rates", `{"country":"DEU","rate":21,"effectiveFrom":"01/01/2027"}`, true)
+	require.Equal(t, http.StatusBadRequest, w.Code, w.Body.String())
+	w = doRequest(t, server, http.MethodPost, "/admin/vat-rates", `{"country":"DEU","rate":21,"effectiveFrom":"2027-01-01"}`, true)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	require.Contains(t, w.Body.String(), `"createdBy":"`+testUserAddress+`"`)
+
+	w = doRequest(t, server, http.MethodGet, "/admin/vat-rates", "", true)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	require.Contains(t, w.Body.String(), `"effectiveFrom":"2027-01-01T00:00:00Z"`)
+}
+
+func TestAdminOssReportFormats(t *testing.T) {
+	server, _ := newTestServer(t)
+	previousAdmins := config.Config.AdminAddresses
+	config.Config.AdminAddresses = []string{testUserAddress}
+	t.Cleanup(func() { config.Config.AdminAddresses = previousAdmins })
+
+	w := doRequest(t, server, http.MethodGet, "/admin/oss-report?year=2026&quarter=5", "", true)
+	require.Equal(t, http.StatusBadRequest, w.Code, w.Body.String())
+	w = doRequest(t, server, http.MethodGet, "/admin/oss-report?year=2026", "", true)
+	require.Equal(t, http.StatusBadRequest, w.Code, w.Body.String())
+
+	w = doRequest(t, server, http.MethodGet, "/admin/oss-report?year=2026&quarter=3", "", true)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	require.Contains(t, w.Body.String(), `"lines":[]`)
+
+	w = doRequest(t, server, http.MethodGet, "/admin/oss-report?year=2026&quarter=3&format=csv", "", true)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	require.Equal(t, "attachment; filename=oss_report_2026_q3.csv", w.Header().Get("Content-Disposition"))
+	require.Contains(t, w.Body.String(), "Member state;VAT rate")
+
+	w = doRequest(t, server, http.MethodGet, "/admin/oss-report?year=2026&quarter=3&format=xml", "", true)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	require.Contains(t, w.Body.String(), "<OSSReturn>")
+}
+
+func TestTokenSupplyReadsLatestStats(t *testing.T) {
+	server, repos := newTestServer(t)
+
+	oneToken := new(big.Int).Exp(big.NewInt(10), big.NewInt(18), nil)
+	require.NoError(t, repos.Stats.Create(&model.Stats{
+		CreationTimestamp: time.Now().UTC(),
+		TotalSupply:       new(big.Int).Mul(big.NewInt(1000), oneToken),
+		TeamWalletsSupply: new(big.Int).Mul(big.NewInt(250), oneToken),
+	}))
+
+	w := doRequest(t, server, http.MethodGet, "/token/supply?extract=circulatingSupply", "", false)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	require.Equal(t, "750", w.Body.String())
+}
@@ -0,0 +1,294 @@
+package rpc
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+)
+
+// Priority orders the calls waiting for the budget of a provider.
+type Priority int
+
+const (
+	// PriorityBackfill is for cron jobs and indexing, these calls never take
+	// the share of the budget kept for the others
+	PriorityBackfill Priority = iota
+	PriorityNormal
+	// PriorityUser is for calls a user is waiting on, like signature checks
+	PriorityUser
+	priorityCount
+)
+
+// backfillReserve is the share of the bucket backfill calls leave untouched.
+const backfillReserve = 0.25
+
+func (p Priority) String() string {
+	switch p {
+	case PriorityBackfill:
+		return "backfill"
+	case PriorityUser:
+		return "user"
+	default:
+		return "normal"
+	}
+}
+
+type priorityKey struct{}
+
+// WithPriority sets the priority of the calls made with ctx, calls default to
+// PriorityNormal.
+func WithPriority(ctx context.Context, priority Priority) context.Context {
+	return context.WithValue(ctx, priorityKey{}, priority)
+}
+
+func priorityFrom(ctx context.Context) Priority {
+	if priority, ok := ctx.Value(priorityKey{}).(Priority); ok && priority >= 0 && priority < priorityCount {
+		return priority
+	}
+	return PriorityNormal
+}
+
+// BudgetUsage reports what a provider budget has been spent on.
+type BudgetUsage struct {
+	CreditsPerSecond float64                  `json:"creditsPerSecond"`
+	Available        float64                  `json:"available"`
+	Queued           int                      `json:"queued"`
+	Methods          map[string]MethodUsage   `json:"methods"`
+	Priorities       map[string]PriorityUsage `json:"priorities"`
+}
+
+type MethodUsage struct {
+	Calls   uint64  `json:"calls"`
+	Credits float64 `json:"credits"`
+}
+
+type PriorityUsage struct {
+	Calls uint64 `json:"calls"`
+	// Waits counts the calls that had to wait for credits
+	Waits       uint64  `json:"waits"`
+	WaitSeconds float64 `json:"waitSeconds"`
+}
+
+// budget is the token bucket of a provider, refilled at rate credits per
+// second. Waiting calls are served by priority, then in arrival order.
+type budget struct {
+	mu      sync.Mutex
+	rate    float64
+	burst   float64
+	tokens  float64
+	last    time.Time
+	credits map[string]float64
+	queues  [priorityCount][]*budgetWaiter
+	timer   *time.Timer
+	now     func() time.Time
+
+	methods    map[string]MethodUsage
+	priorities [priorityCount]PriorityUsage
+}
+
+type budgetWaiter struct {
+	cost    float64
+	ready   chan struct{}
+	granted bool
+}
+
+// newBudget returns the budget of a provider plan. With credits per second
+// every method costs its configured credits, defaulting to 1, otherwise every
+// call costs 1 out of requests per second. A zero rate means no limit.
+func newBudget(requestsPerSecond, creditsPerSecond float64, methodCredits map[string]float64) *budget {
+	b := &budget{
+		rate:    requestsPerSecond,
+		credits: map[string]float64{},
+		now:     time.Now,
+		methods: map[string]MethodUsage{},
+	}
+	if creditsPerSecond > 0 {
+		b.rate = creditsPerSecond
+		for method, credits := range methodCredits {
+			b.credits[method] = credits
+		}
+	}
+
+	b.burst = math.Max(1, math.Ceil(b.rate))
+	for _, credits := range b.credits {
+		b.burst = math.Max(b.burst, credits)
+	}
+	b.tokens = b.burst
+	return b
+}
+
+func (b *budget) cost(method string) float64 {
+	if credits, ok := b.credits[method]; ok && credits > 0 {
+		return credits
+	}
+	return 1
+}
+
+// floor is the credits a call of priority has to leave in the bucket.
+func (b *budget) floor(priority Priority, cost float64) float64 {
+	if priority != PriorityBackfill {
+		return 0
+	}
+	return math.Min(b.burst*backfillReserve, b.burst-cost)
+}
+
+func (b *budget) refill() {
+	now := b.now()
+	if !b.last.IsZero() {
+		b.tokens = math.Min(b.burst, b.tokens+now.Sub(b.last).Seconds()*b.rate)
+	}
+	b.last = now
+}
+
+// canTake reports whether a call can be served right away, calls queued with
+// the same or a higher priority go first.
+func (b *budget) canTake(priority Priority, cost float64) bool {
+	for p := priority; p < priorityCount; p++ {
+		if len(b.queues[p]) > 0 {
+			return false
+		}
+	}
+	return b.tokens-cost >= b.floor(priority, cost)
+}
+
+// available reports whether a call of method would be served without waiting.
+func (b *budget) available(method string, priority Priority) bool {
+	if b.rate <= 0 {
+		return true
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.refill()
+	return b.canTake(priority, b.cost(method))
+}
+
+// acquire takes the credits of a call of method, waiting for them when the
+// bucket is short.
+func (b *budget) acquire(ctx context.Context, method string, priority Priority) error {
+	b.mu.Lock()
+	cost := b.cost(method)
+	b.record(method, priority, cost)
+	if b.rate <= 0 {
+		b.mu.Unlock()
+		return nil
+	}
+
+	b.refill()
+	if b.canTake(priority, cost) {
+		b.tokens -= cost
+		b.mu.Unlock()
+		return nil
+	}
+
+	waiter := &budgetWaiter{cost: cost, ready: make(chan struct{})}
+	b.queues[priority] = append(b.queues[priority], waiter)
+	b.priorities[priority].Waits++
+	b.schedule()
+	b.mu.Unlock()
+
+	start := b.now()
+	select {
+	case <-waiter.ready:
+		b.mu.Lock()
+		b.priorities[priority].WaitSeconds += b.now().Sub(start).Seconds()
+		b.mu.Unlock()
+		return nil
+	case <-ctx.Done():
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		b.priorities[priority].WaitSeconds += b.now().Sub(start).Seconds()
+		if waiter.granted {
+			b.tokens += cost
+		} else {
+			b.remove(priority, waiter)
+		}
+		b.schedule()
+		return ctx.Err()
+	}
+}
+
+func (b *budget) record(method string, priority Priority, cost float64) {
+	usage := b.methods[method]
+	usage.Calls++
+	usage.Credits += cost
+	b.methods[method] = usage
+	b.priorities[priority].Calls++
+}
+
+func (b *budget) remove(priority Priority, waiter *budgetWaiter) {
+	queue := b.queues[priority]
+	for i, queued := range queue {
+		if queued == waiter {
+			b.queues[priority] = append(queue[:i], queue[i+1:]...)
+			return
+		}
+	}
+}
+
+// dispatch serves the waiting calls the bucket has credits for.
+func (b *budget) dispatch() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.refill()
+	for p := priorityCount - 1; p >= 0; p-- {
+		for len(b.queues[p]) > 0 {
+			waiter := b.queues[p][0]
+			if b.tokens-waiter.cost < b.floor(p, waiter.cost) {
+				// lower priorities wait behind this call
+				b.schedule()
+				return
+			}
+			b.tokens -= waiter.cost
+			waiter.granted = true
+			close(waiter.ready)
+			b.queues[p] = b.queues[p][1:]
+		}
+	}
+	b.schedule()
+}
+
+// schedule arms the timer for the time the first waiting call can be served.
+func (b *budget) schedule() {
+	if b.timer != nil {
+		b.timer.Stop()
+		b.timer = nil
+	}
+
+	for p := priorityCount - 1; p >= 0; p-- {
+		if len(b.queues[p]) == 0 {
+			continue
+		}
+		waiter := b.queues[p][0]
+		missing := waiter.cost + b.floor(p, waiter.cost) - b.tokens
+		delay := time.Duration(math.Max(0, missing) / b.rate * float64(time.Second))
+		b.timer = time.AfterFunc(delay, b.dispatch)
+		return
+	}
+}
+
+func (b *budget) usage() BudgetUsage {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.rate > 0 {
+		b.refill()
+	}
+	usage := BudgetUsage{
+		CreditsPerSecond: b.rate,
+		Available:        b.tokens,
+		Methods:          make(map[string]MethodUsage, len(b.methods)),
+		Priorities:       make(map[string]PriorityUsage, priorityCount),
+	}
+	for method, methodUsage := range b.methods {
+		usage.Methods[method] = methodUsage
+	}
+	for p := Priority(0); p < priorityCount; p++ {
+		usage.Queued += len(b.queues[p])
+		usage.Priorities[p.String()] = b.priorities[p]
+	}
+	return usage
+}
@@ -0,0 +1,76 @@
+package rpc
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestBudgetChargesMethodCreditsAndKeepsReserveFromBackfill(t *testing.T) {
+	b := newBudget(0, 100, map[string]float64{methodGetLogs: 75, methodCall: 26})
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	b.now = func() time.Time { return now }
+
+	require.NoError(t, b.acquire(context.Background(), methodGetLogs, PriorityNormal))
+	require.False(t, b.available(methodCall, PriorityNormal))
+
+	now = now.Add(time.Second)
+	require.True(t, b.available(methodGetLogs, PriorityBackfill))
+	require.NoError(t, b.acquire(context.Background(), methodGetLogs, PriorityBackfill))
+
+	now = now.Add(10 * time.Millisecond)
+	require.True(t, b.available(methodCall, PriorityUser))
+	require.False(t, b.available(methodCall, PriorityBackfill))
+
+	usage := b.usage()
+	require.Equal(t, 100.0, usage.CreditsPerSecond)
+	require.Equal(t, MethodUsage{Calls: 2, Credits: 150}, usage.Methods[methodGetLogs])
+	require.Equal(t, uint64(1), usage.Priorities["backfill"].Calls)
+	require.Zero(t, usage.Priorities["backfill"].Waits)
+}
+
+func TestBudgetServesUserCallsBeforeQueuedBackfill(t *testing.T) {
+	b := newBudget(20, 0, nil)
+	for i := 0; i < 20; i++ {
+		require.NoError(t, b.acquire(context.Background(), methodCall, PriorityNormal))
+	}
+
+	served := make(chan Priority, 2)
+	go func() {
+		require.NoError(t, b.acquire(context.Background(), methodGetLogs, PriorityBackfill))
+		served <- PriorityBackfill
+	}()
+	require.Eventually(t, func() bool { return b.usage().Queued == 1 }, time.Second, time.Millisecond)
+	go func() {
+		require.NoError(t, b.acquire(context.Background(), methodCall, PriorityUser))
+		served <- PriorityUser
+	}()
+
+	require.Equal(t, PriorityUser, <-served)
+	require.Equal(t, PriorityBackfill, <-served)
+
+	usage := b.usage()
+	require.Equal(t, uint64(1), usage.Priorities["user"].Waits)
+	require.Equal(t, uint64(1), usage.Priorities["backfill"].Waits)
+	require.Positive(t, usage.Priorities["backfill"].WaitSeconds)
+}
+
+func TestBudgetDropsWaitingCallOnCancel(t *testing.T) {
+	b := newBudget(1, 0, nil)
+	require.NoError(t, b.acquire(context.Background(), methodCall, PriorityNormal))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	err := b.acquire(ctx, methodCall, PriorityNormal)
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+	require.Zero(t, b.usage().Queued)
+
+	require.True(t, newBudget(0, 0, nil).available(methodGetLogs, PriorityBackfill))
+}
+
+func TestWithPrioritySetsCallPriority(t *testing.T) {
+	require.Equal(t, PriorityNormal, priorityFrom(context.Background()))
+	require.Equal(t, PriorityUser, priorityFrom(WithPriority(context.Background(), PriorityUser)))
+}
@@ -0,0 +1,93 @@
+package rpc
+
+import (
+	"context"
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/NaeuralEdgeProtocol/ratio1-backend/config"
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+const (
+	methodCall      = "eth_call"
+	methodGetLogs   = "eth_getLogs"
+	methodGetHeader = "eth_getBlockByNumber"
+)
+
+const (
+	defaultMaxFailures = 3
+	defaultCooldown    = time.Minute
+)
+
+// Client is the subset of the ethereum json-rpc api used by the backend.
+type Client interface {
+	CallContract(ctx context.Context, msg ethereum.CallMsg, blockNumber *big.Int) ([]byte, error)
+	FilterLogs(ctx context.Context, query ethereum.FilterQuery) ([]types.Log, error)
+	HeaderByNumber(ctx context.Context, number *big.Int) (*types.Header, error)
+}
+
+var (
+	clientMu sync.Mutex
+	client   Client
+)
+
+// Get returns the shared client, the provider pool is built from the
+// configuration on first use.
+func Get() Client {
+	clientMu.Lock()
+	defer clientMu.Unlock()
+
+	if client == nil {
+		client = NewPool(ConfiguredProviders(), PoolOptions{
+			MaxFailures: config.Config.Rpc.MaxFailures,
+			Cooldown:    time.Duration(config.Config.Rpc.CooldownSeconds) * time.Second,
+		})
+	}
+	return client
+}
+
+// SetClient replaces the shared client and returns the previous one, tests use
+// it to replay fixtures.
+func SetClient(c Client) Client {
+	clientMu.Lock()
+	defer clientMu.Unlock()
+
+	previous := client
+	client = c
+	return previous
+}
+
+// Status returns the health and budget usage of the providers of the shared
+// client, nil when it is not a provider pool.
+func Status() []ProviderHealth {
+	if pool, ok := Get().(*Pool); ok {
+		return pool.Health()
+	}
+	return nil
+}
+
+// ConfiguredProviders returns the providers of the configuration, falling back
+// to the infura endpoint when none is listed.
+func ConfiguredProviders() []Provider {
+	if len(config.Config.Rpc.Providers) == 0 {
+		return []Provider{{
+			Name: "infura",
+			Url:  config.Config.Infura.ApiUrl + config.Config.Infura.Secret,
+		}}
+	}
+
+	providers := make([]Provider, 0, len(config.Config.Rpc.Providers))
+	for _, p := range config.Config.Rpc.Providers {
+		providers = append(providers, Provider{
+			Name:              p.Name,
+			Url:               p.ApiUrl + p.Secret,
+			RequestsPerSecond: p.RequestsPerSecond,
+			CreditsPerSecond:  p.CreditsPerSecond,
+			MethodCredits:     p.MethodCredits,
+		})
+	}
+	return providers
+}
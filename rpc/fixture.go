@@ -0,0 +1,238 @@
+package rpc
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"os"
+	"sync"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+var ErrFixtureNotFound = errors.New("no recorded rpc response")
+
+// Fixture is a recorded rpc response.
+type Fixture struct {
+	Method string          `json:"method"`
+	Params json.RawMessage `json:"params"`
+	Result json.RawMessage `json:"result,omitempty"`
+	Error  string          `json:"error,omitempty"`
+}
+
+type callParams struct {
+	From  common.Address  `json:"from"`
+	To    *common.Address `json:"to"`
+	Data  hexutil.Bytes   `json:"data"`
+	Block *hexutil.Big    `json:"block"`
+}
+
+type filterParams struct {
+	BlockHash *common.Hash     `json:"blockHash,omitempty"`
+	FromBlock *hexutil.Big     `json:"fromBlock"`
+	ToBlock   *hexutil.Big     `json:"toBlock"`
+	Addresses []common.Address `json:"addresses"`
+	Topics    [][]common.Hash  `json:"topics"`
+}
+
+type headerParams struct {
+	Number *hexutil.Big `json:"number"`
+}
+
+// FixtureBackend replays recorded responses, a call without a recording fails
+// with ErrFixtureNotFound so tests never reach the network.
+type FixtureBackend struct {
+	mu       sync.RWMutex
+	order    []string
+	fixtures map[string]Fixture
+}
+
+func NewFixtureBackend() *FixtureBackend {
+	return &FixtureBackend{fixtures: make(map[string]Fixture)}
+}
+
+// LoadFixtures reads a file written by Save.
+func LoadFixtures(path string) (*FixtureBackend, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, errors.New("error while reading fixtures: " + err.Error())
+	}
+
+	var fixtures []Fixture
+	err = json.Unmarshal(data, &fixtures)
+	if err != nil {
+		return nil, errors.New("error while decoding fixtures: " + err.Error())
+	}
+
+	backend := NewFixtureBackend()
+	for _, fixture := range fixtures {
+		backend.add(fixture)
+	}
+	return backend, nil
+}
+
+// Save writes the fixtures in recording order.
+func (f *FixtureBackend) Save(path string) error {
+	f.mu.RLock()
+	fixtures := make([]Fixture, 0, len(f.order))
+	for _, key := range f.order {
+		fixtures = append(fixtures, f.fixtures[key])
+	}
+	f.mu.RUnlock()
+
+	data, err := json.MarshalIndent(fixtures, "", "  ")
+	if err != nil {
+		return errors.New("error while encoding fixtures: " + err.Error())
+	}
+	return os.WriteFile(path, append(data, '\n'), 0644)
+}
+
+func (f *FixtureBackend) AddCall(msg ethereum.CallMsg, blockNumber *big.Int, result []byte, err error) error {
+	return f.record(methodCall, newCallParams(msg, blockNumber), hexutil.Bytes(result), err)
+}
+
+func (f *FixtureBackend) AddLogs(query ethereum.FilterQuery, logs []types.Log, err error) error {
+	if logs == nil {
+		logs = []types.Log{}
+	}
+	return f.record(methodGetLogs, newFilterParams(query), logs, err)
+}
+
+func (f *FixtureBackend) AddHeader(number *big.Int, header *types.Header, err error) error {
+	if header != nil && header.Difficulty == nil {
+		withDifficulty := types.CopyHeader(header)
+		withDifficulty.Difficulty = big.NewInt(0)
+		header = withDifficulty
+	}
+	return f.record(methodGetHeader, headerParams{Number: toHexBig(number)}, header, err)
+}
+
+func (f *FixtureBackend) CallContract(_ context.Context, msg ethereum.CallMsg, blockNumber *big.Int) ([]byte, error) {
+	var result hexutil.Bytes
+	err := f.replay(methodCall, newCallParams(msg, blockNumber), &result)
+	return result, err
+}
+
+func (f *FixtureBackend) FilterLogs(_ context.Context, query ethereum.FilterQuery) ([]types.Log, error) {
+	var logs []types.Log
+	err := f.replay(methodGetLogs, newFilterParams(query), &logs)
+	return logs, err
+}
+
+func (f *FixtureBackend) HeaderByNumber(_ context.Context, number *big.Int) (*types.Header, error) {
+	var header *types.Header
+	err := f.replay(methodGetHeader, headerParams{Number: toHexBig(number)}, &header)
+	return header, err
+}
+
+func (f *FixtureBackend) record(method string, params any, result any, callErr error) error {
+	encodedParams, err := json.Marshal(params)
+	if err != nil {
+		return errors.New("error while encoding params: " + err.Error())
+	}
+
+	fixture := Fixture{Method: method, Params: encodedParams}
+	if callErr != nil {
+		fixture.Error = callErr.Error()
+	} else {
+		fixture.Result, err = json.Marshal(result)
+		if err != nil {
+			return errors.New("error while encoding result: " + err.Error())
+		}
+	}
+	f.add(fixture)
+	return nil
+}
+
+func (f *FixtureBackend) add(fixture Fixture) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	var params bytes.Buffer
+	if err := json.Compact(&params, fixture.Params); err == nil {
+		fixture.Params = params.Bytes()
+	}
+	key := fixture.Method + string(fixture.Params)
+	if _, ok := f.fixtures[key]; !ok {
+		f.order = append(f.order, key)
+	}
+	f.fixtures[key] = fixture
+}
+
+func (f *FixtureBackend) replay(method string, params any, result any) error {
+	encodedParams, err := json.Marshal(params)
+	if err != nil {
+		return errors.New("error while encoding params: " + err.Error())
+	}
+
+	f.mu.RLock()
+	fixture, ok := f.fixtures[method+string(encodedParams)]
+	f.mu.RUnlock()
+	if !ok {
+		return errors.New(ErrFixtureNotFound.Error() + " for " + method + " " + string(encodedParams))
+	} else if fixture.Error != "" {
+		return errors.New(fixture.Error)
+	}
+	return json.Unmarshal(fixture.Result, result)
+}
+
+// Recorder forwards calls to a client and records the responses, saving them
+// produces a fixture file for FixtureBackend.
+type Recorder struct {
+	Client   Client
+	Fixtures *FixtureBackend
+}
+
+func NewRecorder(client Client) *Recorder {
+	return &Recorder{Client: client, Fixtures: NewFixtureBackend()}
+}
+
+func (r *Recorder) CallContract(ctx context.Context, msg ethereum.CallMsg, blockNumber *big.Int) ([]byte, error) {
+	result, err := r.Client.CallContract(ctx, msg, blockNumber)
+	if recordErr := r.Fixtures.AddCall(msg, blockNumber, result, err); recordErr != nil {
+		return nil, recordErr
+	}
+	return result, err
+}
+
+func (r *Recorder) FilterLogs(ctx context.Context, query ethereum.FilterQuery) ([]types.Log, error) {
+	logs, err := r.Client.FilterLogs(ctx, query)
+	if recordErr := r.Fixtures.AddLogs(query, logs, err); recordErr != nil {
+		return nil, recordErr
+	}
+	return logs, err
+}
+
+func (r *Recorder) HeaderByNumber(ctx context.Context, number *big.Int) (*types.Header, error) {
+	header, err := r.Client.HeaderByNumber(ctx, number)
+	if recordErr := r.Fixtures.AddHeader(number, header, err); recordErr != nil {
+		return nil, recordErr
+	}
+	return header, err
+}
+
+func newCallParams(msg ethereum.CallMsg, blockNumber *big.Int) callParams {
+	return callParams{From: msg.From, To: msg.To, Data: msg.Data, Block: toHexBig(blockNumber)}
+}
+
+func newFilterParams(query ethereum.FilterQuery) filterParams {
+	return filterParams{
+		BlockHash: query.BlockHash,
+		FromBlock: toHexBig(query.FromBlock),
+		ToBlock:   toHexBig(query.ToBlock),
+		Addresses: query.Addresses,
+		Topics:    query.Topics,
+	}
+}
+
+func toHexBig(n *big.Int) *hexutil.Big {
+	if n == nil {
+		return nil
+	}
+	return (*hexutil.Big)(n)
+}
@@ -0,0 +1,78 @@
+package rpc
+
+import (
+	"context"
+	"math/big"
+	"path/filepath"
+	"testing"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/stretchr/testify/require"
+)
+
+type scriptedClient struct{}
+
+func (scriptedClient) CallContract(_ context.Context, msg ethereum.CallMsg, _ *big.Int) ([]byte, error) {
+	return append([]byte{0x01}, msg.Data...), nil
+}
+
+func (scriptedClient) FilterLogs(_ context.Context, query ethereum.FilterQuery) ([]types.Log, error) {
+	return []types.Log{{
+		Address:     query.Addresses[0],
+		Topics:      []common.Hash{query.Topics[0][0]},
+		Data:        []byte{0x2a},
+		BlockNumber: query.FromBlock.Uint64(),
+		TxHash:      common.HexToHash("0x01"),
+	}}, nil
+}
+
+func (scriptedClient) HeaderByNumber(_ context.Context, number *big.Int) (*types.Header, error) {
+	if number == nil {
+		number = big.NewInt(100)
+	}
+	return &types.Header{Number: number, Time: 1700000000}, nil
+}
+
+func TestRecordedFixturesReplayOffline(t *testing.T) {
+	ctx := context.Background()
+	recorder := NewRecorder(scriptedClient{})
+	contract := common.HexToAddress("0xa1")
+	query := ethereum.FilterQuery{
+		FromBlock: big.NewInt(10),
+		ToBlock:   big.NewInt(20),
+		Addresses: []common.Address{contract},
+		Topics:    [][]common.Hash{{common.HexToHash("0xbeef")}, {}},
+	}
+
+	result, err := recorder.CallContract(ctx, ethereum.CallMsg{To: &contract, Data: []byte{0x02}}, nil)
+	require.NoError(t, err)
+	logs, err := recorder.FilterLogs(ctx, query)
+	require.NoError(t, err)
+	head, err := recorder.HeaderByNumber(ctx, nil)
+	require.NoError(t, err)
+
+	path := filepath.Join(t.TempDir(), "fixtures.json")
+	require.NoError(t, recorder.Fixtures.Save(path))
+	backend, err := LoadFixtures(path)
+	require.NoError(t, err)
+
+	replayedResult, err := backend.CallContract(ctx, ethereum.CallMsg{To: &contract, Data: []byte{0x02}}, nil)
+	require.NoError(t, err)
+	require.Equal(t, result, replayedResult)
+
+	replayedLogs, err := backend.FilterLogs(ctx, query)
+	require.NoError(t, err)
+	require.Len(t, replayedLogs, 1)
+	require.Equal(t, logs[0].BlockNumber, replayedLogs[0].BlockNumber)
+	require.Equal(t, logs[0].Data, replayedLogs[0].Data)
+
+	replayedHead, err := backend.HeaderByNumber(ctx, nil)
+	require.NoError(t, err)
+	require.Equal(t, head.Number.Int64(), replayedHead.Number.Int64())
+	require.Equal(t, head.Hash(), replayedHead.Hash())
+
+	_, err = backend.CallContract(ctx, ethereum.CallMsg{To: &contract, Data: []byte{0x03}}, nil)
+	require.ErrorContains(t, err, ErrFixtureNotFound.Error())
+}
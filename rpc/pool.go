@@ -0,0 +1,338 @@
+package rpc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethclient"
+	gethrpc "github.com/ethereum/go-ethereum/rpc"
+)
+
+var ErrNoProviders = errors.New("no rpc provider configured")
+
+// latencyWeight is the weight of the latest call in the moving average of the
+// provider latency.
+const latencyWeight = 0.2
+
+// Provider is an rpc endpoint of the pool.
+type Provider struct {
+	Name string
+	Url  string
+	// RequestsPerSecond limits the calls sent to the provider, 0 means no limit
+	RequestsPerSecond float64
+	// CreditsPerSecond replaces RequestsPerSecond for plans billing every
+	// method its own MethodCredits
+	CreditsPerSecond float64
+	MethodCredits    map[string]float64
+}
+
+type PoolOptions struct {
+	// MaxFailures is the number of consecutive failures after which a provider
+	// is put on cooldown
+	MaxFailures int
+	Cooldown    time.Duration
+	// Dial connects to a provider url, ethclient when nil
+	Dial func(url string) (Client, func(), error)
+}
+
+// ProviderHealth is a snapshot of the state of a provider.
+type ProviderHealth struct {
+	Name           string      `json:"name"`
+	Healthy        bool        `json:"healthy"`
+	Failures       int         `json:"failures"`
+	LatencyMs      int64       `json:"latencyMs"`
+	UnhealthyUntil time.Time   `json:"unhealthyUntil"`
+	Budget         BudgetUsage `json:"budget"`
+}
+
+// Pool sends every call to the healthiest provider and fails over to the next
+// one when a provider is unreachable, rate limited or erroring. Calls are paced
+// by the budget of the provider plan, a provider out of credits is skipped
+// when another one has some left. Connections are dialed once and reused.
+type Pool struct {
+	providers   []*provider
+	maxFailures int
+	cooldown    time.Duration
+	dial        func(url string) (Client, func(), error)
+	now         func() time.Time
+}
+
+type provider struct {
+	Provider
+	budget *budget
+
+	mu             sync.Mutex
+	client         Client
+	close          func()
+	failures       int
+	latency        time.Duration
+	unhealthyUntil time.Time
+}
+
+func NewPool(providers []Provider, options PoolOptions) *Pool {
+	pool := &Pool{
+		maxFailures: options.MaxFailures,
+		cooldown:    options.Cooldown,
+		dial:        options.Dial,
+		now:         time.Now,
+	}
+	if pool.maxFailures <= 0 {
+		pool.maxFailures = defaultMaxFailures
+	}
+	if pool.cooldown <= 0 {
+		pool.cooldown = defaultCooldown
+	}
+	if pool.dial == nil {
+		pool.dial = dialEthClient
+	}
+	for _, p := range providers {
+		pool.providers = append(pool.providers, &provider{
+			Provider: p,
+			budget:   newBudget(p.RequestsPerSecond, p.CreditsPerSecond, p.MethodCredits),
+		})
+	}
+	return pool
+}
+
+func dialEthClient(url string) (Client, func(), error) {
+	client, err := ethclient.Dial(url)
+	if err != nil {
+		return nil, nil, err
+	}
+	return client, client.Close, nil
+}
+
+func (p *Pool) CallContract(ctx context.Context, msg ethereum.CallMsg, blockNumber *big.Int) ([]byte, error) {
+	var result []byte
+	err := p.do(ctx, methodCall, func(client Client) error {
+		var err error
+		result, err = client.CallContract(ctx, msg, blockNumber)
+		return err
+	})
+	return result, err
+}
+
+func (p *Pool) FilterLogs(ctx context.Context, query ethereum.FilterQuery) ([]types.Log, error) {
+	var logs []types.Log
+	err := p.do(ctx, methodGetLogs, func(client Client) error {
+		var err error
+		logs, err = client.FilterLogs(ctx, query)
+		return err
+	})
+	return logs, err
+}
+
+func (p *Pool) HeaderByNumber(ctx context.Context, number *big.Int) (*types.Header, error) {
+	var header *types.Header
+	err := p.do(ctx, methodGetHeader, func(client Client) error {
+		var err error
+		header, err = client.HeaderByNumber(ctx, number)
+		return err
+	})
+	return header, err
+}
+
+// Health returns the state and the budget usage of the providers in
+// configuration order.
+func (p *Pool) Health() []ProviderHealth {
+	now := p.now()
+	health := make([]ProviderHealth, 0, len(p.providers))
+	for _, provider := range p.providers {
+		provider.mu.Lock()
+		health = append(health, ProviderHealth{
+			Name:           provider.Name,
+			Healthy:        !now.Before(provider.unhealthyUntil),
+			Failures:       provider.failures,
+			LatencyMs:      provider.latency.Milliseconds(),
+			UnhealthyUntil: provider.unhealthyUntil,
+		})
+		provider.mu.Unlock()
+		health[len(health)-1].Budget = provider.budget.usage()
+	}
+	return health
+}
+
+// Close closes the dialed connections.
+func (p *Pool) Close() {
+	for _, provider := range p.providers {
+		provider.mu.Lock()
+		if provider.close != nil {
+			provider.close()
+		}
+		provider.client, provider.close = nil, nil
+		provider.mu.Unlock()
+	}
+}
+
+func (p *Pool) do(ctx context.Context, method string, call func(Client) error) error {
+	if len(p.providers) == 0 {
+		return ErrNoProviders
+	}
+
+	priority := priorityFrom(ctx)
+	var failures []string
+	var lastErr error
+	ordered := p.ordered()
+	for i, provider := range ordered {
+		if !provider.budget.available(method, priority) && p.anyAvailable(ordered[i+1:], method, priority) {
+			continue
+		}
+
+		client, err := provider.connect(p.dial)
+		if err != nil {
+			p.markFailure(provider)
+			failures = append(failures, provider.Name+": "+err.Error())
+			lastErr = err
+			continue
+		}
+
+		err = provider.budget.acquire(ctx, method, priority)
+		if err != nil {
+			return err
+		}
+
+		start := p.now()
+		err = call(client)
+		if err == nil || !isProviderError(err) {
+			p.markSuccess(provider, p.now().Sub(start))
+			return err
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		p.markFailure(provider)
+		failures = append(failures, provider.Name+": "+err.Error())
+		lastErr = err
+	}
+
+	return fmt.Errorf("all rpc providers failed (%s): %w", strings.Join(failures, "; "), lastErr)
+}
+
+// anyAvailable reports whether a healthy provider can serve the call without
+// waiting for credits.
+func (p *Pool) anyAvailable(providers []*provider, method string, priority Priority) bool {
+	now := p.now()
+	for _, provider := range providers {
+		provider.mu.Lock()
+		healthy := !now.Before(provider.unhealthyUntil)
+		provider.mu.Unlock()
+		if healthy && provider.budget.available(method, priority) {
+			return true
+		}
+	}
+	return false
+}
+
+// ordered returns the healthy providers by latency and failures, then the ones
+// on cooldown by the time they are back. Ties keep the configuration order.
+func (p *Pool) ordered() []*provider {
+	now := p.now()
+	type candidate struct {
+		provider *provider
+		healthy  bool
+		score    float64
+		until    time.Time
+	}
+
+	candidates := make([]candidate, 0, len(p.providers))
+	for _, provider := range p.providers {
+		provider.mu.Lock()
+		candidates = append(candidates, candidate{
+			provider: provider,
+			healthy:  !now.Before(provider.unhealthyUntil),
+			score:    float64(provider.latency) * float64(1+provider.failures),
+			until:    provider.unhealthyUntil,
+		})
+		provider.mu.Unlock()
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		if candidates[i].healthy != candidates[j].healthy {
+			return candidates[i].healthy
+		}
+		if !candidates[i].healthy {
+			return candidates[i].until.Before(candidates[j].until)
+		}
+		return candidates[i].score < candidates[j].score
+	})
+
+	ordered := make([]*provider, 0, len(candidates))
+	for _, c := range candidates {
+		ordered = append(ordered, c.provider)
+	}
+	return ordered
+}
+
+func (p *Pool) markSuccess(provider *provider, latency time.Duration) {
+	provider.mu.Lock()
+	defer provider.mu.Unlock()
+
+	provider.failures = 0
+	provider.unhealthyUntil = time.Time{}
+	if provider.latency == 0 {
+		provider.latency = latency
+	} else {
+		provider.latency = time.Duration(latencyWeight*float64(latency) + (1-latencyWeight)*float64(provider.latency))
+	}
+}
+
+func (p *Pool) markFailure(provider *provider) {
+	provider.mu.Lock()
+	defer provider.mu.Unlock()
+
+	provider.failures++
+	if provider.failures >= p.maxFailures {
+		provider.unhealthyUntil = p.now().Add(p.cooldown)
+	}
+}
+
+func (provider *provider) connect(dial func(url string) (Client, func(), error)) (Client, error) {
+	provider.mu.Lock()
+	defer provider.mu.Unlock()
+
+	if provider.client != nil {
+		return provider.client, nil
+	}
+	client, closeClient, err := dial(provider.Url)
+	if err != nil {
+		return nil, errors.New("error while dialing client: " + err.Error())
+	}
+	provider.client, provider.close = client, closeClient
+	return client, nil
+}
+
+// isProviderError reports whether err comes from the provider rather than from
+// the call itself, a reverted call or a missing block fails the same way on
+// every provider.
+func isProviderError(err error) bool {
+	if errors.Is(err, ethereum.NotFound) {
+		return false
+	}
+
+	var httpErr gethrpc.HTTPError
+	if errors.As(err, &httpErr) {
+		return true
+	}
+
+	var rpcErr gethrpc.Error
+	if errors.As(err, &rpcErr) {
+		switch rpcErr.ErrorCode() {
+		case -32005, -32603: // limit exceeded, internal error
+			return true
+		}
+		message := strings.ToLower(rpcErr.Error())
+		return strings.Contains(message, "rate limit") || strings.Contains(message, "too many requests")
+	}
+
+	// transport errors: refused connections, timeouts, malformed responses
+	return true
+}
@@ -0,0 +1,152 @@
+package rpc
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/core/types"
+	gethrpc "github.com/ethereum/go-ethereum/rpc"
+	"github.com/stretchr/testify/require"
+)
+
+type revertError struct{}
+
+func (revertError) Error() string  { return "execution reverted" }
+func (revertError) ErrorCode() int { return 3 }
+
+// stubClient answers every call with its block number or err.
+type stubClient struct {
+	block int64
+	err   error
+	calls int
+}
+
+func (s *stubClient) CallContract(context.Context, ethereum.CallMsg, *big.Int) ([]byte, error) {
+	s.calls++
+	if s.err != nil {
+		return nil, s.err
+	}
+	return big.NewInt(s.block).Bytes(), nil
+}
+
+func (s *stubClient) FilterLogs(context.Context, ethereum.FilterQuery) ([]types.Log, error) {
+	s.calls++
+	return nil, s.err
+}
+
+func (s *stubClient) HeaderByNumber(context.Context, *big.Int) (*types.Header, error) {
+	s.calls++
+	if s.err != nil {
+		return nil, s.err
+	}
+	return &types.Header{Number: big.NewInt(s.block)}, nil
+}
+
+func newStubPool(clients map[string]*stubClient, names ...string) *Pool {
+	var providers []Provider
+	for _, name := range names {
+		providers = append(providers, Provider{Name: name, Url: name})
+	}
+	return NewPool(providers, PoolOptions{
+		MaxFailures: 2,
+		Cooldown:    time.Minute,
+		Dial: func(url string) (Client, func(), error) {
+			client, ok := clients[url]
+			if !ok {
+				return nil, nil, errors.New("unknown provider " + url)
+			}
+			return client, func() {}, nil
+		},
+	})
+}
+
+func TestPoolFailsOverToNextProvider(t *testing.T) {
+	primary := &stubClient{block: 1, err: gethrpc.HTTPError{StatusCode: 429, Status: "429 Too Many Requests"}}
+	secondary := &stubClient{block: 2}
+	pool := newStubPool(map[string]*stubClient{"primary": primary, "secondary": secondary}, "primary", "secondary")
+
+	header, err := pool.HeaderByNumber(context.Background(), nil)
+	require.NoError(t, err)
+	require.Equal(t, int64(2), header.Number.Int64())
+	require.Equal(t, 1, primary.calls)
+
+	health := pool.Health()
+	require.Equal(t, 1, health[0].Failures)
+	require.True(t, health[0].Healthy)
+	require.Equal(t, 0, health[1].Failures)
+}
+
+func TestPoolSkipsProviderOnCooldown(t *testing.T) {
+	primary := &stubClient{block: 1, err: errors.New("connection refused")}
+	secondary := &stubClient{block: 2}
+	pool := newStubPool(map[string]*stubClient{"primary": primary, "secondary": secondary}, "primary", "secondary")
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	pool.now = func() time.Time { return now }
+
+	for i := 0; i < 3; i++ {
+		_, err := pool.HeaderByNumber(context.Background(), nil)
+		require.NoError(t, err)
+	}
+	require.Equal(t, 2, primary.calls)
+	require.False(t, pool.Health()[0].Healthy)
+
+	primary.err = nil
+	now = now.Add(2 * time.Minute)
+	header, err := pool.HeaderByNumber(context.Background(), nil)
+	require.NoError(t, err)
+	require.Equal(t, int64(1), header.Number.Int64())
+	require.True(t, pool.Health()[0].Healthy)
+	require.Equal(t, 0, pool.Health()[0].Failures)
+}
+
+func TestPoolReturnsCallErrorsWithoutFailover(t *testing.T) {
+	primary := &stubClient{err: revertError{}}
+	secondary := &stubClient{block: 2}
+	pool := newStubPool(map[string]*stubClient{"primary": primary, "secondary": secondary}, "primary", "secondary")
+
+	_, err := pool.CallContract(context.Background(), ethereum.CallMsg{}, nil)
+	require.ErrorIs(t, err, revertError{})
+	require.Equal(t, 0, secondary.calls)
+	require.Equal(t, 0, pool.Health()[0].Failures)
+}
+
+func TestPoolReportsEveryProviderFailure(t *testing.T) {
+	primary := &stubClient{err: errors.New("429 Too Many Requests")}
+	pool := newStubPool(map[string]*stubClient{"primary": primary}, "primary", "missing")
+
+	_, err := pool.FilterLogs(context.Background(), ethereum.FilterQuery{})
+	require.ErrorContains(t, err, "primary: 429 Too Many Requests")
+	require.ErrorContains(t, err, "missing: error while dialing client")
+
+	_, err = NewPool(nil, PoolOptions{}).HeaderByNumber(context.Background(), nil)
+	require.ErrorIs(t, err, ErrNoProviders)
+}
+
+func TestPoolSendsCallsToProviderWithCreditsLeft(t *testing.T) {
+	primary := &stubClient{block: 1}
+	secondary := &stubClient{block: 2}
+	pool := NewPool([]Provider{
+		{Name: "primary", Url: "primary", RequestsPerSecond: 0.01},
+		{Name: "secondary", Url: "secondary"},
+	}, PoolOptions{Dial: func(url string) (Client, func(), error) {
+		if url == "primary" {
+			return primary, func() {}, nil
+		}
+		return secondary, func() {}, nil
+	}})
+
+	for i := 0; i < 3; i++ {
+		_, err := pool.HeaderByNumber(context.Background(), nil)
+		require.NoError(t, err)
+	}
+	require.Equal(t, 1, primary.calls)
+	require.Equal(t, 2, secondary.calls)
+
+	health := pool.Health()
+	require.Equal(t, uint64(1), health[0].Budget.Methods[methodGetHeader].Calls)
+	require.Equal(t, uint64(2), health[1].Budget.Priorities[PriorityNormal.String()].Calls)
+}
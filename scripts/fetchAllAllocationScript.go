@@ -16,6 +16,7 @@ import (
 
 	"github.com/NaeuralEdgeProtocol/ratio1-backend/model"
 	"github.com/NaeuralEdgeProtocol/ratio1-backend/process"
+	"github.com/NaeuralEdgeProtocol/ratio1-backend/service"
 	"github.com/btcsuite/btcd/btcec/v2"
 	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/ethclient"
@@ -72,6 +73,7 @@ func GetAllAllocations() []model.Allocation {
 		return nil
 	}
 	defer client.Close()
+	useServiceBlockTimes(client)
 
 	latestBlock, err := getChainLastBlockNumber(client)
 	if err != nil {
@@ -125,7 +127,7 @@ func GetAllAllocations() []model.Allocation {
 	}
 
 	for k := range blocks {
-		v, err := getBlockTimestamp(k, client)
+		v, err := service.GetBlockTime(k)
 		if err != nil {
 			fmt.Println("cannot fetch correct timestamp for block: ", k, "with error: ", err.Error())
 			continue
@@ -5,6 +5,7 @@ import (
 	"time"
 
 	"github.com/NaeuralEdgeProtocol/ratio1-backend/model"
+	"github.com/NaeuralEdgeProtocol/ratio1-backend/service"
 	"github.com/ethereum/go-ethereum/ethclient"
 )
 
@@ -37,6 +38,7 @@ func GetBurnEvents() []model.BurnEvent {
 		return nil
 	}
 	defer client.Close()
+	useServiceBlockTimes(client)
 
 	latestBlock, err := getChainLastBlockNumber(client)
 	if err != nil {
@@ -65,7 +67,7 @@ func GetBurnEvents() []model.BurnEvent {
 	}
 
 	for k := range blocks {
-		v, err := getBlockTimestamp(k, client)
+		v, err := service.GetBlockTime(k)
 		if err != nil {
 			fmt.Println("cannot fetch correct timestamp for block: ", k, "with error: ", err.Error())
 			continue
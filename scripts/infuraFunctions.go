@@ -4,14 +4,14 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"math"
 	"math/big"
 	"strconv"
 	"strings"
-	"sync"
-	"time"
 
 	"github.com/NaeuralEdgeProtocol/ratio1-backend/model"
+	"github.com/NaeuralEdgeProtocol/ratio1-backend/rpc"
+	"github.com/NaeuralEdgeProtocol/ratio1-backend/service"
+	"github.com/NaeuralEdgeProtocol/ratio1-backend/storage/memory"
 	"github.com/ethereum/go-ethereum"
 	"github.com/ethereum/go-ethereum/accounts/abi"
 	"github.com/ethereum/go-ethereum/common"
@@ -606,144 +606,9 @@ func decodeBurnLogs(vLog types.Log) (*model.BurnEvent, error) {
 	return &result, nil
 }
 
-func getBlockTimestamp(blockNumber int64, client *ethclient.Client) (time.Time, error) {
-	header, err := client.HeaderByNumber(context.Background(), big.NewInt(blockNumber))
-	if err != nil {
-		return time.Time{}, errors.New("error while retrieving block: " + err.Error())
-	}
-
-	return time.Unix(int64(header.Time), 0).UTC(), nil
-}
-
-type headerCache struct {
-	mu sync.RWMutex
-	m  map[int64]uint64 // blockNumber -> unix ts
-}
-
-func newHeaderCache() *headerCache { return &headerCache{m: make(map[int64]uint64)} }
-
-func (c *headerCache) get(n int64) (uint64, bool) {
-	c.mu.RLock()
-	ts, ok := c.m[n]
-	c.mu.RUnlock()
-	return ts, ok
-}
-
-func (c *headerCache) put(n int64, ts uint64) {
-	c.mu.Lock()
-	c.m[n] = ts
-	c.mu.Unlock()
-}
-
-func findBlockByTimestampSmart(ctx context.Context, client *ethclient.Client, t time.Time, low, high int64) (int64, error) {
-	if low < 0 {
-		low = 0
-	}
-	target := t.UTC().Unix()
-
-	if high == 0 || high < low {
-		h, err := client.HeaderByNumber(ctx, nil)
-		if err != nil {
-			return 0, err
-		}
-		high = h.Number.Int64()
-	}
-
-	cache := newHeaderCache()
-	getTs := func(n int64) (int64, error) {
-		if n < 0 {
-			return 0, errors.New("negative block number")
-		}
-		if ts, ok := cache.get(n); ok {
-			return int64(ts), nil
-		}
-		h, err := client.HeaderByNumber(ctx, big.NewInt(n))
-		if err != nil {
-			return 0, err
-		}
-		cache.put(n, h.Time)
-		return int64(h.Time), nil
-	}
-
-	lowTs, err := getTs(low)
-	if err != nil {
-		return 0, err
-	}
-	highTs, err := getTs(high)
-	if err != nil {
-		return 0, err
-	}
-	if target <= lowTs {
-		return low, nil
-	}
-	if target > highTs {
-		return high + 1, nil
-	}
-
-	const (
-		maxIters          = 32
-		switchToBinaryGap = int64(10_000)
-	)
-
-	L, H := low, high
-	Lts, Hts := lowTs, highTs
-
-	for iter := 0; iter < maxIters && L < H; iter++ {
-		time.Sleep(100 * time.Millisecond)
-		spanBlocks := H - L
-		spanTime := Hts - Lts
-		if spanBlocks <= 0 || spanTime <= 0 {
-			break
-		}
-
-		frac := float64(target-Lts) / float64(spanTime)
-		if frac < 0 {
-			frac = 0
-		} else if frac > 1 {
-			frac = 1
-		}
-		guess := L + int64(math.Round(frac*float64(spanBlocks)))
-
-		if guess <= L {
-			guess = L + 1
-		} else if guess >= H {
-			guess = H - 1
-		}
-
-		guessTs, err := getTs(guess)
-		if err != nil {
-			return 0, err
-		}
-
-		if guessTs >= target {
-			H, Hts = guess, guessTs
-		} else {
-			L, Lts = guess+1, 0
-			if L <= H {
-				Lts, err = getTs(L)
-				if err != nil {
-					return 0, err
-				}
-			}
-		}
-
-		if H-L <= switchToBinaryGap {
-			break
-		}
-	}
-
-	// ----- Binary tighten -----
-	for L < H {
-		mid := L + (H-L)/2
-		mts, err := getTs(mid)
-		if err != nil {
-			return 0, err
-		}
-		if mts >= target {
-			H = mid
-		} else {
-			L = mid + 1
-		}
-	}
-	return L, nil
+// useServiceBlockTimes makes the block time lookups of the service package
+// query client, the headers it fetches are cached in memory for the run.
+func useServiceBlockTimes(client *ethclient.Client) {
+	rpc.SetClient(client)
+	service.SetRepositories(memory.NewRepositories())
 }
@@ -9,6 +9,7 @@ import (
 	"time"
 
 	"github.com/NaeuralEdgeProtocol/ratio1-backend/model"
+	"github.com/NaeuralEdgeProtocol/ratio1-backend/service"
 	"github.com/ethereum/go-ethereum/ethclient"
 )
 
@@ -33,12 +34,7 @@ func BackfillStatsFrom(startTs *time.Time, days int) {
 		return
 	}
 	defer client.Close()
-
-	latestBlock, err := getChainLastBlockNumber(client)
-	if err != nil {
-		fmt.Println("error getting last block number:", err)
-		return
-	}
+	useServiceBlockTimes(client)
 
 	time.Sleep(SleepTime)
 	cspAddresses, err := getAllCSPAddress(client)
@@ -61,7 +57,7 @@ func BackfillStatsFrom(startTs *time.Time, days int) {
 		dayEnd := startTs.Add(24 * time.Hour)
 		fromBlock := oldStats.LastBlockNumber + 1
 		time.Sleep(SleepTime)
-		toBlock, err := findBlockByTimestampSmart(ctx, client, dayEnd, fromBlock, latestBlock)
+		toBlock, err := service.GetBlockAtOrBefore(dayEnd.Add(-time.Nanosecond))
 		if err != nil {
 			fmt.Println("error locating toBlock:", err)
 			return
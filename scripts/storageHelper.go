@@ -8,6 +8,8 @@ import (
 	"time"
 
 	"github.com/NaeuralEdgeProtocol/ratio1-backend/model"
+	// registers the pii serializer used by the model tags
+	_ "github.com/NaeuralEdgeProtocol/ratio1-backend/storage"
 	_ "github.com/lib/pq"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
@@ -0,0 +1,341 @@
+package service
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/NaeuralEdgeProtocol/ratio1-backend/config"
+	"github.com/NaeuralEdgeProtocol/ratio1-backend/crypto"
+	"github.com/NaeuralEdgeProtocol/ratio1-backend/model"
+	"github.com/google/uuid"
+	"github.com/spruceid/siwe-go"
+)
+
+// AccountErasureStatement is the statement the user signs, with a sign in with
+// ethereum message, to request the erasure of the account.
+const AccountErasureStatement = "I request the erasure of the personal data of my Ratio1 account."
+
+const erasedValue = "erased"
+
+var (
+	ErrorAccountErasureNotFound      = errors.New("account erasure request not found")
+	ErrorAccountErasureAlreadyOpen   = errors.New("an account erasure request is already open")
+	ErrorAccountErasureNotReviewable = errors.New("account erasure request cannot be reviewed in its current status")
+)
+
+var removeSubscriberFn = RemoveSubscriber
+
+// RequestAccountErasure verifies the signed erasure request of address and
+// stores it for admin review.
+func RequestAccountErasure(address, message, signature string) (*model.AccountErasureRequest, error) {
+	err := verifyAccountErasureSignature(address, message, signature)
+	if err != nil {
+		return nil, err
+	}
+
+	open, err := repos.AccountErasures.GetOpenByAddress(address)
+	if err != nil {
+		return nil, errors.New("error while retrieving account erasure request from storage: " + err.Error())
+	} else if open != nil {
+		return nil, ErrorAccountErasureAlreadyOpen
+	}
+
+	request := &model.AccountErasureRequest{
+		Id:        uuid.New(),
+		Address:   address,
+		Status:    model.AccountErasureStatusPending,
+		Message:   message,
+		Signature: signature,
+		CreatedAt: time.Now(),
+	}
+	err = repos.AccountErasures.Create(request)
+	if err != nil {
+		return nil, errors.New("error while creating account erasure request in storage: " + err.Error())
+	}
+
+	err = recordAudit(model.AuditActionAccountErasureRequested, address, address, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return request, nil
+}
+
+func GetPendingAccountErasures() ([]model.AccountErasureRequest, error) {
+	requests, err := repos.AccountErasures.GetByStatus(model.AccountErasureStatusPending)
+	if err != nil {
+		return nil, errors.New("error while retrieving account erasure requests from storage: " + err.Error())
+	}
+	return requests, nil
+}
+
+// ApproveAccountErasure anonymizes the account of the request. An approved
+// request whose erasure failed can be approved again to retry it.
+func ApproveAccountErasure(id uuid.UUID, adminAddress string) (*model.AccountErasureRequest, error) {
+	request, err := getReviewableAccountErasure(id)
+	if err != nil {
+		return nil, err
+	}
+
+	if request.Status == model.AccountErasureStatusPending {
+		now := time.Now()
+		request.Status = model.AccountErasureStatusApproved
+		request.ReviewedBy = &adminAddress
+		request.ReviewedAt = &now
+		err = repos.AccountErasures.Update(request)
+		if err != nil {
+			return nil, errors.New("error while updating account erasure request in storage: " + err.Error())
+		}
+	}
+
+	err = eraseAccount(request.Address)
+	if err != nil {
+		return nil, errors.New("error while erasing account " + request.Address + ": " + err.Error())
+	}
+
+	completedAt := time.Now()
+	request.Status = model.AccountErasureStatusCompleted
+	request.CompletedAt = &completedAt
+	err = repos.AccountErasures.Update(request)
+	if err != nil {
+		return nil, errors.New("error while updating account erasure request in storage: " + err.Error())
+	}
+
+	details := "request " + request.Id.String()
+	err = recordAudit(model.AuditActionAccountErased, adminAddress, request.Address, &details)
+	if err != nil {
+		return nil, err
+	}
+
+	return request, nil
+}
+
+func RejectAccountErasure(id uuid.UUID, adminAddress, reason string) (*model.AccountErasureRequest, error) {
+	request, err := getReviewableAccountErasure(id)
+	if err != nil {
+		return nil, err
+	}
+	if request.Status != model.AccountErasureStatusPending {
+		return nil, ErrorAccountErasureNotReviewable
+	}
+
+	now := time.Now()
+	request.Status = model.AccountErasureStatusRejected
+	request.ReviewedBy = &adminAddress
+	request.ReviewedAt = &now
+	request.ReviewNote = &reason
+	err = repos.AccountErasures.Update(request)
+	if err != nil {
+		return nil, errors.New("error while updating account erasure request in storage: " + err.Error())
+	}
+
+	err = recordAudit(model.AuditActionAccountErasureRejected, adminAddress, request.Address, &reason)
+	if err != nil {
+		return nil, err
+	}
+
+	return request, nil
+}
+
+func getReviewableAccountErasure(id uuid.UUID) (*model.AccountErasureRequest, error) {
+	request, err := repos.AccountErasures.GetById(id)
+	if err != nil {
+		return nil, errors.New("error while retrieving account erasure request from storage: " + err.Error())
+	} else if request == nil {
+		return nil, ErrorAccountErasureNotFound
+	}
+	if request.Status != model.AccountErasureStatusPending && request.Status != model.AccountErasureStatusApproved {
+		return nil, ErrorAccountErasureNotReviewable
+	}
+	return request, nil
+}
+
+// eraseAccount anonymizes the personal data of address. Invoices and drafts
+// are accounting records, they keep the billing identity they were issued
+// with and only lose the contact email. The account exports are deleted, the
+// links already sent for them stop working. Every step can be run again.
+func eraseAccount(address string) error {
+	account, err := getAcocunt(address)
+	if err != nil {
+		return errors.New("error while retrieving account from storage: " + err.Error())
+	}
+
+	if account != nil {
+		if account.Email != nil && *account.Email != "" {
+			err = eraseKyc(*account.Email)
+			if err != nil {
+				return err
+			}
+		}
+
+		now := time.Now()
+		account.Email = nil
+		account.EmailConfirmed = false
+		account.PendingEmail = ""
+		account.PendingReceiveUpdates = false
+		account.UpdatedAt = now
+		account.ErasedAt = &now
+		err = repos.Accounts.Update(account)
+		if err != nil {
+			return errors.New("error while anonymizing account: " + err.Error())
+		}
+	}
+
+	err = repos.NotificationEmails.Delete(address)
+	if err != nil {
+		return errors.New("error while deleting notification email: " + err.Error())
+	}
+
+	err = repos.AccountExports.DeleteByAddress(address)
+	if err != nil {
+		return errors.New("error while deleting account exports: " + err.Error())
+	}
+
+	err = eraseUserInfo(address)
+	if err != nil {
+		return err
+	}
+
+	invoices, err := repos.Invoices.GetByAddress(address)
+	if err != nil {
+		return errors.New("error while retrieving invoices from storage: " + err.Error())
+	}
+	for _, invoice := range invoices {
+		if invoice.UserEmail == nil || *invoice.UserEmail == "" {
+			continue
+		}
+		empty := ""
+		invoice.UserEmail = &empty
+		err = repos.Invoices.Update(&invoice)
+		if err != nil {
+			return errors.New("error while removing email from invoice: " + err.Error())
+		}
+	}
+
+	return nil
+}
+
+func eraseKyc(email string) error {
+	kyc, found, err := repos.Kycs.GetByEmail(email)
+	if err != nil {
+		return errors.New("error while retrieving kyc from storage: " + err.Error())
+	} else if !found {
+		return nil
+	}
+
+	err = removeSubscriberFn(kyc.Email)
+	if err != nil {
+		return errors.New("error while removing subscriber from mailerlite: " + err.Error())
+	}
+
+	receiveUpdates := false
+	kyc.Email = ""
+	kyc.ReceiveUpdates = &receiveUpdates
+	kyc.IsActive = false
+	kyc.HasBeenDeleted = true
+	kyc.LastUpdated = time.Now()
+	err = repos.Kycs.Update(kyc)
+	if err != nil {
+		return errors.New("error while anonymizing kyc: " + err.Error())
+	}
+
+	return nil
+}
+
+// eraseUserInfo anonymizes the profile of address and drops its history.
+// Drafts generated before identity snapshots render the billing identity from
+// this profile, so it is kept while the address has any of them.
+func eraseUserInfo(address string) error {
+	userInfo, err := repos.UserInfos.GetByAddress(address)
+	if err != nil {
+		return errors.New("error while retrieving user info from storage: " + err.Error())
+	} else if userInfo == nil {
+		return nil
+	}
+
+	drafts, err := getAccountDrafts(address)
+	if err != nil {
+		return err
+	}
+	keepIdentity := false
+	for _, draft := range drafts {
+		if !draft.HasIdentitySnapshot() {
+			keepIdentity = true
+			break
+		}
+	}
+
+	userInfo.Email = ""
+	if !keepIdentity {
+		erased := erasedValue
+		userInfo.Name = &erased
+		userInfo.Surname = &erased
+		userInfo.CompanyName = nil
+		userInfo.IdentificationCode = erasedValue
+		userInfo.Address = erasedValue
+		userInfo.City = ""
+		userInfo.State = ""
+	}
+
+	err = repos.UserInfos.Update(userInfo)
+	if err != nil {
+		return errors.New("error while anonymizing user info: " + err.Error())
+	}
+
+	err = repos.UserInfos.DeleteVersions(address)
+	if err != nil {
+		return errors.New("error while deleting user info history: " + err.Error())
+	}
+
+	return nil
+}
+
+func verifyAccountErasureSignature(address, message, signature string) error {
+	siweMessage, err := siwe.ParseMessage(message)
+	if err != nil {
+		return errors.New("error while parsing siwe message: " + err.Error())
+	}
+
+	_, err = siweMessage.ValidNow()
+	if err != nil {
+		return errors.New("error while validating message: " + err.Error())
+	}
+
+	if !strings.EqualFold(siweMessage.GetAddress().String(), address) {
+		return errors.New("the message is not signed by the account address")
+	}
+
+	if siweMessage.GetChainID() != config.Config.ChainID {
+		return errors.New("wrong chain id in message")
+	}
+
+	statement := siweMessage.GetStatement()
+	if statement == nil || *statement != AccountErasureStatement {
+		return errors.New("the message is not an account erasure request")
+	}
+
+	_, err = siweMessage.VerifyEIP191(signature)
+	if err != nil {
+		safeErr := crypto.VerifySafeSignature(siweMessage.GetAddress().String(), message, signature)
+		if safeErr != nil {
+			return errors.New("error while verifying signature: " + safeErr.Error())
+		}
+	}
+
+	return nil
+}
+
+func recordAudit(action, actor, subject string, details *string) error {
+	err := repos.AuditLogs.Create(&model.AuditLog{
+		Action:    action,
+		Actor:     actor,
+		Subject:   subject,
+		Details:   details,
+		CreatedAt: time.Now(),
+	})
+	if err != nil {
+		return errors.New("error while recording audit log: " + err.Error())
+	}
+	return nil
+}
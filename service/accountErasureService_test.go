@@ -0,0 +1,218 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/NaeuralEdgeProtocol/ratio1-backend/config"
+	"github.com/NaeuralEdgeProtocol/ratio1-backend/model"
+	"github.com/NaeuralEdgeProtocol/ratio1-backend/storage/memory"
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	ethcrypto "github.com/ethereum/go-ethereum/crypto"
+	"github.com/google/uuid"
+	"github.com/spruceid/siwe-go"
+	"github.com/stretchr/testify/require"
+)
+
+func withAccountErasureStore(t *testing.T) *[]string {
+	previousRepos := GetRepositories()
+	previousRemoveSubscriber := removeSubscriberFn
+	previousChainId := config.Config.ChainID
+	SetRepositories(memory.NewRepositories())
+	config.Config.ChainID = 84532
+
+	var removed []string
+	removeSubscriberFn = func(email string) error {
+		removed = append(removed, email)
+		return nil
+	}
+	t.Cleanup(func() {
+		SetRepositories(previousRepos)
+		removeSubscriberFn = previousRemoveSubscriber
+		config.Config.ChainID = previousChainId
+	})
+	return &removed
+}
+
+func signErasureMessage(t *testing.T, statement string) (string, string, string) {
+	key, err := ethcrypto.GenerateKey()
+	require.NoError(t, err)
+	address := ethcrypto.PubkeyToAddress(key.PublicKey).Hex()
+
+	message, err := siwe.InitMessage("app.ratio1.ai", address, "https://app.ratio1.ai", siwe.GenerateNonce(), map[string]interface{}{
+		"statement": statement,
+		"chainId":   84532,
+	})
+	require.NoError(t, err)
+
+	signature, err := ethcrypto.Sign(accounts.TextHash([]byte(message.String())), key)
+	require.NoError(t, err)
+	signature[64] += 27
+
+	return address, message.String(), hexutil.Encode(signature)
+}
+
+func TestRequestAccountErasureVerifiesSignature(t *testing.T) {
+	withAccountErasureStore(t)
+
+	address, message, signature := signErasureMessage(t, AccountErasureStatement)
+	request, err := RequestAccountErasure(address, message, signature)
+	require.NoError(t, err)
+	require.Equal(t, model.AccountErasureStatusPending, request.Status)
+
+	_, err = RequestAccountErasure(address, message, signature)
+	require.ErrorIs(t, err, ErrorAccountErasureAlreadyOpen)
+
+	_, err = RequestAccountErasure("0x0000000000000000000000000000000000000001", message, signature)
+	require.Error(t, err)
+
+	loginAddress, loginMessage, loginSignature := signErasureMessage(t, "Sign in to Ratio1")
+	_, err = RequestAccountErasure(loginAddress, loginMessage, loginSignature)
+	require.Error(t, err)
+
+	entries, err := repos.AuditLogs.GetBySubject(address)
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	require.Equal(t, model.AuditActionAccountErasureRequested, entries[0].Action)
+}
+
+func TestApproveAccountErasureAnonymizesAccount(t *testing.T) {
+	removed := withAccountErasureStore(t)
+
+	email := "owner@example.com"
+	receiveUpdates := true
+	name := "Mario"
+	require.NoError(t, repos.Accounts.Create(&model.Account{Address: "0xowner", Email: &email, EmailConfirmed: true}))
+	require.NoError(t, repos.Kycs.CreateOrUpdate(&model.Kyc{Email: email, ReceiveUpdates: &receiveUpdates, IsActive: true}))
+	require.NoError(t, repos.NotificationEmails.CreateOrUpdate(&model.AccountNotificationEmail{AccountAddress: "0xowner", Email: &email}))
+	require.NoError(t, repos.UserInfos.Create(&model.UserInfo{BlockchainAddress: "0xowner", Email: email, Name: &name, IdentificationCode: "RSSMRA", Address: "Via Roma 1", Country: "ITA"}))
+	invoiceId := "invoice1"
+	require.NoError(t, repos.Invoices.Create(&model.InvoiceClient{Uuid: &invoiceId, BlockchainAddress: "0xowner", UserEmail: &email, Name: &name, IdentificationCode: "RSSMRA"}))
+	export := &model.AccountExport{Id: uuid.New(), Address: "0xowner", Status: model.AccountExportStatusReady, Archive: "archive", ExpiresAt: time.Now().Add(24 * time.Hour)}
+	require.NoError(t, repos.AccountExports.Create(export))
+
+	request := &model.AccountErasureRequest{Address: "0xowner", Status: model.AccountErasureStatusPending}
+	require.NoError(t, repos.AccountErasures.Create(request))
+
+	completed, err := ApproveAccountErasure(request.Id, "0xadmin")
+	require.NoError(t, err)
+	require.Equal(t, model.AccountErasureStatusCompleted, completed.Status)
+	require.Equal(t, "0xadmin", *completed.ReviewedBy)
+	require.Equal(t, []string{email}, *removed)
+
+	account, _, err := repos.Accounts.GetByAddress("0xowner")
+	require.NoError(t, err)
+	require.Nil(t, account.Email)
+	require.NotNil(t, account.ErasedAt)
+
+	_, found, err := repos.Kycs.GetByEmail(email)
+	require.NoError(t, err)
+	require.False(t, found)
+
+	_, found, err = repos.NotificationEmails.GetByAddress("0xowner")
+	require.NoError(t, err)
+	require.False(t, found)
+
+	userInfo, err := repos.UserInfos.GetByAddress("0xowner")
+	require.NoError(t, err)
+	require.Empty(t, userInfo.Email)
+	require.Equal(t, erasedValue, *userInfo.Name)
+	require.Equal(t, erasedValue, userInfo.IdentificationCode)
+	require.Equal(t, "ITA", userInfo.Country)
+
+	invoice, _, err := repos.Invoices.GetByID(invoiceId)
+	require.NoError(t, err)
+	require.Empty(t, *invoice.UserEmail)
+	require.Equal(t, "Mario", *invoice.Name)
+	require.Equal(t, "RSSMRA", invoice.IdentificationCode)
+
+	latestExport, err := repos.AccountExports.GetLatestByAddress("0xowner")
+	require.NoError(t, err)
+	require.Nil(t, latestExport)
+	downloaded, err := repos.AccountExports.GetById(export.Id)
+	require.NoError(t, err)
+	require.Nil(t, downloaded)
+
+	entries, err := repos.AuditLogs.GetBySubject("0xowner")
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	require.Equal(t, model.AuditActionAccountErased, entries[0].Action)
+	require.Equal(t, "0xadmin", entries[0].Actor)
+
+	_, err = ApproveAccountErasure(request.Id, "0xadmin")
+	require.ErrorIs(t, err, ErrorAccountErasureNotReviewable)
+}
+
+func TestApproveAccountErasureKeepsBillingIdentityOfDrafts(t *testing.T) {
+	withAccountErasureStore(t)
+
+	name := "Mario"
+	require.NoError(t, repos.UserInfos.Create(&model.UserInfo{BlockchainAddress: "0xowner", Email: "owner@example.com", Name: &name, IdentificationCode: "RSSMRA"}))
+	require.NoError(t, repos.Preferences.Create(&model.Preference{UserAddress: "0xowner", InvoiceSeries: "R1", NextNumber: 1}))
+	draft, err := generateInvoiceDraft("0xowner", "0xcsp", withTestProfiles(t, createTestAllocations(t, "0xowner", "0xcsp", "1000000")), fixedFxRates(nil))
+	require.NoError(t, err)
+
+	request := &model.AccountErasureRequest{Address: "0xowner", Status: model.AccountErasureStatusPending}
+	require.NoError(t, repos.AccountErasures.Create(request))
+	_, err = ApproveAccountErasure(request.Id, "0xadmin")
+	require.NoError(t, err)
+
+	userInfo, err := repos.UserInfos.GetByAddress("0xowner")
+	require.NoError(t, err)
+	require.Empty(t, userInfo.Email)
+	require.Equal(t, erasedValue, *userInfo.Name)
+	require.Equal(t, erasedValue, userInfo.IdentificationCode)
+
+	versions, err := repos.UserInfos.GetVersions("0xowner")
+	require.NoError(t, err)
+	require.Empty(t, versions)
+
+	drafts, err := repos.Drafts.GetListByNodeOwner("0xowner")
+	require.NoError(t, err)
+	require.Len(t, drafts, 1)
+	require.Equal(t, draft.DraftId, drafts[0].DraftId)
+	seller := drafts[0].Seller()
+	require.Equal(t, "Mario", *seller.Name)
+	require.Equal(t, "RSSMRA", seller.IdentificationCode)
+}
+
+func TestApproveAccountErasureKeepsBillingIdentityOfLegacyDrafts(t *testing.T) {
+	withAccountErasureStore(t)
+
+	name := "Mario"
+	require.NoError(t, repos.UserInfos.Create(&model.UserInfo{BlockchainAddress: "0xowner", Email: "owner@example.com", Name: &name, IdentificationCode: "RSSMRA"}))
+	require.NoError(t, repos.Drafts.Create(&model.InvoiceDraft{DraftId: uuid.New(), UserAddress: "0xowner", CspOwner: "0xcsp"}))
+
+	request := &model.AccountErasureRequest{Address: "0xowner", Status: model.AccountErasureStatusPending}
+	require.NoError(t, repos.AccountErasures.Create(request))
+	_, err := ApproveAccountErasure(request.Id, "0xadmin")
+	require.NoError(t, err)
+
+	userInfo, err := repos.UserInfos.GetByAddress("0xowner")
+	require.NoError(t, err)
+	require.Empty(t, userInfo.Email)
+	require.Equal(t, "Mario", *userInfo.Name)
+	require.Equal(t, "RSSMRA", userInfo.IdentificationCode)
+}
+
+func TestRejectAccountErasure(t *testing.T) {
+	withAccountErasureStore(t)
+
+	request := &model.AccountErasureRequest{Address: "0xowner", Status: model.AccountErasureStatusPending}
+	require.NoError(t, repos.AccountErasures.Create(request))
+
+	rejected, err := RejectAccountErasure(request.Id, "0xadmin", "open invoices")
+	require.NoError(t, err)
+	require.Equal(t, model.AccountErasureStatusRejected, rejected.Status)
+	require.Equal(t, "open invoices", *rejected.ReviewNote)
+
+	open, err := repos.AccountErasures.GetOpenByAddress("0xowner")
+	require.NoError(t, err)
+	require.Nil(t, open)
+
+	entries, err := repos.AuditLogs.GetBySubject("0xowner")
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	require.Equal(t, model.AuditActionAccountErasureRejected, entries[0].Action)
+}
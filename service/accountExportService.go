@@ -0,0 +1,302 @@
+package service
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/NaeuralEdgeProtocol/ratio1-backend/config"
+	"github.com/NaeuralEdgeProtocol/ratio1-backend/crypto"
+	"github.com/NaeuralEdgeProtocol/ratio1-backend/model"
+	"github.com/google/uuid"
+)
+
+const (
+	accountExportRetention = 24 * time.Hour
+	// a pending export older than this was interrupted, e.g. by a restart
+	accountExportTimeout = time.Hour
+)
+
+var (
+	ErrorAccountExportNotFound = errors.New("account export not found")
+	ErrorAccountExportNotReady = errors.New("account export is not ready")
+)
+
+// startAccountExport runs the archive generation in the background, tests
+// replace it to run synchronously.
+var startAccountExport = func(fn func()) { go fn() }
+
+// RequestAccountExport returns the export of everything stored for address.
+// A pending or downloadable export is reused, otherwise a new one is started
+// in the background and returned as pending. With refresh a downloadable
+// export is replaced, for records changed after it was built.
+func RequestAccountExport(address string, refresh bool) (*model.AccountExport, error) {
+	now := time.Now()
+	err := repos.AccountExports.DeleteExpired(now)
+	if err != nil {
+		return nil, errors.New("error while deleting expired account exports: " + err.Error())
+	}
+
+	latest, err := repos.AccountExports.GetLatestByAddress(address)
+	if err != nil {
+		return nil, errors.New("error while retrieving account export from storage: " + err.Error())
+	}
+	if latest != nil {
+		if (latest.Status == model.AccountExportStatusReady && !refresh) ||
+			(latest.Status == model.AccountExportStatusPending && now.Sub(latest.CreatedAt) < accountExportTimeout) {
+			return latest, nil
+		}
+	}
+
+	export := &model.AccountExport{
+		Id:        uuid.New(),
+		Address:   address,
+		Status:    model.AccountExportStatusPending,
+		CreatedAt: now,
+		ExpiresAt: now.Add(accountExportRetention),
+	}
+	err = repos.AccountExports.Create(export)
+	if err != nil {
+		return nil, errors.New("error while creating account export in storage: " + err.Error())
+	}
+
+	pending := *export
+	startAccountExport(func() { completeAccountExport(pending) })
+
+	return export, nil
+}
+
+func completeAccountExport(export model.AccountExport) {
+	archive, err := BuildAccountExportArchive(export.Address)
+	if err != nil {
+		fmt.Println("error while building account export " + export.Id.String() + ": " + err.Error())
+		export.Status = model.AccountExportStatusFailed
+	} else {
+		export.Status = model.AccountExportStatusReady
+		export.Archive = base64.StdEncoding.EncodeToString(archive)
+	}
+
+	completedAt := time.Now()
+	export.CompletedAt = &completedAt
+	err = repos.AccountExports.Update(&export)
+	if err != nil {
+		fmt.Println("error while updating account export " + export.Id.String() + ": " + err.Error())
+	}
+}
+
+// NewAccountExportToken signs a short lived token granting the download of a
+// ready export.
+func NewAccountExportToken(export *model.AccountExport) (string, error) {
+	if export.Status != model.AccountExportStatusReady {
+		return "", ErrorAccountExportNotReady
+	}
+
+	return crypto.GenerateExportJwt(
+		export.Id.String(),
+		config.Config.Jwt.ExportSecret,
+		config.Config.Jwt.Issuer,
+		config.Config.Jwt.ExportExpiryMins,
+	)
+}
+
+// GetAccountExportArchive validates a download token and returns the ZIP of
+// the export it was signed for.
+func GetAccountExportArchive(token string) ([]byte, error) {
+	claims, err := crypto.ValidateExportJwt(token, config.Config.Jwt.ExportSecret)
+	if err != nil {
+		return nil, errors.New("error while validating export token: " + err.Error())
+	}
+	exportId, err := uuid.Parse(claims.ExportId)
+	if err != nil {
+		return nil, errors.New("found bad claims in export token")
+	}
+
+	export, err := repos.AccountExports.GetById(exportId)
+	if err != nil {
+		return nil, errors.New("error while retrieving account export from storage: " + err.Error())
+	} else if export == nil || export.ExpiresAt.Before(time.Now()) {
+		return nil, ErrorAccountExportNotFound
+	} else if export.Status != model.AccountExportStatusReady {
+		return nil, ErrorAccountExportNotReady
+	}
+
+	archive, err := base64.StdEncoding.DecodeString(export.Archive)
+	if err != nil {
+		return nil, errors.New("error while decoding account export archive: " + err.Error())
+	}
+
+	return archive, nil
+}
+
+// BuildAccountExportArchive collects every record held for address into a ZIP
+// with a JSON file per entity and the rendered invoice drafts.
+func BuildAccountExportArchive(address string) ([]byte, error) {
+	account, err := getAcocunt(address)
+	if err != nil {
+		return nil, errors.New("error while retrieving account from storage: " + err.Error())
+	}
+
+	notificationEmail, _, err := repos.NotificationEmails.GetByAddress(address)
+	if err != nil {
+		return nil, errors.New("error while retrieving notification email from storage: " + err.Error())
+	}
+
+	var kyc *model.Kyc
+	if account != nil && account.Email != nil && *account.Email != "" {
+		kyc, _, err = repos.Kycs.GetByEmail(*account.Email)
+		if err != nil {
+			return nil, errors.New("error while retrieving kyc from storage: " + err.Error())
+		}
+	}
+
+	userInfo, err := repos.UserInfos.GetByAddress(address)
+	if err != nil {
+		return nil, errors.New("error while retrieving user info from storage: " + err.Error())
+	}
+
+	userInfoVersions, err := repos.UserInfos.GetVersions(address)
+	if err != nil {
+		return nil, errors.New("error while retrieving user info history from storage: " + err.Error())
+	}
+
+	invoices, err := repos.Invoices.GetByAddress(address)
+	if err != nil {
+		return nil, errors.New("error while retrieving invoices from storage: " + err.Error())
+	}
+
+	drafts, err := getAccountDrafts(address)
+	if err != nil {
+		return nil, err
+	}
+
+	allocations, err := repos.Allocations.GetByAddress(address)
+	if err != nil {
+		return nil, errors.New("error while retrieving allocations from storage: " + err.Error())
+	}
+
+	burnEvents, err := repos.BurnEvents.GetByOwnerAddress(address)
+	if err != nil {
+		return nil, errors.New("error while retrieving burn events from storage: " + err.Error())
+	}
+
+	preference, err := repos.Preferences.GetByAddress(address)
+	if err != nil {
+		return nil, errors.New("error while retrieving preferences from storage: " + err.Error())
+	}
+
+	branding, err := repos.Brandings.GetByAddress(address)
+	if err != nil {
+		return nil, errors.New("error while retrieving branding from storage: " + err.Error())
+	}
+
+	seller, err := repos.Sellers.GetByAddress(address)
+	if err != nil {
+		return nil, errors.New("error while retrieving seller from storage: " + err.Error())
+	}
+
+	var buf bytes.Buffer
+	archive := zip.NewWriter(&buf)
+
+	entities := []struct {
+		name string
+		data any
+	}{
+		{"account.json", account},
+		{"notification_email.json", notificationEmail},
+		{"kyc.json", kyc},
+		{"user_info.json", userInfo},
+		{"user_info_versions.json", userInfoVersions},
+		{"invoices.json", invoices},
+		{"invoice_drafts.json", stripDraftProfiles(drafts)},
+		{"allocations.json", allocations},
+		{"burn_events.json", burnEvents},
+		{"preferences.json", preference},
+		{"branding.json", branding},
+		{"seller.json", seller},
+	}
+	for _, entity := range entities {
+		content, err := json.MarshalIndent(entity.data, "", "  ")
+		if err != nil {
+			return nil, errors.New("error while marshalling " + entity.name + ": " + err.Error())
+		}
+		err = writeZipEntry(archive, entity.name, content)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	for _, draft := range drafts {
+		draftAllocations, err := GetDraftAllocations(draft)
+		if err != nil {
+			return nil, errors.New("error while retrieving draft allocations from storage: " + err.Error())
+		}
+		document, err := FillInvoiceDraftTemplate(draft, draftAllocations)
+		if err != nil {
+			return nil, errors.New("error while rendering invoice draft " + draft.DraftId.String() + ": " + err.Error())
+		}
+		err = writeZipEntry(archive, "invoice_drafts/"+draft.DraftId.String()+".doc", document)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	err = archive.Close()
+	if err != nil {
+		return nil, errors.New("error while closing export archive: " + err.Error())
+	}
+
+	return buf.Bytes(), nil
+}
+
+// getAccountDrafts returns the drafts where address is either the node owner
+// or the csp owner.
+func getAccountDrafts(address string) ([]model.InvoiceDraft, error) {
+	nodeOwnerDrafts, err := repos.Drafts.GetListByNodeOwner(address)
+	if err != nil {
+		return nil, errors.New("error while retrieving node owner drafts from storage: " + err.Error())
+	}
+	cspDrafts, err := repos.Drafts.GetListByCSP(address)
+	if err != nil {
+		return nil, errors.New("error while retrieving csp drafts from storage: " + err.Error())
+	}
+
+	seen := make(map[uuid.UUID]bool)
+	var drafts []model.InvoiceDraft
+	for _, draft := range append(nodeOwnerDrafts, cspDrafts...) {
+		if seen[draft.DraftId] {
+			continue
+		}
+		seen[draft.DraftId] = true
+		drafts = append(drafts, draft)
+	}
+
+	return drafts, nil
+}
+
+// stripDraftProfiles drops the preloaded profiles, the caller's own profile is
+// exported on its own and the counterparty's one is not theirs to export.
+func stripDraftProfiles(drafts []model.InvoiceDraft) []model.InvoiceDraft {
+	stripped := make([]model.InvoiceDraft, 0, len(drafts))
+	for _, draft := range drafts {
+		draft.CspProfile = model.UserInfo{}
+		draft.UserProfile = model.UserInfo{}
+		stripped = append(stripped, draft)
+	}
+	return stripped
+}
+
+func writeZipEntry(archive *zip.Writer, name string, content []byte) error {
+	w, err := archive.Create(name)
+	if err != nil {
+		return errors.New("error while adding " + name + " to export archive: " + err.Error())
+	}
+	_, err = w.Write(content)
+	if err != nil {
+		return errors.New("error while writing " + name + " to export archive: " + err.Error())
+	}
+	return nil
+}
@@ -0,0 +1,138 @@
+package service
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/NaeuralEdgeProtocol/ratio1-backend/config"
+	"github.com/NaeuralEdgeProtocol/ratio1-backend/model"
+	"github.com/NaeuralEdgeProtocol/ratio1-backend/storage/memory"
+	"github.com/stretchr/testify/require"
+)
+
+func withSynchronousAccountExport(t *testing.T) {
+	previousRepos := GetRepositories()
+	previousStart := startAccountExport
+	previousSecret := config.Config.Jwt.ExportSecret
+	SetRepositories(memory.NewRepositories())
+	startAccountExport = func(fn func()) { fn() }
+	config.Config.Jwt.ExportSecret = "export-secret"
+	config.Config.Jwt.ExportExpiryMins = 15
+	t.Cleanup(func() {
+		SetRepositories(previousRepos)
+		startAccountExport = previousStart
+		config.Config.Jwt.ExportSecret = previousSecret
+	})
+}
+
+func readExportArchive(t *testing.T, archive []byte) map[string][]byte {
+	reader, err := zip.NewReader(bytes.NewReader(archive), int64(len(archive)))
+	require.NoError(t, err)
+
+	files := make(map[string][]byte)
+	for _, f := range reader.File {
+		rc, err := f.Open()
+		require.NoError(t, err)
+		content, err := io.ReadAll(rc)
+		require.NoError(t, err)
+		rc.Close()
+		files[f.Name] = content
+	}
+	return files
+}
+
+func TestBuildAccountExportArchive(t *testing.T) {
+	withSynchronousAccountExport(t)
+
+	email := "owner@example.com"
+	require.NoError(t, repos.Accounts.Create(&model.Account{Address: "0xowner", Email: &email, EmailConfirmed: true}))
+	require.NoError(t, repos.UserInfos.Create(&model.UserInfo{BlockchainAddress: "0xowner", Email: email, Country: "ITA"}))
+	invoiceId := "invoice1"
+	require.NoError(t, repos.Invoices.Create(&model.InvoiceClient{Uuid: &invoiceId, BlockchainAddress: "0xowner", UserEmail: &email}))
+	require.NoError(t, repos.Preferences.Create(&model.Preference{UserAddress: "0xowner", InvoiceSeries: "R1", NextNumber: 1}))
+	allocations := createTestAllocations(t, "0xowner", "0xcsp", "1000000")
+	draft, err := generateInvoiceDraft("0xowner", "0xcsp", allocations, fixedFxRates(nil))
+	require.NoError(t, err)
+
+	archive, err := BuildAccountExportArchive("0xowner")
+	require.NoError(t, err)
+	files := readExportArchive(t, archive)
+
+	for _, name := range []string{
+		"account.json", "notification_email.json", "kyc.json", "user_info.json", "user_info_versions.json",
+		"invoices.json",
+		"invoice_drafts.json", "allocations.json", "burn_events.json", "preferences.json",
+		"branding.json", "seller.json", "invoice_drafts/" + draft.DraftId.String() + ".doc",
+	} {
+		require.Contains(t, files, name)
+	}
+
+	var account model.Account
+	require.NoError(t, json.Unmarshal(files["account.json"], &account))
+	require.Equal(t, "0xowner", account.Address)
+
+	var invoices []model.InvoiceClient
+	require.NoError(t, json.Unmarshal(files["invoices.json"], &invoices))
+	require.Len(t, invoices, 1)
+
+	var exportedAllocations []model.Allocation
+	require.NoError(t, json.Unmarshal(files["allocations.json"], &exportedAllocations))
+	require.Len(t, exportedAllocations, 1)
+	require.Equal(t, "null", string(files["kyc.json"]))
+}
+
+func TestRequestAccountExportAndDownload(t *testing.T) {
+	withSynchronousAccountExport(t)
+	require.NoError(t, repos.Accounts.Create(&model.Account{Address: "0xowner"}))
+
+	export, err := RequestAccountExport("0xowner", false)
+	require.NoError(t, err)
+	require.Equal(t, model.AccountExportStatusPending, export.Status)
+
+	ready, err := RequestAccountExport("0xowner", false)
+	require.NoError(t, err)
+	require.Equal(t, export.Id, ready.Id)
+	require.Equal(t, model.AccountExportStatusReady, ready.Status)
+
+	refreshed, err := RequestAccountExport("0xowner", true)
+	require.NoError(t, err)
+	require.NotEqual(t, ready.Id, refreshed.Id)
+	ready, err = RequestAccountExport("0xowner", false)
+	require.NoError(t, err)
+	require.Equal(t, refreshed.Id, ready.Id)
+
+	token, err := NewAccountExportToken(ready)
+	require.NoError(t, err)
+	archive, err := GetAccountExportArchive(token)
+	require.NoError(t, err)
+	require.Contains(t, readExportArchive(t, archive), "account.json")
+
+	config.Config.Jwt.ExportSecret = "another-secret"
+	_, err = GetAccountExportArchive(token)
+	require.Error(t, err)
+}
+
+func TestRequestAccountExportReplacesExpiredExport(t *testing.T) {
+	withSynchronousAccountExport(t)
+
+	expired := &model.AccountExport{
+		Id:        [16]byte{1},
+		Address:   "0xowner",
+		Status:    model.AccountExportStatusReady,
+		CreatedAt: time.Now().Add(-2 * accountExportRetention),
+		ExpiresAt: time.Now().Add(-accountExportRetention),
+	}
+	require.NoError(t, repos.AccountExports.Create(expired))
+
+	export, err := RequestAccountExport("0xowner", false)
+	require.NoError(t, err)
+	require.NotEqual(t, expired.Id, export.Id)
+
+	removed, err := repos.AccountExports.GetById(expired.Id)
+	require.NoError(t, err)
+	require.Nil(t, removed)
+}
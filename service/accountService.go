@@ -9,7 +9,6 @@ import (
 	"github.com/NaeuralEdgeProtocol/ratio1-backend/config"
 	"github.com/NaeuralEdgeProtocol/ratio1-backend/crypto"
 	"github.com/NaeuralEdgeProtocol/ratio1-backend/model"
-	"github.com/NaeuralEdgeProtocol/ratio1-backend/storage"
 	"github.com/google/uuid"
 )
 
@@ -22,7 +21,7 @@ func GetOrCreateAccount(address string) (*model.Account, error) {
 	}
 	if account == nil {
 		newAccount := &model.Account{Address: address, CreatedAt: time.Now(), UpdatedAt: time.Now()}
-		err = storage.CreateAccount(newAccount)
+		err = repos.Accounts.Create(newAccount)
 		if err != nil {
 			return nil, errors.New("error while creating account in storage:" + err.Error())
 		}
@@ -38,7 +37,7 @@ func RegisterEmail(address, email string, receiveUpdates bool) (*model.Account,
 		return nil, errors.New("invalid email address: " + email)
 	}
 
-	_, found, err := storage.GetAccountByEmail(email)
+	_, found, err := repos.Accounts.GetByEmail(email)
 	if err != nil {
 		return nil, errors.New("error while retrieving email from storage: " + err.Error())
 	}
@@ -60,7 +59,7 @@ func RegisterEmail(address, email string, receiveUpdates bool) (*model.Account,
 	account.PendingEmail = email
 	account.PendingReceiveUpdates = receiveUpdates
 
-	err = storage.UpdateAccount(account)
+	err = repos.Accounts.Update(account)
 	if err != nil {
 		return nil, errors.New("error while updating account on storage: " + err.Error())
 	}
@@ -103,7 +102,7 @@ func ConfirmEmail(token string) (*model.Account, error) {
 		return confirmPrimaryEmail(account, email)
 	}
 
-	notificationEmail, found, err := storage.GetAccountNotificationEmailByAddress(claims.Address)
+	notificationEmail, found, err := repos.NotificationEmails.GetByAddress(claims.Address)
 	if err != nil {
 		return nil, errors.New("error while retrieving notification email from storage: " + err.Error())
 	}
@@ -113,7 +112,7 @@ func ConfirmEmail(token string) (*model.Account, error) {
 			return nil, err
 		}
 		if confirmed {
-			err = storage.CreateOrUpdateAccountNotificationEmail(notificationEmail)
+			err = repos.NotificationEmails.CreateOrUpdate(notificationEmail)
 			if err != nil {
 				return nil, errors.New("error while updating notification email on storage: " + err.Error())
 			}
@@ -138,12 +137,12 @@ func confirmPrimaryEmail(account *model.Account, email string) (*model.Account,
 	}
 	account.PendingReceiveUpdates = false
 
-	err := storage.UpdateAccount(account)
+	err := repos.Accounts.Update(account)
 	if err != nil {
 		return nil, errors.New("error while updating account on storage: " + err.Error())
 	}
 
-	err = storage.CreateOrUpdateKyc(&kyc)
+	err = repos.Kycs.CreateOrUpdate(&kyc)
 	if err != nil {
 		return nil, errors.New("error while updating kyc on storage: " + err.Error())
 	}
@@ -165,7 +164,7 @@ func SubscribeEmail(kyc *model.Kyc) error {
 	var rUpd = true
 	kyc.ReceiveUpdates = &rUpd
 
-	err := storage.CreateOrUpdateKyc(kyc)
+	err := repos.Kycs.CreateOrUpdate(kyc)
 	if err != nil {
 		return errors.New("error while update kyc in storage: " + err.Error())
 	}
@@ -183,7 +182,7 @@ func UnsubscribeEmail(kyc *model.Kyc) error {
 	var rUpd = false
 	kyc.ReceiveUpdates = &rUpd
 
-	err := storage.CreateOrUpdateKyc(kyc)
+	err := repos.Kycs.CreateOrUpdate(kyc)
 	if err != nil {
 		return errors.New("error while update kyc in storage: " + err.Error())
 	}
@@ -194,7 +193,7 @@ func UnsubscribeEmail(kyc *model.Kyc) error {
 }
 
 func NewAccountDto(account *model.Account, kyc *model.Kyc) (*model.AccountDto, error) {
-	notificationEmail, found, err := storage.GetAccountNotificationEmailByAddress(account.Address)
+	notificationEmail, found, err := repos.NotificationEmails.GetByAddress(account.Address)
 	if err != nil {
 		return nil, errors.New("error while retrieving notification email from storage: " + err.Error())
 	}
@@ -215,22 +214,13 @@ func NewAccountDto(account *model.Account, kyc *model.Kyc) (*model.AccountDto, e
 			limit = config.Config.BuyLimitUSD.Individual
 		}
 
-		vatPercentage := int64(ROUVatPerc)
-		if account.Address == config.Config.NaeuralAddress {
-			vatPercentage = 0
-		} else if !isUeCountry(kyc.Country) {
-			vatPercentage = 0
-		} else if kyc.Country != model.ROU_ID {
-			if kyc.ApplicantType == model.BusinessCustomer && kyc.ViesRegistered {
-				vatPercentage = 0
-			} else if kyc.ApplicantType == model.IndividualCustomer {
-				vat := GetEuVatPercentage(kyc.Country)
-				if vat != nil {
-					vatPercentage = *vat
-				} else {
-					vatPercentage = 0
-				}
+		vatPercentage := int64(0)
+		if account.Address != config.Config.NaeuralAddress {
+			vat, err := LicenseVat(kyc.Country, kyc.ApplicantType == model.BusinessCustomer, kyc.ViesRegistered, time.Now())
+			if err != nil {
+				return nil, errors.New("error while computing vat: " + err.Error())
 			}
+			vatPercentage = VatBasisPoints(vat.Rate)
 		}
 
 		return &model.AccountDto{
@@ -284,7 +274,7 @@ func NewAccountDto(account *model.Account, kyc *model.Kyc) (*model.AccountDto, e
 }
 
 func getAcocunt(address string) (*model.Account, error) {
-	storedAccount, found, err := storage.GetAccountByAddress(address)
+	storedAccount, found, err := repos.Accounts.GetByAddress(address)
 	if err != nil {
 		return nil, errors.New("error while retrieving account from storage: " + err.Error())
 	}
@@ -0,0 +1,163 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"math"
+	"math/big"
+	"time"
+
+	"github.com/NaeuralEdgeProtocol/ratio1-backend/config"
+	"github.com/NaeuralEdgeProtocol/ratio1-backend/model"
+	"github.com/NaeuralEdgeProtocol/ratio1-backend/rpc"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// GetBlockTime returns the time block number was mined, from the blocks cache
+// or from the chain.
+func GetBlockTime(number int64) (time.Time, error) {
+	ctx, cancel := context.WithTimeout(backfillContext(), rpcRequestTimeout)
+	defer cancel()
+
+	block, err := getBlock(ctx, rpc.Get(), number, &confirmedHead{})
+	if err != nil {
+		return time.Time{}, err
+	}
+	return block.Timestamp, nil
+}
+
+// GetBlockAtOrBefore returns the newest block mined at or before timestamp, -1
+// when the chain starts after it. The search starts from the cached blocks
+// closest to timestamp and caches the confirmed headers it fetches.
+func GetBlockAtOrBefore(timestamp time.Time) (int64, error) {
+	ctx, cancel := context.WithTimeout(backfillContext(), rpcRequestTimeout)
+	defer cancel()
+	client := rpc.Get()
+	confirmed := &confirmedHead{}
+
+	low, err := repos.Blocks.GetAtOrBefore(timestamp)
+	if err != nil {
+		return 0, errors.New("error while retrieving block from storage: " + err.Error())
+	} else if low == nil {
+		low, err = getBlock(ctx, client, 0, confirmed)
+		if err != nil {
+			return 0, err
+		} else if low.Timestamp.After(timestamp) {
+			return -1, nil
+		}
+	}
+
+	high, err := repos.Blocks.GetAfter(timestamp)
+	if err != nil {
+		return 0, errors.New("error while retrieving block from storage: " + err.Error())
+	} else if high == nil {
+		// the head is not cached, it can still be reorged
+		header, err := client.HeaderByNumber(ctx, nil)
+		if err != nil {
+			return 0, errors.New("error while retrieving chain head: " + err.Error())
+		}
+		head := blockFromHeader(header)
+		confirmed.set(head.Number)
+		if !head.Timestamp.After(timestamp) {
+			return head.Number, nil
+		}
+		high = &head
+	}
+
+	// low is mined at or before timestamp and high after it, interpolation
+	// steps alternate with bisections to bound the number of headers fetched
+	for step := 0; high.Number-low.Number > 1; step++ {
+		guess := low.Number + (high.Number-low.Number)/2
+		if step%2 == 0 {
+			span := high.Timestamp.Sub(low.Timestamp).Seconds()
+			frac := timestamp.Sub(low.Timestamp).Seconds() / span
+			guess = low.Number + int64(math.Round(frac*float64(high.Number-low.Number)))
+			guess = min(max(guess, low.Number+1), high.Number-1)
+		}
+
+		block, err := getBlock(ctx, client, guess, confirmed)
+		if err != nil {
+			return 0, err
+		}
+		if block.Timestamp.After(timestamp) {
+			high = block
+		} else {
+			low = block
+		}
+	}
+
+	return low.Number, nil
+}
+
+// GetBlockRange returns the first and the last block mined in [from, to), the
+// last block is before the first one when no block was mined in between.
+func GetBlockRange(from, to time.Time) (int64, int64, error) {
+	beforeFrom, err := GetBlockAtOrBefore(from.Add(-time.Nanosecond))
+	if err != nil {
+		return 0, 0, err
+	}
+	last, err := GetBlockAtOrBefore(to.Add(-time.Nanosecond))
+	if err != nil {
+		return 0, 0, err
+	}
+	return beforeFrom + 1, last, nil
+}
+
+// confirmedHead is the newest block deep enough under the chain head not to be
+// reorged anymore, only the blocks up to it are cached. The head is fetched on
+// first need.
+type confirmedHead struct {
+	number int64
+	known  bool
+}
+
+func (c *confirmedHead) set(head int64) {
+	c.number = head - config.Config.Indexer.Confirmations
+	c.known = true
+}
+
+func (c *confirmedHead) covers(ctx context.Context, client chainReader, number int64) (bool, error) {
+	if !c.known {
+		header, err := client.HeaderByNumber(ctx, nil)
+		if err != nil {
+			return false, errors.New("error while retrieving chain head: " + err.Error())
+		}
+		c.set(header.Number.Int64())
+	}
+	return number <= c.number, nil
+}
+
+func getBlock(ctx context.Context, client chainReader, number int64, confirmed *confirmedHead) (*model.Block, error) {
+	block, err := repos.Blocks.Get(number)
+	if err != nil {
+		return nil, errors.New("error while retrieving block from storage: " + err.Error())
+	} else if block != nil {
+		return block, nil
+	}
+
+	header, err := client.HeaderByNumber(ctx, big.NewInt(number))
+	if err != nil {
+		return nil, errors.New("error while retrieving header: " + err.Error())
+	}
+
+	fetched := blockFromHeader(header)
+	cache, err := confirmed.covers(ctx, client, fetched.Number)
+	if err != nil {
+		return nil, err
+	} else if !cache {
+		return &fetched, nil
+	}
+	err = repos.Blocks.Save(fetched)
+	if err != nil {
+		return nil, errors.New("error while storing block: " + err.Error())
+	}
+	return &fetched, nil
+}
+
+func blockFromHeader(header *types.Header) model.Block {
+	return model.Block{
+		Number:    header.Number.Int64(),
+		Hash:      header.Hash().Hex(),
+		Timestamp: time.Unix(int64(header.Time), 0).UTC(),
+	}
+}
@@ -0,0 +1,103 @@
+package service
+
+import (
+	"context"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/NaeuralEdgeProtocol/ratio1-backend/model"
+	"github.com/stretchr/testify/require"
+)
+
+func fakeBlockTime(number int64) time.Time {
+	return time.Unix(fakeChainGenesis+2*number, 0).UTC()
+}
+
+func TestGetBlockTimeCachesHeaders(t *testing.T) {
+	chain := &fakeChain{head: 100}
+	withFakeChain(t, chain)
+
+	blockTime, err := GetBlockTime(42)
+	require.NoError(t, err)
+	require.Equal(t, fakeBlockTime(42), blockTime)
+
+	blockTime, err = GetBlockTime(42)
+	require.NoError(t, err)
+	require.Equal(t, fakeBlockTime(42), blockTime)
+	// the header and the head the first time only
+	require.Equal(t, 2, chain.headerCalls)
+
+	// blocks within the confirmations can still be reorged
+	_, err = GetBlockTime(95)
+	require.NoError(t, err)
+	cached, err := repos.Blocks.Get(95)
+	require.NoError(t, err)
+	require.Nil(t, cached)
+}
+
+func TestGetBlockAtOrBefore(t *testing.T) {
+	chain := &fakeChain{head: 1000}
+	withFakeChain(t, chain)
+
+	number, err := GetBlockAtOrBefore(fakeBlockTime(300))
+	require.NoError(t, err)
+	require.Equal(t, int64(300), number)
+
+	number, err = GetBlockAtOrBefore(fakeBlockTime(300).Add(time.Second))
+	require.NoError(t, err)
+	require.Equal(t, int64(300), number)
+
+	number, err = GetBlockAtOrBefore(fakeBlockTime(0).Add(-time.Second))
+	require.NoError(t, err)
+	require.Equal(t, int64(-1), number)
+
+	number, err = GetBlockAtOrBefore(fakeBlockTime(2000))
+	require.NoError(t, err)
+	require.Equal(t, int64(1000), number)
+
+	// the cached blocks around the timestamp bound the search
+	calls := chain.headerCalls
+	number, err = GetBlockAtOrBefore(fakeBlockTime(300).Add(time.Second))
+	require.NoError(t, err)
+	require.Equal(t, int64(300), number)
+	require.Equal(t, calls, chain.headerCalls)
+}
+
+func TestGetBlockRange(t *testing.T) {
+	chain := &fakeChain{head: 1000}
+	withFakeChain(t, chain)
+
+	first, last, err := GetBlockRange(fakeBlockTime(100), fakeBlockTime(200))
+	require.NoError(t, err)
+	require.Equal(t, int64(100), first)
+	require.Equal(t, int64(199), last)
+
+	first, last, err = GetBlockRange(fakeBlockTime(100).Add(time.Second), fakeBlockTime(101))
+	require.NoError(t, err)
+	require.Less(t, last, first)
+}
+
+func TestIndexChainDropsReorgedBlocks(t *testing.T) {
+	chain := &fakeChain{head: 100}
+	withFakeChain(t, chain)
+
+	IndexChain()
+	cached, err := repos.Blocks.Get(90)
+	require.NoError(t, err)
+	require.NotNil(t, cached)
+
+	chain.fork, chain.forkAt = "fork", 70
+	chain.head = 120
+	IndexChain()
+
+	cached, err = repos.Blocks.Get(90)
+	require.NoError(t, err)
+	require.Nil(t, cached)
+
+	cached, err = repos.Blocks.Get(110)
+	require.NoError(t, err)
+	require.NotNil(t, cached)
+	header, _ := chain.HeaderByNumber(context.Background(), big.NewInt(110))
+	require.Equal(t, model.Block{Number: 110, Hash: header.Hash().Hex(), Timestamp: fakeBlockTime(110)}, *cached)
+}
@@ -31,6 +31,7 @@ func GenerateBurnReportCSV(burnEvents []model.BurnEvent) ([]byte, error) {
 	totalUsdcSwapped := big.NewInt(0)
 	totalPreferredCurrency := float64(0)
 	for _, event := range burnEvents {
+		event.ExchangeRatio = burnExchangeRatio(event)
 		totalR1Burned.Add(totalR1Burned, event.GetR1AmountBurned())
 		totalUsdcSwapped.Add(totalUsdcSwapped, event.GetUsdcAmountSwapped())
 		totalPreferredCurrency += GetAmountAsFloat(event.GetUsdcAmountSwapped(), model.UsdcDecimals) * event.ExchangeRatio
@@ -89,6 +90,20 @@ func GenerateBurnReportCSV(burnEvents []model.BurnEvent) ([]byte, error) {
 	return []byte(csvData.String()), nil
 }
 
+// burnExchangeRatio returns the ratio stored with the burn, burns indexed
+// while no rate was available get the one of the day of the burn.
+func burnExchangeRatio(event model.BurnEvent) float64 {
+	if event.ExchangeRatio != 0 || event.LocalCurrency == "" {
+		return event.ExchangeRatio
+	}
+	ratio, err := GetFxRate(event.LocalCurrency, event.BurnTimestamp)
+	if err != nil {
+		fmt.Println("error while converting burn " + event.TxHash + ": " + err.Error())
+		return 0
+	}
+	return ratio
+}
+
 const DisclaimerText = `Protocol Burn Fee.
 The 'burn' recorded in this report represents a protocol-level fee required to execute computation on Ratio1 Edge Nodes.
 The burned tokens are irrevocably destroyed on-chain and permanently removed from circulation.
@@ -3,6 +3,7 @@ package service
 import (
 	"math/big"
 	"os"
+	"path/filepath"
 	"testing"
 	"time"
 
@@ -35,5 +36,5 @@ func Test_BurnReportgenerator(t *testing.T) {
 
 	csvBytes, err := GenerateBurnReportCSV(burnEvents)
 	require.Nil(t, err)
-	os.WriteFile("file.csv", csvBytes, 0644)
+	os.WriteFile(filepath.Join(t.TempDir(), "file.csv"), csvBytes, 0644)
 }
@@ -0,0 +1,234 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"sort"
+	"time"
+
+	"github.com/NaeuralEdgeProtocol/ratio1-backend/config"
+	"github.com/NaeuralEdgeProtocol/ratio1-backend/model"
+	"github.com/NaeuralEdgeProtocol/ratio1-backend/rpc"
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+const chainIndexerTimeout = 30 * time.Minute
+
+const (
+	defaultIndexerTrackedBlocks = 64
+	defaultIndexerMaxBlockRange = 50_000
+)
+
+var ErrReorgTooDeep = errors.New("reorg deeper than the tracked blocks")
+
+type chainReader interface {
+	logFilterer
+	HeaderByNumber(ctx context.Context, number *big.Int) (*types.Header, error)
+}
+
+// indexerStream is an event stream followed by the indexer with its own
+// checkpoint.
+type indexerStream struct {
+	name string
+	// start returns the first block to index when the stream has no checkpoint
+	start func() (int64, error)
+	// queries returns the log filters of the stream, the indexer sets the range
+	queries func() ([]ethereum.FilterQuery, error)
+	// store writes the decoded events, logs can be delivered more than once
+	store func(logs []types.Log) error
+	// rollback removes the events from block on after a reorg
+	rollback func(block int64) error
+}
+
+var chainIndexerStreams = func() []indexerStream {
+	return []indexerStream{
+		allocationStream(),
+		burnStream(),
+		licensePurchaseStream(),
+		tokenTransferStream(),
+	}
+}
+
+// IndexChain brings every stream up to the chain head minus the configured
+// confirmations. Streams are independent, one failing does not hold back the
+// others.
+func IndexChain() {
+	client := rpc.Get()
+	ctx, cancel := context.WithTimeout(backfillContext(), chainIndexerTimeout)
+	defer cancel()
+
+	head, err := client.HeaderByNumber(ctx, nil)
+	if err != nil {
+		fmt.Println("error while retrieving chain head: " + err.Error())
+		return
+	}
+	safeHead := head.Number.Int64() - config.Config.Indexer.Confirmations
+
+	for _, stream := range chainIndexerStreams() {
+		err = indexStream(ctx, client, stream, safeHead)
+		if err != nil {
+			fmt.Println("error while indexing " + stream.name + ": " + err.Error())
+		}
+	}
+}
+
+func indexStream(ctx context.Context, client chainReader, stream indexerStream, safeHead int64) error {
+	checkpoint, err := repos.ChainIndex.GetCheckpoint(stream.name)
+	if err != nil {
+		return errors.New("error while retrieving checkpoint from storage: " + err.Error())
+	}
+
+	var previous *int64
+	var from int64
+	if checkpoint == nil {
+		from, err = stream.start()
+		if err != nil {
+			return errors.New("error while retrieving start block: " + err.Error())
+		}
+	} else {
+		checkpoint, err = resolveReorg(ctx, client, stream, checkpoint)
+		if err != nil {
+			return err
+		}
+		previous = &checkpoint.BlockNumber
+		from = checkpoint.BlockNumber + 1
+	}
+	if from > safeHead {
+		return nil
+	}
+
+	queries, err := stream.queries()
+	if err != nil {
+		return errors.New("error while building log filters: " + err.Error())
+	}
+
+	for from <= safeHead {
+		to := safeHead
+		if to-from >= indexerMaxBlockRange() {
+			to = from + indexerMaxBlockRange() - 1
+		}
+
+		var logs []types.Log
+		for _, query := range queries {
+			queryLogs, err := filterLogsInChunks(ctx, client, query, from, to)
+			if err != nil {
+				return errors.New("error while filtering logs: " + err.Error())
+			}
+			logs = append(logs, queryLogs...)
+		}
+		sort.SliceStable(logs, func(i, j int) bool {
+			if logs[i].BlockNumber != logs[j].BlockNumber {
+				return logs[i].BlockNumber < logs[j].BlockNumber
+			}
+			return logs[i].Index < logs[j].Index
+		})
+
+		err = stream.store(logs)
+		if err != nil {
+			return errors.New("error while storing events: " + err.Error())
+		}
+
+		header, err := getBlock(ctx, client, to, &confirmedHead{number: safeHead, known: true})
+		if err != nil {
+			return err
+		}
+		block := model.IndexedBlock{BlockNumber: to, BlockHash: header.Hash}
+		err = repos.ChainIndex.Advance(stream.name, previous, block, indexerTrackedBlocks())
+		if err != nil {
+			return errors.New("error while advancing checkpoint: " + err.Error())
+		}
+
+		previous = &block.BlockNumber
+		from = to + 1
+	}
+
+	return nil
+}
+
+// resolveReorg returns the checkpoint to continue from. When the checkpoint
+// block is no longer on the chain the stream is rolled back to the newest
+// tracked block that still is.
+func resolveReorg(ctx context.Context, client chainReader, stream indexerStream, checkpoint *model.IndexerCheckpoint) (*model.IndexerCheckpoint, error) {
+	onChain, err := isBlockOnChain(ctx, client, checkpoint.BlockNumber, checkpoint.BlockHash)
+	if err != nil {
+		return nil, err
+	} else if onChain {
+		return checkpoint, nil
+	}
+
+	blocks, err := repos.ChainIndex.GetBlocks(stream.name)
+	if err != nil {
+		return nil, errors.New("error while retrieving indexed blocks from storage: " + err.Error())
+	}
+	for _, block := range blocks {
+		if block.BlockNumber >= checkpoint.BlockNumber {
+			continue
+		}
+		onChain, err = isBlockOnChain(ctx, client, block.BlockNumber, block.BlockHash)
+		if err != nil {
+			return nil, err
+		} else if !onChain {
+			continue
+		}
+
+		fmt.Printf("reorg detected on %s, rolling back from block %d to %d\n", stream.name, checkpoint.BlockNumber, block.BlockNumber)
+		err = stream.rollback(block.BlockNumber + 1)
+		if err != nil {
+			return nil, errors.New("error while rolling back events: " + err.Error())
+		}
+		err = repos.Blocks.DeleteFrom(block.BlockNumber + 1)
+		if err != nil {
+			return nil, errors.New("error while removing reorged blocks: " + err.Error())
+		}
+		err = repos.ChainIndex.Rewind(stream.name, block)
+		if err != nil {
+			return nil, errors.New("error while rewinding checkpoint: " + err.Error())
+		}
+		return &model.IndexerCheckpoint{Stream: stream.name, BlockNumber: block.BlockNumber, BlockHash: block.BlockHash}, nil
+	}
+
+	return nil, ErrReorgTooDeep
+}
+
+func isBlockOnChain(ctx context.Context, client chainReader, number int64, hash string) (bool, error) {
+	header, err := client.HeaderByNumber(ctx, big.NewInt(number))
+	if err != nil {
+		return false, errors.New("error while retrieving header: " + err.Error())
+	}
+	return header.Hash().Hex() == hash, nil
+}
+
+// GetIndexedBlock returns the last block indexed by every given stream, -1
+// when one of them has not indexed anything yet.
+func GetIndexedBlock(streams ...string) (int64, error) {
+	indexed := int64(-1)
+	for i, stream := range streams {
+		checkpoint, err := repos.ChainIndex.GetCheckpoint(stream)
+		if err != nil {
+			return 0, errors.New("error while retrieving checkpoint from storage: " + err.Error())
+		} else if checkpoint == nil {
+			return -1, nil
+		}
+		if i == 0 || checkpoint.BlockNumber < indexed {
+			indexed = checkpoint.BlockNumber
+		}
+	}
+	return indexed, nil
+}
+
+func indexerTrackedBlocks() int {
+	if config.Config.Indexer.TrackedBlocks > 0 {
+		return config.Config.Indexer.TrackedBlocks
+	}
+	return defaultIndexerTrackedBlocks
+}
+
+func indexerMaxBlockRange() int64 {
+	if config.Config.Indexer.MaxBlockRange > 0 {
+		return config.Config.Indexer.MaxBlockRange
+	}
+	return defaultIndexerMaxBlockRange
+}
@@ -0,0 +1,392 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/NaeuralEdgeProtocol/ratio1-backend/config"
+	"github.com/NaeuralEdgeProtocol/ratio1-backend/model"
+	"github.com/NaeuralEdgeProtocol/ratio1-backend/ratio1abi"
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+var zeroAddress = common.Address{}
+
+// statsStartBlock is where the streams used by the daily stats start, the
+// blocks up to the last stats were fetched before the indexer existed.
+func statsStartBlock() (int64, error) {
+	stats, err := repos.Stats.GetLatest()
+	if err != nil {
+		return 0, err
+	} else if stats == nil || stats.LastBlockNumber == 0 {
+		return 0, nil
+	}
+	return stats.LastBlockNumber + 1, nil
+}
+
+func cspEventQueries(signature string, cspOwners map[string]string) []ethereum.FilterQuery {
+	if len(cspOwners) == 0 {
+		return nil
+	}
+
+	var addresses []common.Address
+	for k := range cspOwners {
+		addresses = append(addresses, common.HexToAddress(k))
+	}
+
+	return []ethereum.FilterQuery{{
+		Addresses: addresses,
+		Topics:    [][]common.Hash{{crypto.Keccak256Hash([]byte(signature))}},
+	}}
+}
+
+func allocationStream() indexerStream {
+	var cspOwners map[string]string
+	return indexerStream{
+		name:  model.IndexerStreamAllocations,
+		start: statsStartBlock,
+		queries: func() ([]ethereum.FilterQuery, error) {
+			var err error
+			cspOwners, err = getAllCSPAddress()
+			if err != nil {
+				return nil, err
+			}
+			return cspEventQueries(ratio1abi.AllocationEventSignature, cspOwners), nil
+		},
+		store: func(logs []types.Log) error {
+			var events []model.Allocation
+			for _, vLog := range logs {
+				event, err := decodeAllocLogs(vLog)
+				if err != nil {
+					fmt.Println("error while decoding logs: " + err.Error())
+					continue
+				}
+				event.CspOwner = cspOwners[event.CspAddress]
+				events = append(events, *event)
+			}
+			if len(events) == 0 {
+				return nil
+			}
+
+			err := enrichAllocations(events)
+			if err != nil {
+				return err
+			}
+			return generateAllocations(events)
+		},
+		rollback: func(block int64) error {
+			return repos.Allocations.DeleteUnclaimedFromBlock(block)
+		},
+	}
+}
+
+// enrichAllocations sets the node owner, the block time and the job details of
+// freshly decoded allocations.
+func enrichAllocations(events []model.Allocation) error {
+	nodeToOwner := make(map[string]string) // map[nodeAddress]ownerAddress
+	for _, a := range events {
+		nodeToOwner[a.NodeAddress] = ""
+	}
+	uniqueNodes := make([]string, 0, len(nodeToOwner))
+	for nodeAddr := range nodeToOwner {
+		uniqueNodes = append(uniqueNodes, nodeAddr)
+	}
+
+	nodeToOwner, err := getNodeOwners(uniqueNodes)
+	if err != nil {
+		return errors.New("error fetching node owners: " + err.Error())
+	}
+
+	blocks, err := getBlockTimestamps(allocationBlocks(events))
+	if err != nil {
+		return err
+	}
+
+	allJobsDetails := make(map[string]*JobDetailsResult)
+	for _, a := range events {
+		allJobsDetails[a.JobId] = nil
+	}
+
+	jobIDs := make([]string, 0, len(allJobsDetails))
+	for k := range allJobsDetails {
+		jobIDs = append(jobIDs, k)
+	}
+
+	prevAllocations, err := repos.Allocations.GetByJobIDsForJobDetails(jobIDs)
+	if err != nil {
+		return errors.New("error getting allocations for job details: " + err.Error())
+	}
+
+	for k := range allJobsDetails {
+		prevAlloc, ok := prevAllocations[k]
+		if !ok {
+			res, err := GetJobDetails(k, config.Config.DeeployApi)
+			if err != nil {
+				continue
+			}
+			allJobsDetails[k] = res
+		} else {
+			res := JobDetailsResult{
+				JobName:     prevAlloc.JobName,
+				JobType:     int(prevAlloc.JobType),
+				ProjectName: prevAlloc.ProjectName,
+			}
+			allJobsDetails[k] = &res
+		}
+	}
+
+	for i, a := range events {
+		if owner, ok := nodeToOwner[a.NodeAddress]; ok {
+			a.UserAddress = owner
+		}
+		if v := blocks[a.BlockNumber]; v != nil {
+			a.AllocationCreation = *v
+		}
+		if v := allJobsDetails[a.JobId]; v != nil {
+			a.JobName = v.JobName
+			a.JobType = model.JobType(v.JobType)
+			a.ProjectName = v.ProjectName
+		}
+		events[i] = a
+	}
+
+	return nil
+}
+
+func allocationBlocks(events []model.Allocation) []int64 {
+	var blocks []int64
+	for _, a := range events {
+		blocks = append(blocks, a.BlockNumber)
+	}
+	return blocks
+}
+
+func burnStream() indexerStream {
+	var cspOwners map[string]string
+	return indexerStream{
+		name:  model.IndexerStreamBurns,
+		start: statsStartBlock,
+		queries: func() ([]ethereum.FilterQuery, error) {
+			var err error
+			cspOwners, err = getAllCSPAddress()
+			if err != nil {
+				return nil, err
+			}
+			return cspEventQueries(ratio1abi.BurnEventSignature, cspOwners), nil
+		},
+		store: func(logs []types.Log) error {
+			var events []model.BurnEvent
+			for _, vLog := range logs {
+				event, err := decodeBurnLogs(vLog)
+				if err != nil {
+					fmt.Println("error while decoding logs: " + err.Error())
+					continue
+				}
+				event.CspOwner = cspOwners[event.CspAddress]
+				events = append(events, *event)
+			}
+			if len(events) == 0 {
+				return nil
+			}
+
+			err := enrichBurns(events)
+			if err != nil {
+				return err
+			}
+			return generateBurns(events)
+		},
+		rollback: func(block int64) error {
+			return repos.BurnEvents.DeleteFromBlock(block)
+		},
+	}
+}
+
+// enrichBurns sets the block time and the exchange ratio to the preferred
+// currency of the csp owner of freshly decoded burn events, at the rate of the
+// day of the burn.
+func enrichBurns(events []model.BurnEvent) error {
+	var blockNumbers []int64
+	for _, b := range events {
+		blockNumbers = append(blockNumbers, b.BlockNumber)
+	}
+	blocks, err := getBlockTimestamps(blockNumbers)
+	if err != nil {
+		return err
+	}
+
+	cspPreferences := make(map[string]*model.Preference) // map[cspOwnerAddress]Preference
+	for _, b := range events {
+		if _, ok := cspPreferences[b.CspOwner]; ok {
+			continue
+		}
+		preference, err := repos.Preferences.GetByAddress(b.CspOwner)
+		if err != nil || preference == nil {
+			preference = &model.Preference{
+				LocalCurrency: "USD",
+			}
+		}
+		cspPreferences[b.CspOwner] = preference
+	}
+
+	for i, b := range events {
+		if v := blocks[b.BlockNumber]; v != nil {
+			b.BurnTimestamp = *v
+		}
+		if pref := cspPreferences[b.CspOwner]; pref != nil {
+			b.LocalCurrency = pref.LocalCurrency
+			ratio, err := GetFxRate(pref.LocalCurrency, b.BurnTimestamp)
+			if errors.Is(err, ErrorFxRateNotFound) {
+				fmt.Println("burn " + b.TxHash + " left without exchange ratio: " + err.Error())
+			} else if err != nil {
+				return err
+			}
+			b.ExchangeRatio = ratio
+		}
+		events[i] = b
+	}
+
+	return nil
+}
+
+func getBlockTimestamps(blockNumbers []int64) (map[int64]*time.Time, error) {
+	blocks := make(map[int64]*time.Time)
+	for _, n := range blockNumbers {
+		blocks[n] = nil
+	}
+
+	for k := range blocks {
+		v, err := GetBlockTime(k)
+		if err != nil {
+			return nil, errors.New("cannot fetch correct timestamp: " + err.Error())
+		}
+		blocks[k] = &v
+	}
+
+	return blocks, nil
+}
+
+func licensePurchaseStream() indexerStream {
+	return indexerStream{
+		name: model.IndexerStreamLicensePurchases,
+		start: func() (int64, error) {
+			latestSeenBlock, _, err := repos.Invoices.GetLatestBlock()
+			if err != nil || latestSeenBlock == nil {
+				return 0, err
+			}
+			return *latestSeenBlock, nil
+		},
+		queries: func() ([]ethereum.FilterQuery, error) {
+			return []ethereum.FilterQuery{{
+				Addresses: []common.Address{common.HexToAddress(config.Config.NDContractAddress)},
+				Topics:    [][]common.Hash{{crypto.Keccak256Hash([]byte(ratio1abi.OblioEventSignature))}},
+			}}, nil
+		},
+		store: func(logs []types.Log) error {
+			for _, vLog := range logs {
+				event, err := decodeLogs(vLog)
+				if err != nil {
+					fmt.Println("error while decoding logs: " + err.Error())
+					continue
+				}
+				err = repos.LicensePurchases.Create(&model.LicensePurchaseEvent{
+					BlockNumber:  event.BlockNumber,
+					TxHash:       event.TxHash,
+					LogIndex:     vLog.Index,
+					Address:      event.Address,
+					InvoiceID:    event.InvoiceID,
+					NumLicenses:  event.NumLicenses,
+					UnitUsdPrice: event.UnitUsdPrice,
+					TokenPaid:    event.TokenPaid,
+					Status:       model.LicensePurchaseStatusPending,
+				})
+				if err != nil {
+					return errors.New("error while saving license purchase: " + err.Error())
+				}
+			}
+			return nil
+		},
+		rollback: func(block int64) error {
+			return repos.LicensePurchases.DeleteUninvoicedFromBlock(block)
+		},
+	}
+}
+
+// tokenTransferStream follows the mints and burns of the R1 token.
+func tokenTransferStream() indexerStream {
+	return indexerStream{
+		name:  model.IndexerStreamTokenTransfers,
+		start: statsStartBlock,
+		queries: func() ([]ethereum.FilterQuery, error) {
+			tokenAddress := common.HexToAddress(config.Config.R1ContractAddress)
+			transferEventSigHash := crypto.Keccak256Hash([]byte(ratio1abi.TransferEventSignature))
+			zeroTopic := common.BytesToHash(zeroAddress.Bytes())
+			return []ethereum.FilterQuery{
+				{
+					Addresses: []common.Address{tokenAddress},
+					Topics:    [][]common.Hash{{transferEventSigHash}, {zeroTopic}},
+				},
+				{
+					Addresses: []common.Address{tokenAddress},
+					Topics:    [][]common.Hash{{transferEventSigHash}, {}, {zeroTopic}},
+				},
+			}, nil
+		},
+		store: func(logs []types.Log) error {
+			for _, vLog := range logs {
+				transfer, err := decodeTransferLogs(vLog)
+				if err != nil {
+					return err
+				}
+				err = repos.TokenTransfers.Create(transfer)
+				if err != nil {
+					return errors.New("error while saving token transfer: " + err.Error())
+				}
+			}
+			return nil
+		},
+		rollback: func(block int64) error {
+			return repos.TokenTransfers.DeleteFromBlock(block)
+		},
+	}
+}
+
+func decodeTransferLogs(vLog types.Log) (*model.TokenTransfer, error) {
+	if len(vLog.Topics) != 3 || len(vLog.Data) != 32 {
+		return nil, errors.New("unexpected transfer log layout in tx " + vLog.TxHash.Hex())
+	}
+
+	transfer := model.TokenTransfer{
+		BlockNumber: int64(vLog.BlockNumber),
+		TxHash:      vLog.TxHash.Hex(),
+		LogIndex:    vLog.Index,
+		From:        common.BytesToAddress(vLog.Topics[1].Bytes()).Hex(),
+		To:          common.BytesToAddress(vLog.Topics[2].Bytes()).Hex(),
+	}
+	transfer.SetAmount(new(big.Int).SetBytes(vLog.Data))
+	return &transfer, nil
+}
+
+// sumTokenTransfers returns the R1 minted, burned and burned by the ND
+// contract in the indexed transfers.
+func sumTokenTransfers(transfers []model.TokenTransfer) (minted, burned, ndContractBurned *big.Int) {
+	minted, burned, ndContractBurned = big.NewInt(0), big.NewInt(0), big.NewInt(0)
+	ndContract := common.HexToAddress(config.Config.NDContractAddress)
+	for _, transfer := range transfers {
+		from, to := common.HexToAddress(transfer.From), common.HexToAddress(transfer.To)
+		if from == zeroAddress {
+			minted.Add(minted, transfer.GetAmount())
+		}
+		if to == zeroAddress {
+			burned.Add(burned, transfer.GetAmount())
+			if from == ndContract {
+				ndContractBurned.Add(ndContractBurned, transfer.GetAmount())
+			}
+		}
+	}
+	return minted, burned, ndContractBurned
+}
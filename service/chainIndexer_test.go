@@ -0,0 +1,231 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/NaeuralEdgeProtocol/ratio1-backend/config"
+	"github.com/NaeuralEdgeProtocol/ratio1-backend/model"
+	"github.com/NaeuralEdgeProtocol/ratio1-backend/ratio1abi"
+	"github.com/NaeuralEdgeProtocol/ratio1-backend/rpc"
+	"github.com/NaeuralEdgeProtocol/ratio1-backend/storage/memory"
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/stretchr/testify/require"
+)
+
+const testR1Contract = "0x00000000000000000000000000000000000000a1"
+
+// fakeChain serves headers and logs, blocks from forkAt on belong to the fork
+// named fork.
+type fakeChain struct {
+	head   int64
+	fork   string
+	forkAt int64
+	logs   []types.Log
+	// headerCalls counts the headers served
+	headerCalls int
+}
+
+// fakeChainGenesis is the time of block 0, blocks are mined every 2 seconds.
+const fakeChainGenesis = 1700000000
+
+func (c *fakeChain) HeaderByNumber(_ context.Context, number *big.Int) (*types.Header, error) {
+	n := c.head
+	if number != nil {
+		n = number.Int64()
+	}
+	branch := "main"
+	if c.fork != "" && n >= c.forkAt {
+		branch = c.fork
+	}
+	c.headerCalls++
+	return &types.Header{Number: big.NewInt(n), Time: uint64(fakeChainGenesis + 2*n), Extra: []byte(branch)}, nil
+}
+
+func (c *fakeChain) CallContract(context.Context, ethereum.CallMsg, *big.Int) ([]byte, error) {
+	return nil, errors.New("fake chain has no contracts")
+}
+
+func (c *fakeChain) FilterLogs(_ context.Context, query ethereum.FilterQuery) ([]types.Log, error) {
+	var logs []types.Log
+	for _, vLog := range c.logs {
+		n := int64(vLog.BlockNumber)
+		if n < query.FromBlock.Int64() || n > query.ToBlock.Int64() || !matchesTopics(vLog, query.Topics) {
+			continue
+		}
+		logs = append(logs, vLog)
+	}
+	return logs, nil
+}
+
+func matchesTopics(vLog types.Log, topics [][]common.Hash) bool {
+	for i, wanted := range topics {
+		if len(wanted) == 0 {
+			continue
+		}
+		if i >= len(vLog.Topics) || vLog.Topics[i] != wanted[0] {
+			return false
+		}
+	}
+	return true
+}
+
+func transferLog(block int64, index uint, from, to common.Address, amount int64) types.Log {
+	return types.Log{
+		Address: common.HexToAddress(testR1Contract),
+		Topics: []common.Hash{
+			crypto.Keccak256Hash([]byte(ratio1abi.TransferEventSignature)),
+			common.BytesToHash(from.Bytes()),
+			common.BytesToHash(to.Bytes()),
+		},
+		Data:        common.BigToHash(big.NewInt(amount)).Bytes(),
+		BlockNumber: uint64(block),
+		TxHash:      common.BigToHash(big.NewInt(block*1000 + int64(index))),
+		Index:       index,
+	}
+}
+
+func withFakeChain(t *testing.T, chain *fakeChain) {
+	previousRepos := GetRepositories()
+	previousClient := rpc.SetClient(chain)
+	previousStreams := chainIndexerStreams
+	previousIndexer := config.Config.Indexer
+	previousR1 := config.Config.R1ContractAddress
+	SetRepositories(memory.NewRepositories())
+	chainIndexerStreams = func() []indexerStream { return []indexerStream{tokenTransferStream()} }
+	config.Config.Indexer = config.IndexerConfig{Confirmations: 10, TrackedBlocks: 8, MaxBlockRange: 20}
+	config.Config.R1ContractAddress = testR1Contract
+	t.Cleanup(func() {
+		SetRepositories(previousRepos)
+		rpc.SetClient(previousClient)
+		chainIndexerStreams = previousStreams
+		config.Config.Indexer = previousIndexer
+		config.Config.R1ContractAddress = previousR1
+	})
+}
+
+// withRpcFixtures replays the rpc responses recorded in testdata/rpc/name, with
+// RECORD_RPC_FIXTURES set they are recorded from the configured providers
+// instead.
+func withRpcFixtures(t *testing.T, name string) {
+	path := filepath.Join("testdata", "rpc", name)
+	if os.Getenv("RECORD_RPC_FIXTURES") != "" {
+		recorder := rpc.NewRecorder(rpc.Get())
+		previous := rpc.SetClient(recorder)
+		t.Cleanup(func() {
+			rpc.SetClient(previous)
+			require.NoError(t, recorder.Fixtures.Save(path))
+		})
+		return
+	}
+
+	fixtures, err := rpc.LoadFixtures(path)
+	require.NoError(t, err)
+	previous := rpc.SetClient(fixtures)
+	t.Cleanup(func() { rpc.SetClient(previous) })
+}
+
+func TestIndexChainStoresEventsBehindHeadOnce(t *testing.T) {
+	holder := common.HexToAddress("0x1")
+	chain := &fakeChain{head: 100, logs: []types.Log{
+		transferLog(50, 0, zeroAddress, holder, 5),
+		transferLog(95, 3, holder, zeroAddress, 2),
+	}}
+	withFakeChain(t, chain)
+
+	IndexChain()
+	transfers, err := repos.TokenTransfers.GetInBlockRange(0, 1000)
+	require.NoError(t, err)
+	require.Len(t, transfers, 1)
+	indexed, err := GetIndexedBlock(model.IndexerStreamTokenTransfers)
+	require.NoError(t, err)
+	require.Equal(t, int64(90), indexed)
+
+	chain.head = 110
+	IndexChain()
+	IndexChain()
+	transfers, err = repos.TokenTransfers.GetInBlockRange(0, 1000)
+	require.NoError(t, err)
+	require.Len(t, transfers, 2)
+
+	minted, burned, _ := sumTokenTransfers(transfers)
+	require.Equal(t, int64(5), minted.Int64())
+	require.Equal(t, int64(2), burned.Int64())
+
+	blocks, err := repos.ChainIndex.GetBlocks(model.IndexerStreamTokenTransfers)
+	require.NoError(t, err)
+	require.Len(t, blocks, 6)
+	require.Equal(t, int64(100), blocks[0].BlockNumber)
+}
+
+func TestIndexChainRollsBackReorgedBlocks(t *testing.T) {
+	holder := common.HexToAddress("0x1")
+	chain := &fakeChain{head: 100, logs: []types.Log{
+		transferLog(30, 0, zeroAddress, holder, 5),
+		transferLog(85, 0, zeroAddress, holder, 7),
+	}}
+	withFakeChain(t, chain)
+
+	IndexChain()
+	transfers, err := repos.TokenTransfers.GetInBlockRange(0, 1000)
+	require.NoError(t, err)
+	require.Len(t, transfers, 2)
+
+	chain.fork, chain.forkAt = "fork", 70
+	chain.logs = []types.Log{
+		transferLog(30, 0, zeroAddress, holder, 5),
+		transferLog(88, 1, zeroAddress, holder, 9),
+	}
+	chain.head = 120
+	IndexChain()
+
+	transfers, err = repos.TokenTransfers.GetInBlockRange(0, 1000)
+	require.NoError(t, err)
+	require.Len(t, transfers, 2)
+	require.Equal(t, int64(30), transfers[0].BlockNumber)
+	require.Equal(t, int64(88), transfers[1].BlockNumber)
+
+	checkpoint, err := repos.ChainIndex.GetCheckpoint(model.IndexerStreamTokenTransfers)
+	require.NoError(t, err)
+	require.Equal(t, int64(110), checkpoint.BlockNumber)
+	header, _ := chain.HeaderByNumber(context.Background(), big.NewInt(110))
+	require.Equal(t, header.Hash().Hex(), checkpoint.BlockHash)
+}
+
+func TestIndexStreamStopsOnReorgDeeperThanTrackedBlocks(t *testing.T) {
+	chain := &fakeChain{head: 100}
+	withFakeChain(t, chain)
+
+	IndexChain()
+	chain.fork, chain.forkAt = "fork", 1
+
+	stream := tokenTransferStream()
+	err := indexStream(context.Background(), chain, stream, 110)
+	require.ErrorIs(t, err, ErrReorgTooDeep)
+
+	checkpoint, err := repos.ChainIndex.GetCheckpoint(model.IndexerStreamTokenTransfers)
+	require.NoError(t, err)
+	require.Equal(t, int64(90), checkpoint.BlockNumber)
+}
+
+func TestIndexStreamStartsAfterLatestStats(t *testing.T) {
+	chain := &fakeChain{head: 100, logs: []types.Log{
+		transferLog(40, 0, zeroAddress, common.HexToAddress("0x1"), 5),
+		transferLog(60, 0, zeroAddress, common.HexToAddress("0x1"), 6),
+	}}
+	withFakeChain(t, chain)
+	require.NoError(t, repos.Stats.Create(&model.Stats{LastBlockNumber: 50}))
+
+	IndexChain()
+	transfers, err := repos.TokenTransfers.GetInBlockRange(0, 1000)
+	require.NoError(t, err)
+	require.Len(t, transfers, 1)
+	require.Equal(t, int64(60), transfers[0].BlockNumber)
+}
@@ -0,0 +1,93 @@
+package service
+
+import (
+	"encoding/xml"
+	"errors"
+	"time"
+
+	"github.com/NaeuralEdgeProtocol/ratio1-backend/process"
+)
+
+const (
+	ecbDailyRatesUrl      = "https://www.ecb.europa.eu/stats/eurofxref/eurofxref-daily.xml"
+	ecbLast90DaysRatesUrl = "https://www.ecb.europa.eu/stats/eurofxref/eurofxref-hist-90d.xml"
+	ecbHistoryRatesUrl    = "https://www.ecb.europa.eu/stats/eurofxref/eurofxref-hist.xml"
+)
+
+// ecbEnvelope is the eurofxref document, a cube per day holding a cube per
+// currency with its rate for 1 EUR.
+type ecbEnvelope struct {
+	Cube struct {
+		Days []struct {
+			Time  string `xml:"time,attr"`
+			Rates []struct {
+				Currency string  `xml:"currency,attr"`
+				Rate     float64 `xml:"rate,attr"`
+			} `xml:"Cube"`
+		} `xml:"Cube"`
+	} `xml:"Cube"`
+}
+
+// ecbProvider reads the euro foreign exchange reference rates of the ECB. They
+// are published on working days only, a day without rates gets the ones of
+// the previous publication.
+type ecbProvider struct{}
+
+func (ecbProvider) Name() string {
+	return "ecb"
+}
+
+func (ecbProvider) Rates(date time.Time) (time.Time, map[string]float64, error) {
+	url := ecbHistoryRatesUrl
+	if age := utcDay(time.Now()).Sub(date); age <= 0 {
+		url = ecbDailyRatesUrl
+	} else if age < 85*24*time.Hour {
+		url = ecbLast90DaysRatesUrl
+	}
+
+	body, err := process.HttpGetBytes(url)
+	if err != nil {
+		return time.Time{}, nil, errors.New("error while making request: " + err.Error())
+	}
+	return parseEcbRates(body, date)
+}
+
+// parseEcbRates picks the latest day published on or before date and rebases
+// its rates from 1 EUR to 1 USD.
+func parseEcbRates(body []byte, date time.Time) (time.Time, map[string]float64, error) {
+	var envelope ecbEnvelope
+	err := xml.Unmarshal(body, &envelope)
+	if err != nil {
+		return time.Time{}, nil, errors.New("error while parsing ecb rates: " + err.Error())
+	}
+
+	found := -1
+	var foundDay time.Time
+	for i, day := range envelope.Cube.Days {
+		published, err := time.Parse(time.DateOnly, day.Time)
+		if err != nil || published.After(date) {
+			continue
+		}
+		if found == -1 || published.After(foundDay) {
+			found, foundDay = i, published
+		}
+	}
+	if found == -1 {
+		return time.Time{}, nil, errors.New("no ecb rates published on or before " + date.Format(time.DateOnly))
+	}
+
+	eurRates := map[string]float64{"EUR": 1}
+	for _, rate := range envelope.Cube.Days[found].Rates {
+		eurRates[rate.Currency] = rate.Rate
+	}
+	usdPerEur, ok := eurRates["USD"]
+	if !ok || usdPerEur == 0 {
+		return time.Time{}, nil, errors.New("ecb rates of " + foundDay.Format(time.DateOnly) + " miss USD")
+	}
+
+	rates := make(map[string]float64, len(eurRates))
+	for currency, rate := range eurRates {
+		rates[currency] = rate / usdPerEur
+	}
+	return foundDay, rates, nil
+}
@@ -25,7 +25,9 @@ const (
 	subjectEmailAccountResetted  = "Your KYC has been resetted"
 	subjectAddressBlacklisted    = "Address is blacklisted"
 	subjectNewBuyLicenseInvoice  = "A new buy license invoice has been sent"
+	subjectLicenseInvoice        = "Ratio1 - Your license purchase invoice"
 	subjectNewInvoiceDraft       = "New draft invoices have been issued"
+	subjectDraftStatus           = "Ratio1 - Invoice draft status changed"
 	subjectJobsEndingSoon        = "Ratio1 - Jobs ending soon"
 	subjectNodesOffline          = "Ratio1 - Linked nodes offline for more than 24h"
 )
@@ -199,11 +201,57 @@ func SendCspDraftEmail(email string) error {
 	return callSendEmail(email, subjectNewInvoiceDraft, body.String())
 }
 
+func SendDraftStatusEmail(email, url, draftNumber, status, message string) error {
+	template, err := templates.GetDraftStatusEmailTemplate()
+	if err != nil {
+		return errors.New("error while retrieving email template: " + err.Error())
+	}
+
+	var body bytes.Buffer
+	err = template.Execute(&body, struct {
+		Url         string
+		DraftNumber string
+		Status      string
+		Message     string
+	}{
+		Url:         url,
+		DraftNumber: draftNumber,
+		Status:      status,
+		Message:     message,
+	})
+	if err != nil {
+		return errors.New("error while executing email template: " + err.Error())
+	}
+	return callSendEmail(email, subjectDraftStatus, body.String())
+}
+
 func SendBuyLicenseEmail(email, url, invoiceNumber string) error {
 	text := "A new invoice has been submitted. Invoice Number: " + invoiceNumber + " , link: " + url
 	return callSendTextEmail(email, subjectNewBuyLicenseInvoice, text)
 }
 
+func SendLicenseInvoiceEmail(email string, numLicenses int, invoiceNumber string, attachments ...EmailAttachment) error {
+	template, err := templates.GetLicenseInvoiceEmailTemplate()
+	if err != nil {
+		return errors.New("error while retrieving email template: " + err.Error())
+	}
+
+	var body bytes.Buffer
+	err = template.Execute(&body, struct {
+		Url           string
+		NumLicenses   int
+		InvoiceNumber string
+	}{
+		Url:           config.Config.Ratio1redirectUrl.OperatorUrl,
+		NumLicenses:   numLicenses,
+		InvoiceNumber: invoiceNumber,
+	})
+	if err != nil {
+		return errors.New("error while executing email template: " + err.Error())
+	}
+	return callSendEmailWithAttachments(email, subjectLicenseInvoice, body.String(), attachments)
+}
+
 func SendJobsEndingEmail(email string, jobs []EndingJob) error {
 	if len(jobs) == 0 {
 		return nil
@@ -2,6 +2,7 @@ package service
 
 import (
 	"errors"
+	"time"
 
 	"github.com/NaeuralEdgeProtocol/ratio1-backend/config"
 	"github.com/NaeuralEdgeProtocol/ratio1-backend/process"
@@ -17,3 +18,36 @@ func GetFreeCurrencyValues() (map[string]float64, error) {
 	}
 	return response.Data, nil
 }
+
+// GetFreeCurrencyHistoricalValues returns the rates of a past day, always
+// based 1 usd -> value.
+func GetFreeCurrencyHistoricalValues(date time.Time) (map[string]float64, error) {
+	day := date.Format(time.DateOnly)
+	response := struct {
+		Data map[string]map[string]float64 `json:"data"`
+	}{}
+	err := process.HttpGet("https://api.freecurrencyapi.com/v1/historical?apikey="+config.Config.FreeCurrencyApiKey+"&date="+day, &response)
+	if err != nil {
+		return nil, errors.New("error while making request: " + err.Error())
+	}
+	rates, ok := response.Data[day]
+	if !ok {
+		return nil, errors.New("no rates returned for " + day)
+	}
+	return rates, nil
+}
+
+type freeCurrencyApiProvider struct{}
+
+func (freeCurrencyApiProvider) Name() string {
+	return "freecurrencyapi"
+}
+
+func (freeCurrencyApiProvider) Rates(date time.Time) (time.Time, map[string]float64, error) {
+	if !date.Before(utcDay(time.Now())) {
+		rates, err := GetFreeCurrencyValues()
+		return utcDay(time.Now()), rates, err
+	}
+	rates, err := GetFreeCurrencyHistoricalValues(date)
+	return date, rates, err
+}
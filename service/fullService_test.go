@@ -9,7 +9,7 @@ import (
 	"github.com/NaeuralEdgeProtocol/ratio1-backend/config"
 	"github.com/NaeuralEdgeProtocol/ratio1-backend/crypto"
 	"github.com/NaeuralEdgeProtocol/ratio1-backend/model"
-	"github.com/NaeuralEdgeProtocol/ratio1-backend/storage"
+	"github.com/NaeuralEdgeProtocol/ratio1-backend/storage/memory"
 	"github.com/stretchr/testify/require"
 )
 
@@ -17,6 +17,10 @@ func Test_FullTest(t *testing.T) { //TO TEST THIS RETURN NIL ON emailService.go
 	address := "0xpipppoPippi"
 	email := "alberto.bast29@gmail.com"
 
+	previous := GetRepositories()
+	SetRepositories(memory.NewRepositories())
+	defer SetRepositories(previous)
+
 	account, err := GetOrCreateAccount(address)
 	require.Nil(t, err)
 	require.Equal(t, account.Address, address)
@@ -65,20 +69,20 @@ func Test_FullTest(t *testing.T) { //TO TEST THIS RETURN NIL ON emailService.go
 	_, err = RegisterEmail(address, email, false)
 	require.Equal(t, err, errors.New("email is already used"))
 
-	kyc, found, err := storage.GetKycByEmail(*account.Email)
+	kyc, found, err := repos.Kycs.GetByEmail(*account.Email)
 	require.Nil(t, err)
 	require.True(t, found)
 	NewAccountDto(account, kyc)
 	err = SubscribeEmail(kyc)
 	require.Nil(t, err)
-	newKyc, found, err := storage.GetKycByEmail(*account.Email)
+	newKyc, found, err := repos.Kycs.GetByEmail(*account.Email)
 	require.Nil(t, err)
 	require.True(t, found)
 	require.True(t, *newKyc.ReceiveUpdates)
 	NewAccountDto(account, kyc)
 	err = UnsubscribeEmail(newKyc)
 	require.Nil(t, err)
-	kyc, found, err = storage.GetKycByEmail(*account.Email)
+	kyc, found, err = repos.Kycs.GetByEmail(*account.Email)
 	require.Nil(t, err)
 	require.True(t, found)
 	require.False(t, *kyc.ReceiveUpdates)
@@ -90,7 +94,7 @@ func Test_FullTest(t *testing.T) { //TO TEST THIS RETURN NIL ON emailService.go
 	require.Nil(t, err)
 	fmt.Println(*url)
 
-	kyc, found, err = storage.GetKycByUuid(kyc.Uuid)
+	kyc, found, err = repos.Kycs.GetByUuid(kyc.Uuid)
 	require.Nil(t, err)
 	require.True(t, found)
 	event := model.SumsubEvent{
@@ -101,7 +105,7 @@ func Test_FullTest(t *testing.T) { //TO TEST THIS RETURN NIL ON emailService.go
 	}
 	err = ProcessKycEvent(event, *kyc, "")
 	require.Nil(t, err)
-	kyc, found, err = storage.GetKycByUuid(kyc.Uuid)
+	kyc, found, err = repos.Kycs.GetByUuid(kyc.Uuid)
 	require.Nil(t, err)
 	require.True(t, found)
 	require.Equal(t, kyc.KycStatus, model.StatusPending)
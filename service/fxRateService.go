@@ -0,0 +1,168 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/NaeuralEdgeProtocol/ratio1-backend/model"
+)
+
+var ErrorFxRateNotFound = errors.New("exchange rate not available")
+
+// fxRateMaxAge is how old a stored rate can be and still answer for a day,
+// it covers the weekends and bank holidays the sources do not publish on.
+const fxRateMaxAge = 4 * 24 * time.Hour
+
+// FxProvider is a source of reference rates. Rates returns the rates of the
+// given day, or of the last day published before it, always based 1 usd ->
+// value, together with the day they were published for.
+type FxProvider interface {
+	Name() string
+	Rates(date time.Time) (time.Time, map[string]float64, error)
+}
+
+// fxProviders are asked in order, the next one is only used when the previous
+// one fails.
+var fxProviders = []FxProvider{freeCurrencyApiProvider{}, ecbProvider{}}
+
+// fxRateFunc answers the rate of a currency on a day, see GetFxRate.
+type fxRateFunc func(currency string, date time.Time) (float64, error)
+
+// StoreDailyFxRates fills the fx_rates table with the rates of today. It runs
+// on the daily schedule of every node, so the providers are only asked when
+// no rate of today is stored yet, EUR being published by all of them.
+func StoreDailyFxRates() {
+	today := utcDay(time.Now())
+	rate, err := getStoredFxRate("EUR", today)
+	if err != nil {
+		fmt.Println("error while storing daily fx rates: " + err.Error())
+		return
+	} else if rate != nil && utcDay(rate.Date).Equal(today) {
+		fmt.Println("fx rates already fetched")
+		return
+	}
+
+	err = fetchFxRates(today)
+	if err != nil {
+		fmt.Println("error while storing daily fx rates: " + err.Error())
+	}
+}
+
+// GetFxRate returns how many units of currency 1 USD was worth on date. The
+// stored rates are used when one was published recently enough before date,
+// otherwise the providers are asked for that day and their answer is stored.
+func GetFxRate(currency string, date time.Time) (float64, error) {
+	currency = strings.ToUpper(strings.TrimSpace(currency))
+	if currency == "" || currency == "USD" {
+		return 1, nil
+	}
+	day := utcDay(date)
+	if today := utcDay(time.Now()); day.After(today) {
+		day = today
+	}
+
+	rate, err := getStoredFxRate(currency, day)
+	if err != nil {
+		return 0, err
+	} else if rate != nil {
+		return rate.Rate, nil
+	}
+
+	err = fetchFxRates(day)
+	if err != nil {
+		return 0, fmt.Errorf("%w for %s on %s: %s", ErrorFxRateNotFound, currency, day.Format(time.DateOnly), err.Error())
+	}
+	rate, err = getStoredFxRate(currency, day)
+	if err != nil {
+		return 0, err
+	} else if rate == nil {
+		return 0, fmt.Errorf("%w for %s on %s", ErrorFxRateNotFound, currency, day.Format(time.DateOnly))
+	}
+	return rate.Rate, nil
+}
+
+// draftExchangeRatio converts every allocation at the rate of the day it was
+// created and returns the ratio of the draft total, which is the average of
+// the daily rates weighted by the amount of each allocation. Allocations
+// without a creation time use the rate of fallbackDate.
+func draftExchangeRatio(allocations []model.Allocation, currency string, fallbackDate time.Time, rate fxRateFunc) (float64, error) {
+	rates := make(map[time.Time]float64)
+	total, converted := float64(0), float64(0)
+	for _, alloc := range allocations {
+		date := alloc.AllocationCreation
+		if date.IsZero() {
+			date = fallbackDate
+		}
+		day := utcDay(date)
+		dayRate, ok := rates[day]
+		if !ok {
+			var err error
+			dayRate, err = rate(currency, day)
+			if err != nil {
+				return 0, err
+			}
+			rates[day] = dayRate
+		}
+		amount := GetAmountAsFloat(alloc.GetUsdcAmountPayed(), model.UsdcDecimals)
+		total += amount
+		converted += amount * dayRate
+	}
+	if total == 0 {
+		return rate(currency, fallbackDate)
+	}
+	return converted / total, nil
+}
+
+// fetchFxRates stores the rates of the first provider able to answer for day.
+func fetchFxRates(day time.Time) error {
+	var failures []string
+	for _, provider := range fxProviders {
+		published, rates, err := provider.Rates(day)
+		if err == nil && len(rates) == 0 {
+			err = errors.New("no rates returned")
+		}
+		if err != nil {
+			fmt.Println("error while fetching fx rates from " + provider.Name() + ": " + err.Error())
+			failures = append(failures, provider.Name()+": "+err.Error())
+			continue
+		}
+
+		fxRates := make([]model.FxRate, 0, len(rates))
+		for currency, value := range rates {
+			if value <= 0 {
+				continue
+			}
+			fxRates = append(fxRates, model.FxRate{
+				Date:     utcDay(published),
+				Currency: strings.ToUpper(currency),
+				Rate:     value,
+				Source:   provider.Name(),
+			})
+		}
+		err = repos.FxRates.Save(fxRates...)
+		if err != nil {
+			return errors.New("error while storing fx rates: " + err.Error())
+		}
+		return nil
+	}
+	return errors.New("no fx provider answered: " + strings.Join(failures, "; "))
+}
+
+func getStoredFxRate(currency string, day time.Time) (*model.FxRate, error) {
+	rate, err := repos.FxRates.GetOnOrBefore(currency, day)
+	if err != nil {
+		return nil, errors.New("error while retrieving fx rate: " + err.Error())
+	}
+	if rate == nil || day.Sub(utcDay(rate.Date)) > fxRateMaxAge {
+		return nil, nil
+	}
+	return rate, nil
+}
+
+// utcDay truncates a time to its UTC day, the granularity of the rates.
+func utcDay(t time.Time) time.Time {
+	t = t.UTC()
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
@@ -0,0 +1,205 @@
+package service
+
+import (
+	"errors"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/NaeuralEdgeProtocol/ratio1-backend/model"
+	"github.com/NaeuralEdgeProtocol/ratio1-backend/storage/memory"
+	"github.com/stretchr/testify/require"
+)
+
+// fixedFxRates answers the same rates whatever the date.
+func fixedFxRates(rates map[string]float64) fxRateFunc {
+	return func(currency string, date time.Time) (float64, error) {
+		if currency == "" || currency == "USD" {
+			return 1, nil
+		}
+		rate, ok := rates[currency]
+		if !ok {
+			return 0, ErrorFxRateNotFound
+		}
+		return rate, nil
+	}
+}
+
+// stubFxProvider publishes the rates of the days it knows about and counts
+// how many times it was asked.
+type stubFxProvider struct {
+	name  string
+	days  map[string]map[string]float64
+	err   error
+	calls *int
+}
+
+func (p stubFxProvider) Name() string {
+	return p.name
+}
+
+func (p stubFxProvider) Rates(date time.Time) (time.Time, map[string]float64, error) {
+	*p.calls++
+	if p.err != nil {
+		return time.Time{}, nil, p.err
+	}
+	rates, ok := p.days[date.Format(time.DateOnly)]
+	if !ok {
+		return time.Time{}, nil, errors.New("no rates")
+	}
+	return date, rates, nil
+}
+
+func withFxProviders(t *testing.T, providers ...FxProvider) {
+	previousRepos, previousProviders := GetRepositories(), fxProviders
+	SetRepositories(memory.NewRepositories())
+	fxProviders = providers
+	t.Cleanup(func() {
+		SetRepositories(previousRepos)
+		fxProviders = previousProviders
+	})
+}
+
+func TestGetFxRateStoresAndReusesProviderRates(t *testing.T) {
+	calls := 0
+	withFxProviders(t, stubFxProvider{name: "stub", calls: &calls, days: map[string]map[string]float64{
+		"2026-03-06": {"EUR": 0.92, "RON": 4.6},
+	}})
+
+	// a Friday rate answers for the weekend
+	rate, err := GetFxRate("eur", time.Date(2026, 3, 6, 15, 0, 0, 0, time.UTC))
+	require.NoError(t, err)
+	require.Equal(t, 0.92, rate)
+	rate, err = GetFxRate("RON", time.Date(2026, 3, 8, 10, 0, 0, 0, time.UTC))
+	require.NoError(t, err)
+	require.Equal(t, 4.6, rate)
+	require.Equal(t, 1, calls)
+
+	stored, err := repos.FxRates.GetOnOrBefore("EUR", time.Date(2026, 3, 6, 0, 0, 0, 0, time.UTC))
+	require.NoError(t, err)
+	require.Equal(t, "stub", stored.Source)
+
+	rate, err = GetFxRate("USD", time.Now())
+	require.NoError(t, err)
+	require.Equal(t, float64(1), rate)
+
+	// a stale rate is not used and the provider knows nothing for that day
+	_, err = GetFxRate("EUR", time.Date(2026, 3, 20, 0, 0, 0, 0, time.UTC))
+	require.ErrorIs(t, err, ErrorFxRateNotFound)
+	require.Equal(t, 2, calls)
+}
+
+func TestGetFxRateFallsBackToNextProvider(t *testing.T) {
+	primaryCalls, fallbackCalls := 0, 0
+	withFxProviders(t,
+		stubFxProvider{name: "primary", calls: &primaryCalls, err: errors.New("quota exceeded")},
+		stubFxProvider{name: "fallback", calls: &fallbackCalls, days: map[string]map[string]float64{
+			"2026-02-02": {"EUR": 0.9},
+		}},
+	)
+
+	rate, err := GetFxRate("EUR", time.Date(2026, 2, 2, 0, 0, 0, 0, time.UTC))
+	require.NoError(t, err)
+	require.Equal(t, 0.9, rate)
+	require.Equal(t, 1, primaryCalls)
+	require.Equal(t, 1, fallbackCalls)
+
+	stored, err := repos.FxRates.GetOnOrBefore("EUR", time.Date(2026, 2, 2, 0, 0, 0, 0, time.UTC))
+	require.NoError(t, err)
+	require.Equal(t, "fallback", stored.Source)
+}
+
+func TestStoreDailyFxRatesAsksProvidersOncePerDay(t *testing.T) {
+	calls := 0
+	today := utcDay(time.Now())
+	withFxProviders(t, stubFxProvider{name: "stub", calls: &calls, days: map[string]map[string]float64{
+		today.Format(time.DateOnly): {"EUR": 0.92, "RON": 4.6},
+	}})
+	require.NoError(t, repos.FxRates.Save(model.FxRate{Date: today.AddDate(0, 0, -1), Currency: "EUR", Rate: 0.91, Source: "stub"}))
+
+	StoreDailyFxRates()
+	StoreDailyFxRates()
+	require.Equal(t, 1, calls)
+
+	stored, err := repos.FxRates.GetOnOrBefore("RON", today)
+	require.NoError(t, err)
+	require.Equal(t, 4.6, stored.Rate)
+}
+
+func TestParseEcbRatesRebasesToUsd(t *testing.T) {
+	body := []byte(`<?xml version="1.0" encoding="UTF-8"?>
+<gesmes:Envelope xmlns:gesmes="http://www.gesmes.org/xml/2002-08-01" xmlns="http://www.ecb.int/vocabulary/2002-08-01/eurofxref">
+	<gesmes:subject>Reference rates</gesmes:subject>
+	<Cube>
+		<Cube time="2026-03-09">
+			<Cube currency="USD" rate="1.25"/>
+			<Cube currency="RON" rate="5.0"/>
+		</Cube>
+		<Cube time="2026-03-06">
+			<Cube currency="USD" rate="1.0"/>
+			<Cube currency="RON" rate="4.9"/>
+		</Cube>
+	</Cube>
+</gesmes:Envelope>`)
+
+	published, rates, err := parseEcbRates(body, time.Date(2026, 3, 8, 0, 0, 0, 0, time.UTC))
+	require.NoError(t, err)
+	require.Equal(t, time.Date(2026, 3, 6, 0, 0, 0, 0, time.UTC), published)
+	require.Equal(t, 4.9, rates["RON"])
+
+	_, rates, err = parseEcbRates(body, time.Date(2026, 3, 9, 0, 0, 0, 0, time.UTC))
+	require.NoError(t, err)
+	require.Equal(t, 0.8, rates["EUR"])
+	require.Equal(t, float64(4), rates["RON"])
+	require.Equal(t, float64(1), rates["USD"])
+
+	_, _, err = parseEcbRates(body, time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC))
+	require.Error(t, err)
+}
+
+func TestGenerateInvoiceDraftConvertsAllocationsAtTheirDate(t *testing.T) {
+	previous := GetRepositories()
+	SetRepositories(memory.NewRepositories())
+	defer SetRepositories(previous)
+
+	require.NoError(t, repos.Preferences.Create(&model.Preference{UserAddress: "0xowner", InvoiceSeries: "R1", NextNumber: 1, LocalCurrency: "EUR"}))
+	allocations := createTestAllocations(t, "0xowner", "0xcsp", "1000000", "3000000")
+	allocations[0].AllocationCreation = time.Date(2026, 3, 2, 10, 0, 0, 0, time.UTC)
+	allocations[1].AllocationCreation = time.Date(2026, 3, 20, 10, 0, 0, 0, time.UTC)
+
+	rates := map[string]float64{"2026-03-02": 0.8, "2026-03-20": 1}
+	draft, err := generateInvoiceDraft("0xowner", "0xcsp", allocations, func(currency string, date time.Time) (float64, error) {
+		require.Equal(t, "EUR", currency)
+		return rates[date.Format(time.DateOnly)], nil
+	})
+	require.NoError(t, err)
+	// 1 USDC at 0.8 and 3 USDC at 1
+	require.InDelta(t, 3.8/4, draft.LocalCurrencyExchangeRatio, 1e-9)
+	require.InDelta(t, 3.8, draft.TotalUsdcAmount*draft.LocalCurrencyExchangeRatio, 1e-9)
+
+	// a missing rate leaves the allocations unclaimed
+	allocations = createTestAllocations(t, "0xowner", "0xcsp", "1000000")
+	_, err = generateInvoiceDraft("0xowner", "0xcsp", allocations, fixedFxRates(nil))
+	require.ErrorIs(t, err, ErrorFxRateNotFound)
+	unclaimed, err := repos.Allocations.GetMonthlyUnclaimed(time.Now().AddDate(0, 1, 0))
+	require.NoError(t, err)
+	require.Len(t, unclaimed, 1)
+}
+
+func TestBurnExchangeRatioUsesTheBurnDate(t *testing.T) {
+	calls := 0
+	withFxProviders(t, stubFxProvider{name: "stub", calls: &calls, days: map[string]map[string]float64{
+		"2026-01-15": {"EUR": 0.95},
+	}})
+
+	event := model.BurnEvent{
+		UsdcAmountSwapped: big.NewInt(2000000).String(),
+		LocalCurrency:     "EUR",
+		BurnTimestamp:     time.Date(2026, 1, 15, 18, 0, 0, 0, time.UTC),
+	}
+	require.Equal(t, 0.95, burnExchangeRatio(event))
+
+	event.ExchangeRatio = 0.9
+	require.Equal(t, 0.9, burnExchangeRatio(event))
+	require.Equal(t, 1, calls)
+}
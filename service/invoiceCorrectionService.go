@@ -0,0 +1,325 @@
+package service
+
+import (
+	"errors"
+	"time"
+
+	"github.com/NaeuralEdgeProtocol/ratio1-backend/model"
+)
+
+// invoiceSystemActor is the actor of the status changes made by the invoicing
+// worker.
+const invoiceSystemActor = "system"
+
+// oblioInvoiceDocumentType is the type of the document a credit note refers to.
+const oblioInvoiceDocumentType = "Factura"
+
+var (
+	ErrorInvoiceNotFound       = errors.New("invoice not found")
+	ErrorInvoiceNotCorrectable = errors.New("invoice cannot be corrected in its current status")
+)
+
+// InvoiceCorrection is an admin correction of an issued invoice. With Reissue
+// a new invoice is issued for the purchase once the original one is voided,
+// with the client data updated by Client.
+type InvoiceCorrection struct {
+	Reason  string
+	Reissue bool
+	Client  *InvoiceClientUpdate
+}
+
+// InvoiceClientUpdate holds the client fields to change, nil fields are kept.
+type InvoiceClientUpdate struct {
+	Name               *string `json:"name"`
+	Surname            *string `json:"surname"`
+	CompanyName        *string `json:"companyName"`
+	UserEmail          *string `json:"userEmail"`
+	IdentificationCode *string `json:"identificationCode"`
+	Address            *string `json:"address"`
+	State              *string `json:"state"`
+	City               *string `json:"city"`
+	Country            *string `json:"country"`
+	IsCompany          *bool   `json:"isCompany"`
+	ReverseCharge      *bool   `json:"reverseCharge"`
+	IsUe               *bool   `json:"isUe"`
+}
+
+// CancelInvoice cancels an issued invoice with the invoicing provider.
+func CancelInvoice(invoiceUuid, adminAddress string, correction InvoiceCorrection) (*model.InvoiceClient, error) {
+	invoice, event, err := getCorrectableInvoice(invoiceUuid)
+	if err != nil {
+		return nil, err
+	}
+
+	provider := GetInvoiceProvider()
+	err = provider.Authenticate()
+	if err != nil {
+		return nil, errors.New("error while authenticating to invoice provider: " + err.Error())
+	}
+	err = provider.Cancel(*invoice.InvoiceSeries, *invoice.InvoiceNumber)
+	if err != nil {
+		return nil, errors.New("error while cancelling invoice: " + err.Error())
+	}
+
+	return voidInvoice(invoice, event, provider, adminAddress, invoiceVoiding{
+		status:       model.InvoiceStatusCancelled,
+		documentType: model.InvoiceDocumentInvoice,
+		auditAction:  model.AuditActionInvoiceCancelled,
+	}, correction)
+}
+
+// CreditInvoice issues a credit note reversing an issued invoice, the invoice
+// itself is kept.
+func CreditInvoice(invoiceUuid, adminAddress string, correction InvoiceCorrection) (*model.InvoiceClient, error) {
+	invoice, event, err := getCorrectableInvoice(invoiceUuid)
+	if err != nil {
+		return nil, err
+	}
+
+	provider := GetInvoiceProvider()
+	err = provider.Authenticate()
+	if err != nil {
+		return nil, errors.New("error while authenticating to invoice provider: " + err.Error())
+	}
+
+	creditNote, err := buildInvoiceRequest(*invoice, event)
+	if err != nil {
+		return nil, err
+	}
+	creditNote.Collect = nil
+	creditNote.Mentions = "Credit note of invoice " + *invoice.InvoiceSeries + " " + *invoice.InvoiceNumber
+	if correction.Reason != "" {
+		creditNote.Mentions += "\nReason: " + correction.Reason
+	}
+	creditNote.ReferenceDocument = &model.InvoiceReferenceDocument{
+		Type:       oblioInvoiceDocumentType,
+		SeriesName: *invoice.InvoiceSeries,
+		Number:     *invoice.InvoiceNumber,
+		Refund:     1,
+	}
+	for i := range creditNote.Product {
+		creditNote.Product[i].Quantity = -creditNote.Product[i].Quantity
+	}
+
+	issued, err := provider.Issue(creditNote)
+	if err != nil {
+		return nil, errors.New("error while issuing credit note: " + err.Error())
+	}
+
+	return voidInvoice(invoice, event, provider, adminAddress, invoiceVoiding{
+		status:       model.InvoiceStatusRefunded,
+		documentType: model.InvoiceDocumentCreditNote,
+		auditAction:  model.AuditActionInvoiceCredited,
+		document:     issued,
+	}, correction)
+}
+
+// GetInvoiceStatusHistory returns the status changes of an invoice, oldest
+// first.
+func GetInvoiceStatusHistory(invoiceUuid string) ([]model.InvoiceStatusChange, error) {
+	_, found, err := repos.Invoices.GetByID(invoiceUuid)
+	if err != nil {
+		return nil, errors.New("error while retrieving invoice from storage: " + err.Error())
+	} else if !found {
+		return nil, ErrorInvoiceNotFound
+	}
+
+	changes, err := repos.Invoices.GetStatusChanges(invoiceUuid)
+	if err != nil {
+		return nil, errors.New("error while retrieving invoice status history from storage: " + err.Error())
+	}
+	return changes, nil
+}
+
+func getCorrectableInvoice(invoiceUuid string) (*model.InvoiceClient, model.Event, error) {
+	invoice, found, err := repos.Invoices.GetByID(invoiceUuid)
+	if err != nil {
+		return nil, model.Event{}, errors.New("error while retrieving invoice from storage: " + err.Error())
+	} else if !found {
+		return nil, model.Event{}, ErrorInvoiceNotFound
+	}
+	if invoice.Status == nil || *invoice.Status != model.InvoiceStatusPaid ||
+		invoice.InvoiceSeries == nil || invoice.InvoiceNumber == nil || *invoice.InvoiceNumber == "" {
+		return nil, model.Event{}, ErrorInvoiceNotCorrectable
+	}
+
+	purchase, err := repos.LicensePurchases.GetByInvoiceID(invoiceUuid)
+	if err != nil {
+		return nil, model.Event{}, errors.New("error while retrieving license purchase from storage: " + err.Error())
+	} else if purchase == nil {
+		return nil, model.Event{}, errors.New("license purchase not found for invoice " + invoiceUuid)
+	}
+
+	return invoice, purchase.Event(), nil
+}
+
+// invoiceVoiding is how an invoice has been voided, document is the credit
+// note when there is one.
+type invoiceVoiding struct {
+	status       string
+	documentType string
+	auditAction  string
+	document     *IssuedInvoice
+}
+
+// voidInvoice records that the invoice has been voided and reissues it when
+// asked to. The history keeps the voiding document, the invoice record keeps
+// the original invoice until it is reissued.
+func voidInvoice(invoice *model.InvoiceClient, event model.Event, provider InvoiceProvider, adminAddress string, voiding invoiceVoiding, correction InvoiceCorrection) (*model.InvoiceClient, error) {
+	var reason *string
+	if correction.Reason != "" {
+		reason = &correction.Reason
+	}
+
+	voided := *invoice
+	voided.Status = &voiding.status
+	recorded := voided
+	if voiding.document != nil {
+		recorded.InvoiceSeries = &voiding.document.SeriesName
+		recorded.InvoiceNumber = &voiding.document.Number
+		recorded.InvoiceUrl = &voiding.document.Link
+	}
+	err := recordInvoiceStatus(&recorded, voiding.documentType, adminAddress, reason)
+	if err != nil {
+		return nil, err
+	}
+
+	details := "invoice " + *invoice.InvoiceSeries + " " + *invoice.InvoiceNumber + " " + voiding.status
+	if reason != nil {
+		details += ": " + *reason
+	}
+	err = recordAudit(voiding.auditAction, adminAddress, invoice.BlockchainAddress, &details)
+	if err != nil {
+		return nil, err
+	}
+
+	if !correction.Reissue {
+		err = repos.Invoices.Update(&voided)
+		if err != nil {
+			return nil, errors.New("error while updating invoice in storage: " + err.Error())
+		}
+		return &voided, nil
+	}
+
+	corrected := *invoice
+	correction.Client.apply(&corrected)
+	var issued *IssuedInvoice
+	var request model.InvoiceRequest
+	if correction.Client.changesVat() {
+		err = applyLicenseVat(&corrected)
+	}
+	if err == nil {
+		request, err = buildInvoiceRequest(corrected, event)
+	}
+	if err == nil {
+		issued, err = provider.Issue(request)
+	}
+	if err != nil {
+		// the original invoice is voided, the record must say so even though
+		// the new one could not be issued
+		updateErr := repos.Invoices.Update(&voided)
+		if updateErr != nil {
+			return nil, errors.New("error while updating invoice in storage: " + updateErr.Error())
+		}
+		return nil, errors.New("error while reissuing invoice: " + err.Error())
+	}
+
+	corrected.InvoiceSeries = &issued.SeriesName
+	corrected.InvoiceNumber = &issued.Number
+	corrected.InvoiceUrl = &issued.Link
+	err = repos.Invoices.Update(&corrected)
+	if err != nil {
+		return nil, errors.New("error while updating invoice in storage: " + err.Error())
+	}
+	err = recordInvoiceStatus(&corrected, model.InvoiceDocumentInvoice, adminAddress, reason)
+	if err != nil {
+		return nil, err
+	}
+
+	return &corrected, nil
+}
+
+func (u *InvoiceClientUpdate) apply(invoice *model.InvoiceClient) {
+	if u == nil {
+		return
+	}
+	if u.Name != nil {
+		invoice.Name = u.Name
+	}
+	if u.Surname != nil {
+		invoice.Surname = u.Surname
+	}
+	if u.CompanyName != nil {
+		invoice.CompanyName = u.CompanyName
+	}
+	if u.UserEmail != nil {
+		invoice.UserEmail = u.UserEmail
+	}
+	if u.IdentificationCode != nil {
+		invoice.IdentificationCode = *u.IdentificationCode
+	}
+	if u.Address != nil {
+		invoice.Address = *u.Address
+	}
+	if u.State != nil {
+		invoice.State = *u.State
+	}
+	if u.City != nil {
+		invoice.City = *u.City
+	}
+	if u.Country != nil {
+		invoice.Country = *u.Country
+	}
+	if u.IsCompany != nil {
+		invoice.IsCompany = *u.IsCompany
+	}
+	if u.ReverseCharge != nil {
+		invoice.ReverseCharge = *u.ReverseCharge
+	}
+	if u.IsUe != nil {
+		invoice.IsUe = *u.IsUe
+	}
+}
+
+// changesVat reports whether the update touches a field the vat of the
+// licenses depends on.
+func (u *InvoiceClientUpdate) changesVat() bool {
+	return u != nil && (u.Country != nil || u.IsCompany != nil || u.ReverseCharge != nil)
+}
+
+// applyLicenseVat recomputes the vat of a corrected invoice at the date the
+// licenses were paid, the stored one was computed for the former buyer.
+func applyLicenseVat(invoice *model.InvoiceClient) error {
+	supplyDate := time.Now()
+	if invoice.PaidAt != nil {
+		supplyDate = *invoice.PaidAt
+	}
+	vat, err := LicenseVat(invoice.Country, invoice.IsCompany, invoice.ReverseCharge, supplyDate)
+	if err != nil {
+		return errors.New("error while computing vat: " + err.Error())
+	}
+	invoice.VatRate = &vat.Rate
+	invoice.VatScheme = &vat.Scheme
+	return nil
+}
+
+func recordInvoiceStatus(invoice *model.InvoiceClient, documentType, actor string, reason *string) error {
+	err := repos.Invoices.AddStatusChange(&model.InvoiceStatusChange{
+		InvoiceUuid:   *invoice.Uuid,
+		Status:        *invoice.Status,
+		DocumentType:  documentType,
+		InvoiceSeries: invoice.InvoiceSeries,
+		InvoiceNumber: invoice.InvoiceNumber,
+		InvoiceUrl:    invoice.InvoiceUrl,
+		Country:       invoice.Country,
+		VatRate:       invoice.VatRate,
+		VatScheme:     invoice.VatScheme,
+		Actor:         actor,
+		Reason:        reason,
+		CreatedAt:     time.Now(),
+	})
+	if err != nil {
+		return errors.New("error while recording invoice status: " + err.Error())
+	}
+	return nil
+}
@@ -0,0 +1,108 @@
+package service
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/NaeuralEdgeProtocol/ratio1-backend/model"
+	"github.com/stretchr/testify/require"
+)
+
+// withPaidInvoice runs the invoicing worker on a queued purchase and returns
+// the paid invoice.
+func withPaidInvoice(t *testing.T) (*model.InvoiceClient, *failingInvoiceProvider) {
+	purchase, provider := withInvoiceQueue(t)
+	ElaborateInvoices()
+
+	invoice, _, err := repos.Invoices.GetByID(purchase.InvoiceID)
+	require.NoError(t, err)
+	require.Equal(t, model.InvoiceStatusPaid, *invoice.Status)
+	return invoice, provider
+}
+
+func TestCancelInvoiceReissuesWithCorrectedClient(t *testing.T) {
+	invoice, provider := withPaidInvoice(t)
+	companyName := "Buyer Corrected Ltd"
+
+	corrected, err := CancelInvoice(*invoice.Uuid, "0xadmin", InvoiceCorrection{
+		Reason:  "wrong company name",
+		Reissue: true,
+		Client:  &InvoiceClientUpdate{CompanyName: &companyName},
+	})
+	require.NoError(t, err)
+	require.Equal(t, model.InvoiceStatusPaid, *corrected.Status)
+	require.Equal(t, companyName, *corrected.CompanyName)
+	require.Equal(t, "2", *corrected.InvoiceNumber)
+
+	link, err := provider.PdfLink(*invoice.InvoiceSeries, *invoice.InvoiceNumber)
+	require.NoError(t, err)
+	require.True(t, strings.HasSuffix(link, ".cancelled.pdf"))
+
+	history, err := GetInvoiceStatusHistory(*invoice.Uuid)
+	require.NoError(t, err)
+	require.Len(t, history, 3)
+	require.Equal(t, model.InvoiceStatusPaid, history[0].Status)
+	require.Equal(t, invoiceSystemActor, history[0].Actor)
+	require.Equal(t, model.InvoiceStatusCancelled, history[1].Status)
+	require.Equal(t, "1", *history[1].InvoiceNumber)
+	require.Equal(t, "wrong company name", *history[1].Reason)
+	require.Equal(t, model.InvoiceStatusPaid, history[2].Status)
+	require.Equal(t, "2", *history[2].InvoiceNumber)
+	require.Equal(t, "0xadmin", history[2].Actor)
+
+	stored, _, err := repos.Invoices.GetByID(*invoice.Uuid)
+	require.NoError(t, err)
+	require.Equal(t, *corrected, *stored)
+}
+
+func TestCancelInvoiceRecomputesVatOfCorrectedCountry(t *testing.T) {
+	invoice, provider := withPaidInvoice(t)
+	rate, scheme := float64(21), VatSchemeDomestic
+	invoice.VatRate, invoice.VatScheme = &rate, &scheme
+	require.NoError(t, repos.Invoices.Update(invoice))
+	country, isCompany, name, surname := "FRA", false, "Jean", "Dupont"
+
+	corrected, err := CancelInvoice(*invoice.Uuid, "0xadmin", InvoiceCorrection{
+		Reason:  "buyer is a french consumer",
+		Reissue: true,
+		Client:  &InvoiceClientUpdate{Name: &name, Surname: &surname, Country: &country, IsCompany: &isCompany},
+	})
+	require.NoError(t, err)
+	require.Equal(t, float64(20), *corrected.VatRate)
+	require.Equal(t, VatSchemeOss, *corrected.VatScheme)
+	require.Equal(t, float64(20), provider.last.Product[0].VatPercentage)
+	require.Equal(t, "VAT FRA", provider.last.Product[0].VatName)
+}
+
+func TestCreditInvoiceIssuesCreditNote(t *testing.T) {
+	invoice, _ := withPaidInvoice(t)
+
+	refunded, err := CreditInvoice(*invoice.Uuid, "0xadmin", InvoiceCorrection{Reason: "refund"})
+	require.NoError(t, err)
+	require.Equal(t, model.InvoiceStatusRefunded, *refunded.Status)
+	require.Equal(t, *invoice.InvoiceNumber, *refunded.InvoiceNumber)
+
+	history, err := GetInvoiceStatusHistory(*invoice.Uuid)
+	require.NoError(t, err)
+	require.Len(t, history, 2)
+	creditNote := history[1]
+	require.Equal(t, model.InvoiceDocumentCreditNote, creditNote.DocumentType)
+	require.Equal(t, "2", *creditNote.InvoiceNumber)
+
+	data, err := os.ReadFile(strings.TrimPrefix(*creditNote.InvoiceUrl, "file://"))
+	require.NoError(t, err)
+	require.Contains(t, string(data), "CREDIT NOTE")
+	require.Contains(t, string(data), "Reverses invoice "+*invoice.InvoiceSeries+" no. 1")
+	require.Contains(t, string(data), "(-2) Tj")
+
+	_, err = CreditInvoice(*invoice.Uuid, "0xadmin", InvoiceCorrection{Reason: "refund"})
+	require.ErrorIs(t, err, ErrorInvoiceNotCorrectable)
+	_, err = CancelInvoice("missing", "0xadmin", InvoiceCorrection{Reason: "refund"})
+	require.ErrorIs(t, err, ErrorInvoiceNotFound)
+
+	logs, err := repos.AuditLogs.GetBySubject(invoice.BlockchainAddress)
+	require.NoError(t, err)
+	require.Len(t, logs, 1)
+	require.Equal(t, model.AuditActionInvoiceCredited, logs[0].Action)
+}
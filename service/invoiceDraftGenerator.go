@@ -72,15 +72,18 @@ type invoiceVM struct {
 
 	ExtraLines []extraLineVM
 
-	JobCount int
-	Notes    string
-	Status   string
+	JobCount   int
+	Notes      string
+	VatMention string
+	Status     string
 }
 
 func buildInvoiceView(invoice model.InvoiceDraft, allocations []model.Allocation) invoiceVM {
 	// Seller/Buyer lines
-	from := &invoice.UserProfile
-	to := &invoice.CspProfile
+	seller := invoice.Seller()
+	buyer := invoice.Buyer()
+	from := &seller
+	to := &buyer
 	fromLines := formatUserInfo(from)
 	toLines := formatUserInfo(to)
 
@@ -146,7 +149,7 @@ func buildInvoiceView(invoice model.InvoiceDraft, allocations []model.Allocation
 	}
 
 	title := "Invoice Draft"
-	if !invoice.UserProfile.IsCompany {
+	if !seller.IsCompany {
 		title = "Consumption Report"
 	}
 	//draft fields filling
@@ -170,6 +173,7 @@ func buildInvoiceView(invoice model.InvoiceDraft, allocations []model.Allocation
 		ExtraLines:     extraVM,
 		JobCount:       len(allocations),
 		Notes:          safe(invoice.ExtraText),
+		VatMention:     invoice.VatMention,
 		LocalCurrency:  invoice.LocalCurrency,
 		TotalLocal:     fmt.Sprintf("%.2f", invoice.TotalUsdcAmount*invoice.LocalCurrencyExchangeRatio),
 		VatAmountLocal: fmt.Sprintf("%.2f", vatAmount*invoice.LocalCurrencyExchangeRatio),
@@ -184,8 +188,10 @@ func buildInvoiceView(invoice model.InvoiceDraft, allocations []model.Allocation
 
 func buildInvoiceViewJSON(invoice model.InvoiceDraft, allocations []model.Allocation) invoiceVM {
 	// Seller/Buyer lines
-	from := &invoice.UserProfile
-	to := &invoice.CspProfile
+	seller := invoice.Seller()
+	buyer := invoice.Buyer()
+	from := &seller
+	to := &buyer
 	fromLines := formatUserInfoJSON(from)
 	toLines := formatUserInfoJSON(to)
 
@@ -251,7 +257,7 @@ func buildInvoiceViewJSON(invoice model.InvoiceDraft, allocations []model.Alloca
 	}
 
 	title := "Invoice Draft"
-	if !invoice.UserProfile.IsCompany {
+	if !seller.IsCompany {
 		title = "Consumption Report"
 	}
 	//draft fields filling
@@ -275,6 +281,7 @@ func buildInvoiceViewJSON(invoice model.InvoiceDraft, allocations []model.Alloca
 		ExtraLines:     extraVM,
 		JobCount:       len(allocations),
 		Notes:          safe(invoice.ExtraText),
+		VatMention:     invoice.VatMention,
 		LocalCurrency:  invoice.LocalCurrency,
 		TotalLocal:     fmt.Sprintf("%.2f", invoice.TotalUsdcAmount*invoice.LocalCurrencyExchangeRatio),
 		VatAmountLocal: fmt.Sprintf("%.2f", vatAmount*invoice.LocalCurrencyExchangeRatio),
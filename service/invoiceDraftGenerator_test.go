@@ -2,6 +2,7 @@ package service
 
 import (
 	"os"
+	"path/filepath"
 	"testing"
 	"time"
 
@@ -111,7 +112,7 @@ func Test_generateInvoiceDraft(t *testing.T) {
 	file, err := FillInvoiceDraftTemplate(invoice, allocations)
 	require.Nil(t, err)
 
-	if err := os.WriteFile("invoice_draft.doc", file, 0644); err != nil {
+	if err := os.WriteFile(filepath.Join(t.TempDir(), "invoice_draft.doc"), file, 0644); err != nil {
 		require.Nil(t, err)
 	}
 }
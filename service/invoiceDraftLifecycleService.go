@@ -0,0 +1,234 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/NaeuralEdgeProtocol/ratio1-backend/config"
+	"github.com/NaeuralEdgeProtocol/ratio1-backend/model"
+	"github.com/NaeuralEdgeProtocol/ratio1-backend/storage"
+	"github.com/google/uuid"
+)
+
+var (
+	ErrorDraftNotFound              = errors.New("invoice draft not found")
+	ErrorDraftTransitionNotAllowed  = errors.New("invoice draft transition not allowed")
+	ErrorDraftActorNotAllowed       = errors.New("address not allowed to change the invoice draft")
+	ErrorDraftVoidReasonRequired    = errors.New("a reason is required to void an invoice draft")
+	ErrorDraftDisputeReasonRequired = errors.New("a reason is required to dispute an invoice draft")
+)
+
+var sendDraftStatusEmailFn = SendDraftStatusEmail
+
+// draftTransitionRule lists the statuses a draft can reach a status from and
+// which party of the draft can move it there.
+type draftTransitionRule struct {
+	from  []string
+	owner bool
+	csp   bool
+}
+
+var draftTransitionRules = map[string]draftTransitionRule{
+	model.DraftStatusIssued:       {from: []string{model.DraftStatusDraft}, owner: true},
+	model.DraftStatusAcknowledged: {from: []string{model.DraftStatusIssued}, csp: true},
+	model.DraftStatusPaid:         {from: []string{model.DraftStatusIssued, model.DraftStatusAcknowledged}, owner: true, csp: true},
+	model.DraftStatusDisputed:     {from: []string{model.DraftStatusIssued, model.DraftStatusAcknowledged}, csp: true},
+	model.DraftStatusVoid:         {from: []string{model.DraftStatusDraft, model.DraftStatusIssued, model.DraftStatusAcknowledged, model.DraftStatusDisputed}, owner: true},
+}
+
+// FinalizeDraft issues the draft to the csp, only the node owner can do it.
+func FinalizeDraft(address, draftId string) (*model.InvoiceDraft, error) {
+	return transitionDraft(address, draftId, model.DraftStatusIssued, nil, nil)
+}
+
+// AcknowledgeDraft records that the csp received the issued draft.
+func AcknowledgeDraft(address, draftId string) (*model.InvoiceDraft, error) {
+	return transitionDraft(address, draftId, model.DraftStatusAcknowledged, nil, nil)
+}
+
+// MarkDraftPaid can be called by either party, reference is the optional tx
+// hash or bank reference of the payment.
+func MarkDraftPaid(address, draftId, reference string) (*model.InvoiceDraft, error) {
+	return transitionDraft(address, draftId, model.DraftStatusPaid, optionalText(reference), nil)
+}
+
+// VoidDraft cancels a draft that was not paid yet, only the node owner can do
+// it and the reason is mandatory.
+func VoidDraft(address, draftId, reason string) (*model.InvoiceDraft, error) {
+	if strings.TrimSpace(reason) == "" {
+		return nil, ErrorDraftVoidReasonRequired
+	}
+	return transitionDraft(address, draftId, model.DraftStatusVoid, nil, optionalText(reason))
+}
+
+// DisputeDraft records that the csp contests an issued draft, only the csp
+// can do it and the reason is mandatory. The node owner can then void it.
+func DisputeDraft(address, draftId, reason string) (*model.InvoiceDraft, error) {
+	if strings.TrimSpace(reason) == "" {
+		return nil, ErrorDraftDisputeReasonRequired
+	}
+	return transitionDraft(address, draftId, model.DraftStatusDisputed, nil, optionalText(reason))
+}
+
+// GetDraftTransitions returns the lifecycle history of a draft to one of its
+// parties, oldest first.
+func GetDraftTransitions(address, draftId string) ([]model.InvoiceDraftTransition, error) {
+	draft, _, err := getPartyDraft(address, draftId)
+	if err != nil {
+		return nil, err
+	}
+
+	transitions, err := repos.Drafts.GetTransitions(draft.DraftId)
+	if err != nil {
+		return nil, errors.New("error while retrieving draft transitions: " + err.Error())
+	}
+	if transitions == nil {
+		transitions = []model.InvoiceDraftTransition{}
+	}
+	return transitions, nil
+}
+
+// FilterDraftsByStatus keeps the drafts in the given status, an empty status
+// keeps them all.
+func FilterDraftsByStatus(drafts []model.InvoiceDraft, status string) []model.InvoiceDraft {
+	if status == "" {
+		return drafts
+	}
+	filtered := make([]model.InvoiceDraft, 0, len(drafts))
+	for _, draft := range drafts {
+		if draft.CurrentStatus() == status {
+			filtered = append(filtered, draft)
+		}
+	}
+	return filtered
+}
+
+// IsDraftStatus reports whether status is one of the draft lifecycle statuses.
+func IsDraftStatus(status string) bool {
+	_, ok := draftTransitionRules[status]
+	return ok || status == model.DraftStatusDraft || status == model.DraftStatusSuperseded
+}
+
+func transitionDraft(address, draftId, to string, reference, reason *string) (*model.InvoiceDraft, error) {
+	draft, isOwner, err := getPartyDraft(address, draftId)
+	if err != nil {
+		return nil, err
+	}
+
+	rule := draftTransitionRules[to]
+	if (isOwner && !rule.owner) || (!isOwner && !rule.csp) {
+		return nil, ErrorDraftActorNotAllowed
+	}
+	from := draft.CurrentStatus()
+	if !slices.Contains(rule.from, from) {
+		return nil, fmt.Errorf("%w: %s to %s", ErrorDraftTransitionNotAllowed, from, to)
+	}
+
+	transition := model.InvoiceDraftTransition{
+		FromStatus: from,
+		ToStatus:   to,
+		Actor:      address,
+		Reference:  reference,
+		Reason:     reason,
+	}
+	// drafts stored before the status existed have it empty until the
+	// migration default is applied
+	allowed := rule.from
+	if from == model.DraftStatusDraft {
+		allowed = append(slices.Clone(allowed), "")
+	}
+	err = repos.Drafts.Transition(draft.DraftId, allowed, &transition)
+	if errors.Is(err, storage.ErrDraftStatusChanged) {
+		return nil, fmt.Errorf("%w: %s", ErrorDraftTransitionNotAllowed, err.Error())
+	} else if err != nil {
+		return nil, errors.New("error while updating draft status: " + err.Error())
+	}
+	draft.Status = to
+
+	notifyDraftStatus(draft, transition, isOwner)
+	return draft, nil
+}
+
+// getPartyDraft returns the draft when address is its node owner or its csp
+// owner, the bool is true for the node owner.
+func getPartyDraft(address, draftId string) (*model.InvoiceDraft, bool, error) {
+	if _, err := uuid.Parse(draftId); err != nil {
+		return nil, false, ErrorDraftNotFound
+	}
+
+	draft, err := repos.Drafts.GetByReportId(draftId, address)
+	if err != nil {
+		return nil, false, errors.New("error while retrieving draft: " + err.Error())
+	}
+	if draft.DraftId != uuid.Nil {
+		return draft, true, nil
+	}
+
+	draft, err = repos.Drafts.GetCspByReportId(draftId, address)
+	if err != nil {
+		return nil, false, errors.New("error while retrieving draft: " + err.Error())
+	}
+	if draft.DraftId != uuid.Nil {
+		return draft, false, nil
+	}
+	return nil, false, ErrorDraftNotFound
+}
+
+// notifyDraftStatus emails the other party of the draft, failures are logged
+// since the transition is already stored.
+func notifyDraftStatus(draft *model.InvoiceDraft, transition model.InvoiceDraftTransition, byOwner bool) {
+	address, fallbackEmail, url := draft.CspOwner, draft.CspProfile.Email, config.Config.Ratio1redirectUrl.CspUrl
+	if !byOwner {
+		address, fallbackEmail, url = draft.UserAddress, draft.UserProfile.Email, config.Config.Ratio1redirectUrl.OperatorUrl
+	}
+
+	number := draftDisplayNumber(draft)
+	message := draftStatusMessage(transition)
+	for _, email := range draftNotificationEmails(address, fallbackEmail) {
+		err := sendDraftStatusEmailFn(email, url, number, transition.ToStatus, message)
+		if err != nil {
+			fmt.Println("error while sending draft status email to " + email + ": " + err.Error())
+		}
+	}
+}
+
+func draftStatusMessage(transition model.InvoiceDraftTransition) string {
+	switch transition.ToStatus {
+	case model.DraftStatusIssued:
+		return "The node owner finalized it and it is ready for your review."
+	case model.DraftStatusAcknowledged:
+		return "The CSP acknowledged it."
+	case model.DraftStatusPaid:
+		if transition.Reference != nil {
+			return "It was marked as paid, payment reference " + *transition.Reference + "."
+		}
+		return "It was marked as paid."
+	case model.DraftStatusDisputed:
+		return "The CSP disputed it: " + *transition.Reason
+	case model.DraftStatusVoid:
+		return "The node owner voided it: " + *transition.Reason
+	}
+	return ""
+}
+
+func draftDisplayNumber(draft *model.InvoiceDraft) string {
+	if draft.InvoiceNumber == 0 {
+		return draft.DraftId.String()
+	}
+	number := strconv.Itoa(draft.InvoiceNumber)
+	if draft.InvoiceSeries != "" {
+		number += " " + draft.InvoiceSeries
+	}
+	return number
+}
+
+func optionalText(text string) *string {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return nil
+	}
+	return &text
+}
@@ -0,0 +1,169 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/NaeuralEdgeProtocol/ratio1-backend/model"
+	"github.com/NaeuralEdgeProtocol/ratio1-backend/storage/memory"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	lifecycleOwner = "0x00000000000000000000000000000000000000a1"
+	lifecycleCsp   = "0x00000000000000000000000000000000000000c1"
+)
+
+type sentDraftStatus struct {
+	email  string
+	status string
+}
+
+func withDraftLifecycle(t *testing.T) (*model.InvoiceDraft, *[]sentDraftStatus) {
+	previousRepos := repos
+	SetRepositories(memory.NewRepositories())
+	t.Cleanup(func() { SetRepositories(previousRepos) })
+
+	sent := &[]sentDraftStatus{}
+	previousSend := sendDraftStatusEmailFn
+	sendDraftStatusEmailFn = func(email, url, draftNumber, status, message string) error {
+		*sent = append(*sent, sentDraftStatus{email, status})
+		return nil
+	}
+	t.Cleanup(func() { sendDraftStatusEmailFn = previousSend })
+
+	require.NoError(t, repos.UserInfos.Create(&model.UserInfo{BlockchainAddress: lifecycleOwner, Email: "owner@example.com"}))
+	require.NoError(t, repos.UserInfos.Create(&model.UserInfo{BlockchainAddress: lifecycleCsp, Email: "csp@example.com"}))
+	draft := &model.InvoiceDraft{
+		CreationTimestamp: time.Now(),
+		UserAddress:       lifecycleOwner,
+		CspOwner:          lifecycleCsp,
+		InvoiceSeries:     "R1",
+		InvoiceNumber:     7,
+	}
+	require.NoError(t, repos.Drafts.Create(draft))
+	return draft, sent
+}
+
+func TestDraftLifecycleFinalizeAcknowledgePay(t *testing.T) {
+	draft, sent := withDraftLifecycle(t)
+	id := draft.DraftId.String()
+
+	_, err := AcknowledgeDraft(lifecycleCsp, id)
+	require.ErrorIs(t, err, ErrorDraftTransitionNotAllowed)
+	_, err = FinalizeDraft(lifecycleCsp, id)
+	require.ErrorIs(t, err, ErrorDraftActorNotAllowed)
+
+	updated, err := FinalizeDraft(lifecycleOwner, id)
+	require.NoError(t, err)
+	require.Equal(t, model.DraftStatusIssued, updated.Status)
+
+	_, err = AcknowledgeDraft(lifecycleOwner, id)
+	require.ErrorIs(t, err, ErrorDraftActorNotAllowed)
+	_, err = AcknowledgeDraft(lifecycleCsp, id)
+	require.NoError(t, err)
+
+	updated, err = MarkDraftPaid(lifecycleCsp, id, " 0xabc ")
+	require.NoError(t, err)
+	require.Equal(t, model.DraftStatusPaid, updated.Status)
+
+	_, err = VoidDraft(lifecycleOwner, id, "sent twice")
+	require.ErrorIs(t, err, ErrorDraftTransitionNotAllowed)
+
+	transitions, err := GetDraftTransitions(lifecycleCsp, id)
+	require.NoError(t, err)
+	require.Len(t, transitions, 3)
+	require.Equal(t, model.DraftStatusDraft, transitions[0].FromStatus)
+	require.Equal(t, model.DraftStatusIssued, transitions[0].ToStatus)
+	require.Equal(t, lifecycleOwner, transitions[0].Actor)
+	require.Equal(t, lifecycleCsp, transitions[2].Actor)
+	require.Equal(t, "0xabc", *transitions[2].Reference)
+	require.False(t, transitions[2].CreatedAt.IsZero())
+
+	require.Equal(t, []sentDraftStatus{
+		{"csp@example.com", model.DraftStatusIssued},
+		{"owner@example.com", model.DraftStatusAcknowledged},
+		{"owner@example.com", model.DraftStatusPaid},
+	}, *sent)
+}
+
+func TestDraftLifecycleVoid(t *testing.T) {
+	draft, sent := withDraftLifecycle(t)
+	id := draft.DraftId.String()
+
+	_, err := VoidDraft(lifecycleOwner, id, " ")
+	require.ErrorIs(t, err, ErrorDraftVoidReasonRequired)
+	_, err = VoidDraft(lifecycleCsp, id, "wrong amount")
+	require.ErrorIs(t, err, ErrorDraftActorNotAllowed)
+
+	updated, err := VoidDraft(lifecycleOwner, id, "wrong amount")
+	require.NoError(t, err)
+	require.Equal(t, model.DraftStatusVoid, updated.Status)
+	_, err = FinalizeDraft(lifecycleOwner, id)
+	require.ErrorIs(t, err, ErrorDraftTransitionNotAllowed)
+
+	transitions, err := GetDraftTransitions(lifecycleOwner, id)
+	require.NoError(t, err)
+	require.Len(t, transitions, 1)
+	require.Equal(t, "wrong amount", *transitions[0].Reason)
+	require.Equal(t, []sentDraftStatus{{"csp@example.com", model.DraftStatusVoid}}, *sent)
+
+	_, err = GetDraftTransitions("0x00000000000000000000000000000000000000b2", id)
+	require.ErrorIs(t, err, ErrorDraftNotFound)
+	_, err = MarkDraftPaid(lifecycleOwner, uuid.NewString(), "")
+	require.ErrorIs(t, err, ErrorDraftNotFound)
+}
+
+func TestDraftLifecycleDispute(t *testing.T) {
+	draft, sent := withDraftLifecycle(t)
+	id := draft.DraftId.String()
+
+	_, err := DisputeDraft(lifecycleCsp, id, "wrong amount")
+	require.ErrorIs(t, err, ErrorDraftTransitionNotAllowed)
+	_, err = FinalizeDraft(lifecycleOwner, id)
+	require.NoError(t, err)
+	_, err = AcknowledgeDraft(lifecycleCsp, id)
+	require.NoError(t, err)
+
+	_, err = DisputeDraft(lifecycleCsp, id, " ")
+	require.ErrorIs(t, err, ErrorDraftDisputeReasonRequired)
+	_, err = DisputeDraft(lifecycleOwner, id, "wrong amount")
+	require.ErrorIs(t, err, ErrorDraftActorNotAllowed)
+
+	updated, err := DisputeDraft(lifecycleCsp, id, "wrong amount")
+	require.NoError(t, err)
+	require.Equal(t, model.DraftStatusDisputed, updated.Status)
+	require.Equal(t, "The CSP disputed it: wrong amount", draftStatusMessage(model.InvoiceDraftTransition{ToStatus: model.DraftStatusDisputed, Reason: optionalText("wrong amount")}))
+	_, err = MarkDraftPaid(lifecycleCsp, id, "")
+	require.ErrorIs(t, err, ErrorDraftTransitionNotAllowed)
+
+	updated, err = VoidDraft(lifecycleOwner, id, "reissued with the right amount")
+	require.NoError(t, err)
+	require.Equal(t, model.DraftStatusVoid, updated.Status)
+
+	transitions, err := GetDraftTransitions(lifecycleOwner, id)
+	require.NoError(t, err)
+	require.Len(t, transitions, 4)
+	require.Equal(t, model.DraftStatusAcknowledged, transitions[2].FromStatus)
+	require.Equal(t, model.DraftStatusDisputed, transitions[2].ToStatus)
+	require.Equal(t, "wrong amount", *transitions[2].Reason)
+	require.Equal(t, []sentDraftStatus{
+		{"csp@example.com", model.DraftStatusIssued},
+		{"owner@example.com", model.DraftStatusAcknowledged},
+		{"owner@example.com", model.DraftStatusDisputed},
+		{"csp@example.com", model.DraftStatusVoid},
+	}, *sent)
+}
+
+func TestFilterDraftsByStatus(t *testing.T) {
+	drafts := []model.InvoiceDraft{{Status: ""}, {Status: model.DraftStatusIssued}, {Status: model.DraftStatusDraft}, {Status: model.DraftStatusDisputed}}
+
+	require.Len(t, FilterDraftsByStatus(drafts, ""), 4)
+	require.Len(t, FilterDraftsByStatus(drafts, model.DraftStatusDisputed), 1)
+	require.True(t, IsDraftStatus(model.DraftStatusDisputed))
+	require.Len(t, FilterDraftsByStatus(drafts, model.DraftStatusDraft), 2)
+	require.Len(t, FilterDraftsByStatus(drafts, model.DraftStatusIssued), 1)
+	require.True(t, IsDraftStatus(model.DraftStatusVoid))
+	require.False(t, IsDraftStatus("sent"))
+}
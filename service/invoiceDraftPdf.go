@@ -0,0 +1,161 @@
+package service
+
+import (
+	"strconv"
+
+	"github.com/NaeuralEdgeProtocol/ratio1-backend/model"
+	"github.com/NaeuralEdgeProtocol/ratio1-backend/pdf"
+)
+
+const (
+	draftPdfLeft   = 50.0
+	draftPdfRight  = pdf.PageWidth - 50
+	draftPdfTop    = 60.0
+	draftPdfBottom = pdf.PageHeight - 60
+)
+
+// draftPdfColumn is a column of the allocations table, amounts are aligned
+// to the right edge.
+type draftPdfColumn struct {
+	label string
+	x     float64
+	width float64
+	right bool
+	value func(allocationRow) string
+}
+
+var draftPdfColumns = []draftPdfColumn{
+	{label: "Date", x: draftPdfLeft, width: 52, value: func(r allocationRow) string { return r.AllocationCreation }},
+	{label: "Type", x: 104, width: 56, value: func(r allocationRow) string { return r.JobType }},
+	{label: "Job ID", x: 162, width: 40, value: func(r allocationRow) string { return r.JobID }},
+	{label: "Job", x: 204, width: 96, value: func(r allocationRow) string { return r.JobName }},
+	{label: "Project", x: 302, width: 86, value: func(r allocationRow) string { return r.ProjectName }},
+	{label: "Node", x: 390, width: 70, value: func(r allocationRow) string { return r.NodeAddress }},
+	{label: "USDC Paid", x: draftPdfRight, width: 80, right: true, value: func(r allocationRow) string { return r.UsdcPaid }},
+}
+
+// RenderInvoiceDraftPdf renders the draft as the html template lays it out,
+// the allocations table continues on the following pages when needed.
+func RenderInvoiceDraftPdf(invoice model.InvoiceDraft, allocations []model.Allocation) ([]byte, error) {
+	vm := buildInvoiceView(invoice, allocations)
+	return renderInvoiceDraftPdf(vm), nil
+}
+
+func renderInvoiceDraftPdf(vm invoiceVM) []byte {
+	number := strconv.Itoa(vm.InvoiceNumber)
+	if vm.InvoiceSeries != "" {
+		number += " " + vm.InvoiceSeries
+	}
+
+	doc := pdf.New(vm.Title + " " + number)
+	doc.Text(draftPdfLeft, 70, pdf.HelveticaBold, 20, vm.Title)
+	doc.TextRight(draftPdfRight, 62, pdf.Helvetica, 10, "No. "+number)
+	doc.TextRight(draftPdfRight, 76, pdf.Helvetica, 10, "Date "+vm.Date)
+	doc.Line(draftPdfLeft, 90, draftPdfRight, 90)
+
+	sellerEnd := draftPartyLines(doc, draftPdfLeft, "Supplier", vm.SellerLines, vm.SellerWallet)
+	buyerEnd := draftPartyLines(doc, 300, "Customer", vm.BuyerLines, vm.BuyerWallet)
+	y := max(sellerEnd, buyerEnd) + 30
+
+	doc.Text(draftPdfLeft, y, pdf.HelveticaBold, 12, "Summary")
+	y += 6
+	doc.Line(draftPdfLeft, y, draftPdfRight, y)
+	summary := []extraLineVM{
+		{Label: "Net Amount", AmountUSDC: vm.NetBase, AmountLocal: vm.NetBaseLocal},
+		{Label: "VAT (" + vm.VatPerc + "%)", AmountUSDC: vm.VatAmount, AmountLocal: vm.VatAmountLocal},
+	}
+	summary = append(summary, vm.ExtraLines...)
+	summary = append(summary, extraLineVM{Label: "Total Amount (allocations subtotal)", AmountUSDC: vm.TotalUSDC, AmountLocal: vm.TotalLocal})
+	for i, line := range summary {
+		font := pdf.Helvetica
+		if i == len(summary)-1 {
+			font = pdf.HelveticaBold
+		}
+		y += 16
+		doc.Text(draftPdfLeft, y, font, 10, pdf.Truncate(line.Label, font, 10, 260))
+		doc.TextRight(420, y, font, 10, line.AmountUSDC+" USDC")
+		if vm.LocalCurrency != "" && line.AmountLocal != "" {
+			doc.TextRight(draftPdfRight, y, font, 10, line.AmountLocal+" "+vm.LocalCurrency)
+		}
+	}
+	y += 22
+	doc.Text(draftPdfLeft, y, pdf.Helvetica, 10, "Number of allocations: "+strconv.Itoa(vm.JobCount))
+	y += 14
+	doc.Text(draftPdfLeft, y, pdf.Helvetica, 10, vm.Status)
+	if vm.VatMention != "" {
+		for _, line := range pdf.Wrap(vm.VatMention, pdf.Helvetica, 10, draftPdfRight-draftPdfLeft) {
+			y += 14
+			doc.Text(draftPdfLeft, y, pdf.Helvetica, 10, line)
+		}
+	}
+
+	if vm.Notes != "" {
+		y += 28
+		doc.Text(draftPdfLeft, y, pdf.HelveticaBold, 12, "Notes")
+		for _, line := range pdf.Wrap(vm.Notes, pdf.Helvetica, 9, draftPdfRight-draftPdfLeft) {
+			y += 12
+			if y > draftPdfBottom {
+				doc.AddPage()
+				y = draftPdfTop
+			}
+			doc.Text(draftPdfLeft, y, pdf.Helvetica, 9, line)
+		}
+	}
+
+	y += 32
+	if y+40 > draftPdfBottom {
+		doc.AddPage()
+		y = draftPdfTop
+	}
+	doc.Text(draftPdfLeft, y, pdf.HelveticaBold, 12, "Allocations Details")
+	y = draftAllocationsHeader(doc, y+20)
+	for _, row := range vm.Allocations {
+		y += 14
+		if y > draftPdfBottom {
+			doc.AddPage()
+			y = draftAllocationsHeader(doc, draftPdfTop) + 14
+		}
+		for _, column := range draftPdfColumns {
+			text := pdf.Truncate(column.value(row), pdf.Helvetica, 8, column.width)
+			if column.right {
+				doc.TextRight(column.x, y, pdf.Helvetica, 8, text)
+			} else {
+				doc.Text(column.x, y, pdf.Helvetica, 8, text)
+			}
+		}
+	}
+
+	return doc.Bytes()
+}
+
+// draftPartyLines writes a supplier or customer block and returns the
+// baseline of its last line.
+func draftPartyLines(doc *pdf.Document, x float64, label string, lines any, wallet string) float64 {
+	y := 112.0
+	doc.Text(x, y, pdf.HelveticaBold, 10, label)
+	texts, _ := lines.([]string)
+	if wallet != "" {
+		texts = append(texts, wallet)
+	}
+	for _, line := range texts {
+		for _, wrapped := range pdf.Wrap(line, pdf.Helvetica, 9, 240) {
+			y += 13
+			doc.Text(x, y, pdf.Helvetica, 9, wrapped)
+		}
+	}
+	return y
+}
+
+// draftAllocationsHeader writes the header of the allocations table and
+// returns the baseline of its underline.
+func draftAllocationsHeader(doc *pdf.Document, y float64) float64 {
+	for _, column := range draftPdfColumns {
+		if column.right {
+			doc.TextRight(column.x, y, pdf.HelveticaBold, 8, column.label)
+		} else {
+			doc.Text(column.x, y, pdf.HelveticaBold, 8, column.label)
+		}
+	}
+	doc.Line(draftPdfLeft, y+5, draftPdfRight, y+5)
+	return y + 5
+}
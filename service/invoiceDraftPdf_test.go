@@ -0,0 +1,82 @@
+package service
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/NaeuralEdgeProtocol/ratio1-backend/model"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRenderInvoiceDraftPdf(t *testing.T) {
+	company, name, surname := "Acme Nodes SRL", "Mario", "Rossi"
+	notes := "On-chain services as per payment details."
+	extraTaxes := `[{ "Description": "Network fee", "TaxType": 0, "Value": 10.0 }]`
+	draft := model.InvoiceDraft{
+		DraftId:                    uuid.New(),
+		CreationTimestamp:          time.Date(2026, 7, 1, 0, 0, 0, 0, time.UTC),
+		UserAddress:                "0x00000000000000000000000000000000000000a1",
+		CspOwner:                   "0x00000000000000000000000000000000000000c1",
+		UserProfile:                model.UserInfo{BlockchainAddress: "0x00000000000000000000000000000000000000a1", CompanyName: &company, IsCompany: true, Country: "Romania"},
+		CspProfile:                 model.UserInfo{BlockchainAddress: "0x00000000000000000000000000000000000000c1", Name: &name, Surname: &surname, Country: "Italy"},
+		InvoiceSeries:              "NODE",
+		InvoiceNumber:              42,
+		TotalUsdcAmount:            1220,
+		VatApplied:                 21,
+		LocalCurrency:              "RON",
+		LocalCurrencyExchangeRatio: 4.5,
+		ExtraText:                  &notes,
+		ExtraTaxes:                 &extraTaxes,
+	}
+
+	var allocations []model.Allocation
+	for i := 0; i < 80; i++ {
+		allocations = append(allocations, model.Allocation{
+			AllocationCreation: draft.CreationTimestamp.Add(time.Duration(i) * time.Hour),
+			JobId:              fmt.Sprintf("%d", i+1),
+			JobName:            "A job name long enough to be cut in its column",
+			JobType:            1,
+			ProjectName:        "Project Alpha",
+			NodeAddress:        "0xai_000000000000000000000000000000000000001",
+			UsdcAmountPayed:    "15250000",
+		})
+	}
+
+	out, err := RenderInvoiceDraftPdf(draft, allocations)
+	require.NoError(t, err)
+	require.True(t, bytes.HasPrefix(out, []byte("%PDF-1.4\n")))
+	require.Contains(t, string(out), "/Count 3")
+	require.Contains(t, string(out), "(Invoice Draft) Tj")
+	require.Contains(t, string(out), "(No. 42 NODE) Tj")
+	require.Contains(t, string(out), "(Acme Nodes SRL) Tj")
+	require.Contains(t, string(out), "(Network fee) Tj")
+	require.Contains(t, string(out), "(1220.00 USDC) Tj")
+	require.Contains(t, string(out), "(5490.00 RON) Tj")
+	require.Contains(t, string(out), "(15.250000) Tj")
+	require.Equal(t, 3, bytes.Count(out, []byte("(USDC Paid) Tj")))
+}
+
+func TestRenderInvoiceDraftPdfWritesRomanianLetters(t *testing.T) {
+	company, name, surname := "Țesătoria Bistrița SRL", "Ana", "Ștefănescu"
+	draft := model.InvoiceDraft{
+		DraftId:           uuid.New(),
+		CreationTimestamp: time.Date(2026, 7, 1, 0, 0, 0, 0, time.UTC),
+		UserAddress:       "0x00000000000000000000000000000000000000a1",
+		CspOwner:          "0x00000000000000000000000000000000000000c1",
+		UserProfile:       model.UserInfo{BlockchainAddress: "0x00000000000000000000000000000000000000a1", CompanyName: &company, IsCompany: true, Address: "Strada Mărășești 3", Country: "Romania"},
+		CspProfile:        model.UserInfo{BlockchainAddress: "0x00000000000000000000000000000000000000c1", Name: &name, Surname: &surname, Country: "Romania"},
+		InvoiceSeries:     "NODE",
+		InvoiceNumber:     43,
+		TotalUsdcAmount:   100,
+	}
+
+	out, err := RenderInvoiceDraftPdf(draft, nil)
+	require.NoError(t, err)
+	require.Contains(t, string(out), `(Ana ) Tj /F3 9.00 Tf (\051) Tj /F1 9.00 Tf (tef) Tj /F3 9.00 Tf (\002) Tj /F1 9.00 Tf (nescu) Tj`)
+	require.Contains(t, string(out), `(\053) Tj`)
+	require.NotContains(t, string(out), "?tef?nescu")
+	require.NotContains(t, string(out), "M?r")
+}
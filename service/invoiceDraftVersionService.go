@@ -0,0 +1,171 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"strconv"
+	"time"
+
+	"github.com/NaeuralEdgeProtocol/ratio1-backend/model"
+	"github.com/NaeuralEdgeProtocol/ratio1-backend/storage"
+	"github.com/google/uuid"
+)
+
+var (
+	ErrorDraftNotRegenerable  = errors.New("only drafts not finalized yet can be regenerated")
+	ErrorDraftCurrencyChanged = errors.New("the local currency changed while generating the draft, try again")
+)
+
+// RegenerateDraft builds a new version of a draft from the current preference
+// and profiles of its parties. The previous version is kept read only and the
+// allocations move to the new one in the same transaction. The invoice number
+// is kept unless newNumber asks for the next one of the preference.
+func RegenerateDraft(address, draftId string, newNumber bool) (*model.InvoiceDraft, error) {
+	previous, isOwner, err := getPartyDraft(address, draftId)
+	if err != nil {
+		return nil, err
+	}
+	if !isOwner {
+		return nil, ErrorDraftActorNotAllowed
+	}
+	if previous.CurrentStatus() != model.DraftStatusDraft || previous.SupersededBy != nil {
+		return nil, ErrorDraftNotRegenerable
+	}
+
+	allocations, err := repos.Allocations.GetByDraftId(previous.DraftId.String())
+	if err != nil {
+		return nil, errors.New("error while retrieving allocations: " + err.Error())
+	}
+	allocationIds := make([]uint, 0, len(allocations))
+	totalUsdcAmount := big.NewInt(0)
+	for _, alloc := range allocations {
+		allocationIds = append(allocationIds, alloc.Id)
+		totalUsdcAmount.Add(totalUsdcAmount, alloc.GetUsdcAmountPayed())
+	}
+
+	// the exchange rate of the original draft is kept, the allocations are
+	// only converted again when the preference moved to another currency
+	currency, ratio, err := preferenceExchangeRatio(previous.UserAddress, previous.LocalCurrency, allocations, previous.CreationTimestamp, func(currency string, date time.Time) (float64, error) {
+		if currency == previous.LocalCurrency {
+			return previous.LocalCurrencyExchangeRatio, nil
+		}
+		return GetFxRate(currency, date)
+	})
+	if err != nil {
+		return nil, err
+	} else if currency == previous.LocalCurrency {
+		ratio = previous.LocalCurrencyExchangeRatio
+	}
+	vatEngine, err := LoadVatEngine()
+	if err != nil {
+		return nil, err
+	}
+	buyerViesValid, err := draftBuyerViesValid(previous.CspOwner)
+	if err != nil {
+		return nil, err
+	}
+
+	var invoice model.InvoiceDraft
+	err = repos.Drafts.Generate(func(tx storage.DraftGenerationTx) error {
+		invoice = model.InvoiceDraft{
+			DraftId:                    uuid.New(),
+			UserAddress:                previous.UserAddress,
+			CspOwner:                   previous.CspOwner,
+			CreationTimestamp:          previous.CreationTimestamp,
+			UserProfile:                previous.UserProfile,
+			CspProfile:                 previous.CspProfile,
+			TotalUsdcAmount:            GetAmountAsFloat(totalUsdcAmount, model.UsdcDecimals),
+			VatApplied:                 previous.VatApplied,
+			VatCategory:                previous.VatCategory,
+			VatMention:                 previous.VatMention,
+			InvoiceSeries:              previous.InvoiceSeries,
+			InvoiceNumber:              previous.InvoiceNumber,
+			ExtraText:                  previous.ExtraText,
+			ExtraTaxes:                 previous.ExtraTaxes,
+			LocalCurrency:              previous.LocalCurrency,
+			LocalCurrencyExchangeRatio: previous.LocalCurrencyExchangeRatio,
+			Status:                     model.DraftStatusDraft,
+			Version:                    previous.CurrentVersion() + 1,
+			PreviousVersionId:          &previous.DraftId,
+		}
+		invoice.SnapshotIdentities()
+
+		preference, err := tx.GetPreferenceForUpdate(previous.UserAddress)
+		if err != nil {
+			return errors.New("error while retrieving user preference: " + err.Error())
+		} else if preference != nil {
+			applyDraftPreference(&invoice, preference)
+			err = applyDraftVat(&invoice, preference, vatEngine, buyerViesValid)
+			if err != nil {
+				return err
+			}
+		}
+
+		if invoice.LocalCurrency != currency {
+			return ErrorDraftCurrencyChanged
+		}
+		invoice.LocalCurrencyExchangeRatio = ratio
+
+		if newNumber && invoice.UserAddress != invoice.CspOwner {
+			if preference == nil {
+				preference = defaultPreference(invoice.UserAddress)
+			}
+			invoice.InvoiceNumber, err = takeSeriesNumber(tx, preference, invoice.CreationTimestamp)
+			if err != nil {
+				return err
+			}
+			invoice.InvoiceSeries = preference.InvoiceSeries
+			err = tx.SavePreference(preference)
+			if err != nil {
+				return errors.New("error while updating preference: " + err.Error())
+			}
+		}
+
+		err = tx.CreateDraft(&invoice)
+		if err != nil {
+			return errors.New("error while saving invoice: " + err.Error())
+		}
+
+		reason := "regenerated as version " + strconv.Itoa(invoice.Version)
+		err = tx.SupersedeDraft(previous.DraftId, invoice.DraftId, &model.InvoiceDraftTransition{
+			FromStatus: model.DraftStatusDraft,
+			ToStatus:   model.DraftStatusSuperseded,
+			Actor:      address,
+			Reason:     &reason,
+		})
+		if errors.Is(err, storage.ErrDraftStatusChanged) {
+			return fmt.Errorf("%w: %s", ErrorDraftNotRegenerable, err.Error())
+		} else if err != nil {
+			return errors.New("error while superseding draft: " + err.Error())
+		}
+
+		err = tx.MoveAllocations(previous.DraftId, invoice.DraftId, allocationIds)
+		if err != nil {
+			return errors.New("error while moving allocations: " + err.Error())
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &invoice, nil
+}
+
+// GetDraftAllocations returns the allocations of any version of a draft, they
+// always point to the latest version.
+func GetDraftAllocations(draft model.InvoiceDraft) ([]model.Allocation, error) {
+	latest := draft
+	for latest.SupersededBy != nil {
+		next, err := repos.Drafts.GetByReportId(latest.SupersededBy.String(), latest.UserAddress)
+		if err != nil {
+			return nil, err
+		}
+		if next.DraftId == uuid.Nil {
+			return nil, errors.New("draft version " + latest.SupersededBy.String() + " not found")
+		}
+		latest = *next
+	}
+	return repos.Allocations.GetByDraftId(latest.DraftId.String())
+}
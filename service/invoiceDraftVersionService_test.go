@@ -0,0 +1,136 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/NaeuralEdgeProtocol/ratio1-backend/model"
+	"github.com/stretchr/testify/require"
+)
+
+func withRegenerableDraft(t *testing.T) *model.InvoiceDraft {
+	draft, _ := withDraftLifecycle(t)
+
+	owner, err := repos.UserInfos.GetByAddress(lifecycleOwner)
+	require.NoError(t, err)
+	owner.Country = "ROU"
+	require.NoError(t, repos.UserInfos.Update(owner))
+	csp, err := repos.UserInfos.GetByAddress(lifecycleCsp)
+	require.NoError(t, err)
+	csp.Country = "ROU"
+	require.NoError(t, repos.UserInfos.Update(csp))
+
+	draft.VatApplied = 19
+	draft.LocalCurrency = "RON"
+	draft.LocalCurrencyExchangeRatio = 4.5
+	draft.TotalUsdcAmount = 30
+	require.NoError(t, repos.Drafts.Update(draft))
+	require.NoError(t, repos.Preferences.Create(&model.Preference{
+		UserAddress:   lifecycleOwner,
+		InvoiceSeries: "R1",
+		NextNumber:    8,
+		CountryVat:    21,
+		LocalCurrency: "RON",
+	}))
+	for _, amount := range []string{"10000000", "20000000"} {
+		require.NoError(t, repos.Allocations.Create(&model.Allocation{
+			UserAddress:     lifecycleOwner,
+			CspOwner:        lifecycleCsp,
+			UsdcAmountPayed: amount,
+			DraftId:         &draft.DraftId,
+		}))
+	}
+	return draft
+}
+
+func TestRegenerateDraftKeepsNumberAndPreviousVersion(t *testing.T) {
+	draft := withRegenerableDraft(t)
+
+	regenerated, err := RegenerateDraft(lifecycleOwner, draft.DraftId.String(), false)
+	require.NoError(t, err)
+	require.NotEqual(t, draft.DraftId, regenerated.DraftId)
+	require.Equal(t, 2, regenerated.Version)
+	require.Equal(t, draft.DraftId, *regenerated.PreviousVersionId)
+	require.Equal(t, float64(21), regenerated.VatApplied)
+	require.Equal(t, 7, regenerated.InvoiceNumber)
+	require.Equal(t, 4.5, regenerated.LocalCurrencyExchangeRatio)
+	require.Equal(t, float64(30), regenerated.TotalUsdcAmount)
+	require.Equal(t, model.DraftStatusDraft, regenerated.Status)
+
+	previous, err := repos.Drafts.GetByReportId(draft.DraftId.String(), lifecycleOwner)
+	require.NoError(t, err)
+	require.Equal(t, model.DraftStatusSuperseded, previous.Status)
+	require.Equal(t, regenerated.DraftId, *previous.SupersededBy)
+	require.Equal(t, float64(19), previous.VatApplied)
+
+	allocations, err := repos.Allocations.GetByDraftId(regenerated.DraftId.String())
+	require.NoError(t, err)
+	require.Len(t, allocations, 2)
+	previousAllocations, err := GetDraftAllocations(*previous)
+	require.NoError(t, err)
+	require.Equal(t, allocations, previousAllocations)
+
+	_, err = RegenerateDraft(lifecycleOwner, draft.DraftId.String(), false)
+	require.ErrorIs(t, err, ErrorDraftNotRegenerable)
+	_, err = FinalizeDraft(lifecycleOwner, draft.DraftId.String())
+	require.ErrorIs(t, err, ErrorDraftTransitionNotAllowed)
+
+	transitions, err := GetDraftTransitions(lifecycleOwner, draft.DraftId.String())
+	require.NoError(t, err)
+	require.Len(t, transitions, 1)
+	require.Equal(t, model.DraftStatusSuperseded, transitions[0].ToStatus)
+
+	pref, err := repos.Preferences.GetByAddress(lifecycleOwner)
+	require.NoError(t, err)
+	require.Equal(t, 8, pref.NextNumber)
+}
+
+func TestRegenerateDraftWithNewNumber(t *testing.T) {
+	draft := withRegenerableDraft(t)
+
+	regenerated, err := RegenerateDraft(lifecycleOwner, draft.DraftId.String(), true)
+	require.NoError(t, err)
+	require.Equal(t, 8, regenerated.InvoiceNumber)
+	require.Equal(t, "R1", regenerated.InvoiceSeries)
+
+	pref, err := repos.Preferences.GetByAddress(lifecycleOwner)
+	require.NoError(t, err)
+	require.Equal(t, 9, pref.NextNumber)
+}
+
+func TestRegenerateDraftRejectsCspAndFinalizedDrafts(t *testing.T) {
+	draft := withRegenerableDraft(t)
+
+	_, err := RegenerateDraft(lifecycleCsp, draft.DraftId.String(), false)
+	require.ErrorIs(t, err, ErrorDraftActorNotAllowed)
+
+	_, err = FinalizeDraft(lifecycleOwner, draft.DraftId.String())
+	require.NoError(t, err)
+	_, err = RegenerateDraft(lifecycleOwner, draft.DraftId.String(), false)
+	require.ErrorIs(t, err, ErrorDraftNotRegenerable)
+
+	allocations, err := repos.Allocations.GetByDraftId(draft.DraftId.String())
+	require.NoError(t, err)
+	require.Len(t, allocations, 2)
+}
+
+func TestRegenerateDraftConvertsAgainWhenCurrencyChanged(t *testing.T) {
+	draft := withRegenerableDraft(t)
+	calls := 0
+	previousProviders := fxProviders
+	fxProviders = []FxProvider{stubFxProvider{name: "stub", calls: &calls, days: map[string]map[string]float64{
+		draft.CreationTimestamp.UTC().Format(time.DateOnly): {"EUR": 0.9, "RON": 4.5},
+	}}}
+	defer func() { fxProviders = previousProviders }()
+
+	pref, err := repos.Preferences.GetByAddress(lifecycleOwner)
+	require.NoError(t, err)
+	pref.LocalCurrency = "EUR"
+	require.NoError(t, repos.Preferences.Update(pref))
+
+	regenerated, err := RegenerateDraft(lifecycleOwner, draft.DraftId.String(), false)
+	require.NoError(t, err)
+	require.Equal(t, "EUR", regenerated.LocalCurrency)
+	require.Equal(t, 0.9, regenerated.LocalCurrencyExchangeRatio)
+	require.Equal(t, 1, calls)
+}
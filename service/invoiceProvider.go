@@ -0,0 +1,56 @@
+package service
+
+import (
+	"sync"
+
+	"github.com/NaeuralEdgeProtocol/ratio1-backend/config"
+	"github.com/NaeuralEdgeProtocol/ratio1-backend/model"
+)
+
+// IssuedInvoice identifies an invoice issued by an InvoiceProvider.
+type IssuedInvoice struct {
+	SeriesName string
+	Number     string
+	Link       string
+}
+
+// InvoiceProvider issues the license purchase invoices.
+type InvoiceProvider interface {
+	// Authenticate is called once per batch before the first Issue
+	Authenticate() error
+	Issue(request model.InvoiceRequest) (*IssuedInvoice, error)
+	Cancel(seriesName, number string) error
+	PdfLink(seriesName, number string) (string, error)
+}
+
+var (
+	invoiceProviderMu sync.Mutex
+	invoiceProvider   InvoiceProvider
+)
+
+// GetInvoiceProvider returns the provider of the configuration, built on first
+// use.
+func GetInvoiceProvider() InvoiceProvider {
+	invoiceProviderMu.Lock()
+	defer invoiceProviderMu.Unlock()
+
+	if invoiceProvider == nil {
+		switch config.Config.Invoicing.Provider {
+		case config.InvoiceProviderLocal:
+			invoiceProvider = NewLocalInvoiceProvider(config.Config.Invoicing.LocalDir)
+		default:
+			invoiceProvider = &oblioInvoiceProvider{}
+		}
+	}
+	return invoiceProvider
+}
+
+// SetInvoiceProvider replaces the provider and returns the previous one.
+func SetInvoiceProvider(provider InvoiceProvider) InvoiceProvider {
+	invoiceProviderMu.Lock()
+	defer invoiceProviderMu.Unlock()
+
+	previous := invoiceProvider
+	invoiceProvider = provider
+	return previous
+}
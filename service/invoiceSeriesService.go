@@ -0,0 +1,377 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/NaeuralEdgeProtocol/ratio1-backend/model"
+	"github.com/NaeuralEdgeProtocol/ratio1-backend/storage"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrorSeriesNotFound       = errors.New("invoice series not found")
+	ErrorSeriesExists         = errors.New("invoice series already exists")
+	ErrorSeriesInvalid        = errors.New("invalid invoice series")
+	ErrorSeriesMovedBackwards = errors.New("invoice series cannot move backwards")
+)
+
+const maxSeriesNameLength = 32
+
+type InvoiceNumberRange struct {
+	From int `json:"from"`
+	To   int `json:"to"`
+}
+
+type InvoiceNumberDuplicate struct {
+	Number   int         `json:"number"`
+	DraftIds []uuid.UUID `json:"draftIds"`
+}
+
+// InvoiceSeriesPeriod checks the numbers of a series used in a year, or in
+// the whole life of the series when it is not reset yearly.
+type InvoiceSeriesPeriod struct {
+	Year       int                      `json:"year,omitempty"`
+	Count      int                      `json:"count"`
+	First      int                      `json:"first"`
+	Last       int                      `json:"last"`
+	Gaps       []InvoiceNumberRange     `json:"gaps"`
+	Duplicates []InvoiceNumberDuplicate `json:"duplicates"`
+}
+
+type InvoiceSeriesReport struct {
+	Series      string                `json:"series"`
+	NextNumber  int                   `json:"nextNumber"`
+	YearlyReset bool                  `json:"yearlyReset"`
+	Periods     []InvoiceSeriesPeriod `json:"periods"`
+}
+
+// GetInvoiceSeries lists the series of a node owner. The series of a
+// preference saved before the series existed is listed until its first use.
+func GetInvoiceSeries(address string) ([]model.InvoiceSeries, error) {
+	series, err := repos.InvoiceSeries.GetByUser(address)
+	if err != nil {
+		return nil, errors.New("error while retrieving invoice series: " + err.Error())
+	}
+	if series == nil {
+		series = []model.InvoiceSeries{}
+	}
+
+	pref, err := repos.Preferences.GetByAddress(address)
+	if err != nil {
+		return nil, errors.New("error while retrieving user preference: " + err.Error())
+	}
+	if pref != nil && !hasSeries(series, pref.InvoiceSeries) {
+		series = append(series, preferenceSeries(pref))
+		sort.Slice(series, func(i, j int) bool { return series[i].Name < series[j].Name })
+	}
+	return series, nil
+}
+
+func CreateInvoiceSeries(address string, series model.InvoiceSeries) (*model.InvoiceSeries, error) {
+	series.Name = strings.TrimSpace(series.Name)
+	if series.Name == "" || len(series.Name) > maxSeriesNameLength || series.NextNumber < 0 {
+		return nil, ErrorSeriesInvalid
+	}
+	if series.NextNumber == 0 {
+		series.NextNumber = 1
+	}
+
+	existing, err := GetInvoiceSeries(address)
+	if err != nil {
+		return nil, err
+	}
+	if hasSeries(existing, series.Name) {
+		return nil, ErrorSeriesExists
+	}
+
+	series.Id = 0
+	series.UserAddress = address
+	series.Year = 0
+	err = repos.InvoiceSeries.Create(&series)
+	if err != nil {
+		return nil, errors.New("error while creating invoice series: " + err.Error())
+	}
+	return &series, nil
+}
+
+// ChangeInvoiceSeries moves the next number of a series forward and sets its
+// yearly reset, the preference follows when the series is its default one.
+func ChangeInvoiceSeries(address, name string, nextNumber int, yearlyReset bool) (*model.InvoiceSeries, error) {
+	err := ensureInvoiceSeries(address, name)
+	if err != nil {
+		return nil, err
+	}
+
+	var changed model.InvoiceSeries
+	err = repos.InvoiceSeries.Change(address, name, func(series *model.InvoiceSeries) error {
+		err := moveInvoiceSeries(series, nextNumber, time.Now())
+		if err != nil {
+			return err
+		}
+		series.YearlyReset = yearlyReset
+		changed = *series
+		return nil
+	})
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrorSeriesNotFound
+	} else if errors.Is(err, ErrorSeriesInvalid) || errors.Is(err, ErrorSeriesMovedBackwards) {
+		return nil, err
+	} else if err != nil {
+		return nil, errors.New("error while updating invoice series: " + err.Error())
+	}
+
+	pref, err := repos.Preferences.GetByAddress(address)
+	if err != nil {
+		return nil, errors.New("error while retrieving user preference: " + err.Error())
+	}
+	if pref != nil && pref.InvoiceSeries == name && pref.NextNumber != changed.NextNumber {
+		pref.NextNumber = changed.NextNumber
+		err = repos.Preferences.Update(pref)
+		if err != nil {
+			return nil, errors.New("error while updating preference: " + err.Error())
+		}
+	}
+	return &changed, nil
+}
+
+// CreatePreference stores the first preference of a node owner and the
+// series it points to, an existing series keeps its own next number.
+func CreatePreference(pref *model.Preference) error {
+	err := syncPreferenceSeries(pref, true)
+	if err != nil {
+		return err
+	}
+	return repos.Preferences.Create(pref)
+}
+
+// ChangePreference updates the preference of a node owner. The next number is
+// applied to the default series and cannot move it backwards; when the
+// preference switches to another existing series, that series keeps its own
+// next number.
+func ChangePreference(pref *model.Preference) error {
+	current, err := repos.Preferences.GetByAddress(pref.UserAddress)
+	if err != nil {
+		return errors.New("error while retrieving user preference: " + err.Error())
+	}
+	err = ensureInvoiceSeries(pref.UserAddress, pref.InvoiceSeries)
+	if err != nil {
+		return err
+	}
+
+	switched := current == nil || current.InvoiceSeries != pref.InvoiceSeries
+	err = syncPreferenceSeries(pref, switched)
+	if err != nil {
+		return err
+	}
+	return repos.Preferences.Update(pref)
+}
+
+// GetInvoiceSeriesReport looks for gaps and duplicates among the numbers the
+// drafts of a series use. Superseded draft versions share the number of the
+// version that replaced them and are not counted.
+func GetInvoiceSeriesReport(address, name string) (*InvoiceSeriesReport, error) {
+	all, err := GetInvoiceSeries(address)
+	if err != nil {
+		return nil, err
+	}
+	index := seriesIndex(all, name)
+	if index < 0 {
+		return nil, ErrorSeriesNotFound
+	}
+	series := all[index]
+
+	drafts, err := repos.Drafts.GetListByNodeOwner(address)
+	if err != nil {
+		return nil, errors.New("error while retrieving drafts: " + err.Error())
+	}
+
+	numbers := make(map[int]map[int][]uuid.UUID)
+	for _, draft := range drafts {
+		if draft.InvoiceSeries != name || draft.InvoiceNumber <= 0 || draft.UserAddress == draft.CspOwner || draft.CurrentStatus() == model.DraftStatusSuperseded {
+			continue
+		}
+		year := 0
+		if series.YearlyReset {
+			year = draft.CreationTimestamp.Year()
+		}
+		if numbers[year] == nil {
+			numbers[year] = make(map[int][]uuid.UUID)
+		}
+		numbers[year][draft.InvoiceNumber] = append(numbers[year][draft.InvoiceNumber], draft.DraftId)
+	}
+
+	report := &InvoiceSeriesReport{
+		Series:      series.Name,
+		NextNumber:  series.NextNumber,
+		YearlyReset: series.YearlyReset,
+		Periods:     []InvoiceSeriesPeriod{},
+	}
+	for year, used := range numbers {
+		period := InvoiceSeriesPeriod{Year: year, Gaps: []InvoiceNumberRange{}, Duplicates: []InvoiceNumberDuplicate{}}
+		sorted := make([]int, 0, len(used))
+		for number, ids := range used {
+			sorted = append(sorted, number)
+			period.Count += len(ids)
+			if len(ids) > 1 {
+				period.Duplicates = append(period.Duplicates, InvoiceNumberDuplicate{Number: number, DraftIds: ids})
+			}
+		}
+		sort.Ints(sorted)
+		sort.Slice(period.Duplicates, func(i, j int) bool { return period.Duplicates[i].Number < period.Duplicates[j].Number })
+		period.First, period.Last = sorted[0], sorted[len(sorted)-1]
+
+		// a yearly sequence starts from 1, numbers handed out after the last
+		// draft of the current period have no draft either
+		previous := period.First - 1
+		if series.YearlyReset {
+			previous = 0
+		}
+		for _, number := range sorted {
+			if number > previous+1 {
+				period.Gaps = append(period.Gaps, InvoiceNumberRange{From: previous + 1, To: number - 1})
+			}
+			previous = number
+		}
+		if (!series.YearlyReset || year == series.Year) && series.NextNumber > period.Last+1 {
+			period.Gaps = append(period.Gaps, InvoiceNumberRange{From: period.Last + 1, To: series.NextNumber - 1})
+		}
+		report.Periods = append(report.Periods, period)
+	}
+	sort.Slice(report.Periods, func(i, j int) bool { return report.Periods[i].Year < report.Periods[j].Year })
+	return report, nil
+}
+
+// takeSeriesNumber hands out the next number of the default series of the
+// preference inside the draft transaction, the preference mirrors the series
+// and has to be saved by the caller.
+func takeSeriesNumber(tx storage.DraftGenerationTx, pref *model.Preference, at time.Time) (int, error) {
+	series, err := tx.GetSeriesForUpdate(pref.UserAddress, pref.InvoiceSeries)
+	if err != nil {
+		return 0, errors.New("error while retrieving invoice series: " + err.Error())
+	}
+	if series == nil {
+		legacy := preferenceSeries(pref)
+		series = &legacy
+	}
+
+	number := series.Take(at)
+	err = tx.SaveSeries(series)
+	if err != nil {
+		return 0, errors.New("error while updating invoice series: " + err.Error())
+	}
+	pref.NextNumber = series.NextNumber
+	return number, nil
+}
+
+// ensureInvoiceSeries stores the series of a preference saved before the
+// series existed, so it can be locked and changed.
+func ensureInvoiceSeries(address, name string) error {
+	series, err := repos.InvoiceSeries.Get(address, name)
+	if err != nil {
+		return errors.New("error while retrieving invoice series: " + err.Error())
+	}
+	if series != nil {
+		return nil
+	}
+
+	pref, err := repos.Preferences.GetByAddress(address)
+	if err != nil {
+		return errors.New("error while retrieving user preference: " + err.Error())
+	}
+	if pref == nil || pref.InvoiceSeries != name {
+		return nil
+	}
+	legacy := preferenceSeries(pref)
+	err = repos.InvoiceSeries.Create(&legacy)
+	if err != nil {
+		return errors.New("error while creating invoice series: " + err.Error())
+	}
+	return nil
+}
+
+// syncPreferenceSeries creates the default series of the preference when it
+// is missing, otherwise it moves it to the preference next number, or copies
+// the series next number into the preference when keepSeries is set.
+func syncPreferenceSeries(pref *model.Preference, keepSeries bool) error {
+	if pref.NextNumber < 0 || len(pref.InvoiceSeries) > maxSeriesNameLength {
+		return ErrorSeriesInvalid
+	}
+	if pref.NextNumber == 0 {
+		pref.NextNumber = 1
+	}
+
+	series, err := repos.InvoiceSeries.Get(pref.UserAddress, pref.InvoiceSeries)
+	if err != nil {
+		return errors.New("error while retrieving invoice series: " + err.Error())
+	}
+	if series == nil {
+		created := preferenceSeries(pref)
+		err = repos.InvoiceSeries.Create(&created)
+		if err != nil {
+			return errors.New("error while creating invoice series: " + err.Error())
+		}
+		return nil
+	}
+	if keepSeries {
+		pref.NextNumber = series.NextNumber
+		return nil
+	}
+
+	err = repos.InvoiceSeries.Change(pref.UserAddress, pref.InvoiceSeries, func(series *model.InvoiceSeries) error {
+		err := moveInvoiceSeries(series, pref.NextNumber, time.Now())
+		if err != nil {
+			return err
+		}
+		pref.NextNumber = series.NextNumber
+		return nil
+	})
+	if errors.Is(err, ErrorSeriesInvalid) || errors.Is(err, ErrorSeriesMovedBackwards) {
+		return err
+	} else if err != nil {
+		return errors.New("error while updating invoice series: " + err.Error())
+	}
+	return nil
+}
+
+// moveInvoiceSeries sets the next number of the series, a yearly series that
+// did not hand out a number this year yet can start from any number.
+func moveInvoiceSeries(series *model.InvoiceSeries, nextNumber int, now time.Time) error {
+	if nextNumber < 1 {
+		return ErrorSeriesInvalid
+	}
+	freshYear := series.YearlyReset && series.Year != 0 && series.Year != now.Year()
+	if !freshYear && nextNumber < series.NextNumber {
+		return fmt.Errorf("%w: the next number of %s is %d", ErrorSeriesMovedBackwards, series.Name, series.NextNumber)
+	}
+	if freshYear {
+		series.Year = now.Year()
+	}
+	series.NextNumber = nextNumber
+	return nil
+}
+
+func preferenceSeries(pref *model.Preference) model.InvoiceSeries {
+	nextNumber := pref.NextNumber
+	if nextNumber < 1 {
+		nextNumber = 1
+	}
+	return model.InvoiceSeries{
+		UserAddress: pref.UserAddress,
+		Name:        pref.InvoiceSeries,
+		NextNumber:  nextNumber,
+	}
+}
+
+func hasSeries(series []model.InvoiceSeries, name string) bool {
+	return seriesIndex(series, name) >= 0
+}
+
+func seriesIndex(series []model.InvoiceSeries, name string) int {
+	return slices.IndexFunc(series, func(s model.InvoiceSeries) bool { return s.Name == name })
+}
@@ -0,0 +1,139 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/NaeuralEdgeProtocol/ratio1-backend/model"
+	"github.com/NaeuralEdgeProtocol/ratio1-backend/storage/memory"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInvoiceSeriesTakeResetsYearly(t *testing.T) {
+	series := model.InvoiceSeries{NextNumber: 41, YearlyReset: true, Year: 2025}
+
+	require.Equal(t, 41, series.Take(time.Date(2025, 12, 31, 0, 0, 0, 0, time.UTC)))
+	require.Equal(t, 1, series.Take(time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)))
+	require.Equal(t, 2, series.Take(time.Date(2026, 2, 1, 0, 0, 0, 0, time.UTC)))
+	require.Equal(t, 2026, series.Year)
+
+	series.YearlyReset = false
+	require.Equal(t, 3, series.Take(time.Date(2027, 1, 1, 0, 0, 0, 0, time.UTC)))
+}
+
+func TestGenerateInvoiceDraftTakesNumberFromSeries(t *testing.T) {
+	previous := GetRepositories()
+	SetRepositories(memory.NewRepositories())
+	defer SetRepositories(previous)
+
+	require.NoError(t, CreatePreference(&model.Preference{UserAddress: "0xowner", InvoiceSeries: "R1", NextNumber: 5}))
+	_, err := CreateInvoiceSeries("0xowner", model.InvoiceSeries{Name: "R2", NextNumber: 100})
+	require.NoError(t, err)
+
+	draft, err := generateInvoiceDraft("0xowner", "0xcsp", createTestAllocations(t, "0xowner", "0xcsp", "1000000"), fixedFxRates(nil))
+	require.NoError(t, err)
+	require.Equal(t, "R1", draft.InvoiceSeries)
+	require.Equal(t, 5, draft.InvoiceNumber)
+
+	// switching to an existing series keeps its own counter
+	require.NoError(t, ChangePreference(&model.Preference{UserAddress: "0xowner", InvoiceSeries: "R2", NextNumber: 6}))
+	draft, err = generateInvoiceDraft("0xowner", "0xcsp", createTestAllocations(t, "0xowner", "0xcsp", "1000000"), fixedFxRates(nil))
+	require.NoError(t, err)
+	require.Equal(t, "R2", draft.InvoiceSeries)
+	require.Equal(t, 100, draft.InvoiceNumber)
+
+	series, err := GetInvoiceSeries("0xowner")
+	require.NoError(t, err)
+	require.Len(t, series, 2)
+	require.Equal(t, 6, series[0].NextNumber)
+	require.Equal(t, 101, series[1].NextNumber)
+	preference, err := repos.Preferences.GetByAddress("0xowner")
+	require.NoError(t, err)
+	require.Equal(t, 101, preference.NextNumber)
+}
+
+func TestChangeInvoiceSeriesRejectsMovingBackwards(t *testing.T) {
+	previous := GetRepositories()
+	SetRepositories(memory.NewRepositories())
+	defer SetRepositories(previous)
+
+	// a preference stored before the series existed
+	require.NoError(t, repos.Preferences.Create(&model.Preference{UserAddress: "0xowner", InvoiceSeries: "R1", NextNumber: 10}))
+
+	_, err := CreateInvoiceSeries("0xowner", model.InvoiceSeries{Name: "R1"})
+	require.ErrorIs(t, err, ErrorSeriesExists)
+	_, err = CreateInvoiceSeries("0xowner", model.InvoiceSeries{Name: " "})
+	require.ErrorIs(t, err, ErrorSeriesInvalid)
+	_, err = ChangeInvoiceSeries("0xowner", "R9", 1, false)
+	require.ErrorIs(t, err, ErrorSeriesNotFound)
+
+	_, err = ChangeInvoiceSeries("0xowner", "R1", 9, false)
+	require.ErrorIs(t, err, ErrorSeriesMovedBackwards)
+	err = ChangePreference(&model.Preference{UserAddress: "0xowner", InvoiceSeries: "R1", NextNumber: 3})
+	require.ErrorIs(t, err, ErrorSeriesMovedBackwards)
+
+	series, err := ChangeInvoiceSeries("0xowner", "R1", 20, true)
+	require.NoError(t, err)
+	require.Equal(t, 20, series.NextNumber)
+	require.True(t, series.YearlyReset)
+	preference, err := repos.Preferences.GetByAddress("0xowner")
+	require.NoError(t, err)
+	require.Equal(t, 20, preference.NextNumber)
+
+	// a yearly series that did not number anything this year can restart
+	lastYear := time.Now().Year() - 1
+	require.NoError(t, repos.InvoiceSeries.Change("0xowner", "R1", func(series *model.InvoiceSeries) error {
+		series.Year = lastYear
+		return nil
+	}))
+	series, err = ChangeInvoiceSeries("0xowner", "R1", 1, true)
+	require.NoError(t, err)
+	require.Equal(t, 1, series.NextNumber)
+	require.Equal(t, time.Now().Year(), series.Year)
+}
+
+func TestGetInvoiceSeriesReportFindsGapsAndDuplicates(t *testing.T) {
+	previous := GetRepositories()
+	SetRepositories(memory.NewRepositories())
+	defer SetRepositories(previous)
+
+	require.NoError(t, CreatePreference(&model.Preference{UserAddress: "0xowner", InvoiceSeries: "R1", NextNumber: 1}))
+	_, err := ChangeInvoiceSeries("0xowner", "R1", 9, false)
+	require.NoError(t, err)
+
+	draft := func(number int, status string) uuid.UUID {
+		d := &model.InvoiceDraft{
+			CreationTimestamp: time.Now(),
+			UserAddress:       "0xowner",
+			CspOwner:          "0xcsp",
+			InvoiceSeries:     "R1",
+			InvoiceNumber:     number,
+			Status:            status,
+		}
+		require.NoError(t, repos.Drafts.Create(d))
+		return d.DraftId
+	}
+	draft(1, model.DraftStatusDraft)
+	draft(2, model.DraftStatusVoid)
+	first := draft(4, model.DraftStatusIssued)
+	second := draft(4, model.DraftStatusDraft)
+	draft(5, model.DraftStatusSuperseded)
+	draft(6, model.DraftStatusDraft)
+
+	report, err := GetInvoiceSeriesReport("0xowner", "R1")
+	require.NoError(t, err)
+	require.Equal(t, 9, report.NextNumber)
+	require.Len(t, report.Periods, 1)
+	period := report.Periods[0]
+	require.Equal(t, 5, period.Count)
+	require.Equal(t, 1, period.First)
+	require.Equal(t, 6, period.Last)
+	require.Equal(t, []InvoiceNumberRange{{From: 3, To: 3}, {From: 5, To: 5}, {From: 7, To: 8}}, period.Gaps)
+	require.Len(t, period.Duplicates, 1)
+	require.Equal(t, 4, period.Duplicates[0].Number)
+	require.ElementsMatch(t, []uuid.UUID{first, second}, period.Duplicates[0].DraftIds)
+
+	_, err = GetInvoiceSeriesReport("0xowner", "R2")
+	require.ErrorIs(t, err, ErrorSeriesNotFound)
+}
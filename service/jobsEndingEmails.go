@@ -9,11 +9,10 @@ import (
 
 	"github.com/NaeuralEdgeProtocol/ratio1-backend/config"
 	"github.com/NaeuralEdgeProtocol/ratio1-backend/ratio1abi"
-	"github.com/NaeuralEdgeProtocol/ratio1-backend/storage"
+	"github.com/NaeuralEdgeProtocol/ratio1-backend/rpc"
 	"github.com/ethereum/go-ethereum"
 	"github.com/ethereum/go-ethereum/accounts/abi"
 	"github.com/ethereum/go-ethereum/common"
-	"github.com/ethereum/go-ethereum/ethclient"
 )
 
 type EndingJob struct {
@@ -98,11 +97,7 @@ func getEndingJobsWithPeriod() ([]EndingJob, error) {
 		return nil, errors.New("error while parsing reader abi: " + err.Error())
 	}
 
-	client, err := ethclient.Dial(config.Config.Infura.ApiUrl + config.Config.Infura.Secret)
-	if err != nil {
-		return nil, errors.New("error while dialing client")
-	}
-	defer client.Close()
+	client := rpc.Get()
 
 	endingJobs := make([]EndingJob, 0)
 	for _, period := range periods {
@@ -116,7 +111,7 @@ func getEndingJobsWithPeriod() ([]EndingJob, error) {
 			Data: data,
 		}
 
-		ctx, cancel := context.WithTimeout(context.Background(), rpcRequestTimeout)
+		ctx, cancel := context.WithTimeout(backfillContext(), rpcRequestTimeout)
 		result, err := client.CallContract(ctx, msg, nil)
 		cancel()
 		if err != nil {
@@ -164,7 +159,7 @@ func sendEmailForEndingJobs(usersWithJobs map[string][]EndingJob) {
 			continue
 		}
 
-		account, found, err := storage.GetAccountByAddress(ownerAddress)
+		account, found, err := repos.Accounts.GetByAddress(ownerAddress)
 		if err != nil {
 			log.Error("error while retrieving account for address %s: %v", ownerAddress, err)
 			continue
@@ -173,7 +168,7 @@ func sendEmailForEndingJobs(usersWithJobs map[string][]EndingJob) {
 			continue
 		}
 
-		notificationEmail, found, err := storage.GetAccountNotificationEmailByAddress(ownerAddress)
+		notificationEmail, found, err := repos.NotificationEmails.GetByAddress(ownerAddress)
 		if err != nil {
 			log.Error("error while retrieving notification email for address %s: %v", ownerAddress, err)
 			continue
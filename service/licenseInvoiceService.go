@@ -0,0 +1,160 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/NaeuralEdgeProtocol/ratio1-backend/model"
+	"github.com/NaeuralEdgeProtocol/ratio1-backend/process"
+)
+
+var (
+	ErrorLicenseInvoiceNotIssued = errors.New("invoice has not been issued")
+)
+
+var sendLicenseInvoiceEmailFn = SendLicenseInvoiceEmail
+
+// LicensePurchaseHistoryEntry is a license purchase of an account with the
+// invoice issued for it. Amounts are in USD.
+type LicensePurchaseHistoryEntry struct {
+	InvoiceUuid   string  `json:"invoiceUuid"`
+	Status        string  `json:"status"`
+	NumLicenses   int     `json:"numLicenses"`
+	UnitUsdPrice  int     `json:"unitUsdPrice"`
+	VatPercentage float64 `json:"vatPercentage"`
+	VatName       string  `json:"vatName"`
+	NetAmount     float64 `json:"netAmount"`
+	VatAmount     float64 `json:"vatAmount"`
+	TotalAmount   float64 `json:"totalAmount"`
+	TxHash        string  `json:"txHash"`
+	BlockNumber   int64   `json:"blockNumber"`
+	InvoiceSeries string  `json:"invoiceSeries"`
+	InvoiceNumber string  `json:"invoiceNumber"`
+	PdfUrl        string  `json:"pdfUrl"`
+}
+
+// GetLicensePurchaseHistory returns the license purchases of address, newest
+// first. Invoices still waiting for their purchase are left out.
+func GetLicensePurchaseHistory(address string) ([]LicensePurchaseHistoryEntry, error) {
+	invoices, err := repos.Invoices.GetByAddress(address)
+	if err != nil {
+		return nil, errors.New("error while retrieving invoices from storage: " + err.Error())
+	}
+
+	history := []LicensePurchaseHistoryEntry{}
+	for _, invoice := range invoices {
+		if invoice.Uuid == nil || invoice.Status == nil || *invoice.Status == model.InvoiceStatusPending {
+			continue
+		}
+		if invoice.NumLicenses == nil || invoice.UnitUsdPrice == nil {
+			continue
+		}
+
+		vatPercentage, vatName, err := licenseVat(invoice)
+		if err != nil {
+			return nil, err
+		}
+		net := float64(*invoice.NumLicenses * *invoice.UnitUsdPrice)
+		vat := math.Round(net*vatPercentage) / 100
+		entry := LicensePurchaseHistoryEntry{
+			InvoiceUuid:   *invoice.Uuid,
+			Status:        *invoice.Status,
+			NumLicenses:   *invoice.NumLicenses,
+			UnitUsdPrice:  *invoice.UnitUsdPrice,
+			VatPercentage: vatPercentage,
+			VatName:       vatName,
+			NetAmount:     net,
+			VatAmount:     vat,
+			TotalAmount:   net + vat,
+		}
+		if invoice.TxHash != nil {
+			entry.TxHash = *invoice.TxHash
+		}
+		if invoice.BlockNumber != nil {
+			entry.BlockNumber = *invoice.BlockNumber
+		}
+		if invoice.InvoiceSeries != nil {
+			entry.InvoiceSeries = *invoice.InvoiceSeries
+		}
+		if invoice.InvoiceNumber != nil && *invoice.InvoiceNumber != "" {
+			entry.InvoiceNumber = *invoice.InvoiceNumber
+			entry.PdfUrl = "/accounts/invoices/" + *invoice.Uuid + "/pdf"
+		}
+		history = append(history, entry)
+	}
+
+	sort.SliceStable(history, func(i, j int) bool {
+		return history[i].BlockNumber > history[j].BlockNumber
+	})
+	return history, nil
+}
+
+// GetLicenseInvoicePdf returns the pdf of an invoice issued to address, with
+// its file name.
+func GetLicenseInvoicePdf(address, invoiceUuid string) ([]byte, string, error) {
+	invoice, found, err := repos.Invoices.GetByID(invoiceUuid)
+	if err != nil {
+		return nil, "", errors.New("error while retrieving invoice from storage: " + err.Error())
+	} else if !found || !strings.EqualFold(invoice.BlockchainAddress, address) {
+		return nil, "", ErrorInvoiceNotFound
+	}
+	if invoice.InvoiceSeries == nil || invoice.InvoiceNumber == nil || *invoice.InvoiceNumber == "" {
+		return nil, "", ErrorLicenseInvoiceNotIssued
+	}
+
+	provider := GetInvoiceProvider()
+	err = provider.Authenticate()
+	if err != nil {
+		return nil, "", errors.New("error while authenticating to invoice provider: " + err.Error())
+	}
+	content, err := getInvoicePdf(provider, *invoice.InvoiceSeries, *invoice.InvoiceNumber)
+	if err != nil {
+		return nil, "", err
+	}
+	return content, invoicePdfName(*invoice.InvoiceSeries, *invoice.InvoiceNumber), nil
+}
+
+// sendLicenseInvoice emails the issued invoice to the buyer, the provider must
+// be authenticated.
+func sendLicenseInvoice(invoice *model.InvoiceClient, provider InvoiceProvider) error {
+	if invoice.UserEmail == nil || *invoice.UserEmail == "" {
+		return nil
+	}
+	if invoice.InvoiceSeries == nil || invoice.InvoiceNumber == nil || *invoice.InvoiceNumber == "" {
+		return nil
+	}
+
+	content, err := getInvoicePdf(provider, *invoice.InvoiceSeries, *invoice.InvoiceNumber)
+	if err != nil {
+		return err
+	}
+	number := *invoice.InvoiceSeries + " " + *invoice.InvoiceNumber
+	attachment := newEmailAttachment(invoicePdfName(*invoice.InvoiceSeries, *invoice.InvoiceNumber), "application/pdf", content)
+	return sendLicenseInvoiceEmailFn(*invoice.UserEmail, *invoice.NumLicenses, number, attachment)
+}
+
+func getInvoicePdf(provider InvoiceProvider, seriesName, number string) ([]byte, error) {
+	link, err := provider.PdfLink(seriesName, number)
+	if err != nil {
+		return nil, errors.New("error while retrieving invoice pdf link: " + err.Error())
+	}
+
+	var content []byte
+	if path, ok := strings.CutPrefix(link, "file://"); ok {
+		content, err = os.ReadFile(path)
+	} else {
+		content, err = process.HttpGetBytes(link)
+	}
+	if err != nil {
+		return nil, errors.New("error while downloading invoice pdf: " + err.Error())
+	}
+	return content, nil
+}
+
+func invoicePdfName(seriesName, number string) string {
+	return fmt.Sprintf("%s-%s.pdf", seriesName, number)
+}
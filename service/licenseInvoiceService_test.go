@@ -0,0 +1,98 @@
+package service
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/NaeuralEdgeProtocol/ratio1-backend/model"
+	"github.com/stretchr/testify/require"
+)
+
+type sentLicenseInvoice struct {
+	email         string
+	numLicenses   int
+	invoiceNumber string
+	attachments   []EmailAttachment
+}
+
+func withSentLicenseInvoices(t *testing.T) *[]sentLicenseInvoice {
+	sent := &[]sentLicenseInvoice{}
+	previous := sendLicenseInvoiceEmailFn
+	sendLicenseInvoiceEmailFn = func(email string, numLicenses int, invoiceNumber string, attachments ...EmailAttachment) error {
+		*sent = append(*sent, sentLicenseInvoice{email, numLicenses, invoiceNumber, attachments})
+		return nil
+	}
+	t.Cleanup(func() { sendLicenseInvoiceEmailFn = previous })
+	return sent
+}
+
+func TestElaborateInvoicesEmailsInvoiceToBuyer(t *testing.T) {
+	_, _ = withInvoiceQueue(t)
+	sent := withSentLicenseInvoices(t)
+
+	ElaborateInvoices()
+
+	require.Len(t, *sent, 1)
+	require.Equal(t, "buyer@example.com", (*sent)[0].email)
+	require.Equal(t, 2, (*sent)[0].numLicenses)
+	require.Equal(t, model.InvoiceROUSeriesName+" 1", (*sent)[0].invoiceNumber)
+	require.Len(t, (*sent)[0].attachments, 1)
+	require.Equal(t, "application/pdf", (*sent)[0].attachments[0].ContentType)
+}
+
+func TestGetLicensePurchaseHistory(t *testing.T) {
+	purchase, _ := withInvoiceQueue(t)
+	withSentLicenseInvoices(t)
+
+	history, err := GetLicensePurchaseHistory(purchase.Address)
+	require.NoError(t, err)
+	require.Empty(t, history)
+
+	ElaborateInvoices()
+
+	history, err = GetLicensePurchaseHistory(purchase.Address)
+	require.NoError(t, err)
+	require.Len(t, history, 1)
+	entry := history[0]
+	require.Equal(t, purchase.InvoiceID, entry.InvoiceUuid)
+	require.Equal(t, 2, entry.NumLicenses)
+	require.Equal(t, 500, entry.UnitUsdPrice)
+	require.Equal(t, float64(21), entry.VatPercentage)
+	require.Equal(t, float64(1000), entry.NetAmount)
+	require.Equal(t, float64(210), entry.VatAmount)
+	require.Equal(t, float64(1210), entry.TotalAmount)
+	require.Equal(t, "0x01", entry.TxHash)
+	require.Equal(t, "1", entry.InvoiceNumber)
+	require.Equal(t, "/accounts/invoices/"+purchase.InvoiceID+"/pdf", entry.PdfUrl)
+
+	other, err := GetLicensePurchaseHistory("0x00000000000000000000000000000000000000b2")
+	require.NoError(t, err)
+	require.Empty(t, other)
+}
+
+func TestGetLicenseInvoicePdf(t *testing.T) {
+	purchase, provider := withInvoiceQueue(t)
+	withSentLicenseInvoices(t)
+
+	_, _, err := GetLicenseInvoicePdf(purchase.Address, purchase.InvoiceID)
+	require.ErrorIs(t, err, ErrorLicenseInvoiceNotIssued)
+
+	ElaborateInvoices()
+
+	content, name, err := GetLicenseInvoicePdf(strings.ToUpper(purchase.Address[:2])+purchase.Address[2:], purchase.InvoiceID)
+	require.NoError(t, err)
+	require.True(t, strings.HasPrefix(string(content), "%PDF-"))
+
+	invoice, _, err := repos.Invoices.GetByID(purchase.InvoiceID)
+	require.NoError(t, err)
+	require.Equal(t, *invoice.InvoiceSeries+"-1.pdf", name)
+	link, err := provider.PdfLink(*invoice.InvoiceSeries, "1")
+	require.NoError(t, err)
+	stored, err := os.ReadFile(strings.TrimPrefix(link, "file://"))
+	require.NoError(t, err)
+	require.Equal(t, stored, content)
+
+	_, _, err = GetLicenseInvoicePdf("0x00000000000000000000000000000000000000b2", purchase.InvoiceID)
+	require.ErrorIs(t, err, ErrorInvoiceNotFound)
+}
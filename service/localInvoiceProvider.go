@@ -0,0 +1,206 @@
+package service
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/NaeuralEdgeProtocol/ratio1-backend/model"
+	"github.com/NaeuralEdgeProtocol/ratio1-backend/pdf"
+)
+
+const cancelledInvoiceSuffix = ".cancelled"
+
+var localInvoiceFile = regexp.MustCompile(`^(.+)-(\d+)(\.cancelled)?\.pdf$`)
+
+// LocalInvoiceProvider numbers the invoices itself and writes their pdf to a
+// directory, it needs no credentials and is meant for development and tests.
+// The numbers of a series continue from the files found in the directory.
+type LocalInvoiceProvider struct {
+	mu  sync.Mutex
+	dir string
+	now func() time.Time
+}
+
+func NewLocalInvoiceProvider(dir string) *LocalInvoiceProvider {
+	return &LocalInvoiceProvider{dir: dir, now: time.Now}
+}
+
+func (p *LocalInvoiceProvider) Authenticate() error {
+	return nil
+}
+
+func (p *LocalInvoiceProvider) Issue(invoice model.InvoiceRequest) (*IssuedInvoice, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	err := os.MkdirAll(p.dir, 0755)
+	if err != nil {
+		return nil, errors.New("error while creating invoice directory: " + err.Error())
+	}
+
+	number, err := p.nextNumber(invoice.SeriesName)
+	if err != nil {
+		return nil, err
+	}
+
+	path := p.path(invoice.SeriesName, number, "")
+	err = os.WriteFile(path, renderLocalInvoice(invoice, number, p.now()), 0644)
+	if err != nil {
+		return nil, errors.New("error while writing invoice: " + err.Error())
+	}
+
+	return &IssuedInvoice{SeriesName: invoice.SeriesName, Number: number, Link: fileLink(path)}, nil
+}
+
+// Cancel keeps the pdf of the invoice under a cancelled name, its number is not
+// reused.
+func (p *LocalInvoiceProvider) Cancel(seriesName, number string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	err := os.Rename(p.path(seriesName, number, ""), p.path(seriesName, number, cancelledInvoiceSuffix))
+	if err != nil {
+		return errors.New("error while cancelling invoice: " + err.Error())
+	}
+	return nil
+}
+
+func (p *LocalInvoiceProvider) PdfLink(seriesName, number string) (string, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for _, suffix := range []string{"", cancelledInvoiceSuffix} {
+		path := p.path(seriesName, number, suffix)
+		if _, err := os.Stat(path); err == nil {
+			return fileLink(path), nil
+		}
+	}
+	return "", errors.New("invoice not found: " + seriesName + " " + number)
+}
+
+func (p *LocalInvoiceProvider) path(seriesName, number, suffix string) string {
+	return filepath.Join(p.dir, seriesName+"-"+number+suffix+".pdf")
+}
+
+func (p *LocalInvoiceProvider) nextNumber(seriesName string) (string, error) {
+	entries, err := os.ReadDir(p.dir)
+	if err != nil {
+		return "", errors.New("error while reading invoice directory: " + err.Error())
+	}
+
+	last := 0
+	for _, entry := range entries {
+		match := localInvoiceFile.FindStringSubmatch(entry.Name())
+		if match == nil || match[1] != seriesName {
+			continue
+		}
+		number, err := strconv.Atoi(match[2])
+		if err == nil {
+			last = max(last, number)
+		}
+	}
+	return strconv.Itoa(last + 1), nil
+}
+
+func fileLink(path string) string {
+	absolute, err := filepath.Abs(path)
+	if err != nil {
+		absolute = path
+	}
+	return "file://" + filepath.ToSlash(absolute)
+}
+
+// renderLocalInvoice lays out the invoice, or the credit note, on a single
+// page.
+func renderLocalInvoice(invoice model.InvoiceRequest, number string, issuedAt time.Time) []byte {
+	const (
+		left  = 50.0
+		right = pdf.PageWidth - 50
+	)
+
+	title := "INVOICE"
+	if invoice.ReferenceDocument != nil {
+		title = "CREDIT NOTE"
+	}
+
+	doc := pdf.New(title + " " + invoice.SeriesName + " " + number)
+	doc.Text(left, 70, pdf.HelveticaBold, 20, title)
+	doc.TextRight(right, 62, pdf.Helvetica, 10, "Series "+invoice.SeriesName+" no. "+number)
+	doc.TextRight(right, 76, pdf.Helvetica, 10, "Date "+issuedAt.UTC().Format("2006-01-02"))
+	if invoice.ReferenceDocument != nil {
+		doc.Text(left, 86, pdf.Helvetica, 9, "Reverses invoice "+invoice.ReferenceDocument.SeriesName+" no. "+invoice.ReferenceDocument.Number)
+	}
+	doc.Line(left, 90, right, 90)
+
+	doc.Text(left, 112, pdf.HelveticaBold, 10, "Seller")
+	doc.Text(left, 126, pdf.Helvetica, 10, "CIF "+invoice.CIF)
+
+	client := invoice.Client
+	y := 112.0
+	doc.Text(300, y, pdf.HelveticaBold, 10, "Client")
+	for _, line := range []string{client.Name, client.CIF, client.Address, joinNonEmpty(", ", []string{client.City, client.State, client.Country}), client.Email} {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		y += 14
+		doc.Text(300, y, pdf.Helvetica, 10, line)
+	}
+
+	y = max(y, 126) + 36
+	doc.Text(left, y, pdf.HelveticaBold, 9, "Product")
+	doc.TextRight(360, y, pdf.HelveticaBold, 9, "Qty")
+	doc.TextRight(420, y, pdf.HelveticaBold, 9, "Price")
+	doc.TextRight(470, y, pdf.HelveticaBold, 9, "VAT")
+	doc.TextRight(right, y, pdf.HelveticaBold, 9, "Amount")
+	doc.Line(left, y+6, right, y+6)
+
+	var net, vat float64
+	for _, product := range invoice.Product {
+		amount := float64(product.Price * product.Quantity)
+		net += amount
+		vat += amount * product.VatPercentage / 100
+
+		y += 20
+		doc.TextRight(360, y, pdf.Helvetica, 9, strconv.FormatInt(product.Quantity, 10))
+		doc.TextRight(420, y, pdf.Helvetica, 9, strconv.FormatInt(product.Price, 10))
+		doc.TextRight(470, y, pdf.Helvetica, 9, strconv.FormatFloat(product.VatPercentage, 'f', -1, 64)+"%")
+		doc.TextRight(right, y, pdf.Helvetica, 9, strconv.FormatFloat(amount, 'f', 2, 64))
+		for i, line := range pdf.Wrap(product.Name, pdf.Helvetica, 9, 250) {
+			if i > 0 {
+				y += 12
+			}
+			doc.Text(left, y, pdf.Helvetica, 9, line)
+		}
+		if product.VatName != "" {
+			y += 12
+			doc.Text(left, y, pdf.Helvetica, 8, product.VatName)
+		}
+	}
+
+	y += 16
+	doc.Line(300, y, right, y)
+	for _, total := range []struct {
+		label  string
+		amount float64
+	}{{"Net", net}, {"VAT", vat}, {"Total " + invoice.Currency, net + vat}} {
+		y += 16
+		doc.Text(300, y, pdf.HelveticaBold, 10, total.label)
+		doc.TextRight(right, y, pdf.Helvetica, 10, strconv.FormatFloat(total.amount, 'f', 2, 64))
+	}
+
+	y += 36
+	for _, line := range strings.Split(invoice.Mentions, "\n") {
+		for _, wrapped := range pdf.Wrap(line, pdf.Helvetica, 8, right-left) {
+			doc.Text(left, y, pdf.Helvetica, 8, wrapped)
+			y += 11
+		}
+	}
+
+	return doc.Bytes()
+}
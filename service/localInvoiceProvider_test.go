@@ -0,0 +1,58 @@
+package service
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/NaeuralEdgeProtocol/ratio1-backend/model"
+	"github.com/NaeuralEdgeProtocol/ratio1-backend/storage/memory"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLocalInvoiceProviderNumbersEverySeries(t *testing.T) {
+	previous := GetRepositories()
+	SetRepositories(memory.NewRepositories())
+	defer SetRepositories(previous)
+
+	dir := t.TempDir()
+	provider := NewLocalInvoiceProvider(dir)
+	name, email := "Buyer Ltd", "buyer@example.com"
+	client := model.InvoiceClient{CompanyName: &name, UserEmail: &email, IsCompany: true, Country: "DEU", ReverseCharge: true}
+	event := model.Event{NumLicenses: 1, UnitUsdPrice: 500, TokenPaid: 2500, TxHash: "0x01"}
+
+	request, err := buildInvoiceRequest(client, event)
+	require.NoError(t, err)
+	first, err := provider.Issue(request)
+	require.NoError(t, err)
+	require.Equal(t, model.InvoiceSeriesName, first.SeriesName)
+	require.Equal(t, "1", first.Number)
+	require.True(t, strings.HasPrefix(first.Link, "file://"))
+
+	data, err := os.ReadFile(strings.TrimPrefix(first.Link, "file://"))
+	require.NoError(t, err)
+	require.True(t, strings.HasPrefix(string(data), "%PDF-"))
+	require.Contains(t, string(data), "Taxare inversa")
+
+	require.NoError(t, provider.Cancel(first.SeriesName, first.Number))
+	link, err := provider.PdfLink(first.SeriesName, first.Number)
+	require.NoError(t, err)
+	require.True(t, strings.HasSuffix(link, ".cancelled.pdf"))
+	require.Error(t, provider.Cancel(first.SeriesName, first.Number))
+
+	// numbers continue after a restart and are not reused after a cancel
+	second, err := NewLocalInvoiceProvider(dir).Issue(request)
+	require.NoError(t, err)
+	require.Equal(t, "2", second.Number)
+
+	client.Country = model.ROU_ID
+	request, err = buildInvoiceRequest(client, event)
+	require.NoError(t, err)
+	romanian, err := provider.Issue(request)
+	require.NoError(t, err)
+	require.Equal(t, model.InvoiceROUSeriesName, romanian.SeriesName)
+	require.Equal(t, "1", romanian.Number)
+
+	_, err = provider.PdfLink(model.InvoiceSeriesName, "9")
+	require.Error(t, err)
+}
@@ -16,7 +16,6 @@ const maxLogFilterBlockRange int64 = 10_000
 const maxLogFilterResults = 10_000
 const logFilterMaxAttempts = 3
 const logFilterRateLimitMaxAttempts = 6
-const logFilterRequestInterval = time.Second
 const logFilterRetryDelay = 250 * time.Millisecond
 const logFilterRateLimitRetryDelay = 2 * time.Second
 
@@ -34,7 +33,6 @@ func filterLogsInChunks(ctx context.Context, client logFilterer, query ethereum.
 		query,
 		from,
 		to,
-		logFilterRequestInterval,
 		waitForLogFilterDelay,
 		randomLogFilterRetryJitter,
 	)
@@ -45,7 +43,6 @@ func filterLogsInChunksWithTiming(
 	client logFilterer,
 	query ethereum.FilterQuery,
 	from, to int64,
-	requestInterval time.Duration,
 	wait logFilterWaitFunc,
 	jitter logFilterJitterFunc,
 ) ([]types.Log, error) {
@@ -56,12 +53,6 @@ func filterLogsInChunksWithTiming(
 		return []types.Log{}, nil
 	}
 
-	pacedClient := &pacedLogFilterer{
-		client:   client,
-		interval: requestInterval,
-		wait:     wait,
-	}
-
 	logs := make([]types.Log, 0)
 	for chunkStart := from; chunkStart <= to; {
 		chunkEnd := to
@@ -69,7 +60,7 @@ func filterLogsInChunksWithTiming(
 			chunkEnd = chunkStart + maxLogFilterBlockRange - 1
 		}
 
-		chunkLogs, err := filterLogsChunk(ctx, pacedClient, query, chunkStart, chunkEnd, wait, jitter)
+		chunkLogs, err := filterLogsChunk(ctx, client, query, chunkStart, chunkEnd, wait, jitter)
 		if err != nil {
 			return nil, err
 		}
@@ -84,23 +75,6 @@ func filterLogsInChunksWithTiming(
 	return logs, nil
 }
 
-type pacedLogFilterer struct {
-	client       logFilterer
-	interval     time.Duration
-	wait         logFilterWaitFunc
-	requestCount int
-}
-
-func (p *pacedLogFilterer) FilterLogs(ctx context.Context, query ethereum.FilterQuery) ([]types.Log, error) {
-	if p.requestCount > 0 && p.interval > 0 {
-		if err := p.wait(ctx, p.interval); err != nil {
-			return nil, err
-		}
-	}
-	p.requestCount++
-	return p.client.FilterLogs(ctx, query)
-}
-
 func filterLogsChunk(
 	ctx context.Context,
 	client logFilterer,
@@ -47,7 +47,7 @@ func filterLogsInChunksForTest(
 	query ethereum.FilterQuery,
 	from, to int64,
 ) ([]types.Log, error) {
-	return filterLogsInChunksWithTiming(ctx, client, query, from, to, 0, noLogFilterWait, noLogFilterJitter)
+	return filterLogsInChunksWithTiming(ctx, client, query, from, to, noLogFilterWait, noLogFilterJitter)
 }
 
 func noLogFilterWait(ctx context.Context, _ time.Duration) error {
@@ -205,30 +205,6 @@ func TestFilterLogsInChunks_ShouldHandleMaximumInt64Boundary(t *testing.T) {
 	require.Equal(t, int64(math.MaxInt64), client.queries[1].ToBlock.Int64())
 }
 
-func TestFilterLogsInChunks_ShouldPaceEveryRequestAfterFirst(t *testing.T) {
-	client := &logFiltererMock{}
-	var delays []time.Duration
-	wait := func(_ context.Context, delay time.Duration) error {
-		delays = append(delays, delay)
-		return nil
-	}
-
-	_, err := filterLogsInChunksWithTiming(
-		context.Background(),
-		client,
-		ethereum.FilterQuery{},
-		1,
-		20_001,
-		time.Second,
-		wait,
-		noLogFilterJitter,
-	)
-
-	require.NoError(t, err)
-	require.Len(t, client.queries, 3)
-	require.Equal(t, []time.Duration{time.Second, time.Second}, delays)
-}
-
 func TestFilterLogsInChunks_ShouldUseLongBackoffForRateLimit(t *testing.T) {
 	client := &logFiltererMock{
 		failAt:     1,
@@ -252,7 +228,6 @@ func TestFilterLogsInChunks_ShouldUseLongBackoffForRateLimit(t *testing.T) {
 		ethereum.FilterQuery{},
 		1,
 		1,
-		0,
 		wait,
 		jitter,
 	)
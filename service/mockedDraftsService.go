@@ -149,6 +149,7 @@ func BuildMocks() {
 			ExtraTaxes:                 strPtr(genExtraTaxesJSON()),
 			LocalCurrency:              lc,
 			LocalCurrencyExchangeRatio: ratio,
+			Status:                     model.DraftStatusDraft,
 			CspProfile:                 cd,
 			UserProfile:                ud,
 		}
@@ -206,6 +207,7 @@ func BuildMocks() {
 			ExtraTaxes:                 strPtr(genExtraTaxesJSON()),
 			LocalCurrency:              lc,
 			LocalCurrencyExchangeRatio: ratio,
+			Status:                     model.DraftStatusDraft,
 			CspProfile:                 cd,
 			UserProfile:                ud,
 		}
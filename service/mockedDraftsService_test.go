@@ -2,6 +2,7 @@ package service
 
 import (
 	"os"
+	"path/filepath"
 	"testing"
 )
 
@@ -9,8 +10,8 @@ func Test_mockedData(t *testing.T) {
 	BuildMocks()
 	i, a := GetMockCspData()
 	file, _ := FillInvoiceDraftTemplate(i[0], a)
-	os.WriteFile("csp.html", file, 0644)
+	os.WriteFile(filepath.Join(t.TempDir(), "csp.html"), file, 0644)
 	i, a = GetMockOperatorData()
 	file, _ = FillInvoiceDraftTemplate(i[0], a)
-	os.WriteFile("operator.html", file, 0644)
+	os.WriteFile(filepath.Join(t.TempDir(), "operator.html"), file, 0644)
 }
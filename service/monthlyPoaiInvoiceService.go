@@ -1,6 +1,7 @@
 package service
 
 import (
+	"errors"
 	"fmt"
 	"math/big"
 	"strings"
@@ -13,9 +14,14 @@ import (
 )
 
 func MonthlyPoaiInvoiceReport() {
+	/* Release allocations left pointing to a draft that was never stored*/
+	if _, err := ReconcileDraftAllocations(); err != nil {
+		fmt.Println("error while reconciling draft allocations: " + err.Error())
+	}
+
 	/* Get all Allocations not invoiced*/
 	now := time.Now().UTC()
-	unclaimedAllocations, err := storage.GetMonthlyUnclaimedAllocations(now)
+	unclaimedAllocations, err := repos.Allocations.GetMonthlyUnclaimed(now)
 	if err != nil {
 		fmt.Println("Error retrieving unclaimed allocations: " + err.Error())
 		return
@@ -26,12 +32,6 @@ func MonthlyPoaiInvoiceReport() {
 		return
 	}
 
-	currencyMap, err := GetFreeCurrencyValues() //map[USD,EUR...]ratio always based 1 usd -> value
-	if err != nil {
-		fmt.Println("could not fetch currency map: ", err.Error())
-		return
-	}
-
 	/*Get all unique nodeOwner - csp pair*/
 	reports := make(map[string][]model.Allocation)
 	for _, alloc := range unclaimedAllocations {
@@ -43,84 +43,12 @@ func MonthlyPoaiInvoiceReport() {
 	var drafts []model.InvoiceDraft
 	for k, allocations := range reports {
 		userAddress, cspOwner := splitKey(k)
-		invoice := model.InvoiceDraft{
-			DraftId:           uuid.New(),
-			UserAddress:       userAddress,
-			CspOwner:          cspOwner,
-			CreationTimestamp: time.Now(),
-			UserProfile:       allocations[0].UserProfile,
-			CspProfile:        allocations[0].CspProfile,
-		}
-
-		preference, err := storage.GetPreferenceByAddress(userAddress)
-		if err != nil {
-			fmt.Println("error while retrieving user preference: " + err.Error())
-			continue
-		} else if preference != nil {
-			if invoice.CspProfile.Country == invoice.UserProfile.Country {
-				invoice.VatApplied = preference.CountryVat
-			} else if isUeCountry(invoice.UserProfile.Country) {
-				if isUeCountry(invoice.CspProfile.Country) {
-					invoice.VatApplied = preference.UeVat
-				} else {
-					invoice.VatApplied = preference.ExtraUeVat
-				}
-			} else {
-				invoice.VatApplied = preference.ExtraUeVat
-			}
-			invoice.InvoiceNumber = preference.NextNumber
-			invoice.InvoiceSeries = preference.InvoiceSeries
-			invoice.ExtraTaxes = preference.ExtraTaxes
-			invoice.ExtraText = preference.ExtraText
-			invoice.LocalCurrency = preference.LocalCurrency
-		} else {
-			preference = &model.Preference{
-				UserAddress:   userAddress,
-				NextNumber:    1,
-				InvoiceSeries: "NODE",
-				CountryVat:    0,
-				UeVat:         0,
-				ExtraUeVat:    0,
-				LocalCurrency: "USD",
-			}
-			invoice.VatApplied = preference.ExtraUeVat
-			invoice.InvoiceNumber = preference.NextNumber
-			invoice.InvoiceSeries = preference.InvoiceSeries
-		}
-
-		totalUsdcAmount := big.NewInt(0)
-		for _, alloc := range allocations {
-			totalUsdcAmount.Add(totalUsdcAmount, alloc.GetUsdcAmountPayed())
-			alloc.DraftId = &invoice.DraftId
-			err = storage.UpdateAllocation(&alloc) //TODO create more stable system with rollback for all invoices
-			if err != nil {
-				fmt.Println("error while updating allocation: " + err.Error())
-				return
-			}
-		}
-		invoice.TotalUsdcAmount += GetAmountAsFloat(totalUsdcAmount, model.UsdcDecimals)
-
-		if userAddress != cspOwner {
-			preference.NextNumber += 1
-			err = storage.UpdatePreference(preference)
-			if err != nil {
-				fmt.Println("error while updating preference: " + err.Error())
-				return
-			}
-		} else {
-			invoice.InvoiceNumber = 0
-			invoice.InvoiceSeries = ""
-		}
-
-		if v, ok := currencyMap[invoice.LocalCurrency]; ok {
-			invoice.LocalCurrencyExchangeRatio = v
-		}
-		err = storage.CreateInvoiceDraft(&invoice)
+		invoice, err := generateInvoiceDraft(userAddress, cspOwner, allocations, GetFxRate)
 		if err != nil {
-			fmt.Println("error while saving invoice: " + err.Error())
+			fmt.Println("error while generating invoice draft for " + k + ": " + err.Error())
 			continue
 		}
-		drafts = append(drafts, invoice)
+		drafts = append(drafts, *invoice)
 	}
 
 	cspEmails := make(map[string][]string)
@@ -153,6 +81,201 @@ func MonthlyPoaiInvoiceReport() {
 	}
 }
 
+// generateInvoiceDraft stores the draft of a node owner - csp pair in a single
+// transaction: the allocations are claimed, the invoice number is consumed and
+// the draft is created together. A failure, or the process dying midway,
+// leaves the allocations unclaimed and the numbering untouched, so the job can
+// simply run again. Each allocation is converted to the local currency at the
+// rate of the day it was created.
+func generateInvoiceDraft(userAddress, cspOwner string, allocations []model.Allocation, rate fxRateFunc) (*model.InvoiceDraft, error) {
+	allocationIds := make([]uint, 0, len(allocations))
+	totalUsdcAmount := big.NewInt(0)
+	for _, alloc := range allocations {
+		allocationIds = append(allocationIds, alloc.Id)
+		totalUsdcAmount.Add(totalUsdcAmount, alloc.GetUsdcAmountPayed())
+	}
+
+	// the rates are looked up before the transaction, it is checked to still
+	// match the locked preference
+	creation := time.Now()
+	currency, ratio, err := preferenceExchangeRatio(userAddress, "", allocations, creation, rate)
+	if err != nil {
+		return nil, err
+	}
+	vatEngine, err := LoadVatEngine()
+	if err != nil {
+		return nil, err
+	}
+	buyerViesValid, err := draftBuyerViesValid(cspOwner)
+	if err != nil {
+		return nil, err
+	}
+
+	var invoice model.InvoiceDraft
+	err = repos.Drafts.Generate(func(tx storage.DraftGenerationTx) error {
+		invoice = model.InvoiceDraft{
+			DraftId:           uuid.New(),
+			UserAddress:       userAddress,
+			CspOwner:          cspOwner,
+			CreationTimestamp: creation,
+			UserProfile:       allocations[0].UserProfile,
+			CspProfile:        allocations[0].CspProfile,
+			TotalUsdcAmount:   GetAmountAsFloat(totalUsdcAmount, model.UsdcDecimals),
+			Status:            model.DraftStatusDraft,
+		}
+		invoice.SnapshotIdentities()
+
+		preference, err := tx.GetPreferenceForUpdate(userAddress)
+		if err != nil {
+			return errors.New("error while retrieving user preference: " + err.Error())
+		} else if preference != nil {
+			applyDraftPreference(&invoice, preference)
+		} else {
+			preference = defaultPreference(userAddress)
+		}
+		err = applyDraftVat(&invoice, preference, vatEngine, buyerViesValid)
+		if err != nil {
+			return err
+		}
+
+		err = tx.ClaimAllocations(invoice.DraftId, allocationIds)
+		if err != nil {
+			return errors.New("error while claiming allocations: " + err.Error())
+		}
+
+		if userAddress != cspOwner {
+			invoice.InvoiceNumber, err = takeSeriesNumber(tx, preference, invoice.CreationTimestamp)
+			if err != nil {
+				return err
+			}
+			invoice.InvoiceSeries = preference.InvoiceSeries
+			err = tx.SavePreference(preference)
+			if err != nil {
+				return errors.New("error while updating preference: " + err.Error())
+			}
+		}
+
+		if invoice.LocalCurrency != currency {
+			return ErrorDraftCurrencyChanged
+		}
+		invoice.LocalCurrencyExchangeRatio = ratio
+		err = tx.CreateDraft(&invoice)
+		if err != nil {
+			return errors.New("error while saving invoice: " + err.Error())
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &invoice, nil
+}
+
+// preferenceExchangeRatio returns the local currency of the stored preference
+// of the node owner, fallbackCurrency when there is none, and the ratio of the
+// allocations converted to it.
+func preferenceExchangeRatio(userAddress, fallbackCurrency string, allocations []model.Allocation, creation time.Time, rate fxRateFunc) (string, float64, error) {
+	preference, err := repos.Preferences.GetByAddress(userAddress)
+	if err != nil {
+		return "", 0, errors.New("error while retrieving user preference: " + err.Error())
+	}
+	currency := fallbackCurrency
+	if preference != nil {
+		currency = preference.LocalCurrency
+	}
+
+	ratio, err := draftExchangeRatio(allocations, currency, creation, rate)
+	if err != nil {
+		return "", 0, fmt.Errorf("error while converting to %s: %w", currency, err)
+	}
+	return currency, ratio, nil
+}
+
+// defaultPreference is used for the node owners that never saved one.
+func defaultPreference(userAddress string) *model.Preference {
+	return &model.Preference{
+		UserAddress:   userAddress,
+		NextNumber:    1,
+		InvoiceSeries: "NODE",
+		CountryVat:    0,
+		LocalCurrency: "USD",
+	}
+}
+
+// applyDraftPreference sets the extra taxes and text and the local currency
+// of the node owner preference on the draft.
+func applyDraftPreference(invoice *model.InvoiceDraft, preference *model.Preference) {
+	invoice.ExtraTaxes = preference.ExtraTaxes
+	invoice.ExtraText = preference.ExtraText
+	invoice.LocalCurrency = preference.LocalCurrency
+}
+
+// applyDraftVat sets the VAT the engine decides for the services the node
+// owner supplied to the csp, as of the creation of the draft. The domestic
+// rate of the preference is only used by the node owners of countries
+// outside the EU, the EU rates are the ones of the engine.
+func applyDraftVat(invoice *model.InvoiceDraft, preference *model.Preference, engine *VatEngine, buyerViesValid bool) error {
+	seller, buyer := invoice.Seller(), invoice.Buyer()
+	vat, err := engine.Apply(VatInput{
+		SellerCountry:      seller.Country,
+		SellerRegistered:   preference.IsVatRegistered(),
+		SellerDomesticRate: preference.CountryVat,
+		BuyerCountry:       buyer.Country,
+		BuyerBusiness:      buyer.IsCompany,
+		BuyerViesValid:     buyerViesValid,
+		SupplyDate:         invoice.CreationTimestamp,
+	})
+	if err != nil {
+		return errors.New("error while computing vat: " + err.Error())
+	}
+	invoice.VatApplied = vat.Rate
+	invoice.VatCategory = vat.Category
+	invoice.VatMention = vat.Mention
+	return nil
+}
+
+// draftBuyerViesValid tells whether the VAT number of the csp owner was found
+// valid in VIES during its kyc.
+func draftBuyerViesValid(cspOwner string) (bool, error) {
+	account, found, err := getAccountByAddressFn(cspOwner)
+	if err != nil {
+		return false, errors.New("error while retrieving csp account: " + err.Error())
+	} else if !found || account == nil || account.Email == nil {
+		return false, nil
+	}
+
+	kyc, found, err := repos.Kycs.GetByEmail(*account.Email)
+	if err != nil {
+		return false, errors.New("error while retrieving csp kyc: " + err.Error())
+	}
+	return found && kyc.ViesRegistered, nil
+}
+
+// ReconcileDraftAllocations finds the allocations pointing to a draft that does
+// not exist and releases them, so the next generation drafts them again.
+func ReconcileDraftAllocations() ([]model.Allocation, error) {
+	orphans, err := repos.Allocations.GetWithMissingDraft()
+	if err != nil {
+		return nil, errors.New("error while retrieving allocations with missing draft: " + err.Error())
+	}
+	if len(orphans) == 0 {
+		return nil, nil
+	}
+
+	ids := make([]uint, 0, len(orphans))
+	for _, alloc := range orphans {
+		fmt.Printf("allocation %d points to missing draft %s\n", alloc.Id, alloc.DraftId.String())
+		ids = append(ids, alloc.Id)
+	}
+	err = repos.Allocations.ReleaseFromDraft(ids)
+	if err != nil {
+		return nil, errors.New("error while releasing allocations: " + err.Error())
+	}
+
+	return orphans, nil
+}
+
 func draftNotificationEmails(address, fallbackEmail string) []string {
 	emails, err := getConfirmedAccountEmails(address)
 	if err != nil {
@@ -173,15 +296,15 @@ func draftNotificationEmails(address, fallbackEmail string) []string {
 func draftInvoiceAttachments(drafts []model.InvoiceDraft) ([]EmailAttachment, error) {
 	attachments := make([]EmailAttachment, 0, len(drafts))
 	for _, draft := range drafts {
-		allocations, err := storage.GetAllocationsByDraftId(draft.DraftId.String())
+		allocations, err := GetDraftAllocations(draft)
 		if err != nil {
 			return nil, err
 		}
-		content, err := FillInvoiceDraftTemplate(draft, allocations)
+		content, err := RenderInvoiceDraftPdf(draft, allocations)
 		if err != nil {
 			return nil, err
 		}
-		attachments = append(attachments, newEmailAttachment(draftInvoiceAttachmentName(draft, ".doc"), "application/msword", content))
+		attachments = append(attachments, newEmailAttachment(draftInvoiceAttachmentName(draft, ".pdf"), "application/pdf", content))
 	}
 	return attachments, nil
 }
@@ -194,11 +317,13 @@ func draftInvoiceAttachmentName(draft model.InvoiceDraft, ext string) string {
 		ext = "." + ext
 	}
 
-	supplier, ok := draft.UserProfile.GetNameAsString()
+	seller := draft.Seller()
+	supplier, ok := seller.GetNameAsString()
 	if !ok {
 		supplier = draft.UserAddress
 	}
-	beneficiary, ok := draft.CspProfile.GetNameAsString()
+	buyer := draft.Buyer()
+	beneficiary, ok := buyer.GetNameAsString()
 	if !ok {
 		beneficiary = draft.CspOwner
 	}
@@ -2,12 +2,15 @@ package service
 
 import (
 	"errors"
+	"fmt"
 	"testing"
 	"time"
 
 	"github.com/NaeuralEdgeProtocol/ratio1-backend/config"
 	"github.com/NaeuralEdgeProtocol/ratio1-backend/model"
 	"github.com/NaeuralEdgeProtocol/ratio1-backend/storage"
+	"github.com/NaeuralEdgeProtocol/ratio1-backend/storage/memory"
+	"github.com/google/uuid"
 	"github.com/stretchr/testify/require"
 )
 
@@ -74,6 +77,166 @@ func TestDraftInvoiceAttachmentName(t *testing.T) {
 	require.Equal(t, "202607_Acme-Nodes-SRL_Ratio-Cloud-EU_42-NODE.doc", draftInvoiceAttachmentName(draft, "doc"))
 }
 
+func TestGenerateInvoiceDraftClaimsAllocationsAndConsumesNumber(t *testing.T) {
+	previous := GetRepositories()
+	SetRepositories(memory.NewRepositories())
+	defer SetRepositories(previous)
+
+	require.NoError(t, repos.Preferences.Create(&model.Preference{UserAddress: "0xowner", InvoiceSeries: "R1", NextNumber: 5, LocalCurrency: "EUR"}))
+	allocations := createTestAllocations(t, "0xowner", "0xcsp", "1000000", "2500000")
+
+	draft, err := generateInvoiceDraft("0xowner", "0xcsp", allocations, fixedFxRates(map[string]float64{"EUR": 0.9}))
+	require.NoError(t, err)
+	require.Equal(t, 5, draft.InvoiceNumber)
+	require.Equal(t, "R1", draft.InvoiceSeries)
+	require.Equal(t, 3.5, draft.TotalUsdcAmount)
+	require.Equal(t, 0.9, draft.LocalCurrencyExchangeRatio)
+
+	claimed, err := repos.Allocations.GetByDraftId(draft.DraftId.String())
+	require.NoError(t, err)
+	require.Len(t, claimed, 2)
+
+	preference, err := repos.Preferences.GetByAddress("0xowner")
+	require.NoError(t, err)
+	require.Equal(t, 6, preference.NextNumber)
+}
+
+func TestGenerateInvoiceDraftSnapshotsBillingIdentity(t *testing.T) {
+	previous := GetRepositories()
+	SetRepositories(memory.NewRepositories())
+	defer SetRepositories(previous)
+
+	sellerName, buyerName := "Acme Nodes SRL", "Ratio Cloud EU"
+	seller := &model.UserInfo{BlockchainAddress: "0xowner", IsCompany: true, CompanyName: &sellerName, Address: "Via Roma 1", Country: "ITA"}
+	require.NoError(t, repos.UserInfos.Create(seller))
+	require.NoError(t, repos.UserInfos.Create(&model.UserInfo{BlockchainAddress: "0xcsp", IsCompany: true, CompanyName: &buyerName, Country: "DEU"}))
+	require.NoError(t, repos.Preferences.Create(&model.Preference{UserAddress: "0xowner", InvoiceSeries: "R1", NextNumber: 1}))
+
+	draft, err := generateInvoiceDraft("0xowner", "0xcsp", withTestProfiles(t, createTestAllocations(t, "0xowner", "0xcsp", "1000000")), fixedFxRates(nil))
+	require.NoError(t, err)
+	require.True(t, draft.HasIdentitySnapshot())
+	require.Equal(t, 1, *draft.SellerInfoVersion)
+	require.Equal(t, 1, *draft.BuyerInfoVersion)
+
+	renamed := "Acme Renamed SRL"
+	seller.CompanyName = &renamed
+	seller.Address = "Via Milano 2"
+	require.NoError(t, repos.UserInfos.Update(seller))
+
+	versions, err := repos.UserInfos.GetVersions("0xowner")
+	require.NoError(t, err)
+	require.Len(t, versions, 2)
+	require.Equal(t, renamed, *versions[1].CompanyName)
+
+	drafts, err := repos.Drafts.GetListByNodeOwner("0xowner")
+	require.NoError(t, err)
+	require.Len(t, drafts, 1)
+	snapshot := drafts[0].Seller()
+	require.Equal(t, sellerName, *snapshot.CompanyName)
+	require.Equal(t, "Via Roma 1", snapshot.Address)
+	buyer := drafts[0].Buyer()
+	require.Equal(t, buyerName, *buyer.CompanyName)
+
+	allocations, err := repos.Allocations.GetByDraftId(draft.DraftId.String())
+	require.NoError(t, err)
+	document, err := FillInvoiceDraftTemplate(drafts[0], allocations)
+	require.NoError(t, err)
+	require.Contains(t, string(document), sellerName)
+	require.NotContains(t, string(document), renamed)
+}
+
+func TestGenerateInvoiceDraftRollsBackWhenAllocationsAreClaimed(t *testing.T) {
+	previous := GetRepositories()
+	SetRepositories(memory.NewRepositories())
+	defer SetRepositories(previous)
+
+	require.NoError(t, repos.Preferences.Create(&model.Preference{UserAddress: "0xowner", InvoiceSeries: "R1", NextNumber: 5}))
+	allocations := createTestAllocations(t, "0xowner", "0xcsp", "1000000", "2500000")
+
+	claimedBy := uuid.New()
+	allocations[1].DraftId = &claimedBy
+	require.NoError(t, repos.Allocations.Update(&allocations[1]))
+	allocations[1].DraftId = nil
+
+	_, err := generateInvoiceDraft("0xowner", "0xcsp", allocations, fixedFxRates(nil))
+	require.ErrorContains(t, err, storage.ErrAllocationsAlreadyClaimed.Error())
+
+	preference, err := repos.Preferences.GetByAddress("0xowner")
+	require.NoError(t, err)
+	require.Equal(t, 5, preference.NextNumber)
+
+	drafts, err := repos.Drafts.GetListByNodeOwner("0xowner")
+	require.NoError(t, err)
+	require.Empty(t, drafts)
+
+	unclaimed, err := repos.Allocations.GetByCspAndUser("0xcspnode", "0xowner", "0xnode")
+	require.NoError(t, err)
+	require.Nil(t, unclaimed[0].DraftId)
+}
+
+func TestReconcileDraftAllocationsReleasesOrphans(t *testing.T) {
+	previous := GetRepositories()
+	SetRepositories(memory.NewRepositories())
+	defer SetRepositories(previous)
+
+	allocations := createTestAllocations(t, "0xowner", "0xcsp", "1000000", "2000000")
+	draft, err := generateInvoiceDraft("0xowner", "0xcsp", allocations[:1], fixedFxRates(nil))
+	require.NoError(t, err)
+
+	missingDraft := uuid.New()
+	allocations[1].DraftId = &missingDraft
+	require.NoError(t, repos.Allocations.Update(&allocations[1]))
+
+	orphans, err := ReconcileDraftAllocations()
+	require.NoError(t, err)
+	require.Len(t, orphans, 1)
+	require.Equal(t, allocations[1].Id, orphans[0].Id)
+
+	remaining, err := repos.Allocations.GetWithMissingDraft()
+	require.NoError(t, err)
+	require.Empty(t, remaining)
+
+	claimed, err := repos.Allocations.GetByDraftId(draft.DraftId.String())
+	require.NoError(t, err)
+	require.Len(t, claimed, 1)
+}
+
+func createTestAllocations(t *testing.T, userAddress, cspOwner string, amounts ...string) []model.Allocation {
+	allocations := make([]model.Allocation, 0, len(amounts))
+	for i, amount := range amounts {
+		alloc := model.Allocation{
+			AllocationCreation: time.Now().UTC(),
+			TxHash:             fmt.Sprintf("0xtx%d", i),
+			JobId:              fmt.Sprintf("%d", i),
+			NodeAddress:        "0xnode",
+			UserAddress:        userAddress,
+			CspAddress:         "0xcspnode",
+			CspOwner:           cspOwner,
+			UsdcAmountPayed:    amount,
+		}
+		require.NoError(t, repos.Allocations.Create(&alloc))
+		allocations = append(allocations, alloc)
+	}
+	return allocations
+}
+
+// withTestProfiles sets the profiles the storage preloads on allocations.
+func withTestProfiles(t *testing.T, allocations []model.Allocation) []model.Allocation {
+	for i := range allocations {
+		userProfile, err := repos.UserInfos.GetByAddress(allocations[i].UserAddress)
+		require.NoError(t, err)
+		cspProfile, err := repos.UserInfos.GetByAddress(allocations[i].CspOwner)
+		require.NoError(t, err)
+		if userProfile != nil {
+			allocations[i].UserProfile = *userProfile
+		}
+		if cspProfile != nil {
+			allocations[i].CspProfile = *cspProfile
+		}
+	}
+	return allocations
+}
+
 func Test_monthlyPoaiInvoiceService(t *testing.T) {
 	config.Config.Mail = config.MailConfig{
 		ApiUrl:    "",
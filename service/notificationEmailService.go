@@ -6,7 +6,6 @@ import (
 	"time"
 
 	"github.com/NaeuralEdgeProtocol/ratio1-backend/model"
-	"github.com/NaeuralEdgeProtocol/ratio1-backend/storage"
 )
 
 func RegisterNotificationEmail(address, email string) (*model.Account, error) {
@@ -28,7 +27,7 @@ func RegisterNotificationEmail(address, email string) (*model.Account, error) {
 		return nil, errors.New("additional notification email must be different from default notification email")
 	}
 
-	notificationEmail, found, err := storage.GetAccountNotificationEmailByAddress(address)
+	notificationEmail, found, err := repos.NotificationEmails.GetByAddress(address)
 	if err != nil {
 		return nil, errors.New("error while retrieving notification email from storage: " + err.Error())
 	}
@@ -46,7 +45,7 @@ func RegisterNotificationEmail(address, email string) (*model.Account, error) {
 	notificationEmail.PendingEmail = email
 	notificationEmail.UpdatedAt = time.Now()
 
-	err = storage.CreateOrUpdateAccountNotificationEmail(notificationEmail)
+	err = repos.NotificationEmails.CreateOrUpdate(notificationEmail)
 	if err != nil {
 		return nil, errors.New("error while updating notification email on storage: " + err.Error())
 	}
@@ -67,7 +66,7 @@ func DeleteNotificationEmail(address string) (*model.Account, error) {
 		return nil, ErrorAccountNotFound
 	}
 
-	err = storage.DeleteAccountNotificationEmail(address)
+	err = repos.NotificationEmails.Delete(address)
 	if err != nil {
 		return nil, errors.New("error while deleting notification email from storage: " + err.Error())
 	}
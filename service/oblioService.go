@@ -1,128 +1,248 @@
 package service
 
 import (
-	"context"
 	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"math/big"
+	"net/url"
 	"strconv"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/NaeuralEdgeProtocol/ratio1-backend/config"
 	"github.com/NaeuralEdgeProtocol/ratio1-backend/model"
 	"github.com/NaeuralEdgeProtocol/ratio1-backend/process"
 	"github.com/NaeuralEdgeProtocol/ratio1-backend/ratio1abi"
-	"github.com/NaeuralEdgeProtocol/ratio1-backend/storage"
-	"github.com/ethereum/go-ethereum"
 	"github.com/ethereum/go-ethereum/accounts/abi"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/types"
-	"github.com/ethereum/go-ethereum/crypto"
-	"github.com/ethereum/go-ethereum/ethclient"
 )
 
+const (
+	// maxLicensePurchaseAttempts is the number of failed attempts after which a
+	// purchase is moved to the dead letter status
+	maxLicensePurchaseAttempts = 8
+	licensePurchaseRetryDelay  = 5 * time.Minute
+	licensePurchaseMaxDelay    = 6 * time.Hour
+)
+
+// issuedInvoiceStoreDelays are the waits before storing an issued invoice on
+// its purchase again, a purchase left without it would be invoiced twice.
+var issuedInvoiceStoreDelays = []time.Duration{time.Second, 3 * time.Second, 10 * time.Second}
+
+var (
+	ErrorLicensePurchaseNotFound     = errors.New("license purchase not found")
+	ErrorLicensePurchaseNotRetryable = errors.New("license purchase cannot be retried in its current status")
+)
+
+// ElaborateInvoices issues the invoices of the license purchases stored by the
+// indexer. A failed purchase is retried with exponential backoff and moved to
+// the dead letter status after maxLicensePurchaseAttempts.
 func ElaborateInvoices() {
-	latestSeenBlock, _, err := storage.GetLatestInvoiceBlock()
+	now := time.Now()
+	purchases, err := repos.LicensePurchases.GetDue(now)
 	if err != nil {
-		fmt.Println("Error receiving latest block from database: " + err.Error())
+		fmt.Println("Error retrieving license purchases from storage: " + err.Error())
 		return
 	}
 
-	events, err := fetchEvents(latestSeenBlock)
-	if err != nil {
-		fmt.Println("Error fetching events: " + err.Error())
-		return
+	provider := GetInvoiceProvider()
+	authenticated := false
+	for i := range purchases {
+		purchase := &purchases[i]
+		err = processLicensePurchase(purchase, provider, &authenticated)
+		if err != nil {
+			fmt.Println("Error processing license purchase " + purchase.TxHash + ": " + err.Error())
+			failLicensePurchase(purchase, err, now)
+		}
 	}
+}
 
-	var auth model.AuthRequest
-	err = process.HttpPostWithUrlEncoded(config.Config.Oblio.AuthUrl, config.Config.Oblio.ClientSecret, &auth)
+// processLicensePurchase issues the invoice of purchase and marks the purchase
+// completed. The provider authenticates before the first invoice of the batch.
+func processLicensePurchase(purchase *model.LicensePurchaseEvent, provider InvoiceProvider, authenticated *bool) error {
+	event := purchase.Event()
+	invoice, found, err := repos.Invoices.GetByID(event.InvoiceID)
 	if err != nil {
-		fmt.Println("Error doing auth http request: " + err.Error())
-		return
+		return errors.New("error while retrieving invoice from storage: " + err.Error())
+	} else if !found {
+		return errors.New("invoice not found in storage: " + event.InvoiceID)
 	}
 
-	for _, event := range events {
-		invoice, found, err := storage.GetInvoiceByID(event.InvoiceID)
-		if err != nil {
-			fmt.Println("Error retrieving invoice infromation from storage: " + err.Error())
-			continue
-		}
-		if !found {
-			fmt.Println("Invoice not found in storage: " + event.InvoiceID)
-			continue
-		}
-		if *invoice.Status != model.InvoiceStatusPending {
-			fmt.Println("Invoice already processed: " + event.InvoiceID)
-			continue
-		}
-		var url, invoiceNumber string
+	if *invoice.Status != model.InvoiceStatusPending {
+		fmt.Println("Invoice already processed: " + event.InvoiceID)
+		return completeLicensePurchase(purchase)
+	}
+
+	if purchase.InvoiceNumber == nil {
+		issued := &IssuedInvoice{}
 		if event.Address != config.Config.NaeuralAddress { //naeural not gonna emit an invoice for itself
-			url, invoiceNumber, err = generateInvoice(*invoice, event, auth)
+			if !*authenticated {
+				err = provider.Authenticate()
+				if err != nil {
+					return errors.New("error while authenticating to invoice provider: " + err.Error())
+				}
+				*authenticated = true
+			}
+			request, err := buildInvoiceRequest(*invoice, event)
 			if err != nil {
-				fmt.Println("Error generating invoice: " + err.Error())
-				continue
+				return err
 			}
+			issued, err = provider.Issue(request)
+			if err != nil {
+				return errors.New("error while generating invoice: " + err.Error())
+			}
+		}
+
+		purchase.InvoiceSeries = &issued.SeriesName
+		purchase.InvoiceNumber = &issued.Number
+		purchase.InvoiceUrl = &issued.Link
+		err = storeIssuedInvoice(purchase)
+		if err != nil {
+			return errors.New("error while updating license purchase in storage: " + err.Error())
 		}
+	}
 
-		status := model.InvoiceStatusPaid
-		invoice.InvoiceNumber = &invoiceNumber
-		invoice.InvoiceUrl = &url
-		invoice.Status = &status
-		invoice.TxHash = &event.TxHash
-		invoice.BlockNumber = &event.BlockNumber
-		invoice.NumLicenses = &event.NumLicenses
-		invoice.UnitUsdPrice = &event.UnitUsdPrice
+	// the licenses are paid when the purchase is mined, not when it is invoiced
+	paidAt, err := GetBlockTime(event.BlockNumber)
+	if err != nil {
+		return errors.New("error while retrieving block time of purchase: " + err.Error())
+	}
+	status := model.InvoiceStatusPaid
+	invoice.PaidAt = &paidAt
+	invoice.InvoiceSeries = purchase.InvoiceSeries
+	invoice.InvoiceNumber = purchase.InvoiceNumber
+	invoice.InvoiceUrl = purchase.InvoiceUrl
+	invoice.Status = &status
+	invoice.TxHash = &event.TxHash
+	invoice.BlockNumber = &event.BlockNumber
+	invoice.NumLicenses = &event.NumLicenses
+	invoice.UnitUsdPrice = &event.UnitUsdPrice
+
+	err = repos.Invoices.Update(invoice)
+	if err != nil {
+		return errors.New("error while updating invoice in storage: " + err.Error())
+	}
+	err = recordInvoiceStatus(invoice, model.InvoiceDocumentInvoice, invoiceSystemActor, nil)
+	if err != nil {
+		fmt.Println("Error recording invoice status: " + err.Error())
+	}
 
-		err = storage.UpdateInvoice(invoice)
+	err = completeLicensePurchase(purchase)
+	if err != nil {
+		return err
+	}
+
+	SendBuyLicenseEmail(config.Config.InvoiceMessageEmail, *purchase.InvoiceUrl, *purchase.InvoiceNumber)
+	if *purchase.InvoiceNumber != "" {
+		if !*authenticated {
+			err = provider.Authenticate()
+			*authenticated = err == nil
+		}
+		if err == nil {
+			err = sendLicenseInvoice(invoice, provider)
+		}
 		if err != nil {
-			fmt.Println("Error updating invoices in storage: " + err.Error())
+			fmt.Println("Error sending invoice to buyer: " + err.Error())
 		}
+	}
+	return nil
+}
 
-		SendBuyLicenseEmail(config.Config.InvoiceMessageEmail, url, invoiceNumber)
+func completeLicensePurchase(purchase *model.LicensePurchaseEvent) error {
+	now := time.Now()
+	purchase.Status = model.LicensePurchaseStatusCompleted
+	purchase.NextAttemptAt = nil
+	purchase.ProcessedAt = &now
+	err := repos.LicensePurchases.Update(purchase)
+	if err != nil {
+		return errors.New("error while updating license purchase in storage: " + err.Error())
 	}
+	return nil
 }
 
-func fetchEvents(latestSeenBlock *int64) ([]model.Event, error) {
-	contractAddress := common.HexToAddress(config.Config.NDContractAddress)
+// failLicensePurchase records a failed attempt and schedules the next one.
+func failLicensePurchase(purchase *model.LicensePurchaseEvent, cause error, now time.Time) {
+	message := cause.Error()
+	purchase.Attempts++
+	purchase.LastError = &message
+	if purchase.Attempts >= maxLicensePurchaseAttempts {
+		purchase.Status = model.LicensePurchaseStatusDeadLetter
+		purchase.NextAttemptAt = nil
+	} else {
+		next := now.Add(licensePurchaseBackoff(purchase.Attempts))
+		purchase.NextAttemptAt = &next
+	}
 
-	var fromBlock *big.Int
-	if latestSeenBlock != nil {
-		fromBlock = big.NewInt(*latestSeenBlock)
+	err := repos.LicensePurchases.Update(purchase)
+	if err != nil {
+		fmt.Println("Error updating license purchase in storage: " + err.Error())
 	}
+}
 
-	eventSignatureAsBytes := []byte(ratio1abi.OblioEventSignature)
-	eventHash := crypto.Keccak256Hash(eventSignatureAsBytes)
+// storeIssuedInvoice stores the invoice issued for purchase, retrying while
+// the storage fails.
+func storeIssuedInvoice(purchase *model.LicensePurchaseEvent) error {
+	for attempt := 0; ; attempt++ {
+		err := repos.LicensePurchases.Update(purchase)
+		if err == nil || attempt >= len(issuedInvoiceStoreDelays) {
+			return err
+		}
+		fmt.Println("Error storing invoice of license purchase " + purchase.TxHash + ", retrying: " + err.Error())
+		time.Sleep(issuedInvoiceStoreDelays[attempt])
+	}
+}
 
-	query := ethereum.FilterQuery{
-		FromBlock: fromBlock,
-		Addresses: []common.Address{contractAddress},
-		Topics:    [][]common.Hash{{eventHash}},
+// licensePurchaseBackoff is the delay before the attempt following the
+// attempts failed ones.
+func licensePurchaseBackoff(attempts int) time.Duration {
+	delay := licensePurchaseRetryDelay
+	for i := 1; i < attempts && delay < licensePurchaseMaxDelay; i++ {
+		delay *= 2
 	}
+	return min(delay, licensePurchaseMaxDelay)
+}
 
-	client, err := ethclient.Dial(config.Config.Infura.ApiUrl + config.Config.Infura.Secret)
+// GetLicensePurchases returns the license purchases in one of statuses, all of
+// them when none is given.
+func GetLicensePurchases(statuses ...string) ([]model.LicensePurchaseEvent, error) {
+	purchases, err := repos.LicensePurchases.GetByStatus(statuses...)
 	if err != nil {
-		return nil, errors.New("error while dialing client: " + err.Error())
+		return nil, errors.New("error while retrieving license purchases from storage: " + err.Error())
 	}
-	defer client.Close()
+	return purchases, nil
+}
 
-	logs, err := client.FilterLogs(context.Background(), query)
+// RetryLicensePurchase puts a pending or dead letter purchase back in the
+// queue with a fresh set of attempts, it is picked up by the next run.
+func RetryLicensePurchase(id uint, adminAddress string) (*model.LicensePurchaseEvent, error) {
+	purchase, err := repos.LicensePurchases.Get(id)
 	if err != nil {
-		return nil, errors.New("error while filtering logs: " + err.Error())
+		return nil, errors.New("error while retrieving license purchase from storage: " + err.Error())
+	} else if purchase == nil {
+		return nil, ErrorLicensePurchaseNotFound
+	} else if purchase.Status == model.LicensePurchaseStatusCompleted {
+		return nil, ErrorLicensePurchaseNotRetryable
 	}
 
-	var events []model.Event
-	for _, vLog := range logs {
-		event, err := decodeLogs(vLog)
-		if err != nil {
-			fmt.Println("error while decoding logs: " + err.Error())
-			continue
-		}
-		events = append(events, *event)
+	purchase.Status = model.LicensePurchaseStatusPending
+	purchase.Attempts = 0
+	purchase.NextAttemptAt = nil
+	err = repos.LicensePurchases.Update(purchase)
+	if err != nil {
+		return nil, errors.New("error while updating license purchase in storage: " + err.Error())
+	}
+
+	details := "license purchase " + strconv.FormatUint(uint64(purchase.Id), 10) + " tx " + purchase.TxHash
+	err = recordAudit(model.AuditActionLicensePurchaseRetried, adminAddress, purchase.Address, &details)
+	if err != nil {
+		return nil, err
 	}
 
-	return events, nil
+	return purchase, nil
 }
 
 func decodeLogs(vLog types.Log) (*model.Event, error) {
@@ -169,12 +289,9 @@ func decodeLogs(vLog types.Log) (*model.Event, error) {
 	return &result, nil
 }
 
-func generateInvoice(invoiceData model.InvoiceClient, invoiceRequest model.Event, auth model.AuthRequest) (url string, invoiceNumber string, err error) {
-	var headers = []process.HttpHeaderPair{
-		{Key: "Authorization",
-			Value: "Bearer " + auth.AccessToken},
-	}
-
+// buildInvoiceRequest returns the invoice of a license purchase, with the vat
+// treatment of the buyer country.
+func buildInvoiceRequest(invoiceData model.InvoiceClient, invoiceRequest model.Event) (model.InvoiceRequest, error) {
 	var name string
 	if invoiceData.IsCompany {
 		name = *invoiceData.CompanyName
@@ -198,29 +315,14 @@ func generateInvoice(invoiceData model.InvoiceClient, invoiceRequest model.Event
 		Quantity:      int64(invoiceRequest.NumLicenses),
 		MeasuringUnit: "unit",
 		Currency:      "USD",
-		VatPercentage: 21,
 		VatIncluded:   0, // 0 = false, 1 = true
 	}
-
-	if invoiceData.IsCompany && invoiceData.Country != model.ROU_ID {
-		if invoiceData.ReverseCharge {
-			product.VatPercentage = 0
-			product.VatName = "Taxare inversa"
-		} else if !invoiceData.IsUe {
-			product.VatPercentage = 0
-			product.VatName = "Scutita"
-		}
-	} else if !invoiceData.IsCompany && invoiceData.Country != model.ROU_ID {
-		vat := GetEuVatPercentage(invoiceData.Country)
-		if vat != nil {
-			vatAsFloat := float64(*vat) / 100
-			product.VatPercentage = vatAsFloat
-			product.VatName = "VAT " + invoiceData.Country
-		} else {
-			product.VatPercentage = 0
-			product.VatName = "Neimpozabil in Romania conform art. 278"
-		}
+	var err error
+	product.VatPercentage, product.VatName, err = licenseVat(invoiceData)
+	if err != nil {
+		return model.InvoiceRequest{}, err
 	}
+
 	percentage := float64(100) + product.VatPercentage                                                     // 100% + VAT percentage
 	tokenPaidWithoutVat := (invoiceRequest.TokenPaid * 100) / percentage                                   // Calculate the token amount without VAT
 	pricePerToken := float64(invoiceRequest.UnitUsdPrice*invoiceRequest.NumLicenses) / tokenPaidWithoutVat // Calculate the price per token in USD
@@ -242,7 +344,7 @@ func generateInvoice(invoiceData model.InvoiceClient, invoiceRequest model.Event
 		SendEmail:  0,
 		Client:     client,
 		Product:    []model.InvoiceProduct{product},
-		Collect:    collect,
+		Collect:    &collect,
 	}
 
 	if invoiceData.Country == model.ROU_ID {
@@ -250,19 +352,112 @@ func generateInvoice(invoiceData model.InvoiceClient, invoiceRequest model.Event
 		invoice.SeriesName = model.InvoiceROUSeriesName
 	}
 
+	return invoice, nil
+}
+
+// licenseVat returns the vat percentage and the vat mention applied to the
+// licenses bought by the invoice client. Clients stored before the treatment
+// was kept with them get the one of today.
+func licenseVat(invoiceData model.InvoiceClient) (float64, string, error) {
+	if invoiceData.VatRate != nil && invoiceData.VatScheme != nil {
+		return *invoiceData.VatRate, licenseVatName(*invoiceData.VatScheme, invoiceData), nil
+	}
+
+	vat, err := LicenseVat(invoiceData.Country, invoiceData.IsCompany, invoiceData.ReverseCharge, time.Now())
+	if err != nil {
+		return 0, "", errors.New("error while computing vat: " + err.Error())
+	}
+	return vat.Rate, licenseVatName(vat.Scheme, invoiceData), nil
+}
+
+// licenseVatName is the vat mention oblio prints for a vat scheme.
+func licenseVatName(scheme string, invoiceData model.InvoiceClient) string {
+	switch scheme {
+	case VatSchemeReverseCharge:
+		return "Taxare inversa"
+	case VatSchemeOss:
+		return "VAT " + invoiceData.Country
+	case VatSchemeOutOfScope:
+		if invoiceData.IsCompany {
+			return "Scutita"
+		}
+		return "Neimpozabil in Romania conform art. 278"
+	}
+	return ""
+}
+
+// oblioInvoiceProvider issues the invoices with the oblio api.
+type oblioInvoiceProvider struct {
+	mu    sync.Mutex
+	token string
+}
+
+func (p *oblioInvoiceProvider) Authenticate() error {
+	var auth model.AuthRequest
+	err := process.HttpPostWithUrlEncoded(config.Config.Oblio.AuthUrl, config.Config.Oblio.ClientSecret, &auth)
+	if err != nil {
+		return errors.New("error while doing auth http request: " + err.Error())
+	}
+
+	p.mu.Lock()
+	p.token = auth.AccessToken
+	p.mu.Unlock()
+	return nil
+}
+
+func (p *oblioInvoiceProvider) headers() []process.HttpHeaderPair {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return []process.HttpHeaderPair{{Key: "Authorization", Value: "Bearer " + p.token}}
+}
+
+func (p *oblioInvoiceProvider) Issue(invoice model.InvoiceRequest) (*IssuedInvoice, error) {
 	data, _ := json.Marshal(invoice)
 	fmt.Println(string(data))
 
 	var oblioResponse model.OblioInvoiceResponse
-	err = process.HttpPost(config.Config.Oblio.InvoiceUrl, invoice, &oblioResponse, headers...)
-
+	err := process.HttpPost(config.Config.Oblio.InvoiceUrl, invoice, &oblioResponse, p.headers()...)
 	if err != nil {
-		return "", "", errors.New("error while doing http request: " + err.Error())
+		return nil, errors.New("error while doing http request: " + err.Error())
+	}
+	if oblioResponse.Status != 200 {
+		return nil, errors.New("error: " + strconv.Itoa(int(oblioResponse.Status)) + " " + oblioResponse.StatusMessage)
 	}
 
+	seriesName := oblioResponse.Data.SeriesName
+	if seriesName == "" {
+		seriesName = invoice.SeriesName
+	}
+	return &IssuedInvoice{SeriesName: seriesName, Number: oblioResponse.Data.Number, Link: oblioResponse.Data.Link}, nil
+}
+
+func (p *oblioInvoiceProvider) Cancel(seriesName, number string) error {
+	request := model.OblioDocumentRequest{CIF: model.InvoiceCif, SeriesName: seriesName, Number: number}
+
+	var oblioResponse model.OblioInvoiceResponse
+	err := process.HttpPut(config.Config.Oblio.InvoiceUrl+"/cancel", request, &oblioResponse, p.headers()...)
+	if err != nil {
+		return errors.New("error while doing http request: " + err.Error())
+	}
 	if oblioResponse.Status != 200 {
-		return "", "", errors.New("error: " + strconv.Itoa(int(oblioResponse.Status)) + " " + oblioResponse.StatusMessage)
+		return errors.New("error: " + strconv.Itoa(int(oblioResponse.Status)) + " " + oblioResponse.StatusMessage)
 	}
+	return nil
+}
 
-	return oblioResponse.Data.Link, oblioResponse.Data.Number, nil
+func (p *oblioInvoiceProvider) PdfLink(seriesName, number string) (string, error) {
+	query := url.Values{}
+	query.Set("cif", model.InvoiceCif)
+	query.Set("seriesName", seriesName)
+	query.Set("number", number)
+
+	var oblioResponse model.OblioInvoiceResponse
+	err := process.HttpGet(config.Config.Oblio.InvoiceUrl+"?"+query.Encode(), &oblioResponse, p.headers()...)
+	if err != nil {
+		return "", errors.New("error while doing http request: " + err.Error())
+	}
+	if oblioResponse.Status != 200 {
+		return "", errors.New("error: " + strconv.Itoa(int(oblioResponse.Status)) + " " + oblioResponse.StatusMessage)
+	}
+	return oblioResponse.Data.Link, nil
 }
@@ -2,11 +2,15 @@ package service
 
 import (
 	"encoding/hex"
+	"errors"
 	"fmt"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/NaeuralEdgeProtocol/ratio1-backend/model"
+	"github.com/NaeuralEdgeProtocol/ratio1-backend/storage"
+	"github.com/NaeuralEdgeProtocol/ratio1-backend/storage/memory"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/stretchr/testify/assert"
@@ -44,14 +48,12 @@ func Test_oblio(t *testing.T) {
 		TokenPaid:    2781.22,
 		TxHash:       hash,
 	}
-	url, invoiceNumber, err := generateInvoice(invoiceData, InvoiceRequest, model.AuthRequest{
-		AccessToken: "ciao"})
+	provider := &oblioInvoiceProvider{token: "ciao"}
+	request, err := buildInvoiceRequest(invoiceData, InvoiceRequest)
 	require.Nil(t, err)
-	fmt.Println(url, invoiceNumber)
-}
-
-func Test_etchfetchEvent(t *testing.T) {
-	fetchEvents(nil)
+	issued, err := provider.Issue(request)
+	require.Nil(t, err)
+	fmt.Println(issued.Link, issued.Number)
 }
 
 func Test_decodeTest(t *testing.T) {
@@ -81,3 +83,181 @@ func Test(t *testing.T) {
 	uuidExp = strings.ReplaceAll(uuidExp, "-", "")
 	assert.Equal(t, string(new), uuidExp)
 }
+
+// failingInvoiceProvider fails to issue invoices while err is set.
+type failingInvoiceProvider struct {
+	*LocalInvoiceProvider
+	err    error
+	issued int
+	last   model.InvoiceRequest
+}
+
+func (p *failingInvoiceProvider) Issue(invoice model.InvoiceRequest) (*IssuedInvoice, error) {
+	if p.err != nil {
+		return nil, p.err
+	}
+	p.issued++
+	p.last = invoice
+	return p.LocalInvoiceProvider.Issue(invoice)
+}
+
+// withInvoiceQueue stores a pending invoice with a purchase for it, invoices
+// are issued by a local provider.
+func withInvoiceQueue(t *testing.T) (*model.LicensePurchaseEvent, *failingInvoiceProvider) {
+	previousRepos := GetRepositories()
+	provider := &failingInvoiceProvider{LocalInvoiceProvider: NewLocalInvoiceProvider(t.TempDir())}
+	previousProvider := SetInvoiceProvider(provider)
+	SetRepositories(memory.NewRepositories())
+	t.Cleanup(func() {
+		SetRepositories(previousRepos)
+		SetInvoiceProvider(previousProvider)
+	})
+
+	id, email, status, name := "invoice-1", "buyer@example.com", model.InvoiceStatusPending, "Buyer Ltd"
+	require.NoError(t, repos.Invoices.Create(&model.InvoiceClient{
+		Uuid:              &id,
+		BlockchainAddress: "0x00000000000000000000000000000000000000b1",
+		UserEmail:         &email,
+		Status:            &status,
+		CompanyName:       &name,
+		IsCompany:         true,
+		Country:           model.ROU_ID,
+	}))
+	purchase := &model.LicensePurchaseEvent{
+		BlockNumber:  10,
+		TxHash:       "0x01",
+		Address:      "0x00000000000000000000000000000000000000b1",
+		InvoiceID:    id,
+		NumLicenses:  2,
+		UnitUsdPrice: 500,
+		TokenPaid:    1210,
+		Status:       model.LicensePurchaseStatusPending,
+	}
+	require.NoError(t, repos.LicensePurchases.Create(purchase))
+	require.NoError(t, repos.Blocks.Save(model.Block{Number: 10, Hash: "0x0a", Timestamp: time.Date(2026, 8, 12, 10, 0, 0, 0, time.UTC)}))
+	return purchase, provider
+}
+
+func TestElaborateInvoicesCompletesPurchase(t *testing.T) {
+	purchase, provider := withInvoiceQueue(t)
+
+	ElaborateInvoices()
+	ElaborateInvoices()
+	require.Equal(t, 1, provider.issued)
+
+	stored, err := repos.LicensePurchases.Get(purchase.Id)
+	require.NoError(t, err)
+	require.Equal(t, model.LicensePurchaseStatusCompleted, stored.Status)
+	require.NotNil(t, stored.ProcessedAt)
+
+	invoice, _, err := repos.Invoices.GetByID(purchase.InvoiceID)
+	require.NoError(t, err)
+	require.Equal(t, model.InvoiceStatusPaid, *invoice.Status)
+	require.Equal(t, model.InvoiceROUSeriesName, *invoice.InvoiceSeries)
+	require.Equal(t, "1", *invoice.InvoiceNumber)
+	require.Equal(t, 2, *invoice.NumLicenses)
+	require.Equal(t, time.Date(2026, 8, 12, 10, 0, 0, 0, time.UTC), *invoice.PaidAt)
+
+	link, err := provider.PdfLink(*invoice.InvoiceSeries, *invoice.InvoiceNumber)
+	require.NoError(t, err)
+	require.Equal(t, *invoice.InvoiceUrl, link)
+}
+
+func TestElaborateInvoicesRetriesWithBackoffThenDeadLetters(t *testing.T) {
+	purchase, provider := withInvoiceQueue(t)
+	provider.err = errors.New("oblio unavailable")
+
+	ElaborateInvoices()
+	stored, err := repos.LicensePurchases.Get(purchase.Id)
+	require.NoError(t, err)
+	require.Equal(t, model.LicensePurchaseStatusPending, stored.Status)
+	require.Equal(t, 1, stored.Attempts)
+	require.Contains(t, *stored.LastError, "oblio unavailable")
+	require.WithinDuration(t, time.Now().Add(licensePurchaseRetryDelay), *stored.NextAttemptAt, time.Minute)
+
+	// not due yet
+	ElaborateInvoices()
+	stored, err = repos.LicensePurchases.Get(purchase.Id)
+	require.NoError(t, err)
+	require.Equal(t, 1, stored.Attempts)
+
+	for stored.Status == model.LicensePurchaseStatusPending {
+		stored.NextAttemptAt = nil
+		require.NoError(t, repos.LicensePurchases.Update(stored))
+		ElaborateInvoices()
+		stored, err = repos.LicensePurchases.Get(purchase.Id)
+		require.NoError(t, err)
+	}
+	require.Equal(t, model.LicensePurchaseStatusDeadLetter, stored.Status)
+	require.Equal(t, maxLicensePurchaseAttempts, stored.Attempts)
+
+	dead, err := GetLicensePurchases(model.LicensePurchaseStatusDeadLetter)
+	require.NoError(t, err)
+	require.Len(t, dead, 1)
+
+	provider.err = nil
+	retried, err := RetryLicensePurchase(purchase.Id, "0xadmin")
+	require.NoError(t, err)
+	require.Equal(t, model.LicensePurchaseStatusPending, retried.Status)
+	require.Equal(t, 0, retried.Attempts)
+
+	ElaborateInvoices()
+	stored, err = repos.LicensePurchases.Get(purchase.Id)
+	require.NoError(t, err)
+	require.Equal(t, model.LicensePurchaseStatusCompleted, stored.Status)
+
+	_, err = RetryLicensePurchase(purchase.Id, "0xadmin")
+	require.ErrorIs(t, err, ErrorLicensePurchaseNotRetryable)
+	_, err = RetryLicensePurchase(99, "0xadmin")
+	require.ErrorIs(t, err, ErrorLicensePurchaseNotFound)
+}
+
+func TestElaborateInvoicesDoesNotIssueInvoiceTwice(t *testing.T) {
+	purchase, provider := withInvoiceQueue(t)
+	stored, err := repos.LicensePurchases.Get(purchase.Id)
+	require.NoError(t, err)
+	series, number, url := model.InvoiceROUSeriesName, "NAE 1", "https://invoices/1"
+	stored.InvoiceSeries, stored.InvoiceNumber, stored.InvoiceUrl = &series, &number, &url
+	require.NoError(t, repos.LicensePurchases.Update(stored))
+
+	ElaborateInvoices()
+	require.Equal(t, 0, provider.issued)
+	invoice, _, err := repos.Invoices.GetByID(purchase.InvoiceID)
+	require.NoError(t, err)
+	require.Equal(t, "NAE 1", *invoice.InvoiceNumber)
+}
+
+// flakyLicensePurchases fails the first failures updates.
+type flakyLicensePurchases struct {
+	storage.LicensePurchaseRepository
+	failures int
+}
+
+func (r *flakyLicensePurchases) Update(purchase *model.LicensePurchaseEvent) error {
+	if r.failures > 0 {
+		r.failures--
+		return errors.New("connection reset")
+	}
+	return r.LicensePurchaseRepository.Update(purchase)
+}
+
+func TestElaborateInvoicesRetriesStoringIssuedInvoice(t *testing.T) {
+	purchase, provider := withInvoiceQueue(t)
+	previousDelays := issuedInvoiceStoreDelays
+	issuedInvoiceStoreDelays = []time.Duration{0, 0}
+	t.Cleanup(func() { issuedInvoiceStoreDelays = previousDelays })
+	repos.LicensePurchases = &flakyLicensePurchases{LicensePurchaseRepository: repos.LicensePurchases, failures: 2}
+
+	ElaborateInvoices()
+	require.Equal(t, 1, provider.issued)
+	stored, err := repos.LicensePurchases.Get(purchase.Id)
+	require.NoError(t, err)
+	require.Equal(t, model.LicensePurchaseStatusCompleted, stored.Status)
+	require.Equal(t, "1", *stored.InvoiceNumber)
+}
+
+func TestLicensePurchaseBackoff(t *testing.T) {
+	require.Equal(t, licensePurchaseRetryDelay, licensePurchaseBackoff(1))
+	require.Equal(t, 4*licensePurchaseRetryDelay, licensePurchaseBackoff(3))
+	require.Equal(t, licensePurchaseMaxDelay, licensePurchaseBackoff(20))
+}
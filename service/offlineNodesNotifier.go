@@ -12,8 +12,8 @@ import (
 	"time"
 
 	"github.com/NaeuralEdgeProtocol/ratio1-backend/config"
+	"github.com/NaeuralEdgeProtocol/ratio1-backend/model"
 	"github.com/NaeuralEdgeProtocol/ratio1-backend/process"
-	"github.com/NaeuralEdgeProtocol/ratio1-backend/storage"
 	"github.com/Ratio1/edge_sdk_go/pkg/cstore"
 )
 
@@ -27,8 +27,8 @@ const (
 var (
 	fetchOracleNodesListFn      = fetchOracleNodesList
 	resolveNodeOwnersFn         = getNodeOwners
-	getAccountByAddressFn       = storage.GetAccountByAddress
-	getNotificationEmailFn      = storage.GetAccountNotificationEmailByAddress
+	getAccountByAddressFn       = getStoredAccountByAddress
+	getNotificationEmailFn      = getStoredNotificationEmailByAddress
 	sendOfflineNodesEmailFn     = SendOfflineNodesEmail
 	newCStoreClientFromEnvFn    = cstore.NewFromEnv
 	newOwnerNotificationStoreFn = newCStoreOwnerNotificationStore
@@ -41,6 +41,14 @@ type OfflineNodeAlert struct {
 	OfflineSeconds int64
 }
 
+func getStoredAccountByAddress(address string) (*model.Account, bool, error) {
+	return repos.Accounts.GetByAddress(address)
+}
+
+func getStoredNotificationEmailByAddress(address string) (*model.AccountNotificationEmail, bool, error) {
+	return repos.NotificationEmails.GetByAddress(address)
+}
+
 func ValidateOfflineNodesNotifierConfig() error {
 	if strings.TrimSpace(config.Config.OraclesApi) == "" {
 		return errors.New("oracles api url is not configured")
@@ -0,0 +1,518 @@
+package service
+
+import (
+	"encoding/csv"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/NaeuralEdgeProtocol/ratio1-backend/model"
+)
+
+var ErrorOssReportInvalidPeriod = errors.New("invalid OSS report period")
+
+// ossReportCurrency is the currency OSS returns are filed in.
+const ossReportCurrency = "EUR"
+
+// ossFxProvider publishes the rates OSS returns are converted with, art. 366
+// of Directive 2006/112/EC requires the ones of the ECB.
+var ossFxProvider FxProvider = ecbProvider{}
+
+// OssReport is the One-Stop-Shop return of the license sales of a quarter:
+// the supplies taxed at the rate of the member state of the buyer, grouped
+// by member state and rate. The invoices cancelled or refunded later stay in
+// the quarter they were paid in, a credit note corrects the quarter of the
+// invoice in the return of the quarter it was issued in. Unresolved lists the
+// invoices that could not be reported for lack of amounts.
+type OssReport struct {
+	Year          int                   `json:"year"`
+	Quarter       int                   `json:"quarter"`
+	From          time.Time             `json:"from"`
+	To            time.Time             `json:"to"`
+	EurRate       float64               `json:"eurRate"`
+	EurRateDate   time.Time             `json:"eurRateDate"`
+	EurRateSource string                `json:"eurRateSource"`
+	Lines         []OssReportLine       `json:"lines"`
+	Corrections   []OssReportCorrection `json:"corrections"`
+	TotalNetEur   float64               `json:"totalNetEur"`
+	TotalVatEur   float64               `json:"totalVatEur"`
+	Unresolved    []string              `json:"unresolved"`
+}
+
+// OssReportLine sums the supplies to a member state at a rate. Country is the
+// ISO3 code used across the backend, MemberState the two letter code of the
+// return.
+type OssReportLine struct {
+	Country      string  `json:"country"`
+	MemberState  string  `json:"memberState"`
+	Rate         float64 `json:"rate"`
+	NetUsd       float64 `json:"netUsd"`
+	VatUsd       float64 `json:"vatUsd"`
+	NetEur       float64 `json:"netEur"`
+	VatEur       float64 `json:"vatEur"`
+	Transactions int     `json:"transactions"`
+}
+
+// OssReportCorrection reverses the supplies of an earlier quarter credited
+// during the quarter of the report. The amounts are negative and converted at
+// the EUR rate of the quarter they correct.
+type OssReportCorrection struct {
+	Year        int     `json:"year"`
+	Quarter     int     `json:"quarter"`
+	Country     string  `json:"country"`
+	MemberState string  `json:"memberState"`
+	Rate        float64 `json:"rate"`
+	EurRate     float64 `json:"eurRate"`
+	NetUsd      float64 `json:"netUsd"`
+	VatUsd      float64 `json:"vatUsd"`
+	NetEur      float64 `json:"netEur"`
+	VatEur      float64 `json:"vatEur"`
+	CreditNotes int     `json:"creditNotes"`
+}
+
+type ossReportKey struct {
+	country string
+	rate    float64
+}
+
+type ossCorrectionKey struct {
+	year    int
+	quarter int
+	ossReportKey
+}
+
+// GetOssReport builds the OSS return of a quarter from the paid license
+// invoices and the credit notes issued during it. The amounts are converted
+// to EUR at the ECB rate of the last day of the quarter, as art. 366 of
+// Directive 2006/112/EC requires.
+func GetOssReport(year, quarter int) (*OssReport, error) {
+	if quarter < 1 || quarter > 4 || year < 2000 {
+		return nil, fmt.Errorf("%w: %d Q%d", ErrorOssReportInvalidPeriod, year, quarter)
+	}
+	from, to := ossQuarterBounds(year, quarter)
+	// the return of a quarter still running would miss its last payments
+	if to.After(time.Now()) {
+		return nil, fmt.Errorf("%w: %d Q%d has not ended", ErrorOssReportInvalidPeriod, year, quarter)
+	}
+
+	invoices, err := repos.Invoices.GetPaid(from, to)
+	if err != nil {
+		return nil, errors.New("error while retrieving paid invoices: " + err.Error())
+	}
+	engine, err := LoadVatEngine()
+	if err != nil {
+		return nil, err
+	}
+
+	report := &OssReport{
+		Year:        year,
+		Quarter:     quarter,
+		From:        from,
+		To:          to,
+		EurRateDate: to.AddDate(0, 0, -1),
+		Lines:       []OssReportLine{},
+		Corrections: []OssReportCorrection{},
+		Unresolved:  []string{},
+	}
+	lines := make(map[ossReportKey]*OssReportLine)
+	for _, invoice := range invoices {
+		key, ok, err := ossSupplyKey(engine, invoice)
+		if err != nil {
+			return nil, err
+		} else if !ok {
+			continue
+		}
+		if invoice.NumLicenses == nil || invoice.UnitUsdPrice == nil {
+			report.Unresolved = append(report.Unresolved, *invoice.Uuid)
+			continue
+		}
+
+		line := ossReportLine(lines, key)
+		net := float64(*invoice.NumLicenses * *invoice.UnitUsdPrice)
+		line.NetUsd += net
+		line.VatUsd += roundCents(net * key.rate / 100)
+		line.Transactions++
+	}
+
+	creditNotes, err := repos.Invoices.GetCreditNotes(from, to)
+	if err != nil {
+		return nil, errors.New("error while retrieving credit notes: " + err.Error())
+	}
+	corrections := make(map[ossCorrectionKey]*OssReportCorrection)
+	for _, creditNote := range creditNotes {
+		invoice, found, err := repos.Invoices.GetByID(creditNote.InvoiceUuid)
+		if err != nil {
+			return nil, errors.New("error while retrieving credited invoice: " + err.Error())
+		} else if !found || invoice.PaidAt == nil {
+			report.Unresolved = append(report.Unresolved, creditNote.InvoiceUuid)
+			continue
+		}
+		// a reissued invoice holds the corrected buyer, the credit note
+		// reverses the vat of the invoice it credits
+		if creditNote.VatScheme != nil && creditNote.VatRate != nil {
+			invoice.Country, invoice.VatRate, invoice.VatScheme = creditNote.Country, creditNote.VatRate, creditNote.VatScheme
+		}
+		key, ok, err := ossSupplyKey(engine, *invoice)
+		if err != nil {
+			return nil, err
+		} else if !ok {
+			continue
+		}
+		if invoice.NumLicenses == nil || invoice.UnitUsdPrice == nil {
+			report.Unresolved = append(report.Unresolved, creditNote.InvoiceUuid)
+			continue
+		}
+
+		net := -float64(*invoice.NumLicenses * *invoice.UnitUsdPrice)
+		vat := roundCents(net * key.rate / 100)
+		paidYear, paidQuarter := ossQuarterOf(*invoice.PaidAt)
+		if paidYear == year && paidQuarter == quarter {
+			line := ossReportLine(lines, key)
+			line.NetUsd += net
+			line.VatUsd += vat
+			continue
+		}
+
+		correctionKey := ossCorrectionKey{year: paidYear, quarter: paidQuarter, ossReportKey: key}
+		correction, ok := corrections[correctionKey]
+		if !ok {
+			correction = &OssReportCorrection{Year: paidYear, Quarter: paidQuarter, Country: key.country, MemberState: euCountries[key.country], Rate: key.rate}
+			corrections[correctionKey] = correction
+		}
+		correction.NetUsd += net
+		correction.VatUsd += vat
+		correction.CreditNotes++
+	}
+
+	if len(lines) > 0 {
+		report.EurRate, report.EurRateDate, err = ossEurRate(report.EurRateDate)
+		if err != nil {
+			return nil, errors.New("error while retrieving eur rate: " + err.Error())
+		}
+		report.EurRateSource = ossFxProvider.Name()
+	}
+	for _, line := range lines {
+		line.NetUsd, line.VatUsd = roundCents(line.NetUsd), roundCents(line.VatUsd)
+		line.NetEur, line.VatEur = roundCents(line.NetUsd*report.EurRate), roundCents(line.VatUsd*report.EurRate)
+		report.TotalNetEur += line.NetEur
+		report.TotalVatEur += line.VatEur
+		report.Lines = append(report.Lines, *line)
+	}
+
+	eurRates := make(map[[2]int]float64)
+	for key, correction := range corrections {
+		period := [2]int{key.year, key.quarter}
+		eurRate, ok := eurRates[period]
+		if !ok {
+			_, periodTo := ossQuarterBounds(key.year, key.quarter)
+			eurRate, _, err = ossEurRate(periodTo.AddDate(0, 0, -1))
+			if err != nil {
+				return nil, errors.New("error while retrieving eur rate: " + err.Error())
+			}
+			eurRates[period] = eurRate
+		}
+		correction.EurRate = eurRate
+		correction.NetUsd, correction.VatUsd = roundCents(correction.NetUsd), roundCents(correction.VatUsd)
+		correction.NetEur, correction.VatEur = roundCents(correction.NetUsd*eurRate), roundCents(correction.VatUsd*eurRate)
+		report.TotalNetEur += correction.NetEur
+		report.TotalVatEur += correction.VatEur
+		report.Corrections = append(report.Corrections, *correction)
+	}
+	if report.EurRateSource == "" && len(corrections) > 0 {
+		report.EurRateSource = ossFxProvider.Name()
+	}
+
+	report.TotalNetEur, report.TotalVatEur = roundCents(report.TotalNetEur), roundCents(report.TotalVatEur)
+	sort.Slice(report.Lines, func(i, j int) bool {
+		if report.Lines[i].MemberState != report.Lines[j].MemberState {
+			return report.Lines[i].MemberState < report.Lines[j].MemberState
+		}
+		return report.Lines[i].Rate < report.Lines[j].Rate
+	})
+	sort.Slice(report.Corrections, func(i, j int) bool {
+		a, b := report.Corrections[i], report.Corrections[j]
+		if a.Year != b.Year || a.Quarter != b.Quarter {
+			return a.Year < b.Year || a.Year == b.Year && a.Quarter < b.Quarter
+		}
+		if a.MemberState != b.MemberState {
+			return a.MemberState < b.MemberState
+		}
+		return a.Rate < b.Rate
+	})
+	sort.Strings(report.Unresolved)
+	return report, nil
+}
+
+// ossSupplyKey returns the member state and rate an invoice is reported
+// under, false when it is not taxed under OSS. The vat of the invoices stored
+// before it was kept is computed at their payment date.
+func ossSupplyKey(engine *VatEngine, invoice model.InvoiceClient) (ossReportKey, bool, error) {
+	rate, scheme := invoice.VatRate, invoice.VatScheme
+	if rate == nil || scheme == nil {
+		vat, err := engine.License(invoice.Country, invoice.IsCompany, invoice.ReverseCharge, *invoice.PaidAt)
+		if err != nil {
+			return ossReportKey{}, false, errors.New("error while computing vat of invoice " + *invoice.Uuid + ": " + err.Error())
+		}
+		rate, scheme = &vat.Rate, &vat.Scheme
+	}
+	if *scheme != VatSchemeOss {
+		return ossReportKey{}, false, nil
+	}
+	return ossReportKey{country: strings.ToUpper(invoice.Country), rate: *rate}, true, nil
+}
+
+func ossReportLine(lines map[ossReportKey]*OssReportLine, key ossReportKey) *OssReportLine {
+	line, ok := lines[key]
+	if !ok {
+		line = &OssReportLine{Country: key.country, MemberState: euCountries[key.country], Rate: key.rate}
+		lines[key] = line
+	}
+	return line
+}
+
+// ossQuarterBounds returns the first day of a quarter and of the next one.
+func ossQuarterBounds(year, quarter int) (time.Time, time.Time) {
+	from := time.Date(year, time.Month(3*(quarter-1)+1), 1, 0, 0, 0, 0, time.UTC)
+	return from, from.AddDate(0, 3, 0)
+}
+
+func ossQuarterOf(t time.Time) (int, int) {
+	t = t.UTC()
+	return t.Year(), (int(t.Month())-1)/3 + 1
+}
+
+// ossEurRate returns the rate published for lastDay or, when nothing was
+// published that day, the one of the next publication.
+func ossEurRate(lastDay time.Time) (float64, time.Time, error) {
+	for day := lastDay; !day.After(utcDay(time.Now())); day = day.AddDate(0, 0, 1) {
+		published, rates, err := ossFxProvider.Rates(day)
+		if err != nil {
+			return 0, time.Time{}, errors.New("error while fetching " + ossFxProvider.Name() + " rates: " + err.Error())
+		} else if published.Before(lastDay) {
+			continue
+		}
+
+		rate, ok := rates[ossReportCurrency]
+		if !ok || rate == 0 {
+			return 0, time.Time{}, errors.New(ossFxProvider.Name() + " rates of " + published.Format(time.DateOnly) + " miss " + ossReportCurrency)
+		}
+		return rate, published, nil
+	}
+	return 0, time.Time{}, fmt.Errorf("%w: no %s rates published since %s", ErrorFxRateNotFound, ossFxProvider.Name(), lastDay.Format(time.DateOnly))
+}
+
+// BackfillInvoicePaidAt dates the payment of the invoices stored before it
+// was kept at the timestamp of the block of their purchase.
+func BackfillInvoicePaidAt() error {
+	invoices, err := repos.Invoices.GetUndatedPayments()
+	if err != nil {
+		return errors.New("error while retrieving undated invoices: " + err.Error())
+	}
+
+	for _, invoice := range invoices {
+		paidAt, err := GetBlockTime(*invoice.BlockNumber)
+		if err != nil {
+			return errors.New("error while retrieving block time of invoice " + *invoice.Uuid + ": " + err.Error())
+		}
+		invoice.PaidAt = &paidAt
+		err = repos.Invoices.Update(&invoice)
+		if err != nil {
+			return errors.New("error while updating invoice in storage: " + err.Error())
+		}
+	}
+	return nil
+}
+
+func roundCents(amount float64) float64 {
+	return math.Round(amount*100) / 100
+}
+
+func GenerateOssReportCSV(report *OssReport) ([]byte, error) {
+	var csvData strings.Builder
+	writer := csv.NewWriter(&csvData)
+	writer.Comma = ';'
+
+	//separator line
+	if err := writer.Write([]string{"sep=;"}); err != nil {
+		return nil, err
+	}
+
+	header := []string{"Member state", "VAT rate", "Net amount USD", "VAT USD", "Net amount EUR", "VAT due EUR", "Transactions"}
+	if err := writer.Write(header); err != nil {
+		return nil, err
+	}
+	for _, line := range report.Lines {
+		record := []string{
+			line.MemberState,
+			strconv.FormatFloat(line.Rate, 'f', 2, 64),
+			fmt.Sprintf("%.2f", line.NetUsd),
+			fmt.Sprintf("%.2f", line.VatUsd),
+			fmt.Sprintf("%.2f", line.NetEur),
+			fmt.Sprintf("%.2f", line.VatEur),
+			strconv.Itoa(line.Transactions),
+		}
+		if err := writer.Write(record); err != nil {
+			return nil, err
+		}
+	}
+
+	if len(report.Corrections) > 0 {
+		if err := writer.Write([]string{}); err != nil {
+			return nil, err
+		}
+		header := []string{"Corrected period", "Member state", "VAT rate", "Net amount USD", "VAT USD", "EUR rate", "Net amount EUR", "VAT due EUR", "Credit notes"}
+		if err := writer.Write(header); err != nil {
+			return nil, err
+		}
+	}
+	for _, correction := range report.Corrections {
+		record := []string{
+			fmt.Sprintf("%d Q%d", correction.Year, correction.Quarter),
+			correction.MemberState,
+			strconv.FormatFloat(correction.Rate, 'f', 2, 64),
+			fmt.Sprintf("%.2f", correction.NetUsd),
+			fmt.Sprintf("%.2f", correction.VatUsd),
+			fmt.Sprintf("%.4f", correction.EurRate),
+			fmt.Sprintf("%.2f", correction.NetEur),
+			fmt.Sprintf("%.2f", correction.VatEur),
+			strconv.Itoa(correction.CreditNotes),
+		}
+		if err := writer.Write(record); err != nil {
+			return nil, err
+		}
+	}
+
+	//add empty line
+	if err := writer.Write([]string{}); err != nil {
+		return nil, err
+	}
+	totals := [][]string{
+		{"Period:", fmt.Sprintf("%d Q%d", report.Year, report.Quarter)},
+		{"EUR rate:", fmt.Sprintf("1 USD = %.4f EUR on %s (%s)", report.EurRate, report.EurRateDate.Format(time.DateOnly), report.EurRateSource)},
+		{"Total net amount EUR:", fmt.Sprintf("%.2f", report.TotalNetEur)},
+		{"Total VAT due EUR:", fmt.Sprintf("%.2f", report.TotalVatEur)},
+	}
+	if len(report.Unresolved) > 0 {
+		totals = append(totals, []string{"Invoices not reported:", strings.Join(report.Unresolved, ",")})
+	}
+	if err := writer.WriteAll(totals); err != nil {
+		return nil, err
+	}
+
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return nil, err
+	}
+	return []byte(csvData.String()), nil
+}
+
+/* OSS return, the data elements of Annex III of Implementing Regulation (EU) 2020/194 */
+
+// ossSellerVatNumber is the VAT identification number the licenses are sold
+// under, the one the return is filed with in the member state of
+// identification.
+const ossSellerVatNumber = "RO" + model.InvoiceCif
+
+const (
+	ossSupplyTypeServices = "SERVICES"
+	ossVatRateStandard    = "STANDARD"
+)
+
+type ossReturnXml struct {
+	XMLName                 xml.Name            `xml:"OSSReturn"`
+	VatIdentificationNumber string              `xml:"VATIdentificationNumber"`
+	Period                  ossPeriodXml        `xml:"Period"`
+	Currency                string              `xml:"Currency"`
+	MemberStates            []ossMemberStateXml `xml:"MSConsumption"`
+	Corrections             []ossCorrectionXml  `xml:"Corrections"`
+	TotalVatAmount          string              `xml:"TotalVATAmountDue"`
+}
+
+type ossCorrectionXml struct {
+	Period         ossPeriodXml `xml:"Period"`
+	MemberState    string       `xml:"MemberStateOfConsumption"`
+	TotalVatAmount string       `xml:"TotalVATAmountCorrection"`
+}
+
+type ossPeriodXml struct {
+	Year    int `xml:"Year"`
+	Quarter int `xml:"Quarter"`
+}
+
+type ossMemberStateXml struct {
+	MemberState    string         `xml:"MemberStateOfConsumption"`
+	Supplies       []ossSupplyXml `xml:"SupplyFromMSI"`
+	TotalVatAmount string         `xml:"TotalVATAmount"`
+}
+
+type ossSupplyXml struct {
+	SupplyType    string `xml:"SupplyType"`
+	VatRateType   string `xml:"VATRateType"`
+	VatRate       string `xml:"VATRate"`
+	TaxableAmount string `xml:"TaxableAmount"`
+	VatAmount     string `xml:"VATAmount"`
+}
+
+// GenerateOssReportXML exports the report as an OSS return, one
+// MSConsumption per member state with a supply per rate and one Corrections
+// per corrected quarter and member state.
+func GenerateOssReportXML(report *OssReport) ([]byte, error) {
+	doc := ossReturnXml{
+		VatIdentificationNumber: ossSellerVatNumber,
+		Period:                  ossPeriodXml{Year: report.Year, Quarter: report.Quarter},
+		Currency:                ossReportCurrency,
+		MemberStates:            []ossMemberStateXml{},
+		TotalVatAmount:          fmt.Sprintf("%.2f", report.TotalVatEur),
+	}
+
+	total := float64(0)
+	for i, line := range report.Lines {
+		if i == 0 || report.Lines[i-1].MemberState != line.MemberState {
+			if i > 0 {
+				doc.MemberStates[len(doc.MemberStates)-1].TotalVatAmount = fmt.Sprintf("%.2f", roundCents(total))
+			}
+			doc.MemberStates = append(doc.MemberStates, ossMemberStateXml{MemberState: line.MemberState})
+			total = 0
+		}
+		state := &doc.MemberStates[len(doc.MemberStates)-1]
+		state.Supplies = append(state.Supplies, ossSupplyXml{
+			SupplyType:    ossSupplyTypeServices,
+			VatRateType:   ossVatRateStandard,
+			VatRate:       strconv.FormatFloat(line.Rate, 'f', 2, 64),
+			TaxableAmount: fmt.Sprintf("%.2f", line.NetEur),
+			VatAmount:     fmt.Sprintf("%.2f", line.VatEur),
+		})
+		total += line.VatEur
+	}
+	if len(doc.MemberStates) > 0 {
+		doc.MemberStates[len(doc.MemberStates)-1].TotalVatAmount = fmt.Sprintf("%.2f", roundCents(total))
+	}
+
+	total = 0
+	for i, correction := range report.Corrections {
+		total += correction.VatEur
+		if i+1 < len(report.Corrections) {
+			next := report.Corrections[i+1]
+			if next.Year == correction.Year && next.Quarter == correction.Quarter && next.MemberState == correction.MemberState {
+				continue
+			}
+		}
+		doc.Corrections = append(doc.Corrections, ossCorrectionXml{
+			Period:         ossPeriodXml{Year: correction.Year, Quarter: correction.Quarter},
+			MemberState:    correction.MemberState,
+			TotalVatAmount: fmt.Sprintf("%.2f", roundCents(total)),
+		})
+		total = 0
+	}
+
+	body, err := xml.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return nil, errors.New("error while marshalling oss return: " + err.Error())
+	}
+	return append([]byte(xml.Header), body...), nil
+}
@@ -0,0 +1,212 @@
+package service
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/NaeuralEdgeProtocol/ratio1-backend/model"
+	"github.com/NaeuralEdgeProtocol/ratio1-backend/storage/memory"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func createOssTestInvoice(t *testing.T, country string, status string, licenses int, paidAt *time.Time, vatRate *float64, vatScheme string) string {
+	id := strings.ReplaceAll(uuid.NewString(), "-", "")
+	email := "buyer@example.com"
+	price := 500
+	invoice := &model.InvoiceClient{
+		Uuid:         &id,
+		UserEmail:    &email,
+		Country:      country,
+		Status:       &status,
+		NumLicenses:  &licenses,
+		UnitUsdPrice: &price,
+		VatRate:      vatRate,
+		PaidAt:       paidAt,
+	}
+	if vatScheme != "" {
+		invoice.VatScheme = &vatScheme
+	}
+	require.NoError(t, repos.Invoices.Create(invoice))
+	return id
+}
+
+func TestGetOssReportGroupsByMemberStateAndRate(t *testing.T) {
+	previous, previousProvider := GetRepositories(), ossFxProvider
+	SetRepositories(memory.NewRepositories())
+	defer func() {
+		SetRepositories(previous)
+		ossFxProvider = previousProvider
+	}()
+
+	// the rate stored by the daily fx job is not the one of the ecb
+	require.NoError(t, repos.FxRates.Save(model.FxRate{Date: time.Date(2026, 9, 30, 0, 0, 0, 0, time.UTC), Currency: "EUR", Rate: 0.8}))
+	calls := 0
+	ossFxProvider = stubFxProvider{name: "ecb", calls: &calls, days: map[string]map[string]float64{
+		"2026-09-30": {"EUR": 0.9},
+	}}
+	inQuarter := time.Date(2026, 8, 12, 10, 0, 0, 0, time.UTC)
+	beforeQuarter := time.Date(2026, 6, 30, 23, 0, 0, 0, time.UTC)
+	rate := func(rate float64) *float64 { return &rate }
+
+	createOssTestInvoice(t, "DEU", model.InvoiceStatusPaid, 2, &inQuarter, rate(19), VatSchemeOss)
+	createOssTestInvoice(t, "DEU", model.InvoiceStatusPaid, 1, &inQuarter, rate(19), VatSchemeOss)
+	createOssTestInvoice(t, "FRA", model.InvoiceStatusPaid, 1, &inQuarter, rate(20), VatSchemeOss)
+	createOssTestInvoice(t, "ROU", model.InvoiceStatusPaid, 1, &inQuarter, rate(21), VatSchemeDomestic)
+	createOssTestInvoice(t, "DEU", model.InvoiceStatusPaid, 1, &inQuarter, rate(0), VatSchemeReverseCharge)
+	createOssTestInvoice(t, "DEU", model.InvoiceStatusPaid, 1, &beforeQuarter, rate(19), VatSchemeOss)
+	// cancelled after the return was filed, it stays in the quarter it was paid in
+	createOssTestInvoice(t, "DEU", model.InvoiceStatusCancelled, 1, &inQuarter, rate(19), VatSchemeOss)
+
+	// invoices stored before the payment date and the vat were kept
+	block := int64(100)
+	legacy := createOssTestInvoice(t, "SWE", model.InvoiceStatusPaid, 1, nil, nil, "")
+	invoice, _, err := repos.Invoices.GetByID(legacy)
+	require.NoError(t, err)
+	invoice.BlockNumber = &block
+	require.NoError(t, repos.Invoices.Update(invoice))
+	require.NoError(t, repos.Blocks.Save(model.Block{Number: block, Hash: "0xblock", Timestamp: inQuarter}))
+	require.NoError(t, BackfillInvoicePaidAt())
+	createOssTestInvoice(t, "SWE", model.InvoiceStatusPaid, 1, nil, nil, "")
+	unresolved := createOssTestInvoice(t, "SWE", model.InvoiceStatusPaid, 1, &inQuarter, nil, "")
+	invoice, _, err = repos.Invoices.GetByID(unresolved)
+	require.NoError(t, err)
+	invoice.NumLicenses = nil
+	require.NoError(t, repos.Invoices.Update(invoice))
+
+	report, err := GetOssReport(2026, 3)
+	require.NoError(t, err)
+	require.Equal(t, 0.9, report.EurRate)
+	require.Equal(t, "ecb", report.EurRateSource)
+	require.Equal(t, time.Date(2026, 9, 30, 0, 0, 0, 0, time.UTC), report.EurRateDate)
+	require.Equal(t, []OssReportLine{
+		{Country: "DEU", MemberState: "DE", Rate: 19, NetUsd: 2000, VatUsd: 380, NetEur: 1800, VatEur: 342, Transactions: 3},
+		{Country: "FRA", MemberState: "FR", Rate: 20, NetUsd: 500, VatUsd: 100, NetEur: 450, VatEur: 90, Transactions: 1},
+		{Country: "SWE", MemberState: "SE", Rate: 25, NetUsd: 500, VatUsd: 125, NetEur: 450, VatEur: 112.5, Transactions: 1},
+	}, report.Lines)
+	require.Equal(t, float64(2700), report.TotalNetEur)
+	require.Equal(t, 544.5, report.TotalVatEur)
+	require.Empty(t, report.Corrections)
+	require.Equal(t, []string{unresolved}, report.Unresolved)
+
+	csvFile, err := GenerateOssReportCSV(report)
+	require.NoError(t, err)
+	require.Contains(t, string(csvFile), "DE;19.00;2000.00;380.00;1800.00;342.00;3")
+	require.Contains(t, string(csvFile), "Total VAT due EUR:;544.50")
+
+	xmlFile, err := GenerateOssReportXML(report)
+	require.NoError(t, err)
+	require.Contains(t, string(xmlFile), "<VATIdentificationNumber>RO"+model.InvoiceCif+"</VATIdentificationNumber>")
+	require.Contains(t, string(xmlFile), "<MemberStateOfConsumption>FR</MemberStateOfConsumption>")
+	require.Contains(t, string(xmlFile), "<TaxableAmount>1800.00</TaxableAmount>")
+	require.Contains(t, string(xmlFile), "<TotalVATAmountDue>544.50</TotalVATAmountDue>")
+	require.NotContains(t, string(xmlFile), "<Corrections>")
+
+	_, err = GetOssReport(2026, 5)
+	require.ErrorIs(t, err, ErrorOssReportInvalidPeriod)
+	_, err = GetOssReport(time.Now().Year()+1, 1)
+	require.ErrorIs(t, err, ErrorOssReportInvalidPeriod)
+	now := time.Now().UTC()
+	_, err = GetOssReport(now.Year(), (int(now.Month())-1)/3+1)
+	require.ErrorIs(t, err, ErrorOssReportInvalidPeriod)
+}
+
+func TestGetOssReportCorrectsCreditedQuarters(t *testing.T) {
+	previous, previousProvider := GetRepositories(), ossFxProvider
+	SetRepositories(memory.NewRepositories())
+	defer func() {
+		SetRepositories(previous)
+		ossFxProvider = previousProvider
+	}()
+
+	calls := 0
+	ossFxProvider = stubFxProvider{name: "ecb", calls: &calls, days: map[string]map[string]float64{
+		"2026-06-30": {"EUR": 0.8},
+		"2026-09-30": {"EUR": 0.9},
+	}}
+	rate := func(rate float64) *float64 { return &rate }
+	creditNote := func(invoiceUuid, country string, vatRate float64, createdAt time.Time) {
+		scheme := VatSchemeOss
+		require.NoError(t, repos.Invoices.AddStatusChange(&model.InvoiceStatusChange{
+			InvoiceUuid:  invoiceUuid,
+			Status:       model.InvoiceStatusRefunded,
+			DocumentType: model.InvoiceDocumentCreditNote,
+			Country:      country,
+			VatRate:      &vatRate,
+			VatScheme:    &scheme,
+			Actor:        "0xadmin",
+			CreatedAt:    createdAt,
+		}))
+	}
+
+	secondQuarter := time.Date(2026, 5, 10, 10, 0, 0, 0, time.UTC)
+	thirdQuarter := time.Date(2026, 8, 12, 10, 0, 0, 0, time.UTC)
+	// credited in the third quarter and reissued to a french buyer
+	reissued := createOssTestInvoice(t, "FRA", model.InvoiceStatusPaid, 2, &secondQuarter, rate(20), VatSchemeOss)
+	creditNote(reissued, "DEU", 19, thirdQuarter)
+	// credited in the quarter it was paid in
+	refunded := createOssTestInvoice(t, "DEU", model.InvoiceStatusRefunded, 1, &thirdQuarter, rate(19), VatSchemeOss)
+	creditNote(refunded, "DEU", 19, thirdQuarter.AddDate(0, 0, 10))
+	createOssTestInvoice(t, "DEU", model.InvoiceStatusPaid, 1, &thirdQuarter, rate(19), VatSchemeOss)
+
+	report, err := GetOssReport(2026, 2)
+	require.NoError(t, err)
+	require.Equal(t, []OssReportLine{
+		{Country: "FRA", MemberState: "FR", Rate: 20, NetUsd: 1000, VatUsd: 200, NetEur: 800, VatEur: 160, Transactions: 1},
+	}, report.Lines)
+	require.Empty(t, report.Corrections)
+
+	report, err = GetOssReport(2026, 3)
+	require.NoError(t, err)
+	require.Equal(t, []OssReportLine{
+		{Country: "DEU", MemberState: "DE", Rate: 19, NetUsd: 500, VatUsd: 95, NetEur: 450, VatEur: 85.5, Transactions: 2},
+	}, report.Lines)
+	require.Equal(t, []OssReportCorrection{
+		{Year: 2026, Quarter: 2, Country: "DEU", MemberState: "DE", Rate: 19, EurRate: 0.8, NetUsd: -1000, VatUsd: -190, NetEur: -800, VatEur: -152, CreditNotes: 1},
+	}, report.Corrections)
+	require.Equal(t, float64(-350), report.TotalNetEur)
+	require.Equal(t, -66.5, report.TotalVatEur)
+
+	csvFile, err := GenerateOssReportCSV(report)
+	require.NoError(t, err)
+	require.Contains(t, string(csvFile), "2026 Q2;DE;19.00;-1000.00;-190.00;0.8000;-800.00;-152.00;1")
+
+	xmlFile, err := GenerateOssReportXML(report)
+	require.NoError(t, err)
+	require.Contains(t, string(xmlFile), "<Corrections>\n    <Period>\n      <Year>2026</Year>\n      <Quarter>2</Quarter>\n    </Period>\n    <MemberStateOfConsumption>DE</MemberStateOfConsumption>\n    <TotalVATAmountCorrection>-152.00</TotalVATAmountCorrection>")
+}
+
+// ecbFixtureProvider publishes the rates of an eurofxref document.
+type ecbFixtureProvider []byte
+
+func (ecbFixtureProvider) Name() string {
+	return "ecb"
+}
+
+func (p ecbFixtureProvider) Rates(date time.Time) (time.Time, map[string]float64, error) {
+	return parseEcbRates(p, date)
+}
+
+func TestOssEurRateTakesNextEcbPublication(t *testing.T) {
+	previousProvider := ossFxProvider
+	defer func() { ossFxProvider = previousProvider }()
+
+	// 2024 Q1 ends on a Sunday followed by Easter Monday
+	ossFxProvider = ecbFixtureProvider(`<?xml version="1.0" encoding="UTF-8"?>
+<gesmes:Envelope xmlns:gesmes="http://www.gesmes.org/xml/2002-08-01" xmlns="http://www.ecb.int/vocabulary/2002-08-01/eurofxref">
+	<Cube>
+		<Cube time="2024-04-02">
+			<Cube currency="USD" rate="1.0"/>
+		</Cube>
+		<Cube time="2024-03-28">
+			<Cube currency="USD" rate="1.25"/>
+		</Cube>
+	</Cube>
+</gesmes:Envelope>`)
+
+	rate, published, err := ossEurRate(time.Date(2024, 3, 31, 0, 0, 0, 0, time.UTC))
+	require.NoError(t, err)
+	require.Equal(t, float64(1), rate)
+	require.Equal(t, time.Date(2024, 4, 2, 0, 0, 0, 0, time.UTC), published)
+}
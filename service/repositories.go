@@ -0,0 +1,15 @@
+package service
+
+import "github.com/NaeuralEdgeProtocol/ratio1-backend/storage"
+
+var repos = storage.NewGormRepositories()
+
+// SetRepositories replaces the storage used by every service, tests inject an
+// in-memory implementation through it.
+func SetRepositories(r *storage.Repositories) {
+	repos = r
+}
+
+func GetRepositories() *storage.Repositories {
+	return repos
+}
@@ -12,19 +12,25 @@ import (
 	"github.com/NaeuralEdgeProtocol/ratio1-backend/config"
 	"github.com/NaeuralEdgeProtocol/ratio1-backend/model"
 	"github.com/NaeuralEdgeProtocol/ratio1-backend/ratio1abi"
-	"github.com/NaeuralEdgeProtocol/ratio1-backend/storage"
+	"github.com/NaeuralEdgeProtocol/ratio1-backend/rpc"
 	"github.com/ethereum/go-ethereum"
 	"github.com/ethereum/go-ethereum/accounts/abi"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/types"
-	"github.com/ethereum/go-ethereum/crypto"
-	"github.com/ethereum/go-ethereum/ethclient"
 )
 
 const rpcRequestTimeout = 2 * time.Minute
 
+// backfillContext is the context of the chain calls made by the cron jobs,
+// they give way to the calls users are waiting on.
+func backfillContext() context.Context {
+	return rpc.WithPriority(context.Background(), rpc.PriorityBackfill)
+}
+
+// DailyGetStats builds the stats of the blocks indexed since the previous
+// stats, the events themselves are stored by IndexChain.
 func DailyGetStats() {
-	oldStats, err := storage.GetLatestStats()
+	oldStats, err := repos.Stats.GetLatest()
 	if err != nil {
 		fmt.Println("error getting latest stats: " + err.Error())
 		return
@@ -34,6 +40,7 @@ func DailyGetStats() {
 			TotalNdContractTokenBurn: big.NewInt(0),
 			TotalMinted:              big.NewInt(0),
 			TotalPOAIRewards:         big.NewInt(0),
+			TotalPoaiTokenBurn:       big.NewInt(0),
 			LastBlockNumber:          0,
 		}
 	}
@@ -43,26 +50,23 @@ func DailyGetStats() {
 		return
 	}
 
-	cspAddresses, err := getAllCSPAddress() // map[cspAddress]ownerAddress
-	if err != nil {
-		fmt.Println("Error while retrieving csp addresses: " + err.Error())
-		return
-	}
-
 	from := oldStats.LastBlockNumber
 	if from > 0 {
 		from++
 	}
-	to, err := getChainLastBlockNumber()
+	to, err := GetIndexedBlock(model.IndexerStreamAllocations, model.IndexerStreamBurns, model.IndexerStreamTokenTransfers)
 	if err != nil {
-		fmt.Println("error getting last block number: " + err.Error())
+		fmt.Println("error getting indexed block: " + err.Error())
+		return
+	} else if to < from {
+		fmt.Println("No blocks indexed since the latest stats")
 		return
 	}
 
 	/*Fetch all allocation events*/
-	allocEvents, err := fetchAllocationEvents(cspAddresses, from, to)
+	allocEvents, err := repos.Allocations.GetInBlockRange(from, to)
 	if err != nil {
-		fmt.Println("Error fetching events: " + err.Error())
+		fmt.Println("Error fetching allocations: " + err.Error())
 		return
 	}
 
@@ -71,168 +75,39 @@ func DailyGetStats() {
 		return
 	}
 
-	time.Sleep(1 * time.Second)
-
-	/* get all unique nodes and fetch their owner */
-	nodeToOwner := make(map[string]string) // map[nodeAddress]ownerAddress
+	allJobsDetails := make(map[string]*JobDetailsResult)
 	for _, a := range allocEvents {
-		nodeToOwner[a.NodeAddress] = ""
-	}
-	uniqueNodes := make([]string, 0, len(nodeToOwner))
-	for nodeAddr := range nodeToOwner {
-		uniqueNodes = append(uniqueNodes, nodeAddr)
-	}
-
-	nodeToOwner, err = getNodeOwners(uniqueNodes)
-	if err != nil {
-		fmt.Println("Error fetching node owners: " + err.Error())
-		return
-	}
-
-	for i, a := range allocEvents {
-		if owner, ok := nodeToOwner[a.NodeAddress]; ok {
-			allocEvents[i].UserAddress = owner
+		allJobsDetails[a.JobId] = &JobDetailsResult{
+			JobName:     a.JobName,
+			JobType:     int(a.JobType),
+			ProjectName: a.ProjectName,
 		}
 	}
 
 	/*Fetch all burned events */
-	burnEvents, err := fetchBurnEvents(cspAddresses, from, to)
+	burnEvents, err := repos.BurnEvents.GetInBlockRange(from, to)
 	if err != nil {
-		fmt.Println("Error fetching events: " + err.Error())
+		fmt.Println("Error fetching burn events: " + err.Error())
 		return
 	} //if allocation has happened, burn has happened too, so no need to check len(burnEvents)==0
 
-	time.Sleep(1 * time.Second)
-
 	poaiTokenBurn := big.NewInt(0)
 	for _, b := range burnEvents {
 		poaiTokenBurn.Add(poaiTokenBurn, b.GetR1AmountBurned())
 	}
 
-	/* get all blocks timestamp(burned event happen same time as allocation)*/
-	blocks := make(map[int64]*time.Time)
-	for _, a := range allocEvents {
-		blocks[a.BlockNumber] = nil
-	}
-
-	for k := range blocks {
-		v, err := getBlockTimestamp(k)
-		if err != nil {
-			fmt.Println("cannot fetch correct timestamp")
-			return
-		}
-		blocks[k] = &v
-		time.Sleep(1 * time.Second)
-	}
-
-	/* get all jobs details */
-	allJobsDetails := make(map[string]*JobDetailsResult)
-	for _, a := range allocEvents {
-		allJobsDetails[a.JobId] = nil
-	}
-
-	jobIDs := make([]string, 0, len(allJobsDetails))
-	for k := range allJobsDetails {
-		jobIDs = append(jobIDs, k)
-	}
-
-	prevAllocations, err := storage.GetAllocationsByJobIDsForJobDetails(jobIDs)
-	if err != nil {
-		fmt.Println("error getting allocations for job details: " + err.Error())
-		return
-	}
-
-	for k := range allJobsDetails {
-		prevAlloc, ok := prevAllocations[k]
-		if !ok {
-			res, err := GetJobDetails(k, config.Config.DeeployApi)
-			if err != nil {
-				continue
-			}
-			allJobsDetails[k] = res
-		} else {
-			res := JobDetailsResult{
-				JobName:     prevAlloc.JobName,
-				JobType:     int(prevAlloc.JobType),
-				ProjectName: prevAlloc.ProjectName,
-			}
-			allJobsDetails[k] = &res
-		}
-	}
-
-	/* in each allocation, add timestamp and job details */
-	for i, a := range allocEvents {
-		if v := blocks[a.BlockNumber]; v != nil {
-			a.AllocationCreation = *v
-		}
-		if v := allJobsDetails[a.JobId]; v != nil {
-			a.JobName = v.JobName
-			a.JobType = model.JobType(v.JobType)
-			a.ProjectName = v.ProjectName
-		}
-		allocEvents[i] = a
-	}
-
-	/* get all currency*/
-	currencyMap, err := GetFreeCurrencyValues() //map[USD,EUR...]ratio always based 1 usd -> value
-	if err != nil {
-		fmt.Println("could not fetch currency map: ", err.Error())
-		return
-	}
-
-	/* get preferences for eache csp owner*/
-	cspPreferences := make(map[string]*model.Preference) // map[cspOwnerAddress]Preference
-	for _, v := range cspAddresses {
-		preference, err := storage.GetPreferenceByAddress(v)
-		if err != nil || preference == nil {
-			preference = &model.Preference{
-				LocalCurrency: "USD",
-			}
-		}
-		cspPreferences[v] = preference
-	}
-
-	/* in each burn, add timestamp + exchange ratio and preferred currency*/
-	for i, b := range burnEvents {
-		if v := blocks[b.BlockNumber]; v != nil {
-			b.BurnTimestamp = *v
-		}
-		if pref, ok := cspPreferences[b.CspOwner]; ok && pref != nil {
-			b.LocalCurrency = pref.LocalCurrency
-			if ratio, ok := currencyMap[pref.LocalCurrency]; ok {
-				b.ExchangeRatio = ratio
-			}
-		}
-		burnEvents[i] = b
-	}
-
 	/* calculate daily stats */
 	dailyPoaiReward := big.NewInt(0)
 	for _, e := range allocEvents {
 		dailyPoaiReward.Add(dailyPoaiReward, e.GetUsdcAmountPayed()) //no need to assign to dailyPoaiReward
 	}
 
-	dailyMinted, err := getPeriodMintedAmount(from, to)
-	if err != nil {
-		fmt.Println("error getting daily minted: " + err.Error())
-		return
-	}
-
-	time.Sleep(1 * time.Second) // to avoid "429 Too Many Requests" error from infura
-
-	dailyTokenBurn, err := getPeriodBurnedAmount(from, to)
-	if err != nil {
-		fmt.Println("error getting daily token burn: " + err.Error())
-		return
-	}
-
-	dailyNdContractTokenBurn, err := getPeriodNdContractBurnedAmount(from, to)
+	transfers, err := repos.TokenTransfers.GetInBlockRange(from, to)
 	if err != nil {
-		fmt.Println("error getting daily nd contract token burn: " + err.Error())
+		fmt.Println("error getting token transfers: " + err.Error())
 		return
 	}
-
-	time.Sleep(1 * time.Second) // to avoid "429 Too Many Requests" error from infura
+	dailyMinted, dailyTokenBurn, dailyNdContractTokenBurn := sumTokenTransfers(transfers)
 
 	totalSupply, err := getTotalSupply()
 	if err != nil {
@@ -246,8 +121,6 @@ func DailyGetStats() {
 		return
 	}
 
-	time.Sleep(1 * time.Second) // to avoid "429 Too Many Requests" error from infura
-
 	dailyUsdcLocked, err := getDailyUsdcLocked()
 	if err != nil {
 		fmt.Println("error getting daily USDC locked: " + err.Error())
@@ -280,32 +153,18 @@ func DailyGetStats() {
 	}
 
 	//last check due to asynch calls, other server might have finished the job first
-	latestStats, err := storage.GetLatestStats()
+	latestStats, err := repos.Stats.GetLatest()
 	if err != nil {
 		fmt.Println("error getting latest stats: " + err.Error())
 		return
 	}
 
-	if getEpoch(latestStats.CreationTimestamp) == getEpoch(time.Now()) { //get epoch of r1 mainnet, if today is already present, skip.
+	if latestStats != nil && getEpoch(latestStats.CreationTimestamp) == getEpoch(time.Now()) { //get epoch of r1 mainnet, if today is already present, skip.
 		fmt.Println("stats already fetched")
 		return
 	}
 
-	/* store all allocation events */
-	err = generateAllocations(allocEvents)
-	if err != nil {
-		fmt.Println("Error generating allocations: " + err.Error())
-		return
-	}
-
-	/* store all burn events */
-	err = generateBurns(burnEvents)
-	if err != nil {
-		fmt.Println("Error generating burns: " + err.Error())
-		return
-	}
-
-	err = storage.CreateStats(&stats)
+	err = repos.Stats.Create(&stats)
 	if err != nil {
 		fmt.Println("error storing daily stats: " + err.Error())
 		return
@@ -315,13 +174,9 @@ func DailyGetStats() {
 }
 
 func getChainLastBlockNumber() (int64, error) {
-	client, err := ethclient.Dial(config.Config.Infura.ApiUrl + config.Config.Infura.Secret)
-	if err != nil {
-		return 0, errors.New("error while dialing client")
-	}
-	defer client.Close()
+	client := rpc.Get()
 
-	ctx, cancel := context.WithTimeout(context.Background(), rpcRequestTimeout)
+	ctx, cancel := context.WithTimeout(backfillContext(), rpcRequestTimeout)
 	defer cancel()
 	header, err := client.HeaderByNumber(ctx, nil)
 	if err != nil {
@@ -338,11 +193,7 @@ func getDailyUsdcLocked() (*big.Int, error) {
 		return big.NewInt(0), errors.New("error while parsing abi: " + err.Error())
 	}
 
-	client, err := ethclient.Dial(config.Config.Infura.ApiUrl + config.Config.Infura.Secret)
-	if err != nil {
-		return big.NewInt(0), errors.New("error while dialing client")
-	}
-	defer client.Close()
+	client := rpc.Get()
 
 	// Pack getTotalEscrowsBalance call
 	balanceData, err := parsedABI.Pack("getTotalEscrowsBalance")
@@ -355,7 +206,7 @@ func getDailyUsdcLocked() (*big.Int, error) {
 		Data: balanceData,
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), rpcRequestTimeout)
+	ctx, cancel := context.WithTimeout(backfillContext(), rpcRequestTimeout)
 	defer cancel()
 	result, err := client.CallContract(ctx, msg, nil)
 	if err != nil {
@@ -379,11 +230,7 @@ func getDailyActiveJobs() (int, error) {
 		return 0, errors.New("error while parsing abi: " + err.Error())
 	}
 
-	client, err := ethclient.Dial(config.Config.Infura.ApiUrl + config.Config.Infura.Secret)
-	if err != nil {
-		return 0, errors.New("error while dialing client: " + err.Error())
-	}
-	defer client.Close()
+	client := rpc.Get()
 
 	jobIdPack, err := parsedABI.Pack("getActiveJobsCount")
 	if err != nil {
@@ -395,7 +242,7 @@ func getDailyActiveJobs() (int, error) {
 		Data: jobIdPack,
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), rpcRequestTimeout)
+	ctx, cancel := context.WithTimeout(backfillContext(), rpcRequestTimeout)
 	defer cancel()
 	result, err := client.CallContract(ctx, msg, nil)
 	if err != nil {
@@ -430,13 +277,9 @@ func getAllCSPAddress() (map[string]string, error) { // map[cspAddress]ownerAddr
 		Data: data,
 	}
 
-	client, err := ethclient.Dial(config.Config.Infura.ApiUrl + config.Config.Infura.Secret)
-	if err != nil {
-		return nil, errors.New("error while dialing client")
-	}
-	defer client.Close()
+	client := rpc.Get()
 
-	ctx, cancel := context.WithTimeout(context.Background(), rpcRequestTimeout)
+	ctx, cancel := context.WithTimeout(backfillContext(), rpcRequestTimeout)
 	defer cancel()
 	result, err := client.CallContract(ctx, msg, nil)
 	if err != nil {
@@ -461,47 +304,6 @@ func getAllCSPAddress() (map[string]string, error) { // map[cspAddress]ownerAddr
 	return cspOwners, nil
 }
 
-func fetchAllocationEvents(cspOwners map[string]string, from, to int64) ([]model.Allocation, error) {
-	var addresses []common.Address
-	for k := range cspOwners {
-		addresses = append(addresses, common.HexToAddress(k))
-	}
-
-	eventSignatureAsBytes := []byte(ratio1abi.AllocationEventSignature)
-	eventHash := crypto.Keccak256Hash(eventSignatureAsBytes)
-
-	query := ethereum.FilterQuery{
-		Addresses: addresses,
-		Topics:    [][]common.Hash{{eventHash}},
-	}
-
-	client, err := ethclient.Dial(config.Config.Infura.ApiUrl + config.Config.Infura.Secret)
-	if err != nil {
-		return nil, errors.New("error while dialing client: " + err.Error())
-	}
-	defer client.Close()
-
-	ctx, cancel := context.WithTimeout(context.Background(), rpcRequestTimeout)
-	defer cancel()
-	logs, err := filterLogsInChunks(ctx, client, query, from, to)
-	if err != nil {
-		return nil, errors.New("error while filtering logs: " + err.Error())
-	}
-
-	var events []model.Allocation
-	for _, vLog := range logs {
-		event, err := decodeAllocLogs(vLog)
-		if err != nil {
-			fmt.Println("error while decoding logs: " + err.Error())
-			continue
-		}
-		event.CspOwner = cspOwners[event.CspAddress]
-		events = append(events, *event)
-	}
-
-	return events, nil
-}
-
 func decodeAllocLogs(vLog types.Log) (*model.Allocation, error) {
 	parsedABI, err := abi.JSON(strings.NewReader(ratio1abi.AllocationLogsAbi))
 	if err != nil {
@@ -538,47 +340,6 @@ func decodeAllocLogs(vLog types.Log) (*model.Allocation, error) {
 	return &result, nil
 }
 
-func fetchBurnEvents(cspOwners map[string]string, from, to int64) ([]model.BurnEvent, error) {
-	var addresses []common.Address
-	for k := range cspOwners {
-		addresses = append(addresses, common.HexToAddress(k))
-	}
-
-	eventSignatureAsBytes := []byte(ratio1abi.BurnEventSignature)
-	eventHash := crypto.Keccak256Hash(eventSignatureAsBytes)
-
-	query := ethereum.FilterQuery{
-		Addresses: addresses,
-		Topics:    [][]common.Hash{{eventHash}},
-	}
-
-	client, err := ethclient.Dial(config.Config.Infura.ApiUrl + config.Config.Infura.Secret)
-	if err != nil {
-		return nil, errors.New("error while dialing client: " + err.Error())
-	}
-	defer client.Close()
-
-	ctx, cancel := context.WithTimeout(context.Background(), rpcRequestTimeout)
-	defer cancel()
-	logs, err := filterLogsInChunks(ctx, client, query, from, to)
-	if err != nil {
-		return nil, errors.New("error while filtering logs: " + err.Error())
-	}
-
-	var events []model.BurnEvent
-	for _, vLog := range logs {
-		event, err := decodeBurnLogs(vLog)
-		if err != nil {
-			fmt.Println("error while decoding logs: " + err.Error())
-			continue
-		}
-		event.CspOwner = cspOwners[event.CspAddress]
-		events = append(events, *event)
-	}
-
-	return events, nil
-}
-
 func decodeBurnLogs(vLog types.Log) (*model.BurnEvent, error) {
 	parsedABI, err := abi.JSON(strings.NewReader(ratio1abi.BurnLogsAbi))
 	if err != nil {
@@ -595,9 +356,11 @@ func decodeBurnLogs(vLog types.Log) (*model.BurnEvent, error) {
 		return nil, errors.New("error while unpacking interface: " + err.Error())
 	}
 
+	logIndex := vLog.Index
 	result := model.BurnEvent{
 		CspAddress:  vLog.Address.String(),
 		TxHash:      vLog.TxHash.Hex(),
+		LogIndex:    &logIndex,
 		BlockNumber: int64(vLog.BlockNumber),
 	}
 	result.SetUsdcAmountSwapped(event.UsdcAmount)
@@ -605,26 +368,9 @@ func decodeBurnLogs(vLog types.Log) (*model.BurnEvent, error) {
 	return &result, nil
 }
 
-func getBlockTimestamp(blockNumber int64) (time.Time, error) {
-	client, err := ethclient.Dial(config.Config.Infura.ApiUrl + config.Config.Infura.Secret)
-	if err != nil {
-		return time.Time{}, errors.New("error while dialing client")
-	}
-	defer client.Close()
-
-	ctx, cancel := context.WithTimeout(context.Background(), rpcRequestTimeout)
-	defer cancel()
-	header, err := client.HeaderByNumber(ctx, big.NewInt(blockNumber))
-	if err != nil {
-		return time.Time{}, errors.New("error while retrieving block: " + err.Error())
-	}
-
-	return time.Unix(int64(header.Time), 0).UTC(), nil
-}
-
 func generateAllocations(allocEevents []model.Allocation) error {
 	for _, event := range allocEevents {
-		err := storage.CreateAllocation(&event)
+		err := repos.Allocations.Create(&event)
 		if err != nil {
 			return errors.New("error while saving allocation: " + err.Error())
 		}
@@ -633,7 +379,7 @@ func generateAllocations(allocEevents []model.Allocation) error {
 }
 func generateBurns(burnEvents []model.BurnEvent) error {
 	for _, event := range burnEvents {
-		err := storage.CreateBurnEvent(&event)
+		err := repos.BurnEvents.Create(&event)
 		if err != nil {
 			return errors.New("error while saving Burn events: " + err.Error())
 		}
@@ -669,13 +415,9 @@ func getNodeOwners(nodes []string) (map[string]string, error) { // map[nodeAddre
 		Data: data,
 	}
 
-	client, err := ethclient.Dial(config.Config.Infura.ApiUrl + config.Config.Infura.Secret)
-	if err != nil {
-		return nil, errors.New("error while dialing client")
-	}
-	defer client.Close()
+	client := rpc.Get()
 
-	ctx, cancel := context.WithTimeout(context.Background(), rpcRequestTimeout)
+	ctx, cancel := context.WithTimeout(backfillContext(), rpcRequestTimeout)
 	defer cancel()
 	result, err := client.CallContract(ctx, msg, nil)
 	if err != nil {
@@ -7,7 +7,9 @@ import (
 	"testing"
 
 	"github.com/NaeuralEdgeProtocol/ratio1-backend/config"
+	"github.com/NaeuralEdgeProtocol/ratio1-backend/model"
 	"github.com/NaeuralEdgeProtocol/ratio1-backend/ratio1abi"
+	"github.com/NaeuralEdgeProtocol/ratio1-backend/storage/memory"
 	"github.com/ethereum/go-ethereum/accounts/abi"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/types"
@@ -18,6 +20,63 @@ func Test_GetDailyStats(t *testing.T) {
 	DailyGetStats() //MAKE SURE TO HAVE A DB CONNECTED
 }
 
+const (
+	testCspAddress  = "0x0b81c24153bFbB2C98813da8Ac0EF7e8b83Ba389"
+	testCspOwner    = "0x00000000000000000000000000000000000000c1"
+	testNodeAddress = "0x3795d06dcd5cb35E25E669978e51c1C60c5105bC"
+	testNodeOwner   = "0x00000000000000000000000000000000000000b1"
+	testNdContract  = "0x00000000000000000000000000000000000000d1"
+)
+
+// withStatsPipeline points the contracts used by the indexer and the daily
+// stats at the addresses of the recorded fixtures.
+func withStatsPipeline(t *testing.T) {
+	previousRepos := GetRepositories()
+	previousConfig := config.Config
+	SetRepositories(memory.NewRepositories())
+	config.Config.Indexer = config.IndexerConfig{Confirmations: 10}
+	config.Config.R1ContractAddress = testR1Contract
+	config.Config.NDContractAddress = testNdContract
+	config.Config.PoaiManagerAddress = "0x00000000000000000000000000000000000000e1"
+	config.Config.ReaderAddress = "0x00000000000000000000000000000000000000f1"
+	config.Config.TeamAddresses = []string{"0x00000000000000000000000000000000000000a2"}
+	config.Config.DeeployApi = "http://127.0.0.1:1"
+	t.Cleanup(func() {
+		SetRepositories(previousRepos)
+		config.Config = previousConfig
+	})
+}
+
+func TestDailyStatsPipelineReplaysRecordedChain(t *testing.T) {
+	withStatsPipeline(t)
+	withRpcFixtures(t, "daily_stats.json")
+
+	IndexChain()
+	purchases, err := repos.LicensePurchases.GetByStatus(model.LicensePurchaseStatusPending)
+	require.NoError(t, err)
+	require.Len(t, purchases, 1)
+	require.Equal(t, int64(700), purchases[0].BlockNumber)
+	require.Equal(t, 1, purchases[0].NumLicenses)
+
+	allocations, err := repos.Allocations.GetInBlockRange(0, 990)
+	require.NoError(t, err)
+	require.Len(t, allocations, 1)
+	require.Equal(t, common.HexToAddress(testNodeOwner).String(), allocations[0].UserAddress)
+	require.Equal(t, int64(1750001000), allocations[0].AllocationCreation.Unix())
+
+	DailyGetStats()
+	stats, err := repos.Stats.GetLatest()
+	require.NoError(t, err)
+	require.NotNil(t, stats)
+	require.Equal(t, int64(990), stats.LastBlockNumber)
+	require.Equal(t, int64(318750), stats.DailyPOAIRewards.Int64())
+	require.Equal(t, int64(50), stats.DailyMinted.Int64())
+	require.Equal(t, int64(20), stats.DailyTokenBurn.Int64())
+	require.Equal(t, int64(5_000_000), stats.DailyUsdcLocked.Int64())
+	require.Equal(t, 3, stats.DailyActiveJobs)
+	require.Equal(t, "100000000000000000000", stats.TeamWalletsSupply.String())
+}
+
 func Test_GetDailyUsdcLocked(t *testing.T) {
 	config.Config.Infura.Secret = "533c2b6ac99b4f11b513d25cfb5dffd1" //test secret, test use only
 	config.Config.Infura.ApiUrl = "https://base-mainnet.infura.io/v3/"
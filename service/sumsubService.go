@@ -15,7 +15,6 @@ import (
 
 	"github.com/NaeuralEdgeProtocol/ratio1-backend/config"
 	"github.com/NaeuralEdgeProtocol/ratio1-backend/model"
-	"github.com/NaeuralEdgeProtocol/ratio1-backend/storage"
 )
 
 type InitSessionResponse struct {
@@ -121,7 +120,7 @@ func ProcessKycEvent(event model.SumsubEvent, kyc model.Kyc, userAddress string)
 		kyc.KycStatus = status
 	}
 
-	err = storage.CreateOrUpdateKyc(&kyc)
+	err = repos.Kycs.CreateOrUpdate(&kyc)
 	if err != nil {
 		return errors.New("error while updateing kyc information on storage: " + err.Error())
 	}
@@ -144,7 +143,7 @@ func createOrUpdateApprovedUserInfo(kyc *model.Kyc, userAddress string) error {
 
 	userInfo.BlockchainAddress = userAddress
 	userInfo.Email = kyc.Email
-	err = storage.CreateOrUpdateUserInfo(userInfo)
+	err = repos.UserInfos.CreateOrUpdate(userInfo)
 	if err != nil {
 		return errors.New("error while creating or updating userinfo: " + err.Error())
 	}
@@ -167,11 +166,9 @@ func fetchUserInfo(kyc *model.Kyc) (*model.UserInfo, error) {
 
 	kyc.Country = client.Country
 	if client.IsCompany {
-		ReverseCharge, IsUe := IsCompanyRegisteredAndUE(client.Country, client.IdentificationCode)
-		if ReverseCharge && IsUe {
-			kyc.ViesRegistered = true
-		} else {
-			kyc.ViesRegistered = false
+		kyc.ViesRegistered, err = viesRegistered(client.Country, client.IdentificationCode)
+		if err != nil {
+			return nil, err
 		}
 	} else {
 		kyc.ViesRegistered = false
@@ -180,6 +177,20 @@ func fetchUserInfo(kyc *model.Kyc) (*model.UserInfo, error) {
 	return client, nil
 }
 
+// viesRegistered reports whether a company is registered in VIES. While VIES
+// is unavailable the company is taken as not registered, RevalidateVatNumbers
+// corrects it once VIES answers again.
+func viesRegistered(country, vatNumber string) (bool, error) {
+	check, err := ValidateVatNumber(country, vatNumber)
+	if errors.Is(err, ErrorViesUnavailable) {
+		log.Warn("vat number " + vatNumber + " stored as not registered: " + err.Error())
+		return false, nil
+	} else if err != nil {
+		return false, errors.New("error while validating vat number: " + err.Error())
+	}
+	return check != nil && check.Valid, nil
+}
+
 func InitNewSession(uuid, level string) (*string, error) {
 	payloadAsString := "{\"ttlInSecs\":600,\"levelName\":\"" + level + "\",\"userId\":\"" + uuid + "\"}"
 	payload := strings.NewReader(payloadAsString)
@@ -1,7 +1,6 @@
 package service
 
 import (
-	"context"
 	"errors"
 	"math/big"
 	"strconv"
@@ -9,11 +8,11 @@ import (
 
 	"github.com/NaeuralEdgeProtocol/ratio1-backend/config"
 	"github.com/NaeuralEdgeProtocol/ratio1-backend/ratio1abi"
+	"github.com/NaeuralEdgeProtocol/ratio1-backend/rpc"
 	"github.com/ethereum/go-ethereum"
 	"github.com/ethereum/go-ethereum/accounts/abi"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/crypto"
-	"github.com/ethereum/go-ethereum/ethclient"
 )
 
 func GetAmountAsFloatString(amount *big.Int, decimals int) string {
@@ -55,11 +54,7 @@ func CalcCircSupply(teamSupply, totalSupply string) string {
 
 func getPeriodMintedAmount(from, to int64) (*big.Int, error) {
 	tokenAddress := common.HexToAddress(config.Config.R1ContractAddress)
-	client, err := ethclient.Dial(config.Config.Infura.ApiUrl + config.Config.Infura.Secret)
-	if err != nil {
-		return big.NewInt(0), errors.New("error while dialing client")
-	}
-	defer client.Close()
+	client := rpc.Get()
 
 	transferEventSignature := []byte(ratio1abi.TransferEventSignature)
 	transferEventSigHash := crypto.Keccak256Hash(transferEventSignature)
@@ -78,7 +73,7 @@ func getPeriodMintedAmount(from, to int64) (*big.Int, error) {
 		},
 	}
 
-	mintedLogs, err := filterLogsInChunks(context.Background(), client, mintedQuery, from, to)
+	mintedLogs, err := filterLogsInChunks(backfillContext(), client, mintedQuery, from, to)
 	if err != nil {
 		return big.NewInt(0), errors.New("error while filtering minted logs: " + err.Error())
 	}
@@ -102,11 +97,7 @@ func getPeriodMintedAmount(from, to int64) (*big.Int, error) {
 
 func getPeriodBurnedAmount(from, to int64) (*big.Int, error) {
 	tokenAddress := common.HexToAddress(config.Config.R1ContractAddress)
-	client, err := ethclient.Dial(config.Config.Infura.ApiUrl + config.Config.Infura.Secret)
-	if err != nil {
-		return big.NewInt(0), errors.New("error while dialing client: " + err.Error())
-	}
-	defer client.Close()
+	client := rpc.Get()
 
 	transferEventSignature := []byte(ratio1abi.TransferEventSignature)
 	transferEventSigHash := crypto.Keccak256Hash(transferEventSignature)
@@ -126,7 +117,7 @@ func getPeriodBurnedAmount(from, to int64) (*big.Int, error) {
 		},
 	}
 
-	burnedLogs, err := filterLogsInChunks(context.Background(), client, burnedQuery, from, to)
+	burnedLogs, err := filterLogsInChunks(backfillContext(), client, burnedQuery, from, to)
 	if err != nil {
 		return big.NewInt(0), errors.New("error while filtering burned logs: " + err.Error())
 	}
@@ -150,11 +141,7 @@ func getPeriodBurnedAmount(from, to int64) (*big.Int, error) {
 
 func getPeriodNdContractBurnedAmount(from, to int64) (*big.Int, error) {
 	tokenAddress := common.HexToAddress(config.Config.R1ContractAddress)
-	client, err := ethclient.Dial(config.Config.Infura.ApiUrl + config.Config.Infura.Secret)
-	if err != nil {
-		return big.NewInt(0), errors.New("error while dialing client: " + err.Error())
-	}
-	defer client.Close()
+	client := rpc.Get()
 
 	transferEventSignature := []byte(ratio1abi.TransferEventSignature)
 	transferEventSigHash := crypto.Keccak256Hash(transferEventSignature)
@@ -177,7 +164,7 @@ func getPeriodNdContractBurnedAmount(from, to int64) (*big.Int, error) {
 		},
 	}
 
-	burnedLogs, err := filterLogsInChunks(context.Background(), client, burnedQuery, from, to)
+	burnedLogs, err := filterLogsInChunks(backfillContext(), client, burnedQuery, from, to)
 	if err != nil {
 		return big.NewInt(0), errors.New("error while filtering burned logs: " + err.Error())
 	}
@@ -218,13 +205,9 @@ func getTotalSupply() (*big.Int, error) {
 		Data: data,
 	}
 
-	client, err := ethclient.Dial(config.Config.Infura.ApiUrl + config.Config.Infura.Secret)
-	if err != nil {
-		return big.NewInt(0), errors.New("error while dialing client: " + err.Error())
-	}
-	defer client.Close()
+	client := rpc.Get()
 
-	result, err := client.CallContract(context.Background(), msg, nil)
+	result, err := client.CallContract(backfillContext(), msg, nil)
 	if err != nil {
 		return big.NewInt(0), errors.New("error while calling contract: " + err.Error())
 	}
@@ -246,11 +229,7 @@ func getTeamWalletsSupply() (*big.Int, error) {
 		return big.NewInt(0), errors.New("error while parsing abi: " + err.Error())
 	}
 
-	client, err := ethclient.Dial(config.Config.Infura.ApiUrl + config.Config.Infura.Secret)
-	if err != nil {
-		return big.NewInt(0), errors.New("error while dialing client: " + err.Error())
-	}
-	defer client.Close()
+	client := rpc.Get()
 
 	var totalTeamBalance = big.NewInt(0)
 
@@ -268,7 +247,7 @@ func getTeamWalletsSupply() (*big.Int, error) {
 			Data: balanceData,
 		}
 
-		result, err := client.CallContract(context.Background(), msg, nil)
+		result, err := client.CallContract(backfillContext(), msg, nil)
 		if err != nil {
 			return big.NewInt(0), errors.New("error calling balanceOf for " + addrStr)
 		}
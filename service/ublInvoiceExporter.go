@@ -0,0 +1,596 @@
+package service
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/NaeuralEdgeProtocol/ratio1-backend/model"
+	"golang.org/x/text/language"
+)
+
+const (
+	ublInvoiceNamespace = "urn:oasis:names:specification:ubl:schema:xsd:Invoice-2"
+	ublCacNamespace     = "urn:oasis:names:specification:ubl:schema:xsd:CommonAggregateComponents-2"
+	ublCbcNamespace     = "urn:oasis:names:specification:ubl:schema:xsd:CommonBasicComponents-2"
+
+	// ublCustomizationID is the EN 16931 specification identifier, restricted
+	// by the romanian national profile for e-Factura.
+	ublCustomizationID = "urn:cen.eu:en16931:2017#compliant#urn:efactura.mfinante.ro:CIUS-RO:1.0.1"
+
+	// ublInvoiceTypeCode is the UNTDID 1001 code of a commercial invoice.
+	ublInvoiceTypeCode = "380"
+	// ublUnitCode is the UN/ECE rec 20 code of a unit.
+	ublUnitCode = "C62"
+	// ublDocumentCurrency is the currency the allocations are paid in, USDC
+	// is invoiced as USD.
+	ublDocumentCurrency = "USD"
+)
+
+// VAT category codes, UNTDID 5305.
+const (
+	ublVatStandard      = "S"
+	ublVatExempt        = "E"
+	ublVatReverseCharge = "AE"
+	ublVatExport        = "G"
+	ublVatNotSubject    = "O"
+)
+
+var ErrorUblMissingData = errors.New("invoice draft misses data required by the e-invoice")
+
+// ublVatCategory is the VAT treatment of the draft, exemption is set for the
+// categories without VAT.
+type ublVatCategory struct {
+	id              string
+	percent         float64
+	exemptionCode   string
+	exemptionReason string
+}
+
+// ublVatCategoryFor returns the VAT category the engine decided for the
+// draft. Drafts generated before it get one picked from the VAT applied and
+// from where the parties are: private sellers are not subject to VAT,
+// services to companies of other EU countries are reverse charged and the
+// ones to buyers outside the EU are exports.
+func ublVatCategoryFor(invoice model.InvoiceDraft, seller, buyer model.UserInfo) ublVatCategory {
+	switch invoice.VatCategory {
+	case VatCategoryStandard:
+		return ublVatCategory{id: ublVatStandard, percent: invoice.VatApplied}
+	case VatCategoryReverseCharge:
+		return ublVatCategory{id: ublVatReverseCharge, exemptionCode: "VATEX-EU-AE", exemptionReason: "Reverse charge"}
+	case VatCategoryNotSubject:
+		return ublVatCategory{id: ublVatNotSubject, exemptionCode: "VATEX-EU-O", exemptionReason: "Not subject to VAT"}
+	}
+
+	switch {
+	case invoice.VatApplied > 0:
+		return ublVatCategory{id: ublVatStandard, percent: invoice.VatApplied}
+	case !seller.IsCompany:
+		return ublVatCategory{id: ublVatNotSubject, exemptionCode: "VATEX-EU-O", exemptionReason: "Not subject to VAT"}
+	case buyer.IsCompany && isUeCountry(buyer.Country) && !strings.EqualFold(buyer.Country, seller.Country):
+		return ublVatCategory{id: ublVatReverseCharge, exemptionCode: "VATEX-EU-AE", exemptionReason: "Reverse charge"}
+	case !isUeCountry(buyer.Country):
+		return ublVatCategory{id: ublVatExport, exemptionCode: "VATEX-EU-G", exemptionReason: "Export outside the EU"}
+	default:
+		return ublVatCategory{id: ublVatExempt, exemptionReason: "Exempt from VAT"}
+	}
+}
+
+// ExportInvoiceDraftUbl returns the draft as an UBL 2.1 invoice following
+// EN 16931 and CIUS-RO. Amounts are in USD, the VAT total is repeated in the
+// local currency of the draft when it is another one.
+func ExportInvoiceDraftUbl(invoice model.InvoiceDraft, allocations []model.Allocation) ([]byte, error) {
+	seller, buyer := invoice.Seller(), invoice.Buyer()
+	sellerParty, err := ublPartyFor(seller)
+	if err != nil {
+		return nil, fmt.Errorf("seller: %w", err)
+	}
+	buyerParty, err := ublPartyFor(buyer)
+	if err != nil {
+		return nil, fmt.Errorf("buyer: %w", err)
+	}
+
+	category := ublVatCategoryFor(invoice, seller, buyer)
+	if category.id == ublVatNotSubject {
+		// BR-O-02, BR-O-03: no VAT identifiers on invoices not subject to VAT
+		sellerParty.PartyTaxScheme = nil
+		buyerParty.PartyTaxScheme = nil
+	} else if sellerParty.PartyTaxScheme == nil {
+		return nil, fmt.Errorf("%w: seller VAT identifier", ErrorUblMissingData)
+	}
+	if category.id == ublVatReverseCharge && buyerParty.PartyTaxScheme == nil {
+		return nil, fmt.Errorf("%w: buyer VAT identifier", ErrorUblMissingData)
+	}
+
+	ratio := invoice.LocalCurrencyExchangeRatio
+	if ratio <= 0 {
+		ratio = 1
+	}
+
+	// the draft total is gross: net + VAT + extra taxes, the extra taxes are
+	// outside the VAT base so they are charged with an exempt category
+	extras, _ := invoice.GetExtraTaxes()
+	fixedCents, extraPercent := int64(0), 0.0
+	for _, e := range extras {
+		switch e.TaxType {
+		case model.Fixed:
+			fixedCents += toCents(e.Value / ratio)
+		case model.Percentage:
+			extraPercent += e.Value
+		}
+	}
+	totalCents := toCents(invoice.TotalUsdcAmount)
+	netCents := int64(0)
+	if den := 1 + category.percent/100 + extraPercent/100; totalCents > fixedCents {
+		netCents = int64(math.Round(float64(totalCents-fixedCents) / den))
+	}
+
+	extraCategory := ublVatCategory{id: ublVatExempt, exemptionReason: "Exempt from VAT"}
+	if category.id == ublVatNotSubject || category.id == ublVatExempt {
+		extraCategory = category
+	}
+	var charges []ublAllowanceCharge
+	chargeCents := int64(0)
+	for _, e := range extras {
+		cents := toCents(e.Value / ratio)
+		if e.TaxType == model.Percentage {
+			cents = int64(math.Round(float64(netCents) * e.Value / 100))
+		}
+		chargeCents += cents
+		charges = append(charges, ublAllowanceCharge{
+			ChargeIndicator: true,
+			Reason:          nonEmptyOr("Extra tax", e.Description),
+			Amount:          ublMoney(cents),
+			TaxCategory:     ublTaxCategoryFor(extraCategory),
+		})
+	}
+
+	lines := ublInvoiceLines(allocations, netCents, category)
+
+	vatCents := int64(math.Round(float64(netCents) * category.percent / 100))
+	subtotals := []ublTaxSubtotal{{
+		TaxableAmount: ublMoney(netCents),
+		TaxAmount:     ublMoney(vatCents),
+		TaxCategory:   ublTaxCategoryFor(category),
+	}}
+	if len(charges) > 0 {
+		if extraCategory.id == category.id {
+			subtotals[0].TaxableAmount = ublMoney(netCents + chargeCents)
+		} else {
+			subtotals = append(subtotals, ublTaxSubtotal{
+				TaxableAmount: ublMoney(chargeCents),
+				TaxAmount:     ublMoney(0),
+				TaxCategory:   ublTaxCategoryFor(extraCategory),
+			})
+		}
+	}
+
+	taxExclusive := netCents + chargeCents
+	taxInclusive := taxExclusive + vatCents
+	rounding := totalCents - taxInclusive
+	monetaryTotal := ublMonetaryTotal{
+		LineExtensionAmount: ublMoney(netCents),
+		TaxExclusiveAmount:  ublMoney(taxExclusive),
+		TaxInclusiveAmount:  ublMoney(taxInclusive),
+		PrepaidAmount:       ublMoney(totalCents),
+		PayableAmount:       ublMoney(0),
+	}
+	if len(charges) > 0 {
+		total := ublMoney(chargeCents)
+		monetaryTotal.ChargeTotalAmount = &total
+	}
+	if rounding != 0 {
+		amount := ublMoney(rounding)
+		monetaryTotal.PayableRoundingAmount = &amount
+	}
+
+	notes := []string{"Paid on-chain in USDC, allocations settled through the Ratio1 protocol"}
+	if category.id == ublVatReverseCharge {
+		notes = append(notes, "Reverse charge - Taxare inversa")
+	}
+	if notesText := safe(invoice.ExtraText); strings.TrimSpace(notesText) != "" {
+		notes = append(notes, notesText)
+	}
+
+	taxTotals := []ublTaxTotal{{TaxAmount: ublMoney(vatCents), TaxSubtotal: subtotals}}
+	document := ublInvoice{
+		XmlnsCac:             ublCacNamespace,
+		XmlnsCbc:             ublCbcNamespace,
+		Xmlns:                ublInvoiceNamespace,
+		CustomizationID:      ublCustomizationID,
+		ID:                   ublInvoiceID(invoice),
+		IssueDate:            invoice.CreationTimestamp.UTC().Format("2006-01-02"),
+		InvoiceTypeCode:      ublInvoiceTypeCode,
+		Notes:                notes,
+		DocumentCurrencyCode: ublDocumentCurrency,
+		BuyerReference:       invoice.DraftId.String(),
+		Supplier:             ublPartyWrapper{Party: sellerParty},
+		Customer:             ublPartyWrapper{Party: buyerParty},
+		AllowanceCharges:     charges,
+		LegalMonetaryTotal:   monetaryTotal,
+		InvoiceLines:         lines,
+	}
+
+	localCurrency := strings.ToUpper(strings.TrimSpace(invoice.LocalCurrency))
+	if localCurrency != "" && localCurrency != ublDocumentCurrency {
+		// BR-53: the VAT total is also given in the tax currency
+		document.TaxCurrencyCode = localCurrency
+		document.Notes = append(document.Notes, "Exchange rate: 1 "+ublDocumentCurrency+" = "+strconv.FormatFloat(ratio, 'f', -1, 64)+" "+localCurrency)
+		taxTotals = append(taxTotals, ublTaxTotal{TaxAmount: ublAmount{Currency: localCurrency, Value: formatCents(int64(math.Round(float64(vatCents) * ratio)))}})
+	}
+	document.TaxTotals = taxTotals
+
+	out, err := xml.MarshalIndent(document, "", "  ")
+	if err != nil {
+		return nil, errors.New("error while encoding invoice: " + err.Error())
+	}
+	return append([]byte(xml.Header), out...), nil
+}
+
+// ublInvoiceLines splits the net amount between the allocations, in
+// proportion to what was paid for each, the last line takes the rounding.
+func ublInvoiceLines(allocations []model.Allocation, netCents int64, category ublVatCategory) []ublInvoiceLine {
+	sorted := make([]model.Allocation, len(allocations))
+	copy(sorted, allocations)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].AllocationCreation.Before(sorted[j].AllocationCreation)
+	})
+
+	paid := big.NewInt(0)
+	for i := range sorted {
+		paid.Add(paid, sorted[i].GetUsdcAmountPayed())
+	}
+
+	taxCategory := ublClassifiedTaxCategory(category)
+	if len(sorted) == 0 || paid.Sign() == 0 {
+		return []ublInvoiceLine{{
+			ID:                  "1",
+			InvoicedQuantity:    ublQuantity{UnitCode: ublUnitCode, Value: "1"},
+			LineExtensionAmount: ublMoney(netCents),
+			Item:                ublItem{Name: "Compute services", ClassifiedTaxCategory: taxCategory},
+			Price:               ublPrice{PriceAmount: ublMoney(netCents)},
+		}}
+	}
+
+	lines := make([]ublInvoiceLine, 0, len(sorted))
+	assigned := int64(0)
+	for i := range sorted {
+		allocation := sorted[i]
+		cents := netCents - assigned
+		if i < len(sorted)-1 {
+			share := new(big.Int).Mul(big.NewInt(netCents), allocation.GetUsdcAmountPayed())
+			cents = share.Quo(share, paid).Int64()
+		}
+		assigned += cents
+
+		name := allocation.JobName
+		if strings.TrimSpace(name) == "" {
+			name = "Job " + allocation.JobId
+		}
+		description := joinNonEmpty(" - ", []string{
+			allocation.ProjectName,
+			allocation.JobType.GetName(),
+			"job " + allocation.JobId,
+			"node " + allocation.NodeAddress,
+			allocation.AllocationCreation.UTC().Format("2006-01-02"),
+		})
+		lines = append(lines, ublInvoiceLine{
+			ID:                  strconv.Itoa(i + 1),
+			InvoicedQuantity:    ublQuantity{UnitCode: ublUnitCode, Value: "1"},
+			LineExtensionAmount: ublMoney(cents),
+			Item: ublItem{
+				Description:           description,
+				Name:                  name,
+				ClassifiedTaxCategory: taxCategory,
+			},
+			Price: ublPrice{PriceAmount: ublMoney(cents)},
+		})
+	}
+	return lines
+}
+
+func ublPartyFor(info model.UserInfo) (ublParty, error) {
+	country, err := isoAlpha2(info.Country)
+	if err != nil {
+		return ublParty{}, err
+	}
+
+	name := safe(info.CompanyName)
+	if !info.IsCompany || strings.TrimSpace(name) == "" {
+		name = strings.TrimSpace(safe(info.Name) + " " + safe(info.Surname))
+	}
+	if name == "" {
+		return ublParty{}, fmt.Errorf("%w: name", ErrorUblMissingData)
+	}
+
+	party := ublParty{
+		PostalAddress: ublAddress{
+			StreetName:       info.Address,
+			CityName:         info.City,
+			CountrySubentity: info.State,
+			Country:          ublCountry{IdentificationCode: country},
+		},
+		PartyLegalEntity: ublLegalEntity{RegistrationName: name},
+	}
+	if country == "RO" {
+		// BR-RO-110, BR-RO-100: counties by ISO 3166-2 code, the sectors of
+		// Bucharest as SECTOR1 to SECTOR6
+		party.PostalAddress.CountrySubentity, party.PostalAddress.CityName = romanianCounty(info.State, info.City)
+	}
+	if info.Email != "" {
+		party.EndpointID = &ublIdentifier{SchemeID: "EM", Value: info.Email}
+		party.Contact = &ublContact{ElectronicMail: info.Email}
+	}
+	if info.BlockchainAddress != "" {
+		party.PartyIdentification = &ublPartyIdentification{ID: info.BlockchainAddress}
+	}
+
+	code := strings.ToUpper(strings.Join(strings.Fields(info.IdentificationCode), ""))
+	if code != "" {
+		if info.IsCompany {
+			party.PartyTaxScheme = &ublPartyTaxScheme{CompanyID: vatIdentifier(code, info.Country, country), TaxScheme: ublTaxScheme{ID: "VAT"}}
+			party.PartyLegalEntity.CompanyID = strings.TrimLeftFunc(code, unicode.IsLetter)
+		} else {
+			party.PartyLegalEntity.CompanyID = code
+		}
+	}
+	return party, nil
+}
+
+// vatIdentifier prefixes the identification code with the VAT country code
+// when it has none, as BR-CO-09 requires.
+func vatIdentifier(code, iso3, iso2 string) string {
+	if len(code) > 2 && unicode.IsLetter(rune(code[0])) && unicode.IsLetter(rune(code[1])) {
+		return code
+	}
+	if prefix, ok := euCountries[strings.ToUpper(iso3)]; ok {
+		return prefix + code
+	}
+	return iso2 + code
+}
+
+// isoAlpha2 converts the ISO 3166-1 alpha-3 country codes of the profiles to
+// the alpha-2 ones of UBL.
+func isoAlpha2(iso3 string) (string, error) {
+	region, err := language.ParseRegion(strings.TrimSpace(iso3))
+	if err != nil || !region.IsCountry() {
+		return "", fmt.Errorf("%w: country %q", ErrorUblMissingData, iso3)
+	}
+	return region.String(), nil
+}
+
+var romanianCounties = map[string]string{
+	"alba": "AB", "arad": "AR", "arges": "AG", "bacau": "BC", "bihor": "BH",
+	"bistrita-nasaud": "BN", "botosani": "BT", "braila": "BR", "brasov": "BV",
+	"bucuresti": "B", "bucharest": "B", "buzau": "BZ", "calarasi": "CL",
+	"caras-severin": "CS", "cluj": "CJ", "constanta": "CT", "covasna": "CV",
+	"dambovita": "DB", "dolj": "DJ", "galati": "GL", "giurgiu": "GR", "gorj": "GJ",
+	"harghita": "HR", "hunedoara": "HD", "ialomita": "IL", "iasi": "IS", "ilfov": "IF",
+	"maramures": "MM", "mehedinti": "MH", "mures": "MS", "neamt": "NT", "olt": "OT",
+	"prahova": "PH", "salaj": "SJ", "satu mare": "SM", "sibiu": "SB", "suceava": "SV",
+	"teleorman": "TR", "timis": "TM", "tulcea": "TL", "valcea": "VL", "vaslui": "VS",
+	"vrancea": "VN",
+}
+
+var romanianLetters = strings.NewReplacer("ă", "a", "â", "a", "î", "i", "ș", "s", "ş", "s", "ț", "t", "ţ", "t")
+
+// romanianCounty returns the ISO 3166-2 code of a county and the city, the
+// city is the sector for Bucharest. Unknown values are left as they are.
+func romanianCounty(state, city string) (string, string) {
+	normalize := func(s string) string {
+		s = romanianLetters.Replace(strings.ToLower(strings.TrimSpace(s)))
+		s = strings.TrimPrefix(s, "judetul ")
+		return strings.TrimPrefix(s, "ro-")
+	}
+
+	code, ok := romanianCounties[normalize(state)]
+	if !ok {
+		for _, known := range romanianCounties {
+			if strings.EqualFold(normalize(state), known) {
+				code, ok = known, true
+			}
+		}
+	}
+	if !ok {
+		return state, city
+	}
+	if code == "B" {
+		sector := strings.ReplaceAll(strings.ToUpper(city), " ", "")
+		if idx := strings.Index(sector, "SECTOR"); idx >= 0 && len(sector) >= idx+7 {
+			city = sector[idx : idx+7]
+		}
+	}
+	return "RO-" + code, city
+}
+
+func ublInvoiceID(invoice model.InvoiceDraft) string {
+	number := strconv.Itoa(invoice.InvoiceNumber)
+	if series := strings.TrimSpace(invoice.InvoiceSeries); series != "" {
+		return series + "-" + number
+	}
+	return number
+}
+
+func ublTaxCategoryFor(category ublVatCategory) ublTaxCategory {
+	tax := ublTaxCategory{ID: category.id, TaxScheme: ublTaxScheme{ID: "VAT"}}
+	if category.id != ublVatNotSubject {
+		percent := strconv.FormatFloat(category.percent, 'f', 2, 64)
+		tax.Percent = &percent
+	}
+	tax.TaxExemptionReasonCode = category.exemptionCode
+	tax.TaxExemptionReason = category.exemptionReason
+	return tax
+}
+
+// ublClassifiedTaxCategory is the tax category of a line, lines carry no
+// exemption reason.
+func ublClassifiedTaxCategory(category ublVatCategory) ublTaxCategory {
+	tax := ublTaxCategoryFor(category)
+	tax.TaxExemptionReasonCode = ""
+	tax.TaxExemptionReason = ""
+	return tax
+}
+
+func toCents(amount float64) int64 {
+	return int64(math.Round(amount * 100))
+}
+
+func formatCents(cents int64) string {
+	sign := ""
+	if cents < 0 {
+		sign = "-"
+		cents = -cents
+	}
+	return fmt.Sprintf("%s%d.%02d", sign, cents/100, cents%100)
+}
+
+func ublMoney(cents int64) ublAmount {
+	return ublAmount{Currency: ublDocumentCurrency, Value: formatCents(cents)}
+}
+
+// ------------------ UBL documents ------------------
+//
+// Fields follow the element order of the UBL 2.1 schema, only the elements
+// used by EN 16931 are declared.
+
+type ublInvoice struct {
+	XMLName              xml.Name             `xml:"Invoice"`
+	Xmlns                string               `xml:"xmlns,attr"`
+	XmlnsCac             string               `xml:"xmlns:cac,attr"`
+	XmlnsCbc             string               `xml:"xmlns:cbc,attr"`
+	CustomizationID      string               `xml:"cbc:CustomizationID"`
+	ID                   string               `xml:"cbc:ID"`
+	IssueDate            string               `xml:"cbc:IssueDate"`
+	InvoiceTypeCode      string               `xml:"cbc:InvoiceTypeCode"`
+	Notes                []string             `xml:"cbc:Note"`
+	DocumentCurrencyCode string               `xml:"cbc:DocumentCurrencyCode"`
+	TaxCurrencyCode      string               `xml:"cbc:TaxCurrencyCode,omitempty"`
+	BuyerReference       string               `xml:"cbc:BuyerReference,omitempty"`
+	Supplier             ublPartyWrapper      `xml:"cac:AccountingSupplierParty"`
+	Customer             ublPartyWrapper      `xml:"cac:AccountingCustomerParty"`
+	AllowanceCharges     []ublAllowanceCharge `xml:"cac:AllowanceCharge"`
+	TaxTotals            []ublTaxTotal        `xml:"cac:TaxTotal"`
+	LegalMonetaryTotal   ublMonetaryTotal     `xml:"cac:LegalMonetaryTotal"`
+	InvoiceLines         []ublInvoiceLine     `xml:"cac:InvoiceLine"`
+}
+
+type ublAmount struct {
+	Currency string `xml:"currencyID,attr"`
+	Value    string `xml:",chardata"`
+}
+
+type ublQuantity struct {
+	UnitCode string `xml:"unitCode,attr"`
+	Value    string `xml:",chardata"`
+}
+
+type ublIdentifier struct {
+	SchemeID string `xml:"schemeID,attr"`
+	Value    string `xml:",chardata"`
+}
+
+type ublPartyWrapper struct {
+	Party ublParty `xml:"cac:Party"`
+}
+
+type ublParty struct {
+	EndpointID          *ublIdentifier          `xml:"cbc:EndpointID"`
+	PartyIdentification *ublPartyIdentification `xml:"cac:PartyIdentification"`
+	PostalAddress       ublAddress              `xml:"cac:PostalAddress"`
+	PartyTaxScheme      *ublPartyTaxScheme      `xml:"cac:PartyTaxScheme"`
+	PartyLegalEntity    ublLegalEntity          `xml:"cac:PartyLegalEntity"`
+	Contact             *ublContact             `xml:"cac:Contact"`
+}
+
+type ublPartyIdentification struct {
+	ID string `xml:"cbc:ID"`
+}
+
+type ublAddress struct {
+	StreetName       string     `xml:"cbc:StreetName,omitempty"`
+	CityName         string     `xml:"cbc:CityName,omitempty"`
+	CountrySubentity string     `xml:"cbc:CountrySubentity,omitempty"`
+	Country          ublCountry `xml:"cac:Country"`
+}
+
+type ublCountry struct {
+	IdentificationCode string `xml:"cbc:IdentificationCode"`
+}
+
+type ublPartyTaxScheme struct {
+	CompanyID string       `xml:"cbc:CompanyID"`
+	TaxScheme ublTaxScheme `xml:"cac:TaxScheme"`
+}
+
+type ublTaxScheme struct {
+	ID string `xml:"cbc:ID"`
+}
+
+type ublLegalEntity struct {
+	RegistrationName string `xml:"cbc:RegistrationName"`
+	CompanyID        string `xml:"cbc:CompanyID,omitempty"`
+}
+
+type ublContact struct {
+	ElectronicMail string `xml:"cbc:ElectronicMail"`
+}
+
+type ublAllowanceCharge struct {
+	ChargeIndicator bool           `xml:"cbc:ChargeIndicator"`
+	Reason          string         `xml:"cbc:AllowanceChargeReason"`
+	Amount          ublAmount      `xml:"cbc:Amount"`
+	TaxCategory     ublTaxCategory `xml:"cac:TaxCategory"`
+}
+
+type ublTaxTotal struct {
+	TaxAmount   ublAmount        `xml:"cbc:TaxAmount"`
+	TaxSubtotal []ublTaxSubtotal `xml:"cac:TaxSubtotal"`
+}
+
+type ublTaxSubtotal struct {
+	TaxableAmount ublAmount      `xml:"cbc:TaxableAmount"`
+	TaxAmount     ublAmount      `xml:"cbc:TaxAmount"`
+	TaxCategory   ublTaxCategory `xml:"cac:TaxCategory"`
+}
+
+type ublTaxCategory struct {
+	ID                     string       `xml:"cbc:ID"`
+	Percent                *string      `xml:"cbc:Percent"`
+	TaxExemptionReasonCode string       `xml:"cbc:TaxExemptionReasonCode,omitempty"`
+	TaxExemptionReason     string       `xml:"cbc:TaxExemptionReason,omitempty"`
+	TaxScheme              ublTaxScheme `xml:"cac:TaxScheme"`
+}
+
+type ublMonetaryTotal struct {
+	LineExtensionAmount   ublAmount  `xml:"cbc:LineExtensionAmount"`
+	TaxExclusiveAmount    ublAmount  `xml:"cbc:TaxExclusiveAmount"`
+	TaxInclusiveAmount    ublAmount  `xml:"cbc:TaxInclusiveAmount"`
+	ChargeTotalAmount     *ublAmount `xml:"cbc:ChargeTotalAmount"`
+	PrepaidAmount         ublAmount  `xml:"cbc:PrepaidAmount"`
+	PayableRoundingAmount *ublAmount `xml:"cbc:PayableRoundingAmount"`
+	PayableAmount         ublAmount  `xml:"cbc:PayableAmount"`
+}
+
+type ublInvoiceLine struct {
+	ID                  string      `xml:"cbc:ID"`
+	InvoicedQuantity    ublQuantity `xml:"cbc:InvoicedQuantity"`
+	LineExtensionAmount ublAmount   `xml:"cbc:LineExtensionAmount"`
+	Item                ublItem     `xml:"cac:Item"`
+	Price               ublPrice    `xml:"cac:Price"`
+}
+
+type ublItem struct {
+	Description           string         `xml:"cbc:Description,omitempty"`
+	Name                  string         `xml:"cbc:Name"`
+	ClassifiedTaxCategory ublTaxCategory `xml:"cac:ClassifiedTaxCategory"`
+}
+
+type ublPrice struct {
+	PriceAmount ublAmount `xml:"cbc:PriceAmount"`
+}
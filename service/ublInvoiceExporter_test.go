@@ -0,0 +1,362 @@
+package service
+
+import (
+	"bytes"
+	"encoding/xml"
+	"io"
+	"math"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/NaeuralEdgeProtocol/ratio1-backend/model"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+// ublNode is a parsed element of an exported invoice.
+type ublNode struct {
+	name     xml.Name
+	attrs    map[string]string
+	text     string
+	children []*ublNode
+}
+
+func (n *ublNode) all(local string) []*ublNode {
+	var found []*ublNode
+	for _, child := range n.children {
+		if child.name.Local == local {
+			found = append(found, child)
+		}
+	}
+	return found
+}
+
+func (n *ublNode) one(t *testing.T, path ...string) *ublNode {
+	node := n
+	for _, local := range path {
+		children := node.all(local)
+		require.Len(t, children, 1, "%s in %s", local, node.name.Local)
+		node = children[0]
+	}
+	return node
+}
+
+func (n *ublNode) cents(t *testing.T, path ...string) int64 {
+	value, err := strconv.ParseFloat(n.one(t, path...).text, 64)
+	require.NoError(t, err)
+	return int64(math.Round(value * 100))
+}
+
+func parseUbl(t *testing.T, out []byte) *ublNode {
+	decoder := xml.NewDecoder(bytes.NewReader(out))
+	var stack []*ublNode
+	var root *ublNode
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		switch token := token.(type) {
+		case xml.StartElement:
+			node := &ublNode{name: token.Name, attrs: map[string]string{}}
+			for _, attr := range token.Attr {
+				if attr.Name.Space == "" {
+					node.attrs[attr.Name.Local] = attr.Value
+				}
+			}
+			if len(stack) > 0 {
+				parent := stack[len(stack)-1]
+				parent.children = append(parent.children, node)
+			} else {
+				root = node
+			}
+			stack = append(stack, node)
+		case xml.CharData:
+			if len(stack) > 0 {
+				stack[len(stack)-1].text += strings.TrimSpace(string(token))
+			}
+		case xml.EndElement:
+			stack = stack[:len(stack)-1]
+		}
+	}
+	require.NotNil(t, root)
+	return root
+}
+
+var ublDecimal = regexp.MustCompile(`^-?\d+(\.\d{1,2})?$`)
+
+// ublXsdPath is the UBL 2.1 invoice schema, vendored with the schemas it
+// includes as described in testdata/ubl/README.md.
+const ublXsdPath = "testdata/ubl/xsd/maindoc/UBL-Invoice-2.1.xsd"
+
+// requireUbl validates an exported invoice against the UBL 2.1 schema with
+// xmllint and parses it. The schema is vendored as described in
+// testdata/ubl/README.md, the test is skipped when xmllint is not installed.
+func requireUbl(t *testing.T, out []byte) *ublNode {
+	if _, err := os.Stat(ublXsdPath); err != nil {
+		t.Fatal("UBL 2.1 schema is not vendored, see testdata/ubl/README.md: " + err.Error())
+	}
+	xmllint, err := exec.LookPath("xmllint")
+	if err != nil {
+		t.Skip("xmllint is not installed, UBL schema validation skipped")
+	}
+
+	path := filepath.Join(t.TempDir(), "invoice.xml")
+	require.NoError(t, os.WriteFile(path, out, 0o600))
+	output, err := exec.Command(xmllint, "--noout", "--nonet", "--schema", ublXsdPath, path).CombinedOutput()
+	require.NoError(t, err, string(output))
+	return parseUbl(t, out)
+}
+
+// requireUblDecimals checks that the amounts have at most two decimals.
+func requireUblDecimals(t *testing.T, node *ublNode) {
+	if _, ok := node.attrs["currencyID"]; ok {
+		require.Regexp(t, ublDecimal, node.text, node.name.Local)
+	}
+	for _, child := range node.children {
+		requireUblDecimals(t, child)
+	}
+}
+
+// requireEn16931 checks the EN 16931 and CIUS-RO business rules the exporter
+// is concerned with.
+func requireEn16931(t *testing.T, invoice *ublNode) {
+	require.Equal(t, ublCustomizationID, invoice.one(t, "CustomizationID").text)
+	require.Regexp(t, `\d`, invoice.one(t, "ID").text) // BR-RO-010
+	currency := invoice.one(t, "DocumentCurrencyCode").text
+	requireUblDecimals(t, invoice) // BR-DEC
+
+	// BR-CO-10 line totals
+	lines := int64(0)
+	for _, line := range invoice.all("InvoiceLine") {
+		lines += line.cents(t, "LineExtensionAmount")
+		require.NotEmpty(t, line.one(t, "Item", "ClassifiedTaxCategory", "ID").text) // BR-CO-04
+	}
+	totals := invoice.one(t, "LegalMonetaryTotal")
+	require.Equal(t, lines, totals.cents(t, "LineExtensionAmount"))
+
+	// BR-CO-11, BR-CO-13 charges
+	charges := int64(0)
+	for _, charge := range invoice.all("AllowanceCharge") {
+		require.Equal(t, "true", charge.one(t, "ChargeIndicator").text)
+		require.NotEmpty(t, charge.one(t, "AllowanceChargeReason").text) // BR-38
+		charges += charge.cents(t, "Amount")
+	}
+	if len(totals.all("ChargeTotalAmount")) > 0 {
+		require.Equal(t, charges, totals.cents(t, "ChargeTotalAmount"))
+	}
+	require.Equal(t, lines+charges, totals.cents(t, "TaxExclusiveAmount"))
+
+	// BR-CO-14, BR-53 VAT totals
+	var vat int64
+	taxCurrencies := map[string]bool{}
+	for _, total := range invoice.all("TaxTotal") {
+		amountCurrency := total.one(t, "TaxAmount").attrs["currencyID"]
+		taxCurrencies[amountCurrency] = true
+		if amountCurrency != currency {
+			require.Empty(t, total.all("TaxSubtotal"))
+			continue
+		}
+		vat = total.cents(t, "TaxAmount")
+		sum := int64(0)
+		categories := map[string]bool{}
+		for _, subtotal := range total.all("TaxSubtotal") {
+			category := subtotal.one(t, "TaxCategory")
+			id := category.one(t, "ID").text
+			categories[id] = true
+			tax := subtotal.cents(t, "TaxAmount")
+			sum += tax
+			switch id {
+			case ublVatStandard:
+				percent, err := strconv.ParseFloat(category.one(t, "Percent").text, 64)
+				require.NoError(t, err)
+				require.Greater(t, percent, 0.0)                                                             // BR-S-05
+				require.InDelta(t, float64(subtotal.cents(t, "TaxableAmount"))*percent/100, float64(tax), 1) // BR-S-09
+			case ublVatNotSubject:
+				require.Empty(t, category.all("Percent")) // BR-O-05
+				require.Zero(t, tax)
+			default:
+				require.Equal(t, "0.00", category.one(t, "Percent").text)
+				require.Zero(t, tax)
+				require.NotEmpty(t, category.all("TaxExemptionReason")) // BR-E-10, BR-AE-10, BR-G-10
+			}
+		}
+		require.Equal(t, vat, sum)
+		if categories[ublVatNotSubject] {
+			require.Len(t, categories, 1) // BR-O-11
+		}
+	}
+	require.True(t, taxCurrencies[currency])
+	if taxCurrency := invoice.all("TaxCurrencyCode"); len(taxCurrency) > 0 {
+		require.True(t, taxCurrencies[taxCurrency[0].text])
+	}
+
+	// BR-CO-15, BR-CO-16 payable
+	require.Equal(t, totals.cents(t, "TaxExclusiveAmount")+vat, totals.cents(t, "TaxInclusiveAmount"))
+	rounding := int64(0)
+	if len(totals.all("PayableRoundingAmount")) > 0 {
+		rounding = totals.cents(t, "PayableRoundingAmount")
+	}
+	require.Equal(t, totals.cents(t, "TaxInclusiveAmount")-totals.cents(t, "PrepaidAmount")+rounding, totals.cents(t, "PayableAmount"))
+
+	for _, role := range []string{"AccountingSupplierParty", "AccountingCustomerParty"} {
+		party := invoice.one(t, role, "Party")
+		require.NotEmpty(t, party.one(t, "PartyLegalEntity", "RegistrationName").text)
+		country := party.one(t, "PostalAddress", "Country", "IdentificationCode").text
+		require.Regexp(t, `^[A-Z]{2}$`, country)
+		for _, scheme := range party.all("PartyTaxScheme") {
+			require.Regexp(t, `^[A-Z]{2}`, scheme.one(t, "CompanyID").text) // BR-CO-09
+		}
+		if country == "RO" {
+			require.Regexp(t, `^RO-[A-Z]{1,2}$`, party.one(t, "PostalAddress", "CountrySubentity").text) // BR-RO-110
+		}
+	}
+}
+
+func ublTestDraft(seller, buyer model.UserInfo, vat float64) model.InvoiceDraft {
+	return model.InvoiceDraft{
+		DraftId:                    uuid.New(),
+		CreationTimestamp:          time.Date(2026, 7, 1, 0, 0, 0, 0, time.UTC),
+		UserAddress:                seller.BlockchainAddress,
+		CspOwner:                   buyer.BlockchainAddress,
+		UserProfile:                seller,
+		CspProfile:                 buyer,
+		InvoiceSeries:              "NODE",
+		InvoiceNumber:              42,
+		TotalUsdcAmount:            100,
+		VatApplied:                 vat,
+		LocalCurrency:              "USD",
+		LocalCurrencyExchangeRatio: 1,
+	}
+}
+
+func ublTestAllocations(amounts ...string) []model.Allocation {
+	var allocations []model.Allocation
+	for i, amount := range amounts {
+		allocations = append(allocations, model.Allocation{
+			AllocationCreation: time.Date(2026, 6, i+1, 0, 0, 0, 0, time.UTC),
+			JobId:              strconv.Itoa(i + 1),
+			JobName:            "Inference job",
+			JobType:            1,
+			ProjectName:        "Project Alpha",
+			NodeAddress:        "0xai_000000000000000000000000000000000000001",
+			UsdcAmountPayed:    amount,
+		})
+	}
+	return allocations
+}
+
+func TestExportInvoiceDraftUblStandardVatWithLocalCurrency(t *testing.T) {
+	company, csp := "Acme Nodes SRL", "Ratio Cloud SRL"
+	notes := "Services for June"
+	extraTaxes := `[{ "Description": "Stamp duty", "TaxType": 0, "Value": 9.0 }, { "Description": "Local fee", "TaxType": 1, "Value": 1.0 }]`
+	seller := model.UserInfo{BlockchainAddress: "0x00000000000000000000000000000000000000a1", Email: "billing@acme.example", CompanyName: &company,
+		IdentificationCode: "12345678", Address: "Str. Lunga 1", City: "Sector 3", State: "București", Country: "ROU", IsCompany: true}
+	buyer := model.UserInfo{BlockchainAddress: "0x00000000000000000000000000000000000000c1", CompanyName: &csp,
+		IdentificationCode: "RO87654321", Address: "Str. Scurta 2", City: "Cluj-Napoca", State: "Cluj", Country: "ROU", IsCompany: true}
+	draft := ublTestDraft(seller, buyer, 19)
+	draft.TotalUsdcAmount = 1000
+	draft.LocalCurrency = "RON"
+	draft.LocalCurrencyExchangeRatio = 4.5
+	draft.ExtraText = &notes
+	draft.ExtraTaxes = &extraTaxes
+
+	out, err := ExportInvoiceDraftUbl(draft, ublTestAllocations("333333333", "333333333", "333333334"))
+	require.NoError(t, err)
+	invoice := requireUbl(t, out)
+	requireEn16931(t, invoice)
+
+	require.Equal(t, "NODE-42", invoice.one(t, "ID").text)
+	require.Equal(t, "RON", invoice.one(t, "TaxCurrencyCode").text)
+	require.Len(t, invoice.all("InvoiceLine"), 3)
+	require.Len(t, invoice.all("AllowanceCharge"), 2)
+
+	supplier := invoice.one(t, "AccountingSupplierParty", "Party")
+	require.Equal(t, "RO12345678", supplier.one(t, "PartyTaxScheme", "CompanyID").text)
+	require.Equal(t, "12345678", supplier.one(t, "PartyLegalEntity", "CompanyID").text)
+	require.Equal(t, "RO-B", supplier.one(t, "PostalAddress", "CountrySubentity").text)
+	require.Equal(t, "SECTOR3", supplier.one(t, "PostalAddress", "CityName").text)
+	require.Equal(t, "billing@acme.example", supplier.one(t, "EndpointID").text)
+	customer := invoice.one(t, "AccountingCustomerParty", "Party")
+	require.Equal(t, "RO87654321", customer.one(t, "PartyTaxScheme", "CompanyID").text)
+	require.Equal(t, "RO-CJ", customer.one(t, "PostalAddress", "CountrySubentity").text)
+
+	// 1000 = net + 19% VAT + 1% fee + 2.00 stamp duty
+	totals := invoice.one(t, "LegalMonetaryTotal")
+	require.Equal(t, int64(83167), totals.cents(t, "LineExtensionAmount"))
+	require.Equal(t, int64(100000), totals.cents(t, "PrepaidAmount"))
+	require.Equal(t, int64(0), totals.cents(t, "PayableAmount"))
+	for _, total := range invoice.all("TaxTotal") {
+		if total.one(t, "TaxAmount").attrs["currencyID"] == "RON" {
+			require.Equal(t, int64(71109), total.cents(t, "TaxAmount"))
+		}
+	}
+	require.Contains(t, string(out), "Exchange rate: 1 USD = 4.5 RON")
+	require.Contains(t, string(out), "Services for June")
+}
+
+func TestExportInvoiceDraftUblVatCategories(t *testing.T) {
+	company, csp, name, surname := "Acme Nodes SRL", "Cloud GmbH", "Mario", "Rossi"
+	romanian := model.UserInfo{BlockchainAddress: "0x00000000000000000000000000000000000000a1", CompanyName: &company,
+		IdentificationCode: "RO12345678", City: "Iasi", State: "Iasi", Country: "ROU", IsCompany: true}
+	german := model.UserInfo{BlockchainAddress: "0x00000000000000000000000000000000000000c1", CompanyName: &csp,
+		IdentificationCode: "123456789", City: "Berlin", Country: "DEU", IsCompany: true}
+	american := model.UserInfo{BlockchainAddress: "0x00000000000000000000000000000000000000c2", CompanyName: &csp,
+		IdentificationCode: "98-7654321", City: "Austin", Country: "USA", IsCompany: true}
+	private := model.UserInfo{BlockchainAddress: "0x00000000000000000000000000000000000000a2", Name: &name, Surname: &surname,
+		IdentificationCode: "1900101000000", City: "Milano", Country: "ITA"}
+
+	for _, test := range []struct {
+		name     string
+		seller   model.UserInfo
+		buyer    model.UserInfo
+		category string
+	}{
+		{"reverse charge", romanian, german, ublVatReverseCharge},
+		{"export", romanian, american, ublVatExport},
+		{"not subject", private, german, ublVatNotSubject},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			out, err := ExportInvoiceDraftUbl(ublTestDraft(test.seller, test.buyer, 0), ublTestAllocations("60000000", "40000000"))
+			require.NoError(t, err)
+			invoice := requireUbl(t, out)
+			requireEn16931(t, invoice)
+
+			subtotal := invoice.one(t, "TaxTotal", "TaxSubtotal")
+			require.Equal(t, test.category, subtotal.one(t, "TaxCategory", "ID").text)
+			require.Equal(t, int64(10000), subtotal.cents(t, "TaxableAmount"))
+			for _, line := range invoice.all("InvoiceLine") {
+				require.Equal(t, test.category, line.one(t, "Item", "ClassifiedTaxCategory", "ID").text)
+			}
+
+			supplier := invoice.one(t, "AccountingSupplierParty", "Party")
+			customer := invoice.one(t, "AccountingCustomerParty", "Party")
+			switch test.category {
+			case ublVatReverseCharge:
+				require.Contains(t, string(out), "Reverse charge - Taxare inversa")
+				require.Equal(t, "DE123456789", customer.one(t, "PartyTaxScheme", "CompanyID").text) // BR-AE-02
+				require.Equal(t, "RO-IS", supplier.one(t, "PostalAddress", "CountrySubentity").text)
+			case ublVatNotSubject:
+				require.Empty(t, supplier.all("PartyTaxScheme")) // BR-O-02
+				require.Empty(t, customer.all("PartyTaxScheme"))
+				require.Equal(t, "Mario Rossi", supplier.one(t, "PartyLegalEntity", "RegistrationName").text)
+			}
+		})
+	}
+}
+
+func TestExportInvoiceDraftUblRequiresCountry(t *testing.T) {
+	company := "Acme Nodes SRL"
+	seller := model.UserInfo{CompanyName: &company, IdentificationCode: "RO12345678", Country: "Romania", IsCompany: true}
+	buyer := model.UserInfo{CompanyName: &company, IdentificationCode: "RO12345678", Country: "ROU", IsCompany: true}
+
+	_, err := ExportInvoiceDraftUbl(ublTestDraft(seller, buyer, 19), nil)
+	require.ErrorIs(t, err, ErrorUblMissingData)
+}
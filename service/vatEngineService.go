@@ -0,0 +1,279 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/NaeuralEdgeProtocol/ratio1-backend/model"
+)
+
+var (
+	ErrorVatRateNotFound = errors.New("no VAT rate configured")
+	ErrorVatRateInvalid  = errors.New("invalid VAT rate")
+)
+
+// VAT category codes of EN 16931, UNTDID 5305.
+const (
+	VatCategoryStandard      = "S"
+	VatCategoryReverseCharge = "AE"
+	VatCategoryNotSubject    = "O"
+)
+
+// VAT schemes the engine can apply to a supply.
+const (
+	VatSchemeDomestic      = "domestic"
+	VatSchemeReverseCharge = "reverse_charge"
+	VatSchemeOss           = "oss"
+	VatSchemeOutOfScope    = "out_of_scope"
+	VatSchemeNotRegistered = "not_registered"
+)
+
+// VatInput describes a supply of services: who sells, who buys and when.
+// BuyerViesValid tells whether the VAT number of a business buyer was found
+// valid in VIES, SellerRegistered whether the seller charges VAT at all.
+type VatInput struct {
+	SellerCountry    string
+	SellerRegistered bool
+	// SellerDomesticRate is the rate the seller declared for its own
+	// country, used for its domestic supplies outside the EU.
+	SellerDomesticRate float64
+	BuyerCountry       string
+	BuyerBusiness      bool
+	BuyerViesValid     bool
+	SupplyDate         time.Time
+}
+
+// VatTreatment is the VAT the engine applies to a supply, Rate is a
+// percentage and Mention the legal text to print on the invoice.
+type VatTreatment struct {
+	Rate     float64 `json:"rate"`
+	Category string  `json:"category"`
+	Scheme   string  `json:"scheme"`
+	Mention  string  `json:"mention"`
+}
+
+// vatRatesEpoch is the date the built-in rates are effective from.
+var vatRatesEpoch = time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// defaultVatRates are the standard rates of the EU countries the engine
+// starts from, the versions saved by the admins are added on top of them.
+var defaultVatRates = []model.VatRate{
+	{Country: "AUT", Rate: 20, EffectiveFrom: vatRatesEpoch},
+	{Country: "BEL", Rate: 21, EffectiveFrom: vatRatesEpoch},
+	{Country: "BGR", Rate: 20, EffectiveFrom: vatRatesEpoch},
+	{Country: "HRV", Rate: 25, EffectiveFrom: vatRatesEpoch},
+	{Country: "CYP", Rate: 19, EffectiveFrom: vatRatesEpoch},
+	{Country: "CZE", Rate: 21, EffectiveFrom: vatRatesEpoch},
+	{Country: "DNK", Rate: 25, EffectiveFrom: vatRatesEpoch},
+	{Country: "EST", Rate: 22, EffectiveFrom: vatRatesEpoch},
+	{Country: "FIN", Rate: 26, EffectiveFrom: vatRatesEpoch},
+	{Country: "FRA", Rate: 20, EffectiveFrom: vatRatesEpoch},
+	{Country: "DEU", Rate: 19, EffectiveFrom: vatRatesEpoch},
+	{Country: "GRC", Rate: 24, EffectiveFrom: vatRatesEpoch},
+	{Country: "HUN", Rate: 27, EffectiveFrom: vatRatesEpoch},
+	{Country: "IRL", Rate: 23, EffectiveFrom: vatRatesEpoch},
+	{Country: "ITA", Rate: 22, EffectiveFrom: vatRatesEpoch},
+	{Country: "LVA", Rate: 21, EffectiveFrom: vatRatesEpoch},
+	{Country: "LTU", Rate: 21, EffectiveFrom: vatRatesEpoch},
+	{Country: "LUX", Rate: 17, EffectiveFrom: vatRatesEpoch},
+	{Country: "MLT", Rate: 18, EffectiveFrom: vatRatesEpoch},
+	{Country: "NLD", Rate: 21, EffectiveFrom: vatRatesEpoch},
+	{Country: "POL", Rate: 23, EffectiveFrom: vatRatesEpoch},
+	{Country: "PRT", Rate: 23, EffectiveFrom: vatRatesEpoch},
+	{Country: "ROU", Rate: 19, EffectiveFrom: vatRatesEpoch},
+	{Country: "ROU", Rate: 21, EffectiveFrom: time.Date(2025, 8, 1, 0, 0, 0, 0, time.UTC)},
+	{Country: "SVK", Rate: 23, EffectiveFrom: vatRatesEpoch},
+	{Country: "SVN", Rate: 22, EffectiveFrom: vatRatesEpoch},
+	{Country: "ESP", Rate: 21, EffectiveFrom: vatRatesEpoch},
+	{Country: "SWE", Rate: 25, EffectiveFrom: vatRatesEpoch},
+}
+
+// VatEngine decides the VAT of a supply from a snapshot of the rate tables,
+// it does not touch the storage so it can be used inside transactions.
+type VatEngine struct {
+	rates map[string][]model.VatRate // by country, oldest version first
+}
+
+// LoadVatEngine reads the rate versions saved by the admins.
+func LoadVatEngine() (*VatEngine, error) {
+	rates, err := GetVatRates()
+	if err != nil {
+		return nil, err
+	}
+	return NewVatEngine(rates), nil
+}
+
+// NewVatEngine builds an engine from the given rate versions.
+func NewVatEngine(rates []model.VatRate) *VatEngine {
+	engine := &VatEngine{rates: make(map[string][]model.VatRate)}
+	for _, rate := range rates {
+		country := strings.ToUpper(rate.Country)
+		engine.rates[country] = append(engine.rates[country], rate)
+	}
+	for _, versions := range engine.rates {
+		sort.SliceStable(versions, func(i, j int) bool { return versions[i].EffectiveFrom.Before(versions[j].EffectiveFrom) })
+	}
+	return engine
+}
+
+// Rate returns the standard rate of country in effect on date.
+func (e *VatEngine) Rate(country string, date time.Time) (float64, error) {
+	versions := e.rates[strings.ToUpper(country)]
+	for i := len(versions) - 1; i >= 0; i-- {
+		if !versions[i].EffectiveFrom.After(date) {
+			return versions[i].Rate, nil
+		}
+	}
+	return 0, fmt.Errorf("%w for %s on %s", ErrorVatRateNotFound, country, date.Format(time.DateOnly))
+}
+
+// Apply decides the VAT of a supply of services. Supplies inside a country
+// are taxed at its rate, or at the domestic rate of the seller for the
+// countries outside the EU without one, the ones to businesses of other EU countries with a
+// valid VAT number are reverse charged, the other ones to EU buyers are
+// taxed at the rate of the buyer country through the OSS and the ones to
+// buyers outside the EU are out of scope.
+func (e *VatEngine) Apply(in VatInput) (VatTreatment, error) {
+	seller, buyer := strings.ToUpper(in.SellerCountry), strings.ToUpper(in.BuyerCountry)
+	switch {
+	case !in.SellerRegistered:
+		return VatTreatment{
+			Category: VatCategoryNotSubject,
+			Scheme:   VatSchemeNotRegistered,
+			Mention:  "Not subject to VAT, the supplier is not registered for VAT",
+		}, nil
+	case seller == buyer:
+		rate, err := e.Rate(seller, in.SupplyDate)
+		if errors.Is(err, ErrorVatRateNotFound) && !isUeCountry(seller) {
+			if in.SellerDomesticRate <= 0 {
+				return vatOutOfScope(), nil
+			}
+			rate = in.SellerDomesticRate
+		} else if err != nil {
+			return VatTreatment{}, err
+		}
+		return VatTreatment{Rate: rate, Category: VatCategoryStandard, Scheme: VatSchemeDomestic}, nil
+	case !isUeCountry(seller) || !isUeCountry(buyer):
+		return vatOutOfScope(), nil
+	case in.BuyerBusiness && in.BuyerViesValid:
+		return VatTreatment{
+			Category: VatCategoryReverseCharge,
+			Scheme:   VatSchemeReverseCharge,
+			Mention:  "Reverse charge, VAT due by the customer according to art. 196 of Directive 2006/112/EC",
+		}, nil
+	default:
+		rate, err := e.Rate(buyer, in.SupplyDate)
+		if err != nil {
+			return VatTreatment{}, err
+		}
+		return VatTreatment{
+			Rate:     rate,
+			Category: VatCategoryStandard,
+			Scheme:   VatSchemeOss,
+			Mention:  "VAT of " + buyer + " declared through the OSS according to art. 58 of Directive 2006/112/EC",
+		}, nil
+	}
+}
+
+func vatOutOfScope() VatTreatment {
+	return VatTreatment{
+		Category: VatCategoryNotSubject,
+		Scheme:   VatSchemeOutOfScope,
+		Mention:  "Out of scope of EU VAT, place of supply outside the EU",
+	}
+}
+
+// VatBasisPoints returns a rate as the hundredths of percent the license
+// signatures use, 21% is 2100.
+func VatBasisPoints(rate float64) int64 {
+	return int64(math.Round(rate * 100))
+}
+
+// GetVatRates returns every rate version, the built-in ones included, by
+// country and oldest first.
+func GetVatRates() ([]model.VatRate, error) {
+	stored, err := repos.VatRates.GetAll()
+	if err != nil {
+		return nil, errors.New("error while retrieving vat rates: " + err.Error())
+	}
+
+	rates := make([]model.VatRate, 0, len(defaultVatRates)+len(stored))
+	for _, rate := range defaultVatRates {
+		if !hasVatRateVersion(stored, rate) {
+			rates = append(rates, rate)
+		}
+	}
+	rates = append(rates, stored...)
+	sort.SliceStable(rates, func(i, j int) bool {
+		if rates[i].Country != rates[j].Country {
+			return rates[i].Country < rates[j].Country
+		}
+		return rates[i].EffectiveFrom.Before(rates[j].EffectiveFrom)
+	})
+	return rates, nil
+}
+
+// SaveVatRate adds a version of the rate of a country effective from the
+// given day, a version of the same day is replaced.
+func SaveVatRate(country string, rate float64, effectiveFrom time.Time, adminAddress string) (*model.VatRate, error) {
+	country = strings.ToUpper(strings.TrimSpace(country))
+	if GetRoNameForISOCode(country) == "" {
+		return nil, fmt.Errorf("%w: unknown country %s", ErrorVatRateInvalid, country)
+	}
+	if rate < 0 || rate > 100 {
+		return nil, fmt.Errorf("%w: rate must be between 0 and 100", ErrorVatRateInvalid)
+	}
+	if effectiveFrom.IsZero() {
+		return nil, fmt.Errorf("%w: effective date is required", ErrorVatRateInvalid)
+	}
+
+	version := &model.VatRate{
+		Country:       country,
+		EffectiveFrom: utcDay(effectiveFrom),
+		Rate:          rate,
+		CreatedBy:     adminAddress,
+	}
+	err := repos.VatRates.Save(version)
+	if err != nil {
+		return nil, errors.New("error while saving vat rate: " + err.Error())
+	}
+	return version, nil
+}
+
+func hasVatRateVersion(rates []model.VatRate, version model.VatRate) bool {
+	for _, rate := range rates {
+		if rate.Country == version.Country && rate.EffectiveFrom.Equal(version.EffectiveFrom) {
+			return true
+		}
+	}
+	return false
+}
+
+// licenseSellerCountry is the country the node licenses are sold from.
+const licenseSellerCountry = model.ROU_ID
+
+// LicenseVat returns the VAT of node licenses bought on date by a buyer of
+// country, viesValid tells whether a business buyer has a valid VAT number.
+func LicenseVat(country string, business, viesValid bool, date time.Time) (VatTreatment, error) {
+	engine, err := LoadVatEngine()
+	if err != nil {
+		return VatTreatment{}, err
+	}
+	return engine.License(country, business, viesValid, date)
+}
+
+// License is LicenseVat with the rates of the engine.
+func (e *VatEngine) License(country string, business, viesValid bool, date time.Time) (VatTreatment, error) {
+	return e.Apply(VatInput{
+		SellerCountry:    licenseSellerCountry,
+		SellerRegistered: true,
+		BuyerCountry:     country,
+		BuyerBusiness:    business,
+		BuyerViesValid:   viesValid,
+		SupplyDate:       date,
+	})
+}
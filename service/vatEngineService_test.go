@@ -0,0 +1,208 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/NaeuralEdgeProtocol/ratio1-backend/model"
+	"github.com/NaeuralEdgeProtocol/ratio1-backend/storage/memory"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVatEngineApply(t *testing.T) {
+	engine := NewVatEngine(defaultVatRates)
+	beforeChange := time.Date(2025, 7, 31, 0, 0, 0, 0, time.UTC)
+	afterChange := time.Date(2025, 8, 1, 0, 0, 0, 0, time.UTC)
+
+	cases := []struct {
+		name     string
+		in       VatInput
+		expected VatTreatment
+	}{
+		{
+			name:     "domestic before the rate change",
+			in:       VatInput{SellerCountry: "ROU", SellerRegistered: true, BuyerCountry: "ROU", SupplyDate: beforeChange},
+			expected: VatTreatment{Rate: 19, Category: VatCategoryStandard, Scheme: VatSchemeDomestic},
+		},
+		{
+			name:     "domestic after the rate change",
+			in:       VatInput{SellerCountry: "ROU", SellerRegistered: true, BuyerCountry: "ROU", BuyerBusiness: true, SupplyDate: afterChange},
+			expected: VatTreatment{Rate: 21, Category: VatCategoryStandard, Scheme: VatSchemeDomestic},
+		},
+		{
+			name: "eu business with valid vies",
+			in:   VatInput{SellerCountry: "ROU", SellerRegistered: true, BuyerCountry: "DEU", BuyerBusiness: true, BuyerViesValid: true, SupplyDate: afterChange},
+			expected: VatTreatment{
+				Category: VatCategoryReverseCharge,
+				Scheme:   VatSchemeReverseCharge,
+				Mention:  "Reverse charge, VAT due by the customer according to art. 196 of Directive 2006/112/EC",
+			},
+		},
+		{
+			name: "eu business without valid vies",
+			in:   VatInput{SellerCountry: "ROU", SellerRegistered: true, BuyerCountry: "DEU", BuyerBusiness: true, SupplyDate: afterChange},
+			expected: VatTreatment{
+				Rate:     19,
+				Category: VatCategoryStandard,
+				Scheme:   VatSchemeOss,
+				Mention:  "VAT of DEU declared through the OSS according to art. 58 of Directive 2006/112/EC",
+			},
+		},
+		{
+			name: "eu consumer",
+			in:   VatInput{SellerCountry: "ROU", SellerRegistered: true, BuyerCountry: "SWE", SupplyDate: afterChange},
+			expected: VatTreatment{
+				Rate:     25,
+				Category: VatCategoryStandard,
+				Scheme:   VatSchemeOss,
+				Mention:  "VAT of SWE declared through the OSS according to art. 58 of Directive 2006/112/EC",
+			},
+		},
+		{
+			name:     "buyer outside the eu",
+			in:       VatInput{SellerCountry: "ROU", SellerRegistered: true, BuyerCountry: "USA", BuyerBusiness: true, SupplyDate: afterChange},
+			expected: vatOutOfScope(),
+		},
+		{
+			name:     "domestic outside the eu",
+			in:       VatInput{SellerCountry: "USA", SellerRegistered: true, BuyerCountry: "USA", SupplyDate: afterChange},
+			expected: vatOutOfScope(),
+		},
+		{
+			name:     "domestic outside the eu at the rate of the seller",
+			in:       VatInput{SellerCountry: "CHE", SellerRegistered: true, SellerDomesticRate: 8.1, BuyerCountry: "CHE", SupplyDate: afterChange},
+			expected: VatTreatment{Rate: 8.1, Category: VatCategoryStandard, Scheme: VatSchemeDomestic},
+		},
+		{
+			name:     "domestic in the eu ignores the rate of the seller",
+			in:       VatInput{SellerCountry: "ROU", SellerRegistered: true, SellerDomesticRate: 5, BuyerCountry: "ROU", SupplyDate: afterChange},
+			expected: VatTreatment{Rate: 21, Category: VatCategoryStandard, Scheme: VatSchemeDomestic},
+		},
+		{
+			name:     "buyer of another country outside the eu",
+			in:       VatInput{SellerCountry: "CHE", SellerRegistered: true, SellerDomesticRate: 8.1, BuyerCountry: "USA", SupplyDate: afterChange},
+			expected: vatOutOfScope(),
+		},
+		{
+			name: "seller not registered",
+			in:   VatInput{SellerCountry: "ROU", BuyerCountry: "ROU", SupplyDate: afterChange},
+			expected: VatTreatment{
+				Category: VatCategoryNotSubject,
+				Scheme:   VatSchemeNotRegistered,
+				Mention:  "Not subject to VAT, the supplier is not registered for VAT",
+			},
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			vat, err := engine.Apply(c.in)
+			require.NoError(t, err)
+			require.Equal(t, c.expected, vat)
+		})
+	}
+
+	_, err := engine.Rate("ROU", time.Date(1999, 12, 31, 0, 0, 0, 0, time.UTC))
+	require.ErrorIs(t, err, ErrorVatRateNotFound)
+	require.Equal(t, int64(2100), VatBasisPoints(21))
+	require.Equal(t, int64(550), VatBasisPoints(5.5))
+}
+
+func TestSaveVatRateAddsVersions(t *testing.T) {
+	previous := GetRepositories()
+	SetRepositories(memory.NewRepositories())
+	defer SetRepositories(previous)
+
+	_, err := SaveVatRate("XXX", 20, time.Now(), "0xadmin")
+	require.ErrorIs(t, err, ErrorVatRateInvalid)
+	_, err = SaveVatRate("DEU", 120, time.Now(), "0xadmin")
+	require.ErrorIs(t, err, ErrorVatRateInvalid)
+	_, err = SaveVatRate("DEU", 20, time.Time{}, "0xadmin")
+	require.ErrorIs(t, err, ErrorVatRateInvalid)
+
+	change := time.Date(2027, 1, 1, 10, 0, 0, 0, time.UTC)
+	saved, err := SaveVatRate(" deu", 21, change, "0xadmin")
+	require.NoError(t, err)
+	require.Equal(t, "DEU", saved.Country)
+	require.Equal(t, time.Date(2027, 1, 1, 0, 0, 0, 0, time.UTC), saved.EffectiveFrom)
+	// the built-in version of the same day is replaced
+	_, err = SaveVatRate("DEU", 16, vatRatesEpoch, "0xadmin")
+	require.NoError(t, err)
+
+	engine, err := LoadVatEngine()
+	require.NoError(t, err)
+	rate, err := engine.Rate("DEU", time.Date(2026, 12, 31, 0, 0, 0, 0, time.UTC))
+	require.NoError(t, err)
+	require.Equal(t, float64(16), rate)
+	rate, err = engine.Rate("DEU", change)
+	require.NoError(t, err)
+	require.Equal(t, float64(21), rate)
+
+	vat, err := LicenseVat("DEU", false, false, change)
+	require.NoError(t, err)
+	require.Equal(t, float64(21), vat.Rate)
+	require.Equal(t, VatSchemeOss, vat.Scheme)
+
+	rates, err := GetVatRates()
+	require.NoError(t, err)
+	germany := make([]model.VatRate, 0, 2)
+	for _, rate := range rates {
+		if rate.Country == "DEU" {
+			germany = append(germany, rate)
+		}
+	}
+	require.Len(t, germany, 2)
+	require.Equal(t, "0xadmin", germany[0].CreatedBy)
+	require.Equal(t, float64(21), germany[1].Rate)
+}
+
+func TestGenerateInvoiceDraftAppliesVatEngine(t *testing.T) {
+	previous := GetRepositories()
+	SetRepositories(memory.NewRepositories())
+	defer SetRepositories(previous)
+	previousGetAccount := getAccountByAddressFn
+	defer func() { getAccountByAddressFn = previousGetAccount }()
+
+	buyerEmail := "csp@example.com"
+	getAccountByAddressFn = func(address string) (*model.Account, bool, error) {
+		return &model.Account{Address: address, Email: &buyerEmail}, true, nil
+	}
+	sellerName, buyerName := "Acme Nodes SRL", "Ratio Cloud GmbH"
+	require.NoError(t, repos.UserInfos.Create(&model.UserInfo{BlockchainAddress: "0xowner", IsCompany: true, CompanyName: &sellerName, Country: "ROU"}))
+	require.NoError(t, repos.UserInfos.Create(&model.UserInfo{BlockchainAddress: "0xcsp", IsCompany: true, CompanyName: &buyerName, Country: "DEU"}))
+	require.NoError(t, repos.Preferences.Create(&model.Preference{UserAddress: "0xowner", InvoiceSeries: "R1", NextNumber: 1, VatRegistered: true}))
+
+	draft, err := generateInvoiceDraft("0xowner", "0xcsp", withTestProfiles(t, createTestAllocations(t, "0xowner", "0xcsp", "1000000")), fixedFxRates(nil))
+	require.NoError(t, err)
+	require.Equal(t, float64(19), draft.VatApplied)
+	require.Equal(t, VatCategoryStandard, draft.VatCategory)
+	require.Contains(t, draft.VatMention, "OSS")
+
+	require.NoError(t, repos.Kycs.CreateOrUpdate(&model.Kyc{Email: buyerEmail, ViesRegistered: true}))
+	draft, err = generateInvoiceDraft("0xowner", "0xcsp", withTestProfiles(t, createTestAllocations(t, "0xowner", "0xcsp", "1000000")), fixedFxRates(nil))
+	require.NoError(t, err)
+	require.Equal(t, float64(0), draft.VatApplied)
+	require.Equal(t, VatCategoryReverseCharge, draft.VatCategory)
+	require.Contains(t, draft.VatMention, "Reverse charge")
+}
+
+func TestGenerateInvoiceDraftUsesDomesticRateOutsideEu(t *testing.T) {
+	previous := GetRepositories()
+	SetRepositories(memory.NewRepositories())
+	defer SetRepositories(previous)
+	previousGetAccount := getAccountByAddressFn
+	defer func() { getAccountByAddressFn = previousGetAccount }()
+
+	getAccountByAddressFn = func(address string) (*model.Account, bool, error) {
+		return nil, false, nil
+	}
+	sellerName, buyerName := "Alpine Nodes AG", "Ratio Cloud AG"
+	require.NoError(t, repos.UserInfos.Create(&model.UserInfo{BlockchainAddress: "0xowner", IsCompany: true, CompanyName: &sellerName, Country: "CHE"}))
+	require.NoError(t, repos.UserInfos.Create(&model.UserInfo{BlockchainAddress: "0xcsp", IsCompany: true, CompanyName: &buyerName, Country: "CHE"}))
+	require.NoError(t, repos.Preferences.Create(&model.Preference{UserAddress: "0xowner", InvoiceSeries: "CH", NextNumber: 1, CountryVat: 8.1}))
+
+	draft, err := generateInvoiceDraft("0xowner", "0xcsp", withTestProfiles(t, createTestAllocations(t, "0xowner", "0xcsp", "1000000")), fixedFxRates(nil))
+	require.NoError(t, err)
+	require.Equal(t, 8.1, draft.VatApplied)
+	require.Equal(t, VatCategoryStandard, draft.VatCategory)
+	require.Empty(t, draft.VatMention)
+}
@@ -2,11 +2,15 @@ package service
 
 import (
 	"encoding/xml"
+	"errors"
+	"fmt"
 	"io"
 	"net/http"
 	"strings"
+	"time"
 
 	"github.com/NaeuralEdgeProtocol/ratio1-backend/config"
+	"github.com/NaeuralEdgeProtocol/ratio1-backend/model"
 )
 
 var euCountries = map[string]string{
@@ -39,36 +43,6 @@ var euCountries = map[string]string{
 	"SWE": "SE",
 }
 
-var euCountriesVat = map[string]int64{
-	"AUT": 20_00,
-	"BEL": 21_00,
-	"BGR": 20_00,
-	"HRV": 25_00,
-	"CYP": 19_00,
-	"CZE": 21_00,
-	"DNK": 25_00,
-	"EST": 22_00,
-	"FIN": 26_00,
-	"FRA": 20_00,
-	"DEU": 19_00,
-	"GRC": 24_00,
-	"HUN": 27_00,
-	"IRL": 23_00,
-	"ITA": 22_00,
-	"LVA": 21_00,
-	"LTU": 21_00,
-	"LUX": 17_00,
-	"MLT": 18_00,
-	"NLD": 21_00,
-	"POL": 23_00,
-	"PRT": 23_00,
-	"ROU": 21_00,
-	"SVK": 23_00,
-	"SVN": 22_00,
-	"ESP": 21_00,
-	"SWE": 25_00,
-}
-
 var countriesName = map[string]string{
 	"ABW": "Aruba",
 	"AFG": "Afganistan",
@@ -321,8 +295,6 @@ var countriesName = map[string]string{
 	"MNP": "Insulele Mariane de Nord",
 }
 
-const ROUVatPerc = 21_00
-
 type VIESResponse struct {
 	XMLName xml.Name `xml:"result"`
 	Vies    struct {
@@ -344,56 +316,199 @@ type VIESResponse struct {
 	} `xml:"error"`
 }
 
-func IsCompanyRegisteredAndUE(countryCode, vat string) (idRegistered bool, isUe bool) {
+var (
+	ErrorViesUnavailable = errors.New("VIES is unavailable, try again later")
+	errViesTransient     = errors.New("transient VIES error")
+)
+
+// viesCacheTtl is how long the answer of VIES for a VAT number is reused.
+var viesCacheTtl = 24 * time.Hour
+
+// viesRetryDelays are the waits before asking VIES again after a transient
+// error, one more attempt than delays is made.
+var viesRetryDelays = []time.Duration{time.Second, 3 * time.Second}
+
+// viesTransientCodes are the VIES error codes of a member state or of VIES
+// itself being unavailable, the other codes are an answer about the number.
+var viesTransientCodes = map[string]bool{
+	"SERVICE_UNAVAILABLE":       true,
+	"MS_UNAVAILABLE":            true,
+	"TIMEOUT":                   true,
+	"SERVER_BUSY":               true,
+	"MS_MAX_CONCURRENT_REQ":     true,
+	"GLOBAL_MAX_CONCURRENT_REQ": true,
+}
+
+var consultViesFn = consultVies
+
+// ValidateVatNumber returns the VIES check of the VAT number of a company of
+// countryCode, nil when the country is not in the EU. A check younger than
+// viesCacheTtl is reused, otherwise VIES is asked and its answer stored as
+// evidence. ErrorViesUnavailable is returned when VIES could not answer.
+func ValidateVatNumber(countryCode, vat string) (*model.ViesCheck, error) {
+	return validateVatNumber(countryCode, vat, viesCacheTtl)
+}
+
+func validateVatNumber(countryCode, vat string, maxAge time.Duration) (*model.ViesCheck, error) {
+	vat, ok := viesVatNumber(countryCode, vat)
+	if !ok {
+		return nil, nil
+	}
+
+	if maxAge > 0 {
+		cached, err := repos.ViesChecks.GetLatest(vat)
+		if err != nil {
+			return nil, errors.New("error while retrieving vies check: " + err.Error())
+		}
+		if cached != nil && time.Since(cached.CheckedAt) < maxAge {
+			return cached, nil
+		}
+	}
+
+	var viesResp *VIESResponse
+	var err error
+	for attempt := 0; ; attempt++ {
+		viesResp, err = consultViesFn(vat)
+		if !errors.Is(err, errViesTransient) || attempt >= len(viesRetryDelays) {
+			break
+		}
+		log.Error("vies unavailable for " + vat + ", retrying: " + err.Error())
+		time.Sleep(viesRetryDelays[attempt])
+	}
+	if errors.Is(err, errViesTransient) {
+		return nil, fmt.Errorf("%w: %s", ErrorViesUnavailable, err.Error())
+	} else if err != nil {
+		return nil, err
+	}
+
+	check := &model.ViesCheck{
+		VatNumber:     vat,
+		Valid:         viesResp.Error.Code == "" && viesResp.Vies.Valid,
+		RequestId:     viesResp.Vies.ID,
+		TraderName:    viesResp.Vies.TraderName,
+		TraderAddress: viesResp.Vies.TraderAddress,
+		ViesDate:      viesResp.Vies.Date,
+		Source:        viesResp.Vies.Source,
+		ErrorCode:     viesResp.Error.Code,
+		CheckedAt:     time.Now().UTC(),
+	}
+	err = repos.ViesChecks.Create(check)
+	if err != nil {
+		return nil, errors.New("error while saving vies check: " + err.Error())
+	}
+	return check, nil
+}
+
+// viesVatNumber returns the VAT number with the two letter prefix VIES
+// expects, false when the country is not in the EU.
+func viesVatNumber(countryCode, vat string) (string, bool) {
 	twoLetter, ok := euCountries[strings.ToUpper(countryCode)]
 	if !ok {
-		return false, false
+		return "", false
 	}
 
-	vat = strings.ToUpper(strings.TrimSpace(vat))
+	vat = strings.ToUpper(strings.ReplaceAll(strings.TrimSpace(vat), " ", ""))
 	if !strings.HasPrefix(vat, twoLetter) {
 		vat = twoLetter + vat
 	}
+	return vat, true
+}
+
+// consultVies asks the VIES proxy about a VAT number, the errors of the call
+// and the unavailability codes of VIES wrap errViesTransient.
+func consultVies(vat string) (*VIESResponse, error) {
 	url := config.Config.ViesApi.BaseUrl + "/get/vies/euvat/" + vat
 	authURL := strings.Replace(url, "https://", "https://"+config.Config.ViesApi.User+":"+config.Config.ViesApi.Password+"@", 1)
 
 	resp, err := http.Get(authURL)
 	if err != nil {
-		log.Error("error while calling api: " + err.Error())
-		return false, true
+		return nil, fmt.Errorf("%w: error while calling api: %s", errViesTransient, err.Error())
 	}
 	defer resp.Body.Close()
 
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
-		log.Error("error while reading response: " + err.Error())
-		return false, true
+		return nil, fmt.Errorf("%w: error while reading response: %s", errViesTransient, err.Error())
+	}
+	if resp.StatusCode >= http.StatusInternalServerError || resp.StatusCode == http.StatusTooManyRequests {
+		return nil, fmt.Errorf("%w: status code %d", errViesTransient, resp.StatusCode)
 	}
 
 	var viesResp VIESResponse
 	if err := xml.Unmarshal(body, &viesResp); err != nil {
-		log.Error("error while parsing response: " + err.Error())
-		return false, true
+		return nil, fmt.Errorf("%w: error while parsing response: %s", errViesTransient, err.Error())
+	}
+	if viesTransientCodes[viesResp.Error.Code] {
+		return nil, fmt.Errorf("%w: error code %s: %s %s", errViesTransient, viesResp.Error.Code, viesResp.Error.Description, viesResp.Error.Details)
 	}
 	if viesResp.Error.Code != "" {
 		log.Error("error code: " + viesResp.Error.Code + " with description: " + viesResp.Error.Description + " details: " + viesResp.Error.Details)
-		return false, true
 	}
 
-	return viesResp.Vies.Valid, true
+	return &viesResp, nil
 }
 
-func isUeCountry(countryCode string) bool {
-	_, ok := euCountries[strings.ToUpper(countryCode)]
-	return ok
+// GetViesChecks returns the VIES checks stored for a VAT number, oldest first.
+func GetViesChecks(countryCode, vat string) ([]model.ViesCheck, error) {
+	vat, ok := viesVatNumber(countryCode, vat)
+	if !ok {
+		return []model.ViesCheck{}, nil
+	}
+
+	checks, err := repos.ViesChecks.GetAll(vat)
+	if err != nil {
+		return nil, errors.New("error while retrieving vies checks: " + err.Error())
+	}
+	if checks == nil {
+		checks = []model.ViesCheck{}
+	}
+	return checks, nil
 }
 
-func GetEuVatPercentage(countryCode string) *int64 {
-	vat, ok := euCountriesVat[strings.ToUpper(countryCode)]
-	if !ok {
-		return nil
+// RevalidateVatNumbers asks VIES again about the VAT numbers of the active
+// company customers and updates their kyc when the answer changed. It runs on
+// the daily schedule of every node, so a VAT number checked less than
+// viesCacheTtl ago is not asked again.
+func RevalidateVatNumbers() {
+	companies, err := repos.UserInfos.GetCompanies()
+	if err != nil {
+		fmt.Println("error while retrieving company customers: " + err.Error())
+		return
+	}
+
+	for _, company := range companies {
+		if !isUeCountry(company.Country) || company.Email == "" || company.IdentificationCode == "" {
+			continue
+		}
+		kyc, found, err := repos.Kycs.GetByEmail(company.Email)
+		if err != nil {
+			fmt.Println("error while retrieving kyc of " + company.BlockchainAddress + ": " + err.Error())
+			continue
+		} else if !found || !kyc.IsActive || kyc.HasBeenDeleted || kyc.KycStatus != model.StatusApproved {
+			continue
+		}
+
+		check, err := validateVatNumber(company.Country, company.IdentificationCode, viesCacheTtl)
+		if err != nil {
+			fmt.Println("error while revalidating vat number of " + company.BlockchainAddress + ": " + err.Error())
+			continue
+		}
+		if check.Valid == kyc.ViesRegistered {
+			continue
+		}
+
+		fmt.Printf("vat number %s of %s is now valid: %t\n", check.VatNumber, company.BlockchainAddress, check.Valid)
+		kyc.ViesRegistered = check.Valid
+		err = repos.Kycs.Update(kyc)
+		if err != nil {
+			fmt.Println("error while updating kyc of " + company.BlockchainAddress + ": " + err.Error())
+		}
 	}
-	return &vat
+}
+
+func isUeCountry(countryCode string) bool {
+	_, ok := euCountries[strings.ToUpper(countryCode)]
+	return ok
 }
 
 func GetRoNameForISOCode(isoCode string) string {
@@ -1,9 +1,14 @@
 package service
 
 import (
+	"fmt"
 	"testing"
+	"time"
 
 	"github.com/NaeuralEdgeProtocol/ratio1-backend/config"
+	"github.com/NaeuralEdgeProtocol/ratio1-backend/model"
+	"github.com/NaeuralEdgeProtocol/ratio1-backend/storage/memory"
+	"github.com/google/uuid"
 	"github.com/stretchr/testify/require"
 )
 
@@ -13,8 +18,155 @@ func Test_ViesIntegration(t *testing.T) {
 		User:     "test_id",
 		Password: "test_key",
 	}
-	isValid, isUe := IsCompanyRegisteredAndUE("POL", "7171642051")
-	require.True(t, isUe)
-	require.True(t, isValid)
+	previous := GetRepositories()
+	SetRepositories(memory.NewRepositories())
+	defer SetRepositories(previous)
 
+	check, err := ValidateVatNumber("POL", "7171642051")
+	require.NoError(t, err)
+	require.NotNil(t, check)
+	require.True(t, check.Valid)
+
+}
+
+// withViesAnswers makes the VIES consultations return the given answers in
+// order, an error string is returned as a transient error.
+func withViesAnswers(t *testing.T, answers ...any) *int {
+	previousRepos := repos
+	SetRepositories(memory.NewRepositories())
+	previousConsult, previousDelays := consultViesFn, viesRetryDelays
+	viesRetryDelays = []time.Duration{0, 0}
+	t.Cleanup(func() {
+		SetRepositories(previousRepos)
+		consultViesFn, viesRetryDelays = previousConsult, previousDelays
+	})
+
+	calls := 0
+	consultViesFn = func(vat string) (*VIESResponse, error) {
+		require.Less(t, calls, len(answers), "unexpected vies consultation of "+vat)
+		answer := answers[calls]
+		calls++
+		if text, ok := answer.(string); ok {
+			return nil, fmt.Errorf("%w: %s", errViesTransient, text)
+		}
+		return answer.(*VIESResponse), nil
+	}
+	return &calls
+}
+
+func viesAnswer(valid bool, id string) *VIESResponse {
+	resp := &VIESResponse{}
+	resp.Vies.Valid = valid
+	resp.Vies.ID = id
+	resp.Vies.TraderName = "Ratio Cloud GmbH"
+	resp.Vies.TraderAddress = "Berlin"
+	resp.Vies.Date = "2026-10-19"
+	return resp
+}
+
+func TestValidateVatNumberStoresEvidenceAndCaches(t *testing.T) {
+	calls := withViesAnswers(t, viesAnswer(true, "WAPIAAAAX"))
+
+	check, err := ValidateVatNumber("DEU", " 123 456 789")
+	require.NoError(t, err)
+	require.True(t, check.Valid)
+	require.Equal(t, "DE123456789", check.VatNumber)
+	require.Equal(t, "WAPIAAAAX", check.RequestId)
+	require.Equal(t, "Ratio Cloud GmbH", check.TraderName)
+	require.NotZero(t, check.Id)
+
+	cached, err := ValidateVatNumber("DEU", "DE123456789")
+	require.NoError(t, err)
+	require.Equal(t, check.Id, cached.Id)
+	require.Equal(t, 1, *calls)
+
+	check, err = ValidateVatNumber("USA", "123")
+	require.NoError(t, err)
+	require.Nil(t, check)
+
+	checks, err := GetViesChecks("DEU", "123456789")
+	require.NoError(t, err)
+	require.Len(t, checks, 1)
+}
+
+func TestValidateVatNumberRetriesTransientErrors(t *testing.T) {
+	calls := withViesAnswers(t, "MS_UNAVAILABLE", "timeout", viesAnswer(false, "WAPIAAAAY"))
+
+	check, err := ValidateVatNumber("FRA", "12345678901")
+	require.NoError(t, err)
+	require.False(t, check.Valid)
+	require.Equal(t, 3, *calls)
+
+	calls = withViesAnswers(t, "MS_UNAVAILABLE", "MS_UNAVAILABLE", "MS_UNAVAILABLE")
+	_, err = ValidateVatNumber("FRA", "12345678901")
+	require.ErrorIs(t, err, ErrorViesUnavailable)
+	require.Equal(t, 3, *calls)
+	checks, err := GetViesChecks("FRA", "12345678901")
+	require.NoError(t, err)
+	require.Empty(t, checks)
+
+	invalidInput := &VIESResponse{}
+	invalidInput.Error.Code = "INVALID_INPUT"
+	withViesAnswers(t, invalidInput)
+	check, err = ValidateVatNumber("FRA", "1")
+	require.NoError(t, err)
+	require.False(t, check.Valid)
+	require.Equal(t, "INVALID_INPUT", check.ErrorCode)
+}
+
+func TestViesRegisteredToleratesUnavailableVies(t *testing.T) {
+	withViesAnswers(t, "MS_UNAVAILABLE", "MS_UNAVAILABLE", "MS_UNAVAILABLE")
+	registered, err := viesRegistered("DEU", "123456789")
+	require.NoError(t, err)
+	require.False(t, registered)
+
+	withViesAnswers(t, viesAnswer(true, "WAPIAAAAW"))
+	registered, err = viesRegistered("DEU", "123456789")
+	require.NoError(t, err)
+	require.True(t, registered)
+}
+
+func TestRevalidateVatNumbersUpdatesKyc(t *testing.T) {
+	calls := withViesAnswers(t, viesAnswer(false, "WAPIAAAAZ"))
+
+	companyName := "Ratio Cloud GmbH"
+	require.NoError(t, repos.UserInfos.Create(&model.UserInfo{BlockchainAddress: "0xcsp", Email: "csp@example.com", IsCompany: true, CompanyName: &companyName, IdentificationCode: "123456789", Country: "DEU"}))
+	require.NoError(t, repos.UserInfos.Create(&model.UserInfo{BlockchainAddress: "0xus", Email: "us@example.com", IsCompany: true, CompanyName: &companyName, IdentificationCode: "123", Country: "USA"}))
+	require.NoError(t, repos.Kycs.CreateOrUpdate(&model.Kyc{Uuid: uuid.New(), Email: "csp@example.com", KycStatus: model.StatusApproved, IsActive: true, ViesRegistered: true}))
+	require.NoError(t, repos.Kycs.CreateOrUpdate(&model.Kyc{Uuid: uuid.New(), Email: "us@example.com", KycStatus: model.StatusApproved, IsActive: true}))
+	// a valid answer of the day before is asked again
+	require.NoError(t, repos.ViesChecks.Create(&model.ViesCheck{VatNumber: "DE123456789", Valid: true, CheckedAt: time.Now().Add(-25 * time.Hour)}))
+
+	RevalidateVatNumbers()
+	require.Equal(t, 1, *calls)
+
+	kyc, found, err := repos.Kycs.GetByEmail("csp@example.com")
+	require.NoError(t, err)
+	require.True(t, found)
+	require.False(t, kyc.ViesRegistered)
+
+	latest, err := repos.ViesChecks.GetLatest("DE123456789")
+	require.NoError(t, err)
+	require.Equal(t, "WAPIAAAAZ", latest.RequestId)
+}
+
+func TestRevalidateVatNumbersAsksViesOncePerDay(t *testing.T) {
+	calls := withViesAnswers(t, viesAnswer(true, "WAPIAAAAA"), viesAnswer(true, "WAPIAAAAB"))
+
+	companyName := "Ratio Cloud GmbH"
+	for _, customer := range []struct{ address, email, vat string }{
+		{"0xcsp1", "csp1@example.com", "123456789"},
+		{"0xcsp2", "csp2@example.com", "987654321"},
+	} {
+		require.NoError(t, repos.UserInfos.Create(&model.UserInfo{BlockchainAddress: customer.address, Email: customer.email, IsCompany: true, CompanyName: &companyName, IdentificationCode: customer.vat, Country: "DEU"}))
+		require.NoError(t, repos.Kycs.CreateOrUpdate(&model.Kyc{Uuid: uuid.New(), Email: customer.email, KycStatus: model.StatusApproved, IsActive: true, ViesRegistered: true}))
+	}
+
+	RevalidateVatNumbers()
+	RevalidateVatNumbers()
+	require.Equal(t, 2, *calls)
+
+	checks, err := GetViesChecks("DEU", "123456789")
+	require.NoError(t, err)
+	require.Len(t, checks, 1)
 }
@@ -0,0 +1,82 @@
+package storage
+
+import (
+	"github.com/NaeuralEdgeProtocol/ratio1-backend/model"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+func CreateAccountErasureRequest(request *model.AccountErasureRequest) error {
+	return Transaction(func(tx *gorm.DB) error {
+		return tx.Create(request).Error
+	})
+}
+
+func UpdateAccountErasureRequest(request *model.AccountErasureRequest) error {
+	return Transaction(func(tx *gorm.DB) error {
+		txUpdate := tx.Save(request)
+		if txUpdate.Error != nil {
+			return txUpdate.Error
+		}
+		if txUpdate.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+
+		return nil
+	})
+}
+
+func GetAccountErasureRequestById(id uuid.UUID) (*model.AccountErasureRequest, error) {
+	db, err := GetDB()
+	if err != nil {
+		return nil, err
+	}
+
+	var request model.AccountErasureRequest
+	txRead := db.Find(&request, "id = ?", id)
+	if txRead.Error != nil {
+		return nil, txRead.Error
+	}
+	if txRead.RowsAffected == 0 {
+		return nil, nil
+	}
+
+	return &request, nil
+}
+
+// GetOpenAccountErasureRequest returns the pending or approved request of
+// address, if any.
+func GetOpenAccountErasureRequest(address string) (*model.AccountErasureRequest, error) {
+	db, err := GetDB()
+	if err != nil {
+		return nil, err
+	}
+
+	var request model.AccountErasureRequest
+	txRead := db.Where("address = ? AND status IN ?", address, []string{model.AccountErasureStatusPending, model.AccountErasureStatusApproved}).
+		Limit(1).
+		Find(&request)
+	if txRead.Error != nil {
+		return nil, txRead.Error
+	}
+	if txRead.RowsAffected == 0 {
+		return nil, nil
+	}
+
+	return &request, nil
+}
+
+func GetAccountErasureRequestsByStatus(status string) ([]model.AccountErasureRequest, error) {
+	db, err := GetDB()
+	if err != nil {
+		return nil, err
+	}
+
+	var requests []model.AccountErasureRequest
+	txRead := db.Where("status = ?", status).Order("created_at").Find(&requests)
+	if txRead.Error != nil {
+		return nil, txRead.Error
+	}
+
+	return requests, nil
+}
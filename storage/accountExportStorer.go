@@ -0,0 +1,79 @@
+package storage
+
+import (
+	"time"
+
+	"github.com/NaeuralEdgeProtocol/ratio1-backend/model"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+func CreateAccountExport(export *model.AccountExport) error {
+	return Transaction(func(tx *gorm.DB) error {
+		return tx.Create(export).Error
+	})
+}
+
+func UpdateAccountExport(export *model.AccountExport) error {
+	return Transaction(func(tx *gorm.DB) error {
+		txUpdate := tx.Save(export)
+		if txUpdate.Error != nil {
+			return txUpdate.Error
+		}
+		if txUpdate.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+
+		return nil
+	})
+}
+
+func GetAccountExportById(id uuid.UUID) (*model.AccountExport, error) {
+	db, err := GetDB()
+	if err != nil {
+		return nil, err
+	}
+
+	var export model.AccountExport
+	txRead := db.Find(&export, "id = ?", id)
+	if txRead.Error != nil {
+		return nil, txRead.Error
+	}
+	if txRead.RowsAffected == 0 {
+		return nil, nil
+	}
+
+	return &export, nil
+}
+
+// GetLatestAccountExport returns the most recent export requested for address,
+// without its archive.
+func GetLatestAccountExport(address string) (*model.AccountExport, error) {
+	db, err := GetDB()
+	if err != nil {
+		return nil, err
+	}
+
+	var export model.AccountExport
+	txRead := db.Omit("archive").Where("address = ?", address).Order("created_at DESC").Limit(1).Find(&export)
+	if txRead.Error != nil {
+		return nil, txRead.Error
+	}
+	if txRead.RowsAffected == 0 {
+		return nil, nil
+	}
+
+	return &export, nil
+}
+
+func DeleteAccountExports(address string) error {
+	return Transaction(func(tx *gorm.DB) error {
+		return tx.Where("address = ?", address).Delete(&model.AccountExport{}).Error
+	})
+}
+
+func DeleteExpiredAccountExports(now time.Time) error {
+	return Transaction(func(tx *gorm.DB) error {
+		return tx.Where("expires_at < ?", now).Delete(&model.AccountExport{}).Error
+	})
+}
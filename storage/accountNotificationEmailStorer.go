@@ -2,6 +2,7 @@ package storage
 
 import (
 	"github.com/NaeuralEdgeProtocol/ratio1-backend/model"
+	"gorm.io/gorm"
 )
 
 func GetAccountNotificationEmailByAddress(address string) (*model.AccountNotificationEmail, bool, error) {
@@ -23,35 +24,26 @@ func GetAccountNotificationEmailByAddress(address string) (*model.AccountNotific
 }
 
 func CreateOrUpdateAccountNotificationEmail(notificationEmail *model.AccountNotificationEmail) error {
-	db, err := GetDB()
-	if err != nil {
-		return err
-	}
+	return Transaction(func(tx *gorm.DB) error {
+		txUpdate := tx.Save(notificationEmail)
+		if txUpdate.Error != nil {
+			return txUpdate.Error
+		}
+		if txUpdate.RowsAffected == 0 {
+			return nil
+		}
 
-	txUpdate := db.Save(notificationEmail)
-	if txUpdate.Error != nil {
-		txUpdate.Rollback()
-		return txUpdate.Error
-	}
-	if txUpdate.RowsAffected == 0 {
-		txUpdate.Rollback()
 		return nil
-	}
-
-	return nil
+	})
 }
 
 func DeleteAccountNotificationEmail(address string) error {
-	db, err := GetDB()
-	if err != nil {
-		return err
-	}
+	return Transaction(func(tx *gorm.DB) error {
+		txDelete := tx.Delete(&model.AccountNotificationEmail{}, "account_address = ?", address)
+		if txDelete.Error != nil {
+			return txDelete.Error
+		}
 
-	txDelete := db.Delete(&model.AccountNotificationEmail{}, "account_address = ?", address)
-	if txDelete.Error != nil {
-		txDelete.Rollback()
-		return txDelete.Error
-	}
-
-	return nil
+		return nil
+	})
 }
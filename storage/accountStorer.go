@@ -42,41 +42,31 @@ func GetAccountByEmail(email string) (*model.Account, bool, error) {
 }
 
 func CreateAccount(account *model.Account) error {
-	db, err := GetDB()
-	if err != nil {
-		return err
-	}
-
-	txCreate := db.Create(&account)
-	if txCreate.Error != nil {
-		txCreate.Rollback()
-		return txCreate.Error
-	}
-	if txCreate.RowsAffected == 0 {
-		txCreate.Rollback()
-		return gorm.ErrRecordNotFound
-	}
-
-	return nil
+	return Transaction(func(tx *gorm.DB) error {
+		txCreate := tx.Create(&account)
+		if txCreate.Error != nil {
+			return txCreate.Error
+		}
+		if txCreate.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+
+		return nil
+	})
 }
 
 func UpdateAccount(account *model.Account) error {
-	db, err := GetDB()
-	if err != nil {
-		return err
-	}
-
-	txUpdate := db.Save(&account)
-	if txUpdate.Error != nil {
-		txUpdate.Rollback()
-		return txUpdate.Error
-	}
-	if txUpdate.RowsAffected == 0 {
-		txUpdate.Rollback()
-		return gorm.ErrRecordNotFound
-	}
-
-	return nil
+	return Transaction(func(tx *gorm.DB) error {
+		txUpdate := tx.Save(&account)
+		if txUpdate.Error != nil {
+			return txUpdate.Error
+		}
+		if txUpdate.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+
+		return nil
+	})
 }
 
 func GetAccountsBySellerCode(sellerCode string) (*[]model.Account, error) {
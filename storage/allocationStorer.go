@@ -27,45 +27,60 @@ func GetLatestAllocationBlock() (int64, error) {
 }
 
 func CreateAllocation(alloc *model.Allocation) error {
-	db, err := GetDB()
+	return Transaction(func(tx *gorm.DB) error {
+		txCreate := tx.Clauses(clause.OnConflict{
+			Columns: []clause.Column{
+				{Name: "tx_hash"},
+				{Name: "log_index"},
+			},
+			TargetWhere: clause.Where{Exprs: []clause.Expression{
+				clause.Expr{SQL: "log_index IS NOT NULL"},
+			}},
+			DoNothing: true,
+		}).Create(&alloc)
+		if txCreate.Error != nil {
+			return txCreate.Error
+		}
+
+		return nil
+	})
+}
+
+func GetAllocationsInBlockRange(from, to int64) ([]model.Allocation, error) {
+	db, err := GetReadDB()
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	txCreate := db.Clauses(clause.OnConflict{
-		Columns: []clause.Column{
-			{Name: "tx_hash"},
-			{Name: "log_index"},
-		},
-		TargetWhere: clause.Where{Exprs: []clause.Expression{
-			clause.Expr{SQL: "log_index IS NOT NULL"},
-		}},
-		DoNothing: true,
-	}).Create(&alloc)
-	if txCreate.Error != nil {
-		return txCreate.Error
+	var allocations []model.Allocation
+	txRead := db.Where("block_number >= ? AND block_number <= ?", from, to).Order("block_number ASC, id ASC").Find(&allocations)
+	if txRead.Error != nil {
+		return nil, txRead.Error
 	}
 
-	return nil
+	return allocations, nil
 }
 
-func UpdateAllocation(alloc *model.Allocation) error {
-	db, err := GetDB()
-	if err != nil {
-		return err
-	}
+// DeleteUnclaimedAllocationsFromBlock removes the allocations from block on
+// that no draft claimed yet, claimed ones are accounting records already.
+func DeleteUnclaimedAllocationsFromBlock(block int64) error {
+	return Transaction(func(tx *gorm.DB) error {
+		return tx.Where("block_number >= ? AND draft_id IS NULL", block).Delete(&model.Allocation{}).Error
+	})
+}
 
-	txUpdate := db.Save(&alloc)
-	if txUpdate.Error != nil {
-		txUpdate.Rollback()
-		return txUpdate.Error
-	}
-	if txUpdate.RowsAffected == 0 {
-		txUpdate.Rollback()
-		return gorm.ErrRecordNotFound
-	}
+func UpdateAllocation(alloc *model.Allocation) error {
+	return Transaction(func(tx *gorm.DB) error {
+		txUpdate := tx.Save(&alloc)
+		if txUpdate.Error != nil {
+			return txUpdate.Error
+		}
+		if txUpdate.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
 
-	return nil
+		return nil
+	})
 }
 
 func GetAllocationsByCspAndUser(cspAddress, userAddress, nodeAddress string) ([]model.Allocation, error) {
@@ -132,6 +147,54 @@ func GetAllocationsByDraftId(draftId string) ([]model.Allocation, error) {
 	return allocations, nil
 }
 
+// GetAllocationsWithMissingDraft returns the allocations that reference a
+// draft id for which no invoice draft exists.
+func GetAllocationsWithMissingDraft() ([]model.Allocation, error) {
+	db, err := GetDB()
+	if err != nil {
+		return nil, err
+	}
+
+	var allocations []model.Allocation
+	txRead := db.Where("draft_id IS NOT NULL AND NOT EXISTS (SELECT 1 FROM invoice_drafts WHERE invoice_drafts.draft_id = allocations.draft_id)").
+		Order("id").
+		Find(&allocations)
+	if txRead.Error != nil {
+		return nil, txRead.Error
+	}
+
+	return allocations, nil
+}
+
+// ReleaseAllocationsFromDraft clears the draft id of the given allocations so
+// that the next draft generation picks them up again.
+func ReleaseAllocationsFromDraft(ids []uint) error {
+	if len(ids) == 0 {
+		return nil
+	}
+
+	return Transaction(func(tx *gorm.DB) error {
+		return tx.Model(&model.Allocation{}).Where("id IN ?", ids).Update("draft_id", nil).Error
+	})
+}
+
+// GetAllocationsByAddress returns the allocations where address is either the
+// user or the csp owner.
+func GetAllocationsByAddress(address string) ([]model.Allocation, error) {
+	db, err := GetReadDB()
+	if err != nil {
+		return nil, err
+	}
+
+	var allocations []model.Allocation
+	txRead := db.Where("user_address = ? OR csp_owner = ?", address, address).Order("id").Find(&allocations)
+	if txRead.Error != nil {
+		return nil, txRead.Error
+	}
+
+	return allocations, nil
+}
+
 func GetAllocationByJobIDForJobDetails(jobId string) (*model.Allocation, error) {
 	allocations, err := GetAllocationsByJobIDsForJobDetails([]string{jobId})
 	if err != nil {
@@ -10,8 +10,7 @@ import (
 )
 
 func TestGetAllocationsByJobIDsForJobDetails(t *testing.T) {
-	db, err := GetDB()
-	require.NoError(t, err)
+	db := testDB(t)
 
 	now := time.Now().UTC()
 	suffix := now.UnixNano()
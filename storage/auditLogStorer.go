@@ -0,0 +1,27 @@
+package storage
+
+import (
+	"github.com/NaeuralEdgeProtocol/ratio1-backend/model"
+	"gorm.io/gorm"
+)
+
+func CreateAuditLog(entry *model.AuditLog) error {
+	return Transaction(func(tx *gorm.DB) error {
+		return tx.Create(entry).Error
+	})
+}
+
+func GetAuditLogsBySubject(subject string) ([]model.AuditLog, error) {
+	db, err := GetDB()
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []model.AuditLog
+	txRead := db.Where("subject = ?", subject).Order("id").Find(&entries)
+	if txRead.Error != nil {
+		return nil, txRead.Error
+	}
+
+	return entries, nil
+}
@@ -0,0 +1,93 @@
+package storage
+
+import (
+	"time"
+
+	"github.com/NaeuralEdgeProtocol/ratio1-backend/model"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// SaveBlocks stores the headers, a block seen again after a reorg replaces the
+// cached one.
+func SaveBlocks(blocks []model.Block) error {
+	if len(blocks) == 0 {
+		return nil
+	}
+
+	return Transaction(func(tx *gorm.DB) error {
+		txCreate := tx.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "number"}},
+			DoUpdates: clause.AssignmentColumns([]string{"hash", "timestamp"}),
+		}).Create(&blocks)
+		if txCreate.Error != nil {
+			return txCreate.Error
+		}
+
+		return nil
+	})
+}
+
+func GetBlock(number int64) (*model.Block, error) {
+	db, err := GetReadDB()
+	if err != nil {
+		return nil, err
+	}
+
+	var block model.Block
+	txRead := db.Where("number = ?", number).Find(&block)
+	if txRead.Error != nil {
+		return nil, txRead.Error
+	}
+	if txRead.RowsAffected == 0 {
+		return nil, nil
+	}
+
+	return &block, nil
+}
+
+// GetBlockAtOrBefore returns the newest cached block mined at or before
+// timestamp, nil when there is none.
+func GetBlockAtOrBefore(timestamp time.Time) (*model.Block, error) {
+	db, err := GetReadDB()
+	if err != nil {
+		return nil, err
+	}
+
+	var block model.Block
+	txRead := db.Where("timestamp <= ?", timestamp).Order("number DESC").Limit(1).Find(&block)
+	if txRead.Error != nil {
+		return nil, txRead.Error
+	}
+	if txRead.RowsAffected == 0 {
+		return nil, nil
+	}
+
+	return &block, nil
+}
+
+// GetBlockAfter returns the oldest cached block mined after timestamp, nil
+// when there is none.
+func GetBlockAfter(timestamp time.Time) (*model.Block, error) {
+	db, err := GetReadDB()
+	if err != nil {
+		return nil, err
+	}
+
+	var block model.Block
+	txRead := db.Where("timestamp > ?", timestamp).Order("number ASC").Limit(1).Find(&block)
+	if txRead.Error != nil {
+		return nil, txRead.Error
+	}
+	if txRead.RowsAffected == 0 {
+		return nil, nil
+	}
+
+	return &block, nil
+}
+
+func DeleteBlocksFrom(number int64) error {
+	return Transaction(func(tx *gorm.DB) error {
+		return tx.Where("number >= ?", number).Delete(&model.Block{}).Error
+	})
+}
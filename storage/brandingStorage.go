@@ -6,22 +6,17 @@ import (
 )
 
 func SaveBrand(seller *model.Branding) error {
-	db, err := GetDB()
-	if err != nil {
-		return err
-	}
-
-	txCreate := db.Save(&seller)
-	if txCreate.Error != nil {
-		txCreate.Rollback()
-		return txCreate.Error
-	}
-	if txCreate.RowsAffected == 0 {
-		txCreate.Rollback()
-		return gorm.ErrRecordNotFound
-	}
+	return Transaction(func(tx *gorm.DB) error {
+		txCreate := tx.Save(&seller)
+		if txCreate.Error != nil {
+			return txCreate.Error
+		}
+		if txCreate.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
 
-	return nil
+		return nil
+	})
 }
 
 func GetBrandByAddress(address string) (*model.Branding, error) {
@@ -5,29 +5,52 @@ import (
 
 	"github.com/NaeuralEdgeProtocol/ratio1-backend/model"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 func CreateBurnEvent(burnEvent *model.BurnEvent) error {
-	db, err := GetDB()
+	return Transaction(func(tx *gorm.DB) error {
+		txCreate := tx.Clauses(clause.OnConflict{
+			Columns: []clause.Column{
+				{Name: "tx_hash"},
+				{Name: "log_index"},
+			},
+			TargetWhere: clause.Where{Exprs: []clause.Expression{
+				clause.Expr{SQL: "log_index IS NOT NULL"},
+			}},
+			DoNothing: true,
+		}).Create(&burnEvent)
+		if txCreate.Error != nil {
+			return txCreate.Error
+		}
+
+		return nil
+	})
+}
+
+func GetBurnEventsInBlockRange(from, to int64) ([]model.BurnEvent, error) {
+	db, err := GetReadDB()
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	txCreate := db.Create(&burnEvent)
-	if txCreate.Error != nil {
-		txCreate.Rollback()
-		return txCreate.Error
-	}
-	if txCreate.RowsAffected == 0 {
-		txCreate.Rollback()
-		return gorm.ErrRecordNotFound
+	var bEvent []model.BurnEvent
+	txRead := db.Where("block_number >= ? AND block_number <= ?", from, to).Order("block_number ASC").Find(&bEvent)
+	if txRead.Error != nil {
+		return nil, txRead.Error
 	}
 
-	return nil
+	return bEvent, nil
+}
+
+func DeleteBurnEventsFromBlock(block int64) error {
+	return Transaction(func(tx *gorm.DB) error {
+		return tx.Where("block_number >= ?", block).Delete(&model.BurnEvent{}).Error
+	})
 }
 
 func GetBurnEventsByOwnerAddress(userAddress string) ([]model.BurnEvent, error) {
-	db, err := GetDB()
+	db, err := GetReadDB()
 	if err != nil {
 		return nil, err
 	}
@@ -42,7 +65,7 @@ func GetBurnEventsByOwnerAddress(userAddress string) ([]model.BurnEvent, error)
 }
 
 func GetBurnEventsForUserInTimeRange(start, end time.Time, userAddress string) ([]model.BurnEvent, error) {
-	db, err := GetDB()
+	db, err := GetReadDB()
 	if err != nil {
 		return nil, err
 	}
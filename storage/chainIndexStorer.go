@@ -0,0 +1,259 @@
+package storage
+
+import (
+	"errors"
+	"time"
+
+	"github.com/NaeuralEdgeProtocol/ratio1-backend/model"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ErrIndexerCheckpointMoved is returned when another indexer moved the
+// checkpoint of the stream in the meantime.
+var ErrIndexerCheckpointMoved = errors.New("indexer checkpoint moved concurrently")
+
+func GetIndexerCheckpoint(stream string) (*model.IndexerCheckpoint, error) {
+	db, err := GetDB()
+	if err != nil {
+		return nil, err
+	}
+
+	var checkpoint model.IndexerCheckpoint
+	txRead := db.Where("stream = ?", stream).Find(&checkpoint)
+	if txRead.Error != nil {
+		return nil, txRead.Error
+	}
+	if txRead.RowsAffected == 0 {
+		return nil, nil
+	}
+
+	return &checkpoint, nil
+}
+
+// GetIndexedBlocks returns the tracked blocks of stream, newest first.
+func GetIndexedBlocks(stream string) ([]model.IndexedBlock, error) {
+	db, err := GetDB()
+	if err != nil {
+		return nil, err
+	}
+
+	var blocks []model.IndexedBlock
+	txRead := db.Where("stream = ?", stream).Order("block_number DESC").Find(&blocks)
+	if txRead.Error != nil {
+		return nil, txRead.Error
+	}
+
+	return blocks, nil
+}
+
+// AdvanceIndexerCheckpoint moves the checkpoint of stream from previous, nil
+// when the stream has none yet, to block. Only the last keep blocks are
+// tracked.
+func AdvanceIndexerCheckpoint(stream string, previous *int64, block model.IndexedBlock, keep int) error {
+	return Transaction(func(tx *gorm.DB) error {
+		var current model.IndexerCheckpoint
+		txRead := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("stream = ?", stream).Find(&current)
+		if txRead.Error != nil {
+			return txRead.Error
+		}
+		found := txRead.RowsAffected > 0
+		if found != (previous != nil) || (found && current.BlockNumber != *previous) {
+			return ErrIndexerCheckpointMoved
+		}
+
+		return moveIndexerCheckpoint(tx, stream, block, keep)
+	})
+}
+
+// RewindIndexerCheckpoint moves the checkpoint of stream back to block and
+// forgets the tracked blocks after it.
+func RewindIndexerCheckpoint(stream string, block model.IndexedBlock) error {
+	return Transaction(func(tx *gorm.DB) error {
+		txDelete := tx.Where("stream = ? AND block_number > ?", stream, block.BlockNumber).Delete(&model.IndexedBlock{})
+		if txDelete.Error != nil {
+			return txDelete.Error
+		}
+
+		return moveIndexerCheckpoint(tx, stream, block, 0)
+	})
+}
+
+func moveIndexerCheckpoint(tx *gorm.DB, stream string, block model.IndexedBlock, keep int) error {
+	block.Stream = stream
+	txSave := tx.Save(&model.IndexerCheckpoint{
+		Stream:      stream,
+		BlockNumber: block.BlockNumber,
+		BlockHash:   block.BlockHash,
+		UpdatedAt:   time.Now(),
+	})
+	if txSave.Error != nil {
+		return txSave.Error
+	}
+
+	txSave = tx.Save(&block)
+	if txSave.Error != nil {
+		return txSave.Error
+	}
+
+	if keep > 0 {
+		txDelete := tx.Where("stream = ? AND block_number NOT IN (?)", stream,
+			tx.Model(&model.IndexedBlock{}).Select("block_number").Where("stream = ?", stream).Order("block_number DESC").Limit(keep),
+		).Delete(&model.IndexedBlock{})
+		if txDelete.Error != nil {
+			return txDelete.Error
+		}
+	}
+
+	return nil
+}
+
+func CreateLicensePurchase(purchase *model.LicensePurchaseEvent) error {
+	return Transaction(func(tx *gorm.DB) error {
+		txCreate := tx.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "tx_hash"}, {Name: "log_index"}},
+			DoNothing: true,
+		}).Create(&purchase)
+		if txCreate.Error != nil {
+			return txCreate.Error
+		}
+
+		return nil
+	})
+}
+
+func GetLicensePurchase(id uint) (*model.LicensePurchaseEvent, error) {
+	db, err := GetDB()
+	if err != nil {
+		return nil, err
+	}
+
+	var purchase model.LicensePurchaseEvent
+	txRead := db.Find(&purchase, "id = ?", id)
+	if txRead.Error != nil {
+		return nil, txRead.Error
+	}
+	if txRead.RowsAffected == 0 {
+		return nil, nil
+	}
+
+	return &purchase, nil
+}
+
+func GetLicensePurchaseByInvoiceID(invoiceID string) (*model.LicensePurchaseEvent, error) {
+	db, err := GetDB()
+	if err != nil {
+		return nil, err
+	}
+
+	var purchase model.LicensePurchaseEvent
+	txRead := db.Order("block_number DESC, log_index DESC").Limit(1).Find(&purchase, "invoice_id = ?", invoiceID)
+	if txRead.Error != nil {
+		return nil, txRead.Error
+	}
+	if txRead.RowsAffected == 0 {
+		return nil, nil
+	}
+
+	return &purchase, nil
+}
+
+// GetLicensePurchasesByStatus returns the purchases in one of statuses, all of
+// them when none is given, in chain order.
+func GetLicensePurchasesByStatus(statuses ...string) ([]model.LicensePurchaseEvent, error) {
+	db, err := GetReadDB()
+	if err != nil {
+		return nil, err
+	}
+
+	query := db.Order("block_number ASC, log_index ASC")
+	if len(statuses) > 0 {
+		query = query.Where("status IN ?", statuses)
+	}
+
+	var purchases []model.LicensePurchaseEvent
+	txRead := query.Find(&purchases)
+	if txRead.Error != nil {
+		return nil, txRead.Error
+	}
+
+	return purchases, nil
+}
+
+// GetDueLicensePurchases returns the pending purchases whose next attempt is
+// due at now, in chain order.
+func GetDueLicensePurchases(now time.Time) ([]model.LicensePurchaseEvent, error) {
+	db, err := GetDB()
+	if err != nil {
+		return nil, err
+	}
+
+	var purchases []model.LicensePurchaseEvent
+	txRead := db.Order("block_number ASC, log_index ASC").
+		Where("status = ? AND (next_attempt_at IS NULL OR next_attempt_at <= ?)", model.LicensePurchaseStatusPending, now).
+		Find(&purchases)
+	if txRead.Error != nil {
+		return nil, txRead.Error
+	}
+
+	return purchases, nil
+}
+
+func UpdateLicensePurchase(purchase *model.LicensePurchaseEvent) error {
+	return Transaction(func(tx *gorm.DB) error {
+		txUpdate := tx.Save(purchase)
+		if txUpdate.Error != nil {
+			return txUpdate.Error
+		}
+		if txUpdate.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+
+		return nil
+	})
+}
+
+// DeleteUninvoicedLicensePurchasesFromBlock removes the purchases from block
+// on that have no invoice yet, invoiced or completed ones are accounting
+// records already.
+func DeleteUninvoicedLicensePurchasesFromBlock(block int64) error {
+	return Transaction(func(tx *gorm.DB) error {
+		return tx.Where("block_number >= ? AND invoice_number IS NULL AND status <> ?", block, model.LicensePurchaseStatusCompleted).
+			Delete(&model.LicensePurchaseEvent{}).Error
+	})
+}
+
+func CreateTokenTransfer(transfer *model.TokenTransfer) error {
+	return Transaction(func(tx *gorm.DB) error {
+		txCreate := tx.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "tx_hash"}, {Name: "log_index"}},
+			DoNothing: true,
+		}).Create(&transfer)
+		if txCreate.Error != nil {
+			return txCreate.Error
+		}
+
+		return nil
+	})
+}
+
+func GetTokenTransfersInBlockRange(from, to int64) ([]model.TokenTransfer, error) {
+	db, err := GetReadDB()
+	if err != nil {
+		return nil, err
+	}
+
+	var transfers []model.TokenTransfer
+	txRead := db.Where("block_number >= ? AND block_number <= ?", from, to).Order("block_number ASC, log_index ASC").Find(&transfers)
+	if txRead.Error != nil {
+		return nil, txRead.Error
+	}
+
+	return transfers, nil
+}
+
+func DeleteTokenTransfersFromBlock(block int64) error {
+	return Transaction(func(tx *gorm.DB) error {
+		return tx.Where("block_number >= ?", block).Delete(&model.TokenTransfer{}).Error
+	})
+}
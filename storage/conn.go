@@ -6,6 +6,7 @@ import (
 	"sync"
 
 	"github.com/NaeuralEdgeProtocol/ratio1-backend/config"
+	"github.com/NaeuralEdgeProtocol/ratio1-backend/crypto"
 	"github.com/NaeuralEdgeProtocol/ratio1-backend/model"
 	_ "github.com/lib/pq"
 	"gorm.io/driver/postgres"
@@ -37,10 +38,18 @@ func Connect() {
 
 		database = conn
 
+		keyring, err := crypto.LoadPiiKeyring(config.Config.Pii)
+		if err != nil {
+			panic(err)
+		}
+		SetPiiKeyring(keyring)
+
 		err = TryMigrate()
 		if err != nil {
 			panic(err)
 		}
+
+		connectReplicas()
 	})
 }
 
@@ -50,14 +59,29 @@ func TryMigrate() error {
 		&model.AccountNotificationEmail{},
 		&model.Kyc{},
 		&model.InvoiceClient{},
+		&model.InvoiceStatusChange{},
 		&model.Seller{},
 		&model.Stats{},
 		&model.Allocation{},
 		&model.Preference{},
+		&model.InvoiceSeries{},
 		&model.InvoiceDraft{},
+		&model.InvoiceDraftTransition{},
 		&model.UserInfo{},
+		&model.UserInfoVersion{},
 		&model.BurnEvent{},
 		&model.Branding{},
+		&model.AccountExport{},
+		&model.AccountErasureRequest{},
+		&model.AuditLog{},
+		&model.IndexerCheckpoint{},
+		&model.IndexedBlock{},
+		&model.Block{},
+		&model.FxRate{},
+		&model.VatRate{},
+		&model.ViesCheck{},
+		&model.LicensePurchaseEvent{},
+		&model.TokenTransfer{},
 	)
 	if err != nil {
 		return err
@@ -0,0 +1,49 @@
+package storage
+
+import (
+	"time"
+
+	"github.com/NaeuralEdgeProtocol/ratio1-backend/model"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// SaveFxRates stores the rates of a day, a rate fetched again replaces the
+// stored one.
+func SaveFxRates(rates []model.FxRate) error {
+	if len(rates) == 0 {
+		return nil
+	}
+
+	return Transaction(func(tx *gorm.DB) error {
+		txCreate := tx.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "date"}, {Name: "currency"}},
+			DoUpdates: clause.AssignmentColumns([]string{"rate", "source"}),
+		}).Create(&rates)
+		if txCreate.Error != nil {
+			return txCreate.Error
+		}
+
+		return nil
+	})
+}
+
+// GetFxRateOnOrBefore returns the newest rate of currency published on or
+// before date, nil when there is none.
+func GetFxRateOnOrBefore(currency string, date time.Time) (*model.FxRate, error) {
+	db, err := GetReadDB()
+	if err != nil {
+		return nil, err
+	}
+
+	var rate model.FxRate
+	txRead := db.Where("currency = ? AND date <= ?", currency, date).Order("date DESC").Limit(1).Find(&rate)
+	if txRead.Error != nil {
+		return nil, txRead.Error
+	}
+	if txRead.RowsAffected == 0 {
+		return nil, nil
+	}
+
+	return &rate, nil
+}
@@ -0,0 +1,528 @@
+package storage
+
+import (
+	"time"
+
+	"github.com/NaeuralEdgeProtocol/ratio1-backend/model"
+	"github.com/google/uuid"
+)
+
+/* gorm backed repositories, they delegate to the storer functions of this package */
+
+type gormAccountRepository struct{}
+
+func (gormAccountRepository) GetByAddress(address string) (*model.Account, bool, error) {
+	return GetAccountByAddress(address)
+}
+
+func (gormAccountRepository) GetByEmail(email string) (*model.Account, bool, error) {
+	return GetAccountByEmail(email)
+}
+
+func (gormAccountRepository) Create(account *model.Account) error {
+	return CreateAccount(account)
+}
+
+func (gormAccountRepository) Update(account *model.Account) error {
+	return UpdateAccount(account)
+}
+
+func (gormAccountRepository) GetBySellerCode(sellerCode string) (*[]model.Account, error) {
+	return GetAccountsBySellerCode(sellerCode)
+}
+
+type gormNotificationEmailRepository struct{}
+
+func (gormNotificationEmailRepository) GetByAddress(address string) (*model.AccountNotificationEmail, bool, error) {
+	return GetAccountNotificationEmailByAddress(address)
+}
+
+func (gormNotificationEmailRepository) CreateOrUpdate(notificationEmail *model.AccountNotificationEmail) error {
+	return CreateOrUpdateAccountNotificationEmail(notificationEmail)
+}
+
+func (gormNotificationEmailRepository) Delete(address string) error {
+	return DeleteAccountNotificationEmail(address)
+}
+
+type gormKycRepository struct{}
+
+func (gormKycRepository) GetByEmail(email string) (*model.Kyc, bool, error) {
+	return GetKycByEmail(email)
+}
+
+func (gormKycRepository) GetByApplicantID(applicantId string) (*model.Kyc, bool, error) {
+	return GetKycByApplicantID(applicantId)
+}
+
+func (gormKycRepository) GetByUuid(uuid uuid.UUID) (*model.Kyc, bool, error) {
+	return GetKycByUuid(uuid)
+}
+
+func (gormKycRepository) CreateOrUpdate(kyc *model.Kyc) error {
+	return CreateOrUpdateKyc(kyc)
+}
+
+func (gormKycRepository) Update(kyc *model.Kyc) error {
+	return UpdateKyc(kyc)
+}
+
+func (gormKycRepository) GetAllUsersEmails() ([]string, error) {
+	return GetAllUsersEmails()
+}
+
+type gormUserInfoRepository struct{}
+
+func (gormUserInfoRepository) Create(userInfo *model.UserInfo) error {
+	return CreateUserInfo(userInfo)
+}
+
+func (gormUserInfoRepository) Update(userInfo *model.UserInfo) error {
+	return UpdateUserInfo(userInfo)
+}
+
+func (gormUserInfoRepository) CreateOrUpdate(userInfo *model.UserInfo) error {
+	return CreateOrUpdateUserInfo(userInfo)
+}
+
+func (gormUserInfoRepository) GetByAddress(address string) (*model.UserInfo, error) {
+	return GetUserInfoByAddress(address)
+}
+
+func (gormUserInfoRepository) GetVersions(address string) ([]model.UserInfoVersion, error) {
+	return GetUserInfoVersions(address)
+}
+
+func (gormUserInfoRepository) DeleteVersions(address string) error {
+	return DeleteUserInfoVersions(address)
+}
+
+func (gormUserInfoRepository) GetCompanies() ([]model.UserInfo, error) {
+	return GetCompanyUserInfos()
+}
+
+type gormInvoiceRepository struct{}
+
+func (gormInvoiceRepository) GetLatestBlock() (*int64, bool, error) {
+	return GetLatestInvoiceBlock()
+}
+
+func (gormInvoiceRepository) GetByID(id string) (*model.InvoiceClient, bool, error) {
+	return GetInvoiceByID(id)
+}
+
+func (gormInvoiceRepository) Create(invoice *model.InvoiceClient) error {
+	return CreateInvoice(invoice)
+}
+
+func (gormInvoiceRepository) Update(invoice *model.InvoiceClient) error {
+	return UpdateInvoice(invoice)
+}
+
+func (gormInvoiceRepository) GetUserInvoices(address string) (*[]model.InvoiceClient, error) {
+	return GetUserInvoices(address)
+}
+
+func (gormInvoiceRepository) GetByAddress(address string) ([]model.InvoiceClient, error) {
+	return GetInvoicesByAddress(address)
+}
+
+func (gormInvoiceRepository) GetPaid(from, to time.Time) ([]model.InvoiceClient, error) {
+	return GetPaidInvoices(from, to)
+}
+
+func (gormInvoiceRepository) GetUndatedPayments() ([]model.InvoiceClient, error) {
+	return GetInvoicesWithoutPaidAt()
+}
+
+func (gormInvoiceRepository) AddStatusChange(change *model.InvoiceStatusChange) error {
+	return CreateInvoiceStatusChange(change)
+}
+
+func (gormInvoiceRepository) GetStatusChanges(invoiceUuid string) ([]model.InvoiceStatusChange, error) {
+	return GetInvoiceStatusChanges(invoiceUuid)
+}
+
+func (gormInvoiceRepository) GetCreditNotes(from, to time.Time) ([]model.InvoiceStatusChange, error) {
+	return GetInvoiceCreditNotes(from, to)
+}
+
+type gormAllocationRepository struct{}
+
+func (gormAllocationRepository) GetLatestBlock() (int64, error) {
+	return GetLatestAllocationBlock()
+}
+
+func (gormAllocationRepository) Create(alloc *model.Allocation) error {
+	return CreateAllocation(alloc)
+}
+
+func (gormAllocationRepository) Update(alloc *model.Allocation) error {
+	return UpdateAllocation(alloc)
+}
+
+func (gormAllocationRepository) GetByCspAndUser(cspAddress, userAddress, nodeAddress string) ([]model.Allocation, error) {
+	return GetAllocationsByCspAndUser(cspAddress, userAddress, nodeAddress)
+}
+
+func (gormAllocationRepository) GetMonthlyUnclaimed(now time.Time) ([]model.Allocation, error) {
+	return GetMonthlyUnclaimedAllocations(now)
+}
+
+func (gormAllocationRepository) GetByDraftId(draftId string) ([]model.Allocation, error) {
+	return GetAllocationsByDraftId(draftId)
+}
+
+func (gormAllocationRepository) GetByJobIDsForJobDetails(jobIDs []string) (map[string]*model.Allocation, error) {
+	return GetAllocationsByJobIDsForJobDetails(jobIDs)
+}
+
+func (gormAllocationRepository) GetWithMissingDraft() ([]model.Allocation, error) {
+	return GetAllocationsWithMissingDraft()
+}
+
+func (gormAllocationRepository) ReleaseFromDraft(ids []uint) error {
+	return ReleaseAllocationsFromDraft(ids)
+}
+
+func (gormAllocationRepository) GetByAddress(address string) ([]model.Allocation, error) {
+	return GetAllocationsByAddress(address)
+}
+
+func (gormAllocationRepository) GetInBlockRange(from, to int64) ([]model.Allocation, error) {
+	return GetAllocationsInBlockRange(from, to)
+}
+
+func (gormAllocationRepository) DeleteUnclaimedFromBlock(block int64) error {
+	return DeleteUnclaimedAllocationsFromBlock(block)
+}
+
+type gormDraftRepository struct{}
+
+func (gormDraftRepository) GetListByNodeOwner(userAddress string) ([]model.InvoiceDraft, error) {
+	return GetDraftListByNodeOwner(userAddress)
+}
+
+func (gormDraftRepository) GetListByCSP(userAddress string) ([]model.InvoiceDraft, error) {
+	return GetDraftListByCSP(userAddress)
+}
+
+func (gormDraftRepository) GetByReportId(id, userAddress string) (*model.InvoiceDraft, error) {
+	return GetDraftByReportId(id, userAddress)
+}
+
+func (gormDraftRepository) GetCspByReportId(id, userAddress string) (*model.InvoiceDraft, error) {
+	return GetCspDraftByReportId(id, userAddress)
+}
+
+func (gormDraftRepository) Create(draft *model.InvoiceDraft) error {
+	return CreateInvoiceDraft(draft)
+}
+
+func (gormDraftRepository) Update(draft *model.InvoiceDraft) error {
+	return UpdateInvoiceDraft(draft)
+}
+
+func (gormDraftRepository) Generate(fn func(tx DraftGenerationTx) error) error {
+	return GenerateInvoiceDraft(fn)
+}
+
+func (gormDraftRepository) Transition(draftId uuid.UUID, from []string, transition *model.InvoiceDraftTransition) error {
+	return TransitionInvoiceDraft(draftId, from, transition)
+}
+
+func (gormDraftRepository) GetTransitions(draftId uuid.UUID) ([]model.InvoiceDraftTransition, error) {
+	return GetInvoiceDraftTransitions(draftId)
+}
+
+type gormPreferenceRepository struct{}
+
+func (gormPreferenceRepository) GetByAddress(userAddress string) (*model.Preference, error) {
+	return GetPreferenceByAddress(userAddress)
+}
+
+func (gormPreferenceRepository) Create(pref *model.Preference) error {
+	return CreatePreference(pref)
+}
+
+func (gormPreferenceRepository) Update(pref *model.Preference) error {
+	return UpdatePreference(pref)
+}
+
+type gormInvoiceSeriesRepository struct{}
+
+func (gormInvoiceSeriesRepository) GetByUser(userAddress string) ([]model.InvoiceSeries, error) {
+	return GetInvoiceSeriesByUser(userAddress)
+}
+
+func (gormInvoiceSeriesRepository) Get(userAddress, name string) (*model.InvoiceSeries, error) {
+	return GetInvoiceSeries(userAddress, name)
+}
+
+func (gormInvoiceSeriesRepository) Create(series *model.InvoiceSeries) error {
+	return CreateInvoiceSeries(series)
+}
+
+func (gormInvoiceSeriesRepository) Change(userAddress, name string, fn func(series *model.InvoiceSeries) error) error {
+	return ChangeInvoiceSeries(userAddress, name, fn)
+}
+
+type gormBurnEventRepository struct{}
+
+func (gormBurnEventRepository) Create(burnEvent *model.BurnEvent) error {
+	return CreateBurnEvent(burnEvent)
+}
+
+func (gormBurnEventRepository) GetByOwnerAddress(userAddress string) ([]model.BurnEvent, error) {
+	return GetBurnEventsByOwnerAddress(userAddress)
+}
+
+func (gormBurnEventRepository) GetForUserInTimeRange(start, end time.Time, userAddress string) ([]model.BurnEvent, error) {
+	return GetBurnEventsForUserInTimeRange(start, end, userAddress)
+}
+
+func (gormBurnEventRepository) GetInBlockRange(from, to int64) ([]model.BurnEvent, error) {
+	return GetBurnEventsInBlockRange(from, to)
+}
+
+func (gormBurnEventRepository) DeleteFromBlock(block int64) error {
+	return DeleteBurnEventsFromBlock(block)
+}
+
+type gormStatsRepository struct{}
+
+func (gormStatsRepository) Create(stats *model.Stats) error {
+	return CreateStats(stats)
+}
+
+func (gormStatsRepository) Update(stats *model.Stats) error {
+	return UpdateStats(stats)
+}
+
+func (gormStatsRepository) GetLatest() (*model.Stats, error) {
+	return GetLatestStats()
+}
+
+func (gormStatsRepository) GetAllASC() (*[]model.Stats, error) {
+	return GetAllStatsASC()
+}
+
+type gormSellerRepository struct{}
+
+func (gormSellerRepository) Create(seller *model.Seller) error {
+	return CreateSeller(seller)
+}
+
+func (gormSellerRepository) GetCodeByAddress(address string) (*string, error) {
+	return GetSellerCodeByAddress(address)
+}
+
+func (gormSellerRepository) GetByAddress(address string) (*model.Seller, error) {
+	return GetSellerByAddress(address)
+}
+
+func (gormSellerRepository) AddressHasCode(accountID string) (bool, error) {
+	return AddressHasCode(accountID)
+}
+
+func (gormSellerRepository) CodeExists(sellerCode string) (bool, error) {
+	return SellerCodeDoExist(sellerCode)
+}
+
+func (gormSellerRepository) GetAll() ([]model.Seller, error) {
+	return GetAllSellerCode()
+}
+
+func (gormSellerRepository) GetByCode(sellerCode string) (*model.Seller, error) {
+	return GetSellerByCode(sellerCode)
+}
+
+func (gormSellerRepository) Update(seller *model.Seller) error {
+	return UpdateSeller(seller)
+}
+
+type gormBrandingRepository struct{}
+
+func (gormBrandingRepository) Save(brand *model.Branding) error {
+	return SaveBrand(brand)
+}
+
+func (gormBrandingRepository) GetByAddress(address string) (*model.Branding, error) {
+	return GetBrandByAddress(address)
+}
+
+type gormAccountExportRepository struct{}
+
+func (gormAccountExportRepository) Create(export *model.AccountExport) error {
+	return CreateAccountExport(export)
+}
+
+func (gormAccountExportRepository) Update(export *model.AccountExport) error {
+	return UpdateAccountExport(export)
+}
+
+func (gormAccountExportRepository) GetById(id uuid.UUID) (*model.AccountExport, error) {
+	return GetAccountExportById(id)
+}
+
+func (gormAccountExportRepository) GetLatestByAddress(address string) (*model.AccountExport, error) {
+	return GetLatestAccountExport(address)
+}
+
+func (gormAccountExportRepository) DeleteByAddress(address string) error {
+	return DeleteAccountExports(address)
+}
+
+func (gormAccountExportRepository) DeleteExpired(now time.Time) error {
+	return DeleteExpiredAccountExports(now)
+}
+
+type gormAccountErasureRepository struct{}
+
+func (gormAccountErasureRepository) Create(request *model.AccountErasureRequest) error {
+	return CreateAccountErasureRequest(request)
+}
+
+func (gormAccountErasureRepository) Update(request *model.AccountErasureRequest) error {
+	return UpdateAccountErasureRequest(request)
+}
+
+func (gormAccountErasureRepository) GetById(id uuid.UUID) (*model.AccountErasureRequest, error) {
+	return GetAccountErasureRequestById(id)
+}
+
+func (gormAccountErasureRepository) GetOpenByAddress(address string) (*model.AccountErasureRequest, error) {
+	return GetOpenAccountErasureRequest(address)
+}
+
+func (gormAccountErasureRepository) GetByStatus(status string) ([]model.AccountErasureRequest, error) {
+	return GetAccountErasureRequestsByStatus(status)
+}
+
+type gormAuditLogRepository struct{}
+
+func (gormAuditLogRepository) Create(entry *model.AuditLog) error {
+	return CreateAuditLog(entry)
+}
+
+func (gormAuditLogRepository) GetBySubject(subject string) ([]model.AuditLog, error) {
+	return GetAuditLogsBySubject(subject)
+}
+
+type gormChainIndexRepository struct{}
+
+func (gormChainIndexRepository) GetCheckpoint(stream string) (*model.IndexerCheckpoint, error) {
+	return GetIndexerCheckpoint(stream)
+}
+
+func (gormChainIndexRepository) GetBlocks(stream string) ([]model.IndexedBlock, error) {
+	return GetIndexedBlocks(stream)
+}
+
+func (gormChainIndexRepository) Advance(stream string, previous *int64, block model.IndexedBlock, keep int) error {
+	return AdvanceIndexerCheckpoint(stream, previous, block, keep)
+}
+
+func (gormChainIndexRepository) Rewind(stream string, block model.IndexedBlock) error {
+	return RewindIndexerCheckpoint(stream, block)
+}
+
+type gormBlockRepository struct{}
+
+func (gormBlockRepository) Save(blocks ...model.Block) error {
+	return SaveBlocks(blocks)
+}
+
+func (gormBlockRepository) Get(number int64) (*model.Block, error) {
+	return GetBlock(number)
+}
+
+func (gormBlockRepository) GetAtOrBefore(timestamp time.Time) (*model.Block, error) {
+	return GetBlockAtOrBefore(timestamp)
+}
+
+func (gormBlockRepository) GetAfter(timestamp time.Time) (*model.Block, error) {
+	return GetBlockAfter(timestamp)
+}
+
+func (gormBlockRepository) DeleteFrom(number int64) error {
+	return DeleteBlocksFrom(number)
+}
+
+type gormFxRateRepository struct{}
+
+func (gormFxRateRepository) Save(rates ...model.FxRate) error {
+	return SaveFxRates(rates)
+}
+
+func (gormFxRateRepository) GetOnOrBefore(currency string, date time.Time) (*model.FxRate, error) {
+	return GetFxRateOnOrBefore(currency, date)
+}
+
+type gormVatRateRepository struct{}
+
+func (gormVatRateRepository) GetAll() ([]model.VatRate, error) {
+	return GetVatRates()
+}
+
+func (gormVatRateRepository) Save(rate *model.VatRate) error {
+	return SaveVatRate(rate)
+}
+
+type gormViesCheckRepository struct{}
+
+func (gormViesCheckRepository) Create(check *model.ViesCheck) error {
+	return CreateViesCheck(check)
+}
+
+func (gormViesCheckRepository) GetLatest(vatNumber string) (*model.ViesCheck, error) {
+	return GetLatestViesCheck(vatNumber)
+}
+
+func (gormViesCheckRepository) GetAll(vatNumber string) ([]model.ViesCheck, error) {
+	return GetViesChecks(vatNumber)
+}
+
+type gormLicensePurchaseRepository struct{}
+
+func (gormLicensePurchaseRepository) Create(purchase *model.LicensePurchaseEvent) error {
+	return CreateLicensePurchase(purchase)
+}
+
+func (gormLicensePurchaseRepository) Get(id uint) (*model.LicensePurchaseEvent, error) {
+	return GetLicensePurchase(id)
+}
+
+func (gormLicensePurchaseRepository) GetByInvoiceID(invoiceID string) (*model.LicensePurchaseEvent, error) {
+	return GetLicensePurchaseByInvoiceID(invoiceID)
+}
+
+func (gormLicensePurchaseRepository) GetByStatus(statuses ...string) ([]model.LicensePurchaseEvent, error) {
+	return GetLicensePurchasesByStatus(statuses...)
+}
+
+func (gormLicensePurchaseRepository) GetDue(now time.Time) ([]model.LicensePurchaseEvent, error) {
+	return GetDueLicensePurchases(now)
+}
+
+func (gormLicensePurchaseRepository) Update(purchase *model.LicensePurchaseEvent) error {
+	return UpdateLicensePurchase(purchase)
+}
+
+func (gormLicensePurchaseRepository) DeleteUninvoicedFromBlock(block int64) error {
+	return DeleteUninvoicedLicensePurchasesFromBlock(block)
+}
+
+type gormTokenTransferRepository struct{}
+
+func (gormTokenTransferRepository) Create(transfer *model.TokenTransfer) error {
+	return CreateTokenTransfer(transfer)
+}
+
+func (gormTokenTransferRepository) GetInBlockRange(from, to int64) ([]model.TokenTransfer, error) {
+	return GetTokenTransfersInBlockRange(from, to)
+}
+
+func (gormTokenTransferRepository) DeleteFromBlock(block int64) error {
+	return DeleteTokenTransfersFromBlock(block)
+}
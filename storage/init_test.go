@@ -1,10 +1,31 @@
 package storage
 
-import "github.com/NaeuralEdgeProtocol/ratio1-backend/config"
+import (
+	"os"
+	"testing"
 
+	"github.com/NaeuralEdgeProtocol/ratio1-backend/config"
+	"gorm.io/gorm"
+)
+
+// dbConfig is the database the storage tests run against, the tests needing
+// it are skipped while no host is set.
 var dbConfig = config.DatabaseConfig{}
 
-func init() {
-	config.Config.Database = dbConfig
-	Connect()
+func TestMain(m *testing.M) {
+	if dbConfig.Host != "" {
+		config.Config.Database = dbConfig
+		config.Config.Pii = config.PiiConfig{AllowPlaintext: true}
+		Connect()
+	}
+	os.Exit(m.Run())
+}
+
+// testDB returns the test database, the test is skipped without one.
+func testDB(t *testing.T) *gorm.DB {
+	db, err := GetDB()
+	if err != nil {
+		t.Skip("no test database configured: " + err.Error())
+	}
+	return db
 }
@@ -1,12 +1,19 @@
 package storage
 
 import (
+	"errors"
+
 	"github.com/NaeuralEdgeProtocol/ratio1-backend/model"
+	"github.com/google/uuid"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
+var ErrAllocationsAlreadyClaimed = errors.New("allocations already claimed by another draft")
+var ErrDraftStatusChanged = errors.New("draft status changed by another request")
+
 func GetDraftListByNodeOwner(userAddress string) ([]model.InvoiceDraft, error) {
-	db, err := GetDB()
+	db, err := GetReadDB()
 	if err != nil {
 		return nil, err
 	}
@@ -21,7 +28,7 @@ func GetDraftListByNodeOwner(userAddress string) ([]model.InvoiceDraft, error) {
 }
 
 func GetDraftListByCSP(userAddress string) ([]model.InvoiceDraft, error) {
-	db, err := GetDB()
+	db, err := GetReadDB()
 	if err != nil {
 		return nil, err
 	}
@@ -66,39 +73,164 @@ func GetCspDraftByReportId(id, userAddress string) (*model.InvoiceDraft, error)
 }
 
 func CreateInvoiceDraft(pInv *model.InvoiceDraft) error {
-	db, err := GetDB()
-	if err != nil {
-		return err
+	return Transaction(func(tx *gorm.DB) error {
+		txCreate := tx.Create(&pInv)
+		if txCreate.Error != nil {
+			return txCreate.Error
+		}
+		if txCreate.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+
+		return nil
+	})
+}
+
+func UpdateInvoiceDraft(pInv *model.InvoiceDraft) error {
+	return Transaction(func(tx *gorm.DB) error {
+		txUpdate := tx.Save(&pInv)
+		if txUpdate.Error != nil {
+			return txUpdate.Error
+		}
+		if txUpdate.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+
+		return nil
+	})
+}
+
+// GenerateInvoiceDraft runs fn in a single transaction, the preference, the
+// claimed allocations and the draft are written together or not at all.
+func GenerateInvoiceDraft(fn func(tx DraftGenerationTx) error) error {
+	return Transaction(func(tx *gorm.DB) error {
+		return fn(gormDraftGenerationTx{db: tx})
+	})
+}
+
+type gormDraftGenerationTx struct {
+	db *gorm.DB
+}
+
+func (t gormDraftGenerationTx) GetPreferenceForUpdate(userAddress string) (*model.Preference, error) {
+	var pref model.Preference
+	txRead := t.db.Clauses(clause.Locking{Strength: "UPDATE"}).Where("user_address = ?", userAddress).Find(&pref)
+	if txRead.Error != nil {
+		return nil, txRead.Error
+	} else if txRead.RowsAffected == 0 {
+		return nil, nil
+	}
+
+	return &pref, nil
+}
+
+func (t gormDraftGenerationTx) SavePreference(pref *model.Preference) error {
+	return t.db.Save(pref).Error
+}
+
+func (t gormDraftGenerationTx) GetSeriesForUpdate(userAddress, name string) (*model.InvoiceSeries, error) {
+	var series model.InvoiceSeries
+	txRead := t.db.Clauses(clause.Locking{Strength: "UPDATE"}).Where("user_address = ? AND name = ?", userAddress, name).Find(&series)
+	if txRead.Error != nil {
+		return nil, txRead.Error
+	} else if txRead.RowsAffected == 0 {
+		return nil, nil
+	}
+
+	return &series, nil
+}
+
+func (t gormDraftGenerationTx) SaveSeries(series *model.InvoiceSeries) error {
+	return t.db.Save(series).Error
+}
+
+func (t gormDraftGenerationTx) ClaimAllocations(draftId uuid.UUID, allocationIds []uint) error {
+	txUpdate := t.db.Model(&model.Allocation{}).
+		Where("id IN ? AND draft_id IS NULL", allocationIds).
+		Update("draft_id", draftId)
+	if txUpdate.Error != nil {
+		return txUpdate.Error
+	}
+	if txUpdate.RowsAffected != int64(len(allocationIds)) {
+		return ErrAllocationsAlreadyClaimed
 	}
 
-	txCreate := db.Create(&pInv)
+	return nil
+}
+
+func (t gormDraftGenerationTx) CreateDraft(draft *model.InvoiceDraft) error {
+	txCreate := t.db.Create(draft)
 	if txCreate.Error != nil {
-		txCreate.Rollback()
 		return txCreate.Error
 	}
 	if txCreate.RowsAffected == 0 {
-		txCreate.Rollback()
 		return gorm.ErrRecordNotFound
 	}
 
 	return nil
 }
 
-func UpdateInvoiceDraft(pInv *model.InvoiceDraft) error {
-	db, err := GetDB()
-	if err != nil {
-		return err
+func (t gormDraftGenerationTx) MoveAllocations(fromDraftId, toDraftId uuid.UUID, allocationIds []uint) error {
+	txUpdate := t.db.Model(&model.Allocation{}).
+		Where("id IN ? AND draft_id = ?", allocationIds, fromDraftId).
+		Update("draft_id", toDraftId)
+	if txUpdate.Error != nil {
+		return txUpdate.Error
 	}
+	if txUpdate.RowsAffected != int64(len(allocationIds)) {
+		return ErrAllocationsAlreadyClaimed
+	}
+
+	return nil
+}
 
-	txUpdate := db.Save(&pInv)
+func (t gormDraftGenerationTx) SupersedeDraft(draftId, supersededBy uuid.UUID, transition *model.InvoiceDraftTransition) error {
+	txUpdate := t.db.Model(&model.InvoiceDraft{}).
+		Where("draft_id = ? AND status = ? AND superseded_by IS NULL", draftId, model.DraftStatusDraft).
+		Updates(map[string]any{"status": model.DraftStatusSuperseded, "superseded_by": supersededBy})
 	if txUpdate.Error != nil {
-		txUpdate.Rollback()
 		return txUpdate.Error
 	}
 	if txUpdate.RowsAffected == 0 {
-		txUpdate.Rollback()
-		return gorm.ErrRecordNotFound
+		return ErrDraftStatusChanged
 	}
 
-	return nil
+	transition.DraftId = draftId
+	return t.db.Create(transition).Error
+}
+
+// TransitionInvoiceDraft moves the draft to the status of the transition when
+// its current status is one of from, and records the transition with it.
+func TransitionInvoiceDraft(draftId uuid.UUID, from []string, transition *model.InvoiceDraftTransition) error {
+	return Transaction(func(tx *gorm.DB) error {
+		txUpdate := tx.Model(&model.InvoiceDraft{}).
+			Where("draft_id = ? AND status IN ?", draftId, from).
+			Update("status", transition.ToStatus)
+		if txUpdate.Error != nil {
+			return txUpdate.Error
+		}
+		if txUpdate.RowsAffected == 0 {
+			return ErrDraftStatusChanged
+		}
+
+		transition.DraftId = draftId
+		return tx.Create(transition).Error
+	})
+}
+
+// GetInvoiceDraftTransitions returns the lifecycle history of a draft, oldest
+// first.
+func GetInvoiceDraftTransitions(draftId uuid.UUID) ([]model.InvoiceDraftTransition, error) {
+	db, err := GetReadDB()
+	if err != nil {
+		return nil, err
+	}
+
+	var transitions []model.InvoiceDraftTransition
+	txRead := db.Where("draft_id = ?", draftId).Order("created_at ASC, id ASC").Find(&transitions)
+	if txRead.Error != nil {
+		return nil, txRead.Error
+	}
+
+	return transitions, nil
 }
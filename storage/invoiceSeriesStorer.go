@@ -0,0 +1,65 @@
+package storage
+
+import (
+	"github.com/NaeuralEdgeProtocol/ratio1-backend/model"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+func GetInvoiceSeriesByUser(userAddress string) ([]model.InvoiceSeries, error) {
+	db, err := GetDB()
+	if err != nil {
+		return nil, err
+	}
+
+	var series []model.InvoiceSeries
+	txRead := db.Where("user_address = ?", userAddress).Order("name ASC").Find(&series)
+	if txRead.Error != nil {
+		return nil, txRead.Error
+	}
+
+	return series, nil
+}
+
+func GetInvoiceSeries(userAddress, name string) (*model.InvoiceSeries, error) {
+	db, err := GetDB()
+	if err != nil {
+		return nil, err
+	}
+
+	var series model.InvoiceSeries
+	txRead := db.Where("user_address = ? AND name = ?", userAddress, name).Find(&series)
+	if txRead.Error != nil {
+		return nil, txRead.Error
+	} else if txRead.RowsAffected == 0 {
+		return nil, nil
+	}
+
+	return &series, nil
+}
+
+func CreateInvoiceSeries(series *model.InvoiceSeries) error {
+	return Transaction(func(tx *gorm.DB) error {
+		return tx.Create(series).Error
+	})
+}
+
+// ChangeInvoiceSeries locks the series, lets fn validate and edit it and
+// saves it, nothing is written when fn fails.
+func ChangeInvoiceSeries(userAddress, name string, fn func(series *model.InvoiceSeries) error) error {
+	return Transaction(func(tx *gorm.DB) error {
+		var series model.InvoiceSeries
+		txRead := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("user_address = ? AND name = ?", userAddress, name).Find(&series)
+		if txRead.Error != nil {
+			return txRead.Error
+		} else if txRead.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+
+		err := fn(&series)
+		if err != nil {
+			return err
+		}
+		return tx.Save(&series).Error
+	})
+}
@@ -1,6 +1,8 @@
 package storage
 
 import (
+	"time"
+
 	"github.com/NaeuralEdgeProtocol/ratio1-backend/model"
 	"gorm.io/gorm"
 )
@@ -42,57 +44,134 @@ func GetInvoiceByID(id string) (*model.InvoiceClient, bool, error) {
 }
 
 func CreateInvoice(invoice *model.InvoiceClient) error {
+	return Transaction(func(tx *gorm.DB) error {
+		txCreate := tx.Create(invoice)
+		if txCreate.Error != nil {
+			return txCreate.Error
+		}
+		if txCreate.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+
+		return nil
+	})
+}
+
+func UpdateInvoice(invoice *model.InvoiceClient) error {
+	return Transaction(func(tx *gorm.DB) error {
+		txUpdate := tx.Save(invoice)
+		if txUpdate.Error != nil {
+			return txUpdate.Error
+		}
+		if txUpdate.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+
+		return nil
+	})
+}
+
+func GetUserInvoices(address string) (*[]model.InvoiceClient, error) {
 	db, err := GetDB()
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	txCreate := db.Create(invoice)
-	if txCreate.Error != nil {
-		txCreate.Rollback()
-		return txCreate.Error
+	invoices := []model.InvoiceClient{}
+	txRead := db.Find(&invoices, "blockchain_address = ? AND status = ?", address, model.InvoiceStatusPaid)
+	if txRead.Error != nil {
+		return nil, txRead.Error
+	}
+
+	return &invoices, nil
+}
+
+// GetPaidInvoices returns the invoices paid in [from, to), with the ones
+// cancelled or refunded since.
+func GetPaidInvoices(from, to time.Time) ([]model.InvoiceClient, error) {
+	db, err := GetReadDB()
+	if err != nil {
+		return nil, err
 	}
-	if txCreate.RowsAffected == 0 {
-		txCreate.Rollback()
-		return gorm.ErrRecordNotFound
+
+	statuses := []string{model.InvoiceStatusPaid, model.InvoiceStatusCancelled, model.InvoiceStatusRefunded}
+	var invoices []model.InvoiceClient
+	txRead := db.Where("status IN ? AND paid_at >= ? AND paid_at < ?", statuses, from, to).Find(&invoices)
+	if txRead.Error != nil {
+		return nil, txRead.Error
 	}
 
-	return nil
+	return invoices, nil
 }
 
-func UpdateInvoice(invoice *model.InvoiceClient) error {
+// GetInvoicesWithoutPaidAt returns the invoices of a mined purchase stored
+// before their payment date was kept.
+func GetInvoicesWithoutPaidAt() ([]model.InvoiceClient, error) {
 	db, err := GetDB()
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	txUpdate := db.Save(invoice)
-	if txUpdate.Error != nil {
-		txUpdate.Rollback()
-		return txUpdate.Error
-	}
-	if txUpdate.RowsAffected == 0 {
-		txUpdate.Rollback()
-		return gorm.ErrRecordNotFound
+	var invoices []model.InvoiceClient
+	txRead := db.Where("block_number IS NOT NULL AND paid_at IS NULL").Find(&invoices)
+	if txRead.Error != nil {
+		return nil, txRead.Error
 	}
 
-	return nil
+	return invoices, nil
 }
 
-func GetUserInvoices(address string) (*[]model.InvoiceClient, error) {
-	db, err := GetDB()
+func GetInvoicesByAddress(address string) ([]model.InvoiceClient, error) {
+	db, err := GetReadDB()
 	if err != nil {
 		return nil, err
 	}
 
 	var invoices []model.InvoiceClient
-	txRead := db.Find(&invoices, "address = ? && status =  paid", address)
+	txRead := db.Where("blockchain_address = ?", address).Find(&invoices)
 	if txRead.Error != nil {
 		return nil, txRead.Error
 	}
-	if txRead.RowsAffected == 0 {
-		return nil, nil
+
+	return invoices, nil
+}
+
+func CreateInvoiceStatusChange(change *model.InvoiceStatusChange) error {
+	return Transaction(func(tx *gorm.DB) error {
+		return tx.Create(change).Error
+	})
+}
+
+// GetInvoiceStatusChanges returns the status history of an invoice, oldest
+// first.
+func GetInvoiceStatusChanges(invoiceUuid string) ([]model.InvoiceStatusChange, error) {
+	db, err := GetReadDB()
+	if err != nil {
+		return nil, err
 	}
 
-	return &invoices, nil
+	var changes []model.InvoiceStatusChange
+	txRead := db.Where("invoice_uuid = ?", invoiceUuid).Order("created_at ASC, id ASC").Find(&changes)
+	if txRead.Error != nil {
+		return nil, txRead.Error
+	}
+
+	return changes, nil
+}
+
+// GetInvoiceCreditNotes returns the credit notes issued in [from, to), oldest
+// first.
+func GetInvoiceCreditNotes(from, to time.Time) ([]model.InvoiceStatusChange, error) {
+	db, err := GetReadDB()
+	if err != nil {
+		return nil, err
+	}
+
+	var changes []model.InvoiceStatusChange
+	txRead := db.Where("document_type = ? AND created_at >= ? AND created_at < ?", model.InvoiceDocumentCreditNote, from, to).Order("created_at ASC, id ASC").Find(&changes)
+	if txRead.Error != nil {
+		return nil, txRead.Error
+	}
+
+	return changes, nil
 }
@@ -7,6 +7,10 @@ import (
 	"github.com/google/uuid"
 )
 
+// kycEmailCondition matches the email through its blind index, rows not yet
+// processed by the encrypt-pii migration still hold the email in plaintext.
+const kycEmailCondition = "email_index = ? OR (COALESCE(email_index, '') = '' AND email = ?)"
+
 func GetKycByEmail(email string) (*model.Kyc, bool, error) {
 	db, err := GetDB()
 	if err != nil {
@@ -14,7 +18,7 @@ func GetKycByEmail(email string) (*model.Kyc, bool, error) {
 	}
 
 	var acc model.Kyc
-	txRead := db.Find(&acc, "email = ?", email)
+	txRead := db.Where(kycEmailCondition, PiiBlindIndex(email), email).Find(&acc)
 	if txRead.Error != nil {
 		return nil, false, txRead.Error
 	}
@@ -61,27 +65,48 @@ func GetKycByUuid(uuid uuid.UUID) (*model.Kyc, bool, error) {
 	return &acc, true, nil
 }
 
-func CreateOrUpdateKyc(kyc *model.Kyc) error {
-	db, err := GetDB()
-	if err != nil {
-		return err
-	}
+// UpdateKyc saves every field of kyc by its uuid, including an emptied email.
+func UpdateKyc(kyc *model.Kyc) error {
+	return Transaction(func(tx *gorm.DB) error {
+		kyc.EmailIndex = ""
+		if kyc.Email != "" {
+			kyc.EmailIndex = PiiBlindIndex(kyc.Email)
+		}
 
-	var existingKyc model.Kyc
-	err = db.Where("email = ?", kyc.Email).First(&existingKyc).Error
-	if err == gorm.ErrRecordNotFound {
-		err = db.Create(kyc).Error
-		if err != nil {
-			return err
+		txUpdate := tx.Save(kyc)
+		if txUpdate.Error != nil {
+			return txUpdate.Error
+		}
+		if txUpdate.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
 		}
-	} else if err == nil {
-		err = db.Model(&existingKyc).Where("email = ?", existingKyc.Email).Updates(kyc).Error
-		if err != nil {
+
+		return nil
+	})
+}
+
+func CreateOrUpdateKyc(kyc *model.Kyc) error {
+	return Transaction(func(tx *gorm.DB) error {
+		kyc.EmailIndex = PiiBlindIndex(kyc.Email)
+
+		var existingKyc model.Kyc
+		err := tx.Where(kycEmailCondition, kyc.EmailIndex, kyc.Email).First(&existingKyc).Error
+		if err == gorm.ErrRecordNotFound {
+			err = tx.Create(kyc).Error
+			if err != nil {
+				return err
+			}
+		} else if err == nil {
+			err = tx.Model(&existingKyc).Where("uuid = ?", existingKyc.Uuid).Updates(kyc).Error
+			if err != nil {
+				return err
+			}
+		} else {
 			return err
 		}
-	}
 
-	return nil
+		return nil
+	})
 }
 
 func GetAllUsersEmails() ([]string, error) {
@@ -0,0 +1,77 @@
+package memory
+
+import (
+	"sort"
+	"time"
+
+	"github.com/NaeuralEdgeProtocol/ratio1-backend/model"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type accountErasureRepository struct{ s *Store }
+
+func (r accountErasureRepository) Create(request *model.AccountErasureRequest) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	if _, ok := r.s.accountErasures[request.Id]; ok {
+		return ErrDuplicateKey
+	}
+	if request.CreatedAt.IsZero() {
+		request.CreatedAt = time.Now()
+	}
+	r.s.accountErasures[request.Id] = *request
+	return nil
+}
+
+func (r accountErasureRepository) Update(request *model.AccountErasureRequest) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	if _, ok := r.s.accountErasures[request.Id]; !ok {
+		return gorm.ErrRecordNotFound
+	}
+	r.s.accountErasures[request.Id] = *request
+	return nil
+}
+
+func (r accountErasureRepository) GetById(id uuid.UUID) (*model.AccountErasureRequest, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	request, ok := r.s.accountErasures[id]
+	if !ok {
+		return nil, nil
+	}
+	return &request, nil
+}
+
+func (r accountErasureRepository) GetOpenByAddress(address string) (*model.AccountErasureRequest, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	for _, request := range r.s.accountErasures {
+		if request.Address != address {
+			continue
+		}
+		if request.Status == model.AccountErasureStatusPending || request.Status == model.AccountErasureStatusApproved {
+			return &request, nil
+		}
+	}
+	return nil, nil
+}
+
+func (r accountErasureRepository) GetByStatus(status string) ([]model.AccountErasureRequest, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	var requests []model.AccountErasureRequest
+	for _, request := range r.s.accountErasures {
+		if request.Status == status {
+			requests = append(requests, request)
+		}
+	}
+	sort.Slice(requests, func(i, j int) bool { return requests[i].CreatedAt.Before(requests[j].CreatedAt) })
+	return requests, nil
+}
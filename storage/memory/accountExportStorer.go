@@ -0,0 +1,93 @@
+package memory
+
+import (
+	"time"
+
+	"github.com/NaeuralEdgeProtocol/ratio1-backend/model"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type accountExportRepository struct{ s *Store }
+
+func (r accountExportRepository) Create(export *model.AccountExport) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	if _, ok := r.s.accountExports[export.Id]; ok {
+		return ErrDuplicateKey
+	}
+	if export.CreatedAt.IsZero() {
+		export.CreatedAt = time.Now()
+	}
+	r.s.accountExports[export.Id] = *export
+	return nil
+}
+
+func (r accountExportRepository) Update(export *model.AccountExport) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	if _, ok := r.s.accountExports[export.Id]; !ok {
+		return gorm.ErrRecordNotFound
+	}
+	r.s.accountExports[export.Id] = *export
+	return nil
+}
+
+func (r accountExportRepository) GetById(id uuid.UUID) (*model.AccountExport, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	export, ok := r.s.accountExports[id]
+	if !ok {
+		return nil, nil
+	}
+	return &export, nil
+}
+
+func (r accountExportRepository) GetLatestByAddress(address string) (*model.AccountExport, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	var latest model.AccountExport
+	found := false
+	for _, export := range r.s.accountExports {
+		if export.Address != address {
+			continue
+		}
+		if !found || export.CreatedAt.After(latest.CreatedAt) {
+			latest = export
+			found = true
+		}
+	}
+	if !found {
+		return nil, nil
+	}
+	latest.Archive = ""
+	return &latest, nil
+}
+
+func (r accountExportRepository) DeleteByAddress(address string) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	for id, export := range r.s.accountExports {
+		if export.Address == address {
+			delete(r.s.accountExports, id)
+		}
+	}
+	return nil
+}
+
+func (r accountExportRepository) DeleteExpired(now time.Time) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	for id, export := range r.s.accountExports {
+		if export.ExpiresAt.Before(now) {
+			delete(r.s.accountExports, id)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,112 @@
+package memory
+
+import (
+	"github.com/NaeuralEdgeProtocol/ratio1-backend/model"
+)
+
+type accountRepository struct{ s *Store }
+
+func (r accountRepository) GetByAddress(address string) (*model.Account, bool, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	account, ok := r.s.accounts[address]
+	if !ok {
+		return nil, false, nil
+	}
+	return &account, true, nil
+}
+
+func (r accountRepository) GetByEmail(email string) (*model.Account, bool, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	for _, account := range r.s.accounts {
+		if account.Email != nil && *account.Email == email {
+			return &account, true, nil
+		}
+	}
+	return nil, false, nil
+}
+
+func (r accountRepository) Create(account *model.Account) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	if _, ok := r.s.accounts[account.Address]; ok {
+		return ErrDuplicateKey
+	}
+	if err := r.checkUniqueEmail(account); err != nil {
+		return err
+	}
+	r.s.accounts[account.Address] = *account
+	return nil
+}
+
+func (r accountRepository) Update(account *model.Account) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	if err := r.checkUniqueEmail(account); err != nil {
+		return err
+	}
+	r.s.accounts[account.Address] = *account
+	return nil
+}
+
+func (r accountRepository) GetBySellerCode(sellerCode string) (*[]model.Account, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	var accounts []model.Account
+	for _, account := range r.s.accounts {
+		if account.UsedSellerCode != nil && *account.UsedSellerCode == sellerCode {
+			accounts = append(accounts, account)
+		}
+	}
+	if len(accounts) == 0 {
+		return nil, nil
+	}
+	return &accounts, nil
+}
+
+func (r accountRepository) checkUniqueEmail(account *model.Account) error {
+	if account.Email == nil {
+		return nil
+	}
+	for address, stored := range r.s.accounts {
+		if address != account.Address && stored.Email != nil && *stored.Email == *account.Email {
+			return ErrDuplicateKey
+		}
+	}
+	return nil
+}
+
+type notificationEmailRepository struct{ s *Store }
+
+func (r notificationEmailRepository) GetByAddress(address string) (*model.AccountNotificationEmail, bool, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	notificationEmail, ok := r.s.notificationEmails[address]
+	if !ok {
+		return nil, false, nil
+	}
+	return &notificationEmail, true, nil
+}
+
+func (r notificationEmailRepository) CreateOrUpdate(notificationEmail *model.AccountNotificationEmail) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	r.s.notificationEmails[notificationEmail.AccountAddress] = *notificationEmail
+	return nil
+}
+
+func (r notificationEmailRepository) Delete(address string) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	delete(r.s.notificationEmails, address)
+	return nil
+}
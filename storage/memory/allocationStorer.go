@@ -0,0 +1,209 @@
+package memory
+
+import (
+	"maps"
+	"sort"
+	"time"
+
+	"github.com/NaeuralEdgeProtocol/ratio1-backend/model"
+	"gorm.io/gorm"
+)
+
+type allocationRepository struct{ s *Store }
+
+func (r allocationRepository) GetLatestBlock() (int64, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	var latest int64
+	for _, alloc := range r.s.allocations {
+		if alloc.BlockNumber > latest {
+			latest = alloc.BlockNumber
+		}
+	}
+	return latest, nil
+}
+
+// Create ignores allocations already stored with the same tx hash and log
+// index, like the ON CONFLICT DO NOTHING clause of the gorm storer.
+func (r allocationRepository) Create(alloc *model.Allocation) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	if alloc.LogIndex != nil {
+		for _, stored := range r.s.allocations {
+			if stored.LogIndex != nil && stored.TxHash == alloc.TxHash && *stored.LogIndex == *alloc.LogIndex {
+				return nil
+			}
+		}
+	}
+
+	if alloc.Id == 0 {
+		r.s.nextAllocationId++
+		alloc.Id = r.s.nextAllocationId
+	} else if _, ok := r.s.allocations[alloc.Id]; ok {
+		return ErrDuplicateKey
+	} else if alloc.Id > r.s.nextAllocationId {
+		r.s.nextAllocationId = alloc.Id
+	}
+	r.s.allocations[alloc.Id] = stripAllocationProfiles(*alloc)
+	return nil
+}
+
+func (r allocationRepository) Update(alloc *model.Allocation) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	if alloc.Id == 0 {
+		r.s.nextAllocationId++
+		alloc.Id = r.s.nextAllocationId
+	}
+	r.s.allocations[alloc.Id] = stripAllocationProfiles(*alloc)
+	return nil
+}
+
+func (r allocationRepository) GetByCspAndUser(cspAddress, userAddress, nodeAddress string) ([]model.Allocation, error) {
+	allocations := r.filter(false, func(a model.Allocation) bool {
+		return a.CspAddress == cspAddress && a.UserAddress == userAddress && a.NodeAddress == nodeAddress
+	})
+	if len(allocations) == 0 {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return allocations, nil
+}
+
+func (r allocationRepository) GetMonthlyUnclaimed(now time.Time) ([]model.Allocation, error) {
+	currStart := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+	prevStart := currStart.AddDate(0, -1, 0)
+
+	allocations := r.filter(true, func(a model.Allocation) bool {
+		return !a.AllocationCreation.Before(prevStart) && a.AllocationCreation.Before(currStart) && a.DraftId == nil
+	})
+	if len(allocations) == 0 {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return allocations, nil
+}
+
+func (r allocationRepository) GetByDraftId(draftId string) ([]model.Allocation, error) {
+	allocations := r.filter(false, func(a model.Allocation) bool {
+		return a.DraftId != nil && a.DraftId.String() == draftId
+	})
+	if len(allocations) == 0 {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return allocations, nil
+}
+
+func (r allocationRepository) GetByJobIDsForJobDetails(jobIDs []string) (map[string]*model.Allocation, error) {
+	allocationsByJobID := make(map[string]*model.Allocation)
+	if len(jobIDs) == 0 {
+		return allocationsByJobID, nil
+	}
+
+	wanted := make(map[string]bool, len(jobIDs))
+	for _, id := range jobIDs {
+		wanted[id] = true
+	}
+
+	allocations := r.filter(false, func(a model.Allocation) bool {
+		return wanted[a.JobId] && a.JobName != ""
+	})
+	for i := range allocations {
+		current, ok := allocationsByJobID[allocations[i].JobId]
+		if !ok || isNewerAllocation(allocations[i], *current) {
+			allocationsByJobID[allocations[i].JobId] = &allocations[i]
+		}
+	}
+	return allocationsByJobID, nil
+}
+
+func (r allocationRepository) GetWithMissingDraft() ([]model.Allocation, error) {
+	r.s.mu.RLock()
+	drafts := maps.Clone(r.s.drafts)
+	r.s.mu.RUnlock()
+
+	return r.filter(false, func(a model.Allocation) bool {
+		if a.DraftId == nil {
+			return false
+		}
+		_, ok := drafts[*a.DraftId]
+		return !ok
+	}), nil
+}
+
+func (r allocationRepository) ReleaseFromDraft(ids []uint) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	for _, id := range ids {
+		if alloc, ok := r.s.allocations[id]; ok {
+			alloc.DraftId = nil
+			r.s.allocations[id] = alloc
+		}
+	}
+	return nil
+}
+
+func (r allocationRepository) GetByAddress(address string) ([]model.Allocation, error) {
+	return r.filter(false, func(a model.Allocation) bool {
+		return a.UserAddress == address || a.CspOwner == address
+	}), nil
+}
+
+func (r allocationRepository) GetInBlockRange(from, to int64) ([]model.Allocation, error) {
+	allocations := r.filter(false, func(a model.Allocation) bool {
+		return a.BlockNumber >= from && a.BlockNumber <= to
+	})
+	sort.SliceStable(allocations, func(i, j int) bool { return allocations[i].BlockNumber < allocations[j].BlockNumber })
+	return allocations, nil
+}
+
+func (r allocationRepository) DeleteUnclaimedFromBlock(block int64) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	for id, alloc := range r.s.allocations {
+		if alloc.BlockNumber >= block && alloc.DraftId == nil {
+			delete(r.s.allocations, id)
+		}
+	}
+	return nil
+}
+
+// filter returns the matching allocations ordered by id, optionally with the
+// csp and user profiles attached.
+func (r allocationRepository) filter(withProfiles bool, match func(model.Allocation) bool) []model.Allocation {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	var allocations []model.Allocation
+	for _, alloc := range r.s.allocations {
+		if !match(alloc) {
+			continue
+		}
+		if withProfiles {
+			alloc.CspProfile = r.s.profile(alloc.CspOwner)
+			alloc.UserProfile = r.s.profile(alloc.UserAddress)
+		}
+		allocations = append(allocations, alloc)
+	}
+	sort.Slice(allocations, func(i, j int) bool { return allocations[i].Id < allocations[j].Id })
+	return allocations
+}
+
+func isNewerAllocation(a, b model.Allocation) bool {
+	if a.BlockNumber != b.BlockNumber {
+		return a.BlockNumber > b.BlockNumber
+	}
+	if !a.AllocationCreation.Equal(b.AllocationCreation) {
+		return a.AllocationCreation.After(b.AllocationCreation)
+	}
+	return a.Id > b.Id
+}
+
+func stripAllocationProfiles(alloc model.Allocation) model.Allocation {
+	alloc.CspProfile = model.UserInfo{}
+	alloc.UserProfile = model.UserInfo{}
+	return alloc
+}
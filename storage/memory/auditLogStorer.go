@@ -0,0 +1,34 @@
+package memory
+
+import (
+	"time"
+
+	"github.com/NaeuralEdgeProtocol/ratio1-backend/model"
+)
+
+type auditLogRepository struct{ s *Store }
+
+func (r auditLogRepository) Create(entry *model.AuditLog) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	entry.Id = uint(len(r.s.auditLogs) + 1)
+	if entry.CreatedAt.IsZero() {
+		entry.CreatedAt = time.Now()
+	}
+	r.s.auditLogs = append(r.s.auditLogs, *entry)
+	return nil
+}
+
+func (r auditLogRepository) GetBySubject(subject string) ([]model.AuditLog, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	var entries []model.AuditLog
+	for _, entry := range r.s.auditLogs {
+		if entry.Subject == subject {
+			entries = append(entries, entry)
+		}
+	}
+	return entries, nil
+}
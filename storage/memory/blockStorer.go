@@ -0,0 +1,70 @@
+package memory
+
+import (
+	"time"
+
+	"github.com/NaeuralEdgeProtocol/ratio1-backend/model"
+)
+
+type blockRepository struct{ s *Store }
+
+func (r blockRepository) Save(blocks ...model.Block) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	for _, block := range blocks {
+		r.s.blocks[block.Number] = block
+	}
+	return nil
+}
+
+func (r blockRepository) Get(number int64) (*model.Block, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	block, ok := r.s.blocks[number]
+	if !ok {
+		return nil, nil
+	}
+	return &block, nil
+}
+
+func (r blockRepository) GetAtOrBefore(timestamp time.Time) (*model.Block, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	var found *model.Block
+	for _, block := range r.s.blocks {
+		if !block.Timestamp.After(timestamp) && (found == nil || block.Number > found.Number) {
+			block := block
+			found = &block
+		}
+	}
+	return found, nil
+}
+
+func (r blockRepository) GetAfter(timestamp time.Time) (*model.Block, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	var found *model.Block
+	for _, block := range r.s.blocks {
+		if block.Timestamp.After(timestamp) && (found == nil || block.Number < found.Number) {
+			block := block
+			found = &block
+		}
+	}
+	return found, nil
+}
+
+func (r blockRepository) DeleteFrom(number int64) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	for n := range r.s.blocks {
+		if n >= number {
+			delete(r.s.blocks, n)
+		}
+	}
+	return nil
+}
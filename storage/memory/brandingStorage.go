@@ -0,0 +1,26 @@
+package memory
+
+import (
+	"github.com/NaeuralEdgeProtocol/ratio1-backend/model"
+)
+
+type brandingRepository struct{ s *Store }
+
+func (r brandingRepository) Save(brand *model.Branding) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	r.s.brandings[brand.UserAddress] = *brand
+	return nil
+}
+
+func (r brandingRepository) GetByAddress(address string) (*model.Branding, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	brand, ok := r.s.brandings[address]
+	if !ok {
+		return nil, nil
+	}
+	return &brand, nil
+}
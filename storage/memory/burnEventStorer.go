@@ -0,0 +1,94 @@
+package memory
+
+import (
+	"sort"
+	"time"
+
+	"github.com/NaeuralEdgeProtocol/ratio1-backend/model"
+)
+
+type burnEventRepository struct{ s *Store }
+
+// Create ignores burn events already stored with the same tx hash and log
+// index, like the ON CONFLICT DO NOTHING clause of the gorm storer.
+func (r burnEventRepository) Create(burnEvent *model.BurnEvent) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	if burnEvent.LogIndex != nil {
+		for _, stored := range r.s.burnEvents {
+			if stored.LogIndex != nil && stored.TxHash == burnEvent.TxHash && *stored.LogIndex == *burnEvent.LogIndex {
+				return nil
+			}
+		}
+	}
+
+	if burnEvent.Id == 0 {
+		r.s.nextBurnEventId++
+		burnEvent.Id = r.s.nextBurnEventId
+	} else if _, ok := r.s.burnEvents[burnEvent.Id]; ok {
+		return ErrDuplicateKey
+	} else if burnEvent.Id > r.s.nextBurnEventId {
+		r.s.nextBurnEventId = burnEvent.Id
+	}
+	stored := *burnEvent
+	stored.CspProfile = model.UserInfo{}
+	r.s.burnEvents[stored.Id] = stored
+	return nil
+}
+
+func (r burnEventRepository) GetByOwnerAddress(userAddress string) ([]model.BurnEvent, error) {
+	return r.filter(func(b model.BurnEvent) bool { return b.CspOwner == userAddress }), nil
+}
+
+func (r burnEventRepository) GetForUserInTimeRange(start, end time.Time, userAddress string) ([]model.BurnEvent, error) {
+	return r.filter(func(b model.BurnEvent) bool {
+		return b.CspOwner == userAddress && !b.BurnTimestamp.Before(start) && !b.BurnTimestamp.After(end)
+	}), nil
+}
+
+func (r burnEventRepository) GetInBlockRange(from, to int64) ([]model.BurnEvent, error) {
+	events := r.filter(func(b model.BurnEvent) bool {
+		return b.BlockNumber >= from && b.BlockNumber <= to
+	})
+	for i := range events {
+		events[i].CspProfile = model.UserInfo{}
+	}
+	sort.SliceStable(events, func(i, j int) bool { return events[i].BlockNumber < events[j].BlockNumber })
+	return events, nil
+}
+
+func (r burnEventRepository) DeleteFromBlock(block int64) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	for id, event := range r.s.burnEvents {
+		if event.BlockNumber >= block {
+			delete(r.s.burnEvents, id)
+		}
+	}
+	return nil
+}
+
+// filter returns the matching burn events by block number descending with the
+// csp profile attached.
+func (r burnEventRepository) filter(match func(model.BurnEvent) bool) []model.BurnEvent {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	var events []model.BurnEvent
+	for _, event := range r.s.burnEvents {
+		if !match(event) {
+			continue
+		}
+		event.CspProfile = r.s.profile(event.CspOwner)
+		events = append(events, event)
+	}
+	sort.Slice(events, func(i, j int) bool {
+		if events[i].BlockNumber != events[j].BlockNumber {
+			return events[i].BlockNumber > events[j].BlockNumber
+		}
+		return events[i].Id < events[j].Id
+	})
+	return events
+}
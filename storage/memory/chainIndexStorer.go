@@ -0,0 +1,240 @@
+package memory
+
+import (
+	"slices"
+	"sort"
+	"time"
+
+	"github.com/NaeuralEdgeProtocol/ratio1-backend/model"
+	"github.com/NaeuralEdgeProtocol/ratio1-backend/storage"
+	"gorm.io/gorm"
+)
+
+type chainIndexRepository struct{ s *Store }
+
+func (r chainIndexRepository) GetCheckpoint(stream string) (*model.IndexerCheckpoint, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	checkpoint, ok := r.s.checkpoints[stream]
+	if !ok {
+		return nil, nil
+	}
+	return &checkpoint, nil
+}
+
+func (r chainIndexRepository) GetBlocks(stream string) ([]model.IndexedBlock, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	blocks := append([]model.IndexedBlock(nil), r.s.indexedBlocks[stream]...)
+	sort.Slice(blocks, func(i, j int) bool { return blocks[i].BlockNumber > blocks[j].BlockNumber })
+	return blocks, nil
+}
+
+func (r chainIndexRepository) Advance(stream string, previous *int64, block model.IndexedBlock, keep int) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	current, found := r.s.checkpoints[stream]
+	if found != (previous != nil) || (found && current.BlockNumber != *previous) {
+		return storage.ErrIndexerCheckpointMoved
+	}
+
+	r.move(stream, block)
+	blocks := r.s.indexedBlocks[stream]
+	sort.Slice(blocks, func(i, j int) bool { return blocks[i].BlockNumber > blocks[j].BlockNumber })
+	if keep > 0 && len(blocks) > keep {
+		blocks = blocks[:keep]
+	}
+	r.s.indexedBlocks[stream] = blocks
+	return nil
+}
+
+func (r chainIndexRepository) Rewind(stream string, block model.IndexedBlock) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	var kept []model.IndexedBlock
+	for _, b := range r.s.indexedBlocks[stream] {
+		if b.BlockNumber <= block.BlockNumber {
+			kept = append(kept, b)
+		}
+	}
+	r.s.indexedBlocks[stream] = kept
+	r.move(stream, block)
+	return nil
+}
+
+// move must be called with the lock held.
+func (r chainIndexRepository) move(stream string, block model.IndexedBlock) {
+	block.Stream = stream
+	r.s.checkpoints[stream] = model.IndexerCheckpoint{
+		Stream:      stream,
+		BlockNumber: block.BlockNumber,
+		BlockHash:   block.BlockHash,
+		UpdatedAt:   time.Now(),
+	}
+
+	blocks := r.s.indexedBlocks[stream]
+	for i := range blocks {
+		if blocks[i].BlockNumber == block.BlockNumber {
+			blocks[i] = block
+			return
+		}
+	}
+	r.s.indexedBlocks[stream] = append(blocks, block)
+}
+
+type licensePurchaseRepository struct{ s *Store }
+
+func (r licensePurchaseRepository) Create(purchase *model.LicensePurchaseEvent) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	for _, stored := range r.s.licensePurchases {
+		if stored.TxHash == purchase.TxHash && stored.LogIndex == purchase.LogIndex {
+			return nil
+		}
+	}
+	purchase.Id = 1
+	for _, stored := range r.s.licensePurchases {
+		purchase.Id = max(purchase.Id, stored.Id+1)
+	}
+	if purchase.Status == "" {
+		purchase.Status = model.LicensePurchaseStatusPending
+	}
+	r.s.licensePurchases = append(r.s.licensePurchases, *purchase)
+	return nil
+}
+
+func (r licensePurchaseRepository) Get(id uint) (*model.LicensePurchaseEvent, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	for _, purchase := range r.s.licensePurchases {
+		if purchase.Id == id {
+			return &purchase, nil
+		}
+	}
+	return nil, nil
+}
+
+func (r licensePurchaseRepository) GetByInvoiceID(invoiceID string) (*model.LicensePurchaseEvent, error) {
+	purchases := r.filter(func(purchase model.LicensePurchaseEvent) bool {
+		return purchase.InvoiceID == invoiceID
+	})
+	if len(purchases) == 0 {
+		return nil, nil
+	}
+	return &purchases[len(purchases)-1], nil
+}
+
+func (r licensePurchaseRepository) GetByStatus(statuses ...string) ([]model.LicensePurchaseEvent, error) {
+	return r.filter(func(purchase model.LicensePurchaseEvent) bool {
+		return len(statuses) == 0 || slices.Contains(statuses, purchase.Status)
+	}), nil
+}
+
+func (r licensePurchaseRepository) GetDue(now time.Time) ([]model.LicensePurchaseEvent, error) {
+	return r.filter(func(purchase model.LicensePurchaseEvent) bool {
+		return purchase.Status == model.LicensePurchaseStatusPending &&
+			(purchase.NextAttemptAt == nil || !purchase.NextAttemptAt.After(now))
+	}), nil
+}
+
+func (r licensePurchaseRepository) Update(purchase *model.LicensePurchaseEvent) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	for i, stored := range r.s.licensePurchases {
+		if stored.Id == purchase.Id {
+			r.s.licensePurchases[i] = *purchase
+			return nil
+		}
+	}
+	return gorm.ErrRecordNotFound
+}
+
+// filter returns the purchases matching keep in chain order.
+func (r licensePurchaseRepository) filter(keep func(model.LicensePurchaseEvent) bool) []model.LicensePurchaseEvent {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	var purchases []model.LicensePurchaseEvent
+	for _, purchase := range r.s.licensePurchases {
+		if keep(purchase) {
+			purchases = append(purchases, purchase)
+		}
+	}
+	sort.SliceStable(purchases, func(i, j int) bool {
+		if purchases[i].BlockNumber != purchases[j].BlockNumber {
+			return purchases[i].BlockNumber < purchases[j].BlockNumber
+		}
+		return purchases[i].LogIndex < purchases[j].LogIndex
+	})
+	return purchases
+}
+
+func (r licensePurchaseRepository) DeleteUninvoicedFromBlock(block int64) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	var kept []model.LicensePurchaseEvent
+	for _, purchase := range r.s.licensePurchases {
+		if purchase.BlockNumber < block || purchase.InvoiceNumber != nil || purchase.Status == model.LicensePurchaseStatusCompleted {
+			kept = append(kept, purchase)
+		}
+	}
+	r.s.licensePurchases = kept
+	return nil
+}
+
+type tokenTransferRepository struct{ s *Store }
+
+func (r tokenTransferRepository) Create(transfer *model.TokenTransfer) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	for _, stored := range r.s.tokenTransfers {
+		if stored.TxHash == transfer.TxHash && stored.LogIndex == transfer.LogIndex {
+			return nil
+		}
+	}
+	transfer.Id = uint(len(r.s.tokenTransfers) + 1)
+	r.s.tokenTransfers = append(r.s.tokenTransfers, *transfer)
+	return nil
+}
+
+func (r tokenTransferRepository) GetInBlockRange(from, to int64) ([]model.TokenTransfer, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	var transfers []model.TokenTransfer
+	for _, transfer := range r.s.tokenTransfers {
+		if transfer.BlockNumber >= from && transfer.BlockNumber <= to {
+			transfers = append(transfers, transfer)
+		}
+	}
+	sort.SliceStable(transfers, func(i, j int) bool {
+		if transfers[i].BlockNumber != transfers[j].BlockNumber {
+			return transfers[i].BlockNumber < transfers[j].BlockNumber
+		}
+		return transfers[i].LogIndex < transfers[j].LogIndex
+	})
+	return transfers, nil
+}
+
+func (r tokenTransferRepository) DeleteFromBlock(block int64) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	var kept []model.TokenTransfer
+	for _, transfer := range r.s.tokenTransfers {
+		if transfer.BlockNumber < block {
+			kept = append(kept, transfer)
+		}
+	}
+	r.s.tokenTransfers = kept
+	return nil
+}
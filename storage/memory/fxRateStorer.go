@@ -0,0 +1,44 @@
+package memory
+
+import (
+	"time"
+
+	"github.com/NaeuralEdgeProtocol/ratio1-backend/model"
+)
+
+type fxRateKey struct {
+	date     time.Time
+	currency string
+}
+
+type fxRateRepository struct{ s *Store }
+
+func (r fxRateRepository) Save(rates ...model.FxRate) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	for _, rate := range rates {
+		key := fxRateKey{rate.Date.UTC(), rate.Currency}
+		if stored, ok := r.s.fxRates[key]; ok {
+			rate.CreatedAt = stored.CreatedAt
+		} else {
+			rate.CreatedAt = time.Now()
+		}
+		r.s.fxRates[key] = rate
+	}
+	return nil
+}
+
+func (r fxRateRepository) GetOnOrBefore(currency string, date time.Time) (*model.FxRate, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	var found *model.FxRate
+	for key, rate := range r.s.fxRates {
+		if key.currency == currency && !key.date.After(date) && (found == nil || key.date.After(found.Date)) {
+			rate := rate
+			found = &rate
+		}
+	}
+	return found, nil
+}
@@ -0,0 +1,243 @@
+package memory
+
+import (
+	"maps"
+	"slices"
+	"sort"
+	"time"
+
+	"github.com/NaeuralEdgeProtocol/ratio1-backend/model"
+	"github.com/NaeuralEdgeProtocol/ratio1-backend/storage"
+	"github.com/google/uuid"
+)
+
+type draftRepository struct{ s *Store }
+
+func (r draftRepository) GetListByNodeOwner(userAddress string) ([]model.InvoiceDraft, error) {
+	return r.filter(func(d model.InvoiceDraft) bool { return d.UserAddress == userAddress }), nil
+}
+
+func (r draftRepository) GetListByCSP(userAddress string) ([]model.InvoiceDraft, error) {
+	return r.filter(func(d model.InvoiceDraft) bool { return d.CspOwner == userAddress }), nil
+}
+
+// GetByReportId returns an empty draft when nothing matches, as gorm Find does.
+func (r draftRepository) GetByReportId(id, userAddress string) (*model.InvoiceDraft, error) {
+	return r.first(func(d model.InvoiceDraft) bool {
+		return d.DraftId.String() == id && d.UserAddress == userAddress
+	}), nil
+}
+
+func (r draftRepository) GetCspByReportId(id, userAddress string) (*model.InvoiceDraft, error) {
+	return r.first(func(d model.InvoiceDraft) bool {
+		return d.DraftId.String() == id && d.CspOwner == userAddress
+	}), nil
+}
+
+func (r draftRepository) Create(draft *model.InvoiceDraft) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	if draft.DraftId == uuid.Nil {
+		draft.DraftId = uuid.New()
+	}
+	if _, ok := r.s.drafts[draft.DraftId]; ok {
+		return ErrDuplicateKey
+	}
+	setDraftDefaults(draft)
+	r.s.drafts[draft.DraftId] = stripDraftProfiles(*draft)
+	return nil
+}
+
+func (r draftRepository) Update(draft *model.InvoiceDraft) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	r.s.drafts[draft.DraftId] = stripDraftProfiles(*draft)
+	return nil
+}
+
+// Generate holds the store lock for the whole callback and restores the
+// touched aggregates when it fails, which gives the same all or nothing result
+// as the database transaction.
+func (r draftRepository) Generate(fn func(tx storage.DraftGenerationTx) error) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	allocations := maps.Clone(r.s.allocations)
+	preferences := maps.Clone(r.s.preferences)
+	invoiceSeries := maps.Clone(r.s.invoiceSeries)
+	nextInvoiceSeriesId := r.s.nextInvoiceSeriesId
+	drafts := maps.Clone(r.s.drafts)
+	draftTransitions := slices.Clone(r.s.draftTransitions)
+
+	err := fn(draftGenerationTx{r.s})
+	if err != nil {
+		r.s.allocations = allocations
+		r.s.preferences = preferences
+		r.s.invoiceSeries = invoiceSeries
+		r.s.nextInvoiceSeriesId = nextInvoiceSeriesId
+		r.s.drafts = drafts
+		r.s.draftTransitions = draftTransitions
+	}
+	return err
+}
+
+func (r draftRepository) Transition(draftId uuid.UUID, from []string, transition *model.InvoiceDraftTransition) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	draft, ok := r.s.drafts[draftId]
+	if !ok || !slices.Contains(from, draft.CurrentStatus()) {
+		return storage.ErrDraftStatusChanged
+	}
+	draft.Status = transition.ToStatus
+	r.s.drafts[draftId] = draft
+
+	transition.Id = uint(len(r.s.draftTransitions) + 1)
+	transition.DraftId = draftId
+	if transition.CreatedAt.IsZero() {
+		transition.CreatedAt = time.Now()
+	}
+	r.s.draftTransitions = append(r.s.draftTransitions, *transition)
+	return nil
+}
+
+func (r draftRepository) GetTransitions(draftId uuid.UUID) ([]model.InvoiceDraftTransition, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	var transitions []model.InvoiceDraftTransition
+	for _, transition := range r.s.draftTransitions {
+		if transition.DraftId == draftId {
+			transitions = append(transitions, transition)
+		}
+	}
+	return transitions, nil
+}
+
+func (r draftRepository) filter(match func(model.InvoiceDraft) bool) []model.InvoiceDraft {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	var drafts []model.InvoiceDraft
+	for _, draft := range r.s.drafts {
+		if !match(draft) {
+			continue
+		}
+		draft.CspProfile = r.s.profile(draft.CspOwner)
+		draft.UserProfile = r.s.profile(draft.UserAddress)
+		drafts = append(drafts, draft)
+	}
+	sort.Slice(drafts, func(i, j int) bool {
+		return drafts[i].CreationTimestamp.Before(drafts[j].CreationTimestamp)
+	})
+	return drafts
+}
+
+func (r draftRepository) first(match func(model.InvoiceDraft) bool) *model.InvoiceDraft {
+	drafts := r.filter(match)
+	if len(drafts) == 0 {
+		return &model.InvoiceDraft{}
+	}
+	return &drafts[0]
+}
+
+// setDraftDefaults applies the column defaults the database sets on insert.
+func setDraftDefaults(draft *model.InvoiceDraft) {
+	if draft.Status == "" {
+		draft.Status = model.DraftStatusDraft
+	}
+	if draft.Version == 0 {
+		draft.Version = 1
+	}
+}
+
+func stripDraftProfiles(draft model.InvoiceDraft) model.InvoiceDraft {
+	draft.CspProfile = model.UserInfo{}
+	draft.UserProfile = model.UserInfo{}
+	return draft
+}
+
+// draftGenerationTx works on the store maps directly, the lock is already held
+// by Generate.
+type draftGenerationTx struct{ s *Store }
+
+func (t draftGenerationTx) GetPreferenceForUpdate(userAddress string) (*model.Preference, error) {
+	pref, ok := t.s.preferences[userAddress]
+	if !ok {
+		return nil, nil
+	}
+	return &pref, nil
+}
+
+func (t draftGenerationTx) SavePreference(pref *model.Preference) error {
+	t.s.preferences[pref.UserAddress] = *pref
+	return nil
+}
+
+func (t draftGenerationTx) GetSeriesForUpdate(userAddress, name string) (*model.InvoiceSeries, error) {
+	series, ok := t.s.invoiceSeries[invoiceSeriesKey{userAddress, name}]
+	if !ok {
+		return nil, nil
+	}
+	return &series, nil
+}
+
+func (t draftGenerationTx) SaveSeries(series *model.InvoiceSeries) error {
+	return t.s.saveInvoiceSeries(series, series.Id == 0)
+}
+
+func (t draftGenerationTx) ClaimAllocations(draftId uuid.UUID, allocationIds []uint) error {
+	for _, id := range allocationIds {
+		alloc, ok := t.s.allocations[id]
+		if !ok || alloc.DraftId != nil {
+			return storage.ErrAllocationsAlreadyClaimed
+		}
+		alloc.DraftId = &draftId
+		t.s.allocations[id] = alloc
+	}
+	return nil
+}
+
+func (t draftGenerationTx) CreateDraft(draft *model.InvoiceDraft) error {
+	if draft.DraftId == uuid.Nil {
+		draft.DraftId = uuid.New()
+	}
+	if _, ok := t.s.drafts[draft.DraftId]; ok {
+		return ErrDuplicateKey
+	}
+	setDraftDefaults(draft)
+	t.s.drafts[draft.DraftId] = stripDraftProfiles(*draft)
+	return nil
+}
+
+func (t draftGenerationTx) MoveAllocations(fromDraftId, toDraftId uuid.UUID, allocationIds []uint) error {
+	for _, id := range allocationIds {
+		alloc, ok := t.s.allocations[id]
+		if !ok || alloc.DraftId == nil || *alloc.DraftId != fromDraftId {
+			return storage.ErrAllocationsAlreadyClaimed
+		}
+		alloc.DraftId = &toDraftId
+		t.s.allocations[id] = alloc
+	}
+	return nil
+}
+
+func (t draftGenerationTx) SupersedeDraft(draftId, supersededBy uuid.UUID, transition *model.InvoiceDraftTransition) error {
+	draft, ok := t.s.drafts[draftId]
+	if !ok || draft.CurrentStatus() != model.DraftStatusDraft || draft.SupersededBy != nil {
+		return storage.ErrDraftStatusChanged
+	}
+	draft.Status = model.DraftStatusSuperseded
+	draft.SupersededBy = &supersededBy
+	t.s.drafts[draftId] = draft
+
+	transition.Id = uint(len(t.s.draftTransitions) + 1)
+	transition.DraftId = draftId
+	if transition.CreatedAt.IsZero() {
+		transition.CreatedAt = time.Now()
+	}
+	t.s.draftTransitions = append(t.s.draftTransitions, *transition)
+	return nil
+}
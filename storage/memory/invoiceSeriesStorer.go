@@ -0,0 +1,84 @@
+package memory
+
+import (
+	"sort"
+	"time"
+
+	"github.com/NaeuralEdgeProtocol/ratio1-backend/model"
+	"gorm.io/gorm"
+)
+
+type invoiceSeriesKey struct {
+	userAddress string
+	name        string
+}
+
+type invoiceSeriesRepository struct{ s *Store }
+
+func (r invoiceSeriesRepository) GetByUser(userAddress string) ([]model.InvoiceSeries, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	var series []model.InvoiceSeries
+	for key, s := range r.s.invoiceSeries {
+		if key.userAddress == userAddress {
+			series = append(series, s)
+		}
+	}
+	sort.Slice(series, func(i, j int) bool { return series[i].Name < series[j].Name })
+	return series, nil
+}
+
+func (r invoiceSeriesRepository) Get(userAddress, name string) (*model.InvoiceSeries, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	series, ok := r.s.invoiceSeries[invoiceSeriesKey{userAddress, name}]
+	if !ok {
+		return nil, nil
+	}
+	return &series, nil
+}
+
+func (r invoiceSeriesRepository) Create(series *model.InvoiceSeries) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	return r.s.saveInvoiceSeries(series, true)
+}
+
+func (r invoiceSeriesRepository) Change(userAddress, name string, fn func(series *model.InvoiceSeries) error) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	series, ok := r.s.invoiceSeries[invoiceSeriesKey{userAddress, name}]
+	if !ok {
+		return gorm.ErrRecordNotFound
+	}
+	err := fn(&series)
+	if err != nil {
+		return err
+	}
+	return r.s.saveInvoiceSeries(&series, false)
+}
+
+// saveInvoiceSeries must be called with the lock held.
+func (s *Store) saveInvoiceSeries(series *model.InvoiceSeries, create bool) error {
+	key := invoiceSeriesKey{series.UserAddress, series.Name}
+	if _, ok := s.invoiceSeries[key]; ok && create {
+		return ErrDuplicateKey
+	}
+
+	now := time.Now()
+	if series.Id == 0 {
+		s.nextInvoiceSeriesId++
+		series.Id = s.nextInvoiceSeriesId
+		series.CreatedAt = now
+	}
+	if series.NextNumber == 0 {
+		series.NextNumber = 1
+	}
+	series.UpdatedAt = now
+	s.invoiceSeries[key] = *series
+	return nil
+}
@@ -0,0 +1,163 @@
+package memory
+
+import (
+	"errors"
+	"time"
+
+	"github.com/NaeuralEdgeProtocol/ratio1-backend/model"
+)
+
+type invoiceRepository struct{ s *Store }
+
+func (r invoiceRepository) GetLatestBlock() (*int64, bool, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	var latest *int64
+	for _, invoice := range r.s.invoices {
+		if invoice.BlockNumber == nil {
+			continue
+		}
+		if latest == nil || *invoice.BlockNumber > *latest {
+			block := *invoice.BlockNumber
+			latest = &block
+		}
+	}
+	if latest == nil {
+		return nil, false, nil
+	}
+	return latest, true, nil
+}
+
+func (r invoiceRepository) GetByID(id string) (*model.InvoiceClient, bool, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	invoice, ok := r.s.invoices[id]
+	if !ok {
+		return nil, false, nil
+	}
+	return &invoice, true, nil
+}
+
+func (r invoiceRepository) Create(invoice *model.InvoiceClient) error {
+	if invoice.Uuid == nil {
+		return errors.New("invoice uuid is required")
+	}
+
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	if _, ok := r.s.invoices[*invoice.Uuid]; ok {
+		return ErrDuplicateKey
+	}
+	r.s.invoices[*invoice.Uuid] = *invoice
+	return nil
+}
+
+func (r invoiceRepository) Update(invoice *model.InvoiceClient) error {
+	if invoice.Uuid == nil {
+		return errors.New("invoice uuid is required")
+	}
+
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	r.s.invoices[*invoice.Uuid] = *invoice
+	return nil
+}
+
+// GetUserInvoices returns the paid invoices of the license purchases made by
+// address.
+func (r invoiceRepository) GetUserInvoices(address string) (*[]model.InvoiceClient, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	invoices := []model.InvoiceClient{}
+	for _, invoice := range r.s.invoices {
+		if invoice.Status == nil || *invoice.Status != model.InvoiceStatusPaid {
+			continue
+		}
+		if invoice.BlockchainAddress == address {
+			invoices = append(invoices, invoice)
+		}
+	}
+	return &invoices, nil
+}
+
+func (r invoiceRepository) GetByAddress(address string) ([]model.InvoiceClient, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	var invoices []model.InvoiceClient
+	for _, invoice := range r.s.invoices {
+		if invoice.BlockchainAddress == address {
+			invoices = append(invoices, invoice)
+		}
+	}
+	return invoices, nil
+}
+
+func (r invoiceRepository) GetPaid(from, to time.Time) ([]model.InvoiceClient, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	var invoices []model.InvoiceClient
+	for _, invoice := range r.s.invoices {
+		if invoice.Status == nil || *invoice.Status == model.InvoiceStatusPending {
+			continue
+		}
+		if invoice.PaidAt != nil && !invoice.PaidAt.Before(from) && invoice.PaidAt.Before(to) {
+			invoices = append(invoices, invoice)
+		}
+	}
+	return invoices, nil
+}
+
+func (r invoiceRepository) GetUndatedPayments() ([]model.InvoiceClient, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	var invoices []model.InvoiceClient
+	for _, invoice := range r.s.invoices {
+		if invoice.BlockNumber != nil && invoice.PaidAt == nil {
+			invoices = append(invoices, invoice)
+		}
+	}
+	return invoices, nil
+}
+
+func (r invoiceRepository) AddStatusChange(change *model.InvoiceStatusChange) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	change.Id = uint(len(r.s.invoiceStatusChanges) + 1)
+	r.s.invoiceStatusChanges = append(r.s.invoiceStatusChanges, *change)
+	return nil
+}
+
+func (r invoiceRepository) GetStatusChanges(invoiceUuid string) ([]model.InvoiceStatusChange, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	var changes []model.InvoiceStatusChange
+	for _, change := range r.s.invoiceStatusChanges {
+		if change.InvoiceUuid == invoiceUuid {
+			changes = append(changes, change)
+		}
+	}
+	return changes, nil
+}
+
+func (r invoiceRepository) GetCreditNotes(from, to time.Time) ([]model.InvoiceStatusChange, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	var changes []model.InvoiceStatusChange
+	for _, change := range r.s.invoiceStatusChanges {
+		if change.DocumentType == model.InvoiceDocumentCreditNote && !change.CreatedAt.Before(from) && change.CreatedAt.Before(to) {
+			changes = append(changes, change)
+		}
+	}
+	return changes, nil
+}
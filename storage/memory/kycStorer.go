@@ -0,0 +1,128 @@
+package memory
+
+import (
+	"github.com/NaeuralEdgeProtocol/ratio1-backend/model"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type kycRepository struct{ s *Store }
+
+func (r kycRepository) GetByEmail(email string) (*model.Kyc, bool, error) {
+	return r.find(func(k model.Kyc) bool { return k.Email == email })
+}
+
+func (r kycRepository) GetByApplicantID(applicantId string) (*model.Kyc, bool, error) {
+	return r.find(func(k model.Kyc) bool { return k.ApplicantId == applicantId })
+}
+
+func (r kycRepository) GetByUuid(id uuid.UUID) (*model.Kyc, bool, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	kyc, ok := r.s.kycs[id]
+	if !ok {
+		return nil, false, nil
+	}
+	return &kyc, true, nil
+}
+
+// CreateOrUpdate follows the gorm Updates semantic: on an existing email only
+// the non zero fields of kyc overwrite the stored row.
+func (r kycRepository) CreateOrUpdate(kyc *model.Kyc) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	for id, existing := range r.s.kycs {
+		if existing.Email != kyc.Email {
+			continue
+		}
+		merged := mergeKyc(existing, *kyc)
+		if merged.Uuid != id {
+			delete(r.s.kycs, id)
+		}
+		r.s.kycs[merged.Uuid] = merged
+		return nil
+	}
+
+	if _, ok := r.s.kycs[kyc.Uuid]; ok {
+		return ErrDuplicateKey
+	}
+	stored := *kyc
+	if stored.ReceiveUpdates == nil {
+		receiveUpdates := false
+		stored.ReceiveUpdates = &receiveUpdates
+	}
+	r.s.kycs[stored.Uuid] = stored
+	return nil
+}
+
+func (r kycRepository) Update(kyc *model.Kyc) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	if _, ok := r.s.kycs[kyc.Uuid]; !ok {
+		return gorm.ErrRecordNotFound
+	}
+	r.s.kycs[kyc.Uuid] = *kyc
+	return nil
+}
+
+func (r kycRepository) GetAllUsersEmails() ([]string, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	var emails []string
+	for _, kyc := range r.s.kycs {
+		if kyc.Email != "" {
+			emails = append(emails, kyc.Email)
+		}
+	}
+	return emails, nil
+}
+
+func (r kycRepository) find(match func(model.Kyc) bool) (*model.Kyc, bool, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	for _, kyc := range r.s.kycs {
+		if match(kyc) {
+			return &kyc, true, nil
+		}
+	}
+	return nil, false, nil
+}
+
+func mergeKyc(existing, update model.Kyc) model.Kyc {
+	if update.Uuid != uuid.Nil {
+		existing.Uuid = update.Uuid
+	}
+	if update.ApplicantId != "" {
+		existing.ApplicantId = update.ApplicantId
+	}
+	if update.ApplicantType != "" {
+		existing.ApplicantType = update.ApplicantType
+	}
+	if update.KycStatus != "" {
+		existing.KycStatus = update.KycStatus
+	}
+	if !update.LastUpdated.IsZero() {
+		existing.LastUpdated = update.LastUpdated
+	}
+	if update.IsActive {
+		existing.IsActive = true
+	}
+	if update.HasBeenDeleted {
+		existing.HasBeenDeleted = true
+	}
+	if update.ReceiveUpdates != nil {
+		existing.ReceiveUpdates = update.ReceiveUpdates
+	}
+	if update.Country != "" {
+		existing.Country = update.Country
+	}
+	if update.ViesRegistered {
+		existing.ViesRegistered = true
+	}
+	return existing
+}
@@ -0,0 +1,40 @@
+package memory
+
+import (
+	"github.com/NaeuralEdgeProtocol/ratio1-backend/model"
+)
+
+type preferenceRepository struct{ s *Store }
+
+func (r preferenceRepository) GetByAddress(userAddress string) (*model.Preference, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	pref, ok := r.s.preferences[userAddress]
+	if !ok {
+		return nil, nil
+	}
+	return &pref, nil
+}
+
+func (r preferenceRepository) Create(pref *model.Preference) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	if _, ok := r.s.preferences[pref.UserAddress]; ok {
+		return ErrDuplicateKey
+	}
+	if pref.NextNumber == 0 {
+		pref.NextNumber = 1
+	}
+	r.s.preferences[pref.UserAddress] = *pref
+	return nil
+}
+
+func (r preferenceRepository) Update(pref *model.Preference) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	r.s.preferences[pref.UserAddress] = *pref
+	return nil
+}
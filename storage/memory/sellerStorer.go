@@ -0,0 +1,91 @@
+package memory
+
+import (
+	"sort"
+
+	"github.com/NaeuralEdgeProtocol/ratio1-backend/model"
+)
+
+type sellerRepository struct{ s *Store }
+
+func (r sellerRepository) Create(seller *model.Seller) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	if _, ok := r.s.sellers[seller.SellerCode]; ok {
+		return ErrDuplicateKey
+	}
+	r.s.sellers[seller.SellerCode] = *seller
+	return nil
+}
+
+func (r sellerRepository) GetCodeByAddress(address string) (*string, error) {
+	seller, err := r.GetByAddress(address)
+	if err != nil || seller == nil {
+		return nil, err
+	}
+	return &seller.SellerCode, nil
+}
+
+func (r sellerRepository) GetByAddress(address string) (*model.Seller, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	for _, seller := range r.s.sellers {
+		if seller.AccountID == address {
+			return &seller, nil
+		}
+	}
+	return nil, nil
+}
+
+func (r sellerRepository) AddressHasCode(accountID string) (bool, error) {
+	seller, err := r.GetByAddress(accountID)
+	if err != nil {
+		return false, err
+	}
+	return seller != nil, nil
+}
+
+func (r sellerRepository) CodeExists(sellerCode string) (bool, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	seller, ok := r.s.sellers[sellerCode]
+	if !ok || seller.IsDisabled {
+		return false, nil
+	}
+	return true, nil
+}
+
+func (r sellerRepository) GetAll() ([]model.Seller, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	if len(r.s.sellers) == 0 {
+		return nil, nil
+	}
+	sellers := make([]model.Seller, 0, len(r.s.sellers))
+	for _, seller := range r.s.sellers {
+		sellers = append(sellers, seller)
+	}
+	sort.Slice(sellers, func(i, j int) bool { return sellers[i].SellerCode < sellers[j].SellerCode })
+	return sellers, nil
+}
+
+// GetByCode returns an empty seller when the code is unknown, as gorm Find does.
+func (r sellerRepository) GetByCode(sellerCode string) (*model.Seller, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	seller := r.s.sellers[sellerCode]
+	return &seller, nil
+}
+
+func (r sellerRepository) Update(seller *model.Seller) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	r.s.sellers[seller.SellerCode] = *seller
+	return nil
+}
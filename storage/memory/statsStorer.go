@@ -0,0 +1,61 @@
+package memory
+
+import (
+	"errors"
+	"sort"
+
+	"github.com/NaeuralEdgeProtocol/ratio1-backend/model"
+)
+
+type statsRepository struct{ s *Store }
+
+func (r statsRepository) Create(stats *model.Stats) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	key := stats.CreationTimestamp.UTC()
+	if _, ok := r.s.stats[key]; ok {
+		return ErrDuplicateKey
+	}
+	r.s.stats[key] = *stats
+	return nil
+}
+
+func (r statsRepository) Update(stats *model.Stats) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	key := stats.CreationTimestamp.UTC()
+	if _, ok := r.s.stats[key]; !ok {
+		return errors.New("no row inserted")
+	}
+	r.s.stats[key] = *stats
+	return nil
+}
+
+func (r statsRepository) GetLatest() (*model.Stats, error) {
+	all := r.sorted()
+	if len(all) == 0 {
+		return nil, nil
+	}
+	return &all[len(all)-1], nil
+}
+
+func (r statsRepository) GetAllASC() (*[]model.Stats, error) {
+	all := r.sorted()
+	return &all, nil
+}
+
+func (r statsRepository) sorted() []model.Stats {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	all := make([]model.Stats, 0, len(r.s.stats))
+	for _, stats := range r.s.stats {
+		all = append(all, stats)
+	}
+	sort.Slice(all, func(i, j int) bool {
+		return all[i].CreationTimestamp.Before(all[j].CreationTimestamp)
+	})
+	return all
+}
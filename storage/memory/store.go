@@ -0,0 +1,117 @@
+package memory
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/NaeuralEdgeProtocol/ratio1-backend/model"
+	"github.com/NaeuralEdgeProtocol/ratio1-backend/storage"
+	"github.com/google/uuid"
+)
+
+var ErrDuplicateKey = errors.New("duplicate key value violates unique constraint")
+
+// Store keeps every aggregate in process memory. It mirrors the behaviour of
+// the gorm storers closely enough to run services and handlers in tests.
+type Store struct {
+	mu sync.RWMutex
+
+	accounts             map[string]model.Account
+	notificationEmails   map[string]model.AccountNotificationEmail
+	kycs                 map[uuid.UUID]model.Kyc
+	userInfos            map[string]model.UserInfo
+	userInfoVersions     map[string][]model.UserInfoVersion
+	invoices             map[string]model.InvoiceClient
+	invoiceStatusChanges []model.InvoiceStatusChange
+	allocations          map[uint]model.Allocation
+	drafts               map[uuid.UUID]model.InvoiceDraft
+	draftTransitions     []model.InvoiceDraftTransition
+	preferences          map[string]model.Preference
+	invoiceSeries        map[invoiceSeriesKey]model.InvoiceSeries
+	burnEvents           map[uint]model.BurnEvent
+	stats                map[time.Time]model.Stats
+	sellers              map[string]model.Seller
+	brandings            map[string]model.Branding
+	accountExports       map[uuid.UUID]model.AccountExport
+	accountErasures      map[uuid.UUID]model.AccountErasureRequest
+	auditLogs            []model.AuditLog
+	checkpoints          map[string]model.IndexerCheckpoint
+	indexedBlocks        map[string][]model.IndexedBlock
+	blocks               map[int64]model.Block
+	fxRates              map[fxRateKey]model.FxRate
+	vatRates             []model.VatRate
+	viesChecks           []model.ViesCheck
+	licensePurchases     []model.LicensePurchaseEvent
+	tokenTransfers       []model.TokenTransfer
+
+	nextAllocationId uint
+	nextBurnEventId  uint
+
+	nextInvoiceSeriesId uint
+	nextVatRateId       uint
+}
+
+func NewStore() *Store {
+	return &Store{
+		accounts:           make(map[string]model.Account),
+		notificationEmails: make(map[string]model.AccountNotificationEmail),
+		kycs:               make(map[uuid.UUID]model.Kyc),
+		userInfos:          make(map[string]model.UserInfo),
+		userInfoVersions:   make(map[string][]model.UserInfoVersion),
+		invoices:           make(map[string]model.InvoiceClient),
+		allocations:        make(map[uint]model.Allocation),
+		drafts:             make(map[uuid.UUID]model.InvoiceDraft),
+		preferences:        make(map[string]model.Preference),
+		invoiceSeries:      make(map[invoiceSeriesKey]model.InvoiceSeries),
+		burnEvents:         make(map[uint]model.BurnEvent),
+		stats:              make(map[time.Time]model.Stats),
+		sellers:            make(map[string]model.Seller),
+		brandings:          make(map[string]model.Branding),
+		accountExports:     make(map[uuid.UUID]model.AccountExport),
+		accountErasures:    make(map[uuid.UUID]model.AccountErasureRequest),
+		checkpoints:        make(map[string]model.IndexerCheckpoint),
+		indexedBlocks:      make(map[string][]model.IndexedBlock),
+		blocks:             make(map[int64]model.Block),
+		fxRates:            make(map[fxRateKey]model.FxRate),
+	}
+}
+
+// NewRepositories returns a fresh in-memory store wired as storage.Repositories.
+func NewRepositories() *storage.Repositories {
+	return NewStore().Repositories()
+}
+
+func (s *Store) Repositories() *storage.Repositories {
+	return &storage.Repositories{
+		Accounts:           accountRepository{s},
+		NotificationEmails: notificationEmailRepository{s},
+		Kycs:               kycRepository{s},
+		UserInfos:          userInfoRepository{s},
+		Invoices:           invoiceRepository{s},
+		Allocations:        allocationRepository{s},
+		Drafts:             draftRepository{s},
+		Preferences:        preferenceRepository{s},
+		InvoiceSeries:      invoiceSeriesRepository{s},
+		BurnEvents:         burnEventRepository{s},
+		Stats:              statsRepository{s},
+		Sellers:            sellerRepository{s},
+		Brandings:          brandingRepository{s},
+		AccountExports:     accountExportRepository{s},
+		AccountErasures:    accountErasureRepository{s},
+		AuditLogs:          auditLogRepository{s},
+		ChainIndex:         chainIndexRepository{s},
+		Blocks:             blockRepository{s},
+		FxRates:            fxRateRepository{s},
+		VatRates:           vatRateRepository{s},
+		ViesChecks:         viesCheckRepository{s},
+		LicensePurchases:   licensePurchaseRepository{s},
+		TokenTransfers:     tokenTransferRepository{s},
+	}
+}
+
+// profile emulates the gorm Preload of a UserInfo association, it must be
+// called with the lock held.
+func (s *Store) profile(address string) model.UserInfo {
+	return s.userInfos[address]
+}
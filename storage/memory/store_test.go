@@ -0,0 +1,130 @@
+package memory
+
+import (
+	"testing"
+	"time"
+
+	"github.com/NaeuralEdgeProtocol/ratio1-backend/model"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+func TestAccountRepositoryUniqueEmail(t *testing.T) {
+	repos := NewRepositories()
+	email := "owner@example.com"
+
+	require.NoError(t, repos.Accounts.Create(&model.Account{Address: "0x1", Email: &email}))
+	require.ErrorIs(t, repos.Accounts.Create(&model.Account{Address: "0x1"}), ErrDuplicateKey)
+	require.ErrorIs(t, repos.Accounts.Create(&model.Account{Address: "0x2", Email: &email}), ErrDuplicateKey)
+
+	account, found, err := repos.Accounts.GetByEmail(email)
+	require.NoError(t, err)
+	require.True(t, found)
+	require.Equal(t, "0x1", account.Address)
+
+	_, found, err = repos.Accounts.GetByAddress("0x3")
+	require.NoError(t, err)
+	require.False(t, found)
+}
+
+func TestAccountRepositoryReturnsCopies(t *testing.T) {
+	repos := NewRepositories()
+	require.NoError(t, repos.Accounts.Create(&model.Account{Address: "0x1"}))
+
+	account, _, err := repos.Accounts.GetByAddress("0x1")
+	require.NoError(t, err)
+	account.IsBlacklisted = true
+
+	stored, _, err := repos.Accounts.GetByAddress("0x1")
+	require.NoError(t, err)
+	require.False(t, stored.IsBlacklisted)
+}
+
+func TestKycRepositoryCreateOrUpdateKeepsUnsetFields(t *testing.T) {
+	repos := NewRepositories()
+	receiveUpdates := true
+	kyc := &model.Kyc{Email: "kyc@example.com", Uuid: uuid.New(), ReceiveUpdates: &receiveUpdates}
+	require.NoError(t, repos.Kycs.CreateOrUpdate(kyc))
+
+	require.NoError(t, repos.Kycs.CreateOrUpdate(&model.Kyc{Email: kyc.Email, Uuid: kyc.Uuid, KycStatus: model.StatusPending}))
+
+	stored, found, err := repos.Kycs.GetByUuid(kyc.Uuid)
+	require.NoError(t, err)
+	require.True(t, found)
+	require.Equal(t, model.StatusPending, stored.KycStatus)
+	require.NotNil(t, stored.ReceiveUpdates)
+	require.True(t, *stored.ReceiveUpdates)
+}
+
+func TestAllocationRepositoryIgnoresDuplicatedLogs(t *testing.T) {
+	repos := NewRepositories()
+	logIndex := uint(3)
+
+	first := &model.Allocation{TxHash: "0xabc", LogIndex: &logIndex, BlockNumber: 10, CspAddress: "0xcsp", UserAddress: "0xuser", NodeAddress: "0xnode"}
+	require.NoError(t, repos.Allocations.Create(first))
+	require.NoError(t, repos.Allocations.Create(&model.Allocation{TxHash: "0xabc", LogIndex: &logIndex, BlockNumber: 11}))
+
+	allocations, err := repos.Allocations.GetByCspAndUser("0xcsp", "0xuser", "0xnode")
+	require.NoError(t, err)
+	require.Len(t, allocations, 1)
+
+	latest, err := repos.Allocations.GetLatestBlock()
+	require.NoError(t, err)
+	require.Equal(t, int64(10), latest)
+
+	_, err = repos.Allocations.GetByCspAndUser("0xcsp", "0xother", "0xnode")
+	require.ErrorIs(t, err, gorm.ErrRecordNotFound)
+}
+
+func TestAllocationRepositoryMonthlyUnclaimed(t *testing.T) {
+	repos := NewRepositories()
+	now := time.Date(2025, time.March, 10, 0, 0, 0, 0, time.UTC)
+	draftId := uuid.New()
+
+	require.NoError(t, repos.Allocations.Create(&model.Allocation{TxHash: "0x1", AllocationCreation: time.Date(2025, time.February, 3, 0, 0, 0, 0, time.UTC)}))
+	require.NoError(t, repos.Allocations.Create(&model.Allocation{TxHash: "0x2", AllocationCreation: time.Date(2025, time.February, 4, 0, 0, 0, 0, time.UTC), DraftId: &draftId}))
+	require.NoError(t, repos.Allocations.Create(&model.Allocation{TxHash: "0x3", AllocationCreation: time.Date(2025, time.March, 1, 0, 0, 0, 0, time.UTC)}))
+
+	allocations, err := repos.Allocations.GetMonthlyUnclaimed(now)
+	require.NoError(t, err)
+	require.Len(t, allocations, 1)
+	require.Equal(t, "0x1", allocations[0].TxHash)
+}
+
+func TestStatsRepositoryOrdering(t *testing.T) {
+	repos := NewRepositories()
+	base := time.Date(2025, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+	require.NoError(t, repos.Stats.Create(&model.Stats{CreationTimestamp: base.Add(24 * time.Hour)}))
+	require.NoError(t, repos.Stats.Create(&model.Stats{CreationTimestamp: base}))
+
+	latest, err := repos.Stats.GetLatest()
+	require.NoError(t, err)
+	require.Equal(t, base.Add(24*time.Hour), latest.CreationTimestamp)
+
+	all, err := repos.Stats.GetAllASC()
+	require.NoError(t, err)
+	require.Len(t, *all, 2)
+	require.Equal(t, base, (*all)[0].CreationTimestamp)
+}
+
+func TestLicensePurchaseRepositoryKeepsInvoicedOnRollback(t *testing.T) {
+	repos := NewRepositories()
+	number := "7"
+
+	require.NoError(t, repos.LicensePurchases.Create(&model.LicensePurchaseEvent{TxHash: "0x1", BlockNumber: 9, Status: model.LicensePurchaseStatusPending}))
+	require.NoError(t, repos.LicensePurchases.Create(&model.LicensePurchaseEvent{TxHash: "0x2", BlockNumber: 10, Status: model.LicensePurchaseStatusPending}))
+	require.NoError(t, repos.LicensePurchases.Create(&model.LicensePurchaseEvent{TxHash: "0x3", BlockNumber: 10, Status: model.LicensePurchaseStatusPending, InvoiceNumber: &number}))
+	require.NoError(t, repos.LicensePurchases.Create(&model.LicensePurchaseEvent{TxHash: "0x4", BlockNumber: 11, Status: model.LicensePurchaseStatusCompleted}))
+
+	require.NoError(t, repos.LicensePurchases.DeleteUninvoicedFromBlock(10))
+
+	purchases, err := repos.LicensePurchases.GetByStatus()
+	require.NoError(t, err)
+	hashes := make([]string, 0, len(purchases))
+	for _, purchase := range purchases {
+		hashes = append(hashes, purchase.TxHash)
+	}
+	require.Equal(t, []string{"0x1", "0x3", "0x4"}, hashes)
+}
@@ -0,0 +1,93 @@
+package memory
+
+import (
+	"sort"
+	"time"
+
+	"github.com/NaeuralEdgeProtocol/ratio1-backend/model"
+)
+
+type userInfoRepository struct{ s *Store }
+
+func (r userInfoRepository) Create(userInfo *model.UserInfo) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	if _, ok := r.s.userInfos[userInfo.BlockchainAddress]; ok {
+		return ErrDuplicateKey
+	}
+	r.save(userInfo)
+	return nil
+}
+
+func (r userInfoRepository) Update(userInfo *model.UserInfo) error {
+	return r.CreateOrUpdate(userInfo)
+}
+
+func (r userInfoRepository) CreateOrUpdate(userInfo *model.UserInfo) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	r.save(userInfo)
+	return nil
+}
+
+func (r userInfoRepository) GetByAddress(address string) (*model.UserInfo, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	userInfo, ok := r.s.userInfos[address]
+	if !ok {
+		return nil, nil
+	}
+	return &userInfo, nil
+}
+
+func (r userInfoRepository) GetVersions(address string) ([]model.UserInfoVersion, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	return append([]model.UserInfoVersion(nil), r.s.userInfoVersions[address]...), nil
+}
+
+func (r userInfoRepository) DeleteVersions(address string) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	delete(r.s.userInfoVersions, address)
+	return nil
+}
+
+func (r userInfoRepository) GetCompanies() ([]model.UserInfo, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	var companies []model.UserInfo
+	for _, userInfo := range r.s.userInfos {
+		if userInfo.IsCompany {
+			companies = append(companies, userInfo)
+		}
+	}
+	sort.Slice(companies, func(i, j int) bool { return companies[i].BlockchainAddress < companies[j].BlockchainAddress })
+	return companies, nil
+}
+
+// save stores the profile as a new version, it must be called with the lock
+// held.
+func (r userInfoRepository) save(userInfo *model.UserInfo) {
+	versions := r.s.userInfoVersions[userInfo.BlockchainAddress]
+	userInfo.Version = 1
+	if len(versions) > 0 {
+		userInfo.Version = versions[len(versions)-1].Version + 1
+	}
+
+	r.s.userInfos[userInfo.BlockchainAddress] = *userInfo
+	r.s.userInfoVersions[userInfo.BlockchainAddress] = append(versions, model.UserInfoVersion{
+		Id:                uint(len(versions) + 1),
+		BlockchainAddress: userInfo.BlockchainAddress,
+		Version:           userInfo.Version,
+		Email:             userInfo.Email,
+		CreatedAt:         time.Now(),
+		BillingIdentity:   userInfo.Identity(),
+	})
+}
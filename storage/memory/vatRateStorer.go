@@ -0,0 +1,43 @@
+package memory
+
+import (
+	"sort"
+	"time"
+
+	"github.com/NaeuralEdgeProtocol/ratio1-backend/model"
+)
+
+type vatRateRepository struct{ s *Store }
+
+func (r vatRateRepository) GetAll() ([]model.VatRate, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	rates := make([]model.VatRate, len(r.s.vatRates))
+	copy(rates, r.s.vatRates)
+	sort.Slice(rates, func(i, j int) bool {
+		if rates[i].Country != rates[j].Country {
+			return rates[i].Country < rates[j].Country
+		}
+		return rates[i].EffectiveFrom.Before(rates[j].EffectiveFrom)
+	})
+	return rates, nil
+}
+
+func (r vatRateRepository) Save(rate *model.VatRate) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	for i, stored := range r.s.vatRates {
+		if stored.Country == rate.Country && stored.EffectiveFrom.Equal(rate.EffectiveFrom) {
+			rate.Id, rate.CreatedAt = stored.Id, stored.CreatedAt
+			r.s.vatRates[i] = *rate
+			return nil
+		}
+	}
+	r.s.nextVatRateId++
+	rate.Id = r.s.nextVatRateId
+	rate.CreatedAt = time.Now()
+	r.s.vatRates = append(r.s.vatRates, *rate)
+	return nil
+}
@@ -0,0 +1,43 @@
+package memory
+
+import (
+	"github.com/NaeuralEdgeProtocol/ratio1-backend/model"
+)
+
+type viesCheckRepository struct{ s *Store }
+
+func (r viesCheckRepository) Create(check *model.ViesCheck) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	check.Id = uint(len(r.s.viesChecks) + 1)
+	r.s.viesChecks = append(r.s.viesChecks, *check)
+	return nil
+}
+
+func (r viesCheckRepository) GetLatest(vatNumber string) (*model.ViesCheck, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	var latest *model.ViesCheck
+	for i := range r.s.viesChecks {
+		check := r.s.viesChecks[i]
+		if check.VatNumber == vatNumber && (latest == nil || !check.CheckedAt.Before(latest.CheckedAt)) {
+			latest = &check
+		}
+	}
+	return latest, nil
+}
+
+func (r viesCheckRepository) GetAll(vatNumber string) ([]model.ViesCheck, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	var checks []model.ViesCheck
+	for _, check := range r.s.viesChecks {
+		if check.VatNumber == vatNumber {
+			checks = append(checks, check)
+		}
+	}
+	return checks, nil
+}
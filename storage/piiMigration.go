@@ -0,0 +1,102 @@
+package storage
+
+import (
+	"errors"
+
+	"github.com/NaeuralEdgeProtocol/ratio1-backend/model"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const piiMigrationBatchSize = 200
+
+// EncryptPiiColumns re-saves every row holding personal data so that it is
+// stored encrypted with the active key. It covers both rows written before
+// encryption was enabled and rows encrypted with a rotated out key, and it can
+// be run again safely.
+func EncryptPiiColumns() error {
+	if piiKeyring == nil {
+		return ErrPiiKeyringNotConfigured
+	}
+
+	db, err := GetDB()
+	if err != nil {
+		return err
+	}
+
+	var userInfos []model.UserInfo
+	err = db.FindInBatches(&userInfos, piiMigrationBatchSize, func(batch *gorm.DB, _ int) error {
+		return Transaction(func(tx *gorm.DB) error {
+			return tx.Save(&userInfos).Error
+		})
+	}).Error
+	if err != nil {
+		return errors.New("error while encrypting user info: " + err.Error())
+	}
+
+	var versions []model.UserInfoVersion
+	err = db.FindInBatches(&versions, piiMigrationBatchSize, func(batch *gorm.DB, _ int) error {
+		return Transaction(func(tx *gorm.DB) error {
+			return tx.Save(&versions).Error
+		})
+	}).Error
+	if err != nil {
+		return errors.New("error while encrypting user info versions: " + err.Error())
+	}
+
+	var clients []model.InvoiceClient
+	err = db.FindInBatches(&clients, piiMigrationBatchSize, func(batch *gorm.DB, _ int) error {
+		return Transaction(func(tx *gorm.DB) error {
+			return tx.Save(&clients).Error
+		})
+	}).Error
+	if err != nil {
+		return errors.New("error while encrypting invoice clients: " + err.Error())
+	}
+
+	// the seller and buyer identities frozen on the drafts
+	var drafts []model.InvoiceDraft
+	err = db.FindInBatches(&drafts, piiMigrationBatchSize, func(batch *gorm.DB, _ int) error {
+		return Transaction(func(tx *gorm.DB) error {
+			return tx.Omit(clause.Associations).Save(&drafts).Error
+		})
+	}).Error
+	if err != nil {
+		return errors.New("error while encrypting invoice drafts: " + err.Error())
+	}
+
+	var kycs []model.Kyc
+	err = db.FindInBatches(&kycs, piiMigrationBatchSize, func(batch *gorm.DB, _ int) error {
+		for i := range kycs {
+			kycs[i].EmailIndex = PiiBlindIndex(kycs[i].Email)
+		}
+		return Transaction(func(tx *gorm.DB) error {
+			return tx.Save(&kycs).Error
+		})
+	}).Error
+	if err != nil {
+		return errors.New("error while encrypting kyc: " + err.Error())
+	}
+
+	var checks []model.ViesCheck
+	err = db.FindInBatches(&checks, piiMigrationBatchSize, func(batch *gorm.DB, _ int) error {
+		return Transaction(func(tx *gorm.DB) error {
+			return tx.Save(&checks).Error
+		})
+	}).Error
+	if err != nil {
+		return errors.New("error while encrypting vies checks: " + err.Error())
+	}
+
+	var exports []model.AccountExport
+	err = db.FindInBatches(&exports, piiMigrationBatchSize, func(batch *gorm.DB, _ int) error {
+		return Transaction(func(tx *gorm.DB) error {
+			return tx.Save(&exports).Error
+		})
+	}).Error
+	if err != nil {
+		return errors.New("error while encrypting account exports: " + err.Error())
+	}
+
+	return nil
+}
@@ -0,0 +1,97 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+
+	"github.com/NaeuralEdgeProtocol/ratio1-backend/crypto"
+	"gorm.io/gorm/schema"
+)
+
+var piiKeyring *crypto.PiiKeyring
+
+var ErrPiiKeyringNotConfigured = errors.New("pii keyring not configured")
+
+func init() {
+	schema.RegisterSerializer("pii", piiSerializer{})
+}
+
+// SetPiiKeyring sets the keyring used by the pii serializer. Without a keyring
+// values are written in plaintext, which is only meant for local development.
+func SetPiiKeyring(keyring *crypto.PiiKeyring) {
+	piiKeyring = keyring
+}
+
+// PiiBlindIndex returns the blind index used to look up an encrypted column.
+func PiiBlindIndex(value string) string {
+	if piiKeyring == nil {
+		return crypto.UnkeyedBlindIndex(value)
+	}
+	return piiKeyring.BlindIndex(value)
+}
+
+// piiSerializer encrypts string columns tagged with serializer:pii on write and
+// decrypts them on read, so the models keep plain string fields.
+type piiSerializer struct{}
+
+func (piiSerializer) Scan(ctx context.Context, field *schema.Field, dst reflect.Value, dbValue interface{}) error {
+	fieldValue := reflect.New(field.FieldType)
+
+	if dbValue != nil {
+		var stored string
+		switch v := dbValue.(type) {
+		case []byte:
+			stored = string(v)
+		case string:
+			stored = v
+		default:
+			return fmt.Errorf("unsupported value %T for pii column %s", dbValue, field.DBName)
+		}
+
+		plaintext, err := decryptPii(stored)
+		if err != nil {
+			return errors.New("error while decrypting " + field.DBName + ": " + err.Error())
+		}
+		if field.FieldType.Kind() == reflect.Ptr {
+			fieldValue.Elem().Set(reflect.ValueOf(&plaintext))
+		} else {
+			fieldValue.Elem().SetString(plaintext)
+		}
+	}
+
+	field.ReflectValueOf(ctx, dst).Set(fieldValue.Elem())
+	return nil
+}
+
+func (piiSerializer) Value(ctx context.Context, field *schema.Field, dst reflect.Value, fieldValue interface{}) (interface{}, error) {
+	switch v := fieldValue.(type) {
+	case string:
+		return encryptPii(v)
+	case *string:
+		if v == nil {
+			return nil, nil
+		}
+		return encryptPii(*v)
+	default:
+		return nil, fmt.Errorf("unsupported type %T for pii column %s", fieldValue, field.DBName)
+	}
+}
+
+func encryptPii(plaintext string) (string, error) {
+	if piiKeyring == nil {
+		return plaintext, nil
+	}
+	return piiKeyring.Encrypt(plaintext)
+}
+
+func decryptPii(value string) (string, error) {
+	if piiKeyring == nil {
+		if crypto.IsPiiEncrypted(value) {
+			return "", ErrPiiKeyringNotConfigured
+		}
+		return value, nil
+	}
+	return piiKeyring.Decrypt(value)
+}
@@ -23,39 +23,29 @@ func GetPreferenceByAddress(userAddress string) (*model.Preference, error) {
 }
 
 func CreatePreference(pref *model.Preference) error {
-	db, err := GetDB()
-	if err != nil {
-		return err
-	}
-
-	txCreate := db.Create(&pref)
-	if txCreate.Error != nil {
-		txCreate.Rollback()
-		return txCreate.Error
-	}
-	if txCreate.RowsAffected == 0 {
-		txCreate.Rollback()
-		return gorm.ErrRecordNotFound
-	}
-
-	return nil
+	return Transaction(func(tx *gorm.DB) error {
+		txCreate := tx.Create(&pref)
+		if txCreate.Error != nil {
+			return txCreate.Error
+		}
+		if txCreate.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+
+		return nil
+	})
 }
 
 func UpdatePreference(pref *model.Preference) error {
-	db, err := GetDB()
-	if err != nil {
-		return err
-	}
-
-	txUpdate := db.Save(&pref)
-	if txUpdate.Error != nil {
-		txUpdate.Rollback()
-		return txUpdate.Error
-	}
-	if txUpdate.RowsAffected == 0 {
-		txUpdate.Rollback()
-		return gorm.ErrRecordNotFound
-	}
-
-	return nil
+	return Transaction(func(tx *gorm.DB) error {
+		txUpdate := tx.Save(&pref)
+		if txUpdate.Error != nil {
+			return txUpdate.Error
+		}
+		if txUpdate.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+
+		return nil
+	})
 }
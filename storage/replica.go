@@ -0,0 +1,110 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"sync/atomic"
+	"time"
+
+	"github.com/NaeuralEdgeProtocol/ratio1-backend/config"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+const (
+	defaultReplicaHealthCheckInterval = 10 * time.Second
+	replicaPingTimeout                = 2 * time.Second
+)
+
+type replica struct {
+	db      *gorm.DB
+	ping    func(ctx context.Context) error
+	healthy atomic.Bool
+}
+
+var (
+	replicas      []*replica
+	replicaCursor atomic.Uint64
+)
+
+// connectReplicas opens the configured read replicas. A replica that cannot be
+// reached is kept out of rotation until a health check succeeds, the primary
+// serves its reads meanwhile.
+func connectReplicas() {
+	for _, url := range config.Config.Database.ReplicaUrls {
+		if url == "" {
+			continue
+		}
+
+		sqlDb, err := sql.Open("postgres", url)
+		if err != nil {
+			panic(err)
+		}
+		sqlDb.SetMaxOpenConns(config.Config.Database.MaxOpenConns)
+		sqlDb.SetMaxIdleConns(config.Config.Database.MaxIdleConns)
+		conn, err := gorm.Open(postgres.New(postgres.Config{
+			Conn:       sqlDb,
+			DriverName: "postgres",
+		}), &gorm.Config{DisableAutomaticPing: true})
+		if err != nil {
+			panic(err)
+		}
+
+		replicas = append(replicas, &replica{db: conn, ping: sqlDb.PingContext})
+	}
+
+	if len(replicas) == 0 {
+		return
+	}
+
+	checkReplicas()
+	go func() {
+		ticker := time.NewTicker(replicaHealthCheckInterval())
+		defer ticker.Stop()
+		for range ticker.C {
+			checkReplicas()
+		}
+	}()
+}
+
+func checkReplicas() {
+	for _, r := range replicas {
+		ctx, cancel := context.WithTimeout(context.Background(), replicaPingTimeout)
+		r.healthy.Store(r.ping(ctx) == nil)
+		cancel()
+	}
+}
+
+func replicaHealthCheckInterval() time.Duration {
+	if config.Config.Database.ReplicaHealthCheckSeconds > 0 {
+		return time.Duration(config.Config.Database.ReplicaHealthCheckSeconds) * time.Second
+	}
+	return defaultReplicaHealthCheckInterval
+}
+
+// GetReadDB returns a healthy read replica, picked round robin, or the primary
+// when no replica is configured or healthy. Replicas lag behind the primary, so
+// it must only be used for reads that do not need to observe a write just made.
+func GetReadDB() (*gorm.DB, error) {
+	if db := pickReplica(); db != nil {
+		return db, nil
+	}
+
+	return GetDB()
+}
+
+func pickReplica() *gorm.DB {
+	count := uint64(len(replicas))
+	if count == 0 {
+		return nil
+	}
+
+	start := replicaCursor.Add(1)
+	for i := uint64(0); i < count; i++ {
+		r := replicas[(start+i)%count]
+		if r.healthy.Load() {
+			return r.db
+		}
+	}
+	return nil
+}
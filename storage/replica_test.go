@@ -0,0 +1,70 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+func withReplicas(t *testing.T, pings ...func(ctx context.Context) error) []*replica {
+	previous := replicas
+	t.Cleanup(func() { replicas = previous })
+
+	replicas = nil
+	for i, ping := range pings {
+		// RowsAffected only tells the fake connections apart.
+		replicas = append(replicas, &replica{db: &gorm.DB{RowsAffected: int64(i + 1)}, ping: ping})
+	}
+	return replicas
+}
+
+func pingOk(ctx context.Context) error { return nil }
+
+func pingFail(ctx context.Context) error { return errors.New("connection refused") }
+
+func TestPickReplicaWithoutReplicas(t *testing.T) {
+	withReplicas(t)
+	require.Nil(t, pickReplica())
+}
+
+func TestPickReplicaRoundRobinOnHealthyReplicas(t *testing.T) {
+	configured := withReplicas(t, pingOk, pingFail, pingOk)
+	checkReplicas()
+
+	require.True(t, configured[0].healthy.Load())
+	require.False(t, configured[1].healthy.Load())
+	require.True(t, configured[2].healthy.Load())
+
+	seen := make(map[*gorm.DB]int)
+	for i := 0; i < 10; i++ {
+		seen[pickReplica()]++
+	}
+	require.Len(t, seen, 2)
+	require.NotContains(t, seen, configured[1].db)
+	require.Contains(t, seen, configured[0].db)
+	require.Contains(t, seen, configured[2].db)
+}
+
+func TestPickReplicaFallsBackWhenAllUnhealthy(t *testing.T) {
+	healthy := true
+	configured := withReplicas(t, func(ctx context.Context) error {
+		if healthy {
+			return nil
+		}
+		return errors.New("timeout")
+	})
+
+	checkReplicas()
+	require.Equal(t, configured[0].db, pickReplica())
+
+	healthy = false
+	checkReplicas()
+	require.Nil(t, pickReplica())
+
+	healthy = true
+	checkReplicas()
+	require.Equal(t, configured[0].db, pickReplica())
+}
@@ -0,0 +1,277 @@
+package storage
+
+import (
+	"time"
+
+	"github.com/NaeuralEdgeProtocol/ratio1-backend/model"
+	"github.com/google/uuid"
+)
+
+type AccountRepository interface {
+	GetByAddress(address string) (*model.Account, bool, error)
+	GetByEmail(email string) (*model.Account, bool, error)
+	Create(account *model.Account) error
+	Update(account *model.Account) error
+	GetBySellerCode(sellerCode string) (*[]model.Account, error)
+}
+
+type NotificationEmailRepository interface {
+	GetByAddress(address string) (*model.AccountNotificationEmail, bool, error)
+	CreateOrUpdate(notificationEmail *model.AccountNotificationEmail) error
+	Delete(address string) error
+}
+
+type KycRepository interface {
+	GetByEmail(email string) (*model.Kyc, bool, error)
+	GetByApplicantID(applicantId string) (*model.Kyc, bool, error)
+	GetByUuid(uuid uuid.UUID) (*model.Kyc, bool, error)
+	CreateOrUpdate(kyc *model.Kyc) error
+	Update(kyc *model.Kyc) error
+	GetAllUsersEmails() ([]string, error)
+}
+
+type UserInfoRepository interface {
+	Create(userInfo *model.UserInfo) error
+	Update(userInfo *model.UserInfo) error
+	CreateOrUpdate(userInfo *model.UserInfo) error
+	GetByAddress(address string) (*model.UserInfo, error)
+	GetVersions(address string) ([]model.UserInfoVersion, error)
+	DeleteVersions(address string) error
+	GetCompanies() ([]model.UserInfo, error)
+}
+
+type InvoiceRepository interface {
+	GetLatestBlock() (*int64, bool, error)
+	GetByID(id string) (*model.InvoiceClient, bool, error)
+	Create(invoice *model.InvoiceClient) error
+	Update(invoice *model.InvoiceClient) error
+	GetUserInvoices(address string) (*[]model.InvoiceClient, error)
+	GetByAddress(address string) ([]model.InvoiceClient, error)
+	// GetPaid returns the invoices paid in [from, to), with the ones
+	// cancelled or refunded since.
+	GetPaid(from, to time.Time) ([]model.InvoiceClient, error)
+	// GetUndatedPayments returns the invoices of a mined purchase stored
+	// before their payment date was kept.
+	GetUndatedPayments() ([]model.InvoiceClient, error)
+	AddStatusChange(change *model.InvoiceStatusChange) error
+	GetStatusChanges(invoiceUuid string) ([]model.InvoiceStatusChange, error)
+	// GetCreditNotes returns the credit notes issued in [from, to).
+	GetCreditNotes(from, to time.Time) ([]model.InvoiceStatusChange, error)
+}
+
+type AllocationRepository interface {
+	GetLatestBlock() (int64, error)
+	Create(alloc *model.Allocation) error
+	Update(alloc *model.Allocation) error
+	GetByCspAndUser(cspAddress, userAddress, nodeAddress string) ([]model.Allocation, error)
+	GetMonthlyUnclaimed(now time.Time) ([]model.Allocation, error)
+	GetByDraftId(draftId string) ([]model.Allocation, error)
+	GetByJobIDsForJobDetails(jobIDs []string) (map[string]*model.Allocation, error)
+	GetWithMissingDraft() ([]model.Allocation, error)
+	ReleaseFromDraft(ids []uint) error
+	GetByAddress(address string) ([]model.Allocation, error)
+	GetInBlockRange(from, to int64) ([]model.Allocation, error)
+	DeleteUnclaimedFromBlock(block int64) error
+}
+
+type DraftRepository interface {
+	GetListByNodeOwner(userAddress string) ([]model.InvoiceDraft, error)
+	GetListByCSP(userAddress string) ([]model.InvoiceDraft, error)
+	GetByReportId(id, userAddress string) (*model.InvoiceDraft, error)
+	GetCspByReportId(id, userAddress string) (*model.InvoiceDraft, error)
+	Create(draft *model.InvoiceDraft) error
+	Update(draft *model.InvoiceDraft) error
+	Generate(fn func(tx DraftGenerationTx) error) error
+	Transition(draftId uuid.UUID, from []string, transition *model.InvoiceDraftTransition) error
+	GetTransitions(draftId uuid.UUID) ([]model.InvoiceDraftTransition, error)
+}
+
+// DraftGenerationTx holds the writes needed to generate a single invoice
+// draft. They are committed together when the Generate callback succeeds.
+type DraftGenerationTx interface {
+	GetPreferenceForUpdate(userAddress string) (*model.Preference, error)
+	SavePreference(pref *model.Preference) error
+	// GetSeriesForUpdate returns nil when the user has no series with that name.
+	GetSeriesForUpdate(userAddress, name string) (*model.InvoiceSeries, error)
+	SaveSeries(series *model.InvoiceSeries) error
+	ClaimAllocations(draftId uuid.UUID, allocationIds []uint) error
+	CreateDraft(draft *model.InvoiceDraft) error
+	// MoveAllocations re-points allocations of a draft to its new version.
+	MoveAllocations(fromDraftId, toDraftId uuid.UUID, allocationIds []uint) error
+	// SupersedeDraft marks a draft not finalized yet as replaced by a new
+	// version and records the transition.
+	SupersedeDraft(draftId, supersededBy uuid.UUID, transition *model.InvoiceDraftTransition) error
+}
+
+type InvoiceSeriesRepository interface {
+	GetByUser(userAddress string) ([]model.InvoiceSeries, error)
+	// Get returns nil when the user has no series with that name.
+	Get(userAddress, name string) (*model.InvoiceSeries, error)
+	Create(series *model.InvoiceSeries) error
+	Change(userAddress, name string, fn func(series *model.InvoiceSeries) error) error
+}
+
+type PreferenceRepository interface {
+	GetByAddress(userAddress string) (*model.Preference, error)
+	Create(pref *model.Preference) error
+	Update(pref *model.Preference) error
+}
+
+type BurnEventRepository interface {
+	Create(burnEvent *model.BurnEvent) error
+	GetByOwnerAddress(userAddress string) ([]model.BurnEvent, error)
+	GetForUserInTimeRange(start, end time.Time, userAddress string) ([]model.BurnEvent, error)
+	GetInBlockRange(from, to int64) ([]model.BurnEvent, error)
+	DeleteFromBlock(block int64) error
+}
+
+type StatsRepository interface {
+	Create(stats *model.Stats) error
+	Update(stats *model.Stats) error
+	GetLatest() (*model.Stats, error)
+	GetAllASC() (*[]model.Stats, error)
+}
+
+type SellerRepository interface {
+	Create(seller *model.Seller) error
+	GetCodeByAddress(address string) (*string, error)
+	GetByAddress(address string) (*model.Seller, error)
+	AddressHasCode(accountID string) (bool, error)
+	CodeExists(sellerCode string) (bool, error)
+	GetAll() ([]model.Seller, error)
+	GetByCode(sellerCode string) (*model.Seller, error)
+	Update(seller *model.Seller) error
+}
+
+type BrandingRepository interface {
+	Save(brand *model.Branding) error
+	GetByAddress(address string) (*model.Branding, error)
+}
+
+type AccountExportRepository interface {
+	Create(export *model.AccountExport) error
+	Update(export *model.AccountExport) error
+	GetById(id uuid.UUID) (*model.AccountExport, error)
+	GetLatestByAddress(address string) (*model.AccountExport, error)
+	DeleteByAddress(address string) error
+	DeleteExpired(now time.Time) error
+}
+
+type AccountErasureRepository interface {
+	Create(request *model.AccountErasureRequest) error
+	Update(request *model.AccountErasureRequest) error
+	GetById(id uuid.UUID) (*model.AccountErasureRequest, error)
+	GetOpenByAddress(address string) (*model.AccountErasureRequest, error)
+	GetByStatus(status string) ([]model.AccountErasureRequest, error)
+}
+
+type AuditLogRepository interface {
+	Create(entry *model.AuditLog) error
+	GetBySubject(subject string) ([]model.AuditLog, error)
+}
+
+type ChainIndexRepository interface {
+	GetCheckpoint(stream string) (*model.IndexerCheckpoint, error)
+	GetBlocks(stream string) ([]model.IndexedBlock, error)
+	Advance(stream string, previous *int64, block model.IndexedBlock, keep int) error
+	Rewind(stream string, block model.IndexedBlock) error
+}
+
+type BlockRepository interface {
+	Save(blocks ...model.Block) error
+	Get(number int64) (*model.Block, error)
+	GetAtOrBefore(timestamp time.Time) (*model.Block, error)
+	GetAfter(timestamp time.Time) (*model.Block, error)
+	DeleteFrom(number int64) error
+}
+
+type FxRateRepository interface {
+	Save(rates ...model.FxRate) error
+	// GetOnOrBefore returns nil when no rate of the currency was published
+	// on or before date.
+	GetOnOrBefore(currency string, date time.Time) (*model.FxRate, error)
+}
+
+type VatRateRepository interface {
+	GetAll() ([]model.VatRate, error)
+	Save(rate *model.VatRate) error
+}
+
+type ViesCheckRepository interface {
+	Create(check *model.ViesCheck) error
+	// GetLatest returns nil when the VAT number was never checked.
+	GetLatest(vatNumber string) (*model.ViesCheck, error)
+	GetAll(vatNumber string) ([]model.ViesCheck, error)
+}
+
+type LicensePurchaseRepository interface {
+	Create(purchase *model.LicensePurchaseEvent) error
+	Get(id uint) (*model.LicensePurchaseEvent, error)
+	GetByInvoiceID(invoiceID string) (*model.LicensePurchaseEvent, error)
+	GetByStatus(statuses ...string) ([]model.LicensePurchaseEvent, error)
+	GetDue(now time.Time) ([]model.LicensePurchaseEvent, error)
+	Update(purchase *model.LicensePurchaseEvent) error
+	DeleteUninvoicedFromBlock(block int64) error
+}
+
+type TokenTransferRepository interface {
+	Create(transfer *model.TokenTransfer) error
+	GetInBlockRange(from, to int64) ([]model.TokenTransfer, error)
+	DeleteFromBlock(block int64) error
+}
+
+// Repositories groups every aggregate repository so that services and
+// handlers can be wired against either the database or an in-memory store.
+type Repositories struct {
+	Accounts           AccountRepository
+	NotificationEmails NotificationEmailRepository
+	Kycs               KycRepository
+	UserInfos          UserInfoRepository
+	Invoices           InvoiceRepository
+	Allocations        AllocationRepository
+	Drafts             DraftRepository
+	Preferences        PreferenceRepository
+	InvoiceSeries      InvoiceSeriesRepository
+	BurnEvents         BurnEventRepository
+	Stats              StatsRepository
+	Sellers            SellerRepository
+	Brandings          BrandingRepository
+	AccountExports     AccountExportRepository
+	AccountErasures    AccountErasureRepository
+	AuditLogs          AuditLogRepository
+	ChainIndex         ChainIndexRepository
+	Blocks             BlockRepository
+	FxRates            FxRateRepository
+	VatRates           VatRateRepository
+	ViesChecks         ViesCheckRepository
+	LicensePurchases   LicensePurchaseRepository
+	TokenTransfers     TokenTransferRepository
+}
+
+func NewGormRepositories() *Repositories {
+	return &Repositories{
+		Accounts:           gormAccountRepository{},
+		NotificationEmails: gormNotificationEmailRepository{},
+		Kycs:               gormKycRepository{},
+		UserInfos:          gormUserInfoRepository{},
+		Invoices:           gormInvoiceRepository{},
+		Allocations:        gormAllocationRepository{},
+		Drafts:             gormDraftRepository{},
+		Preferences:        gormPreferenceRepository{},
+		InvoiceSeries:      gormInvoiceSeriesRepository{},
+		BurnEvents:         gormBurnEventRepository{},
+		Stats:              gormStatsRepository{},
+		Sellers:            gormSellerRepository{},
+		Brandings:          gormBrandingRepository{},
+		AccountExports:     gormAccountExportRepository{},
+		AccountErasures:    gormAccountErasureRepository{},
+		AuditLogs:          gormAuditLogRepository{},
+		ChainIndex:         gormChainIndexRepository{},
+		Blocks:             gormBlockRepository{},
+		FxRates:            gormFxRateRepository{},
+		VatRates:           gormVatRateRepository{},
+		ViesChecks:         gormViesCheckRepository{},
+		LicensePurchases:   gormLicensePurchaseRepository{},
+		TokenTransfers:     gormTokenTransferRepository{},
+	}
+}
@@ -6,22 +6,17 @@ import (
 )
 
 func CreateSeller(seller *model.Seller) error {
-	db, err := GetDB()
-	if err != nil {
-		return err
-	}
-
-	txCreate := db.Create(&seller)
-	if txCreate.Error != nil {
-		txCreate.Rollback()
-		return txCreate.Error
-	}
-	if txCreate.RowsAffected == 0 {
-		txCreate.Rollback()
-		return gorm.ErrRecordNotFound
-	}
-
-	return nil
+	return Transaction(func(tx *gorm.DB) error {
+		txCreate := tx.Create(&seller)
+		if txCreate.Error != nil {
+			return txCreate.Error
+		}
+		if txCreate.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+
+		return nil
+	})
 }
 
 func GetSellerCodeByAddress(address string) (*string, error) {
@@ -127,20 +122,15 @@ func GetSellerByCode(sellerCode string) (*model.Seller, error) {
 }
 
 func UpdateSeller(sel *model.Seller) error {
-	db, err := GetDB()
-	if err != nil {
-		return err
-	}
-
-	txUpdate := db.Save(sel)
-	if txUpdate.Error != nil {
-		txUpdate.Rollback()
-		return txUpdate.Error
-	}
-	if txUpdate.RowsAffected == 0 {
-		txUpdate.Rollback()
-		return gorm.ErrRecordNotFound
-	}
-
-	return nil
+	return Transaction(func(tx *gorm.DB) error {
+		txUpdate := tx.Save(sel)
+		if txUpdate.Error != nil {
+			return txUpdate.Error
+		}
+		if txUpdate.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+
+		return nil
+	})
 }
@@ -10,72 +10,66 @@ import (
 )
 
 func CreateStats(stats *model.Stats) error {
-	db, err := GetDB()
-	if err != nil {
-		return err
-	}
-
-	row := map[string]any{
-		"creation_timestamp":           stats.CreationTimestamp,
-		"daily_active_jobs":            stats.DailyActiveJobs,
-		"daily_usdc_locked":            toNumericExpr(stats.DailyUsdcLocked),
-		"daily_token_burn":             toNumericExpr(stats.DailyTokenBurn),
-		"total_token_burn":             toNumericExpr(stats.TotalTokenBurn),
-		"daily_nd_contract_token_burn": toNumericExpr(stats.DailyNdContractTokenBurn),
-		"total_nd_contract_token_burn": toNumericExpr(stats.TotalNdContractTokenBurn),
-		"daily_poai_rewards":           toNumericExpr(stats.DailyPOAIRewards),
-		"total_poai_rewards":           toNumericExpr(stats.TotalPOAIRewards),
-		"daily_minted":                 toNumericExpr(stats.DailyMinted),
-		"total_minted":                 toNumericExpr(stats.TotalMinted),
-		"total_supply":                 toNumericExpr(stats.TotalSupply),
-		"team_wallets_supply":          toNumericExpr(stats.TeamWalletsSupply),
-		"last_block_number":            stats.LastBlockNumber, // bigint
-		"daily_poai_token_burn":        toNumericExpr(stats.DailyPoaiTokenBurn),
-		"total_poai_token_burn":        toNumericExpr(stats.TotalPoaiTokenBurn),
-	}
+	return Transaction(func(tx *gorm.DB) error {
+		row := map[string]any{
+			"creation_timestamp":           stats.CreationTimestamp,
+			"daily_active_jobs":            stats.DailyActiveJobs,
+			"daily_usdc_locked":            toNumericExpr(stats.DailyUsdcLocked),
+			"daily_token_burn":             toNumericExpr(stats.DailyTokenBurn),
+			"total_token_burn":             toNumericExpr(stats.TotalTokenBurn),
+			"daily_nd_contract_token_burn": toNumericExpr(stats.DailyNdContractTokenBurn),
+			"total_nd_contract_token_burn": toNumericExpr(stats.TotalNdContractTokenBurn),
+			"daily_poai_rewards":           toNumericExpr(stats.DailyPOAIRewards),
+			"total_poai_rewards":           toNumericExpr(stats.TotalPOAIRewards),
+			"daily_minted":                 toNumericExpr(stats.DailyMinted),
+			"total_minted":                 toNumericExpr(stats.TotalMinted),
+			"total_supply":                 toNumericExpr(stats.TotalSupply),
+			"team_wallets_supply":          toNumericExpr(stats.TeamWalletsSupply),
+			"last_block_number":            stats.LastBlockNumber, // bigint
+			"daily_poai_token_burn":        toNumericExpr(stats.DailyPoaiTokenBurn),
+			"total_poai_token_burn":        toNumericExpr(stats.TotalPoaiTokenBurn),
+		}
 
-	res := db.Table("stats").Create(row)
-	if res.Error != nil {
-		return res.Error
-	}
-	if res.RowsAffected != 1 {
-		return errors.New("no row inserted")
-	}
-	return nil
+		res := tx.Table("stats").Create(row)
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected != 1 {
+			return errors.New("no row inserted")
+		}
+		return nil
+	})
 }
 
 func UpdateStats(stats *model.Stats) error {
-	db, err := GetDB()
-	if err != nil {
-		return err
-	}
-
-	row := map[string]any{
-		"daily_active_jobs":            stats.DailyActiveJobs,
-		"daily_usdc_locked":            toNumericExpr(stats.DailyUsdcLocked),
-		"daily_token_burn":             toNumericExpr(stats.DailyTokenBurn),
-		"total_token_burn":             toNumericExpr(stats.TotalTokenBurn),
-		"daily_nd_contract_token_burn": toNumericExpr(stats.DailyNdContractTokenBurn),
-		"total_nd_contract_token_burn": toNumericExpr(stats.TotalNdContractTokenBurn),
-		"daily_poai_rewards":           toNumericExpr(stats.DailyPOAIRewards),
-		"total_poai_rewards":           toNumericExpr(stats.TotalPOAIRewards),
-		"daily_minted":                 toNumericExpr(stats.DailyMinted),
-		"total_minted":                 toNumericExpr(stats.TotalMinted),
-		"total_supply":                 toNumericExpr(stats.TotalSupply),
-		"team_wallets_supply":          toNumericExpr(stats.TeamWalletsSupply),
-		"last_block_number":            stats.LastBlockNumber, // bigint
-		"daily_poai_token_burn":        toNumericExpr(stats.DailyPoaiTokenBurn),
-		"total_poai_token_burn":        toNumericExpr(stats.TotalPoaiTokenBurn),
-	}
+	return Transaction(func(tx *gorm.DB) error {
+		row := map[string]any{
+			"daily_active_jobs":            stats.DailyActiveJobs,
+			"daily_usdc_locked":            toNumericExpr(stats.DailyUsdcLocked),
+			"daily_token_burn":             toNumericExpr(stats.DailyTokenBurn),
+			"total_token_burn":             toNumericExpr(stats.TotalTokenBurn),
+			"daily_nd_contract_token_burn": toNumericExpr(stats.DailyNdContractTokenBurn),
+			"total_nd_contract_token_burn": toNumericExpr(stats.TotalNdContractTokenBurn),
+			"daily_poai_rewards":           toNumericExpr(stats.DailyPOAIRewards),
+			"total_poai_rewards":           toNumericExpr(stats.TotalPOAIRewards),
+			"daily_minted":                 toNumericExpr(stats.DailyMinted),
+			"total_minted":                 toNumericExpr(stats.TotalMinted),
+			"total_supply":                 toNumericExpr(stats.TotalSupply),
+			"team_wallets_supply":          toNumericExpr(stats.TeamWalletsSupply),
+			"last_block_number":            stats.LastBlockNumber, // bigint
+			"daily_poai_token_burn":        toNumericExpr(stats.DailyPoaiTokenBurn),
+			"total_poai_token_burn":        toNumericExpr(stats.TotalPoaiTokenBurn),
+		}
 
-	res := db.Where("creation_timestamp = ?", stats.CreationTimestamp).Table("stats").Updates(row)
-	if res.Error != nil {
-		return res.Error
-	}
-	if res.RowsAffected != 1 {
-		return errors.New("no row inserted")
-	}
-	return nil
+		res := tx.Where("creation_timestamp = ?", stats.CreationTimestamp).Table("stats").Updates(row)
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected != 1 {
+			return errors.New("no row inserted")
+		}
+		return nil
+	})
 }
 
 func GetLatestStats() (*model.Stats, error) {
@@ -120,7 +114,7 @@ func GetLatestStats() (*model.Stats, error) {
 }
 
 func GetAllStatsASC() (*[]model.Stats, error) {
-	db, err := GetDB()
+	db, err := GetReadDB()
 	if err != nil {
 		return nil, err
 	}
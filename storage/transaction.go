@@ -0,0 +1,119 @@
+package storage
+
+import (
+	"errors"
+	"math/rand"
+	"time"
+
+	"github.com/NaeuralEdgeProtocol/ratio1-backend/config"
+	"github.com/lib/pq"
+	"gorm.io/gorm"
+)
+
+const (
+	serializationFailureCode  = "40001"
+	cockroachRestartSavepoint = "cockroach_restart"
+	defaultMaxTxRetries       = 5
+)
+
+var (
+	txRetryBaseDelay = 20 * time.Millisecond
+	txRetryMaxDelay  = 2 * time.Second
+	txRetrySleep     = time.Sleep
+)
+
+// Transaction runs fn inside a database transaction, the transaction is
+// committed when fn returns nil and rolled back otherwise.
+//
+// Serialization failures (SQLSTATE 40001) are retried with backoff. On
+// CockroachDB this follows the cockroach_restart savepoint protocol, on
+// Postgres the whole transaction runs again. fn can therefore be called more
+// than once and must not keep state between attempts.
+func Transaction(fn func(tx *gorm.DB) error) error {
+	db, err := GetDB()
+	if err != nil {
+		return err
+	}
+
+	if config.Config.Database.IsCockroachDB() {
+		return db.Transaction(func(tx *gorm.DB) error {
+			exec := func(stmt string) error {
+				return tx.Exec(stmt).Error
+			}
+			return retryWithSavepoint(exec, func() error {
+				return fn(tx)
+			})
+		})
+	}
+
+	return retryTransaction(func() error {
+		return db.Transaction(fn)
+	})
+}
+
+// IsRetryableTxError reports whether err is a serialization failure that the
+// client is expected to retry.
+func IsRetryableTxError(err error) bool {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		return pqErr.Code == serializationFailureCode
+	}
+	return false
+}
+
+func retryTransaction(run func() error) error {
+	for attempt := 0; ; attempt++ {
+		err := run()
+		if err == nil || !IsRetryableTxError(err) || attempt >= maxTxRetries() {
+			return err
+		}
+		txRetrySleep(txRetryBackoff(attempt))
+	}
+}
+
+// retryWithSavepoint implements the CockroachDB client side retry protocol on
+// an already open transaction: the work runs after SAVEPOINT cockroach_restart
+// and is rolled back to it on a retryable error, RELEASE commits the attempt.
+func retryWithSavepoint(exec func(stmt string) error, fn func() error) error {
+	err := exec("SAVEPOINT " + cockroachRestartSavepoint)
+	if err != nil {
+		return err
+	}
+
+	for attempt := 0; ; attempt++ {
+		err = fn()
+		if err == nil {
+			err = exec("RELEASE SAVEPOINT " + cockroachRestartSavepoint)
+			if err == nil {
+				return nil
+			}
+		}
+		if !IsRetryableTxError(err) || attempt >= maxTxRetries() {
+			return err
+		}
+
+		rollbackErr := exec("ROLLBACK TO SAVEPOINT " + cockroachRestartSavepoint)
+		if rollbackErr != nil {
+			return errors.New("error while rolling back to savepoint: " + rollbackErr.Error())
+		}
+		txRetrySleep(txRetryBackoff(attempt))
+	}
+}
+
+func maxTxRetries() int {
+	if config.Config.Database.MaxTxRetries > 0 {
+		return config.Config.Database.MaxTxRetries
+	}
+	return defaultMaxTxRetries
+}
+
+// txRetryBackoff doubles the delay on every attempt up to txRetryMaxDelay, half
+// of it is randomized so that conflicting writers do not retry in lockstep.
+func txRetryBackoff(attempt int) time.Duration {
+	delay := txRetryMaxDelay
+	if attempt < 16 {
+		delay = min(txRetryBaseDelay<<attempt, txRetryMaxDelay)
+	}
+	half := delay / 2
+	return half + time.Duration(rand.Int63n(int64(half)+1))
+}
@@ -0,0 +1,157 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/NaeuralEdgeProtocol/ratio1-backend/config"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/require"
+)
+
+var errSerialization = &pq.Error{Code: serializationFailureCode, Message: "restart transaction"}
+
+func withoutRetrySleep(t *testing.T) *[]time.Duration {
+	previous := txRetrySleep
+	var delays []time.Duration
+	txRetrySleep = func(d time.Duration) { delays = append(delays, d) }
+	t.Cleanup(func() { txRetrySleep = previous })
+	return &delays
+}
+
+func TestIsRetryableTxError(t *testing.T) {
+	require.True(t, IsRetryableTxError(errSerialization))
+	require.True(t, IsRetryableTxError(fmt.Errorf("commit: %w", errSerialization)))
+	require.False(t, IsRetryableTxError(&pq.Error{Code: "23505"}))
+	require.False(t, IsRetryableTxError(errors.New("restart transaction")))
+	require.False(t, IsRetryableTxError(nil))
+}
+
+func TestRetryTransactionRetriesSerializationFailures(t *testing.T) {
+	delays := withoutRetrySleep(t)
+
+	attempts := 0
+	err := retryTransaction(func() error {
+		attempts++
+		if attempts < 3 {
+			return errSerialization
+		}
+		return nil
+	})
+	require.NoError(t, err)
+	require.Equal(t, 3, attempts)
+	require.Len(t, *delays, 2)
+}
+
+func TestRetryTransactionStopsOnOtherErrors(t *testing.T) {
+	withoutRetrySleep(t)
+
+	attempts := 0
+	expected := errors.New("duplicate key")
+	err := retryTransaction(func() error {
+		attempts++
+		return expected
+	})
+	require.Equal(t, expected, err)
+	require.Equal(t, 1, attempts)
+}
+
+func TestRetryTransactionGivesUpAfterMaxRetries(t *testing.T) {
+	withoutRetrySleep(t)
+	previous := config.Config.Database.MaxTxRetries
+	config.Config.Database.MaxTxRetries = 2
+	defer func() { config.Config.Database.MaxTxRetries = previous }()
+
+	attempts := 0
+	err := retryTransaction(func() error {
+		attempts++
+		return errSerialization
+	})
+	require.ErrorIs(t, err, errSerialization)
+	require.Equal(t, 3, attempts)
+}
+
+func TestRetryWithSavepointProtocol(t *testing.T) {
+	withoutRetrySleep(t)
+
+	var statements []string
+	exec := func(stmt string) error {
+		statements = append(statements, stmt)
+		return nil
+	}
+
+	attempts := 0
+	err := retryWithSavepoint(exec, func() error {
+		attempts++
+		if attempts == 1 {
+			return errSerialization
+		}
+		return nil
+	})
+	require.NoError(t, err)
+	require.Equal(t, 2, attempts)
+	require.Equal(t, []string{
+		"SAVEPOINT cockroach_restart",
+		"ROLLBACK TO SAVEPOINT cockroach_restart",
+		"RELEASE SAVEPOINT cockroach_restart",
+	}, statements)
+}
+
+func TestRetryWithSavepointRetriesFailedRelease(t *testing.T) {
+	withoutRetrySleep(t)
+
+	var statements []string
+	releases := 0
+	exec := func(stmt string) error {
+		statements = append(statements, stmt)
+		if stmt == "RELEASE SAVEPOINT cockroach_restart" {
+			releases++
+			if releases == 1 {
+				return errSerialization
+			}
+		}
+		return nil
+	}
+
+	attempts := 0
+	err := retryWithSavepoint(exec, func() error {
+		attempts++
+		return nil
+	})
+	require.NoError(t, err)
+	require.Equal(t, 2, attempts)
+	require.Equal(t, []string{
+		"SAVEPOINT cockroach_restart",
+		"RELEASE SAVEPOINT cockroach_restart",
+		"ROLLBACK TO SAVEPOINT cockroach_restart",
+		"RELEASE SAVEPOINT cockroach_restart",
+	}, statements)
+}
+
+func TestRetryWithSavepointDoesNotRetryOtherErrors(t *testing.T) {
+	withoutRetrySleep(t)
+
+	var statements []string
+	exec := func(stmt string) error {
+		statements = append(statements, stmt)
+		return nil
+	}
+
+	expected := errors.New("constraint violation")
+	err := retryWithSavepoint(exec, func() error {
+		return expected
+	})
+	require.Equal(t, expected, err)
+	require.Equal(t, []string{"SAVEPOINT cockroach_restart"}, statements)
+}
+
+func TestTxRetryBackoffIsBounded(t *testing.T) {
+	for attempt := 0; attempt < 40; attempt++ {
+		delay := txRetryBackoff(attempt)
+		require.Greater(t, delay, time.Duration(0))
+		require.LessOrEqual(t, delay, txRetryMaxDelay)
+	}
+	require.GreaterOrEqual(t, txRetryBackoff(30), txRetryMaxDelay/2)
+}
@@ -1,96 +1,149 @@
 package storage
 
 import (
+	"time"
+
 	"github.com/NaeuralEdgeProtocol/ratio1-backend/model"
 	"gorm.io/gorm"
 	"gorm.io/gorm/clause"
 )
 
 func CreateUserInfo(userInfo *model.UserInfo) error {
+	return Transaction(func(tx *gorm.DB) error {
+		txCreate := tx.Create(&userInfo)
+		if txCreate.Error != nil {
+			return txCreate.Error
+		}
+		if txCreate.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+
+		return recordUserInfoVersion(tx, userInfo)
+	})
+}
+
+func UpdateUserInfo(userInfo *model.UserInfo) error {
+	return Transaction(func(tx *gorm.DB) error {
+		txUpdate := tx.Save(&userInfo)
+		if txUpdate.Error != nil {
+			return txUpdate.Error
+		}
+		if txUpdate.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+
+		return recordUserInfoVersion(tx, userInfo)
+	})
+}
+
+func CreateOrUpdateUserInfo(userInfo *model.UserInfo) error {
+	return Transaction(func(tx *gorm.DB) error {
+		txUpdate := tx.Clauses(clause.OnConflict{
+			Columns: []clause.Column{{Name: "blockchain_address"}},
+			DoUpdates: clause.AssignmentColumns([]string{
+				"email",
+				"name",
+				"surname",
+				"company_name",
+				"identification_code",
+				"address",
+				"state",
+				"city",
+				"country",
+				"is_company",
+			}),
+		}).Create(userInfo)
+		if txUpdate.Error != nil {
+			return txUpdate.Error
+		}
+		if txUpdate.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+
+		return recordUserInfoVersion(tx, userInfo)
+	})
+}
+
+func GetUserInfoByAddress(address string) (*model.UserInfo, error) {
 	db, err := GetDB()
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	txCreate := db.Create(&userInfo)
-	if txCreate.Error != nil {
-		txCreate.Rollback()
-		return txCreate.Error
+	var userInfo model.UserInfo
+	txRead := db.Find(&userInfo, "blockchain_address = ?", address)
+	if txRead.Error != nil {
+		return nil, txRead.Error
 	}
-	if txCreate.RowsAffected == 0 {
-		txCreate.Rollback()
-		return gorm.ErrRecordNotFound
+	if txRead.RowsAffected == 0 {
+		return nil, nil
 	}
 
-	return nil
+	return &userInfo, nil
 }
 
-func UpdateUserInfo(userInfo *model.UserInfo) error {
-	db, err := GetDB()
+func GetCompanyUserInfos() ([]model.UserInfo, error) {
+	db, err := GetReadDB()
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	txUpdate := db.Save(&userInfo)
-	if txUpdate.Error != nil {
-		txUpdate.Rollback()
-		return txUpdate.Error
-	}
-	if txUpdate.RowsAffected == 0 {
-		txUpdate.Rollback()
-		return gorm.ErrRecordNotFound
+	var userInfos []model.UserInfo
+	txRead := db.Where("is_company = ?", true).Order("blockchain_address").Find(&userInfos)
+	if txRead.Error != nil {
+		return nil, txRead.Error
 	}
 
-	return nil
+	return userInfos, nil
 }
 
-func CreateOrUpdateUserInfo(userInfo *model.UserInfo) error {
-	db, err := GetDB()
+// recordUserInfoVersion appends the profile just written to the history and
+// stores its version on the profile. It runs in the transaction of the write,
+// which holds the row lock that serializes concurrent versions.
+func recordUserInfoVersion(tx *gorm.DB, userInfo *model.UserInfo) error {
+	var latest int
+	err := tx.Model(&model.UserInfoVersion{}).
+		Where("blockchain_address = ?", userInfo.BlockchainAddress).
+		Select("COALESCE(MAX(version), 0)").
+		Scan(&latest).Error
 	if err != nil {
 		return err
 	}
 
-	txUpdate := db.Clauses(clause.OnConflict{
-		Columns: []clause.Column{{Name: "blockchain_address"}},
-		DoUpdates: clause.AssignmentColumns([]string{
-			"email",
-			"name",
-			"surname",
-			"company_name",
-			"identification_code",
-			"address",
-			"state",
-			"city",
-			"country",
-			"is_company",
-		}),
-	}).Create(userInfo)
-	if txUpdate.Error != nil {
-		txUpdate.Rollback()
-		return txUpdate.Error
-	}
-	if txUpdate.RowsAffected == 0 {
-		txUpdate.Rollback()
-		return gorm.ErrRecordNotFound
+	userInfo.Version = latest + 1
+	err = tx.Model(&model.UserInfo{}).
+		Where("blockchain_address = ?", userInfo.BlockchainAddress).
+		Update("version", userInfo.Version).Error
+	if err != nil {
+		return err
 	}
 
-	return nil
+	return tx.Create(&model.UserInfoVersion{
+		BlockchainAddress: userInfo.BlockchainAddress,
+		Version:           userInfo.Version,
+		Email:             userInfo.Email,
+		CreatedAt:         time.Now(),
+		BillingIdentity:   userInfo.Identity(),
+	}).Error
 }
 
-func GetUserInfoByAddress(address string) (*model.UserInfo, error) {
+func GetUserInfoVersions(address string) ([]model.UserInfoVersion, error) {
 	db, err := GetDB()
 	if err != nil {
 		return nil, err
 	}
 
-	var userInfo model.UserInfo
-	txRead := db.Find(&userInfo, "blockchain_address = ?", address)
+	var versions []model.UserInfoVersion
+	txRead := db.Where("blockchain_address = ?", address).Order("version").Find(&versions)
 	if txRead.Error != nil {
 		return nil, txRead.Error
 	}
-	if txRead.RowsAffected == 0 {
-		return nil, nil
-	}
 
-	return &userInfo, nil
+	return versions, nil
+}
+
+func DeleteUserInfoVersions(address string) error {
+	return Transaction(func(tx *gorm.DB) error {
+		return tx.Where("blockchain_address = ?", address).Delete(&model.UserInfoVersion{}).Error
+	})
 }
@@ -0,0 +1,33 @@
+package storage
+
+import (
+	"github.com/NaeuralEdgeProtocol/ratio1-backend/model"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+func GetVatRates() ([]model.VatRate, error) {
+	db, err := GetReadDB()
+	if err != nil {
+		return nil, err
+	}
+
+	var rates []model.VatRate
+	txRead := db.Order("country ASC, effective_from ASC").Find(&rates)
+	if txRead.Error != nil {
+		return nil, txRead.Error
+	}
+
+	return rates, nil
+}
+
+// SaveVatRate stores a version of the rate of a country, saving again the
+// version of the same day replaces it.
+func SaveVatRate(rate *model.VatRate) error {
+	return Transaction(func(tx *gorm.DB) error {
+		return tx.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "country"}, {Name: "effective_from"}},
+			DoUpdates: clause.AssignmentColumns([]string{"rate", "created_by"}),
+		}).Create(rate).Error
+	})
+}
@@ -0,0 +1,46 @@
+package storage
+
+import (
+	"github.com/NaeuralEdgeProtocol/ratio1-backend/model"
+	"gorm.io/gorm"
+)
+
+func CreateViesCheck(check *model.ViesCheck) error {
+	return Transaction(func(tx *gorm.DB) error {
+		return tx.Create(check).Error
+	})
+}
+
+// GetLatestViesCheck returns nil when the VAT number was never checked.
+func GetLatestViesCheck(vatNumber string) (*model.ViesCheck, error) {
+	db, err := GetDB()
+	if err != nil {
+		return nil, err
+	}
+
+	var check model.ViesCheck
+	txRead := db.Where("vat_number = ?", vatNumber).Order("checked_at DESC, id DESC").Limit(1).Find(&check)
+	if txRead.Error != nil {
+		return nil, txRead.Error
+	}
+	if txRead.RowsAffected == 0 {
+		return nil, nil
+	}
+
+	return &check, nil
+}
+
+func GetViesChecks(vatNumber string) ([]model.ViesCheck, error) {
+	db, err := GetReadDB()
+	if err != nil {
+		return nil, err
+	}
+
+	var checks []model.ViesCheck
+	txRead := db.Where("vat_number = ?", vatNumber).Order("checked_at ASC, id ASC").Find(&checks)
+	if txRead.Error != nil {
+		return nil, txRead.Error
+	}
+
+	return checks, nil
+}
@@ -16,6 +16,8 @@ var (
 	accountResettedEmailTemplate  *template.Template
 	jobsEndingEmailTemplate       *template.Template
 	nodesOfflineEmailTemplate     *template.Template
+	licenseInvoiceEmailTemplate   *template.Template
+	draftStatusEmailTemplate      *template.Template
 
 	invoiceDraftTemplate  *template.Template
 	operatorDraftTemplate *template.Template
@@ -56,6 +58,14 @@ func LoadAndCacheTemplates() {
 		if err != nil {
 			panic(err)
 		}
+		licenseInvoice, err := LoadLicenseInvoiceEmailTemplate()
+		if err != nil {
+			panic(err)
+		}
+		draftStatus, err := LoadDraftStatusEmailTemplate()
+		if err != nil {
+			panic(err)
+		}
 
 		invoiceDraftFile, err := LoadInvoiceDraftTemplate()
 		if err != nil {
@@ -80,6 +90,8 @@ func LoadAndCacheTemplates() {
 		accountResettedEmailTemplate = accountResetted
 		jobsEndingEmailTemplate = jobsEnding
 		nodesOfflineEmailTemplate = nodesOffline
+		licenseInvoiceEmailTemplate = licenseInvoice
+		draftStatusEmailTemplate = draftStatus
 
 		invoiceDraftTemplate = invoiceDraftFile
 		operatorDraftTemplate = operatorDraftFile
@@ -119,6 +131,14 @@ func GetNodesOfflineEmailTemplate() (*template.Template, error) {
 	return getOrSetTemplate(LoadNodesOfflineEmailTemplate, nodesOfflineEmailTemplate)
 }
 
+func GetLicenseInvoiceEmailTemplate() (*template.Template, error) {
+	return getOrSetTemplate(LoadLicenseInvoiceEmailTemplate, licenseInvoiceEmailTemplate)
+}
+
+func GetDraftStatusEmailTemplate() (*template.Template, error) {
+	return getOrSetTemplate(LoadDraftStatusEmailTemplate, draftStatusEmailTemplate)
+}
+
 func GetInvoiceDraftTemplate() (*template.Template, error) {
 	return getOrSetTemplate(LoadInvoiceDraftTemplate, invoiceDraftTemplate)
 }
@@ -40,4 +40,12 @@ func TestTemplateGetters(t *testing.T) {
 	c, err = GetNodesOfflineEmailTemplate()
 	require.Nil(t, err)
 	require.Equal(t, c.Name(), emailNodesOfflineFile)
+
+	c, err = GetLicenseInvoiceEmailTemplate()
+	require.Nil(t, err)
+	require.Equal(t, c.Name(), emailLicenseInvoiceFile)
+
+	c, err = GetDraftStatusEmailTemplate()
+	require.Nil(t, err)
+	require.Equal(t, c.Name(), emailDraftStatusFile)
 }
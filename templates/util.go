@@ -16,6 +16,8 @@ const (
 	emailAccountResettedFile = "email.account.resetted.html"
 	emailJobsEndingFile      = "email.jobs.ending.html"
 	emailNodesOfflineFile    = "email.nodes.offline.html"
+	emailLicenseInvoiceFile  = "email.license.invoice.html"
+	emailDraftStatusFile     = "email.draft.status.html"
 
 	invoiceDraftFile  = "invoice.draft.html"
 	emailOperatorFile = "email.operator.draft.html"
@@ -76,6 +78,14 @@ func LoadNodesOfflineEmailTemplate() (*template.Template, error) {
 	return loadTemplate(emailNodesOfflineFile)
 }
 
+func LoadLicenseInvoiceEmailTemplate() (*template.Template, error) {
+	return loadTemplate(emailLicenseInvoiceFile)
+}
+
+func LoadDraftStatusEmailTemplate() (*template.Template, error) {
+	return loadTemplate(emailDraftStatusFile)
+}
+
 func LoadInvoiceDraftTemplate() (*template.Template, error) {
 	return loadInvoiceTemplate(invoiceDraftFile)
 }